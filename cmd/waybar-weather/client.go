@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/service"
+)
+
+// runClient implements the client subcommand, which subscribes to the output of a running service on its
+// control socket and prints it to stdout for waybar, rendered with the templates of the client's config.
+// USR1 toggles the alternative view of the client only. It returns the exit code of the program.
+func runClient(args []string) int {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: waybar-weather client [--config FILE] [--socket PATH]")
+		flags.PrintDefaults()
+	}
+	confPath := flags.String("config", "", "path to the config file with the templates of the client")
+	socket := flags.String("socket", "", "path to the control socket of the service (overrides the config)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	conf, _, err := loadConfig(*confPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "client failed: %s\n", err)
+		return 1
+	}
+	if *socket == "" {
+		*socket = conf.Control.Socket
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+	client := service.NewClient(*socket, service.NewClientTemplates(conf), os.Stdout, logger.New(conf.LogLevel))
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	defer signal.Stop(sigChan)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+				if conf.SignalToggleEnabled() {
+					client.Toggle()
+				}
+			}
+		}
+	}()
+
+	// Waybar closes the pipe of the module when it restarts, see the service
+	signal.Ignore(syscall.SIGPIPE)
+	client.Run(ctx)
+	return 0
+}
@@ -10,10 +10,10 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 
 	"github.com/wneessen/waybar-weather/internal/config"
@@ -29,6 +29,21 @@ var (
 )
 
 func main() {
+	// The daemon subcommand runs the service for the clients only, instead of printing to stdout
+	args, daemon := os.Args[1:], false
+	if len(args) > 0 {
+		switch args[0] {
+		case "preview":
+			os.Exit(runPreview(args[1:]))
+		case "status":
+			os.Exit(runStatus(args[1:]))
+		case "client":
+			os.Exit(runClient(args[1:]))
+		case "daemon":
+			args, daemon = args[1:], true
+		}
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGKILL,
 		syscall.SIGABRT, os.Interrupt)
 	defer cancel()
@@ -46,41 +61,24 @@ func main() {
 	log := logger.NewLogger(slog.LevelError, nil, logFile)
 
 	// Read config
-	confRead := false
 	confPath := flag.String("config", "", "path to the config file")
-	flag.Parse()
-
-	// Read default config
-	conf, err := config.New()
+	_ = flag.CommandLine.Parse(args)
+	conf, files, err := loadConfig(*confPath)
 	if err != nil {
 		log.Error("failed to load config", logger.Err(err))
 		os.Exit(1)
 	}
-
-	// If config file was specified, read it
-	if *confPath != "" {
-		file := filepath.Base(*confPath)
-		path := filepath.Dir(*confPath)
-		conf, err = config.NewFromFile(path, file)
-		if err != nil {
-			log.Error("failed to load config from file", logger.Err(err))
-			os.Exit(1)
-		}
-		confRead = true
-	}
-
-	// Check if we have a config file in the default location
-	if path, file := findConfigFile(); !confRead && (path != "" && file != "") {
-		conf, err = config.NewFromFile(path, file)
-		if err != nil {
-			log.Error("failed to load config from file", logger.Err(err))
-			os.Exit(1)
-		}
+	if daemon && conf.Control.Disabled {
+		log.Error("the daemon serves the clients on the control socket, which is disabled in the config")
+		os.Exit(1)
 	}
 
 	log = logger.NewLogger(conf.LogLevel, nil, logFile)
 	log.Info("logger initialized", slog.String("json_file_output", logFile.Name()),
 		slog.String("text_output", os.Stderr.Name()))
+	if len(files) > 0 {
+		log.Info("config loaded", slog.Any("files", files))
+	}
 	t, err := i18n.New(conf.Locale)
 	if err != nil {
 		log.Error("failed to initialize localizer", logger.Err(err))
@@ -93,35 +91,47 @@ func main() {
 		log.Error("failed to initialize waybar-weather service", logger.Err(err))
 		os.Exit(1)
 	}
+	serv.Daemon = daemon
 
 	// Set up signal handler
 	sigChan := make(chan os.Signal, 1)
 	serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+	signalsDone := make(chan struct{})
 	go func() {
+		defer close(signalsDone)
 		defer serv.SignalSrc.Stop(sigChan)
 		serv.HandleSignals(ctx, sigChan)
 	}()
 
+	// Waybar closes the pipe of the module when it restarts. Ignoring SIGPIPE makes the writes to stdout
+	// fail with EPIPE instead of terminating the process, so that the service can recover the output or,
+	// if the pipe stays broken, shut down cleanly
+	signal.Ignore(syscall.SIGPIPE)
+
 	// Start the service loop
 	log.Info(t.Get("starting waybar-weather service"), slog.String("version", version),
 		slog.String("commit", commit), slog.String("date", date), slog.Int("process_id", os.Getpid()))
 	if err = serv.Run(ctx); err != nil {
 		log.Error(t.Get("failed to start waybar-weather service"), logger.Err(err))
 	}
+	// The service also returns on its own, e. g. if its output pipe stayed broken. Stop the signal handler,
+	// which stops once the context is cancelled, and wait for it
+	cancel()
+	<-signalsDone
 	log.Info(t.Get("shutting down waybar-weather service"))
 }
 
-func findConfigFile() (string, string) {
-	homedir, err := os.UserHomeDir()
-	if err != nil {
-		return "", ""
+// loadConfig reads the config file at the given path. If no path is given, the system-wide and the user's
+// config files are read and layered, if present. Otherwise, the default config is returned. The files that
+// were read are returned along with the config.
+func loadConfig(confPath string) (*config.Config, []string, error) {
+	files := config.FindFiles()
+	if confPath != "" {
+		files = []string{confPath}
 	}
-	exts := []string{"toml", "yaml", "yml", "json"}
-	for _, ext := range exts {
-		path := filepath.Join(homedir, ".config", "waybar-weather", "config."+ext)
-		if _, err = os.Stat(path); err == nil {
-			return filepath.Dir(path), filepath.Base(path)
-		}
+	conf, err := config.NewFromFiles(files...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config from file: %w", err)
 	}
-	return "", ""
+	return conf, files, nil
 }
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/service"
+)
+
+// runPreview implements the preview subcommand, which renders the templates of the config once against
+// sample or live weather data and prints them to stdout. It returns the exit code of the program.
+func runPreview(args []string) int {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: waybar-weather preview [--config FILE] "+
+			"(--sample | --lat LAT --lon LON | --city CITY)")
+		flags.PrintDefaults()
+	}
+	confPath := flags.String("config", "", "path to the config file")
+	sample := flags.Bool("sample", false, "render the templates against embedded sample weather data")
+	lat := flags.Float64("lat", 0, "latitude of the location to fetch the live weather data for")
+	lon := flags.Float64("lon", 0, "longitude of the location to fetch the live weather data for")
+	city := flags.String("city", "", "city to fetch the live weather data for")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	coordsSet := 0
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "lat" || f.Name == "lon" {
+			coordsSet++
+		}
+	})
+	sources := 0
+	for _, set := range []bool{*sample, *city != "", coordsSet > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 || (coordsSet > 0 && coordsSet != 2) {
+		_, _ = fmt.Fprintln(os.Stderr, "preview requires either --sample, --lat and --lon, or --city")
+		flags.Usage()
+		return 2
+	}
+
+	if err := preview(*confPath, os.Stdout, service.PreviewOptions{
+		Sample: *sample, City: *city, Coordinates: geobus.Coordinate{Lat: *lat, Lon: *lon},
+	}); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "preview failed: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// preview renders the templates of the config at the given path and prints each of them with its name.
+func preview(confPath string, output io.Writer, opts service.PreviewOptions) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	conf, _, err := loadConfig(confPath)
+	if err != nil {
+		return err
+	}
+	t, err := i18n.New(conf.Locale)
+	if err != nil {
+		return fmt.Errorf("failed to initialize localizer: %w", err)
+	}
+	serv, err := service.New(conf, logger.New(conf.LogLevel), t)
+	if err != nil {
+		return fmt.Errorf("failed to initialize waybar-weather service: %w", err)
+	}
+	// Templates that failed to render are shown with the error marker, followed by the error
+	rendered, err := serv.Preview(ctx, opts)
+	if rendered == nil {
+		return err
+	}
+
+	for i, field := range service.PreviewFields {
+		if i > 0 {
+			_, _ = fmt.Fprintln(output)
+		}
+		_, _ = fmt.Fprintf(output, "== %s ==\n%s\n", field, strings.TrimRight(rendered[field], "\n"))
+	}
+	return err
+}
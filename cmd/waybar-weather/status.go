@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/service"
+)
+
+// statusTimeout is the time the status subcommand waits for the running service to answer
+const statusTimeout = 5 * time.Second
+
+// runStatus implements the status subcommand, which queries the running service on its control socket
+// and prints its status to stdout. It returns the exit code of the program.
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: waybar-weather status [--config FILE] [--socket PATH] [--json] "+
+			"[--history]")
+		flags.PrintDefaults()
+	}
+	confPath := flags.String("config", "", "path to the config file")
+	socket := flags.String("socket", "", "path to the control socket (overrides the config)")
+	asJSON := flags.Bool("json", false, "print the status as JSON")
+	history := flags.Bool("history", false, "print the latest outputs of the service")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *socket == "" {
+		conf, _, err := loadConfig(*confPath)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "status failed: %s\n", err)
+			return 1
+		}
+		*socket = conf.Control.Socket
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	status, err := service.QueryStatus(ctx, *socket)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "status failed: %s (is waybar-weather running?)\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(status); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to encode status: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+	printStatus(os.Stdout, status, *history)
+	return 0
+}
+
+// printStatus prints the status in a human readable form. Times are printed relative to the time of the
+// status. If history is set, the latest outputs are printed as well.
+func printStatus(output io.Writer, status service.Status, history bool) {
+	ago := func(at time.Time) string {
+		if at.IsZero() {
+			return "never"
+		}
+		return status.Time.Sub(at).Round(time.Second).String() + " ago"
+	}
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Geolocation:\t%s (updated %s)\n", orNone(status.GeolocationSource),
+		ago(status.GeolocationAt))
+	_, _ = fmt.Fprintf(w, "Coordinates:\t%.4f, %.4f\n", status.Latitude, status.Longitude)
+	_, _ = fmt.Fprintf(w, "City:\t%s (%s)\n", orNone(status.City), orNone(status.DisplayName))
+	_, _ = fmt.Fprintf(w, "Weather:\t%s (fetched %s)\n", orNone(status.WeatherProvider),
+		ago(status.WeatherFetchedAt))
+	_, _ = fmt.Fprintf(w, "Render mode:\t%s\n", status.RenderMode)
+	if len(status.Errors) == 0 {
+		_, _ = fmt.Fprintln(w, "Errors:\tnone")
+	}
+	for i, subsystem := range slices.Sorted(maps.Keys(status.Errors)) {
+		label := ""
+		if i == 0 {
+			label = "Errors:"
+		}
+		subsystemErr := status.Errors[subsystem]
+		when := ""
+		if !subsystemErr.At.IsZero() {
+			when = " (" + ago(subsystemErr.At) + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s: %s%s\n", label, subsystem, subsystemErr.Error, when)
+	}
+	if history {
+		if len(status.RenderHistory) == 0 {
+			_, _ = fmt.Fprintln(w, "History:\tnone")
+		}
+		for i, record := range slices.Backward(status.RenderHistory) {
+			label := ""
+			if i == len(status.RenderHistory)-1 {
+				label = "History:"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s (%s): %s\n", label, ago(record.Time), record.Trigger, record.Output)
+		}
+	}
+	_ = w.Flush()
+}
@@ -4,28 +4,31 @@ go 1.26.0
 
 require (
 	github.com/Xuanwo/go-locale v1.1.3
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/godbus/dbus/v5 v5.2.2
 	github.com/kkyr/fig v0.5.0
+	github.com/mattn/go-runewidth v0.0.28
 	github.com/mdlayher/wifi v0.7.2
+	github.com/mitchellh/mapstructure v1.5.0
 	github.com/nathan-osman/go-sunrise v1.1.0
+	github.com/pelletier/go-toml/v2 v2.3.1
 	github.com/vorlif/humanize v1.0.0
 	github.com/vorlif/spreak v1.0.0
 	github.com/wneessen/go-moonphase v0.0.0-20251108174843-0043855bd40d
 	golang.org/x/text v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/mdlayher/genetlink v1.4.0 // indirect
 	github.com/mdlayher/netlink v1.11.2 // indirect
 	github.com/mdlayher/socket v0.6.1 // indirect
-	github.com/mitchellh/mapstructure v1.5.0 // indirect
-	github.com/pelletier/go-toml/v2 v2.3.1 // indirect
 	golang.org/x/crypto v0.52.0 // indirect
 	golang.org/x/net v0.55.0 // indirect
 	golang.org/x/sync v0.20.0 // indirect
 	golang.org/x/sys v0.45.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package clock provides an abstraction of the current time, so that time-dependent logic can be tested
+// without relying on the wall clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is implemented by types that provide the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the Clock implementation that uses the system's wall clock.
+type Real struct{}
+
+// Fake is a Clock implementation that returns a manually controlled point in time. It is safe for
+// concurrent use.
+type Fake struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// Now returns the current system time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewFake returns a new Fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time the Fake clock is currently set to.
+func (f *Fake) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+// Set sets the Fake clock to the given time.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the Fake clock forward by the given duration.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReal_Now(t *testing.T) {
+	before := time.Now()
+	got := Real{}.Now()
+	after := time.Now()
+	if got.Before(before) || got.After(after) {
+		t.Errorf("expected time to be between %s and %s, got %s", before, after, got)
+	}
+}
+
+func TestFake(t *testing.T) {
+	want := time.Date(2026, 1, 18, 14, 30, 0, 0, time.UTC)
+	t.Run("now returns the set time", func(t *testing.T) {
+		fake := NewFake(want)
+		if !fake.Now().Equal(want) {
+			t.Errorf("expected time to be %s, got %s", want, fake.Now())
+		}
+	})
+	t.Run("set changes the time", func(t *testing.T) {
+		fake := NewFake(want)
+		later := want.Add(time.Hour * 24)
+		fake.Set(later)
+		if !fake.Now().Equal(later) {
+			t.Errorf("expected time to be %s, got %s", later, fake.Now())
+		}
+	})
+	t.Run("advance moves the time forward", func(t *testing.T) {
+		fake := NewFake(want)
+		fake.Advance(time.Minute * 90)
+		if !fake.Now().Equal(want.Add(time.Minute * 90)) {
+			t.Errorf("expected time to be %s, got %s", want.Add(time.Minute*90), fake.Now())
+		}
+	})
+}
@@ -7,58 +7,204 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kkyr/fig"
+
+	"github.com/wneessen/waybar-weather/internal/units"
+	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
 const (
 	configEnv         = "WAYBARWEATHER"
-	DefaultTextTpl    = "{{.Current.ConditionIcon}} {{hum .Current.Temperature}}{{.Current.Units.Temperature}}"
-	DefaultAltTextTpl = "{{.Forecast.ConditionIcon}} {{hum .Forecast.Temperature}}{{.Forecast.Units.Temperature}}"
-	DefaultTooltipTpl = "{{.Address.City}}, {{.Address.Country}}\n" +
+	DefaultTextTpl    = "{{padIcon .Current.ConditionIcon}} {{.Current.TemperatureStr}}"
+	DefaultAltTextTpl = "{{padIcon .Forecast.ConditionIcon}} {{.Forecast.TemperatureStr}}"
+	DefaultTooltipTpl = defaultAddressTpl + "\n" +
+		"{{with .FreshnessLine}}{{.}}\n{{end}}" +
+		"{{.Current.ConditionIcon}} {{.Current.Condition}}, {{.Current.TemperatureStr}}\n" +
+		"{{with .Current.FeelsLikeLine}}{{.}}\n{{end}}" +
+		"{{loc \"humidity\"}}: {{.Current.RelativeHumidityStr}}\n" +
+		"{{loc \"pressure\"}}: {{.Current.PressureStr}}\n" +
+		"{{loc \"wind\"}}: {{.Current.WindSpeedStr}} → {{.Current.WindGustsStr}} ({{windDir .Current.WindDirection}})\n" +
+		"{{if .Forecasts}}\n" +
+		"{{with fcastHourOffset . 3}}{{if .TemperatureStr}}+3h: {{.ConditionIcon}} {{.TemperatureStr}}\n{{end}}{{end}}" +
+		"{{with fcastHourOffset . 6}}{{if .TemperatureStr}}+6h: {{.ConditionIcon}} {{.TemperatureStr}}\n{{end}}{{end}}" +
+		"{{with fcastHourOffset . 12}}{{if .TemperatureStr}}+12h: {{.ConditionIcon}} {{.TemperatureStr}}\n{{end}}{{end}}" +
+		"\n" +
+		"{{loc \"today\"}}: ↑ {{.TodayMaxStr}} ↓ {{.TodayMinStr}}\n" +
+		"{{else}}\n" +
+		"{{end}}" +
+		`🌅 {{prefTime .SunriseTime}} • 🌇 {{prefTime .SunsetTime}}` +
+		`{{with .MoonPhase}} • {{$.MoonPhaseIcon}} {{loc .}}{{end}}` +
+		"{{with .Attribution}}\n{{italic .}}{{end}}"
+	// DefaultLegacyTooltipTpl is the default tooltip template of earlier versions, which only shows the
+	// current weather. It is used instead of DefaultTooltipTpl if templates.legacy_default is set.
+	DefaultLegacyTooltipTpl = defaultAddressTpl + "\n" +
 		"{{.Current.Condition}}\n" +
-		"{{loc \"apparent\"}}: {{hum .Current.ApparentTemperature}}{{.Current.Units.Temperature}}\n" +
-		"{{loc \"humidity\"}}: {{.Current.RelativeHumidity}}%\n" +
-		"{{loc \"pressure\"}}: {{hum .Current.PressureMSL}} {{.Current.Units.Pressure}}\n" +
-		"{{loc \"wind\"}}: {{hum .Current.WindSpeed}} → {{hum .Current.WindGusts}} {{.Current.Units.WindSpeed}} ({{windDir .Current.WindDirection}})\n" +
+		"{{loc \"apparent\"}}: {{.Current.ApparentTemperatureStr}}\n" +
+		"{{loc \"humidity\"}}: {{.Current.RelativeHumidityStr}}\n" +
+		"{{loc \"pressure\"}}: {{.Current.PressureStr}}\n" +
+		"{{loc \"wind\"}}: {{.Current.WindSpeedStr}} → {{.Current.WindGustsStr}} ({{windDir .Current.WindDirection}})\n" +
 		"\n" +
-		`🌅 {{localizedTime .SunriseTime}} • 🌇 {{localizedTime .SunsetTime}}`
-	DefaultAltTooltipTpl = "{{.Address.City}}, {{.Address.Country}}\n" +
+		`🌅 {{prefTime .SunriseTime}} • 🌇 {{prefTime .SunsetTime}}`
+	DefaultAltTooltipTpl = defaultAddressTpl + "\n" +
 		"{{.Forecast.Condition}}\n" +
-		"{{loc \"apparent\"}}: {{hum .Forecast.ApparentTemperature}}{{.Forecast.Units.Temperature}}\n" +
-		"{{loc \"humidity\"}}: {{.Forecast.RelativeHumidity}}%\n" +
-		"{{loc \"pressure\"}}: {{hum .Forecast.PressureMSL}} {{.Forecast.Units.Pressure}}\n" +
-		"{{loc \"wind\"}}: {{hum .Forecast.WindSpeed}} → {{hum .Forecast.WindGusts}} {{.Forecast.Units.WindSpeed}} ({{windDir .Forecast.WindDirection}})\n" +
+		"{{loc \"apparent\"}}: {{.Forecast.ApparentTemperatureStr}}\n" +
+		"{{loc \"humidity\"}}: {{.Forecast.RelativeHumidityStr}}\n" +
+		"{{loc \"pressure\"}}: {{.Forecast.PressureStr}}\n" +
+		"{{loc \"wind\"}}: {{.Forecast.WindSpeedStr}} → {{.Forecast.WindGustsStr}} ({{windDir .Forecast.WindDirection}})\n" +
 		"\n" +
-		`🌅 {{localizedTime .SunriseTime}} • 🌇 {{localizedTime .SunsetTime}}`
+		`🌅 {{prefTime .SunriseTime}} • 🌇 {{prefTime .SunsetTime}}`
+	// DefaultWorstAltTextTpl is the default alternative text if templates.worst_condition is set. It shows
+	// the most severe weather condition within the forecast hours instead of the forecast hour.
+	DefaultWorstAltTextTpl = "{{padIcon .WorstForecast.ConditionIcon}} {{.WorstForecast.TemperatureStr}}"
+
+	DefaultPendingTpl = `⏳ {{loc "locating"}}…`
+	DefaultErrorTpl   = `⚠️ {{loc "unavailable"}}`
+
+	// Default number of decimal places of the formatted display values. The pressure and precipitation
+	// defaults depend on the unit, since inHg and inches need more decimal places to be meaningful.
+	DefaultTemperaturePrecision   = 1
+	DefaultWindPrecision          = 0
+	DefaultPressurePrecision      = 0
+	DefaultInHgPrecision          = 2
+	DefaultPrecipitationPrecision = 1
+	DefaultInchPrecision          = 2
+	maxPrecision                  = 6
+
+	// Default weights of the components of the condition severity score
+	DefaultSeverityCategoryWeight      = 40
+	DefaultSeverityWindWeight          = 25
+	DefaultSeverityPrecipitationWeight = 20
+	DefaultSeverityTemperatureWeight   = 15
+
+	// conditionIconNightSuffix is the suffix of the condition_icons keys that only replace the night icon
+	conditionIconNightSuffix = "_night"
+
+	// defaultAddressTpl is the location line of the default tooltips. Without a city, e. g. if reverse
+	// geocoding is disabled, the display name is shown instead, which then holds the coordinates.
+	defaultAddressTpl = "{{if .Address.City}}{{.Address.City}}, {{.Address.Country}}" +
+		"{{else}}{{.Address.DisplayName}}{{end}}"
 )
 
+// DefaultTempGradient is the default color gradient of the tempColor template function, from blue for cold
+// over green to red for hot temperatures. The stops are in °C and converted into °F if needed.
+var DefaultTempGradient = []ColorStop{
+	{Temperature: "-10C", Color: "#3b82f6"},
+	{Temperature: "10C", Color: "#22c55e"},
+	{Temperature: "30C", Color: "#ef4444"},
+}
+
+// WeatherCategories are the general weather conditions that the weather codes are categorized into, ordered
+// by their severity, the mildest category first.
+var WeatherCategories = []string{"clear", "cloudy", "fog", "rain", "snow", "thunderstorm"}
+
+// GeoIPBackends are the APIs the GeoIP geolocation provider can look up the location with.
+var GeoIPBackends = []string{"reallyfreegeoip", "ip-api", "ipinfo"}
+
+// OpenMeteoModels are the weather models the Open-Meteo provider can be set to. The list is not exhaustive,
+// since Open-Meteo adds new models from time to time.
+var OpenMeteoModels = []string{
+	"best_match", "ecmwf_ifs04", "ecmwf_ifs025", "ecmwf_aifs025", "icon_seamless", "icon_global", "icon_eu",
+	"icon_d2", "gfs_seamless", "gfs_global", "gfs_hrrr", "meteofrance_seamless", "meteofrance_arpege_world",
+	"meteofrance_arpege_europe", "meteofrance_arome_france", "meteofrance_arome_france_hd", "jma_seamless",
+	"jma_msm", "jma_gsm", "gem_seamless", "gem_global", "gem_regional", "gem_hrdps_continental",
+	"metno_seamless", "metno_nordic", "ukmo_seamless", "ukmo_global_deterministic_10km",
+	"ukmo_uk_deterministic_2km", "knmi_seamless", "dmi_seamless", "cma_grapes_global", "bom_access_global",
+}
+
+// envReference matches a ${NAME} reference to an environment variable in an API key
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// DefaultControlSocket returns the default path of the control socket in the runtime directory of the
+// user. Without runtime directory, the socket is placed in the temp directory with the user ID in its name.
+func DefaultControlSocket() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "waybar-weather.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("waybar-weather-%d.sock", os.Getuid()))
+}
+
 // Config represents the application's configuration structure.
 type Config struct {
-	// Allowed values: metric, imperial
+	// Allowed values: metric, imperial, auto
 	Units    string     `fig:"units" default:"metric"`
 	Locale   string     `fig:"locale"`
 	LogLevel slog.Level `fig:"loglevel" default:"0"`
 
+	// Per-metric units that take precedence over the global units setting
+	UnitOverrides struct {
+		Temperature   string `fig:"temperature"`
+		WindSpeed     string `fig:"wind_speed"`
+		Pressure      string `fig:"pressure"`
+		Precipitation string `fig:"precipitation"`
+	} `fig:"unit_overrides"`
+
 	Weather struct {
-		Provider string `fig:"provider" default:"open-meteo"`
+		// Weather providers in the order they are tried. The first one is the primary provider, the others
+		// are fallbacks that are used while the providers before them fail
+		Provider []string `fig:"provider" default:"open-meteo"`
+		// Interval in which the primary provider is tried again while a fallback provider is active
+		ProbeInterval time.Duration `fig:"probe_interval" default:"30m"`
+		// Number of consecutive failed fetches of the active provider before the next provider is tried
+		FailoverThreshold uint `fig:"failover_threshold" default:"1"`
+		// API key of the weather provider. Only used by the "pirateweather" provider
+		APIKey string `fig:"apikey"`
+		// File that holds the API key of the weather provider, if the API key is not set
+		APIKeyFile string `fig:"apikey_file"`
+		// Base URL of the weather provider API. Only used by the "wttr" provider
+		BaseURL string `fig:"base_url"`
+		// Weather model and elevation in meters that override the automatic selection of the weather
+		// provider. Only used by the "open-meteo" provider
+		Model     string   `fig:"model"`
+		Elevation *float64 `fig:"elevation"`
+		// Fetch the 15-minutely precipitation nowcast in addition to the hourly forecast. Only used by the
+		// "open-meteo" provider
+		Nowcast bool `fig:"nowcast"`
 
 		// Allowed value: 1 to 24
 		ForecastHours uint `fig:"forecast_hours" default:"3"`
+		// The hourly forecast list of the templates starts this long before the hour in progress
+		ForecastLookback time.Duration `fig:"forecast_lookback" default:"0s"`
 
-		// Cold and hot class thresholds (Defaults are based on °C)
+		// Cold and hot class thresholds. The temperature settings are in the displayed temperature unit,
+		// unless they have an explicit unit suffix like "68F" or "20C" (see units.ParseTemperature).
 		// Defaults are based on suggestions for dangerous driving conditions and uncomfortable heat.
-		ColdThreshold float64 `fig:"cold_threshold" default:"2"`
-		HotThreshold  float64 `fig:"hot_threshold" default:"30"`
+		ColdThreshold string `fig:"cold_threshold" default:"2C"`
+		HotThreshold  string `fig:"hot_threshold" default:"30C"`
+		// Frost class threshold
+		FrostThreshold string `fig:"frost_threshold" default:"0C"`
+		// The apparent temperature is only shown by the default tooltip if it differs from the temperature
+		// by more than this delta
+		ApparentDelta string `fig:"apparent_delta" default:"2"`
+		// Wind gust warning threshold in the preferred wind speed unit (0 disables the warning) and the
+		// forecast window that is checked for gusts at or above it
+		GustWarningThreshold float64       `fig:"gust_warning_threshold" default:"0"`
+		GustWarningWindow    time.Duration `fig:"gust_warning_window" default:"6h"`
+		// Number of consecutive failed fetches before the error state is shown instead of the pending state
+		FailureThreshold uint `fig:"failure_threshold" default:"3"`
+		// Forecast entries missing in a new fetch are kept from previous fetches up to this age (0 disables)
+		ForecastMaxAge time.Duration `fig:"forecast_max_age" default:"6h"`
 	} `fig:"weather"`
 
 	Intervals struct {
 		WeatherUpdate time.Duration `fig:"weather_update" default:"15m"`
 		Output        time.Duration `fig:"output" default:"30s"`
+		// Minimum interval between two outputs. Render triggers within this interval are coalesced
+		MinRender time.Duration `fig:"min_render" default:"1s"`
+		// Factor that the weather update and geolocation poll intervals are multiplied with while the
+		// system runs on battery power (0 disables)
+		BatteryMultiplier float64 `fig:"battery_multiplier" default:"0"`
+		// Upper bound of the random offset that is added to every run of the scheduled jobs and that
+		// delays the initial fetch, so that many instances don't hit the providers at the same time
+		Jitter time.Duration `fig:"jitter" default:"0"`
 	} `fig:"intervals"`
 
 	Templates struct {
@@ -66,10 +212,52 @@ type Config struct {
 		AltText    string `fig:"alt_text"`
 		Tooltip    string `fig:"tooltip"`
 		AltTooltip string `fig:"alt_tooltip"`
+		// Optional templates that replace text and tooltip while it is night at the current location
+		TextNight    string `fig:"text_night"`
+		TooltipNight string `fig:"tooltip_night"`
+		// Templates for the placeholder states while no weather data is available yet
+		Pending    string `fig:"pending"`
+		Error      string `fig:"error"`
 		UseCSSIcon bool   `fig:"use_css_icon"`
+		// Use the single-section default tooltip of earlier versions instead of the current default
+		LegacyDefault bool `fig:"legacy_default"`
+		// Show the most severe weather condition within the forecast hours in the default alternative
+		// text and its CSS classes, instead of the condition of the forecast hour
+		WorstCondition bool `fig:"worst_condition"`
+		// Icons that replace the condition icons of WMO weather codes, keyed by the code. A key with the
+		// "_night" suffix only replaces the night icon of the code
+		ConditionIcons map[string]string `fig:"condition_icons"`
 	} `fig:"templates"`
 
+	Output struct {
+		// Escape the tooltips for Waybar's Pango markup, so that the markup template helpers can be used
+		TooltipMarkup bool `fig:"tooltip_markup"`
+		// Go time layout for the prefTime template function. If unset, the time format of the locale is used
+		TimeFormat string `fig:"time_format"`
+		// Attribution replaces the attribution of the weather provider in the templates. HideAttribution
+		// removes it, e. g. if it is shown elsewhere
+		Attribution     string `fig:"attribution"`
+		HideAttribution bool   `fig:"hide_attribution"`
+		// Display width in terminal cells that the padIcon template function pads the icons to
+		IconWidth uint `fig:"icon_width" default:"2"`
+		// Compute the station pressure at the elevation of the location from the sea-level pressure
+		StationPressure bool `fig:"station_pressure"`
+		// Color gradient of the tempColor template function. If unset, DefaultTempGradient is used
+		TempGradient []ColorStop `fig:"temp_gradient"`
+		// Number of decimal places of the formatted display values like Current.TemperatureStr. The
+		// pointers tell an explicit 0 apart from an unset value, which is set to its default on validation
+		TemperaturePrecision   *uint `fig:"temperature_precision"`
+		WindPrecision          *uint `fig:"wind_precision"`
+		PressurePrecision      *uint `fig:"pressure_precision"`
+		PrecipitationPrecision *uint `fig:"precipitation_precision"`
+		// Toggle between the primary and the alternative view on the USR1 signal. If disabled, waybar
+		// toggles the view with format-alt on the alt field of the output instead. Unset means enabled
+		SignalToggle *bool `fig:"signal_toggle"`
+	} `fig:"output"`
+
 	GeoLocation struct {
+		// The key the service subscribes to for location updates on the geobus
+		SubscriptionKey        string `fig:"subscription_key" default:"location-update"`
 		GeoLocationFile        string `fig:"geolocation_file"`
 		CitynameFile           string `fig:"cityname_file"`
 		DisableGeoIP           bool   `fig:"disable_geoip"`
@@ -78,12 +266,137 @@ type Config struct {
 		DisableCitynameFile    bool   `fig:"disable_cityname_file"`
 		DisableICHNAEA         bool   `fig:"disable_ichnaea"`
 		DisableGPSD            bool   `fig:"disable_gpsd"`
+		// The API the GeoIP provider looks up the location with and its optional token
+		GeoIPBackend   string `fig:"geoip_backend" default:"reallyfreegeoip"`
+		GeoIPToken     string `fig:"geoip_token"`
+		GeoIPTokenFile string `fig:"geoip_token_file"`
+		// Time an expired location is still protected against less accurate geolocation sources
+		GracePeriod time.Duration `fig:"grace_period" default:"10m"`
+		// Moves by more than this distance in km have to be confirmed by a second consistent result
+		// of the same source (0 disables the confirmation)
+		ConfirmDistance float64 `fig:"confirm_distance" default:"20"`
+		// Locations with an accuracy radius of more than this distance in km are marked as approximate
+		// (0 disables the marking)
+		ApproxThreshold float64 `fig:"approx_threshold" default:"10"`
+		// Locations with an accuracy radius of more than this many meters are ignored (0 disables). If no
+		// location is accurate enough within the timeout after start, the error state is shown.
+		MinAccuracy        float64       `fig:"min_accuracy_m" default:"0"`
+		MinAccuracyTimeout time.Duration `fig:"min_accuracy_timeout" default:"2m"`
 	} `fig:"geolocation"`
 
 	GeoCoder struct {
-		Provider string `fig:"provider" default:"nominatim"`
-		APIKey   string `fig:"apikey"`
+		Provider      string `fig:"provider" default:"nominatim"`
+		APIKey        string `fig:"apikey"`
+		APIKeyFile    string `fig:"apikey_file"`
+		BaseURL       string `fig:"base_url"`
+		DisplayFormat string `fig:"display_format"`
+		DiskCache     bool   `fig:"disk_cache"`
+		DiskCacheFile string `fig:"disk_cache_file"`
+		// Number of decimal places of the coordinates that replace the address with the "none" provider
+		CoordinatePrecision uint `fig:"coordinate_precision" default:"2"`
 	} `fig:"geocoder"`
+
+	// Privacy of the requests to the weather and geocoding providers
+	Privacy struct {
+		// Number of decimal places the coordinates are truncated to before they are sent to a provider
+		CoordinatePrecision uint `fig:"coordinate_precision" default:"4"`
+		// Open a new connection for every API request instead of reusing the idle connections
+		DisableKeepAlives bool `fig:"disable_keepalives"`
+	} `fig:"privacy"`
+
+	// D-Bus service on the session bus that exposes the current weather data to other desktop components
+	DBus struct {
+		Enabled bool `fig:"enabled"`
+	} `fig:"dbus"`
+
+	// Icon of the current weather category that is written to a file with a stable path after every render
+	Icons struct {
+		FileOutput bool   `fig:"file_output"`
+		File       string `fig:"file"`
+	} `fig:"icons"`
+
+	// Weights of the components of the condition severity score. The pointers tell an explicit 0, which
+	// leaves a component out, apart from an unset weight, which is set to its default on validation
+	Severity struct {
+		CategoryWeight      *float64 `fig:"category_weight"`
+		WindWeight          *float64 `fig:"wind_weight"`
+		PrecipitationWeight *float64 `fig:"precipitation_weight"`
+		TemperatureWeight   *float64 `fig:"temperature_weight"`
+	} `fig:"severity"`
+
+	// Desktop notifications about upcoming changes of the weather condition
+	Notifications struct {
+		Enabled bool `fig:"enabled"`
+		// Weather categories that a change to is notified about
+		Categories []string `fig:"categories" default:"[rain,snow,thunderstorm]"`
+	} `fig:"notifications"`
+
+	// Control socket that the status subcommand queries for the state of the running service
+	Control struct {
+		Disabled bool   `fig:"disable"`
+		Socket   string `fig:"socket"`
+	} `fig:"control"`
+
+	// State that is kept across restarts of the service, like the display of the alternative view
+	State struct {
+		Disabled bool   `fig:"disable"`
+		File     string `fig:"file"`
+	} `fig:"state"`
+
+	Debug struct {
+		// Retain the raw weather provider responses and write a debug dump on SIGUSR2
+		Enabled bool `fig:"enabled"`
+		// Directory the debug dumps are written to
+		DumpDir string `fig:"dump_dir"`
+		// Number of the latest outputs that are kept for the debug dumps and the status subcommand (0 disables)
+		RenderHistory uint `fig:"render_history" default:"20"`
+	} `fig:"debug"`
+
+	// Additional fixed locations to fetch weather data for
+	Locations []Location `fig:"locations"`
+
+	// Named areas that replace the reverse geocoded address while the location is within them
+	Geofences []Geofence `fig:"geofences"`
+}
+
+// Location represents an additional, fixed location for which weather data is fetched. A location
+// is either identified by a city name that will be looked up by the geocoder or by its coordinates.
+type Location struct {
+	Name      string  `fig:"name"`
+	City      string  `fig:"city"`
+	Latitude  float64 `fig:"latitude"`
+	Longitude float64 `fig:"longitude"`
+}
+
+// Geofence is a named circular area, e. g. "Home" or "Office". While the location is within the radius (in
+// meters) around its center, its name replaces the reverse geocoded address.
+type Geofence struct {
+	Name      string  `fig:"name"`
+	Latitude  float64 `fig:"latitude"`
+	Longitude float64 `fig:"longitude"`
+	Radius    float64 `fig:"radius_m"`
+}
+
+// ColorStop is a stop of the temperature color gradient. The color is a hex value like "#22c55e". The
+// temperature is in the displayed temperature unit, unless it has an explicit unit suffix like "68F".
+type ColorStop struct {
+	Temperature string `fig:"temperature"`
+	Color       string `fig:"color"`
+}
+
+// Temperatures holds the temperature settings normalized into a temperature unit.
+type Temperatures struct {
+	ColdThreshold  float64
+	HotThreshold   float64
+	FrostThreshold float64
+	ApparentDelta  float64
+	Gradient       []GradientStop
+}
+
+// GradientStop is a stop of the temperature color gradient normalized into a temperature unit.
+type GradientStop struct {
+	Temperature float64
+	Color       string
 }
 
 func NewFromFile(path, file string) (*Config, error) {
@@ -95,6 +408,7 @@ func NewFromFile(path, file string) (*Config, error) {
 	if err = fig.Load(conf, fig.Dirs(path), fig.File(file), fig.UseEnv(configEnv)); err != nil {
 		return conf, fmt.Errorf("failed to load Config: %w", err)
 	}
+	conf.resolveSecretFiles(path)
 
 	return conf, conf.Validate()
 }
@@ -108,25 +422,135 @@ func New() (*Config, error) {
 	return conf, conf.Validate()
 }
 
+// Warnings returns the problems of the configuration that do not prevent the service from starting,
+// like an unknown weather model that might have been added upstream after this release.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Weather.Model != "" && !slices.Contains(OpenMeteoModels, c.Weather.Model) {
+		warnings = append(warnings, fmt.Sprintf("unknown Open-Meteo weather model: %s", c.Weather.Model))
+	}
+	return warnings
+}
+
 func (c *Config) Validate() error {
-	if c.Units != "metric" && c.Units != "imperial" {
+	if c.Units != weather.UnitSystemMetric && c.Units != weather.UnitSystemImperial &&
+		c.Units != weather.UnitSystemAuto {
 		return fmt.Errorf("invalid units: %s", c.Units)
 	}
+	c.UnitOverrides.Temperature = strings.ToLower(c.UnitOverrides.Temperature)
+	c.UnitOverrides.WindSpeed = strings.ToLower(c.UnitOverrides.WindSpeed)
+	c.UnitOverrides.Pressure = strings.ToLower(c.UnitOverrides.Pressure)
+	c.UnitOverrides.Precipitation = strings.ToLower(c.UnitOverrides.Precipitation)
+	for metric, unit := range map[string]string{
+		"temperature":   c.UnitOverrides.Temperature,
+		"wind_speed":    c.UnitOverrides.WindSpeed,
+		"pressure":      c.UnitOverrides.Pressure,
+		"precipitation": c.UnitOverrides.Precipitation,
+	} {
+		if unit != "" && !slices.Contains(weather.SupportedUnits[metric], unit) {
+			return fmt.Errorf("invalid %s unit: %s", metric, unit)
+		}
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return err
+	}
+	if err := c.validatePrecision(); err != nil {
+		return err
+	}
+	if err := c.validateTemperatures(); err != nil {
+		return err
+	}
+	if err := c.validateSeverity(); err != nil {
+		return err
+	}
+	if len(c.Weather.Provider) == 0 {
+		return fmt.Errorf("at least one weather provider is required")
+	}
+	for i, provider := range c.Weather.Provider {
+		c.Weather.Provider[i] = strings.ToLower(strings.TrimSpace(provider))
+		if slices.Contains(c.Weather.Provider[:i], c.Weather.Provider[i]) {
+			return fmt.Errorf("duplicate weather provider: %s", c.Weather.Provider[i])
+		}
+	}
+	for i, category := range c.Notifications.Categories {
+		c.Notifications.Categories[i] = strings.ToLower(strings.TrimSpace(category))
+		if !slices.Contains(WeatherCategories, c.Notifications.Categories[i]) {
+			return fmt.Errorf("unsupported notification category: %s", category)
+		}
+	}
+	if c.GeoCoder.CoordinatePrecision > maxPrecision {
+		return fmt.Errorf("invalid coordinate precision: %d", c.GeoCoder.CoordinatePrecision)
+	}
+	if c.Privacy.CoordinatePrecision > maxPrecision {
+		return fmt.Errorf("invalid privacy coordinate precision: %d", c.Privacy.CoordinatePrecision)
+	}
+	if c.Weather.ProbeInterval < 0 {
+		return fmt.Errorf("invalid weather provider probe interval: %s", c.Weather.ProbeInterval)
+	}
+	if c.Weather.GustWarningThreshold < 0 {
+		return fmt.Errorf("invalid gust warning threshold: %g", c.Weather.GustWarningThreshold)
+	}
+	if c.Weather.GustWarningWindow < 0 {
+		return fmt.Errorf("invalid gust warning window: %s", c.Weather.GustWarningWindow)
+	}
+	if c.Intervals.MinRender < 0 {
+		return fmt.Errorf("invalid minimum render interval: %s", c.Intervals.MinRender)
+	}
+	if c.Intervals.BatteryMultiplier != 0 && c.Intervals.BatteryMultiplier < 1 {
+		return fmt.Errorf("invalid battery multiplier: %g", c.Intervals.BatteryMultiplier)
+	}
+	if c.Intervals.Jitter < 0 {
+		return fmt.Errorf("invalid jitter: %s", c.Intervals.Jitter)
+	}
 	if c.Weather.ForecastHours < 1 || c.Weather.ForecastHours > 24 {
 		return fmt.Errorf("invalid forcast hours: %d", c.Weather.ForecastHours)
 	}
+	if c.Weather.ForecastLookback < 0 {
+		return fmt.Errorf("invalid forecast lookback: %s", c.Weather.ForecastLookback)
+	}
 	if c.Templates.Text == "" {
 		c.Templates.Text = DefaultTextTpl
 	}
 	if c.Templates.AltText == "" {
 		c.Templates.AltText = DefaultAltTextTpl
+		if c.Templates.WorstCondition {
+			c.Templates.AltText = DefaultWorstAltTextTpl
+		}
 	}
 	if c.Templates.Tooltip == "" {
 		c.Templates.Tooltip = DefaultTooltipTpl
+		if c.Templates.LegacyDefault {
+			c.Templates.Tooltip = DefaultLegacyTooltipTpl
+		}
 	}
 	if c.Templates.AltTooltip == "" {
 		c.Templates.AltTooltip = DefaultAltTooltipTpl
 	}
+	if c.Templates.Pending == "" {
+		c.Templates.Pending = DefaultPendingTpl
+	}
+	if c.Templates.Error == "" {
+		c.Templates.Error = DefaultErrorTpl
+	}
+	c.GeoLocation.GeoIPBackend = strings.ToLower(strings.TrimSpace(c.GeoLocation.GeoIPBackend))
+	if !slices.Contains(GeoIPBackends, c.GeoLocation.GeoIPBackend) {
+		return fmt.Errorf("unsupported GeoIP backend: %s", c.GeoLocation.GeoIPBackend)
+	}
+	if c.GeoLocation.GracePeriod < 0 {
+		return fmt.Errorf("invalid geolocation grace period: %s", c.GeoLocation.GracePeriod)
+	}
+	if c.GeoLocation.ConfirmDistance < 0 {
+		return fmt.Errorf("invalid geolocation confirm distance: %g", c.GeoLocation.ConfirmDistance)
+	}
+	if c.GeoLocation.ApproxThreshold < 0 {
+		return fmt.Errorf("invalid geolocation approximation threshold: %g", c.GeoLocation.ApproxThreshold)
+	}
+	if c.GeoLocation.MinAccuracy < 0 {
+		return fmt.Errorf("invalid geolocation minimum accuracy: %g", c.GeoLocation.MinAccuracy)
+	}
+	if c.GeoLocation.MinAccuracyTimeout < 0 {
+		return fmt.Errorf("invalid geolocation minimum accuracy timeout: %s", c.GeoLocation.MinAccuracyTimeout)
+	}
 	if c.GeoLocation.GeoLocationFile == "" {
 		home, _ := os.UserHomeDir()
 		c.GeoLocation.GeoLocationFile = filepath.Join(home, ".config", "waybar-weather", "geolocation")
@@ -135,14 +559,354 @@ func (c *Config) Validate() error {
 		home, _ := os.UserHomeDir()
 		c.GeoLocation.CitynameFile = filepath.Join(home, ".config", "waybar-weather", "cityname")
 	}
+	if c.GeoCoder.DiskCacheFile == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			home, _ := os.UserHomeDir()
+			cacheDir = filepath.Join(home, ".cache")
+		}
+		c.GeoCoder.DiskCacheFile = filepath.Join(cacheDir, "waybar-weather", "geocode-cache.json")
+	}
+	if c.Icons.File == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			home, _ := os.UserHomeDir()
+			cacheDir = filepath.Join(home, ".cache")
+		}
+		c.Icons.File = filepath.Join(cacheDir, "waybar-weather", "current.svg")
+	}
+	if c.Control.Socket == "" {
+		c.Control.Socket = DefaultControlSocket()
+	}
+	if c.State.File == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			home, _ := os.UserHomeDir()
+			cacheDir = filepath.Join(home, ".cache")
+		}
+		c.State.File = filepath.Join(cacheDir, "waybar-weather", "state.json")
+	}
+	if c.Debug.DumpDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			home, _ := os.UserHomeDir()
+			cacheDir = filepath.Join(home, ".cache")
+		}
+		c.Debug.DumpDir = filepath.Join(cacheDir, "waybar-weather")
+	}
+	seen := make(map[string]struct{}, len(c.Locations))
+	for _, loc := range c.Locations {
+		if loc.Name == "" {
+			return fmt.Errorf("location name is required")
+		}
+		if _, ok := seen[loc.Name]; ok {
+			return fmt.Errorf("duplicate location name: %s", loc.Name)
+		}
+		seen[loc.Name] = struct{}{}
+		if loc.City == "" && (loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 ||
+			loc.Longitude > 180) {
+			return fmt.Errorf("invalid coordinates for location %s: %f, %f", loc.Name, loc.Latitude, loc.Longitude)
+		}
+	}
+	for _, fence := range c.Geofences {
+		if fence.Name == "" {
+			return fmt.Errorf("geofence name is required")
+		}
+		if fence.Latitude < -90 || fence.Latitude > 90 || fence.Longitude < -180 || fence.Longitude > 180 {
+			return fmt.Errorf("invalid coordinates for geofence %s: %f, %f", fence.Name, fence.Latitude,
+				fence.Longitude)
+		}
+		if fence.Radius <= 0 {
+			return fmt.Errorf("invalid radius for geofence %s: %g", fence.Name, fence.Radius)
+		}
+	}
+	for key := range c.Templates.ConditionIcons {
+		if _, _, err := parseConditionIconKey(key); err != nil {
+			return err
+		}
+	}
 	if c.Templates.UseCSSIcon {
 		if strings.EqualFold(c.Templates.Text, DefaultTextTpl) {
-			c.Templates.Text = ` {{hum .Current.Temperature}}{{.Current.Units.Temperature}}`
+			c.Templates.Text = ` {{.Current.TemperatureStr}}`
 		}
 		if strings.EqualFold(c.Templates.AltText, DefaultAltTextTpl) {
-			c.Templates.AltText = ` {{hum .Forecast.Temperature}}{{.Forecast.Units.Temperature}}`
+			c.Templates.AltText = ` {{.Forecast.TemperatureStr}}`
+		}
+		if strings.EqualFold(c.Templates.AltText, DefaultWorstAltTextTpl) {
+			c.Templates.AltText = ` {{.WorstForecast.TemperatureStr}}`
+		}
+	}
+
+	return nil
+}
+
+// resolveSecretFiles resolves the relative paths of the secret files against the directory of the config
+// file, so that they don't depend on the working directory the service is started in.
+func (c *Config) resolveSecretFiles(dir string) {
+	for _, file := range []*string{&c.Weather.APIKeyFile, &c.GeoCoder.APIKeyFile, &c.GeoLocation.GeoIPTokenFile} {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
 		}
 	}
+}
 
+// resolveSecrets resolves the API keys from their files or the environment variables they reference.
+func (c *Config) resolveSecrets() error {
+	var err error
+	if c.Weather.APIKey, err = resolveSecret("weather.apikey", c.Weather.APIKey, c.Weather.APIKeyFile); err != nil {
+		return err
+	}
+	if c.GeoCoder.APIKey, err = resolveSecret("geocoder.apikey", c.GeoCoder.APIKey,
+		c.GeoCoder.APIKeyFile); err != nil {
+		return err
+	}
+	if c.GeoLocation.GeoIPToken, err = resolveSecret("geolocation.geoip_token", c.GeoLocation.GeoIPToken,
+		c.GeoLocation.GeoIPTokenFile); err != nil {
+		return err
+	}
 	return nil
 }
+
+// resolveSecret returns the secret of a setting, which is either given as an explicit value, as a file whose
+// trimmed content is the secret, or as a value with ${NAME} references to environment variables. An explicit
+// value takes precedence over the file, which takes precedence over the environment variables.
+func resolveSecret(setting, value, file string) (string, error) {
+	if value != "" && !envReference.MatchString(value) {
+		return value, nil
+	}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s file: %w", setting, err)
+		}
+		secret := strings.TrimSpace(string(content))
+		if secret == "" {
+			return "", fmt.Errorf("%s file %s is empty", setting, file)
+		}
+		return secret, nil
+	}
+
+	var err error
+	secret := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s referenced by %s is not set", name, setting)
+		}
+		return env
+	})
+	return secret, err
+}
+
+// validatePrecision sets the unset display precisions to their defaults and checks that the configured
+// precisions are within range.
+func (c *Config) validatePrecision() error {
+	units := c.UnitPreferences()
+	pressure, precipitation := uint(DefaultPressurePrecision), uint(DefaultPrecipitationPrecision)
+	if units.Pressure == weather.UnitInHg {
+		pressure = DefaultInHgPrecision
+	}
+	if units.Precipitation == weather.UnitInch {
+		precipitation = DefaultInchPrecision
+	}
+	for _, prec := range []struct {
+		name     string
+		value    **uint
+		fallback uint
+	}{
+		{"temperature", &c.Output.TemperaturePrecision, DefaultTemperaturePrecision},
+		{"wind", &c.Output.WindPrecision, DefaultWindPrecision},
+		{"pressure", &c.Output.PressurePrecision, pressure},
+		{"precipitation", &c.Output.PrecipitationPrecision, precipitation},
+	} {
+		if *prec.value == nil {
+			*prec.value = &prec.fallback
+			continue
+		}
+		if **prec.value > maxPrecision {
+			return fmt.Errorf("invalid %s precision: %d", prec.name, **prec.value)
+		}
+	}
+	return nil
+}
+
+// validateSeverity sets the unset weights of the severity score to their defaults and checks that the
+// weights are finite and not negative, and that at least one of them is positive.
+func (c *Config) validateSeverity() error {
+	var total float64
+	for _, weight := range []struct {
+		name     string
+		value    **float64
+		fallback float64
+	}{
+		{"category", &c.Severity.CategoryWeight, DefaultSeverityCategoryWeight},
+		{"wind", &c.Severity.WindWeight, DefaultSeverityWindWeight},
+		{"precipitation", &c.Severity.PrecipitationWeight, DefaultSeverityPrecipitationWeight},
+		{"temperature", &c.Severity.TemperatureWeight, DefaultSeverityTemperatureWeight},
+	} {
+		if *weight.value == nil {
+			*weight.value = &weight.fallback
+		}
+		value := **weight.value
+		if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("invalid %s severity weight: %g", weight.name, value)
+		}
+		total += value
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one severity weight must be positive")
+	}
+	return nil
+}
+
+// validateTemperatures checks that the temperature settings are valid temperatures and sets the default
+// gradient, if no gradient is configured. The gradient stops must have valid hex colors and be in ascending
+// order in both temperature units, since stops without unit suffix are not converted.
+func (c *Config) validateTemperatures() error {
+	for setting, value := range map[string]string{
+		"cold threshold":  c.Weather.ColdThreshold,
+		"hot threshold":   c.Weather.HotThreshold,
+		"frost threshold": c.Weather.FrostThreshold,
+	} {
+		if _, err := units.ParseTemperature(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", setting, err)
+		}
+	}
+	delta, err := units.ParseTemperature(c.Weather.ApparentDelta)
+	if err != nil {
+		return fmt.Errorf("invalid apparent temperature delta: %w", err)
+	}
+	if delta.Value < 0 {
+		return fmt.Errorf("invalid apparent temperature delta: %s", c.Weather.ApparentDelta)
+	}
+
+	if len(c.Output.TempGradient) == 0 {
+		c.Output.TempGradient = slices.Clone(DefaultTempGradient)
+		return nil
+	}
+	for _, stop := range c.Output.TempGradient {
+		if _, err = ParseHexColor(stop.Color); err != nil {
+			return fmt.Errorf("invalid temperature gradient color: %w", err)
+		}
+		if _, err = units.ParseTemperature(stop.Temperature); err != nil {
+			return fmt.Errorf("invalid temperature gradient stop: %w", err)
+		}
+	}
+	for _, unit := range []string{units.Celsius, units.Fahrenheit} {
+		gradient := c.TemperaturesIn(unit).Gradient
+		for i := 1; i < len(gradient); i++ {
+			if gradient[i].Temperature <= gradient[i-1].Temperature {
+				return fmt.Errorf("temperature gradient stops must be in ascending order: %s after %s",
+					c.Output.TempGradient[i].Temperature, c.Output.TempGradient[i-1].Temperature)
+			}
+		}
+	}
+	return nil
+}
+
+// TemperaturesIn returns the temperature settings normalized into the given temperature unit. Settings
+// with an explicit unit suffix are converted, settings without suffix are already in the displayed unit.
+// The settings are expected to be validated, invalid settings are returned as zero.
+func (c *Config) TemperaturesIn(unit string) Temperatures {
+	temperature := func(value string) units.Temperature {
+		temp, _ := units.ParseTemperature(value)
+		return temp
+	}
+	temps := Temperatures{
+		ColdThreshold:  temperature(c.Weather.ColdThreshold).In(unit),
+		HotThreshold:   temperature(c.Weather.HotThreshold).In(unit),
+		FrostThreshold: temperature(c.Weather.FrostThreshold).In(unit),
+		ApparentDelta:  temperature(c.Weather.ApparentDelta).DeltaIn(unit),
+		Gradient:       make([]GradientStop, 0, len(c.Output.TempGradient)),
+	}
+	for _, stop := range c.Output.TempGradient {
+		temps.Gradient = append(temps.Gradient, GradientStop{
+			Temperature: temperature(stop.Temperature).In(unit),
+			Color:       stop.Color,
+		})
+	}
+	return temps
+}
+
+// ParseHexColor parses a hex color in the form "#rrggbb" into its red, green and blue components.
+func ParseHexColor(col string) ([3]uint8, error) {
+	var rgb [3]uint8
+	if len(col) != 7 || col[0] != '#' {
+		return rgb, fmt.Errorf("expected a hex color like #22c55e, got %q", col)
+	}
+	value, err := strconv.ParseUint(col[1:], 16, 24)
+	if err != nil {
+		return rgb, fmt.Errorf("expected a hex color like #22c55e, got %q", col)
+	}
+	rgb[0], rgb[1], rgb[2] = uint8(value>>16), uint8(value>>8), uint8(value)
+	return rgb, nil
+}
+
+// SignalToggleEnabled reports whether the USR1 signal toggles between the primary and the alternative view.
+// It is enabled unless output.signal_toggle is set to false.
+func (c *Config) SignalToggleEnabled() bool {
+	return c.Output.SignalToggle == nil || *c.Output.SignalToggle
+}
+
+// ConditionIcons returns the condition icons of the condition_icons setting in the form of the icon map of
+// the presenter: the icons are indexed by the weather code and whether it is day. A key without the
+// "_night" suffix replaces the day and the night icon, unless the night icon is set explicitly. Invalid
+// keys are skipped.
+func (c *Config) ConditionIcons() map[int]map[bool]string {
+	icons := make(map[int]map[bool]string, len(c.Templates.ConditionIcons))
+	for key, icon := range c.Templates.ConditionIcons {
+		code, night, err := parseConditionIconKey(key)
+		if err != nil {
+			continue
+		}
+		if icons[code] == nil {
+			icons[code] = make(map[bool]string, 2)
+		}
+		if night {
+			icons[code][false] = icon
+			continue
+		}
+		icons[code][true] = icon
+		if _, ok := c.Templates.ConditionIcons[key+conditionIconNightSuffix]; !ok {
+			icons[code][false] = icon
+		}
+	}
+	return icons
+}
+
+// parseConditionIconKey returns the weather code of a key of the condition_icons setting and whether it
+// only replaces the night icon.
+func parseConditionIconKey(key string) (int, bool, error) {
+	codeStr, night := strings.CutSuffix(key, conditionIconNightSuffix)
+	code, err := strconv.Atoi(codeStr)
+	if err != nil || code < 0 {
+		return 0, false, fmt.Errorf("invalid condition icon key: %s", key)
+	}
+	return code, night, nil
+}
+
+// UnitPreferences returns the units of the weather metrics. The per-metric overrides take precedence over
+// the defaults of the global units setting. If the units are selected automatically, the metric units
+// are returned.
+func (c *Config) UnitPreferences() weather.UnitPreferences {
+	return c.UnitPreferencesFor(c.Units)
+}
+
+// UnitPreferencesFor returns the units of the weather metrics in the given unit system. The per-metric
+// overrides take precedence over the defaults of the unit system.
+func (c *Config) UnitPreferencesFor(system string) weather.UnitPreferences {
+	units := weather.DefaultUnits(system)
+	if c.UnitOverrides.Temperature != "" {
+		units.Temperature = c.UnitOverrides.Temperature
+	}
+	if c.UnitOverrides.WindSpeed != "" {
+		units.WindSpeed = c.UnitOverrides.WindSpeed
+	}
+	if c.UnitOverrides.Pressure != "" {
+		units.Pressure = c.UnitOverrides.Pressure
+	}
+	if c.UnitOverrides.Precipitation != "" {
+		units.Precipitation = c.UnitOverrides.Precipitation
+	}
+	return units
+}
@@ -6,8 +6,16 @@ package config
 
 import (
 	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/wneessen/waybar-weather/internal/units"
+	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
 func TestNew(t *testing.T) {
@@ -52,8 +60,8 @@ func TestNew(t *testing.T) {
 		if !conf.Templates.UseCSSIcon {
 			t.Error("expected CSS icon mode to be enabled")
 		}
-		wantText := ` {{hum .Current.Temperature}}{{.Current.Units.Temperature}}`
-		wantAltText := ` {{hum .Forecast.Temperature}}{{.Forecast.Units.Temperature}}`
+		wantText := ` {{.Current.TemperatureStr}}`
+		wantAltText := ` {{.Forecast.TemperatureStr}}`
 		if conf.Templates.Text != wantText {
 			t.Errorf("failed to set text template in CSS icon mode: got: %q, want: %q", conf.Templates.Text,
 				wantText)
@@ -63,6 +71,67 @@ func TestNew(t *testing.T) {
 				conf.Templates.AltText, wantAltText)
 		}
 	})
+	t.Run("worst condition switches the default alternative text", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			worst   string
+			cssIcon string
+			want    string
+		}{
+			{"default", "false", "false", DefaultAltTextTpl},
+			{"worst condition", "true", "false", DefaultWorstAltTextTpl},
+			{"worst condition in CSS icon mode", "true", "true", ` {{.WorstForecast.TemperatureStr}}`},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_TEMPLATES_WORST_CONDITION", tc.worst)
+				t.Setenv("WAYBARWEATHER_TEMPLATES_USE_CSS_ICON", tc.cssIcon)
+				t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "")
+				conf, err := New()
+				if err != nil {
+					t.Fatalf("failed to load config: %s", err)
+				}
+				if conf.Templates.AltText != tc.want {
+					t.Errorf("expected alternative text template to be %q, got %q", tc.want,
+						conf.Templates.AltText)
+				}
+			})
+		}
+	})
+	t.Run("legacy default restores the previous default tooltip", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			legacy string
+			want   string
+		}{
+			{"default", "false", DefaultTooltipTpl},
+			{"legacy default", "true", DefaultLegacyTooltipTpl},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_TEMPLATES_LEGACY_DEFAULT", tc.legacy)
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "")
+				conf, err := New()
+				if err != nil {
+					t.Fatalf("failed to load config: %s", err)
+				}
+				if conf.Templates.Tooltip != tc.want {
+					t.Errorf("expected tooltip template to be %q, got %q", tc.want, conf.Templates.Tooltip)
+				}
+			})
+		}
+	})
+	t.Run("legacy default does not replace a configured tooltip", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_LEGACY_DEFAULT", "true")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "custom")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Templates.Tooltip != "custom" {
+			t.Errorf("expected tooltip template to be %q, got %q", "custom", conf.Templates.Tooltip)
+		}
+	})
 	t.Run("new config with invalid values from env", func(t *testing.T) {
 		t.Setenv("WAYBARWEATHER_LOGLEVEL", "invalid")
 		_, err := New()
@@ -70,6 +139,176 @@ func TestNew(t *testing.T) {
 			t.Error("expected config to fail, but didn't")
 		}
 	})
+	t.Run("config validate gust warning and forecast lookback", func(t *testing.T) {
+		for env, value := range map[string]string{
+			"WAYBARWEATHER_WEATHER_GUST_WARNING_THRESHOLD": "-1",
+			"WAYBARWEATHER_WEATHER_GUST_WARNING_WINDOW":    "-1h",
+			"WAYBARWEATHER_WEATHER_FORECAST_LOOKBACK":      "-1h",
+		} {
+			t.Run(env, func(t *testing.T) {
+				t.Setenv(env, value)
+				_, err := New()
+				if err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+			})
+		}
+	})
+	t.Run("unknown weather models are accepted with a warning", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_MODEL", "icon_seamless")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if warnings := conf.Warnings(); len(warnings) != 0 {
+			t.Errorf("expected no warnings for a known weather model, got %v", warnings)
+		}
+
+		t.Setenv("WAYBARWEATHER_WEATHER_MODEL", "future_model")
+		conf, err = New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		warnings := conf.Warnings()
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "future_model") {
+			t.Errorf("expected a warning for the unknown weather model, got %v", warnings)
+		}
+	})
+	t.Run("weather elevation is unset by default", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.Elevation != nil {
+			t.Errorf("expected elevation to be unset, got %f", *conf.Weather.Elevation)
+		}
+
+		t.Setenv("WAYBARWEATHER_WEATHER_ELEVATION", "0")
+		conf, err = New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.Elevation == nil || *conf.Weather.Elevation != 0 {
+			t.Errorf("expected elevation to be set to sea level, got %v", conf.Weather.Elevation)
+		}
+	})
+	t.Run("weather provider defaults to Open-Meteo", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if !slices.Equal(conf.Weather.Provider, []string{"open-meteo"}) {
+			t.Errorf("expected weather provider to be %q, got %q", []string{"open-meteo"}, conf.Weather.Provider)
+		}
+		if conf.Weather.ProbeInterval != time.Minute*30 {
+			t.Errorf("expected probe interval to be %s, got %s", time.Minute*30, conf.Weather.ProbeInterval)
+		}
+		if conf.Weather.FailoverThreshold != 1 {
+			t.Errorf("expected failover threshold to be %d, got %d", 1, conf.Weather.FailoverThreshold)
+		}
+	})
+	t.Run("weather provider list is normalized", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_PROVIDER", "Open-Meteo, wttr")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := []string{"open-meteo", "wttr"}
+		if !slices.Equal(conf.Weather.Provider, want) {
+			t.Errorf("expected weather providers to be %q, got %q", want, conf.Weather.Provider)
+		}
+	})
+	t.Run("config validate weather provider list", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_PROVIDER", "wttr,open-meteo,wttr")
+		if _, err := New(); err == nil {
+			t.Error("expected config with duplicate weather providers to fail, but didn't")
+		}
+		t.Setenv("WAYBARWEATHER_WEATHER_PROVIDER", "wttr")
+		t.Setenv("WAYBARWEATHER_WEATHER_PROBE_INTERVAL", "-1m")
+		if _, err := New(); err == nil {
+			t.Error("expected config with negative probe interval to fail, but didn't")
+		}
+	})
+	t.Run("notification categories default to precipitation and thunderstorms", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := []string{"rain", "snow", "thunderstorm"}
+		if conf.Notifications.Enabled || !slices.Equal(conf.Notifications.Categories, want) {
+			t.Errorf("expected disabled notifications for %q, got %t for %q", want, conf.Notifications.Enabled,
+				conf.Notifications.Categories)
+		}
+	})
+	t.Run("config validate notification categories", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_NOTIFICATIONS_CATEGORIES", "Rain, fog")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if want := []string{"rain", "fog"}; !slices.Equal(conf.Notifications.Categories, want) {
+			t.Errorf("expected notification categories to be %q, got %q", want, conf.Notifications.Categories)
+		}
+		t.Setenv("WAYBARWEATHER_NOTIFICATIONS_CATEGORIES", "rain,hail")
+		if _, err = New(); err == nil {
+			t.Error("expected config with unsupported notification category to fail, but didn't")
+		}
+	})
+	t.Run("config validate coordinate precision", func(t *testing.T) {
+		for value, wantFail := range map[string]bool{"0": false, "2": false, "6": false, "7": true} {
+			t.Run(value, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_GEOCODER_COORDINATE_PRECISION", value)
+				_, err := New()
+				if wantFail && err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+				if !wantFail && err != nil {
+					t.Errorf("failed to load config: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("privacy coordinate precision defaults to four decimal places", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Privacy.CoordinatePrecision != 4 {
+			t.Errorf("expected privacy coordinate precision to be 4, got %d", conf.Privacy.CoordinatePrecision)
+		}
+		t.Setenv("WAYBARWEATHER_PRIVACY_COORDINATE_PRECISION", "7")
+		if _, err = New(); err == nil {
+			t.Error("expected config with invalid privacy coordinate precision to fail, but didn't")
+		}
+	})
+	t.Run("config validate minimum render interval", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_MIN_RENDER", "-1s")
+		_, err := New()
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("config validate battery multiplier", func(t *testing.T) {
+		for value, wantFail := range map[string]bool{"0": false, "1": false, "3": false, "0.5": true, "-2": true} {
+			t.Run(value, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_INTERVALS_BATTERY_MULTIPLIER", value)
+				_, err := New()
+				if wantFail && err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+				if !wantFail && err != nil {
+					t.Errorf("failed to load config: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("config validate jitter", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_JITTER", "-1s")
+		_, err := New()
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
 	t.Run("config validate forecast hours", func(t *testing.T) {
 		t.Setenv("WAYBARWEATHER_WEATHER_FORECAST_HOURS", "-1")
 		_, err := New()
@@ -89,6 +328,329 @@ func TestNew(t *testing.T) {
 			t.Error("expected config to fail, but didn't")
 		}
 	})
+	t.Run("config validate unit overrides", func(t *testing.T) {
+		for _, env := range []string{
+			"WAYBARWEATHER_UNIT_OVERRIDES_TEMPERATURE", "WAYBARWEATHER_UNIT_OVERRIDES_WIND_SPEED",
+			"WAYBARWEATHER_UNIT_OVERRIDES_PRESSURE", "WAYBARWEATHER_UNIT_OVERRIDES_PRECIPITATION",
+		} {
+			t.Run(env, func(t *testing.T) {
+				t.Setenv(env, "kelvin")
+				_, err := New()
+				if err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+			})
+		}
+	})
+	t.Run("unit overrides take precedence over the global units", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_UNITS", "metric")
+		t.Setenv("WAYBARWEATHER_UNIT_OVERRIDES_WIND_SPEED", "MPH")
+		t.Setenv("WAYBARWEATHER_UNIT_OVERRIDES_PRESSURE", "inhg")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := weather.UnitPreferences{
+			Temperature:   weather.UnitCelsius,
+			WindSpeed:     weather.UnitMph,
+			Pressure:      weather.UnitInHg,
+			Precipitation: weather.UnitMillimeter,
+		}
+		if got := conf.UnitPreferences(); got != want {
+			t.Errorf("expected unit preferences to be %+v, got %+v", want, got)
+		}
+	})
+	t.Run("unit overrides take precedence over the automatic units", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_UNITS", "auto")
+		t.Setenv("WAYBARWEATHER_UNIT_OVERRIDES_TEMPERATURE", "celsius")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if got := conf.UnitPreferences(); got != weather.DefaultUnits(weather.UnitSystemMetric) {
+			t.Errorf("expected automatic units to default to metric, got %+v", got)
+		}
+		want := weather.UnitPreferences{
+			Temperature:   weather.UnitCelsius,
+			WindSpeed:     weather.UnitMph,
+			Pressure:      weather.UnitHPa,
+			Precipitation: weather.UnitInch,
+		}
+		if got := conf.UnitPreferencesFor(weather.UnitSystemImperial); got != want {
+			t.Errorf("expected unit preferences to be %+v, got %+v", want, got)
+		}
+	})
+	t.Run("output precision defaults depend on the units", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			env   map[string]string
+			wants [4]uint
+		}{
+			{"metric", map[string]string{"WAYBARWEATHER_UNITS": "metric"}, [4]uint{1, 0, 0, 1}},
+			{
+				"imperial with inHg", map[string]string{
+					"WAYBARWEATHER_UNITS":                   "imperial",
+					"WAYBARWEATHER_UNIT_OVERRIDES_PRESSURE": "inhg",
+				},
+				[4]uint{1, 0, 2, 2},
+			},
+			{
+				"explicit zero is kept", map[string]string{
+					"WAYBARWEATHER_UNITS":                        "metric",
+					"WAYBARWEATHER_OUTPUT_TEMPERATURE_PRECISION": "0",
+					"WAYBARWEATHER_OUTPUT_WIND_PRECISION":        "2",
+				},
+				[4]uint{0, 2, 0, 1},
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				for key, val := range tc.env {
+					t.Setenv(key, val)
+				}
+				conf, err := New()
+				if err != nil {
+					t.Fatalf("failed to load config: %s", err)
+				}
+				got := [4]uint{
+					*conf.Output.TemperaturePrecision, *conf.Output.WindPrecision,
+					*conf.Output.PressurePrecision, *conf.Output.PrecipitationPrecision,
+				}
+				if got != tc.wants {
+					t.Errorf("expected output precisions to be %v, got %v", tc.wants, got)
+				}
+			})
+		}
+	})
+	t.Run("signal toggle is enabled unless disabled explicitly", func(t *testing.T) {
+		for _, tc := range []struct {
+			value string
+			want  bool
+		}{{"", true}, {"true", true}, {"false", false}} {
+			t.Run(tc.value, func(t *testing.T) {
+				if tc.value != "" {
+					t.Setenv("WAYBARWEATHER_OUTPUT_SIGNAL_TOGGLE", tc.value)
+				}
+				conf, err := New()
+				if err != nil {
+					t.Fatalf("failed to load config: %s", err)
+				}
+				if got := conf.SignalToggleEnabled(); got != tc.want {
+					t.Errorf("expected signal toggle to be %t, got %t", tc.want, got)
+				}
+			})
+		}
+	})
+	t.Run("severity weights default unless set", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_SEVERITY_WIND_WEIGHT", "0")
+		t.Setenv("WAYBARWEATHER_SEVERITY_TEMPERATURE_WEIGHT", "2.5")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		got := [4]float64{
+			*conf.Severity.CategoryWeight, *conf.Severity.WindWeight,
+			*conf.Severity.PrecipitationWeight, *conf.Severity.TemperatureWeight,
+		}
+		want := [4]float64{DefaultSeverityCategoryWeight, 0, DefaultSeverityPrecipitationWeight, 2.5}
+		if got != want {
+			t.Errorf("expected severity weights to be %v, got %v", want, got)
+		}
+	})
+	t.Run("config validate severity weights", func(t *testing.T) {
+		tests := []struct {
+			name string
+			env  map[string]string
+		}{
+			{"negative weight", map[string]string{"WAYBARWEATHER_SEVERITY_WIND_WEIGHT": "-1"}},
+			{"infinite weight", map[string]string{"WAYBARWEATHER_SEVERITY_CATEGORY_WEIGHT": "+Inf"}},
+			{"NaN weight", map[string]string{"WAYBARWEATHER_SEVERITY_PRECIPITATION_WEIGHT": "NaN"}},
+			{
+				"all weights zero", map[string]string{
+					"WAYBARWEATHER_SEVERITY_CATEGORY_WEIGHT":      "0",
+					"WAYBARWEATHER_SEVERITY_WIND_WEIGHT":          "0",
+					"WAYBARWEATHER_SEVERITY_PRECIPITATION_WEIGHT": "0",
+					"WAYBARWEATHER_SEVERITY_TEMPERATURE_WEIGHT":   "0",
+				},
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				for key, val := range tc.env {
+					t.Setenv(key, val)
+				}
+				if _, err := New(); err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+			})
+		}
+	})
+	t.Run("config validate output precision", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_OUTPUT_PRESSURE_PRECISION", "7")
+		_, err := New()
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("temperature gradient defaults are converted into the unit", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if !slices.Equal(conf.Output.TempGradient, DefaultTempGradient) {
+			t.Errorf("expected temperature gradient to be %+v, got %+v", DefaultTempGradient, conf.Output.TempGradient)
+		}
+		for unit, want := range map[string][]float64{
+			units.Celsius:    {-10, 10, 30},
+			units.Fahrenheit: {14, 50, 86},
+		} {
+			gradient := conf.TemperaturesIn(unit).Gradient
+			if len(gradient) != len(want) {
+				t.Fatalf("expected %d gradient stops in %s, got %d", len(want), unit, len(gradient))
+			}
+			for i, stop := range gradient {
+				if math.Abs(stop.Temperature-want[i]) > 1e-9 || stop.Color != DefaultTempGradient[i].Color {
+					t.Errorf("expected gradient stop %d in %s to be %g, got %+v", i, unit, want[i], stop)
+				}
+			}
+		}
+	})
+	t.Run("temperature settings are normalized into the unit", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_COLD_THRESHOLD", "35.6F")
+		t.Setenv("WAYBARWEATHER_WEATHER_HOT_THRESHOLD", "25")
+		t.Setenv("WAYBARWEATHER_WEATHER_APPARENT_DELTA", "2C")
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		tests := []struct {
+			unit string
+			want Temperatures
+		}{
+			{units.Celsius, Temperatures{ColdThreshold: 2, HotThreshold: 25, FrostThreshold: 0, ApparentDelta: 2}},
+			{units.Fahrenheit, Temperatures{ColdThreshold: 35.6, HotThreshold: 25, FrostThreshold: 32, ApparentDelta: 3.6}},
+		}
+		for _, tc := range tests {
+			got := conf.TemperaturesIn(tc.unit)
+			for name, values := range map[string][2]float64{
+				"cold threshold":  {tc.want.ColdThreshold, got.ColdThreshold},
+				"hot threshold":   {tc.want.HotThreshold, got.HotThreshold},
+				"frost threshold": {tc.want.FrostThreshold, got.FrostThreshold},
+				"apparent delta":  {tc.want.ApparentDelta, got.ApparentDelta},
+			} {
+				if math.Abs(values[0]-values[1]) > 1e-9 {
+					t.Errorf("expected %s in %s to be %g, got %g", name, tc.unit, values[0], values[1])
+				}
+			}
+		}
+	})
+	t.Run("config validate temperature settings", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			setting string
+			value   string
+		}{
+			{"cold threshold", "WAYBARWEATHER_WEATHER_COLD_THRESHOLD", "cold"},
+			{"hot threshold", "WAYBARWEATHER_WEATHER_HOT_THRESHOLD", "30K"},
+			{"frost threshold", "WAYBARWEATHER_WEATHER_FROST_THRESHOLD", "NaN"},
+			{"apparent delta", "WAYBARWEATHER_WEATHER_APPARENT_DELTA", "2X"},
+			{"negative apparent delta", "WAYBARWEATHER_WEATHER_APPARENT_DELTA", "-1F"},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv(tc.setting, tc.value)
+				if _, err := New(); err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+			})
+		}
+	})
+	t.Run("config validate temperature gradient", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			stops    []ColorStop
+			wantFail bool
+		}{
+			{"single stop", []ColorStop{{Temperature: "0", Color: "#22c55e"}}, false},
+			{"uppercase hex", []ColorStop{{Temperature: "0", Color: "#22C55E"}, {Temperature: "1", Color: "#FFFFFF"}}, false},
+			{"unit suffixes", []ColorStop{{Temperature: "0C", Color: "#22c55e"}, {Temperature: "50F", Color: "#ef4444"}}, false},
+			{"color name", []ColorStop{{Temperature: "0", Color: "green"}}, true},
+			{"short hex", []ColorStop{{Temperature: "0", Color: "#2c5"}}, true},
+			{"invalid hex", []ColorStop{{Temperature: "0", Color: "#22c55g"}}, true},
+			{"invalid temperature", []ColorStop{{Temperature: "warm", Color: "#22c55e"}}, true},
+			{"duplicate stop", []ColorStop{{Temperature: "0", Color: "#22c55e"}, {Temperature: "0", Color: "#ef4444"}}, true},
+			{"same temperature in both units", []ColorStop{
+				{Temperature: "20C", Color: "#22c55e"},
+				{Temperature: "68F", Color: "#ef4444"},
+			}, true},
+			{"unordered in one of the units", []ColorStop{
+				{Temperature: "0C", Color: "#22c55e"},
+				{Temperature: "20", Color: "#ef4444"},
+			}, true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				conf, err := New()
+				if err != nil {
+					t.Fatalf("failed to load config: %s", err)
+				}
+				conf.Output.TempGradient = tc.stops
+				err = conf.Validate()
+				if tc.wantFail && err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+				if !tc.wantFail && err != nil {
+					t.Errorf("failed to validate config: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("config validate GeoIP backend", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.GeoLocation.GeoIPBackend != "reallyfreegeoip" {
+			t.Errorf("expected GeoIP backend to be %q, got %q", "reallyfreegeoip", conf.GeoLocation.GeoIPBackend)
+		}
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_GEOIP_BACKEND", " IPInfo ")
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_GEOIP_TOKEN", "${TEST_GEOIP_TOKEN}")
+		t.Setenv("TEST_GEOIP_TOKEN", "secret")
+		if conf, err = New(); err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.GeoLocation.GeoIPBackend != "ipinfo" || conf.GeoLocation.GeoIPToken != "secret" {
+			t.Errorf("expected GeoIP backend %q with token %q, got %q with %q", "ipinfo", "secret",
+				conf.GeoLocation.GeoIPBackend, conf.GeoLocation.GeoIPToken)
+		}
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_GEOIP_BACKEND", "freegeoip")
+		if _, err = New(); err == nil {
+			t.Error("expected config with unsupported GeoIP backend to fail, but didn't")
+		}
+	})
+	t.Run("config validate geolocation hysteresis", func(t *testing.T) {
+		conf, err := New()
+		if err != nil {
+			t.Fatalf("failed to create config: %s", err)
+		}
+		if conf.GeoLocation.GracePeriod != 10*time.Minute || conf.GeoLocation.ConfirmDistance != 20 {
+			t.Errorf("expected hysteresis defaults of 10m and 20 km, got %s and %g km",
+				conf.GeoLocation.GracePeriod, conf.GeoLocation.ConfirmDistance)
+		}
+		for _, env := range []string{
+			"WAYBARWEATHER_GEOLOCATION_GRACE_PERIOD=-1m", "WAYBARWEATHER_GEOLOCATION_CONFIRM_DISTANCE=-5",
+			"WAYBARWEATHER_GEOLOCATION_APPROX_THRESHOLD=-1", "WAYBARWEATHER_GEOLOCATION_MIN_ACCURACY_M=-1",
+			"WAYBARWEATHER_GEOLOCATION_MIN_ACCURACY_TIMEOUT=-1m",
+		} {
+			name, value, _ := strings.Cut(env, "=")
+			t.Run(name, func(t *testing.T) {
+				t.Setenv(name, value)
+				if _, err = New(); err == nil {
+					t.Error("expected config to fail, but didn't")
+				}
+			})
+		}
+	})
 }
 
 func TestNewFromFile(t *testing.T) {
@@ -134,4 +696,395 @@ func TestNewFromFile(t *testing.T) {
 			t.Error("expected config to fail, but didn't")
 		}
 	})
+	t.Run("reading config with additional locations succeeds", func(t *testing.T) {
+		conf, err := NewFromFile("../../testdata", "locations.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if len(conf.Locations) != 2 {
+			t.Fatalf("expected 2 locations, got %d", len(conf.Locations))
+		}
+		if conf.Locations[0].Name != "Berlin" || conf.Locations[0].City != "Berlin, Germany" {
+			t.Errorf("unexpected first location: %+v", conf.Locations[0])
+		}
+		if conf.Locations[1].Name != "Tokyo" || conf.Locations[1].Latitude != 35.6764 ||
+			conf.Locations[1].Longitude != 139.65 {
+			t.Errorf("unexpected second location: %+v", conf.Locations[1])
+		}
+	})
+	t.Run("reading config with temperature gradient succeeds", func(t *testing.T) {
+		conf, err := NewFromFile("../../testdata", "temp_gradient.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := []ColorStop{{Temperature: "0", Color: "#0000ff"}, {Temperature: "25", Color: "#ff0000"}}
+		if !slices.Equal(conf.Output.TempGradient, want) {
+			t.Errorf("expected temperature gradient to be %+v, got %+v", want, conf.Output.TempGradient)
+		}
+	})
+	t.Run("reading config with temperature unit suffixes succeeds", func(t *testing.T) {
+		conf, err := NewFromFile("../../testdata", "temp_units.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		temps := conf.TemperaturesIn(units.Celsius)
+		if math.Abs(temps.ColdThreshold-2) > 1e-9 || math.Abs(temps.ApparentDelta-2) > 1e-9 {
+			t.Errorf("expected cold threshold and apparent delta of 2°C, got %g and %g", temps.ColdThreshold,
+				temps.ApparentDelta)
+		}
+		// Values without unit suffix are in the displayed unit, whichever unit that is
+		if temps.HotThreshold != 86 {
+			t.Errorf("expected hot threshold of 86, got %g", temps.HotThreshold)
+		}
+		if len(temps.Gradient) != 2 || math.Abs(temps.Gradient[1].Temperature-30) > 1e-9 {
+			t.Errorf("expected the last gradient stop at 30°C, got %+v", temps.Gradient)
+		}
+	})
+	t.Run("reading config with unordered temperature gradient fails", func(t *testing.T) {
+		_, err := NewFromFile("../../testdata", "temp_gradient_unordered.toml")
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("reading config with API key references succeeds", func(t *testing.T) {
+		t.Setenv("TEST_GEOCODER_APIKEY", "geocoder-key-from-env")
+		conf, err := NewFromFile("../../testdata", "apikey_env.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.GeoCoder.APIKey != "geocoder-key-from-env" {
+			t.Errorf("expected geocoder API key from env, got %q", conf.GeoCoder.APIKey)
+		}
+		if conf.Weather.APIKey != "weather-key-from-file" {
+			t.Errorf("expected weather API key from file, got %q", conf.Weather.APIKey)
+		}
+	})
+	t.Run("relative API key file is resolved against the directory of the config file", func(t *testing.T) {
+		t.Setenv("TEST_GEOCODER_APIKEY", "geocoder-key-from-env")
+		conf, err := NewFromFile("../../testdata", "apikey_env.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := filepath.Join("../../testdata", "apikey.txt")
+		if conf.Weather.APIKeyFile != want {
+			t.Errorf("expected API key file to be %q, got %q", want, conf.Weather.APIKeyFile)
+		}
+	})
+	t.Run("reading config with duplicate location names fails", func(t *testing.T) {
+		_, err := NewFromFile("../../testdata", "locations_duplicate.toml")
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("reading config with geofences succeeds", func(t *testing.T) {
+		conf, err := NewFromFile("../../testdata", "geofences.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		want := []Geofence{
+			{Name: "Home", Latitude: 52.5163, Longitude: 13.3777, Radius: 150},
+			{Name: "Office", Latitude: 52.5076, Longitude: 13.3904, Radius: 500},
+		}
+		if !slices.Equal(conf.Geofences, want) {
+			t.Errorf("expected geofences to be %+v, got %+v", want, conf.Geofences)
+		}
+	})
+	t.Run("reading config with condition icons succeeds", func(t *testing.T) {
+		conf, err := NewFromFile("../../testdata", "condition_icons.toml")
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		icons := conf.ConditionIcons()
+		for _, tc := range []struct {
+			code  int
+			isDay bool
+			want  string
+		}{
+			{0, true, "🌞"},
+			{0, false, "🌛"},
+			{61, true, "☂️"},
+			{61, false, "☂️"},
+		} {
+			if got := icons[tc.code][tc.isDay]; got != tc.want {
+				t.Errorf("expected icon of code %d (day: %t) to be %q, got %q", tc.code, tc.isDay, tc.want, got)
+			}
+		}
+		if _, ok := icons[1]; ok {
+			t.Error("expected no icon for codes that are not configured")
+		}
+	})
+	t.Run("reading config with invalid condition icon key fails", func(t *testing.T) {
+		_, err := NewFromFile("../../testdata", "condition_icons_invalid.toml")
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("reading config with invalid geofence radius fails", func(t *testing.T) {
+		_, err := NewFromFile("../../testdata", "geofences_invalid.toml")
+		if err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+}
+
+func TestNewFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %s", err)
+		}
+		return file
+	}
+	system := writeFile(t, "system.toml", `units = "imperial"
+locale = "de"
+
+[weather]
+cold_threshold = 5
+hot_threshold = 25
+
+[intervals]
+output = "1m"
+
+[[locations]]
+name = "Home"
+city = "Berlin"
+
+[[locations]]
+name = "Work"
+city = "Cologne"
+`)
+
+	t.Run("user file overrides scalars of the system file", func(t *testing.T) {
+		user := writeFile(t, "user.toml", "units = \"metric\"\n")
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Units != "metric" {
+			t.Errorf("expected units of the user file, got %s", conf.Units)
+		}
+		if conf.Locale != "de" {
+			t.Errorf("expected locale of the system file, got %s", conf.Locale)
+		}
+	})
+	t.Run("user file overrides nested sections key by key", func(t *testing.T) {
+		user := writeFile(t, "user.toml", "[weather]\nhot_threshold = 35\n")
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.HotThreshold != "35" {
+			t.Errorf("expected hot threshold of the user file, got %s", conf.Weather.HotThreshold)
+		}
+		if conf.Weather.ColdThreshold != "5" {
+			t.Errorf("expected cold threshold of the system file, got %s", conf.Weather.ColdThreshold)
+		}
+		if conf.Intervals.Output != time.Minute {
+			t.Errorf("expected output interval of the system file, got %s", conf.Intervals.Output)
+		}
+		if conf.Intervals.WeatherUpdate != time.Minute*15 {
+			t.Errorf("expected default weather update interval, got %s", conf.Intervals.WeatherUpdate)
+		}
+	})
+	t.Run("user file replaces lists", func(t *testing.T) {
+		user := writeFile(t, "user.toml", "[[locations]]\nname = \"Holiday\"\ncity = \"Lisbon\"\n")
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if len(conf.Locations) != 1 || conf.Locations[0].Name != "Holiday" {
+			t.Errorf("expected locations of the user file, got %+v", conf.Locations)
+		}
+	})
+	t.Run("files of different formats are layered", func(t *testing.T) {
+		user := writeFile(t, "user.yaml", "weather:\n  cold_threshold: -5\n")
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.ColdThreshold != "-5" || conf.Weather.HotThreshold != "25" {
+			t.Errorf("expected cold threshold of the user file and hot threshold of the system file, got %s "+
+				"and %s", conf.Weather.ColdThreshold, conf.Weather.HotThreshold)
+		}
+	})
+	t.Run("environment overrides the layered files", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_HOT_THRESHOLD", "40")
+		user := writeFile(t, "user.json", `{"weather": {"hot_threshold": 35}}`)
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.HotThreshold != "40" {
+			t.Errorf("expected hot threshold of the environment, got %s", conf.Weather.HotThreshold)
+		}
+	})
+	t.Run("layered config is validated", func(t *testing.T) {
+		user := writeFile(t, "user.toml", "[geolocation]\nconfirm_distance = -5\n")
+		if _, err := NewFromFiles(system, user); err == nil {
+			t.Error("expected config to fail, but didn't")
+		}
+	})
+	t.Run("layered files are loaded without a temporary file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		t.Setenv("TMPDIR", tmpDir)
+		user := writeFile(t, "user.toml", "units = \"metric\"\n")
+		if _, err := NewFromFiles(system, user); err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		entries, err := os.ReadDir(tmpDir)
+		if err != nil {
+			t.Fatalf("failed to read temporary directory: %s", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected no temporary files, got %d", len(entries))
+		}
+	})
+	t.Run("relative API key file is resolved against the directory of its file", func(t *testing.T) {
+		keyDir := filepath.Join(dir, "keys")
+		if err := os.MkdirAll(keyDir, 0o700); err != nil {
+			t.Fatalf("failed to create key directory: %s", err)
+		}
+		if err := os.WriteFile(filepath.Join(keyDir, "weather.key"), []byte("key-from-file\n"), 0o600); err != nil {
+			t.Fatalf("failed to write key file: %s", err)
+		}
+		user := writeFile(t, "user.toml", "[weather]\nprovider = \"pirateweather\"\napikey_file = \"keys/weather.key\"\n")
+		conf, err := NewFromFiles(system, user)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Weather.APIKey != "key-from-file" {
+			t.Errorf("expected API key from the file next to the config file, got %q", conf.Weather.APIKey)
+		}
+	})
+	t.Run("invalid or missing file fails", func(t *testing.T) {
+		for _, file := range []string{
+			writeFile(t, "broken.toml", "units = "), writeFile(t, "config.ini", "units=metric"),
+			filepath.Join(dir, "non-existent.toml"),
+		} {
+			if _, err := NewFromFiles(system, file); err == nil {
+				t.Errorf("expected config with %s to fail, but didn't", filepath.Base(file))
+			}
+		}
+	})
+	t.Run("without files the default config is returned", func(t *testing.T) {
+		conf, err := NewFromFiles()
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Units != "metric" {
+			t.Errorf("expected default units, got %s", conf.Units)
+		}
+	})
+}
+
+func TestFindFiles(t *testing.T) {
+	home, xdgDir, otherDir := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CONFIG_DIRS", otherDir+":relative/dir:"+xdgDir)
+	createFile := func(t *testing.T, path string) string {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("failed to create config directory: %s", err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatalf("failed to write config file: %s", err)
+		}
+		return path
+	}
+
+	t.Run("no config files are found", func(t *testing.T) {
+		if files := FindFiles(); slices.ContainsFunc(files, func(file string) bool {
+			return strings.HasPrefix(file, home) || strings.HasPrefix(file, xdgDir)
+		}) {
+			t.Errorf("expected no config files, got %v", files)
+		}
+	})
+	userFile := createFile(t, filepath.Join(home, ".config", "waybar-weather", "config.yaml"))
+	t.Run("user config file is found in ~/.config", func(t *testing.T) {
+		if files := FindFiles(); len(files) == 0 || files[len(files)-1] != userFile {
+			t.Errorf("expected user config file %s, got %v", userFile, files)
+		}
+	})
+	systemFile := createFile(t, filepath.Join(xdgDir, "waybar-weather", "config.toml"))
+	t.Run("system config file comes before the user config file", func(t *testing.T) {
+		if files := FindFiles(); !slices.Equal(files, []string{systemFile, userFile}) {
+			t.Errorf("expected config files %v, got %v", []string{systemFile, userFile}, files)
+		}
+	})
+	t.Run("first entry of XDG_CONFIG_DIRS with a config file wins", func(t *testing.T) {
+		otherFile := createFile(t, filepath.Join(otherDir, "waybar-weather", "config.json"))
+		if files := FindFiles(); !slices.Equal(files, []string{otherFile, userFile}) {
+			t.Errorf("expected config files %v, got %v", []string{otherFile, userFile}, files)
+		}
+	})
+	t.Run("user config file is found in XDG_CONFIG_HOME", func(t *testing.T) {
+		xdgHome := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", xdgHome)
+		t.Setenv("XDG_CONFIG_DIRS", xdgDir)
+		xdgFile := createFile(t, filepath.Join(xdgHome, "waybar-weather", "config.toml"))
+		if files := FindFiles(); !slices.Equal(files, []string{systemFile, xdgFile}) {
+			t.Errorf("expected config files %v, got %v", []string{systemFile, xdgFile}, files)
+		}
+	})
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "apikey")
+	if err := os.WriteFile(keyFile, []byte("  key-from-file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write API key file: %s", err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("failed to write API key file: %s", err)
+	}
+	t.Setenv("TEST_APIKEY", "key-from-env")
+	t.Setenv("TEST_APIKEY_SUFFIX", "suffix")
+
+	tests := []struct {
+		name     string
+		value    string
+		file     string
+		want     string
+		wantFail bool
+	}{
+		{"no API key", "", "", "", false},
+		{"explicit value", "key", "", "key", false},
+		{"explicit value takes precedence over file", "key", keyFile, "key", false},
+		{"file", "", keyFile, "key-from-file", false},
+		{"file takes precedence over env", "${TEST_APIKEY}", keyFile, "key-from-file", false},
+		{"env", "${TEST_APIKEY}", "", "key-from-env", false},
+		{"env inside the value", "${TEST_APIKEY}-${TEST_APIKEY_SUFFIX}", "", "key-from-env-suffix", false},
+		{"missing file", "", filepath.Join(dir, "missing"), "", true},
+		{"empty file", "", emptyFile, "", true},
+		{"unset env", "${TEST_APIKEY_UNSET}", "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveSecret("geocoder.apikey", tc.value, tc.file)
+			if tc.wantFail && err == nil {
+				t.Fatal("expected resolving the API key to fail")
+			}
+			if !tc.wantFail && err != nil {
+				t.Fatalf("failed to resolve API key: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected API key to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+	t.Run("errors name the setting", func(t *testing.T) {
+		_, err := resolveSecret("geocoder.apikey", "${TEST_APIKEY_UNSET}", "")
+		if err == nil || !strings.Contains(err.Error(), "TEST_APIKEY_UNSET") ||
+			!strings.Contains(err.Error(), "geocoder.apikey") {
+			t.Errorf("expected error to name the variable and the setting, got %v", err)
+		}
+		t.Setenv("WAYBARWEATHER_GEOCODER_APIKEY_FILE", filepath.Join(dir, "missing"))
+		if _, err = New(); err == nil || !strings.Contains(err.Error(), "geocoder.apikey") {
+			t.Errorf("expected config with missing API key file to fail, got %v", err)
+		}
+	})
 }
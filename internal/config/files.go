@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kkyr/fig"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// configDir is the name of the directory of the config files within the config base directories
+	configDir = "waybar-weather"
+	// systemConfigDir is the system-wide config directory that is looked up after $XDG_CONFIG_DIRS
+	systemConfigDir = "/etc/waybar-weather"
+)
+
+// configExts are the extensions of the config files in the order they are looked up
+var configExts = []string{"toml", "yaml", "yml", "json"}
+
+// secretFileKeys are the sections and keys of the settings that hold the path of a secret file
+var secretFileKeys = [][2]string{{"weather", "apikey_file"}, {"geocoder", "apikey_file"},
+	{"geolocation", "geoip_token_file"}}
+
+// FindFiles returns the config files that are loaded if no config file is given. The system-wide config file
+// is the first one found in the waybar-weather directory of each entry of $XDG_CONFIG_DIRS (/etc/xdg if unset)
+// and in /etc/waybar-weather. The user's config file is looked up in the waybar-weather directory of
+// $XDG_CONFIG_HOME (~/.config if unset). If both exist, the system-wide file comes first, so that the user's
+// file overlays it in NewFromFiles.
+func FindFiles() []string {
+	var files []string
+	var systemDirs []string
+	for _, dir := range xdgConfigDirs() {
+		systemDirs = append(systemDirs, filepath.Join(dir, configDir))
+	}
+	if file := findFile(append(systemDirs, systemConfigDir)...); file != "" {
+		files = append(files, file)
+	}
+	if home := xdgConfigHome(); home != "" {
+		if file := findFile(filepath.Join(home, configDir)); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// NewFromFiles loads the config from the given files. Each file overlays the files before it: nested sections
+// are merged key by key, while scalars and lists replace the values of the files before. Without files, the
+// default config is returned.
+func NewFromFiles(files ...string) (*Config, error) {
+	switch len(files) {
+	case 0:
+		return New()
+	case 1:
+		return NewFromFile(filepath.Dir(files[0]), filepath.Base(files[0]))
+	}
+
+	merged := make(map[string]any)
+	for _, file := range files {
+		values, err := readFile(file)
+		if err != nil {
+			return new(Config), err
+		}
+		resolveSecretPaths(values, filepath.Dir(file))
+		mergeValues(merged, values)
+	}
+
+	conf := new(Config)
+	if err := decodeValues(merged, conf); err != nil {
+		return conf, fmt.Errorf("failed to load Config: %w", err)
+	}
+	// Environment variables and defaults are applied on top of the merged values, like fig does for a
+	// single config file
+	if err := fig.Load(conf, fig.IgnoreFile(), fig.UseEnv(configEnv)); err != nil {
+		return conf, fmt.Errorf("failed to load Config: %w", err)
+	}
+
+	return conf, conf.Validate()
+}
+
+// decodeValues decodes the merged values of the config files into the Config, with the same tag and
+// conversions that fig uses for the values of a config file.
+func decodeValues(values map[string]any, conf *Config) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+			mapstructure.StringToTimeHookFunc(time.RFC3339),
+		),
+		WeaklyTypedInput: true,
+		Result:           conf,
+		TagName:          "fig",
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(values)
+}
+
+// readFile decodes the config file into a map. The format is derived from the file extension.
+func readFile(file string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Config: %w", err)
+	}
+	values := make(map[string]any)
+	switch ext := strings.TrimPrefix(filepath.Ext(file), "."); ext {
+	case "toml":
+		err = toml.Unmarshal(data, &values)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &values)
+	case "json":
+		err = json.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported Config file extension: %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Config file %s: %w", file, err)
+	}
+	return values, nil
+}
+
+// resolveSecretPaths resolves the relative paths of the secret files in the values of a config file against
+// the directory of the file, before they are merged with the values of other files.
+func resolveSecretPaths(values map[string]any, dir string) {
+	for _, key := range secretFileKeys {
+		section, ok := values[key[0]].(map[string]any)
+		if !ok {
+			continue
+		}
+		if file, ok := section[key[1]].(string); ok && file != "" && !filepath.IsAbs(file) {
+			section[key[1]] = filepath.Join(dir, file)
+		}
+	}
+}
+
+// mergeValues overlays src onto dst. Nested maps are merged recursively, all other values are replaced.
+func mergeValues(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// findFile returns the first config file found in the given directories or an empty string.
+func findFile(dirs ...string) string {
+	for _, dir := range dirs {
+		for _, ext := range configExts {
+			file := filepath.Join(dir, "config."+ext)
+			if _, err := os.Stat(file); err == nil {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME or ~/.config if it is unset. Relative paths are invalid according to
+// the XDG Base Directory Specification and ignored.
+func xdgConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// xdgConfigDirs returns the absolute entries of $XDG_CONFIG_DIRS or /etc/xdg if it is unset.
+func xdgConfigDirs() []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("XDG_CONFIG_DIRS")) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = []string{"/etc/xdg"}
+	}
+	return dirs
+}
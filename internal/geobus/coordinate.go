@@ -25,26 +25,30 @@ type Coordinate struct {
 }
 
 // PosHasSignificantChange checks if the geographic position differs significantly from
-// another based on the distance threshold. We are using the Haversine formula to calculate
-// great-circle distance between two points on a sphere (in our case: Earth).
+// another based on the distance threshold.
 func (c Coordinate) PosHasSignificantChange(other Coordinate) bool {
 	// Higher accuracy always trumps the distance threshold.
 	if c.Acc < other.Acc && math.Abs(c.Acc-other.Acc) > AccuracyThreshold {
 		return true
 	}
 
+	return c.DistanceTo(other) > DistanceThreshold
+}
+
+// DistanceTo returns the distance to another coordinate in meters. We are using the Haversine formula
+// to calculate great-circle distance between two points on a sphere (in our case: Earth).
+func (c Coordinate) DistanceTo(other Coordinate) float64 {
 	dLat := (c.Lat - other.Lat) * math.Pi / 180
 	dLon := (c.Lon - other.Lon) * math.Pi / 180
 	lat1 := c.Lat * math.Pi / 180
 	lat2 := other.Lat * math.Pi / 180
 	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
 		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
-	distance := 2 * EarthRadius * math.Asin(math.Sqrt(h))
-
-	return distance > DistanceThreshold
+	return 2 * EarthRadius * math.Asin(math.Sqrt(h))
 }
 
-// Valid checks if the coordinate is valid according to the EPSG logic
+// Valid checks if the coordinate is valid according to the EPSG logic. Since any comparison with NaN
+// is false, NaN values are considered invalid as well as infinite values.
 func (c Coordinate) Valid() bool {
 	return c.Lat >= -90 && c.Lat <= 90 && c.Lon >= -180 && c.Lon <= 180
 }
@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/logger"
@@ -17,6 +18,12 @@ import (
 
 const (
 	accuracyEpsilon = 1e-6
+
+	// DefaultGracePeriod is the time an expired result is still protected against less accurate results
+	DefaultGracePeriod = 10 * time.Minute
+	// DefaultConfirmDistance is the distance in meters beyond which a move has to be confirmed by a
+	// second consistent result of the same source
+	DefaultConfirmDistance = 20000.0
 )
 
 const (
@@ -27,6 +34,9 @@ const (
 	AccuracyExact   = 5
 	AccuracyUnknown = 1000000
 	TruncPrecision  = 4
+
+	// truncRounding is the scale the value is rounded to before it is truncated
+	truncRounding = 1e6
 )
 
 // Provider defines an interface for geolocation service providers.
@@ -36,13 +46,54 @@ type Provider interface {
 	LookupStream(ctx context.Context, key string) <-chan Result
 }
 
+// PeriodScaler is implemented by providers whose poll period can be scaled after they have been created,
+// e. g. to poll less often while on battery power.
+type PeriodScaler interface {
+	ScalePeriod(factor float64)
+}
+
+// PeriodScale can be embedded into a Provider to implement PeriodScaler. The zero value leaves the poll
+// period unchanged. It is safe for concurrent use.
+type PeriodScale struct {
+	factor atomic.Uint64
+}
+
+// ScalePeriod sets the factor that the poll period is scaled with. A factor of 1 or less than or equal to
+// 0 restores the original poll period.
+func (s *PeriodScale) ScalePeriod(factor float64) {
+	if factor <= 0 {
+		factor = 1
+	}
+	s.factor.Store(math.Float64bits(factor))
+}
+
+// Scaled returns the given poll period scaled with the current factor.
+func (s *PeriodScale) Scaled(period time.Duration) time.Duration {
+	bits := s.factor.Load()
+	if bits == 0 {
+		return period
+	}
+	return time.Duration(float64(period) * math.Float64frombits(bits))
+}
+
 // GeoBus coordinates the publishing and subscribing of geolocation
 // results between providers and consumers.
 type GeoBus struct {
 	mu          sync.RWMutex
 	best        map[string]Result
+	pending     map[pendingKey]Result
 	subscribers map[string]map[chan Result]struct{}
+	all         map[chan Result]struct{}
 	log         *logger.Logger
+
+	gracePeriod     time.Duration
+	confirmDistance float64
+}
+
+// pendingKey identifies the unconfirmed move of a source for a key.
+type pendingKey struct {
+	key    string
+	source string
 }
 
 // Result represents a geolocation result with associated metadata.
@@ -63,12 +114,27 @@ func New(log *logger.Logger) (*GeoBus, error) {
 		return nil, fmt.Errorf("logger is required")
 	}
 	return &GeoBus{
-		best:        make(map[string]Result),
-		subscribers: make(map[string]map[chan Result]struct{}),
-		log:         log,
+		best:            make(map[string]Result),
+		pending:         make(map[pendingKey]Result),
+		subscribers:     make(map[string]map[chan Result]struct{}),
+		all:             make(map[chan Result]struct{}),
+		log:             log,
+		gracePeriod:     DefaultGracePeriod,
+		confirmDistance: DefaultConfirmDistance,
 	}, nil
 }
 
+// SetHysteresis configures the hysteresis that prevents the location from flapping between sources.
+// Once the best result has expired, less accurate results may only replace it after the grace period.
+// A move by more than the confirm distance (in meters) requires two consecutive consistent results of
+// the same source. A confirm distance of 0 disables the confirmation.
+func (b *GeoBus) SetHysteresis(gracePeriod time.Duration, confirmDistance float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gracePeriod = gracePeriod
+	b.confirmDistance = confirmDistance
+}
+
 // Subscribe adds a subscriber for updates associated with the given key and
 // buffer size, returning a result channel and an unsubscribe function.
 func (b *GeoBus) Subscribe(key string, size int) (<-chan Result, func()) {
@@ -82,62 +148,101 @@ func (b *GeoBus) Subscribe(key string, size int) (<-chan Result, func()) {
 
 	// Immediately send the current best if we have it and it’s not expired.
 	if best, ok := b.best[key]; ok && !best.IsExpired() {
-		ch <- best
+		broadcast(ch, best)
 	}
 	b.mu.Unlock()
 
+	// The channel is closed while holding the lock, so that Publish can never send on a closed
+	// channel. Calling unsub more than once is safe.
+	var once sync.Once
 	unsub := func() {
-		b.mu.Lock()
-		if subs, ok := b.subscribers[key]; ok {
-			delete(subs, ch)
-			if len(subs) == 0 {
-				delete(b.subscribers, key)
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if subs, ok := b.subscribers[key]; ok {
+				delete(subs, ch)
+				if len(subs) == 0 {
+					delete(b.subscribers, key)
+				}
 			}
-		}
-		b.mu.Unlock()
-		close(ch)
+			close(ch)
+		})
 	}
 
 	b.log.Debug("subscribed to geobus updates", slog.String("key", key))
 	return ch, unsub
 }
 
+// SubscribeAll adds a subscriber for updates of all keys with the given buffer size, returning a
+// result channel and an unsubscribe function. The Key field of the Result identifies the key the
+// update belongs to.
+func (b *GeoBus) SubscribeAll(size int) (<-chan Result, func()) {
+	ch := make(chan Result, size)
+
+	b.mu.Lock()
+	b.all[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsub := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.all, ch)
+			close(ch)
+		})
+	}
+
+	b.log.Debug("subscribed to geobus updates for all keys")
+	return ch, unsub
+}
+
 // Publish updates the best result for a key and notifies subscribers
 func (b *GeoBus) Publish(r Result) {
 	// Ignore zero-accuracy results; they’re meaningless.
 	if r.AccuracyMeters <= 0 {
 		return
 	}
+	// Drop results with out-of-range coordinates; they would result in nonsense API requests.
+	if !(Coordinate{Lat: r.Lat, Lon: r.Lon}).Valid() {
+		b.log.Warn("dropping geolocation result with invalid coordinates", slog.Float64("latitude", r.Lat),
+			slog.Float64("longitude", r.Lon), slog.String("source", r.Source))
+		return
+	}
 	// Ensure At is set.
 	if r.At.IsZero() {
 		r.At = time.Now()
 	}
 
-	newCoord := Coordinate{
-		Lat: r.Lat,
-		Lon: r.Lon,
-		Acc: r.AccuracyMeters,
-	}
-
 	b.mu.Lock()
-	shouldUpdate := false
-
-	prev, have := b.best[r.Key]
-	prevCoord := Coordinate{
-		Lat: prev.Lat,
-		Lon: prev.Lon,
-		Acc: prev.AccuracyMeters,
+	prev := b.best[r.Key]
+	shouldUpdate := shouldBroadcast(prev, r)
+	heartbeat := !shouldUpdate && isHeartbeat(prev, r)
+	confirmed := b.confirmMove(prev, r)
+	if shouldUpdate && b.inGracePeriod(prev, r) {
+		b.log.Debug("suppressed switch to less accurate geolocation source during grace period",
+			slog.String("source", r.Source), slog.String("best_source", prev.Source),
+			slog.Float64("accuracy", r.AccuracyMeters), slog.Float64("best_accuracy", prev.AccuracyMeters))
+		shouldUpdate = false
 	}
-
-	// If the result is not expired or better and the position has changed significantly, update it.
-	if !have || prev.IsExpired() || r.BetterThan(prev) && newCoord.PosHasSignificantChange(prevCoord) {
-		shouldUpdate = true
+	if shouldUpdate && !confirmed {
+		b.log.Debug("suppressed unconfirmed geolocation move, waiting for a consistent result",
+			slog.String("source", r.Source), slog.String("best_source", prev.Source),
+			slog.Float64("distance", r.coordinate().DistanceTo(prev.coordinate())))
+		shouldUpdate = false
 	}
 
 	b.log.Debug("received publish request", slog.Float64("latitude", r.Lat),
 		slog.Float64("longitude", r.Lon), slog.Float64("accuracy", r.AccuracyMeters),
 		slog.String("source", r.Source), slog.Bool("will_update", shouldUpdate),
+		slog.Bool("heartbeat", heartbeat),
 	)
+	if heartbeat {
+		// Adopt the TTL of the heartbeat, but keep the time of the current best, so that it still expires
+		// and is broadcast again, which lets the subscribers refresh the data of an unchanged position.
+		prev.TTL = r.TTL
+		b.best[r.Key] = prev
+	}
 	if !shouldUpdate {
 		b.mu.Unlock()
 		return
@@ -145,23 +250,97 @@ func (b *GeoBus) Publish(r Result) {
 
 	b.best[r.Key] = r
 
-	// Copy subscribers into a slice while we still hold the lock.
-	var subs []chan Result
-	if m, ok := b.subscribers[r.Key]; ok {
-		subs = make([]chan Result, 0, len(m))
-		for ch := range m {
-			subs = append(subs, ch)
-		}
+	// Non-blocking broadcast; slow subscribers just drop updates. The broadcast happens while
+	// holding the lock, so that an unsubscribe can't close a channel we are sending on.
+	for ch := range b.subscribers[r.Key] {
+		broadcast(ch, r)
+	}
+	for ch := range b.all {
+		broadcast(ch, r)
 	}
 	b.mu.Unlock()
+}
+
+// shouldBroadcast decides whether the new Result replaces the previous best Result for a key and is
+// broadcast to the subscribers. This is the case if:
+//   - there is no previous result yet
+//   - the previous result has expired
+//   - the new result has a strictly better accuracy, regardless of its position
+//   - the new result has the same accuracy, but its position changed significantly
+//
+// Results that are older than the previous result or less accurate are never broadcast.
+func shouldBroadcast(prev, next Result) bool {
+	if prev.Key == "" || prev.IsExpired() {
+		return true
+	}
+	if next.At.Before(prev.At) {
+		return false
+	}
+	if next.BetterThan(prev) {
+		return true
+	}
+	if !next.sameAccuracy(prev) {
+		return false
+	}
+	return next.coordinate().PosHasSignificantChange(prev.coordinate())
+}
+
+// isHeartbeat reports whether the new Result is a heartbeat of the previous best Result, which is a newer
+// result of the same source with the same accuracy at the same position. A heartbeat refreshes the TTL of
+// the previous best Result without being broadcast. Its time is kept, so that the previous best Result
+// still expires and the next result of the source is broadcast again.
+func isHeartbeat(prev, next Result) bool {
+	if prev.Key == "" || prev.Source != next.Source || next.At.Before(prev.At) {
+		return false
+	}
+	return next.sameAccuracy(prev) && !next.coordinate().PosHasSignificantChange(prev.coordinate())
+}
+
+// inGracePeriod reports whether the previous best Result has expired, but is still within the grace
+// period, in which it can't be replaced by a less accurate Result. The caller must hold the lock.
+func (b *GeoBus) inGracePeriod(prev, next Result) bool {
+	if prev.Key == "" || !prev.IsExpired() || time.Since(prev.At) > prev.TTL+b.gracePeriod {
+		return false
+	}
+	return next.AccuracyMeters > prev.AccuracyMeters+accuracyEpsilon
+}
+
+// confirmMove reports whether the new Result is allowed to move the position away from the previous best
+// Result. Moves by more than the confirm distance are kept as pending move of the source and are only
+// allowed if the pending move of the source was consistent with the new Result. The caller must hold the
+// lock.
+func (b *GeoBus) confirmMove(prev, next Result) bool {
+	id := pendingKey{key: next.Key, source: next.Source}
+	pending, hasPending := b.pending[id]
+	if prev.Key == "" || b.confirmDistance <= 0 ||
+		next.coordinate().DistanceTo(prev.coordinate()) <= b.confirmDistance {
+		delete(b.pending, id)
+		return true
+	}
+	b.pending[id] = next
+	return hasPending && next.coordinate().DistanceTo(pending.coordinate()) <= DistanceThreshold
+}
+
+// broadcast sends the Result to the channel without blocking.
+func broadcast(ch chan Result, r Result) {
+	select {
+	case ch <- r:
+	default:
+	}
+}
 
-	// Non-blocking broadcast; slow subscribers just drop updates.
-	for _, ch := range subs {
-		select {
-		case ch <- r:
-		default:
+// Invalidate removes the best result for the given key, so that the next published result is
+// broadcast to the subscribers regardless of its accuracy or position.
+func (b *GeoBus) Invalidate(key string) {
+	b.mu.Lock()
+	delete(b.best, key)
+	for id := range b.pending {
+		if id.key == key {
+			delete(b.pending, id)
 		}
 	}
+	b.mu.Unlock()
+	b.log.Debug("invalidated geobus result", slog.String("key", key))
 }
 
 // BetterThan compares two Result objects to determine if the current instance
@@ -188,35 +367,33 @@ func (r Result) BetterThan(prev Result) bool {
 	return false
 }
 
+// sameAccuracy reports whether the accuracy of both Result objects is the same within accuracyEpsilon.
+func (r Result) sameAccuracy(other Result) bool {
+	return math.Abs(r.AccuracyMeters-other.AccuracyMeters) <= accuracyEpsilon
+}
+
+// coordinate returns the position and accuracy of the Result as Coordinate.
+func (r Result) coordinate() Coordinate {
+	return Coordinate{Lat: r.Lat, Lon: r.Lon, Acc: r.AccuracyMeters}
+}
+
 // IsExpired checks if the Result has exceeded its time-to-live (TTL)
 // based on the current time and the timestamp.
 func (r Result) IsExpired() bool {
 	return r.TTL > 0 && time.Since(r.At) > r.TTL
 }
 
-// Truncate truncates a float to a fixed decimal precision.
+// Truncate truncates a float to a fixed decimal precision. The scaled value is rounded to a few more decimal
+// places first, so that a value that is already truncated doesn't lose its last digit to a floating point
+// error (e. g. 179.9996 * 10000 = 1799995.9999999998).
 func Truncate(x float64, precision int) float64 {
 	p := math.Pow(10, float64(precision))
-	return math.Trunc(x*p) / p
+	return math.Trunc(math.Round(x*p*truncRounding)/truncRounding) / p
 }
 
 // TrackProviders starts one goroutine per provider that streams results into the bus.
-// It returns immediately; goroutines exit when ctx is cancelled or the provider channel closes.
+// It returns immediately; goroutines exit when ctx is cancelled. Provider streams that close their
+// channel are restarted.
 func TrackProviders(ctx context.Context, bus *GeoBus, key string, providers ...Provider) {
-	for _, p := range providers {
-		go func() {
-			ch := p.LookupStream(ctx, key)
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case r, ok := <-ch:
-					if !ok {
-						return
-					}
-					bus.Publish(r)
-				}
-			}
-		}()
-	}
+	NewOrchestrator(bus, key, providers...).Start(ctx)
 }
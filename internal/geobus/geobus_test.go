@@ -5,11 +5,21 @@
 package geobus
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/logger"
@@ -182,6 +192,111 @@ func TestResult_BetterThan(t *testing.T) {
 	}
 }
 
+func TestShouldBroadcast(t *testing.T) {
+	now := time.Now()
+	prev := Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCity, Source: "geoip", At: now}
+	tests := []struct {
+		name      string
+		prev      Result
+		next      Result
+		broadcast bool
+		heartbeat bool
+	}{
+		{
+			name:      "first result",
+			prev:      Result{},
+			next:      Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCity, Source: "geoip", At: now},
+			broadcast: true,
+		},
+		{
+			name: "expired previous result",
+			prev: Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyExact, Source: "gpsd",
+				At: now.Add(-time.Hour), TTL: time.Minute},
+			next:      Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCity, Source: "geoip", At: now},
+			broadcast: true,
+		},
+		{
+			name:      "strictly better accuracy at the same position",
+			prev:      prev,
+			next:      Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyExact, Source: "gpsd", At: now},
+			broadcast: true,
+		},
+		{
+			name:      "strictly better accuracy at a different position",
+			prev:      prev,
+			next:      Result{Key: "test", Lat: 51.0, Lon: 9.0, AccuracyMeters: AccuracyExact, Source: "gpsd", At: now},
+			broadcast: true,
+		},
+		{
+			name:      "same accuracy with significant movement",
+			prev:      prev,
+			next:      Result{Key: "test", Lat: 51.0, Lon: 9.0, AccuracyMeters: AccuracyCity, Source: "geoip", At: now},
+			broadcast: true,
+		},
+		{
+			name: "same accuracy with significant movement from another source",
+			prev: prev,
+			next: Result{Key: "test", Lat: 51.0, Lon: 9.0, AccuracyMeters: AccuracyCity, Source: "geoapi",
+				At: now},
+			broadcast: true,
+		},
+		{
+			name: "same source heartbeat",
+			prev: prev,
+			next: Result{Key: "test", Lat: 50.0001, Lon: 8.0001, AccuracyMeters: AccuracyCity, Source: "geoip",
+				At: now.Add(time.Minute)},
+			heartbeat: true,
+		},
+		{
+			name: "same accuracy without movement from another source",
+			prev: prev,
+			next: Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCity, Source: "geoapi",
+				At: now.Add(time.Minute)},
+		},
+		{
+			name: "worse accuracy at the same position",
+			prev: prev,
+			next: Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCountry, Source: "geoip",
+				At: now.Add(time.Minute)},
+		},
+		{
+			name: "worse accuracy with significant movement",
+			prev: prev,
+			next: Result{Key: "test", Lat: 51.0, Lon: 9.0, AccuracyMeters: AccuracyCountry, Source: "geoip",
+				At: now.Add(time.Minute)},
+		},
+		{
+			name: "out-of-order result with better accuracy",
+			prev: prev,
+			next: Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyExact, Source: "gpsd",
+				At: now.Add(-time.Minute)},
+		},
+		{
+			name: "out-of-order result with significant movement",
+			prev: prev,
+			next: Result{Key: "test", Lat: 51.0, Lon: 9.0, AccuracyMeters: AccuracyCity, Source: "geoip",
+				At: now.Add(-time.Minute)},
+		},
+		{
+			name: "out-of-order result of the same source",
+			prev: prev,
+			next: Result{Key: "test", Lat: 50.0, Lon: 8.0, AccuracyMeters: AccuracyCity, Source: "geoip",
+				At: now.Add(-time.Minute)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shouldBroadcast(tc.prev, tc.next); got != tc.broadcast {
+				t.Errorf("expected shouldBroadcast to return %t, got %t", tc.broadcast, got)
+			}
+			if got := isHeartbeat(tc.prev, tc.next); got != tc.heartbeat {
+				t.Errorf("expected isHeartbeat to return %t, got %t", tc.heartbeat, got)
+			}
+		})
+	}
+}
+
 func TestResult_IsExpired(t *testing.T) {
 	result := Result{At: time.Now().Add(-time.Hour), TTL: time.Hour}
 	if !result.IsExpired() {
@@ -227,19 +342,102 @@ func TestGeoBus_Publish(t *testing.T) {
 			Source:         "mock-provider",
 		})
 		<-ch
-		bus.Publish(Result{
+
+		// A move beyond the confirm distance requires a second consistent result of the source
+		moved := Result{
 			Key:            subID,
 			Lat:            55.0001,
 			Lon:            9.0001,
 			AccuracyMeters: 20,
 			At:             time.Now(),
 			Source:         "mock-provider",
+		}
+		bus.Publish(moved)
+		select {
+		case r := <-ch:
+			t.Fatalf("expected unconfirmed movement to be suppressed, got: %f, %f", r.Lat, r.Lon)
+		case <-time.After(50 * time.Millisecond):
+		}
+		moved.At = time.Now()
+		bus.Publish(moved)
+		select {
+		case r := <-ch:
+			if r.Lat != 55.0001 || r.Lon != 9.0001 {
+				t.Errorf("expected update for significant movement, got: %f, %f", r.Lat, r.Lon)
+			}
+		case <-time.After(50 * time.Millisecond):
+			t.Fatal("expected update for significant movement")
+		}
+	})
+	t.Run("an insignificant change of the same source refreshes the TTL", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		ch, unsub := bus.Subscribe(subID, 1)
+		defer unsub()
+
+		first := time.Now().Add(-time.Minute)
+		bus.Publish(Result{
+			Key:            subID,
+			Lat:            50.0,
+			Lon:            8.0,
+			AccuracyMeters: 20,
+			At:             first,
+			TTL:            time.Minute * 5,
+			Source:         "mock-provider",
+		})
+		<-ch
+		bus.Publish(Result{
+			Key:            subID,
+			Lat:            50.0001,
+			Lon:            8.0001,
+			AccuracyMeters: 20,
+			At:             time.Now(),
+			TTL:            time.Minute * 10,
+			Source:         "mock-provider",
 		})
 		select {
 		case <-ch:
-			t.Fatalf("did not expect update for insignificant movement")
+			t.Fatal("did not expect update for a heartbeat")
 		case <-time.After(50 * time.Millisecond):
 		}
+
+		bus.mu.RLock()
+		best := bus.best[subID]
+		bus.mu.RUnlock()
+		if best.Lat != 50.0 || best.Lon != 8.0 {
+			t.Errorf("expected best position to be kept, got: %f, %f", best.Lat, best.Lon)
+		}
+		if !best.At.Equal(first) {
+			t.Errorf("expected best result time to be kept, got: %s", best.At)
+		}
+		if best.TTL != time.Minute*10 {
+			t.Errorf("expected best result TTL to be refreshed, got: %s", best.TTL)
+		}
+	})
+	t.Run("heartbeats are broadcast again once the best result expired", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		ch, unsub := bus.Subscribe(subID, 10)
+		defer unsub()
+
+		for range 10 {
+			bus.Publish(Result{
+				Key:            subID,
+				Lat:            50.0,
+				Lon:            8.0,
+				AccuracyMeters: 20,
+				TTL:            time.Millisecond * 100,
+				Source:         "mock-provider",
+			})
+			time.Sleep(time.Millisecond * 60)
+		}
+		if len(ch) < 3 {
+			t.Errorf("expected the unchanged position to be broadcast after each expiry, got %d broadcasts", len(ch))
+		}
 	})
 	t.Run("do not publish results without accuracy", func(t *testing.T) {
 		bus, err := New(logger.New(slog.LevelInfo))
@@ -271,6 +469,42 @@ func TestGeoBus_Publish(t *testing.T) {
 			}
 		}
 	})
+	t.Run("results with invalid coordinates are dropped", func(t *testing.T) {
+		tests := []struct {
+			name string
+			lat  float64
+			lon  float64
+		}{
+			{"latitude out of range", 740.2, 8.0},
+			{"longitude out of range", 50.0, -999},
+			{"NaN latitude", math.NaN(), 8.0},
+			{"infinite longitude", 50.0, math.Inf(1)},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				bus, err := New(logger.New(slog.LevelInfo))
+				if err != nil {
+					t.Fatalf("failed to create bus: %s", err)
+				}
+				ch, unsub := bus.Subscribe(subID, 1)
+				defer unsub()
+
+				bus.Publish(Result{
+					Key:            subID,
+					Lat:            tc.lat,
+					Lon:            tc.lon,
+					AccuracyMeters: 20,
+					At:             time.Now(),
+					Source:         "mock-provider",
+				})
+				select {
+				case r := <-ch:
+					t.Fatalf("did not expect update for invalid coordinates, got %+v", r)
+				case <-time.After(50 * time.Millisecond):
+				}
+			})
+		}
+	})
 	t.Run("no At time sets it to 'now'", func(t *testing.T) {
 		bus, err := New(logger.New(slog.LevelInfo))
 		if err != nil {
@@ -339,6 +573,552 @@ func TestTrackProviders(t *testing.T) {
 	}
 }
 
+func TestCoordinate_DistanceTo(t *testing.T) {
+	tests := []struct {
+		name  string
+		coord Coordinate
+		other Coordinate
+		want  float64
+		delta float64
+	}{
+		{"same point", Coordinate{Lat: 52.52, Lon: 13.405}, Coordinate{Lat: 52.52, Lon: 13.405}, 0, 0},
+		{"one degree of latitude", Coordinate{Lat: 0, Lon: 0}, Coordinate{Lat: 1, Lon: 0}, 111195, 1},
+		{"Berlin to Munich", Coordinate{Lat: 52.52, Lon: 13.405}, Coordinate{Lat: 48.1375, Lon: 11.575}, 504200, 500},
+		{"across the date line", Coordinate{Lat: 0, Lon: 179.5}, Coordinate{Lat: 0, Lon: -179.5}, 111195, 1},
+		{"antipodes", Coordinate{Lat: 0, Lon: 0}, Coordinate{Lat: 0, Lon: 180}, math.Pi * EarthRadius, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.coord.DistanceTo(tc.other)
+			if math.Abs(got-tc.want) > tc.delta {
+				t.Errorf("expected distance to be %.0f ± %.0f m, got %.0f m", tc.want, tc.delta, got)
+			}
+			if back := tc.other.DistanceTo(tc.coord); math.Abs(back-got) > 1e-6 {
+				t.Errorf("expected distance to be symmetric, got %f and %f", got, back)
+			}
+		})
+	}
+}
+
+func TestCoordinate_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		coord Coordinate
+		valid bool
+	}{
+		{"null island", Coordinate{Lat: 0, Lon: 0}, true},
+		{"north pole", Coordinate{Lat: 90, Lon: 0}, true},
+		{"south pole", Coordinate{Lat: -90, Lon: 0}, true},
+		{"date line east", Coordinate{Lat: 0, Lon: 180}, true},
+		{"date line west", Coordinate{Lat: 0, Lon: -180}, true},
+		{"extreme north east", Coordinate{Lat: 90, Lon: 180}, true},
+		{"extreme south west", Coordinate{Lat: -90, Lon: -180}, true},
+		{"beyond north pole", Coordinate{Lat: 90.000001, Lon: 0}, false},
+		{"beyond south pole", Coordinate{Lat: -90.000001, Lon: 0}, false},
+		{"beyond date line east", Coordinate{Lat: 0, Lon: 180.000001}, false},
+		{"beyond date line west", Coordinate{Lat: 0, Lon: -180.000001}, false},
+		{"corrupted values", Coordinate{Lat: 740.2, Lon: -999}, false},
+		{"NaN latitude", Coordinate{Lat: math.NaN(), Lon: 0}, false},
+		{"NaN longitude", Coordinate{Lat: 0, Lon: math.NaN()}, false},
+		{"positive infinite latitude", Coordinate{Lat: math.Inf(1), Lon: 0}, false},
+		{"negative infinite latitude", Coordinate{Lat: math.Inf(-1), Lon: 0}, false},
+		{"positive infinite longitude", Coordinate{Lat: 0, Lon: math.Inf(1)}, false},
+		{"negative infinite longitude", Coordinate{Lat: 0, Lon: math.Inf(-1)}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.coord.Valid(); got != tc.valid {
+				t.Errorf("expected coordinate %+v to be valid: %t, got %t", tc.coord, tc.valid, got)
+			}
+		})
+	}
+}
+
+func TestGeoBus_Subscribe(t *testing.T) {
+	t.Run("unsubscribing twice is safe", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		ch, unsub := bus.Subscribe(subID, 1)
+		unsub()
+		unsub()
+		if _, ok := <-ch; ok {
+			t.Fatal("expected channel to be closed")
+		}
+	})
+	t.Run("unbuffered subscriber does not block on the current best", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		bus.Publish(Result{Key: subID, Lat: 50.0, Lon: 8.0, AccuracyMeters: 20, At: time.Now()})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, unsub := bus.Subscribe(subID, 0)
+			unsub()
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("subscribe blocked on unbuffered channel")
+		}
+	})
+	t.Run("multiple subscribers with different keys", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		first, unsubFirst := bus.Subscribe("first", 1)
+		defer unsubFirst()
+		second, unsubSecond := bus.Subscribe("second", 5)
+		defer unsubSecond()
+
+		bus.Publish(Result{Key: "second", Lat: 50.0, Lon: 8.0, AccuracyMeters: 20, At: time.Now()})
+		select {
+		case r := <-second:
+			if r.Key != "second" {
+				t.Errorf("expected result for key %q, got %q", "second", r.Key)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected update for second subscriber")
+		}
+		select {
+		case r := <-first:
+			t.Fatalf("did not expect update for first subscriber, got %+v", r)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestGeoBus_SubscribeAll(t *testing.T) {
+	bus, err := New(logger.New(slog.LevelInfo))
+	if err != nil {
+		t.Fatalf("failed to create bus: %s", err)
+	}
+	ch, unsub := bus.SubscribeAll(2)
+
+	bus.Publish(Result{Key: "first", Lat: 50.0, Lon: 8.0, AccuracyMeters: 20, At: time.Now()})
+	bus.Publish(Result{Key: "second", Lat: 52.0, Lon: 13.0, AccuracyMeters: 20, At: time.Now()})
+	for _, want := range []string{"first", "second"} {
+		select {
+		case r := <-ch:
+			if r.Key != want {
+				t.Errorf("expected result for key %q, got %q", want, r.Key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected update for key %q", want)
+		}
+	}
+
+	unsub()
+	unsub()
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	bus.Publish(Result{Key: "third", Lat: 48.0, Lon: 11.0, AccuracyMeters: 20, At: time.Now()})
+}
+
+// TestGeoBus_concurrentPublishUnsubscribe is meant to be run with the race detector. It makes sure
+// that unsubscribing while results are published neither races nor panics with a send on a
+// closed channel.
+func TestGeoBus_concurrentPublishUnsubscribe(t *testing.T) {
+	bus, err := New(logger.New(slog.LevelInfo))
+	if err != nil {
+		t.Fatalf("failed to create bus: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for n := 0; ctx.Err() == nil; n++ {
+				// Invalidate, so that every result is broadcast
+				bus.Invalidate(subID)
+				bus.Publish(Result{
+					Key:            subID,
+					Lat:            float64(i),
+					Lon:            float64(n % 180),
+					AccuracyMeters: 20,
+					At:             time.Now(),
+				})
+			}
+		})
+	}
+	for range 4 {
+		wg.Go(func() {
+			for ctx.Err() == nil {
+				_, unsub := bus.Subscribe(subID, 1)
+				_, unsubAll := bus.SubscribeAll(1)
+				unsub()
+				unsubAll()
+			}
+		})
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+func TestGeoBus_Invalidate(t *testing.T) {
+	bus, err := New(logger.New(slog.LevelInfo))
+	if err != nil {
+		t.Fatalf("failed to create bus: %s", err)
+	}
+	ch, unsub := bus.Subscribe(subID, 1)
+	defer unsub()
+
+	r := Result{
+		Key:            subID,
+		Lat:            50.0,
+		Lon:            8.0,
+		AccuracyMeters: 20,
+		At:             time.Now(),
+		Source:         "mock-provider",
+	}
+	bus.Publish(r)
+	<-ch
+
+	bus.Publish(r)
+	select {
+	case <-ch:
+		t.Fatalf("did not expect update for unchanged result")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	bus.Invalidate(subID)
+	bus.Publish(r)
+	select {
+	case got := <-ch:
+		if got.Lat != r.Lat || got.Lon != r.Lon {
+			t.Fatalf("unexpected result: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected update after invalidating the best result")
+	}
+}
+
+func TestGeoBus_hysteresis(t *testing.T) {
+	home := [2]float64{50.0, 8.0}
+	hub := [2]float64{50.72, 8.0}
+	other := [2]float64{49.3, 8.0}
+
+	t.Run("flapping between an accurate and a coarse source", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			hysteresis bool
+			want       []string
+			wantLog    string
+		}{
+			{"with hysteresis", true, []string{"ichnaea", "ichnaea"}, "suppressed switch to less accurate"},
+			{"without hysteresis", false, []string{"ichnaea", "geoip", "ichnaea", "geoip"}, ""},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				synctest.Test(t, func(t *testing.T) {
+					ctx, cancel := context.WithCancel(t.Context())
+					defer cancel()
+
+					buf := &syncBuffer{}
+					bus, err := New(logger.NewLogger(slog.LevelDebug, buf, nil))
+					if err != nil {
+						t.Fatalf("failed to create bus: %s", err)
+					}
+					if !tc.hysteresis {
+						bus.SetHysteresis(0, 0)
+					}
+					sub, unsub := bus.Subscribe(subID, 10)
+					defer unsub()
+
+					// The WiFi scans come back empty between 5m and 20m and after 20m, so that the ICHNAEA fix
+					// expires at 15m and 30m, while GeoIP keeps reporting the ISP's hub 80 km away
+					ichnaea := &scriptedProvider{name: "ichnaea", acc: 30, ttl: 10 * time.Minute, script: []scriptedResult{
+						{0, home}, {5 * time.Minute, home}, {20 * time.Minute, home},
+					}}
+					geoip := &scriptedProvider{name: "geoip", acc: AccuracyCity, ttl: 30 * time.Minute,
+						script: []scriptedResult{{time.Minute, hub}, {16 * time.Minute, hub}, {31 * time.Minute, hub}},
+					}
+					NewOrchestrator(bus, subID, ichnaea, geoip).Start(ctx)
+					time.Sleep(time.Hour)
+					synctest.Wait()
+
+					var got []string
+					for len(sub) > 0 {
+						got = append(got, (<-sub).Source)
+					}
+					if !slices.Equal(got, tc.want) {
+						t.Errorf("expected broadcast sources %v, got %v", tc.want, got)
+					}
+					if tc.wantLog != "" && !strings.Contains(buf.String(), tc.wantLog) {
+						t.Errorf("expected log to contain %q, got %q", tc.wantLog, buf.String())
+					}
+				})
+			})
+		}
+	})
+	t.Run("less accurate results replace an expired result after the grace period", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			bus, err := New(logger.New(slog.LevelInfo))
+			if err != nil {
+				t.Fatalf("failed to create bus: %s", err)
+			}
+			sub, unsub := bus.Subscribe(subID, 10)
+			defer unsub()
+
+			ichnaea := &scriptedProvider{name: "ichnaea", acc: 30, ttl: 10 * time.Minute,
+				script: []scriptedResult{{0, home}},
+			}
+			geoip := &scriptedProvider{name: "geoip", acc: AccuracyCity, ttl: 30 * time.Minute,
+				script: []scriptedResult{{15 * time.Minute, hub}, {25 * time.Minute, hub}},
+			}
+			NewOrchestrator(bus, subID, ichnaea, geoip).Start(ctx)
+			time.Sleep(time.Hour)
+			synctest.Wait()
+
+			var got []Result
+			for len(sub) > 0 {
+				got = append(got, <-sub)
+			}
+			if len(got) != 2 {
+				t.Fatalf("expected 2 broadcasts, got %d", len(got))
+			}
+			if got[1].Source != "geoip" || got[1].Lat != hub[0] {
+				t.Errorf("expected GeoIP result after the grace period, got %+v", got[1])
+			}
+		})
+	})
+	t.Run("moves beyond the confirm distance require consistent results", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			buf := &syncBuffer{}
+			bus, err := New(logger.NewLogger(slog.LevelDebug, buf, nil))
+			if err != nil {
+				t.Fatalf("failed to create bus: %s", err)
+			}
+			sub, unsub := bus.Subscribe(subID, 10)
+			defer unsub()
+
+			geoip := &scriptedProvider{name: "geoip", acc: AccuracyCity, ttl: time.Hour, script: []scriptedResult{
+				{0, home}, {15 * time.Minute, hub}, {30 * time.Minute, other}, {45 * time.Minute, other},
+			}}
+			NewOrchestrator(bus, subID, geoip).Start(ctx)
+			time.Sleep(time.Hour)
+			synctest.Wait()
+
+			var got []Result
+			for len(sub) > 0 {
+				got = append(got, <-sub)
+			}
+			if len(got) != 2 {
+				t.Fatalf("expected 2 broadcasts, got %d: %+v", len(got), got)
+			}
+			if got[0].Lat != home[0] || got[1].Lat != other[0] {
+				t.Errorf("expected broadcasts for home and the confirmed move, got %+v", got)
+			}
+			if !strings.Contains(buf.String(), "suppressed unconfirmed geolocation move") {
+				t.Errorf("expected suppressed moves to be logged, got %q", buf.String())
+			}
+		})
+	})
+	t.Run("invalidate discards pending moves", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		now := time.Now()
+		bus.Publish(Result{Key: subID, Lat: home[0], Lon: home[1], AccuracyMeters: 20, At: now, Source: "geoip"})
+		bus.Publish(Result{Key: subID, Lat: hub[0], Lon: hub[1], AccuracyMeters: 20, At: now, Source: "geoip"})
+		if len(bus.pending) != 1 {
+			t.Fatalf("expected 1 pending move, got %d", len(bus.pending))
+		}
+		bus.Invalidate(subID)
+		if len(bus.pending) != 0 {
+			t.Errorf("expected no pending moves after invalidation, got %d", len(bus.pending))
+		}
+	})
+}
+
+func TestOrchestrator_Refresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	bus, err := New(logger.New(slog.LevelInfo))
+	if err != nil {
+		t.Fatalf("failed to create bus: %s", err)
+	}
+	sub, unsub := bus.Subscribe(subID, 1)
+	defer unsub()
+
+	rp := &restartingProvider{lat: 1, lon: 2}
+	orch := NewOrchestrator(bus, subID, rp)
+
+	t.Run("refresh before start is a no-op", func(t *testing.T) {
+		orch.Refresh()
+		if got := rp.calls.Load(); got != 0 {
+			t.Fatalf("expected no lookup before start, got %d", got)
+		}
+	})
+	t.Run("start performs a lookup", func(t *testing.T) {
+		orch.Start(ctx)
+		got := <-sub
+		if got.Lat != 1 || got.Lon != 2 {
+			t.Fatalf("unexpected result: %+v", got)
+		}
+	})
+	t.Run("refresh performs a new lookup", func(t *testing.T) {
+		rp.setPosition(3, 4)
+		bus.Invalidate(subID)
+		orch.Refresh()
+		got := <-sub
+		if got.Lat != 3 || got.Lon != 4 {
+			t.Fatalf("unexpected result: %+v", got)
+		}
+		if calls := rp.calls.Load(); calls != 2 {
+			t.Fatalf("expected 2 lookups, got %d", calls)
+		}
+	})
+	t.Run("refresh after cancel is a no-op", func(t *testing.T) {
+		cancel()
+		orch.Refresh()
+		if calls := rp.calls.Load(); calls != 2 {
+			t.Fatalf("expected 2 lookups, got %d", calls)
+		}
+	})
+}
+
+func TestOrchestrator_supervise(t *testing.T) {
+	t.Run("closed streams are restarted with backoff", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			bus, err := New(logger.New(slog.LevelInfo))
+			if err != nil {
+				t.Fatalf("failed to create bus: %s", err)
+			}
+			cp := &closingProvider{}
+			orch := NewOrchestrator(bus, subID, cp)
+			orch.Start(ctx)
+
+			synctest.Wait()
+			if calls := cp.calls.Load(); calls != 1 {
+				t.Fatalf("expected 1 lookup, got %d", calls)
+			}
+			status, ok := orch.Health()[cp.Name()]
+			if !ok {
+				t.Fatal("expected health status for provider")
+			}
+			if status.Restarts != 1 {
+				t.Errorf("expected 1 restart, got %d", status.Restarts)
+			}
+			if !errors.Is(status.LastError, ErrStreamClosed) {
+				t.Errorf("expected last error to be %s, got %s", ErrStreamClosed, status.LastError)
+			}
+
+			time.Sleep(restartMinBackoff)
+			synctest.Wait()
+			if calls := cp.calls.Load(); calls != 2 {
+				t.Fatalf("expected 2 lookups after first backoff, got %d", calls)
+			}
+
+			// The backoff doubled, so the stream must not be restarted after the initial backoff.
+			time.Sleep(restartMinBackoff)
+			synctest.Wait()
+			if calls := cp.calls.Load(); calls != 2 {
+				t.Fatalf("expected 2 lookups before second backoff expired, got %d", calls)
+			}
+			time.Sleep(restartMinBackoff)
+			synctest.Wait()
+			if calls := cp.calls.Load(); calls != 3 {
+				t.Fatalf("expected 3 lookups after second backoff, got %d", calls)
+			}
+			if restarts := orch.Health()[cp.Name()].Restarts; restarts != 3 {
+				t.Errorf("expected 3 restarts, got %d", restarts)
+			}
+
+			cancel()
+			synctest.Wait()
+			time.Sleep(restartMaxBackoff)
+			synctest.Wait()
+			if calls := cp.calls.Load(); calls != 3 {
+				t.Fatalf("expected no lookups after cancel, got %d", calls)
+			}
+		})
+	})
+	t.Run("results reset the backoff and are recorded", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			bus, err := New(logger.New(slog.LevelInfo))
+			if err != nil {
+				t.Fatalf("failed to create bus: %s", err)
+			}
+			sub, unsub := bus.Subscribe(subID, 1)
+			defer unsub()
+
+			cp := &closingProvider{deliver: true}
+			orch := NewOrchestrator(bus, subID, cp)
+			orch.Start(ctx)
+
+			got := <-sub
+			if got.Lat != 50.0 || got.Lon != 8.0 {
+				t.Fatalf("unexpected result: %+v", got)
+			}
+			synctest.Wait()
+			status := orch.Health()[cp.Name()]
+			if !status.LastResult.Equal(got.At) {
+				t.Errorf("expected last result time to be %s, got %s", got.At, status.LastResult)
+			}
+
+			for i := int32(2); i <= 4; i++ {
+				time.Sleep(restartMinBackoff)
+				synctest.Wait()
+				if calls := cp.calls.Load(); calls != i {
+					t.Fatalf("expected %d lookups, got %d", i, calls)
+				}
+			}
+		})
+	})
+	t.Run("health of an unstarted orchestrator is empty", func(t *testing.T) {
+		bus, err := New(logger.New(slog.LevelInfo))
+		if err != nil {
+			t.Fatalf("failed to create bus: %s", err)
+		}
+		orch := NewOrchestrator(bus, subID, &closingProvider{})
+		if health := orch.Health(); len(health) != 0 {
+			t.Errorf("expected empty health, got %+v", health)
+		}
+	})
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		current time.Duration
+		want    time.Duration
+	}{
+		{restartMinBackoff, restartMinBackoff * 2},
+		{time.Minute, time.Minute * 2},
+		{restartMaxBackoff / 2, restartMaxBackoff},
+		{restartMaxBackoff - time.Second, restartMaxBackoff},
+		{restartMaxBackoff, restartMaxBackoff},
+	}
+	for _, tc := range tests {
+		t.Run(tc.current.String(), func(t *testing.T) {
+			if got := nextBackoff(tc.current); got != tc.want {
+				t.Errorf("expected backoff of %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	in := "123.456789"
 	for i := 5; i >= 1; i-- {
@@ -355,6 +1135,21 @@ func TestTruncate(t *testing.T) {
 			}
 		})
 	}
+	t.Run("truncating a truncated value keeps it", func(t *testing.T) {
+		for _, num := range []float64{179.9996, -179.9979, 52.5126, -33.8688} {
+			if got := Truncate(num, 4); got != num {
+				t.Errorf("expected %f, got %f", num, got)
+			}
+		}
+	})
+	t.Run("truncate coordinates down to city level", func(t *testing.T) {
+		if got := Truncate(52.51269, 2); got != 52.51 {
+			t.Errorf("expected %f, got %f", 52.51, got)
+		}
+		if got := Truncate(-13.38999, 2); got != -13.38 {
+			t.Errorf("expected %f, got %f", -13.38, got)
+		}
+	})
 }
 
 type fakeProvider struct {
@@ -367,3 +1162,193 @@ func (f *fakeProvider) Name() string { return f.name }
 func (f *fakeProvider) LookupStream(context.Context, string) <-chan Result {
 	return f.ch
 }
+
+// restartingProvider mimics a real provider which performs a lookup every time its stream is started
+type restartingProvider struct {
+	calls atomic.Int32
+	mu    sync.Mutex
+	lat   float64
+	lon   float64
+}
+
+func (r *restartingProvider) Name() string { return "restarting" }
+
+func (r *restartingProvider) setPosition(lat, lon float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lat, r.lon = lat, lon
+}
+
+func (r *restartingProvider) LookupStream(ctx context.Context, key string) <-chan Result {
+	r.calls.Add(1)
+	r.mu.Lock()
+	result := Result{
+		Key:            key,
+		Lat:            r.lat,
+		Lon:            r.lon,
+		AccuracyMeters: 10,
+		At:             time.Now(),
+		Source:         r.Name(),
+	}
+	r.mu.Unlock()
+
+	ch := make(chan Result, 1)
+	ch <- result
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+// closingProvider closes its lookup stream right away, optionally after delivering a single result.
+type closingProvider struct {
+	calls   atomic.Int32
+	deliver bool
+}
+
+func (c *closingProvider) Name() string {
+	return "closing"
+}
+
+func (c *closingProvider) LookupStream(_ context.Context, key string) <-chan Result {
+	c.calls.Add(1)
+	ch := make(chan Result, 1)
+	if c.deliver {
+		ch <- Result{
+			Key:            key,
+			Lat:            50.0,
+			Lon:            8.0,
+			AccuracyMeters: 10,
+			At:             time.Now(),
+			Source:         c.Name(),
+		}
+	}
+	close(ch)
+	return ch
+}
+
+// scriptedProvider delivers its scripted results at the given offsets from the start of its stream
+type scriptedProvider struct {
+	name   string
+	acc    float64
+	ttl    time.Duration
+	script []scriptedResult
+}
+
+type scriptedResult struct {
+	after  time.Duration
+	latLon [2]float64
+}
+
+func (s *scriptedProvider) Name() string { return s.name }
+
+func (s *scriptedProvider) LookupStream(ctx context.Context, key string) <-chan Result {
+	ch := make(chan Result)
+	go func() {
+		defer close(ch)
+		start := time.Now()
+		for _, step := range s.script {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(start.Add(step.after))):
+			}
+			result := Result{
+				Key:            key,
+				Lat:            step.latLon[0],
+				Lon:            step.latLon[1],
+				AccuracyMeters: s.acc,
+				At:             time.Now(),
+				TTL:            s.ttl,
+				Source:         s.name,
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- result:
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch
+}
+
+// syncBuffer is a bytes.Buffer that can be written concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestPeriodScale(t *testing.T) {
+	var scale PeriodScale
+	if got := scale.Scaled(time.Minute * 15); got != time.Minute*15 {
+		t.Errorf("expected zero value to leave the period unchanged, got %s", got)
+	}
+	scale.ScalePeriod(3)
+	if got := scale.Scaled(time.Minute * 15); got != time.Minute*45 {
+		t.Errorf("expected scaled period to be %s, got %s", time.Minute*45, got)
+	}
+	scale.ScalePeriod(0)
+	if got := scale.Scaled(time.Minute * 15); got != time.Minute*15 {
+		t.Errorf("expected period to be restored, got %s", got)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	t.Run("changes within the debounce interval are reported once", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "geolocation")
+		changed, err := WatchFile(t.Context(), path, time.Millisecond*100)
+		if err != nil {
+			t.Fatalf("failed to watch file: %s", err)
+		}
+		for i := range 3 {
+			if err = os.WriteFile(path, []byte(strconv.Itoa(i)), 0o600); err != nil {
+				t.Fatalf("failed to write file: %s", err)
+			}
+		}
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the change to be reported")
+		}
+		select {
+		case <-changed:
+			t.Error("expected the changes to be reported once")
+		case <-time.After(time.Millisecond * 300):
+		}
+	})
+	t.Run("changes of other files are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		changed, err := WatchFile(t.Context(), filepath.Join(dir, "geolocation"), time.Millisecond*10)
+		if err != nil {
+			t.Fatalf("failed to watch file: %s", err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, "cityname"), []byte("Berlin"), 0o600); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+		select {
+		case <-changed:
+			t.Error("expected the change of another file to be ignored")
+		case <-time.After(time.Millisecond * 200):
+		}
+	})
+	t.Run("missing directory fails", func(t *testing.T) {
+		if _, err := WatchFile(t.Context(), filepath.Join(t.TempDir(), "missing", "geolocation"),
+			time.Millisecond); err == nil {
+			t.Error("expected watching a file in a missing directory to fail")
+		}
+	})
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geobus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// restartMinBackoff is the initial delay before a provider stream that exited is restarted
+	restartMinBackoff = time.Second
+	// restartMaxBackoff is the maximum delay before a provider stream that exited is restarted
+	restartMaxBackoff = 5 * time.Minute
+)
+
+// ErrStreamClosed is recorded in the ProviderStatus if a provider closed its lookup stream while the
+// Orchestrator was still running.
+var ErrStreamClosed = errors.New("provider closed its lookup stream unexpectedly")
+
+// ProviderStatus describes the health of a provider tracked by the Orchestrator.
+type ProviderStatus struct {
+	LastResult time.Time
+	LastError  error
+	Restarts   int
+}
+
+// Orchestrator streams the results of a set of providers into the GeoBus and allows to restart
+// the provider lookups on demand.
+type Orchestrator struct {
+	bus       *GeoBus
+	key       string
+	providers []Provider
+
+	mu     sync.Mutex
+	parent context.Context
+	cancel context.CancelFunc
+
+	healthLock sync.RWMutex
+	health     map[string]ProviderStatus
+}
+
+// NewOrchestrator returns a new Orchestrator that publishes the results of the given providers
+// for the given key into the bus.
+func NewOrchestrator(bus *GeoBus, key string, providers ...Provider) *Orchestrator {
+	return &Orchestrator{
+		bus:       bus,
+		key:       key,
+		providers: providers,
+		health:    make(map[string]ProviderStatus),
+	}
+}
+
+// Start starts one goroutine per provider that streams results into the bus. It returns immediately;
+// goroutines exit when ctx is cancelled. If a provider closes its channel, its stream is restarted with
+// an increasing backoff. Calling Start again stops the streams of the previous call.
+func (o *Orchestrator) Start(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		o.cancel()
+	}
+	o.parent = ctx
+	o.start()
+}
+
+// Refresh restarts the lookup streams of all providers. Since every provider performs a lookup as
+// soon as its stream is started, this causes an immediate lookup instead of waiting for the next
+// poll period. Refresh is a no-op if the Orchestrator has not been started.
+func (o *Orchestrator) Refresh() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.parent == nil || o.parent.Err() != nil {
+		return
+	}
+	o.cancel()
+	o.start()
+}
+
+// Health returns a snapshot of the status of all providers that have been started by the Orchestrator,
+// keyed by the provider name.
+func (o *Orchestrator) Health() map[string]ProviderStatus {
+	o.healthLock.RLock()
+	defer o.healthLock.RUnlock()
+	health := make(map[string]ProviderStatus, len(o.health))
+	for name, status := range o.health {
+		health[name] = status
+	}
+	return health
+}
+
+// start starts the provider streams with a new cancelable child context of the parent context.
+// The caller must hold the lock.
+func (o *Orchestrator) start() {
+	ctx, cancel := context.WithCancel(o.parent)
+	o.cancel = cancel
+	for _, p := range o.providers {
+		o.updateHealth(p.Name(), func(*ProviderStatus) {})
+		go o.supervise(ctx, p)
+	}
+}
+
+// supervise streams the results of the provider into the bus until ctx is cancelled. If the provider
+// closes its channel, the stream is restarted after a backoff, which is reset once the provider
+// delivers a result again.
+func (o *Orchestrator) supervise(ctx context.Context, p Provider) {
+	backoff := restartMinBackoff
+	for {
+		if o.stream(ctx, p) {
+			backoff = restartMinBackoff
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		o.updateHealth(p.Name(), func(status *ProviderStatus) {
+			status.LastError = ErrStreamClosed
+			status.Restarts++
+		})
+		o.bus.log.Warn("geolocation provider stream closed unexpectedly, restarting",
+			slog.String("provider", p.Name()), slog.Duration("restart_in", backoff))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// stream publishes the results of a single lookup stream of the provider into the bus until ctx is
+// cancelled or the provider closes its channel. It reports whether the provider delivered any result.
+func (o *Orchestrator) stream(ctx context.Context, p Provider) bool {
+	delivered := false
+	ch := p.LookupStream(ctx, o.key)
+	for {
+		select {
+		case <-ctx.Done():
+			return delivered
+		case r, ok := <-ch:
+			if !ok {
+				return delivered
+			}
+			delivered = true
+			o.updateHealth(p.Name(), func(status *ProviderStatus) {
+				status.LastResult = r.At
+				if status.LastResult.IsZero() {
+					status.LastResult = time.Now()
+				}
+			})
+			o.bus.Publish(r)
+		}
+	}
+}
+
+// updateHealth applies fn to the status of the named provider.
+func (o *Orchestrator) updateHealth(name string, fn func(*ProviderStatus)) {
+	o.healthLock.Lock()
+	defer o.healthLock.Unlock()
+	status := o.health[name]
+	fn(&status)
+	o.health[name] = status
+}
+
+// nextBackoff doubles the given backoff, capped at restartMaxBackoff.
+func nextBackoff(current time.Duration) time.Duration {
+	return min(current*2, restartMaxBackoff)
+}
+
+// LogValue implements slog.LogValuer for the ProviderStatus.
+func (s ProviderStatus) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Time("last_result", s.LastResult),
+		slog.Int("restarts", s.Restarts),
+	}
+	if s.LastError != nil {
+		attrs = append(attrs, logger.Err(s.LastError))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -25,16 +25,21 @@ const (
 var ErrNoCoordinates = fmt.Errorf("no valid city name found in cityname file")
 
 // CitynameFileProvider reads city data from a file and emits updates via a stream.
-// It periodically reads a specified file, parses its data, and updates geolocation results based on changes.
+// It reads a specified file whenever it changes and periodically, parses its data, and updates geolocation
+// results based on changes.
 // Each result includes details about the location, accuracy, confidence, and timestamp of the data.
 // Results are subject to a time-to-live (TTL) duration, ensuring outdated data is discarded.
 type CitynameFileProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	path     string
 	period   time.Duration
 	ttl      time.Duration
+	debounce time.Duration
 	coder    geocode.Geocoder
-	locateFn func() (geobus.Coordinate, error)
+	locateFn func(context.Context) (geobus.Coordinate, error)
+	watchFn  func(context.Context) (<-chan struct{}, error)
 }
 
 // NewCitynameFileProvider initializes a CitynameFileProvider with a file path and default update
@@ -44,13 +49,15 @@ func NewCitynameFileProvider(path string, coder geocode.Geocoder) (*CitynameFile
 		return nil, errors.New("geocoder is required")
 	}
 	provider := &CitynameFileProvider{
-		coder:  coder,
-		name:   name,
-		path:   path,
-		period: pollTime,
-		ttl:    ttlTime,
+		coder:    coder,
+		name:     name,
+		path:     path,
+		period:   pollTime,
+		ttl:      ttlTime,
+		debounce: geobus.DefaultWatchDebounce,
 	}
 	provider.locateFn = provider.readFile
+	provider.watchFn = provider.watchFile
 	return provider, nil
 }
 
@@ -67,18 +74,22 @@ func (p *CitynameFileProvider) LookupStream(ctx context.Context, key string) <-c
 		defer close(out)
 		state := geobus.GeolocationState{}
 		firstRun := true
+		// Changes of the file are read right away. If the file can't be watched, the channel is nil and
+		// the file is only polled.
+		changed, _ := p.watchFn(ctx)
 
 		for {
 			if !firstRun {
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-changed:
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
 
-			coords, err := p.locateFn()
+			coords, err := p.locateFn(ctx)
 			if err != nil {
 				continue
 			}
@@ -96,6 +107,11 @@ func (p *CitynameFileProvider) LookupStream(ctx context.Context, key string) <-c
 	return out
 }
 
+// watchFile watches the cityname file for changes.
+func (p *CitynameFileProvider) watchFile(ctx context.Context) (<-chan struct{}, error) {
+	return geobus.WatchFile(ctx, p.path, p.debounce)
+}
+
 // createResult composes and returns a Result using provided geolocation data and metadata.
 func (p *CitynameFileProvider) createResult(key string, coord geobus.Coordinate) geobus.Result {
 	return geobus.Result{
@@ -109,16 +125,17 @@ func (p *CitynameFileProvider) createResult(key string, coord geobus.Coordinate)
 	}
 }
 
-// readFile reads geolocation data from the file at the configured path.
+// readFile reads geolocation data from the file at the configured path. The city is looked up with the given
+// context, so that the lookup is cancelled along with the lookup stream.
 // Returns latitude, longitude, altitude, accuracy, or an error if the file cannot be
 // read or parsed correctly.
-func (p *CitynameFileProvider) readFile() (coords geobus.Coordinate, err error) {
+func (p *CitynameFileProvider) readFile(ctx context.Context) (coords geobus.Coordinate, err error) {
 	data, err := os.ReadFile(p.path)
 	if err != nil {
 		return coords, fmt.Errorf("failed to read cityname file %q: %w", p.path, err)
 	}
 	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
+	for i, line := range lines {
 		if line == "" {
 			continue
 		}
@@ -128,10 +145,14 @@ func (p *CitynameFileProvider) readFile() (coords geobus.Coordinate, err error)
 			continue
 		}
 
-		coords, err = p.coder.Search(context.Background(), line)
+		coords, err = p.coder.Search(ctx, line)
 		if err != nil {
 			return coords, fmt.Errorf("failed to look up city %q: %w", line, err)
 		}
+		if !coords.Valid() {
+			return coords, fmt.Errorf("invalid coordinates for city %q in cityname file %q on line %d: %f, %f",
+				line, p.path, i+1, coords.Lat, coords.Lon)
+		}
 		return coords, nil
 	}
 	return coords, ErrNoCoordinates
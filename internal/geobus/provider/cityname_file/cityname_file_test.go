@@ -7,6 +7,10 @@ package cityname_file
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"testing/synctest"
@@ -56,7 +60,7 @@ func TestNewCitynameFileProvider_readFile(t *testing.T) {
 		if provider == nil {
 			t.Fatal("expected provider to be non-nil")
 		}
-		coords, err := provider.readFile()
+		coords, err := provider.readFile(t.Context())
 		if err != nil {
 			t.Fatalf("failed to read file: %s", err)
 		}
@@ -72,7 +76,7 @@ func TestNewCitynameFileProvider_readFile(t *testing.T) {
 		if provider == nil {
 			t.Fatal("expected provider to be non-nil")
 		}
-		_, err := provider.readFile()
+		_, err := provider.readFile(t.Context())
 		if err == nil {
 			t.Error("expected error, but didn't get one")
 		}
@@ -82,7 +86,7 @@ func TestNewCitynameFileProvider_readFile(t *testing.T) {
 		if provider == nil {
 			t.Fatal("expected provider to be non-nil")
 		}
-		_, err := provider.readFile()
+		_, err := provider.readFile(t.Context())
 		if err == nil {
 			t.Error("expected error, but didn't get one")
 		}
@@ -92,11 +96,51 @@ func TestNewCitynameFileProvider_readFile(t *testing.T) {
 		if provider == nil {
 			t.Fatal("expected provider to be non-nil")
 		}
-		_, err := provider.readFile()
+		_, err := provider.readFile(t.Context())
 		if err == nil {
 			t.Error("expected error, but didn't get one")
 		}
 	})
+	t.Run("coordinates are validated", func(t *testing.T) {
+		tests := []struct {
+			city  string
+			fails bool
+		}{
+			{"North Pole", false},
+			{"South Pole", false},
+			{"Date Line East", false},
+			{"Date Line West", false},
+			{"Beyond North Pole", true},
+			{"Beyond South Pole", true},
+			{"Beyond Date Line East", true},
+			{"Beyond Date Line West", true},
+			{"NaN City", true},
+			{"Inf City", true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.city, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "cityname")
+				if err := os.WriteFile(path, []byte("# Test cityname file\n"+tc.city+"\n"), 0o600); err != nil {
+					t.Fatalf("failed to write cityname file: %s", err)
+				}
+				provider := testProvider(t, path)
+				_, err := provider.readFile(t.Context())
+				if !tc.fails {
+					if err != nil {
+						t.Errorf("failed to read file: %s", err)
+					}
+					return
+				}
+				if err == nil {
+					t.Fatal("expected error, but didn't get one")
+				}
+				wantErr := fmt.Sprintf("invalid coordinates for city %q in cityname file %q on line 2", tc.city, path)
+				if !strings.Contains(err.Error(), wantErr) {
+					t.Errorf("expected error to contain %q, got %q", wantErr, err)
+				}
+			})
+		}
+	})
 }
 
 func TestCitynameFileProvider_createResult(t *testing.T) {
@@ -141,6 +185,7 @@ func TestCitynameFileProvider_LookupStream(t *testing.T) {
 			}
 			provider.ttl = time.Millisecond * 10
 			provider.period = time.Millisecond * 10
+			provider.watchFn = noWatch
 
 			out := provider.LookupStream(ctx, "test")
 			if out == nil {
@@ -197,7 +242,8 @@ func TestCitynameFileProvider_LookupStream(t *testing.T) {
 				t.Fatal("expected provider to be non-nil")
 			}
 			provider.period = time.Millisecond * 10
-			provider.locateFn = func() (geobus.Coordinate, error) {
+			provider.watchFn = noWatch
+			provider.locateFn = func(context.Context) (geobus.Coordinate, error) {
 				if runCount == 0 {
 					runCount++
 					return geobus.Coordinate{}, errors.New("intentionally failing")
@@ -254,5 +300,89 @@ func (m *mockCoder) Search(_ context.Context, addr string) (geobus.Coordinate, e
 	if addr == "Invalid, United Nations" {
 		return geobus.Coordinate{}, errors.New("intentionally failing")
 	}
+	if coords, ok := edgeCities[addr]; ok {
+		return coords, nil
+	}
 	return geobus.Coordinate{Lat: testLat, Lon: testLon}, nil
 }
+
+// edgeCities maps fictional city names to edge case coordinates returned by the mockCoder
+var edgeCities = map[string]geobus.Coordinate{
+	"North Pole":            {Lat: 90, Lon: 0},
+	"South Pole":            {Lat: -90, Lon: 0},
+	"Date Line East":        {Lat: 0, Lon: 180},
+	"Date Line West":        {Lat: 0, Lon: -180},
+	"Beyond North Pole":     {Lat: 740.2, Lon: 0},
+	"Beyond South Pole":     {Lat: -90.000001, Lon: 0},
+	"Beyond Date Line East": {Lat: 0, Lon: 180.000001},
+	"Beyond Date Line West": {Lat: 0, Lon: -999},
+	"NaN City":              {Lat: math.NaN(), Lon: 0},
+	"Inf City":              {Lat: 0, Lon: math.Inf(1)},
+}
+
+func TestCitynameFileProvider_LookupStream_watch(t *testing.T) {
+	// The file watcher reads from the kernel, so these tests run in real time with short timeouts
+	awaitResult := func(t *testing.T, out <-chan geobus.Result) geobus.Result {
+		t.Helper()
+		select {
+		case r := <-out:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a result after the file changed")
+		}
+		return geobus.Result{}
+	}
+	newProvider := func(t *testing.T) (*CitynameFileProvider, string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "cityname")
+		if err := os.WriteFile(path, []byte("North Pole\n"), 0o600); err != nil {
+			t.Fatalf("failed to write cityname file: %s", err)
+		}
+		provider, err := NewCitynameFileProvider(path, new(mockCoder))
+		if err != nil {
+			t.Fatalf("failed to create cityname file provider: %s", err)
+		}
+		provider.period = time.Hour
+		provider.debounce = time.Millisecond * 10
+		return provider, path
+	}
+
+	t.Run("modified file is read right away", func(t *testing.T) {
+		provider, path := newProvider(t)
+		out := provider.LookupStream(t.Context(), "test")
+		if r := awaitResult(t, out); r.Lat != 90 {
+			t.Fatalf("expected initial latitude 90, got %f", r.Lat)
+		}
+
+		if err := os.WriteFile(path, []byte("South Pole\n"), 0o600); err != nil {
+			t.Fatalf("failed to write cityname file: %s", err)
+		}
+		if r := awaitResult(t, out); r.Lat != -90 {
+			t.Errorf("expected modified latitude -90, got %f", r.Lat)
+		}
+	})
+	t.Run("atomically replaced file is read right away", func(t *testing.T) {
+		provider, path := newProvider(t)
+		out := provider.LookupStream(t.Context(), "test")
+		awaitResult(t, out)
+
+		for city, lon := range map[string]float64{"Date Line East": 180, "Date Line West": -180} {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, []byte(city+"\n"), 0o600); err != nil {
+				t.Fatalf("failed to write temporary file: %s", err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				t.Fatalf("failed to replace cityname file: %s", err)
+			}
+			if r := awaitResult(t, out); r.Lon != lon {
+				t.Errorf("expected longitude %f of %s, got %f", lon, city, r.Lon)
+			}
+		}
+	})
+}
+
+// noWatch replaces the file watcher in the tests that run in a synctest bubble, since the watcher blocks
+// in a system call that the bubble can't wait for.
+func noWatch(context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("watching is disabled")
+}
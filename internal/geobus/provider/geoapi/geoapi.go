@@ -23,6 +23,8 @@ const (
 )
 
 type GeolocationGeoAPIProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	http     *http.Client
 	period   time.Duration
@@ -78,7 +80,7 @@ func (p *GeolocationGeoAPIProvider) LookupStream(ctx context.Context, key string
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
@@ -119,7 +121,8 @@ func (p *GeolocationGeoAPIProvider) locate(ctx context.Context) (lat, lon, acc f
 	defer cancelHttp()
 
 	result := new(APIResult)
-	if _, err = p.http.Get(ctxHttp, apiEndpoint, result, nil, nil); err != nil {
+	_, err = p.http.Get(ctxHttp, apiEndpoint, result, nil, nil)
+	if err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
 	}
 
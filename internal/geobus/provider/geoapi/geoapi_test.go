@@ -7,6 +7,7 @@ package geoapi
 import (
 	"context"
 	"errors"
+	"io"
 	"log/slog"
 	stdhttp "net/http"
 	"os"
@@ -157,6 +158,24 @@ func TestNewGeolocationGeoAPIProvider_locate(t *testing.T) {
 			t.Error("expected locate to fail")
 		}
 	})
+	t.Run("locate fails on non-positive response code", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 503,
+				Body:       io.NopCloser(strings.NewReader(`{"error":"Service Unavailable"}`)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client := http.New(logger.New(slog.LevelInfo))
+		client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+		provider, err := NewGeolocationGeoAPIProvider(client)
+		if err != nil {
+			t.Fatalf("failed to create GeoAPI provider: %s", err)
+		}
+		if _, _, _, err = provider.locate(t.Context()); err == nil {
+			t.Error("expected locate to fail")
+		}
+	})
 }
 
 func TestGeolocationGeoAPIProvider_createResult(t *testing.T) {
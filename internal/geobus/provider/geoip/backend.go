@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	// BackendReallyFreeGeoIP looks up the location via https://reallyfreegeoip.org
+	BackendReallyFreeGeoIP = "reallyfreegeoip"
+	// BackendIPAPI looks up the location via the JSON endpoint of https://ip-api.com
+	BackendIPAPI = "ip-api"
+	// BackendIPInfo looks up the location via https://ipinfo.io
+	BackendIPInfo = "ipinfo"
+)
+
+const (
+	reallyFreeGeoIPEndpoint = "https://reallyfreegeoip.org/json/"
+	// The free ip-api.com endpoint is only available via plain HTTP
+	ipAPIEndpoint   = "http://ip-api.com/json/"
+	ipAPIFields     = "status,message,countryCode,region,city,zip,lat,lon"
+	ipInfoEndpoint  = "https://ipinfo.io/json"
+	ipAPIStatusFail = "fail"
+)
+
+// backend is a GeoIP API that looks up the location of the public IP address of the host.
+type backend interface {
+	locate(ctx context.Context, client *http.Client) (lat, lon, acc float64, err error)
+}
+
+// newBackend returns the backend with the given name. The token is only used by the ipinfo backend, which
+// works without token within its rate limits.
+func newBackend(name, token string) (backend, error) {
+	switch strings.ToLower(name) {
+	case "", BackendReallyFreeGeoIP:
+		return reallyFreeGeoIP{}, nil
+	case BackendIPAPI:
+		return ipAPI{}, nil
+	case BackendIPInfo:
+		return ipInfo{token: token}, nil
+	default:
+		return nil, fmt.Errorf("unsupported GeoIP backend: %s", name)
+	}
+}
+
+// reallyFreeGeoIP is the backend of https://reallyfreegeoip.org
+type reallyFreeGeoIP struct{}
+
+// APIResult is the response of the reallyfreegeoip.org API.
+type APIResult struct {
+	IP          string  `json:"ip"`
+	CountryCode string  `json:"country_code"`
+	Country     string  `json:"country_name"`
+	RegionCode  string  `json:"region_code,omitempty"`
+	Region      string  `json:"region_name,omitempty"`
+	City        string  `json:"city,omitempty"`
+	ZipCode     string  `json:"zip_code,omitempty"`
+	TimeZone    string  `json:"time_zone"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	MetroCode   int     `json:"metro_code"`
+}
+
+func (reallyFreeGeoIP) locate(ctx context.Context, client *http.Client) (lat, lon, acc float64, err error) {
+	result := new(APIResult)
+	_, err = client.Get(ctx, reallyFreeGeoIPEndpoint, result, nil, nil)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
+	}
+	acc = accuracy(result.CountryCode, result.RegionCode, result.City, result.ZipCode)
+	return result.Latitude, result.Longitude, acc, nil
+}
+
+// ipAPI is the backend of the JSON endpoint of https://ip-api.com
+type ipAPI struct{}
+
+// ipAPIResult is the response of the ip-api.com JSON endpoint. Failed lookups are answered with the "fail"
+// status and an error message.
+type ipAPIResult struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message,omitempty"`
+	CountryCode string  `json:"countryCode"`
+	Region      string  `json:"region,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Zip         string  `json:"zip,omitempty"`
+	Latitude    float64 `json:"lat"`
+	Longitude   float64 `json:"lon"`
+}
+
+func (ipAPI) locate(ctx context.Context, client *http.Client) (lat, lon, acc float64, err error) {
+	result := new(ipAPIResult)
+	query := url.Values{"fields": {ipAPIFields}}
+	_, err = client.Get(ctx, ipAPIEndpoint, result, query, nil)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
+	}
+	if result.Status == ipAPIStatusFail {
+		return 0, 0, 0, fmt.Errorf("geolocation lookup failed: %s", result.Message)
+	}
+	acc = accuracy(result.CountryCode, result.Region, result.City, result.Zip)
+	return result.Latitude, result.Longitude, acc, nil
+}
+
+// ipInfo is the backend of https://ipinfo.io. The token is optional.
+type ipInfo struct {
+	token string
+}
+
+// ipInfoResult is the response of the ipinfo.io API. The coordinates are returned as "lat,lon" string.
+type ipInfoResult struct {
+	IP       string `json:"ip"`
+	City     string `json:"city,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Country  string `json:"country"`
+	Location string `json:"loc"`
+	Postal   string `json:"postal,omitempty"`
+	TimeZone string `json:"timezone"`
+}
+
+func (b ipInfo) locate(ctx context.Context, client *http.Client) (lat, lon, acc float64, err error) {
+	var headers map[string]string
+	if b.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + b.token}
+	}
+	result := new(ipInfoResult)
+	_, err = client.Get(ctx, ipInfoEndpoint, result, nil, headers)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
+	}
+	if lat, lon, err = parseLocation(result.Location); err != nil {
+		return 0, 0, 0, err
+	}
+	acc = accuracy(result.Country, result.Region, result.City, result.Postal)
+	return lat, lon, acc, nil
+}
+
+// parseLocation parses the coordinates of the "lat,lon" string of the ipinfo.io API.
+func parseLocation(loc string) (lat, lon float64, err error) {
+	latStr, lonStr, ok := strings.Cut(loc, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid location: %q", loc)
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err = errors.Join(latErr, lonErr); err != nil {
+		return 0, 0, fmt.Errorf("invalid location %q: %w", loc, err)
+	}
+	return lat, lon, nil
+}
+
+// accuracy derives the accuracy of the location from the most precise address field the backend returned.
+func accuracy(country, region, city, zip string) float64 {
+	switch {
+	case zip != "":
+		return geobus.AccuracyZip
+	case city != "":
+		return geobus.AccuracyCity
+	case region != "":
+		return geobus.AccuracyRegion
+	case country != "":
+		return geobus.AccuracyCountry
+	default:
+		return geobus.AccuracyUnknown
+	}
+}
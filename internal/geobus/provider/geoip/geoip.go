@@ -14,7 +14,6 @@ import (
 )
 
 const (
-	apiEndpoint   = "https://reallyfreegeoip.org/json/"
 	lookupTimeout = time.Second * 10
 	name          = "geoip"
 	ttlTime       = time.Hour * 2
@@ -22,36 +21,32 @@ const (
 )
 
 type GeolocationGeoIPProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	http     *http.Client
 	period   time.Duration
 	ttl      time.Duration
+	backend  backend
 	locateFn func(ctx context.Context) (lat, lon, acc float64, err error)
 }
 
-type APIResult struct {
-	IP          string  `json:"ip"`
-	CountryCode string  `json:"country_code"`
-	Country     string  `json:"country_name"`
-	RegionCode  string  `json:"region_code,omitempty"`
-	Region      string  `json:"region_name,omitempty"`
-	City        string  `json:"city,omitempty"`
-	ZipCode     string  `json:"zip_code,omitempty"`
-	TimeZone    string  `json:"time_zone"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	MetroCode   int     `json:"metro_code"`
-}
-
-func NewGeolocationGeoIPProvider(http *http.Client) (*GeolocationGeoIPProvider, error) {
+// NewGeolocationGeoIPProvider returns a GeoIP provider that looks up the location via the GeoIP backend with
+// the given name (reallyfreegeoip if empty). The token is passed to the backends that support one.
+func NewGeolocationGeoIPProvider(http *http.Client, backendName, token string) (*GeolocationGeoIPProvider, error) {
 	if http == nil {
 		return nil, fmt.Errorf("http client is required")
 	}
+	backend, err := newBackend(backendName, token)
+	if err != nil {
+		return nil, err
+	}
 	provider := &GeolocationGeoIPProvider{
-		name:   name,
-		http:   http,
-		period: pollTime,
-		ttl:    ttlTime,
+		name:    name,
+		http:    http,
+		period:  pollTime,
+		ttl:     ttlTime,
+		backend: backend,
 	}
 	provider.locateFn = provider.locate
 	return provider, nil
@@ -75,7 +70,7 @@ func (p *GeolocationGeoIPProvider) LookupStream(ctx context.Context, key string)
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
@@ -115,26 +110,9 @@ func (p *GeolocationGeoIPProvider) locate(ctx context.Context) (lat, lon, acc fl
 	ctxHttp, cancelHttp := context.WithTimeout(ctx, lookupTimeout)
 	defer cancelHttp()
 
-	result := new(APIResult)
-	if _, err = p.http.Get(ctxHttp, apiEndpoint, result, nil, nil); err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
-	}
-
-	acc = geobus.AccuracyUnknown
-	if result.CountryCode != "" {
-		acc = geobus.AccuracyCountry
-	}
-	if result.RegionCode != "" {
-		acc = geobus.AccuracyRegion
-	}
-	if result.City != "" {
-		acc = geobus.AccuracyCity
+	if lat, lon, acc, err = p.backend.locate(ctxHttp, p.http); err != nil {
+		return 0, 0, 0, err
 	}
-	if result.ZipCode != "" {
-		acc = geobus.AccuracyZip
-	}
-
-	return geobus.Truncate(result.Latitude, geobus.TruncPrecision),
-		geobus.Truncate(result.Longitude, geobus.TruncPrecision),
+	return geobus.Truncate(lat, geobus.TruncPrecision), geobus.Truncate(lon, geobus.TruncPrecision),
 		geobus.Truncate(acc, geobus.TruncPrecision), nil
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"log/slog"
 	stdhttp "net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -28,7 +29,7 @@ const (
 
 func TestNewGeolocationGeoIPProvider(t *testing.T) {
 	t.Run("new GeoIP provider succeeds", func(t *testing.T) {
-		provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)))
+		provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)), "", "")
 		if err != nil {
 			t.Fatalf("failed to create GeoIP provider: %s", err)
 		}
@@ -36,8 +37,17 @@ func TestNewGeolocationGeoIPProvider(t *testing.T) {
 			t.Fatal("expected provider to be non-nil")
 		}
 	})
+	t.Run("GeoIP with unknown backend fails", func(t *testing.T) {
+		provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)), "freegeoip", "")
+		if err == nil {
+			t.Fatal("expected provider to fail")
+		}
+		if provider != nil {
+			t.Fatal("expected provider to be nil")
+		}
+	})
 	t.Run("GeoIP without http client fails ", func(t *testing.T) {
-		provider, err := NewGeolocationGeoIPProvider(nil)
+		provider, err := NewGeolocationGeoIPProvider(nil, "", "")
 		if err == nil {
 			t.Fatal("expected provider to fail")
 		}
@@ -48,7 +58,7 @@ func TestNewGeolocationGeoIPProvider(t *testing.T) {
 }
 
 func TestGeolocationGeoIPProvider_Name(t *testing.T) {
-	provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)))
+	provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)), "", "")
 	if err != nil {
 		t.Fatalf("failed to create GeoIP provider: %s", err)
 	}
@@ -86,7 +96,7 @@ func TestNewGeolocationGeoIPProvider_locate(t *testing.T) {
 				}
 				client := http.New(logger.New(slog.LevelInfo))
 				client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
-				provider, err := NewGeolocationGeoIPProvider(client)
+				provider, err := NewGeolocationGeoIPProvider(client, "", "")
 				if err != nil {
 					t.Fatalf("failed to create GeoIP provider: %s", err)
 				}
@@ -114,7 +124,7 @@ func TestNewGeolocationGeoIPProvider_locate(t *testing.T) {
 		}
 		client := http.New(logger.New(slog.LevelInfo))
 		client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
-		provider, err := NewGeolocationGeoIPProvider(client)
+		provider, err := NewGeolocationGeoIPProvider(client, "", "")
 		if err != nil {
 			t.Fatalf("failed to create GeoIP provider: %s", err)
 		}
@@ -124,8 +134,146 @@ func TestNewGeolocationGeoIPProvider_locate(t *testing.T) {
 	})
 }
 
+func TestNewGeolocationGeoIPProvider_backends(t *testing.T) {
+	tests := []struct {
+		name       string
+		backend    string
+		token      string
+		file       string
+		wantURL    string
+		wantAuth   string
+		code       int
+		want       float64
+		shouldFail bool
+	}{
+		{
+			name: "reallyfreegeoip", backend: BackendReallyFreeGeoIP, file: "../../../../testdata/geoip.json",
+			wantURL: "https://reallyfreegeoip.org/json/", want: geobus.AccuracyZip,
+		},
+		{
+			name: "ip-api", backend: BackendIPAPI, file: "../../../../testdata/ipapi.json",
+			wantURL: "http://ip-api.com/json/?fields=" + url.QueryEscape(ipAPIFields), want: geobus.AccuracyZip,
+		},
+		{
+			name: "ip-api without city", backend: BackendIPAPI, file: "../../../../testdata/ipapi_nocity.json",
+			wantURL: "http://ip-api.com/json/?fields=" + url.QueryEscape(ipAPIFields), want: geobus.AccuracyRegion,
+		},
+		{
+			name: "ip-api failed lookup", backend: BackendIPAPI, file: "../../../../testdata/ipapi_fail.json",
+			wantURL: "http://ip-api.com/json/?fields=" + url.QueryEscape(ipAPIFields), shouldFail: true,
+		},
+		{
+			name: "ip-api rate limited", backend: BackendIPAPI, file: "../../../../testdata/ipapi_fail.json",
+			wantURL: "http://ip-api.com/json/?fields=" + url.QueryEscape(ipAPIFields), code: 429,
+			shouldFail: true,
+		},
+		{
+			name: "ipinfo", backend: BackendIPInfo, file: "../../../../testdata/ipinfo.json",
+			wantURL: "https://ipinfo.io/json", want: geobus.AccuracyZip,
+		},
+		{
+			name: "ipinfo with token", backend: BackendIPInfo, token: "secret",
+			file: "../../../../testdata/ipinfo_country.json", wantURL: "https://ipinfo.io/json",
+			wantAuth: "Bearer secret", want: geobus.AccuracyCountry,
+		},
+		{
+			name: "ipinfo without location", backend: BackendIPInfo, file: "../../../../testdata/ipinfo_noloc.json",
+			wantURL: "https://ipinfo.io/json", shouldFail: true,
+		},
+		{
+			name: "ipinfo with rejected token", backend: BackendIPInfo, token: "invalid",
+			file: "../../../../testdata/ipinfo_noloc.json", wantURL: "https://ipinfo.io/json",
+			wantAuth: "Bearer invalid", code: 403, shouldFail: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				if req.URL.String() != tc.wantURL {
+					t.Errorf("expected request to %s, got %s", tc.wantURL, req.URL)
+				}
+				if auth := req.Header.Get("Authorization"); auth != tc.wantAuth {
+					t.Errorf("expected authorization header to be %q, got %q", tc.wantAuth, auth)
+				}
+				data, err := os.Open(tc.file)
+				if err != nil {
+					t.Fatalf("failed to open JSON response file: %s", err)
+				}
+				code := tc.code
+				if code == 0 {
+					code = 200
+				}
+				return &stdhttp.Response{
+					StatusCode: code,
+					Body:       data,
+					Header:     stdhttp.Header{"Content-Type": []string{"application/json"}},
+				}, nil
+			}
+			client := http.New(logger.New(slog.LevelInfo))
+			client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+			provider, err := NewGeolocationGeoIPProvider(client, tc.backend, tc.token)
+			if err != nil {
+				t.Fatalf("failed to create GeoIP provider: %s", err)
+			}
+
+			lat, lon, acc, err := provider.locate(t.Context())
+			if tc.shouldFail {
+				if err == nil {
+					t.Fatal("expected locate to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to locate coordinates via GeoIP: %s", err)
+			}
+			if lat != testLat {
+				t.Errorf("expected latitude to be %f, got %f", testLat, lat)
+			}
+			if lon != testLon {
+				t.Errorf("expected longitude to be %f, got %f", testLon, lon)
+			}
+			if geobus.Truncate(acc, 1) != geobus.Truncate(tc.want, 1) {
+				t.Errorf("expected accuracy to be %f, got %f", geobus.Truncate(tc.want, 1), geobus.Truncate(acc, 1))
+			}
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		loc        string
+		lat        float64
+		lon        float64
+		shouldFail bool
+	}{
+		{loc: "40.7185,-74.0025", lat: testLat, lon: testLon},
+		{loc: "40.7185, -74.0025", lat: testLat, lon: testLon},
+		{loc: "", shouldFail: true},
+		{loc: "40.7185", shouldFail: true},
+		{loc: "north,-74.0025", shouldFail: true},
+		{loc: "40.7185,west", shouldFail: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.loc, func(t *testing.T) {
+			lat, lon, err := parseLocation(tc.loc)
+			if tc.shouldFail {
+				if err == nil {
+					t.Error("expected parsing the location to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse location: %s", err)
+			}
+			if lat != tc.lat || lon != tc.lon {
+				t.Errorf("expected coordinates %f, %f, got %f, %f", tc.lat, tc.lon, lat, lon)
+			}
+		})
+	}
+}
+
 func TestGeolocationGeoIPProvider_createResult(t *testing.T) {
-	provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)))
+	provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)), "", "")
 	if err != nil {
 		t.Fatalf("failed to create GeoIP provider: %s", err)
 	}
@@ -170,7 +318,7 @@ func TestGeolocationGeoIPProvider_LookupStream(t *testing.T) {
 			}
 			client := http.New(logger.New(slog.LevelInfo))
 			client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
-			provider, err := NewGeolocationGeoIPProvider(client)
+			provider, err := NewGeolocationGeoIPProvider(client, "", "")
 			if err != nil {
 				t.Fatalf("failed to create GeoIP provider: %s", err)
 			}
@@ -223,7 +371,7 @@ func TestGeolocationGeoIPProvider_LookupStream(t *testing.T) {
 			ctx, cancel := context.WithCancel(t.Context())
 			defer cancel()
 
-			provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)))
+			provider, err := NewGeolocationGeoIPProvider(http.New(logger.New(slog.LevelInfo)), "", "")
 			if err != nil {
 				t.Fatalf("failed to create GeoIP provider: %s", err)
 			}
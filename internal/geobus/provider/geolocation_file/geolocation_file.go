@@ -24,27 +24,34 @@ const (
 var ErrNoCoordinates = fmt.Errorf("no valid coordinates found in geolocation file")
 
 // GeolocationFileProvider reads geolocation data from a file and emits updates via a stream.
-// It periodically reads a specified file, parses its data, and updates geolocation results based on changes.
+// It reads a specified file whenever it changes and periodically, parses its data, and updates geolocation
+// results based on changes.
 // Each result includes details about the location, accuracy, confidence, and timestamp of the data.
 // Results are subject to a time-to-live (TTL) duration, ensuring outdated data is discarded.
 type GeolocationFileProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	path     string
 	period   time.Duration
 	ttl      time.Duration
+	debounce time.Duration
 	locateFn func() (lat, lon float64, err error)
+	watchFn  func(context.Context) (<-chan struct{}, error)
 }
 
 // NewGeolocationFileProvider initializes a GeolocationFileProvider with a file path and default update
 // interval and TTL settings.
 func NewGeolocationFileProvider(path string) *GeolocationFileProvider {
 	provider := &GeolocationFileProvider{
-		name:   name,
-		path:   path,
-		period: pollTime,
-		ttl:    ttlTime,
+		name:     name,
+		path:     path,
+		period:   pollTime,
+		ttl:      ttlTime,
+		debounce: geobus.DefaultWatchDebounce,
 	}
 	provider.locateFn = provider.readFile
+	provider.watchFn = provider.watchFile
 	return provider
 }
 
@@ -61,13 +68,17 @@ func (p *GeolocationFileProvider) LookupStream(ctx context.Context, key string)
 		defer close(out)
 		state := geobus.GeolocationState{}
 		firstRun := true
+		// Changes of the file are read right away. If the file can't be watched, the channel is nil and
+		// the file is only polled.
+		changed, _ := p.watchFn(ctx)
 
 		for {
 			if !firstRun {
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-changed:
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
@@ -90,6 +101,11 @@ func (p *GeolocationFileProvider) LookupStream(ctx context.Context, key string)
 	return out
 }
 
+// watchFile watches the geolocation file for changes.
+func (p *GeolocationFileProvider) watchFile(ctx context.Context) (<-chan struct{}, error) {
+	return geobus.WatchFile(ctx, p.path, p.debounce)
+}
+
 // createResult composes and returns a Result using provided geolocation data and metadata.
 func (p *GeolocationFileProvider) createResult(key string, coord geobus.Coordinate) geobus.Result {
 	return geobus.Result{
@@ -112,7 +128,7 @@ func (p *GeolocationFileProvider) readFile() (lat, lon float64, err error) {
 		return 0, 0, fmt.Errorf("failed to read geolocation file %q: %w", p.path, err)
 	}
 	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "#") {
 			continue
@@ -129,6 +145,10 @@ func (p *GeolocationFileProvider) readFile() (lat, lon float64, err error) {
 		if err != nil {
 			continue
 		}
+		if !(geobus.Coordinate{Lat: lat, Lon: lon}).Valid() {
+			return 0, 0, fmt.Errorf("invalid coordinates in geolocation file %q on line %d: %f, %f",
+				p.path, i+1, lat, lon)
+		}
 		return lat, lon, nil
 	}
 	return 0, 0, ErrNoCoordinates
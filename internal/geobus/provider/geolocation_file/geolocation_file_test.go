@@ -7,6 +7,9 @@ package geolocation_file
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"testing/synctest"
@@ -106,6 +109,52 @@ func TestNewGeolocationFileProvider_readFile(t *testing.T) {
 	})
 }
 
+func TestGeolocationFileProvider_readFile_validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords string
+		fails  bool
+	}{
+		{"north pole", "90,0", false},
+		{"south pole", "-90,0", false},
+		{"date line east", "0,180", false},
+		{"date line west", "0,-180", false},
+		{"extreme north east", "90,180", false},
+		{"extreme south west", "-90,-180", false},
+		{"beyond north pole", "740.2,0", true},
+		{"beyond south pole", "-90.000001,0", true},
+		{"beyond date line east", "0,180.000001", true},
+		{"beyond date line west", "0,-999", true},
+		{"NaN latitude", "NaN,0", true},
+		{"NaN longitude", "0,NaN", true},
+		{"positive infinite latitude", "+Inf,0", true},
+		{"negative infinite longitude", "0,-Inf", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "geolocation")
+			if err := os.WriteFile(path, []byte("# Test geolocation file\n"+tc.coords+"\n"), 0o600); err != nil {
+				t.Fatalf("failed to write geolocation file: %s", err)
+			}
+			provider := NewGeolocationFileProvider(path)
+			_, _, err := provider.readFile()
+			if !tc.fails {
+				if err != nil {
+					t.Errorf("failed to read file: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, but didn't get one")
+			}
+			wantErr := fmt.Sprintf("invalid coordinates in geolocation file %q on line 2", path)
+			if !strings.Contains(err.Error(), wantErr) {
+				t.Errorf("expected error to contain %q, got %q", wantErr, err)
+			}
+		})
+	}
+}
+
 func TestGeolocationFileProvider_createResult(t *testing.T) {
 	provider := NewGeolocationFileProvider(testFile)
 	if provider == nil {
@@ -144,6 +193,7 @@ func TestGeolocationFileProvider_LookupStream(t *testing.T) {
 			}
 			provider.ttl = time.Millisecond * 10
 			provider.period = time.Millisecond * 10
+			provider.watchFn = noWatch
 
 			out := provider.LookupStream(ctx, "test")
 			if out == nil {
@@ -196,6 +246,7 @@ func TestGeolocationFileProvider_LookupStream(t *testing.T) {
 				t.Fatal("expected provider to be non-nil")
 			}
 			provider.period = time.Millisecond * 10
+			provider.watchFn = noWatch
 			provider.locateFn = func() (float64, float64, error) {
 				if runCount == 0 {
 					runCount++
@@ -231,3 +282,74 @@ func TestGeolocationFileProvider_LookupStream(t *testing.T) {
 		})
 	})
 }
+
+func TestGeolocationFileProvider_LookupStream_watch(t *testing.T) {
+	// The file watcher reads from the kernel, so these tests run in real time with short timeouts
+	awaitResult := func(t *testing.T, out <-chan geobus.Result) geobus.Result {
+		t.Helper()
+		select {
+		case r := <-out:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a result after the file changed")
+		}
+		return geobus.Result{}
+	}
+	newProvider := func(t *testing.T) (*GeolocationFileProvider, string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "geolocation")
+		if err := os.WriteFile(path, []byte("1.0,2.0\n"), 0o600); err != nil {
+			t.Fatalf("failed to write geolocation file: %s", err)
+		}
+		provider := NewGeolocationFileProvider(path)
+		provider.period = time.Hour
+		provider.debounce = time.Millisecond * 10
+		return provider, path
+	}
+
+	t.Run("modified file is read right away", func(t *testing.T) {
+		provider, path := newProvider(t)
+		out := provider.LookupStream(t.Context(), "test")
+		if r := awaitResult(t, out); r.Lat != 1.0 || r.Lon != 2.0 {
+			t.Fatalf("expected initial coordinates 1.0, 2.0, got %f, %f", r.Lat, r.Lon)
+		}
+
+		if err := os.WriteFile(path, []byte("3.0,4.0\n"), 0o600); err != nil {
+			t.Fatalf("failed to write geolocation file: %s", err)
+		}
+		if r := awaitResult(t, out); r.Lat != 3.0 || r.Lon != 4.0 {
+			t.Errorf("expected modified coordinates 3.0, 4.0, got %f, %f", r.Lat, r.Lon)
+		}
+	})
+	t.Run("atomically replaced file is read right away", func(t *testing.T) {
+		provider, path := newProvider(t)
+		out := provider.LookupStream(t.Context(), "test")
+		awaitResult(t, out)
+
+		for _, coords := range []string{"3.0,4.0", "5.0,6.0"} {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, []byte(coords+"\n"), 0o600); err != nil {
+				t.Fatalf("failed to write temporary file: %s", err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				t.Fatalf("failed to replace geolocation file: %s", err)
+			}
+			r := awaitResult(t, out)
+			if got := fmt.Sprintf("%.1f,%.1f", r.Lat, r.Lon); got != coords {
+				t.Errorf("expected replaced coordinates %s, got %s", coords, got)
+			}
+		}
+	})
+	t.Run("file in a missing directory is polled", func(t *testing.T) {
+		provider := NewGeolocationFileProvider(filepath.Join(t.TempDir(), "missing", "geolocation"))
+		if _, err := provider.watchFn(t.Context()); err == nil {
+			t.Error("expected watching a file in a missing directory to fail")
+		}
+	})
+}
+
+// noWatch replaces the file watcher in the tests that run in a synctest bubble, since the watcher blocks
+// in a system call that the bubble can't wait for.
+func noWatch(context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("watching is disabled")
+}
@@ -21,6 +21,8 @@ const (
 )
 
 type GeolocationGPSDProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	period   time.Duration
 	ttl      time.Duration
@@ -57,7 +59,7 @@ func (p *GeolocationGPSDProvider) LookupStream(ctx context.Context, key string)
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
@@ -34,6 +34,8 @@ const (
 )
 
 type GeolocationICHNAEAProvider struct {
+	geobus.PeriodScale
+
 	name     string
 	http     *http.Client
 	wlan     *wifi.Client
@@ -111,7 +113,7 @@ func (p *GeolocationICHNAEAProvider) LookupStream(ctx context.Context, key strin
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(p.period):
+				case <-time.After(p.Scaled(p.period)):
 				}
 			}
 			firstRun = false
@@ -286,8 +288,9 @@ func (p *GeolocationICHNAEAProvider) locate(ctx context.Context) (lat, lon, acc
 	ctxHttp, cancelHttp := context.WithTimeout(ctx, lookupTimeout)
 	defer cancelHttp()
 	result := new(APIResult)
-	if _, err = p.http.Post(ctxHttp, apiEndpoint, result, bodyBuffer,
-		map[string]string{"Content-Type": "application/json"}); err != nil {
+	_, err = p.http.Post(ctxHttp, apiEndpoint, result, bodyBuffer,
+		map[string]string{"Content-Type": "application/json"})
+	if err != nil {
 		return 0, 0, 0, fmt.Errorf("failed to get geolocation data from API: %w", err)
 	}
 
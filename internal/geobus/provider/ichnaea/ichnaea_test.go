@@ -137,6 +137,25 @@ func TestGeolocationICHNAEAProvider_locate(t *testing.T) {
 			t.Fatal("expected locate to fail")
 		}
 	})
+	t.Run("locate fails if no location is found", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 404,
+				Body:       io.NopCloser(strings.NewReader(`{"error":{"code":404,"message":"Not found"}}`)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client := http.New(logger.New(slog.LevelInfo))
+		client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+		provider, err := NewGeolocationICHNAEAProvider(client)
+		if err != nil {
+			t.Fatalf("failed to create ICHNAEA provider: %s", err)
+		}
+
+		if _, _, _, err = provider.locate(t.Context()); err == nil {
+			t.Error("expected locate to fail")
+		}
+	})
 }
 
 func TestGeolocationICHNAEAProvider_LookupStream(t *testing.T) {
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geobus
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is the time WatchFile waits for further changes of a file before it reports the
+// change, since editors often write a file in several steps.
+const DefaultWatchDebounce = time.Second
+
+// WatchFile watches the file at the given path and returns a channel that receives a value once the file
+// was created, written or replaced, and no further change followed within the debounce interval. Instead
+// of the file itself, its directory is watched, so that the watch survives the file being removed or
+// replaced atomically by renaming another file over it, like many editors save files. The watch ends with
+// the context. It fails if the directory does not exist or the file system does not support watching,
+// e. g. on some network file systems, in which case the caller has to poll the file instead.
+func WatchFile(ctx context.Context, path string, debounce time.Duration) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	path = filepath.Clean(path)
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("failed to watch directory of %q: %w", path, err)
+	}
+
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				timer.Reset(debounce)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-timer.C:
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changed, nil
+}
@@ -7,10 +7,13 @@ package geocode
 import (
 	"context"
 	"math"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
 )
 
 // coordPrecision is the precision used to quantize coordinates (0.01 degrees ≈ 1.1 km)
@@ -20,6 +23,8 @@ type reverseKey struct {
 	Provider string
 	LatQ     int32
 	LonQ     int32
+	// Detail keeps the coarse addresses of inaccurate geolocations apart from the precise ones
+	Detail Detail `json:",omitempty"`
 }
 
 type reverseCacheEntry struct {
@@ -32,36 +37,68 @@ type searchCacheEntry struct {
 	Expiry time.Time
 }
 
+// searchCall is an in-flight upstream Search request that concurrent callers for the same key wait on.
+type searchCall struct {
+	done   chan struct{}
+	coords geobus.Coordinate
+	err    error
+}
+
 type CachedGeocoder struct {
-	coder   Geocoder
-	ttlHit  time.Duration
-	ttlMiss time.Duration
+	coder         Geocoder
+	ttlHit        time.Duration
+	ttlMiss       time.Duration
+	searchTTLHit  time.Duration
+	searchTTLMiss time.Duration
+	clock         clock.Clock
 
 	mu           sync.RWMutex
 	reverseCache map[reverseKey]reverseCacheEntry
 	searchCache  map[string]searchCacheEntry
+
+	flightMu     sync.Mutex
+	searchFlight map[string]*searchCall
+
+	// Optional disk persistence
+	fileMu sync.Mutex
+	path   string
+	log    *logger.Logger
 }
 
 func NewCachedGeocoder(coder Geocoder, ttlHit, ttlMiss time.Duration) *CachedGeocoder {
 	return &CachedGeocoder{
-		coder:        coder,
-		ttlHit:       ttlHit,
-		ttlMiss:      ttlMiss,
-		reverseCache: make(map[reverseKey]reverseCacheEntry),
-		searchCache:  make(map[string]searchCacheEntry),
+		coder:         coder,
+		ttlHit:        ttlHit,
+		ttlMiss:       ttlMiss,
+		searchTTLHit:  ttlHit,
+		searchTTLMiss: ttlMiss,
+		clock:         clock.Real{},
+		reverseCache:  make(map[reverseKey]reverseCacheEntry),
+		searchCache:   make(map[string]searchCacheEntry),
+		searchFlight:  make(map[string]*searchCall),
 	}
 }
 
+// SetSearchTTL overrides the hit and miss TTLs used for cached Search results. By default, Search
+// results use the same TTLs as Reverse results.
+func (c *CachedGeocoder) SetSearchTTL(ttlHit, ttlMiss time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.searchTTLHit = ttlHit
+	c.searchTTLMiss = ttlMiss
+}
+
 func (c *CachedGeocoder) Name() string {
 	return "geocoder cache using " + c.coder.Name()
 }
 
 func (c *CachedGeocoder) Reverse(ctx context.Context, coords geobus.Coordinate) (Address, error) {
 	key := newKey(c.coder.Name(), coords.Lat, coords.Lon)
+	key.Detail = DetailForAccuracy(coords.Acc)
 
 	c.mu.RLock()
 	entry, ok := c.reverseCache[key]
-	if ok && time.Now().Before(entry.Expiry) {
+	if ok && c.clock.Now().Before(entry.Expiry) {
 		addr := entry.Address
 		c.mu.RUnlock()
 		addr.CacheHit = true
@@ -83,16 +120,22 @@ func (c *CachedGeocoder) Reverse(ctx context.Context, coords geobus.Coordinate)
 	}
 	c.reverseCache[key] = reverseCacheEntry{
 		Address: addr,
-		Expiry:  time.Now().Add(ttl),
+		Expiry:  c.clock.Now().Add(ttl),
 	}
+	c.persistOrLog()
 
 	return addr, nil
 }
 
-func (c *CachedGeocoder) Search(ctx context.Context, key string) (geobus.Coordinate, error) {
+// Search returns the coordinates for the given address. Results are cached by a normalized form of the
+// query, so that queries that only differ in case or whitespace share the same cache entry. Concurrent
+// calls for the same key are coalesced into a single upstream request.
+func (c *CachedGeocoder) Search(ctx context.Context, query string) (geobus.Coordinate, error) {
+	key := newSearchKey(c.coder.Name(), query)
+
 	c.mu.RLock()
 	entry, ok := c.searchCache[key]
-	if ok && time.Now().Before(entry.Expiry) {
+	if ok && c.clock.Now().Before(entry.Expiry) {
 		coords := entry.Coords
 		c.mu.RUnlock()
 		coords.CacheHit = true
@@ -100,7 +143,33 @@ func (c *CachedGeocoder) Search(ctx context.Context, key string) (geobus.Coordin
 	}
 	c.mu.RUnlock()
 
-	coords, err := c.coder.Search(ctx, key)
+	c.flightMu.Lock()
+	if call, ok := c.searchFlight[key]; ok {
+		c.flightMu.Unlock()
+		select {
+		case <-call.done:
+			return call.coords, call.err
+		case <-ctx.Done():
+			return geobus.Coordinate{}, ctx.Err()
+		}
+	}
+	call := &searchCall{done: make(chan struct{})}
+	c.searchFlight[key] = call
+	c.flightMu.Unlock()
+
+	call.coords, call.err = c.search(ctx, key, query)
+
+	c.flightMu.Lock()
+	delete(c.searchFlight, key)
+	c.flightMu.Unlock()
+	close(call.done)
+
+	return call.coords, call.err
+}
+
+// search performs the upstream Search request and stores the result in the cache.
+func (c *CachedGeocoder) search(ctx context.Context, key, query string) (geobus.Coordinate, error) {
+	coords, err := c.coder.Search(ctx, query)
 	if err != nil {
 		return coords, err
 	}
@@ -108,18 +177,27 @@ func (c *CachedGeocoder) Search(ctx context.Context, key string) (geobus.Coordin
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	ttl := c.ttlHit
+	ttl := c.searchTTLHit
 	if !coords.Found {
-		ttl = c.ttlMiss
+		ttl = c.searchTTLMiss
 	}
 	c.searchCache[key] = searchCacheEntry{
 		Coords: coords,
-		Expiry: time.Now().Add(ttl),
+		Expiry: c.clock.Now().Add(ttl),
 	}
+	c.persistOrLog()
 
 	return coords, nil
 }
 
+// persistOrLog writes the cache to disk and logs a failure, since a failing disk cache must not break
+// the lookup itself. The caller must hold at least a read lock on the cache.
+func (c *CachedGeocoder) persistOrLog() {
+	if err := c.persist(); err != nil && c.log != nil {
+		c.log.Error("failed to persist geocode disk cache", logger.Err(err))
+	}
+}
+
 func quantizeCoord(val float64) int32 {
 	return int32(math.Round(val / coordPrecision))
 }
@@ -131,3 +209,9 @@ func newKey(provider string, lat, lon float64) reverseKey {
 		LonQ:     quantizeCoord(lon),
 	}
 }
+
+// newSearchKey returns the cache key for a Search query. The query is lowercased and consecutive
+// whitespace is collapsed into a single space.
+func newSearchKey(provider, query string) string {
+	return provider + ":" + strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -8,15 +8,18 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/geobus"
 )
 
 const (
-	testHitTTL  = 200 * time.Millisecond
-	testMissTTL = 200 * time.Millisecond
+	testHitTTL  = time.Hour
+	testMissTTL = 10 * time.Minute
 )
 
 var testCoords = geobus.Coordinate{Lat: 52.5129, Lon: 13.3910}
@@ -36,6 +39,14 @@ var testAddress = Address{
 
 type (
 	mockCache struct{}
+
+	// blockingCoder counts upstream Search calls and blocks them until release is closed
+	blockingCoder struct {
+		mockCache
+		calls   atomic.Int32
+		started chan struct{}
+		release chan struct{}
+	}
 )
 
 func (c *mockCache) Name() string { return "mock" }
@@ -64,6 +75,14 @@ func (c *mockCache) Search(_ context.Context, address string) (geobus.Coordinate
 	return coords, nil
 }
 
+func (c *blockingCoder) Search(ctx context.Context, address string) (geobus.Coordinate, error) {
+	if c.calls.Add(1) == 1 {
+		close(c.started)
+	}
+	<-c.release
+	return c.mockCache.Search(ctx, address)
+}
+
 func TestNewCachedGeocoder(t *testing.T) {
 	t.Run("a new geocoder should be returned", func(t *testing.T) {
 		coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
@@ -76,8 +95,29 @@ func TestNewCachedGeocoder(t *testing.T) {
 	})
 }
 
+func TestDetailForAccuracy(t *testing.T) {
+	tests := []struct {
+		accuracy float64
+		want     Detail
+	}{
+		{0, DetailStreet},
+		{999, DetailStreet},
+		{1000, DetailSuburb},
+		{9999, DetailSuburb},
+		{10000, DetailCity},
+		{300000, DetailCity},
+	}
+	for _, tc := range tests {
+		if got := DetailForAccuracy(tc.accuracy); got != tc.want {
+			t.Errorf("expected detail for accuracy %g to be %d, got %d", tc.accuracy, tc.want, got)
+		}
+	}
+}
+
 func TestCachedGeocoder_Reverse(t *testing.T) {
+	fakeClock := clock.NewFake(time.Now())
 	coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+	coder.clock = fakeClock
 	t.Run("a cached address should be returned", func(t *testing.T) {
 		addr, err := coder.Reverse(t.Context(), testCoords)
 		if err != nil {
@@ -150,6 +190,20 @@ func TestCachedGeocoder_Reverse(t *testing.T) {
 			t.Errorf("expected address to be %q, got %q", testAddress.DisplayName, addr.DisplayName)
 		}
 	})
+	t.Run("a coarse geolocation doesn't share the cache entry of a precise one", func(t *testing.T) {
+		if _, err := coder.Reverse(t.Context(), testCoords); err != nil {
+			t.Fatal(err)
+		}
+		coarse := testCoords
+		coarse.Acc = 300000
+		addr, err := coder.Reverse(t.Context(), coarse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.CacheHit {
+			t.Error("expected cache miss")
+		}
+	})
 	t.Run("fetching an unknown address causes a cache miss", func(t *testing.T) {
 		addr, err := coder.Reverse(t.Context(), geobus.Coordinate{Lat: 2, Lon: -2})
 		if err != nil {
@@ -176,7 +230,7 @@ func TestCachedGeocoder_Reverse(t *testing.T) {
 		if !strings.EqualFold(addr.DisplayName, testAddress.DisplayName) {
 			t.Errorf("expected address to be %q, got %q", testAddress.DisplayName, addr.DisplayName)
 		}
-		time.Sleep(testHitTTL * 2)
+		fakeClock.Advance(testHitTTL * 2)
 		addr, err = coder.Reverse(t.Context(), testCoords)
 		if err != nil {
 			t.Fatal(err)
@@ -196,7 +250,7 @@ func TestCachedGeocoder_Reverse(t *testing.T) {
 		if !strings.EqualFold(addr.DisplayName, testAddress.DisplayName) {
 			t.Errorf("expected address to be %q, got %q", testAddress.DisplayName, addr.DisplayName)
 		}
-		time.Sleep(testHitTTL - 5*time.Millisecond)
+		fakeClock.Advance(testHitTTL - time.Second)
 		addr, err = coder.Reverse(t.Context(), testCoords)
 		if err != nil {
 			t.Fatal(err)
@@ -211,7 +265,9 @@ func TestCachedGeocoder_Reverse(t *testing.T) {
 }
 
 func TestCachedGeocoder_Search(t *testing.T) {
+	fakeClock := clock.NewFake(time.Now())
 	coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+	coder.clock = fakeClock
 	t.Run("cached coordinates should be returned", func(t *testing.T) {
 		coords, err := coder.Search(t.Context(), "10117 Berlin")
 		if err != nil {
@@ -285,7 +341,7 @@ func TestCachedGeocoder_Search(t *testing.T) {
 		if coords.Lon != testCoords.Lon {
 			t.Errorf("expected longitude to be %f, got %f", testCoords.Lon, coords.Lon)
 		}
-		time.Sleep(testHitTTL * 2)
+		fakeClock.Advance(testHitTTL * 2)
 		coords, err = coder.Search(t.Context(), testAddress.DisplayName)
 		if err != nil {
 			t.Fatal(err)
@@ -311,7 +367,7 @@ func TestCachedGeocoder_Search(t *testing.T) {
 		if coords.Lon != testCoords.Lon {
 			t.Errorf("expected longitude to be %f, got %f", testCoords.Lon, coords.Lon)
 		}
-		time.Sleep(testHitTTL - 5*time.Millisecond)
+		fakeClock.Advance(testHitTTL - time.Second)
 		coords, err = coder.Search(t.Context(), testAddress.DisplayName)
 		if err != nil {
 			t.Fatal(err)
@@ -326,4 +382,120 @@ func TestCachedGeocoder_Search(t *testing.T) {
 			t.Errorf("expected longitude to be %f, got %f", testCoords.Lon, coords.Lon)
 		}
 	})
+	t.Run("queries differing in case and whitespace should hit the cache", func(t *testing.T) {
+		coords, err := coder.Search(t.Context(), "10117 Berlin, Germany")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coords.CacheHit {
+			t.Fatal("expected cache miss")
+		}
+		coords, err = coder.Search(t.Context(), "  10117   BERLIN,\tgermany ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.CacheHit {
+			t.Error("expected cache hit")
+		}
+		if !coords.Found {
+			t.Error("expected coordinates to be found")
+		}
+	})
+}
+
+func TestCachedGeocoder_SetSearchTTL(t *testing.T) {
+	t.Run("search results use their own TTL", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Now())
+		coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+		coder.clock = fakeClock
+		coder.SetSearchTTL(testHitTTL*24, testMissTTL)
+		if _, err := coder.Search(t.Context(), "10117 Berlin"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := coder.Reverse(t.Context(), testCoords); err != nil {
+			t.Fatal(err)
+		}
+		fakeClock.Advance(testHitTTL * 2)
+
+		coords, err := coder.Search(t.Context(), "10117 Berlin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.CacheHit {
+			t.Error("expected search cache hit")
+		}
+		addr, err := coder.Reverse(t.Context(), testCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.CacheHit {
+			t.Error("expected reverse cache miss")
+		}
+	})
+	t.Run("search misses use their own TTL", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Now())
+		coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+		coder.clock = fakeClock
+		coder.SetSearchTTL(testHitTTL, time.Minute)
+		if _, err := coder.Search(t.Context(), "unknown"); err != nil {
+			t.Fatal(err)
+		}
+		fakeClock.Advance(time.Minute * 2)
+		coords, err := coder.Search(t.Context(), "unknown")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coords.CacheHit {
+			t.Error("expected cache miss")
+		}
+	})
+}
+
+func TestCachedGeocoder_Search_singleflight(t *testing.T) {
+	t.Run("concurrent searches for the same key perform a single upstream request", func(t *testing.T) {
+		upstream := &blockingCoder{started: make(chan struct{}), release: make(chan struct{})}
+		coder := NewCachedGeocoder(upstream, testHitTTL, testMissTTL)
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			if _, err := coder.Search(t.Context(), "10117 Berlin"); err != nil {
+				t.Errorf("failed to search: %s", err)
+			}
+		})
+		<-upstream.started
+		for range 10 {
+			wg.Go(func() {
+				coords, err := coder.Search(t.Context(), "10117  berlin")
+				if err != nil {
+					t.Errorf("failed to search: %s", err)
+				}
+				if !coords.Found {
+					t.Error("expected coordinates to be found")
+				}
+			})
+		}
+
+		// Give the waiting callers a chance to join the in-flight request before releasing it
+		time.Sleep(50 * time.Millisecond)
+		close(upstream.release)
+		wg.Wait()
+
+		if calls := upstream.calls.Load(); calls != 1 {
+			t.Errorf("expected 1 upstream request, got %d", calls)
+		}
+	})
+	t.Run("waiting callers return on context cancellation", func(t *testing.T) {
+		upstream := &blockingCoder{started: make(chan struct{}), release: make(chan struct{})}
+		coder := NewCachedGeocoder(upstream, testHitTTL, testMissTTL)
+		defer close(upstream.release)
+
+		go func() { _, _ = coder.Search(context.Background(), "10117 Berlin") }()
+		<-upstream.started
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		if _, err := coder.Search(ctx, "10117 Berlin"); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context canceled error, got: %v", err)
+		}
+	})
 }
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geocode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// diskCacheVersion is the version of the on-disk cache format. Files with a different version are discarded.
+const diskCacheVersion = 1
+
+// diskCache represents the on-disk format of the geocode cache.
+type diskCache struct {
+	Version int                         `json:"version"`
+	Reverse []diskReverseEntry          `json:"reverse"`
+	Search  map[string]searchCacheEntry `json:"search"`
+}
+
+// diskReverseEntry is a reverse cache entry with its key, since struct keys can't be used as JSON map keys.
+type diskReverseEntry struct {
+	Key   reverseKey        `json:"key"`
+	Entry reverseCacheEntry `json:"entry"`
+}
+
+// NewDiskCachedGeocoder returns a CachedGeocoder that persists its cache entries to the file at the given
+// path. Existing, non-expired entries are loaded from the file on construction. The file is updated on
+// every cache write and when Flush is called. If the file is corrupt, it is discarded with a warning.
+func NewDiskCachedGeocoder(coder Geocoder, ttlHit, ttlMiss time.Duration, path string, log *logger.Logger) *CachedGeocoder {
+	cache := NewCachedGeocoder(coder, ttlHit, ttlMiss)
+	cache.path = path
+	cache.log = log
+
+	if err := cache.load(); err != nil {
+		log.Warn("discarding geocode disk cache", logger.Err(err), slog.String("path", path))
+		if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error("failed to remove geocode disk cache", logger.Err(err), slog.String("path", path))
+		}
+	}
+	return cache
+}
+
+// Flush writes the current cache entries to disk. It is a no-op if the cache is not disk-backed.
+func (c *CachedGeocoder) Flush() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.persist()
+}
+
+// load reads the cache entries from disk. A missing file is not considered an error. Expired entries
+// are skipped.
+func (c *CachedGeocoder) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read geocode disk cache: %w", err)
+	}
+
+	var stored diskCache
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode geocode disk cache: %w", err)
+	}
+	if stored.Version != diskCacheVersion {
+		return fmt.Errorf("unsupported geocode disk cache version: %d", stored.Version)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	for _, entry := range stored.Reverse {
+		if now.Before(entry.Entry.Expiry) {
+			c.reverseCache[entry.Key] = entry.Entry
+		}
+	}
+	for key, entry := range stored.Search {
+		if now.Before(entry.Expiry) {
+			c.searchCache[key] = entry
+		}
+	}
+	return nil
+}
+
+// persist writes the cache entries to disk. The caller must hold at least a read lock on the cache.
+// The file is written to a temporary file first and then renamed, so that a crash does not leave a
+// partially written cache file behind.
+func (c *CachedGeocoder) persist() error {
+	if c.path == "" {
+		return nil
+	}
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	stored := diskCache{
+		Version: diskCacheVersion,
+		Reverse: make([]diskReverseEntry, 0, len(c.reverseCache)),
+		Search:  make(map[string]searchCacheEntry, len(c.searchCache)),
+	}
+	for key, entry := range c.reverseCache {
+		stored.Reverse = append(stored.Reverse, diskReverseEntry{Key: key, Entry: entry})
+	}
+	for key, entry := range c.searchCache {
+		stored.Search[key] = entry
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode geocode disk cache: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create geocode disk cache directory: %w", err)
+	}
+	tmpFile := c.path + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write geocode disk cache: %w", err)
+	}
+	if err = os.Rename(tmpFile, c.path); err != nil {
+		return fmt.Errorf("failed to replace geocode disk cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package geocode
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/clock"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+func TestNewDiskCachedGeocoder(t *testing.T) {
+	t.Run("cache entries survive a restart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cache", "geocode-cache.json")
+		log := logger.NewLogger(slog.LevelDebug, bytes.NewBuffer(nil), nil)
+		coder := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		if _, err := coder.Reverse(t.Context(), testCoords); err != nil {
+			t.Fatalf("failed to reverse geocode: %s", err)
+		}
+		if _, err := coder.Search(t.Context(), "10117 Berlin"); err != nil {
+			t.Fatalf("failed to search: %s", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected cache file to exist: %s", err)
+		}
+
+		restarted := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		addr, err := restarted.Reverse(t.Context(), testCoords)
+		if err != nil {
+			t.Fatalf("failed to reverse geocode: %s", err)
+		}
+		if !addr.CacheHit {
+			t.Error("expected cache hit after restart")
+		}
+		if addr.DisplayName != testAddress.DisplayName {
+			t.Errorf("expected address to be %q, got %q", testAddress.DisplayName, addr.DisplayName)
+		}
+		coords, err := restarted.Search(t.Context(), "10117 Berlin")
+		if err != nil {
+			t.Fatalf("failed to search: %s", err)
+		}
+		if !coords.CacheHit {
+			t.Error("expected cache hit after restart")
+		}
+	})
+	t.Run("expired entries are not loaded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "geocode-cache.json")
+		log := logger.NewLogger(slog.LevelDebug, bytes.NewBuffer(nil), nil)
+		coder := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		if _, err := coder.Reverse(t.Context(), testCoords); err != nil {
+			t.Fatalf("failed to reverse geocode: %s", err)
+		}
+
+		restarted := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+		restarted.path = path
+		restarted.clock = clock.NewFake(time.Now().Add(testHitTTL * 2))
+		if err := restarted.load(); err != nil {
+			t.Fatalf("failed to load cache: %s", err)
+		}
+		if len(restarted.reverseCache) != 0 {
+			t.Errorf("expected no entries to be loaded, got %d", len(restarted.reverseCache))
+		}
+	})
+	t.Run("corrupt cache file is discarded with a warning", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "geocode-cache.json")
+		if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+			t.Fatalf("failed to write corrupt cache file: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		log := logger.NewLogger(slog.LevelDebug, buf, nil)
+		coder := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		if coder == nil {
+			t.Fatal("expected a non-nil geocoder")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected corrupt cache file to be removed, got: %v", err)
+		}
+		wantLog := `level=WARN msg="discarding geocode disk cache"`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+	})
+	t.Run("cache file with unknown version is discarded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "geocode-cache.json")
+		if err := os.WriteFile(path, []byte(`{"version":999}`), 0o600); err != nil {
+			t.Fatalf("failed to write cache file: %s", err)
+		}
+		log := logger.NewLogger(slog.LevelDebug, bytes.NewBuffer(nil), nil)
+		_ = NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected cache file to be removed, got: %v", err)
+		}
+	})
+	t.Run("concurrent access is safe", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "geocode-cache.json")
+		log := logger.NewLogger(slog.LevelDebug, bytes.NewBuffer(nil), nil)
+		coder := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Go(func() {
+				coords := geobus.Coordinate{Lat: float64(i), Lon: float64(i)}
+				if _, err := coder.Reverse(t.Context(), coords); err != nil {
+					t.Errorf("failed to reverse geocode: %s", err)
+				}
+				if err := coder.Flush(); err != nil {
+					t.Errorf("failed to flush cache: %s", err)
+				}
+			})
+		}
+		wg.Wait()
+
+		restarted := NewDiskCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL, path, log)
+		if len(restarted.reverseCache) != 10 {
+			t.Errorf("expected 10 entries to be loaded, got %d", len(restarted.reverseCache))
+		}
+	})
+}
+
+func TestCachedGeocoder_Flush(t *testing.T) {
+	t.Run("flushing an in-memory cache is a no-op", func(t *testing.T) {
+		coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+		if err := coder.Flush(); err != nil {
+			t.Errorf("expected flush to succeed, got: %s", err)
+		}
+	})
+	t.Run("flushing to an unwritable path fails", func(t *testing.T) {
+		dir := t.TempDir()
+		blocker := filepath.Join(dir, "file")
+		if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+		coder := NewCachedGeocoder(&mockCache{}, testHitTTL, testMissTTL)
+		coder.path = filepath.Join(blocker, "geocode-cache.json")
+		if err := coder.Flush(); err == nil {
+			t.Error("expected flush to fail")
+		}
+	})
+}
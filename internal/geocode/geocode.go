@@ -19,6 +19,7 @@ type Address struct {
 	Altitude     float64
 	DisplayName  string
 	Country      string
+	CountryCode  string
 	State        string
 	Municipality string
 	CityDistrict string
@@ -27,6 +28,32 @@ type Address struct {
 	Suburb       string
 	Street       string
 	HouseNumber  string
+	// Label is the name of the geofence that the coordinates are in, if any. It replaces City and
+	// DisplayName in the templates.
+	Label string
+}
+
+// Detail is the level of detail of a reverse geocoded address. Providers that support it request a less
+// detailed address for coarse geolocations, e. g. a GeoIP fix, whose street would be misleading.
+type Detail int
+
+const (
+	DetailStreet Detail = iota
+	DetailSuburb
+	DetailCity
+)
+
+// DetailForAccuracy returns the level of detail of the address that suits the accuracy radius of a
+// geolocation in meters. An unknown accuracy of zero is considered precise.
+func DetailForAccuracy(accuracy float64) Detail {
+	switch {
+	case accuracy >= 10000:
+		return DetailCity
+	case accuracy >= 1000:
+		return DetailSuburb
+	default:
+		return DetailStreet
+	}
 }
 
 type Geocoder interface {
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/text/language"
@@ -92,13 +93,10 @@ func (g *GeocodeEarth) Reverse(ctx context.Context, coords geobus.Coordinate) (g
 	query.Set("point.lon", fmt.Sprintf("%f", coords.Lon))
 	query.Set("lang", g.lang.String())
 
-	code, err := g.http.GetWithTimeout(ctx, reverseAPIEndpoint, &response, query, nil, APITimeout)
+	_, err := g.http.GetWithTimeout(ctx, reverseAPIEndpoint, &response, query, nil, APITimeout)
 	if err != nil {
 		return geocode.Address{}, fmt.Errorf("failed to retrieve address details from geocode.earth API: %w", err)
 	}
-	if code != 200 {
-		return geocode.Address{}, fmt.Errorf("received non-positive response code from geocode.earth API: %d", code)
-	}
 	if len(response.Features) < 1 {
 		return geocode.Address{}, fmt.Errorf("no address found for coordinates")
 	}
@@ -111,6 +109,7 @@ func (g *GeocodeEarth) Reverse(ctx context.Context, coords geobus.Coordinate) (g
 		Longitude:    coords.Lon,
 		DisplayName:  result.DisplayName,
 		Country:      result.Country,
+		CountryCode:  strings.ToUpper(result.CountryCode),
 		State:        result.State,
 		Municipality: result.Municipality,
 		CityDistrict: result.CityDistrict,
@@ -131,13 +130,10 @@ func (g *GeocodeEarth) Search(ctx context.Context, address string) (geobus.Coord
 	query.Set("text", address)
 	query.Set("lang", g.lang.String())
 
-	code, err := g.http.GetWithTimeout(ctx, searchAPIEndpoint, &response, query, nil, APITimeout)
+	_, err := g.http.GetWithTimeout(ctx, searchAPIEndpoint, &response, query, nil, APITimeout)
 	if err != nil {
 		return geobus.Coordinate{}, fmt.Errorf("failed to retrieve address details from geocode.earth API: %w", err)
 	}
-	if code != 200 {
-		return geobus.Coordinate{}, fmt.Errorf("received non-positive response code from geocode.earth API: %d", code)
-	}
 	if len(response.Features) < 1 {
 		return geobus.Coordinate{}, fmt.Errorf("no coordinates found for address %q", address)
 	}
@@ -22,6 +22,7 @@ import (
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
 )
 
@@ -89,6 +90,9 @@ func TestGeocodeEarth_Reverse(t *testing.T) {
 		if !strings.EqualFold(addr.DisplayName, cityExpected) {
 			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
 		}
+		if addr.CountryCode != "DE" {
+			t.Errorf("expected country code to be %q, got %q", "DE", addr.CountryCode)
+		}
 	})
 	t.Run("reverse cached geocoding succeeds", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -185,6 +189,9 @@ func TestGeocodeEarth_Reverse(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("API responding with more than one result should fail", func(t *testing.T) {
 		response := ReverseResponse{Features: []ReverseFeature{}}
@@ -237,6 +244,9 @@ func TestGeocodeEarth_Reverse(t *testing.T) {
 		if !strings.EqualFold(err.Error(), wantErr) {
 			t.Errorf("expected error to be %q, got %q", wantErr, err)
 		}
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
 	})
 }
 
@@ -280,6 +290,9 @@ func TestGeocodeEarth_Search(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("API responding with a non-200 reponse", func(t *testing.T) {
 		response := SearchResponse{Features: []SearchFeature{{Geometry: Geometry{
@@ -308,6 +321,9 @@ func TestGeocodeEarth_Search(t *testing.T) {
 		if !strings.EqualFold(err.Error(), wantErr) {
 			t.Errorf("expected error to be %q, got %q", wantErr, err)
 		}
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
 	})
 	t.Run("forward geocoding returning empty array fails", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
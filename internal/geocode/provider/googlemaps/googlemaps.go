@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package googlemaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+)
+
+const (
+	apiEndpoint = "https://maps.googleapis.com/maps/api/geocode/json"
+	APITimeout  = time.Second * 10
+	name        = "google"
+)
+
+// Status values returned by the Google Maps Geocoding API
+const (
+	statusOK             = "OK"
+	statusZeroResults    = "ZERO_RESULTS"
+	statusRequestDenied  = "REQUEST_DENIED"
+	statusOverQueryLimit = "OVER_QUERY_LIMIT"
+)
+
+var (
+	// ErrZeroResults is returned if the API did not find any result for the request
+	ErrZeroResults = errors.New("no results returned by Google Maps API")
+	// ErrRequestDenied is returned if the API rejected the request, usually due to an invalid API key
+	ErrRequestDenied = providererr.Wrap(providererr.ErrAuth, errors.New("request denied by Google Maps API"))
+	// ErrOverQueryLimit is returned if the API key exceeded its quota
+	ErrOverQueryLimit = providererr.Wrap(providererr.ErrRateLimited,
+		errors.New("query limit exceeded for Google Maps API"))
+)
+
+type GoogleMaps struct {
+	apikey string
+	http   *http.Client
+	lang   language.Tag
+}
+
+type Response struct {
+	Results      []Result `json:"results"`
+	Status       string   `json:"status"`
+	ErrorMessage string   `json:"error_message"`
+}
+
+type Result struct {
+	AddressComponents []AddressComponent `json:"address_components"`
+	DisplayName       string             `json:"formatted_address"`
+	Geometry          Geometry           `json:"geometry"`
+	PlaceID           string             `json:"place_id"`
+	Types             []string           `json:"types"`
+}
+
+type AddressComponent struct {
+	LongName  string   `json:"long_name"`
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+type Geometry struct {
+	Location Location `json:"location"`
+}
+
+type Location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lng"`
+}
+
+func New(client *http.Client, lang language.Tag, apikey string) *GoogleMaps {
+	return &GoogleMaps{
+		apikey: apikey,
+		lang:   lang,
+		http:   client,
+	}
+}
+
+func (g *GoogleMaps) Name() string {
+	return name
+}
+
+func (g *GoogleMaps) Reverse(ctx context.Context, coords geobus.Coordinate) (geocode.Address, error) {
+	var response Response
+
+	query := url.Values{}
+	query.Set("key", g.apikey)
+	query.Set("latlng", fmt.Sprintf("%f,%f", coords.Lat, coords.Lon))
+	query.Set("language", g.lang.String())
+
+	_, err := g.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
+	if err != nil {
+		return geocode.Address{}, fmt.Errorf("failed to retrieve address details from Google Maps API: %w", err)
+	}
+	if err = response.err(); err != nil {
+		return geocode.Address{}, err
+	}
+	if len(response.Results) < 1 {
+		return geocode.Address{}, ErrZeroResults
+	}
+
+	// Fill the geocode.Address struct
+	result := response.Results[0]
+	address := geocode.Address{
+		AddressFound: true,
+		Latitude:     result.Geometry.Location.Lat,
+		Longitude:    result.Geometry.Location.Lon,
+		DisplayName:  result.DisplayName,
+	}
+	for _, component := range result.AddressComponents {
+		for _, componentType := range component.Types {
+			switch componentType {
+			case "country":
+				address.Country = component.LongName
+				address.CountryCode = strings.ToUpper(component.ShortName)
+			case "administrative_area_level_1":
+				address.State = component.LongName
+			case "administrative_area_level_2":
+				address.Municipality = component.LongName
+			case "sublocality_level_1":
+				address.CityDistrict = component.LongName
+			case "sublocality_level_2", "neighborhood":
+				if address.Suburb == "" {
+					address.Suburb = component.LongName
+				}
+			case "postal_code":
+				address.Postcode = component.LongName
+			case "locality":
+				address.City = component.LongName
+			case "route":
+				address.Street = component.LongName
+			case "street_number":
+				address.HouseNumber = component.LongName
+			}
+		}
+	}
+
+	// In some countries (i. e. the UK) Google does not always return a locality, but only the
+	// postal town the address belongs to.
+	if address.City == "" {
+		address.City = result.component("postal_town")
+	}
+
+	return address, nil
+}
+
+func (g *GoogleMaps) Search(ctx context.Context, address string) (geobus.Coordinate, error) {
+	var response Response
+
+	query := url.Values{}
+	query.Set("key", g.apikey)
+	query.Set("address", address)
+	query.Set("language", g.lang.String())
+
+	_, err := g.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
+	if err != nil {
+		return geobus.Coordinate{}, fmt.Errorf("failed to retrieve address details from Google Maps API: %w", err)
+	}
+	if err = response.err(); err != nil {
+		return geobus.Coordinate{}, err
+	}
+	if len(response.Results) < 1 {
+		return geobus.Coordinate{}, fmt.Errorf("no coordinates returned for address: %q", address)
+	}
+
+	// Fill the geobus.Coordinate struct
+	result := response.Results[0].Geometry.Location
+	coords := geobus.Coordinate{
+		Lat:   result.Lat,
+		Lon:   result.Lon,
+		Found: true,
+	}
+
+	return coords, nil
+}
+
+// err translates the status field of the API response into an error. The Google Maps API
+// reports most failures with a 200 response code, so the status needs to be checked explicitly.
+func (r Response) err() error {
+	var err error
+	switch r.Status {
+	case statusOK:
+		return nil
+	case statusZeroResults:
+		return ErrZeroResults
+	case statusRequestDenied:
+		err = ErrRequestDenied
+	case statusOverQueryLimit:
+		err = ErrOverQueryLimit
+	default:
+		err = providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("unexpected status returned by Google Maps API: %q", r.Status))
+	}
+	if r.ErrorMessage != "" {
+		return fmt.Errorf("%w: %s", err, r.ErrorMessage)
+	}
+	return err
+}
+
+// component returns the long name of the first address component of the given type
+func (r Result) component(componentType string) string {
+	for _, component := range r.AddressComponents {
+		for _, t := range component.Types {
+			if t == componentType {
+				return component.LongName
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,418 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package googlemaps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	stdhttp "net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/testhelper"
+)
+
+const (
+	cityExpected    = "Friedrichstraße 67, 10117 Berlin, Germany"
+	cityFile        = "../../../../testdata/googlemaps_berlin.json"
+	cityForwardFile = "../../../../testdata/googlemaps_berlin_forward.json"
+	testAPIKey      = "test-api-key"
+	testHitTTL      = 1 * time.Second
+	testMissTTL     = 1 * time.Second
+
+	villageExpected = "Marshfield"
+	villageFile     = "../../../../testdata/googlemaps_marshfield.json"
+
+	townExpected = "Otley"
+	townFile     = "../../../../testdata/googlemaps_otley.json"
+)
+
+var (
+	cityCoords    = geobus.Coordinate{Lat: 52.5129, Lon: 13.3910}
+	cityForward   = geobus.Coordinate{Lat: 52.512274, Lon: 13.390617}
+	villageCoords = geobus.Coordinate{Lat: 51.46292, Lon: -2.31850}
+	townCoords    = geobus.Coordinate{Lat: 53.90712, Lon: -1.69404}
+)
+
+func TestNew(t *testing.T) {
+	t.Run("creating a new provider succeeds", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, nil)
+		if coder == nil {
+			t.Fatal("expected a non-nil geocoder")
+		}
+	})
+	t.Run("provider name is correct", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, nil)
+		if coder.Name() != name {
+			t.Errorf("expected provider name to be %q, got %q", name, coder.Name())
+		}
+	})
+}
+
+func TestGoogleMaps_Reverse(t *testing.T) {
+	t.Run("reverse geocoding returns the correct city", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			file   string
+			coords geobus.Coordinate
+			want   string
+		}{
+			{"city", cityFile, cityCoords, "Berlin"},
+			{"town", townFile, townCoords, townExpected},
+			{"village", villageFile, villageCoords, villageExpected},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				coder := testCoderWithRoundtripFunc(t, language.English, fileResponse(t, tc.file))
+				addr, err := coder.Reverse(t.Context(), tc.coords)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !addr.AddressFound {
+					t.Fatal("expected address to be found")
+				}
+				if !strings.EqualFold(addr.City, tc.want) {
+					t.Errorf("expected city to be %q, got %q", tc.want, addr.City)
+				}
+			})
+		}
+	})
+	t.Run("reverse geocoding maps the address components", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, fileResponse(t, cityFile))
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(addr.DisplayName, cityExpected) {
+			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
+		}
+		want := geocode.Address{
+			AddressFound: true,
+			Latitude:     cityCoords.Lat,
+			Longitude:    cityCoords.Lon,
+			DisplayName:  cityExpected,
+			Country:      "Germany",
+			CountryCode:  "DE",
+			State:        "Berlin",
+			CityDistrict: "Mitte",
+			Postcode:     "10117",
+			City:         "Berlin",
+			Street:       "Friedrichstraße",
+			HouseNumber:  "67",
+		}
+		if addr != want {
+			t.Errorf("expected address to be %+v, got %+v", want, addr)
+		}
+	})
+	t.Run("reverse geocoding sends API key, coordinates and language", func(t *testing.T) {
+		var query map[string][]string
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			query = req.URL.Query()
+			return fileResponse(t, cityFile)(req)
+		}
+		coder := testCoderWithRoundtripFunc(t, language.German, rtFn)
+		if _, err := coder.Reverse(t.Context(), cityCoords); err != nil {
+			t.Fatal(err)
+		}
+		wants := map[string]string{
+			"key":      testAPIKey,
+			"latlng":   "52.512900,13.391000",
+			"language": "de",
+		}
+		for key, want := range wants {
+			if got := query[key]; len(got) != 1 || got[0] != want {
+				t.Errorf("expected query parameter %q to be %q, got %q", key, want, got)
+			}
+		}
+	})
+	t.Run("reverse cached geocoding succeeds", func(t *testing.T) {
+		coder := geocode.NewCachedGeocoder(testCoderWithRoundtripFunc(t, language.English,
+			fileResponse(t, cityFile)), testHitTTL, testMissTTL)
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(addr.DisplayName, cityExpected) {
+			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
+		}
+		addr, err = coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !addr.CacheHit {
+			t.Error("expected cache hit")
+		}
+	})
+	t.Run("reverse geocoding fails", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("intentionally failing")
+		}
+
+		coder := testCoderWithRoundtripFunc(t, language.English, rtFn)
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if err == nil {
+			t.Fatal("expected API request to fail")
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with a non-200 reponse", func(t *testing.T) {
+		response := Response{Status: statusOK, Results: []Result{{DisplayName: cityExpected}}}
+		coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 500, response))
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "unsuccessful response status 500 Internal Server Error"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with OK but no results", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 200, Response{Status: statusOK}))
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if !errors.Is(err, ErrZeroResults) {
+			t.Errorf("expected error to be %q, got %q", ErrZeroResults, err)
+		}
+	})
+	t.Run("API responding with an error status", func(t *testing.T) {
+		for _, tc := range statusTests() {
+			t.Run(tc.status, func(t *testing.T) {
+				response := Response{Status: tc.status, ErrorMessage: tc.message}
+				coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 200, response))
+				_, err := coder.Reverse(t.Context(), cityCoords)
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected error to be %q, got %q", tc.wantErr, err)
+				}
+				if tc.wantKind != nil && !errors.Is(err, tc.wantKind) {
+					t.Errorf("expected error to be %s, got %s", tc.wantKind, err)
+				}
+				if !strings.Contains(err.Error(), tc.wantMsg) {
+					t.Errorf("expected error to contain %q, got %q", tc.wantMsg, err)
+				}
+			})
+		}
+	})
+}
+
+func TestGoogleMaps_Search(t *testing.T) {
+	t.Run("forward geocoding succeeds", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, fileResponse(t, cityForwardFile))
+		coords, err := coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected address to be found")
+		}
+		if coords.Lat != cityForward.Lat {
+			t.Errorf("expected latitude to be %f, got %f", cityForward.Lat, coords.Lat)
+		}
+		if coords.Lon != cityForward.Lon {
+			t.Errorf("expected longitude to be %f, got %f", cityForward.Lon, coords.Lon)
+		}
+	})
+	t.Run("forward geocoding sends API key, address and language", func(t *testing.T) {
+		var query map[string][]string
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			query = req.URL.Query()
+			return fileResponse(t, cityForwardFile)(req)
+		}
+		coder := testCoderWithRoundtripFunc(t, language.French, rtFn)
+		if _, err := coder.Search(t.Context(), cityExpected); err != nil {
+			t.Fatal(err)
+		}
+		wants := map[string]string{
+			"key":      testAPIKey,
+			"address":  cityExpected,
+			"language": "fr",
+		}
+		for key, want := range wants {
+			if got := query[key]; len(got) != 1 || got[0] != want {
+				t.Errorf("expected query parameter %q to be %q, got %q", key, want, got)
+			}
+		}
+	})
+	t.Run("forward geocoding fails", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("intentionally failing")
+		}
+
+		coder := testCoderWithRoundtripFunc(t, language.English, rtFn)
+		_, err := coder.Search(t.Context(), cityExpected)
+		if err == nil {
+			t.Fatal("expected API request to fail")
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with a non-200 reponse", func(t *testing.T) {
+		response := Response{Status: statusOK, Results: []Result{{DisplayName: cityExpected}}}
+		coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 400, response))
+		_, err := coder.Search(t.Context(), cityExpected)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "unsuccessful response status 400 Bad Request"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+	t.Run("API responding with OK but no results", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 200, Response{Status: statusOK}))
+		_, err := coder.Search(t.Context(), cityExpected)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "no coordinates returned for address"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+	})
+	t.Run("API responding with an error status", func(t *testing.T) {
+		for _, tc := range statusTests() {
+			t.Run(tc.status, func(t *testing.T) {
+				response := Response{Status: tc.status, ErrorMessage: tc.message}
+				coder := testCoderWithRoundtripFunc(t, language.English, jsonResponse(t, 200, response))
+				_, err := coder.Search(t.Context(), cityExpected)
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected error to be %q, got %q", tc.wantErr, err)
+				}
+				if tc.wantKind != nil && !errors.Is(err, tc.wantKind) {
+					t.Errorf("expected error to be %s, got %s", tc.wantKind, err)
+				}
+				if !strings.Contains(err.Error(), tc.wantMsg) {
+					t.Errorf("expected error to contain %q, got %q", tc.wantMsg, err)
+				}
+			})
+		}
+	})
+}
+
+func TestGoogleMaps_integration(t *testing.T) {
+	testhelper.PerformIntegrationTests(t)
+	t.Run("reverse geocoding succeeds", func(t *testing.T) {
+		coder := testCoder(t)
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !addr.AddressFound {
+			t.Fatal("expected address to be found")
+		}
+		if !strings.EqualFold(addr.City, "Berlin") {
+			t.Errorf("expected city to be %q, got %q", "Berlin", addr.City)
+		}
+	})
+	t.Run("forward geocoding succeeds", func(t *testing.T) {
+		coder := testCoder(t)
+		coords, err := coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected address to be found")
+		}
+	})
+}
+
+type statusTest struct {
+	status   string
+	message  string
+	wantErr  error
+	wantMsg  string
+	wantKind error
+}
+
+func statusTests() []statusTest {
+	return []statusTest{
+		{statusZeroResults, "", ErrZeroResults, "no results returned by Google Maps API", nil},
+		{
+			statusRequestDenied, "The provided API key is invalid.", ErrRequestDenied,
+			"request denied by Google Maps API: The provided API key is invalid.", providererr.ErrAuth,
+		},
+		{
+			statusOverQueryLimit, "You have exceeded your daily request quota for this API.", ErrOverQueryLimit,
+			"query limit exceeded for Google Maps API", providererr.ErrRateLimited,
+		},
+		{
+			"INVALID_REQUEST", "", nil, `unexpected status returned by Google Maps API: "INVALID_REQUEST"`,
+			providererr.ErrBadResponse,
+		},
+	}
+}
+
+func testCoder(t *testing.T) geocode.Geocoder {
+	testHttpClient := http.New(logger.New(slog.LevelDebug))
+	testLang := language.English
+	apikey := os.Getenv("GOOGLEMAPS_APIKEY")
+	if apikey == "" {
+		t.Skip("no google maps API key set, skipping tests")
+	}
+	return New(testHttpClient, testLang, apikey)
+}
+
+func testCoderWithRoundtripFunc(t *testing.T, lang language.Tag, fn func(req *stdhttp.Request) (*stdhttp.Response, error)) geocode.Geocoder {
+	t.Helper()
+	testHttpClient := http.New(logger.New(slog.LevelDebug))
+	testHttpClient.Transport = testhelper.MockRoundTripper{Fn: fn}
+	return New(testHttpClient, lang, testAPIKey)
+}
+
+func fileResponse(t *testing.T, file string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		data, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("failed to open JSON response file: %s", err)
+		}
+		return &stdhttp.Response{
+			StatusCode: 200,
+			Body:       data,
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+}
+
+func jsonResponse(t *testing.T, code int, response Response) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		buf := bytes.NewBuffer(nil)
+		if err := json.NewEncoder(buf).Encode(response); err != nil {
+			return nil, err
+		}
+		return &stdhttp.Response{
+			StatusCode: code,
+			Body:       io.NopCloser(buf),
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package none implements a geocoder that doesn't contact any geocoding service. Instead of the address,
+// it provides the formatted coordinates of the location.
+package none
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+)
+
+// Name is the name of the geocoder and the value of the geocoder provider setting that selects it.
+const Name = "none"
+
+// ErrSearchNotSupported is returned by Search, since city names can't be looked up without a geocoder.
+var ErrSearchNotSupported = errors.New("city name lookup is not supported without geocoder")
+
+type None struct {
+	precision int
+}
+
+// New returns a new geocoder that formats the coordinates with the given number of decimal places
+// instead of looking up the address.
+func New(precision int) *None {
+	return &None{precision: precision}
+}
+
+func (n *None) Name() string {
+	return Name
+}
+
+// Reverse returns an Address that only holds the coordinates and their formatted representation as
+// DisplayName, e. g. "52.51°N 13.39°E". AddressFound is always false.
+func (n *None) Reverse(_ context.Context, coords geobus.Coordinate) (geocode.Address, error) {
+	return geocode.Address{
+		Latitude:    coords.Lat,
+		Longitude:   coords.Lon,
+		DisplayName: FormatCoordinates(coords, n.precision),
+	}, nil
+}
+
+// Search always fails with ErrSearchNotSupported.
+func (n *None) Search(context.Context, string) (geobus.Coordinate, error) {
+	return geobus.Coordinate{}, ErrSearchNotSupported
+}
+
+// FormatCoordinates formats the coordinates with the given number of decimal places and their cardinal
+// directions, e. g. "52.51°N 13.39°E".
+func FormatCoordinates(coords geobus.Coordinate, precision int) string {
+	latDir, lonDir := "N", "E"
+	if coords.Lat < 0 {
+		latDir = "S"
+	}
+	if coords.Lon < 0 {
+		lonDir = "W"
+	}
+	return fmt.Sprintf("%.*f°%s %.*f°%s", precision, math.Abs(coords.Lat), latDir, precision,
+		math.Abs(coords.Lon), lonDir)
+}
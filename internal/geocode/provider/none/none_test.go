@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package none
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("provider name is correct", func(t *testing.T) {
+		if New(2).Name() != Name {
+			t.Errorf("expected provider name to be %q, got %q", Name, New(2).Name())
+		}
+	})
+}
+
+func TestNone_Reverse(t *testing.T) {
+	coords := geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}
+	addr, err := New(2).Reverse(t.Context(), coords)
+	if err != nil {
+		t.Fatalf("failed to reverse geocode coordinates: %s", err)
+	}
+	if addr.AddressFound {
+		t.Error("expected address not to be found")
+	}
+	if addr.DisplayName != "52.51°N 13.39°E" {
+		t.Errorf("expected display name to be %q, got %q", "52.51°N 13.39°E", addr.DisplayName)
+	}
+	if addr.Latitude != coords.Lat || addr.Longitude != coords.Lon {
+		t.Errorf("expected address coordinates to be %f, %f, got %f, %f", coords.Lat, coords.Lon,
+			addr.Latitude, addr.Longitude)
+	}
+	if addr.City != "" || addr.Country != "" {
+		t.Errorf("expected address to hold no place names, got %q, %q", addr.City, addr.Country)
+	}
+}
+
+func TestNone_Search(t *testing.T) {
+	if _, err := New(2).Search(t.Context(), "Berlin"); !errors.Is(err, ErrSearchNotSupported) {
+		t.Errorf("expected search to fail with %q, got %v", ErrSearchNotSupported, err)
+	}
+}
+
+func TestFormatCoordinates(t *testing.T) {
+	tests := []struct {
+		name      string
+		coords    geobus.Coordinate
+		precision int
+		want      string
+	}{
+		{"north east", geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}, 2, "52.51°N 13.39°E"},
+		{"south west", geobus.Coordinate{Lat: -33.8688, Lon: -70.6693}, 2, "33.87°S 70.67°W"},
+		{"no decimal places", geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}, 0, "53°N 13°E"},
+		{"more decimal places", geobus.Coordinate{Lat: 52.5126, Lon: -1.6938}, 4, "52.5126°N 1.6938°W"},
+		{"null island", geobus.Coordinate{}, 1, "0.0°N 0.0°E"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := FormatCoordinates(tc.coords, tc.precision); got != tc.want {
+				t.Errorf("expected formatted coordinates to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
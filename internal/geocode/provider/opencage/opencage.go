@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/text/language"
@@ -95,13 +96,10 @@ func (o *OpenCage) Reverse(ctx context.Context, coords geobus.Coordinate) (geoco
 	query.Set("no_record", "1")
 	query.Set("language", o.lang.String())
 
-	code, err := o.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
+	_, err := o.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
 	if err != nil {
 		return geocode.Address{}, fmt.Errorf("failed to retrieve address details from OpenCage API: %w", err)
 	}
-	if code != 200 {
-		return geocode.Address{}, fmt.Errorf("received non-positive response code from OpenCage API: %d", code)
-	}
 	if response.TotalResults != 1 {
 		return geocode.Address{}, fmt.Errorf("unambigous amount of results returned for coordinates: %d",
 			response.TotalResults)
@@ -115,6 +113,7 @@ func (o *OpenCage) Reverse(ctx context.Context, coords geobus.Coordinate) (geoco
 		Longitude:    response.Results[0].Geometry.Lon,
 		DisplayName:  response.Results[0].DisplayName,
 		Country:      result.Country,
+		CountryCode:  strings.ToUpper(result.CountryCode),
 		State:        result.State,
 		Municipality: result.Municipality,
 		CityDistrict: result.CityDistrict,
@@ -144,13 +143,10 @@ func (o *OpenCage) Search(ctx context.Context, address string) (geobus.Coordinat
 	query.Set("no_record", "1")
 	query.Set("language", o.lang.String())
 
-	code, err := o.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
+	_, err := o.http.GetWithTimeout(ctx, apiEndpoint, &response, query, nil, APITimeout)
 	if err != nil {
 		return geobus.Coordinate{}, fmt.Errorf("failed to retrieve address details from OpenCage API: %w", err)
 	}
-	if code != 200 {
-		return geobus.Coordinate{}, fmt.Errorf("received non-positive response code from OpenCage API: %d", code)
-	}
 	if response.TotalResults < 1 || len(response.Results) < 1 {
 		return geobus.Coordinate{}, fmt.Errorf("no coordinates returned for address: %q", address)
 	}
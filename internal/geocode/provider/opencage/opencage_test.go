@@ -22,6 +22,7 @@ import (
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
 )
 
@@ -88,6 +89,9 @@ func TestOpenCage_Reverse(t *testing.T) {
 		if !strings.EqualFold(addr.DisplayName, cityExpected) {
 			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
 		}
+		if addr.CountryCode != "DE" {
+			t.Errorf("expected country code to be %q, got %q", "DE", addr.CountryCode)
+		}
 	})
 	t.Run("reverse cached geocoding succeeds", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -184,6 +188,9 @@ func TestOpenCage_Reverse(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("API responding with more than one result should fail", func(t *testing.T) {
 		response := ReverseResponse{TotalResults: 2}
@@ -236,6 +243,9 @@ func TestOpenCage_Reverse(t *testing.T) {
 		if !strings.EqualFold(err.Error(), wantErr) {
 			t.Errorf("expected error to be %q, got %q", wantErr, err)
 		}
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
 	})
 }
 
@@ -269,6 +279,39 @@ func TestOpenCage_Search(t *testing.T) {
 			t.Errorf("expected longitude to be %f, got %f", cityForward.Lon, coords.Lon)
 		}
 	})
+	t.Run("forward cached geocoding succeeds", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			data, err := os.Open(cityForwardFile)
+			if err != nil {
+				t.Fatalf("failed to open JSON response file: %s", err)
+			}
+
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       data,
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		coder := geocode.NewCachedGeocoder(testCoderWithRoundtripFunc(t, rtFn), testHitTTL, testMissTTL)
+		coords, err := coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected address to be found")
+		}
+		if coords.Lat != cityForward.Lat {
+			t.Errorf("expected latitude to be %f, got %f", cityForward.Lat, coords.Lat)
+		}
+		coords, err = coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.CacheHit {
+			t.Error("expected cache hit")
+		}
+	})
 	t.Run("forward geocoding fails", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
 			return nil, errors.New("intentionally failing")
@@ -279,6 +322,9 @@ func TestOpenCage_Search(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("forward geocoding returning empty array fails", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -325,6 +371,9 @@ func TestOpenCage_Search(t *testing.T) {
 		if !strings.EqualFold(err.Error(), wantErr) {
 			t.Errorf("expected error to be %q, got %q", wantErr, err)
 		}
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
 	})
 	t.Run("zero results in search results", func(t *testing.T) {
 		response := SearchResponse{TotalResults: 0}
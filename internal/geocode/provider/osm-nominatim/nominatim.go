@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/text/language"
@@ -16,6 +17,7 @@ import (
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 )
 
 const (
@@ -25,6 +27,13 @@ const (
 	name               = "osm-nominatim"
 )
 
+// zoomLevels are the Nominatim zoom levels of the address details. Street level details use the API
+// default, so that the zoom parameter is omitted.
+var zoomLevels = map[geocode.Detail]int{
+	geocode.DetailSuburb: 14,
+	geocode.DetailCity:   10,
+}
+
 type Nominatim struct {
 	http *http.Client
 	lang language.Tag
@@ -58,6 +67,7 @@ type Address struct {
 	ISO31662Lvl4 string `json:"ISO3166-2-lvl4"`
 	Postcode     string `json:"postcode"`
 	Country      string `json:"country"`
+	CountryCode  string `json:"country_code"`
 }
 
 func New(client *http.Client, lang language.Tag) *Nominatim {
@@ -80,8 +90,12 @@ func (n *Nominatim) Reverse(ctx context.Context, coords geobus.Coordinate) (geoc
 	query.Set("lat", fmt.Sprintf("%f", coords.Lat))
 	query.Set("lon", fmt.Sprintf("%f", coords.Lon))
 	query.Set("accept-language", n.lang.String())
+	if zoom, ok := zoomLevels[geocode.DetailForAccuracy(coords.Acc)]; ok {
+		query.Set("zoom", strconv.Itoa(zoom))
+	}
 
-	if _, err = n.http.GetWithTimeout(ctx, reverseAPIEndpoint, &result, query, nil, APITimeout); err != nil {
+	_, err = n.http.GetWithTimeout(ctx, reverseAPIEndpoint, &result, query, nil, APITimeout)
+	if err != nil {
 		return geocode.Address{}, fmt.Errorf("failed to fetch reverse address details from Nominatim API: %w", err)
 	}
 
@@ -90,6 +104,7 @@ func (n *Nominatim) Reverse(ctx context.Context, coords geobus.Coordinate) (geoc
 		AddressFound: true,
 		DisplayName:  result.DisplayName,
 		Country:      result.Address.Country,
+		CountryCode:  strings.ToUpper(result.Address.CountryCode),
 		State:        result.Address.State,
 		Municipality: result.Address.Municipality,
 		CityDistrict: result.Address.CityDistrict,
@@ -107,11 +122,13 @@ func (n *Nominatim) Reverse(ctx context.Context, coords geobus.Coordinate) (geoc
 	}
 	address.Latitude, err = strconv.ParseFloat(result.APILat, 64)
 	if err != nil {
-		return geocode.Address{}, fmt.Errorf("failed to parse latitude from Nominatim API response: %w", err)
+		return geocode.Address{}, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to parse latitude from Nominatim API response: %w", err))
 	}
 	address.Longitude, err = strconv.ParseFloat(result.APILon, 64)
 	if err != nil {
-		return geocode.Address{}, fmt.Errorf("failed to parse longitude from Nominatim API response: %w", err)
+		return geocode.Address{}, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to parse longitude from Nominatim API response: %w", err))
 	}
 
 	return address, nil
@@ -126,7 +143,8 @@ func (n *Nominatim) Search(ctx context.Context, address string) (geobus.Coordina
 	query.Set("q", address)
 	query.Set("accept-language", n.lang.String())
 
-	if _, err = n.http.GetWithTimeout(ctx, searchAPIEndpoint, &result, query, nil, APITimeout); err != nil {
+	_, err = n.http.GetWithTimeout(ctx, searchAPIEndpoint, &result, query, nil, APITimeout)
+	if err != nil {
 		return geobus.Coordinate{}, fmt.Errorf("failed to fetch address details from Nominatim API: %w", err)
 	}
 
@@ -137,11 +155,13 @@ func (n *Nominatim) Search(ctx context.Context, address string) (geobus.Coordina
 	var coords geobus.Coordinate
 	coords.Lat, err = strconv.ParseFloat(result[0].APILat, 64)
 	if err != nil {
-		return coords, fmt.Errorf("failed to parse latitude from Nominatim API response: %w", err)
+		return coords, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to parse latitude from Nominatim API response: %w", err))
 	}
 	coords.Lon, err = strconv.ParseFloat(result[0].APILon, 64)
 	if err != nil {
-		return coords, fmt.Errorf("failed to parse longitude from Nominatim API response: %w", err)
+		return coords, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to parse longitude from Nominatim API response: %w", err))
 	}
 	coords.Found = true
 
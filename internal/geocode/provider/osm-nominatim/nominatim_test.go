@@ -6,6 +6,7 @@ package nominatim
 
 import (
 	"errors"
+	"io"
 	"log/slog"
 	stdhttp "net/http"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
 )
 
@@ -89,6 +91,39 @@ func TestNominatim_Reverse(t *testing.T) {
 		if !strings.EqualFold(addr.DisplayName, cityExpected) {
 			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
 		}
+		if addr.CountryCode != "DE" {
+			t.Errorf("expected country code to be %q, got %q", "DE", addr.CountryCode)
+		}
+	})
+	t.Run("coarse geolocations request a lower zoom", func(t *testing.T) {
+		tests := []struct {
+			accuracy float64
+			zoom     string
+		}{
+			{0, ""},
+			{25, ""},
+			{1500, "14"},
+			{300000, "10"},
+		}
+		for _, tc := range tests {
+			var zoom string
+			rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				zoom = req.URL.Query().Get("zoom")
+				data, err := os.Open(cityFile)
+				if err != nil {
+					t.Fatalf("failed to open JSON response file: %s", err)
+				}
+				return &stdhttp.Response{StatusCode: 200, Body: data, Header: make(stdhttp.Header)}, nil
+			}
+			coords := cityCoords
+			coords.Acc = tc.accuracy
+			if _, err := testCoderWithRoundtripFunc(t, rtFn).Reverse(t.Context(), coords); err != nil {
+				t.Fatal(err)
+			}
+			if zoom != tc.zoom {
+				t.Errorf("expected zoom for accuracy %g to be %q, got %q", tc.accuracy, tc.zoom, zoom)
+			}
+		}
 	})
 	t.Run("reverse cached geocoding succeeds", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -185,6 +220,31 @@ func TestNominatim_Reverse(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with a non-200 response", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 429,
+				Body:       io.NopCloser(strings.NewReader(`{"error":"Too Many Requests"}`)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		coder := testCoderWithRoundtripFunc(t, rtFn)
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if err == nil {
+			t.Fatal("expected API request to fail")
+		}
+		wantErr := `unsuccessful response status 429 Too Many Requests: {"error":"Too Many Requests"}`
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrRateLimited) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrRateLimited, err)
+		}
 	})
 	t.Run("reverse geocoding fails on NaN latitude response", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -208,6 +268,9 @@ func TestNominatim_Reverse(t *testing.T) {
 		if !strings.Contains(err.Error(), "failed to parse latitude") {
 			t.Errorf("expected error to contain 'failed to parse latitude', got %s", err)
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 	t.Run("reverse geocoding fails on NaN longitude response", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -231,6 +294,9 @@ func TestNominatim_Reverse(t *testing.T) {
 		if !strings.Contains(err.Error(), "failed to parse longitude") {
 			t.Errorf("expected error to contain 'failed to parse longitude', got %s", err)
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 }
 
@@ -264,6 +330,39 @@ func TestNominatim_Search(t *testing.T) {
 			t.Errorf("expected longitude to be %f, got %f", cityCoordsForward.Lon, coords.Lon)
 		}
 	})
+	t.Run("forward cached geocoding succeeds", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			data, err := os.Open(cityFileForward)
+			if err != nil {
+				t.Fatalf("failed to open JSON response file: %s", err)
+			}
+
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       data,
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		coder := geocode.NewCachedGeocoder(testCoderWithRoundtripFunc(t, rtFn), testHitTTL, testMissTTL)
+		coords, err := coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected address to be found")
+		}
+		if coords.Lat != cityCoordsForward.Lat {
+			t.Errorf("expected latitude to be %f, got %f", cityCoordsForward.Lat, coords.Lat)
+		}
+		coords, err = coder.Search(t.Context(), cityExpected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.CacheHit {
+			t.Error("expected cache hit")
+		}
+	})
 	t.Run("forward geocoding fails", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
 			return nil, errors.New("intentionally failing")
@@ -274,6 +373,9 @@ func TestNominatim_Search(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected API request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("forward geocoding fails on empty array response", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -317,6 +419,9 @@ func TestNominatim_Search(t *testing.T) {
 		if !strings.Contains(err.Error(), "failed to parse latitude") {
 			t.Errorf("expected error to contain 'failed to parse latitude', got %s", err)
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 	t.Run("forward geocoding fails on NaN longitue response", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -340,6 +445,9 @@ func TestNominatim_Search(t *testing.T) {
 		if !strings.Contains(err.Error(), "failed to parse longitude") {
 			t.Errorf("expected error to contain 'failed to parse longitude', got %s", err)
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 }
 
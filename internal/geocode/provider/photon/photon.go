@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package photon
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/http"
+)
+
+const (
+	DefaultBaseURL  = "https://photon.komoot.io"
+	reversePath     = "/reverse"
+	searchPath      = "/api"
+	APITimeout      = time.Second * 10
+	name            = "photon"
+	osmKeyPlace     = "place"
+	defaultLanguage = "default"
+)
+
+// supportedLanguages lists the languages that the public Photon instance supports. Any other
+// language will be rejected by the API, so we fall back to the local names instead.
+var supportedLanguages = map[string]struct{}{"de": {}, "en": {}, "fr": {}}
+
+type Photon struct {
+	baseURL string
+	http    *http.Client
+	lang    language.Tag
+}
+
+type Response struct {
+	Features []Feature `json:"features"`
+	Type     string    `json:"type"`
+}
+
+type Feature struct {
+	Geometry   Geometry   `json:"geometry"`
+	Properties Properties `json:"properties"`
+	Type       string     `json:"type"`
+}
+
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type Properties struct {
+	Name        string `json:"name"`
+	HouseNumber string `json:"housenumber"`
+	Street      string `json:"street"`
+	Locality    string `json:"locality"`
+	District    string `json:"district"`
+	City        string `json:"city"`
+	County      string `json:"county"`
+	State       string `json:"state"`
+	Postcode    string `json:"postcode"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countrycode"`
+	OSMKey      string `json:"osm_key"`
+	OSMValue    string `json:"osm_value"`
+	Type        string `json:"type"`
+}
+
+// New returns a new Photon geocoder. If baseURL is empty, the public Photon instance operated
+// by komoot is used. Otherwise baseURL is expected to point to a self-hosted Photon instance.
+func New(client *http.Client, lang language.Tag, baseURL string) *Photon {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Photon{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		lang:    lang,
+		http:    client,
+	}
+}
+
+func (p *Photon) Name() string {
+	return name
+}
+
+func (p *Photon) Reverse(ctx context.Context, coords geobus.Coordinate) (geocode.Address, error) {
+	var response Response
+
+	query := url.Values{}
+	query.Set("lat", fmt.Sprintf("%f", coords.Lat))
+	query.Set("lon", fmt.Sprintf("%f", coords.Lon))
+	query.Set("limit", "1")
+	query.Set("lang", p.language())
+
+	_, err := p.http.GetWithTimeout(ctx, p.baseURL+reversePath, &response, query, nil, APITimeout)
+	if err != nil {
+		return geocode.Address{}, fmt.Errorf("failed to retrieve address details from Photon API: %w", err)
+	}
+	// No feature near the coordinates is not an error, so that the miss is cached like any other result
+	if len(response.Features) < 1 {
+		return geocode.Address{AddressFound: false}, nil
+	}
+
+	// Fill the geocode.Address struct
+	result := response.Features[0].Properties
+	address := geocode.Address{
+		AddressFound: true,
+		Latitude:     coords.Lat,
+		Longitude:    coords.Lon,
+		DisplayName:  displayName(result),
+		Country:      result.Country,
+		CountryCode:  strings.ToUpper(result.CountryCode),
+		State:        result.State,
+		Municipality: result.County,
+		CityDistrict: result.District,
+		Postcode:     result.Postcode,
+		City:         result.City,
+		Suburb:       result.Locality,
+		Street:       result.Street,
+		HouseNumber:  result.HouseNumber,
+	}
+
+	// Photon only sets the city property for features that are located within a city. If the
+	// feature is a settlement itself (i. e. a town or a village), the name holds the city name.
+	if address.City == "" && result.OSMKey == osmKeyPlace {
+		switch result.OSMValue {
+		case "city", "town", "village", "hamlet":
+			address.City = result.Name
+		}
+	}
+
+	return address, nil
+}
+
+func (p *Photon) Search(ctx context.Context, address string) (geobus.Coordinate, error) {
+	var response Response
+
+	query := url.Values{}
+	query.Set("q", address)
+	query.Set("limit", "1")
+	query.Set("lang", p.language())
+
+	_, err := p.http.GetWithTimeout(ctx, p.baseURL+searchPath, &response, query, nil, APITimeout)
+	if err != nil {
+		return geobus.Coordinate{}, fmt.Errorf("failed to retrieve address details from Photon API: %w", err)
+	}
+	if len(response.Features) < 1 {
+		return geobus.Coordinate{}, fmt.Errorf("no coordinates found for address %q", address)
+	}
+
+	// Fill the geobus.Coordinate struct
+	result := response.Features[0].Geometry
+	if len(result.Coordinates) != 2 {
+		return geobus.Coordinate{}, fmt.Errorf("expected 2 coordinates in response, got: %d",
+			len(result.Coordinates))
+	}
+	coords := geobus.Coordinate{
+		Lat:   result.Coordinates[1],
+		Lon:   result.Coordinates[0],
+		Found: true,
+	}
+
+	return coords, nil
+}
+
+// language returns the language parameter for the Photon API. Photon only supports a small set
+// of languages and rejects any other value, so we use the local names for unsupported languages.
+func (p *Photon) language() string {
+	base, _ := p.lang.Base()
+	if _, ok := supportedLanguages[base.String()]; ok {
+		return base.String()
+	}
+	return defaultLanguage
+}
+
+// displayName assembles a human-readable name from the feature properties, since the Photon
+// API does not provide a preformatted label like other geocoding providers do.
+func displayName(props Properties) string {
+	parts := make([]string, 0, 4)
+	street := strings.TrimSpace(props.Street + " " + props.HouseNumber)
+	switch {
+	case props.Name != "":
+		parts = append(parts, props.Name)
+	case street != "":
+		parts = append(parts, street)
+	}
+	for _, part := range []string{props.City, props.Country} {
+		if part == "" || (len(parts) > 0 && parts[len(parts)-1] == part) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
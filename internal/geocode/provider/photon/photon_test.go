@@ -0,0 +1,386 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package photon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	stdhttp "net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/testhelper"
+)
+
+const (
+	cityExpected    = "Charlottenstraße 57, Berlin, Germany"
+	forwardAddress  = "Quartier 205, Friedrichstrasse 67, 10117 Berlin, Germany"
+	cityFile        = "../../../../testdata/photon_berlin.json"
+	cityForwardFile = "../../../../testdata/photon_berlin_forward.json"
+	emptyArray      = "../../../../testdata/empty_array.json"
+	testHitTTL      = 1 * time.Second
+	testMissTTL     = 1 * time.Second
+
+	villageExpected = "Marshfield"
+	villageFile     = "../../../../testdata/photon_marshfield.json"
+
+	townExpected = "Otley"
+	townFile     = "../../../../testdata/photon_otley.json"
+)
+
+var (
+	cityCoords        = geobus.Coordinate{Lat: 52.5129, Lon: 13.3910}
+	cityForwardCoords = geobus.Coordinate{Lat: 52.512274, Lon: 13.390617}
+	villageCoords     = geobus.Coordinate{Lat: 51.46292, Lon: -2.31850}
+	townCoords        = geobus.Coordinate{Lat: 53.90712, Lon: -1.69404}
+)
+
+func TestNew(t *testing.T) {
+	t.Run("creating a new provider succeeds", func(t *testing.T) {
+		coder := testCoder(t, "")
+		if coder == nil {
+			t.Fatal("expected a non-nil geocoder")
+		}
+	})
+	t.Run("provider name is correct", func(t *testing.T) {
+		coder := testCoder(t, "")
+		if coder.Name() != name {
+			t.Errorf("expected provider name to be %q, got %q", name, coder.Name())
+		}
+	})
+	t.Run("empty base URL uses the public instance", func(t *testing.T) {
+		coder := New(http.New(logger.New(slog.LevelDebug)), language.English, "")
+		if coder.baseURL != DefaultBaseURL {
+			t.Errorf("expected base URL to be %q, got %q", DefaultBaseURL, coder.baseURL)
+		}
+	})
+	t.Run("custom base URL is used without trailing slash", func(t *testing.T) {
+		coder := New(http.New(logger.New(slog.LevelDebug)), language.English, "http://localhost:2322/")
+		if coder.baseURL != "http://localhost:2322" {
+			t.Errorf("expected base URL to be %q, got %q", "http://localhost:2322", coder.baseURL)
+		}
+	})
+}
+
+func TestPhoton_Reverse(t *testing.T) {
+	t.Run("reverse geocoding returns the correct city", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			file   string
+			coords geobus.Coordinate
+			want   string
+		}{
+			{"city", cityFile, cityCoords, "Berlin"},
+			{"town", townFile, townCoords, townExpected},
+			{"village", villageFile, villageCoords, villageExpected},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				coder := testCoderWithRoundtripFunc(t, "", fileResponse(t, tc.file))
+				addr, err := coder.Reverse(t.Context(), tc.coords)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !addr.AddressFound {
+					t.Fatal("expected address to be found")
+				}
+				if !strings.EqualFold(addr.City, tc.want) {
+					t.Errorf("expected city to be %q, got %q", tc.want, addr.City)
+				}
+			})
+		}
+	})
+	t.Run("reverse geocoding succeeds", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, "", fileResponse(t, cityFile))
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !addr.AddressFound {
+			t.Fatal("expected address to be found")
+		}
+		if !strings.EqualFold(addr.DisplayName, cityExpected) {
+			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
+		}
+		if addr.Postcode != "10117" {
+			t.Errorf("expected postcode to be %q, got %q", "10117", addr.Postcode)
+		}
+		if addr.CountryCode != "DE" {
+			t.Errorf("expected country code to be %q, got %q", "DE", addr.CountryCode)
+		}
+	})
+	t.Run("reverse cached geocoding succeeds", func(t *testing.T) {
+		coder := geocode.NewCachedGeocoder(testCoderWithRoundtripFunc(t, "", fileResponse(t, cityFile)),
+			testHitTTL, testMissTTL)
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(addr.DisplayName, cityExpected) {
+			t.Errorf("expected address to be %q, got %q", cityExpected, addr.DisplayName)
+		}
+		addr, err = coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !addr.CacheHit {
+			t.Error("expected cache hit")
+		}
+	})
+	t.Run("reverse geocoding uses the configured base URL", func(t *testing.T) {
+		var gotURL string
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			gotURL = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+			return fileResponse(t, cityFile)(req)
+		}
+		coder := testCoderWithRoundtripFunc(t, "http://photon.example.com:2322", rtFn)
+		if _, err := coder.Reverse(t.Context(), cityCoords); err != nil {
+			t.Fatal(err)
+		}
+		want := "http://photon.example.com:2322/reverse"
+		if gotURL != want {
+			t.Errorf("expected request URL to be %q, got %q", want, gotURL)
+		}
+	})
+	t.Run("reverse geocoding fails", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("intentionally failing")
+		}
+
+		coder := testCoderWithRoundtripFunc(t, "", rtFn)
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if err == nil {
+			t.Fatal("expected API request to fail")
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with no features returns no address", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, "", jsonResponse(t, 200, Response{Features: []Feature{}}))
+		address, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatalf("failed to reverse geocode coordinates: %s", err)
+		}
+		if address.AddressFound {
+			t.Errorf("expected no address to be found, got %+v", address)
+		}
+	})
+	t.Run("API responding with a non-200 reponse", func(t *testing.T) {
+		response := Response{Features: []Feature{{Properties: Properties{City: "Berlin"}}}}
+		coder := testCoderWithRoundtripFunc(t, "", jsonResponse(t, 400, response))
+		_, err := coder.Reverse(t.Context(), cityCoords)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "unsuccessful response status 400 Bad Request"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+}
+
+func TestPhoton_Search(t *testing.T) {
+	t.Run("forward geocoding succeeds", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, "", fileResponse(t, cityForwardFile))
+		coords, err := coder.Search(t.Context(), forwardAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected address to be found")
+		}
+		if coords.Lat != cityForwardCoords.Lat {
+			t.Errorf("expected latitude to be %f, got %f", cityForwardCoords.Lat, coords.Lat)
+		}
+		if coords.Lon != cityForwardCoords.Lon {
+			t.Errorf("expected longitude to be %f, got %f", cityForwardCoords.Lon, coords.Lon)
+		}
+	})
+	t.Run("forward geocoding uses the configured base URL", func(t *testing.T) {
+		var gotURL string
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			gotURL = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+			return fileResponse(t, cityForwardFile)(req)
+		}
+		coder := testCoderWithRoundtripFunc(t, "http://photon.example.com:2322/", rtFn)
+		if _, err := coder.Search(t.Context(), forwardAddress); err != nil {
+			t.Fatal(err)
+		}
+		want := "http://photon.example.com:2322/api"
+		if gotURL != want {
+			t.Errorf("expected request URL to be %q, got %q", want, gotURL)
+		}
+	})
+	t.Run("forward geocoding fails", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("intentionally failing")
+		}
+
+		coder := testCoderWithRoundtripFunc(t, "", rtFn)
+		_, err := coder.Search(t.Context(), forwardAddress)
+		if err == nil {
+			t.Fatal("expected API request to fail")
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("API responding with a non-200 reponse", func(t *testing.T) {
+		response := Response{Features: []Feature{{Geometry: Geometry{
+			Coordinates: []float64{cityForwardCoords.Lon, cityForwardCoords.Lat},
+		}}}}
+		coder := testCoderWithRoundtripFunc(t, "", jsonResponse(t, 500, response))
+		_, err := coder.Search(t.Context(), forwardAddress)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "unsuccessful response status 500 Internal Server Error"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("forward geocoding returning empty array fails", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, "", fileResponse(t, emptyArray))
+		_, err := coder.Search(t.Context(), forwardAddress)
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+	t.Run("API responding with a only one coordinate in JSON", func(t *testing.T) {
+		response := Response{Features: []Feature{{Geometry: Geometry{
+			Coordinates: []float64{cityForwardCoords.Lon},
+		}}}}
+		coder := testCoderWithRoundtripFunc(t, "", jsonResponse(t, 200, response))
+		_, err := coder.Search(t.Context(), forwardAddress)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "expected 2 coordinates in response, got: 1"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to be %q, got %q", wantErr, err)
+		}
+	})
+	t.Run("API responding with no features in JSON", func(t *testing.T) {
+		coder := testCoderWithRoundtripFunc(t, "", jsonResponse(t, 200, Response{Type: "FeatureCollection"}))
+		_, err := coder.Search(t.Context(), forwardAddress)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		wantErr := "no coordinates found for address"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to be %q, got %q", wantErr, err)
+		}
+	})
+}
+
+func TestPhoton_language(t *testing.T) {
+	tests := []struct {
+		lang language.Tag
+		want string
+	}{
+		{language.English, "en"},
+		{language.AmericanEnglish, "en"},
+		{language.German, "de"},
+		{language.French, "fr"},
+		{language.Japanese, defaultLanguage},
+	}
+	for _, tc := range tests {
+		t.Run(tc.lang.String(), func(t *testing.T) {
+			coder := New(http.New(logger.New(slog.LevelDebug)), tc.lang, "")
+			if got := coder.language(); got != tc.want {
+				t.Errorf("expected language to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPhoton_integration(t *testing.T) {
+	testhelper.PerformIntegrationTests(t)
+	t.Run("reverse geocoding succeeds", func(t *testing.T) {
+		coder := testCoder(t, "")
+		addr, err := coder.Reverse(t.Context(), cityCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !addr.AddressFound {
+			t.Fatal("expected address to be found")
+		}
+		if !strings.EqualFold(addr.City, "Berlin") {
+			t.Errorf("expected city to be %q, got %q", "Berlin", addr.City)
+		}
+	})
+	t.Run("forward geocoding succeeds", func(t *testing.T) {
+		coder := testCoder(t, "")
+		coords, err := coder.Search(t.Context(), forwardAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !coords.Found {
+			t.Fatal("expected coordinates to be found")
+		}
+	})
+}
+
+func testCoder(t *testing.T, baseURL string) geocode.Geocoder {
+	t.Helper()
+	testHttpClient := http.New(logger.New(slog.LevelDebug))
+	return New(testHttpClient, language.English, baseURL)
+}
+
+func testCoderWithRoundtripFunc(t *testing.T, baseURL string, fn func(req *stdhttp.Request) (*stdhttp.Response, error)) geocode.Geocoder {
+	t.Helper()
+	testHttpClient := http.New(logger.New(slog.LevelDebug))
+	testHttpClient.Transport = testhelper.MockRoundTripper{Fn: fn}
+	return New(testHttpClient, language.English, baseURL)
+}
+
+func fileResponse(t *testing.T, file string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		data, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("failed to open JSON response file: %s", err)
+		}
+		return &stdhttp.Response{
+			StatusCode: 200,
+			Body:       data,
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+}
+
+func jsonResponse(t *testing.T, code int, response Response) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		buf := bytes.NewBuffer(nil)
+		if err := json.NewEncoder(buf).Encode(response); err != nil {
+			return nil, err
+		}
+		return &stdhttp.Response{
+			StatusCode: code,
+			Body:       io.NopCloser(buf),
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+}
@@ -5,24 +5,44 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"reflect"
 	"runtime"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 )
 
 const (
 	// DefaultTimeout is the default timeout value for the HTTPClient
 	DefaultTimeout = time.Second * 10
+	// MaxIdleConnsPerHost is the number of idle connections that a transport keeps to each host. The
+	// providers send few requests to each API, so a couple of connections are enough.
+	MaxIdleConnsPerHost = 2
+	// IdleConnTimeout is the time an idle connection is kept open. It covers the default weather update
+	// interval, so that the scheduled fetches reuse the connection of the previous fetch, if the server
+	// keeps it open that long.
+	IdleConnTimeout = 20 * time.Minute
+	// maxDrainSize is the maximum number of unread bytes of a response body that are discarded before the
+	// body is closed, so that the connection can be reused
+	maxDrainSize = 64 << 10
 )
 
 var (
@@ -36,25 +56,56 @@ var (
 	)
 
 	ErrNonPointerTarget = errors.New("target must be a non-nil pointer")
+	// ErrUnexpectedContentType is returned if the server responds with a content type other than JSON
+	ErrUnexpectedContentType = errors.New("unexpected content type in response")
+
+	// gzipMagic are the first bytes of gzip compressed data
+	gzipMagic = []byte{0x1f, 0x8b}
 )
 
 // Client is a type wrapper for the Go stdlib http.Client and the Config
 type Client struct {
 	*http.Client
 	logger *logger.Logger
+	// pathSecrets are credentials that are part of the request paths and redacted like the credentials
+	// in the query parameters and headers
+	pathSecrets []string
 }
 
-// New returns a new HTTP client
+// New returns a new HTTP client with a transport of its own
 func New(logger *logger.Logger) *Client {
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-	httpTransport := &http.Transport{TLSClientConfig: tlsConfig}
+	return NewWithTransport(logger, NewTransport(false))
+}
+
+// NewWithTransport returns a new HTTP client that sends its requests with the given transport. Clients
+// that share a transport share its idle connections, so that requests to the same host skip the TCP and
+// TLS handshakes.
+func NewWithTransport(logger *logger.Logger, transport http.RoundTripper) *Client {
 	httpClient := &http.Client{
 		Timeout:   DefaultTimeout,
-		Transport: httpTransport,
+		Transport: transport,
+	}
+	return &Client{Client: httpClient, logger: logger}
+}
+
+// WithPathSecrets returns a copy of the client that treats the given values as credentials if they are
+// part of the request path, e. g. an API key that is sent as path segment instead of a query parameter.
+func (h *Client) WithPathSecrets(secrets ...string) *Client {
+	return &Client{Client: h.Client, logger: h.logger, pathSecrets: append(slices.Clone(h.pathSecrets), secrets...)}
+}
+
+// NewTransport returns a new transport for the API requests, which keeps up to MaxIdleConnsPerHost idle
+// connections to each host for IdleConnTimeout. If disableKeepAlives is set, every request uses a new
+// connection instead, so that the requests can't be linked by their connection.
+func NewTransport(disableKeepAlives bool) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+		MaxIdleConnsPerHost: MaxIdleConnsPerHost,
+		IdleConnTimeout:     IdleConnTimeout,
+		DisableKeepAlives:   disableKeepAlives,
 	}
-	return &Client{httpClient, logger}
 }
 
 // Get performs a HTTP GET request for the given URL and json-unmarshals the response
@@ -77,8 +128,35 @@ func (h *Client) PostWithTimeout(ctx context.Context, endpoint string, target an
 	return h.PerformReq(ctx, http.MethodPost, endpoint, target, nil, headers, body, timeout)
 }
 
+// Probe performs a HTTP HEAD request for the given URL to check whether it can be reached. Any HTTP
+// response, regardless of its status code, is considered as reachable.
+func (h *Client) Probe(ctx context.Context, endpoint string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed create new HTTP request with context: %w", err)
+	}
+	request.Header.Set("User-Agent", UserAgent)
+
+	response, err := h.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to perform HTTP request: %w", err)
+	}
+	if response == nil {
+		return errors.New("nil response received")
+	}
+	if err = response.Body.Close(); err != nil {
+		h.logger.Error("failed to close HTTP request body", logger.Err(err))
+	}
+	return nil
+}
+
 // PerformReq performs a HTTP GET or POST request for the given URL and timeout and JSON-unmarshals the
-// response into target
+// response into target. Failed requests are transient errors and responses that can't be decoded are bad
+// responses. Unsuccessful responses return a StatusError with a snippet of the response body, which gets the
+// error kind of its status code (see providererr.Status).
 func (h *Client) PerformReq(ctx context.Context, method string, endpoint string, target any, query url.Values, headers map[string]string, body io.Reader, timeout time.Duration) (int, error) {
 	rv := reflect.ValueOf(target)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
@@ -106,28 +184,120 @@ func (h *Client) PerformReq(ctx context.Context, method string, endpoint string,
 	for k, v := range headers {
 		request.Header.Set(k, v)
 	}
+	// Setting the header disables the transparent decompression of the transport, so that compressed
+	// responses are always decompressed by decodeBody
+	request.Header.Set("Accept-Encoding", "gzip")
 
 	// Execute HTTP request
 	response, err := h.Do(request)
 	if err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if errors.Is(err, context.Canceled) {
 			return 0, err
 		}
-		return 0, fmt.Errorf("failed to perform HTTP request: %w", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, providererr.Wrap(providererr.ErrTransient, err)
+		}
+		return 0, providererr.Wrap(providererr.ErrTransient, fmt.Errorf("failed to perform HTTP request: %w", err))
 	}
 	if response == nil {
-		return 0, errors.New("nil response received")
+		return 0, providererr.Wrap(providererr.ErrTransient, errors.New("nil response received"))
 	}
 	defer func(body io.ReadCloser) {
+		// The connection is only reused if the body was read to the end
+		_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
 		if err := body.Close(); err != nil {
 			h.logger.Error("failed to close HTTP request body", logger.Err(err))
 		}
 	}(response.Body)
 
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response.StatusCode, h.statusError(response, reqURL, headers, target)
+	}
+
+	// Responses without a content type are decoded on a best effort basis
+	if contentType := response.Header.Get("Content-Type"); contentType != "" && !isJSONContentType(contentType) {
+		return response.StatusCode, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("%w: %s", ErrUnexpectedContentType, contentType))
+	}
+
 	// Unmarshal the JSON API response into target
-	if err = json.NewDecoder(response.Body).Decode(target); err != nil {
-		return response.StatusCode, fmt.Errorf("failed to decode JSON: %w", err)
+	body, encoding, err := decodeBody(response)
+	if err != nil {
+		return response.StatusCode, providererr.Wrap(providererr.ErrBadResponse, err)
+	}
+	counter := &countingReader{reader: body}
+	err = json.NewDecoder(counter).Decode(target)
+	// Only the host is logged, since some providers have the API key in the path
+	h.logger.Debug("received API response", slog.String("host", reqURL.Host),
+		slog.Int("status", response.StatusCode), slog.String("content_encoding", encoding),
+		slog.Int64("decompressed_bytes", counter.n))
+	if err != nil {
+		return response.StatusCode, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to decode JSON: %w", err))
 	}
 
 	return response.StatusCode, nil
 }
+
+// decodeBody returns a reader of the decompressed response body and the content encoding it was
+// decompressed from. Bodies that the transport already decompressed are returned as they are. Otherwise,
+// gzip and deflate bodies are decompressed by their Content-Encoding and gzip bodies without it by their
+// magic number, since some servers compress their responses without declaring it.
+func decodeBody(response *http.Response) (io.Reader, string, error) {
+	if response.Uncompressed {
+		return response.Body, "gzip", nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	body := bufio.NewReader(response.Body)
+	if encoding == "" || encoding == "identity" {
+		magic, _ := body.Peek(len(gzipMagic))
+		if !bytes.Equal(magic, gzipMagic) {
+			return body, "", nil
+		}
+		encoding = "gzip"
+	}
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, encoding, fmt.Errorf("failed to decompress gzip response: %w", err)
+		}
+		return reader, encoding, nil
+	case "deflate":
+		// Most servers send zlib wrapped data as specified, but some send raw deflate data
+		header, _ := body.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			reader, err := zlib.NewReader(body)
+			if err != nil {
+				return nil, encoding, fmt.Errorf("failed to decompress deflate response: %w", err)
+			}
+			return reader, encoding, nil
+		}
+		return flate.NewReader(body), encoding, nil
+	default:
+		return nil, encoding, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// countingReader counts the bytes that are read from the underlying reader.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isJSONContentType reports whether the given Content-Type header value denotes a JSON document. This
+// includes structured syntax suffixes like application/geo+json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
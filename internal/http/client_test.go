@@ -5,18 +5,28 @@
 package http
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	stdhttp "net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
 )
 
@@ -36,6 +46,49 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		name              string
+		disableKeepAlives bool
+		wantConns         int
+	}{
+		{"clients sharing a transport reuse the connection", false, 1},
+		{"disabled keep-alives open a connection per request", true, 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var conns atomic.Int32
+			server := httptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"string": "value"}` + "\n"))
+			}))
+			server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+				if state == stdhttp.StateNew {
+					conns.Add(1)
+				}
+			}
+			server.Start()
+			t.Cleanup(server.Close)
+
+			transport := NewTransport(tc.disableKeepAlives)
+			t.Cleanup(transport.CloseIdleConnections)
+			log := logger.New(slog.LevelInfo)
+			clients := []*Client{
+				NewWithTransport(log, transport), NewWithTransport(log, transport), NewWithTransport(log, transport),
+			}
+			for _, client := range clients {
+				var target testType
+				if _, err := client.Get(t.Context(), server.URL, &target, nil, nil); err != nil {
+					t.Fatalf("failed to perform request: %s", err)
+				}
+			}
+			if got := int(conns.Load()); got != tc.wantConns {
+				t.Errorf("expected %d connections, got %d", tc.wantConns, got)
+			}
+		})
+	}
+}
+
 func TestClient_Get(t *testing.T) {
 	t.Run("getting and serializing JSON should work", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -115,6 +168,9 @@ func TestClient_Get(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected get request to fail")
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
 	})
 	t.Run("getting a nil response", func(t *testing.T) {
 		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -133,9 +189,289 @@ func TestClient_Get(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected get request to fail")
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 }
 
+func TestClient_PerformReq(t *testing.T) {
+	t.Run("content type is validated", func(t *testing.T) {
+		tests := []struct {
+			contentType string
+			fails       bool
+		}{
+			{"", false},
+			{"application/json", false},
+			{"application/json; charset=utf-8", false},
+			{"application/geo+json", false},
+			{"text/html; charset=utf-8", true},
+			{"text/plain", true},
+			{"invalid;;", true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.contentType, func(t *testing.T) {
+				rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					header := make(stdhttp.Header)
+					if tc.contentType != "" {
+						header.Set("Content-Type", tc.contentType)
+					}
+					return &stdhttp.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(strings.NewReader(`{"string":"test"}`)),
+						Header:     header,
+					}, nil
+				}
+
+				client := New(logger.New(slog.LevelInfo))
+				client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+
+				target := new(testType)
+				_, err := client.Get(t.Context(), "https://example.com", target, nil, nil)
+				if tc.fails && !errors.Is(err, ErrUnexpectedContentType) {
+					t.Errorf("expected error to be %s, got %v", ErrUnexpectedContentType, err)
+				}
+				if tc.fails && !errors.Is(err, providererr.ErrBadResponse) {
+					t.Errorf("expected error to be %s, got %v", providererr.ErrBadResponse, err)
+				}
+				if !tc.fails && err != nil {
+					t.Errorf("expected request to succeed, got %s", err)
+				}
+			})
+		}
+	})
+	t.Run("compressed responses are decompressed", func(t *testing.T) {
+		const payload = `{"string":"compressed","int":42}`
+		compress := func(t *testing.T, newWriter func(io.Writer) io.WriteCloser) []byte {
+			t.Helper()
+			buf := bytes.NewBuffer(nil)
+			writer := newWriter(buf)
+			if _, err := writer.Write([]byte(payload)); err != nil {
+				t.Fatalf("failed to compress payload: %s", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("failed to compress payload: %s", err)
+			}
+			return buf.Bytes()
+		}
+		gzipped := compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+		zlibbed := compress(t, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+		deflated := compress(t, func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		})
+
+		tests := []struct {
+			name     string
+			body     []byte
+			encoding string
+			// wantEncoding is the logged content encoding the body was decompressed from
+			wantEncoding string
+			fails        bool
+		}{
+			{"plain", []byte(payload), "", `""`, false},
+			{"gzip with content encoding", gzipped, "gzip", "gzip", false},
+			{"gzip without content encoding", gzipped, "", "gzip", false},
+			{"zlib wrapped deflate", zlibbed, "deflate", "deflate", false},
+			{"raw deflate", deflated, "deflate", "deflate", false},
+			{"corrupt gzip", []byte{0x1f, 0x8b, 0x00}, "gzip", "", true},
+			{"unsupported encoding", []byte(payload), "br", "", true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					if got := req.Header.Get("Accept-Encoding"); got != "gzip" {
+						t.Errorf("expected Accept-Encoding to be %q, got %q", "gzip", got)
+					}
+					header := make(stdhttp.Header)
+					header.Set("Content-Type", "application/json")
+					if tc.encoding != "" {
+						header.Set("Content-Encoding", tc.encoding)
+					}
+					return &stdhttp.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(bytes.NewReader(tc.body)),
+						Header:     header,
+					}, nil
+				}
+
+				buf := bytes.NewBuffer(nil)
+				client := New(logger.NewLogger(slog.LevelDebug, buf, nil))
+				client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+
+				// The header of the provider doesn't prevent compressed responses
+				headers := map[string]string{"Accept-Encoding": "identity"}
+				target := new(testType)
+				_, err := client.Get(t.Context(), "https://example.com/api", target, nil, headers)
+				if tc.fails {
+					if !errors.Is(err, providererr.ErrBadResponse) {
+						t.Errorf("expected error to be %s, got %v", providererr.ErrBadResponse, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("expected request to succeed, got %s", err)
+				}
+				if target.String != "compressed" || target.Int != 42 {
+					t.Errorf("expected decompressed payload, got %+v", target)
+				}
+				wantLog := fmt.Sprintf("host=example.com status=200 content_encoding=%s decompressed_bytes=%d",
+					tc.wantEncoding, len(payload))
+				if !strings.Contains(buf.String(), wantLog) {
+					t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+				}
+			})
+		}
+	})
+	t.Run("undecodable responses get the error kind of the status code", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			code  int
+			query url.Values
+			want  error
+		}{
+			{"200", 200, nil, providererr.ErrBadResponse},
+			{"401 with API key", 401, url.Values{"key": {"secret"}}, providererr.ErrAuth},
+			{"401 without credentials", 401, nil, providererr.ErrRateLimited},
+			{"403 with empty API key", 403, url.Values{"key": {""}}, providererr.ErrRateLimited},
+			{"429", 429, nil, providererr.ErrRateLimited},
+			{"503", 503, nil, providererr.ErrTransient},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					header := make(stdhttp.Header)
+					header.Set("Content-Type", "text/html")
+					return &stdhttp.Response{
+						StatusCode: tc.code,
+						Body:       io.NopCloser(strings.NewReader(`<html><body>Error</body></html>`)),
+						Header:     header,
+					}, nil
+				}
+
+				client := New(logger.New(slog.LevelInfo))
+				client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+
+				target := new(testType)
+				code, err := client.Get(t.Context(), "https://example.com", target, tc.query, nil)
+				if code != tc.code {
+					t.Errorf("expected status code to be %d, got %d", tc.code, code)
+				}
+				if !errors.Is(err, tc.want) {
+					t.Errorf("expected error to be %s, got %v", tc.want, err)
+				}
+			})
+		}
+	})
+	t.Run("unsuccessful responses return a snippet of the body", func(t *testing.T) {
+		var target struct {
+			Error string `json:"error"`
+		}
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			header := make(stdhttp.Header)
+			header.Set("Content-Type", "application/json")
+			body := `{"error": "Rate limit exceeded for key secret123",` + "\n\t" + `"retry": 60}`
+			return &stdhttp.Response{
+				StatusCode: 429,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     header,
+			}, nil
+		}
+
+		client := New(logger.New(slog.LevelInfo))
+		client.Transport = &testhelper.MockRoundTripper{Fn: rtFn}
+
+		query := url.Values{"apikey": []string{"secret123"}, "q": []string{"Berlin"}}
+		code, err := client.Get(t.Context(), "https://example.com", &target, query, nil)
+		if code != 429 {
+			t.Errorf("expected status code to be 429, got %d", code)
+		}
+		if !errors.Is(err, providererr.ErrRateLimited) {
+			t.Errorf("expected error to be %s, got %v", providererr.ErrRateLimited, err)
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("expected error to be a StatusError, got %v", err)
+		}
+		wantSnippet := `{"error": "Rate limit exceeded for key [REDACTED]", "retry": 60}`
+		if statusErr.Snippet != wantSnippet {
+			t.Errorf("expected snippet to be %q, got %q", wantSnippet, statusErr.Snippet)
+		}
+		wantErr := "unsuccessful response status 429 Too Many Requests: " + wantSnippet
+		if err.Error() != wantErr {
+			t.Errorf("expected error to be %q, got %q", wantErr, err)
+		}
+		if target.Error != "Rate limit exceeded for key secret123" {
+			t.Errorf("expected the body to be decoded into the target, got %q", target.Error)
+		}
+	})
+	t.Run("path secrets are redacted from unsuccessful responses", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 401,
+				Body:       io.NopCloser(strings.NewReader("invalid key " + req.URL.Path)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		client := New(logger.New(slog.LevelInfo)).WithPathSecrets("secret123", "unused")
+		client.Transport = &testhelper.MockRoundTripper{Fn: rtFn}
+
+		_, err := client.Get(t.Context(), "https://example.com/forecast/secret123/1,2", new(testType), nil, nil)
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %v", providererr.ErrAuth, err)
+		}
+		wantErr := "unsuccessful response status 401 Unauthorized: invalid key /forecast/[REDACTED]/1,2"
+		if err == nil || err.Error() != wantErr {
+			t.Errorf("expected error to be %q, got %v", wantErr, err)
+		}
+	})
+	t.Run("unsuccessful responses without body return the status", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 503,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		client := New(logger.New(slog.LevelInfo))
+		client.Transport = &testhelper.MockRoundTripper{Fn: rtFn}
+
+		_, err := client.Get(t.Context(), "https://example.com", new(testType), nil, nil)
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %v", providererr.ErrTransient, err)
+		}
+		wantErr := "unsuccessful response status 503 Service Unavailable"
+		if err == nil || err.Error() != wantErr {
+			t.Errorf("expected error to be %q, got %v", wantErr, err)
+		}
+	})
+}
+
+func TestSanitizeSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		size int
+		want string
+	}{
+		{"plain text is kept", "Too Many Requests", 256, "Too Many Requests"},
+		{"whitespace is collapsed", "  line one\r\n\tline two  ", 256, "line one line two"},
+		{"control characters are removed", "bad\x00\x1b[31mcolor", 256, "bad[31mcolor"},
+		{"invalid UTF-8 is removed", "caf\xc3", 256, "caf"},
+		{"long text is cut", "abcdefghij", 4, "abcd…"},
+		{"text is cut at rune boundaries", "aäb", 2, "a…"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeSnippet(tc.in, tc.size); got != tc.want {
+				t.Errorf("expected snippet to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
 func TestClient_GetWithTimeout(t *testing.T) {
 	t.Run("get request fails on context cancel", func(t *testing.T) {
 		testhelper.PerformIntegrationTests(t)
@@ -192,6 +528,60 @@ func TestClient_PostWithTimeout(t *testing.T) {
 		if !errors.Is(err, context.DeadlineExceeded) {
 			t.Errorf("expected error to be %s, got %s", context.DeadlineExceeded, err)
 		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+}
+
+func TestClient_Probe(t *testing.T) {
+	t.Run("probe succeeds regardless of the status code", func(t *testing.T) {
+		for _, code := range []int{200, 404, 503} {
+			t.Run(strconv.Itoa(code), func(t *testing.T) {
+				rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					if req.Method != stdhttp.MethodHead {
+						t.Errorf("expected method %s, got %s", stdhttp.MethodHead, req.Method)
+					}
+					return &stdhttp.Response{
+						StatusCode: code,
+						Body:       io.NopCloser(strings.NewReader("")),
+						Header:     make(stdhttp.Header),
+					}, nil
+				}
+
+				client := New(logger.New(slog.LevelInfo))
+				client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+				if err := client.Probe(t.Context(), "https://example.com", time.Second); err != nil {
+					t.Errorf("probe failed: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("probe fails if the request fails", func(t *testing.T) {
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("network is unreachable")
+		}
+
+		client := New(logger.New(slog.LevelInfo))
+		client.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+		if err := client.Probe(t.Context(), "https://example.com", time.Second); err == nil {
+			t.Fatal("expected probe to fail")
+		}
+	})
+	t.Run("probe with an invalid url fails", func(t *testing.T) {
+		client := New(logger.New(slog.LevelInfo))
+		if err := client.Probe(t.Context(), "://invalid", time.Second); err == nil {
+			t.Fatal("expected probe to fail")
+		}
+	})
+	t.Run("probe fails on context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		client := New(logger.New(slog.LevelInfo))
+		if err := client.Probe(ctx, testhelper.TestOnlineAPIURL, time.Second); err == nil {
+			t.Fatal("expected probe to fail")
+		}
 	})
 }
 
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wneessen/waybar-weather/internal/providererr"
+)
+
+const (
+	// maxErrorBodySize is the maximum number of bytes of an unsuccessful response that are read
+	maxErrorBodySize = 16 << 10
+	// maxSnippetSize is the maximum number of bytes of the response body that a StatusError carries
+	maxSnippetSize = 256
+	// redacted replaces credentials in response bodies
+	redacted = "[REDACTED]"
+)
+
+// StatusError is returned by PerformReq if the server responds with a status code outside of the 2xx
+// range. Snippet is the beginning of the response body, which usually explains the status, e. g. that
+// the rate limit is exceeded or a parameter is invalid.
+type StatusError struct {
+	StatusCode int
+	Snippet    string
+}
+
+// Error satisfies the error interface for the StatusError type
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("unsuccessful response status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Snippet == "" {
+		return msg
+	}
+	return msg + ": " + e.Snippet
+}
+
+// statusError reads the body of an unsuccessful response and returns a StatusError with a sanitized
+// snippet of it, wrapped with the error kind of the status code. The full body is logged at debug level.
+// Since some APIs explain errors in JSON documents, the body is also unmarshalled into target on a best
+// effort basis. Credentials of the request are redacted, as some APIs echo the request in their errors.
+func (h *Client) statusError(response *http.Response, reqURL *url.URL, headers map[string]string,
+	target any,
+) error {
+	var content []byte
+	body, encoding, err := decodeBody(response)
+	if err == nil {
+		content, err = io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	}
+	secrets := h.secretsOf(reqURL, headers)
+	text := redactSecrets(string(content), secrets)
+	h.logger.Debug("received unsuccessful API response", slog.String("host", reqURL.Host),
+		slog.Int("status", response.StatusCode), slog.String("content_encoding", encoding),
+		slog.String("body", text))
+	if err != nil {
+		h.logger.Debug("failed to read unsuccessful API response", slog.String("host", reqURL.Host),
+			slog.String("error", err.Error()))
+	}
+	_ = json.Unmarshal(content, target)
+
+	return providererr.Wrap(providererr.Status(response.StatusCode, hasCredentials(secrets)),
+		&StatusError{StatusCode: response.StatusCode, Snippet: sanitizeSnippet(text, maxSnippetSize)})
+}
+
+// secretsOf returns the values of the query parameters and headers of a request that hold credentials,
+// judging by their names, and the path secrets of the client that are part of the request path.
+func (h *Client) secretsOf(reqURL *url.URL, headers map[string]string) []string {
+	var secrets []string
+	for _, secret := range h.pathSecrets {
+		if secret != "" && (strings.Contains(reqURL.Path, secret) || strings.Contains(reqURL.EscapedPath(), secret)) {
+			secrets = append(secrets, secret)
+		}
+	}
+	for name, values := range reqURL.Query() {
+		if isSecretName(name) {
+			secrets = append(secrets, values...)
+		}
+	}
+	for name, value := range headers {
+		if isSecretName(name) || strings.EqualFold(name, "Authorization") {
+			secrets = append(secrets, value)
+			// Authorization headers are usually of the form "<scheme> <credentials>"
+			if _, credentials, ok := strings.Cut(value, " "); ok {
+				secrets = append(secrets, credentials)
+			}
+		}
+	}
+	return secrets
+}
+
+// hasCredentials reports whether the secrets of a request, as returned by secretsOf, hold credentials.
+func hasCredentials(secrets []string) bool {
+	return slices.ContainsFunc(secrets, func(secret string) bool {
+		return secret != ""
+	})
+}
+
+// isSecretName reports whether a query parameter or header of the given name holds credentials.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "key") || strings.Contains(name, "token") || name == "appid"
+}
+
+// redactSecrets replaces all occurrences of the given secrets in s.
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+// sanitizeSnippet returns s as a single line of valid UTF-8 without control characters, cut to at most
+// size bytes. Cut snippets end with an ellipsis.
+func sanitizeSnippet(s string, size int) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= size {
+		return s
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
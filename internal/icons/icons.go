@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package icons provides the embedded SVG icon set of the weather categories and writes the icon of the
+// current weather to a file with a stable path, that e. g. a Waybar CSS background-image can point to.
+package icons
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Unknown is the name of the icon of weather conditions without weather category
+const Unknown = "unknown"
+
+//go:embed svg/*.svg
+var iconSet embed.FS
+
+// Name returns the name of the icon of the weather category. The clear category has a separate icon for
+// the night. Unknown categories fall back to the Unknown icon.
+func Name(category string, isDay bool) string {
+	switch category {
+	case "clear":
+		if isDay {
+			return "clear-day"
+		}
+		return "clear-night"
+	case "cloudy", "fog", "rain", "snow", "thunderstorm":
+		return category
+	default:
+		return Unknown
+	}
+}
+
+// SVG returns the SVG image of the icon with the given name.
+func SVG(name string) ([]byte, error) {
+	data, err := iconSet.ReadFile("svg/" + name + ".svg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon %q: %w", name, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes the icon with the given name to the path. The icon is written to a temporary file
+// first and then renamed, so that a reader never sees a partially written icon.
+func WriteFile(path, name string) error {
+	data, err := SVG(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create icon directory: %w", err)
+	}
+	tmpFile := path + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write icon file: %w", err)
+	}
+	if err = os.Rename(tmpFile, path); err != nil {
+		return fmt.Errorf("failed to replace icon file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package icons
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		category string
+		isDay    bool
+		want     string
+	}{
+		{"clear", true, "clear-day"},
+		{"clear", false, "clear-night"},
+		{"cloudy", true, "cloudy"},
+		{"fog", false, "fog"},
+		{"rain", true, "rain"},
+		{"snow", true, "snow"},
+		{"thunderstorm", false, "thunderstorm"},
+		{"", true, Unknown},
+		{"hurricane", true, Unknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := Name(tc.category, tc.isDay); got != tc.want {
+				t.Errorf("expected icon name to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSVG(t *testing.T) {
+	t.Run("every icon is embedded", func(t *testing.T) {
+		for _, name := range []string{"clear-day", "clear-night", "cloudy", "fog", "rain", "snow",
+			"thunderstorm", Unknown} {
+			data, err := SVG(name)
+			if err != nil {
+				t.Fatalf("failed to read icon: %s", err)
+			}
+			if !bytes.HasPrefix(data, []byte("<svg ")) {
+				t.Errorf("expected icon %q to be an SVG image", name)
+			}
+		}
+	})
+	t.Run("unknown icon name fails", func(t *testing.T) {
+		if _, err := SVG("hurricane"); err == nil {
+			t.Error("expected reading an unknown icon to fail")
+		}
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "waybar-weather", "current.svg")
+	t.Run("icon is written", func(t *testing.T) {
+		if err := WriteFile(path, "rain"); err != nil {
+			t.Fatalf("failed to write icon file: %s", err)
+		}
+		checkIconFile(t, path, "rain")
+	})
+	t.Run("icon is replaced", func(t *testing.T) {
+		if err := WriteFile(path, "snow"); err != nil {
+			t.Fatalf("failed to write icon file: %s", err)
+		}
+		checkIconFile(t, path, "snow")
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("expected temporary icon file to be removed, got: %v", err)
+		}
+	})
+	t.Run("unknown icon name fails", func(t *testing.T) {
+		if err := WriteFile(path, "hurricane"); err == nil {
+			t.Error("expected writing an unknown icon to fail")
+		}
+		checkIconFile(t, path, "snow")
+	})
+}
+
+func checkIconFile(t *testing.T, path, name string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read icon file: %s", err)
+	}
+	want, err := SVG(name)
+	if err != nil {
+		t.Fatalf("failed to read icon: %s", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("expected icon file to hold the %q icon", name)
+	}
+}
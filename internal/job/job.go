@@ -6,52 +6,108 @@ package job
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
 // Job represents a scheduled task that runs at a fixed interval
 // and never overlaps with itself (singleton mode).
 type Job struct {
+	task func(context.Context)
+
+	lock     sync.RWMutex
 	interval time.Duration
-	task     func(context.Context)
+	// jitter returns the random offset that is added to the interval before each run. It is nil if the
+	// job runs at the fixed interval.
+	jitter func() time.Duration
+	// reschedule wakes up Start when the interval has been changed
+	reschedule chan struct{}
 }
 
 // New creates a new Job with the given interval and task.
 func New(interval time.Duration, task func(context.Context)) *Job {
 	return &Job{
-		interval: interval,
-		task:     task,
+		interval:   interval,
+		task:       task,
+		reschedule: make(chan struct{}, 1),
+	}
+}
+
+// Interval returns the current interval of the job.
+func (j *Job) Interval() time.Duration {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return j.interval
+}
+
+// SetJitter sets the function that returns the random offset that is added to the interval before each
+// run of the job. It must be called before the job is started.
+func (j *Job) SetJitter(jitter func() time.Duration) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.jitter = jitter
+}
+
+// nextDelay returns the delay until the next run of the job, which is the interval plus the jitter.
+func (j *Job) nextDelay() time.Duration {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	if j.jitter == nil {
+		return j.interval
+	}
+	return j.interval + j.jitter()
+}
+
+// SetInterval changes the interval of the job. If the job has been started, it is rescheduled right away,
+// so that the next run happens one new interval after the change.
+func (j *Job) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	j.lock.Lock()
+	j.interval = interval
+	j.lock.Unlock()
+
+	select {
+	case j.reschedule <- struct{}{}:
+	default:
 	}
 }
 
-// Start begins executing the job on the given context. It returns when the context is cancelled.
-// It executes jobs in singleton mode, meaning if a tick fires while a previous run is still
-// executing, that tick is skipped.
+// Start begins executing the job on the given context. It returns when the context is cancelled and
+// the run in progress, if any, has finished. It executes jobs in singleton mode, meaning if a tick
+// fires while a previous run is still executing, that tick is skipped.
 func (j *Job) Start(ctx context.Context) {
-	if j.task == nil || j.interval <= 0 {
+	if j.task == nil || j.Interval() <= 0 {
 		return
 	}
 
-	ticker := time.NewTicker(j.interval)
-	defer ticker.Stop()
+	timer := time.NewTimer(j.nextDelay())
+	defer timer.Stop()
 
 	// sem is a 1-slot semaphore that guards "is a run in progress?"
 	sem := make(chan struct{}, 1)
+	// running tracks the run in progress, which receives the cancellation of the context
+	var running sync.WaitGroup
+	defer running.Wait()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-j.reschedule:
+			timer.Reset(j.nextDelay())
+		case <-timer.C:
+			timer.Reset(j.nextDelay())
 			// Try to acquire the semaphore without blocking.
 			select {
 			case sem <- struct{}{}:
-				go func() {
+				running.Go(func() {
 					defer func() { <-sem }()
 					runCtx, cancel := context.WithCancel(ctx)
 					defer cancel()
 					j.task(runCtx)
-				}()
+				})
 			default:
 			}
 		}
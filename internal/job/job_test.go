@@ -6,6 +6,7 @@ package job
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -67,6 +68,104 @@ func TestJob_Start(t *testing.T) {
 			tester.Start(t.Context())
 		})
 	})
+	t.Run("run in progress is awaited on cancellation", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			var finished atomic.Bool
+			testJob := New(time.Millisecond*10, func(ctx context.Context) {
+				<-ctx.Done()
+				time.Sleep(time.Millisecond * 50)
+				finished.Store(true)
+			})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				testJob.Start(ctx)
+			}()
+			time.Sleep(time.Millisecond * 15)
+			cancel()
+			<-done
+			if !finished.Load() {
+				t.Error("expected job to return after the run in progress finished")
+			}
+		})
+	})
+}
+
+func TestJob_SetInterval(t *testing.T) {
+	t.Run("job is rescheduled with the new interval", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			var runs atomic.Int32
+			testJob := New(time.Minute, func(context.Context) { runs.Add(1) })
+			go testJob.Start(ctx)
+
+			time.Sleep(time.Second * 30)
+			testJob.SetInterval(time.Minute * 3)
+			if testJob.Interval() != time.Minute*3 {
+				t.Errorf("expected interval to be %s, got %s", time.Minute*3, testJob.Interval())
+			}
+			time.Sleep(time.Minute*3 - time.Second)
+			synctest.Wait()
+			if runs.Load() != 0 {
+				t.Errorf("expected job to not run before the new interval elapsed, got %d runs", runs.Load())
+			}
+			time.Sleep(time.Second * 2)
+			synctest.Wait()
+			if runs.Load() != 1 {
+				t.Errorf("expected job to run once after the new interval, got %d runs", runs.Load())
+			}
+		})
+	})
+	t.Run("non-positive intervals are ignored", func(t *testing.T) {
+		testJob := New(time.Minute, func(context.Context) {})
+		testJob.SetInterval(0)
+		if testJob.Interval() != time.Minute {
+			t.Errorf("expected interval to be %s, got %s", time.Minute, testJob.Interval())
+		}
+	})
+}
+
+func TestJob_SetJitter(t *testing.T) {
+	t.Run("every run is offset by the jitter", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			var runs atomic.Int32
+			offsets := []time.Duration{time.Second * 30, time.Second * 10}
+			var calls int
+			testJob := New(time.Minute, func(context.Context) { runs.Add(1) })
+			testJob.SetJitter(func() time.Duration {
+				offset := offsets[calls%len(offsets)]
+				calls++
+				return offset
+			})
+			go testJob.Start(ctx)
+
+			time.Sleep(time.Second * 89)
+			synctest.Wait()
+			if runs.Load() != 0 {
+				t.Errorf("expected job to not run before the interval and jitter elapsed, got %d runs", runs.Load())
+			}
+			time.Sleep(time.Second * 2)
+			synctest.Wait()
+			if runs.Load() != 1 {
+				t.Errorf("expected job to run once after the interval and jitter, got %d runs", runs.Load())
+			}
+			time.Sleep(time.Second * 68)
+			synctest.Wait()
+			if runs.Load() != 1 {
+				t.Errorf("expected job to not run before the second jitter elapsed, got %d runs", runs.Load())
+			}
+			time.Sleep(time.Second * 2)
+			synctest.Wait()
+			if runs.Load() != 2 {
+				t.Errorf("expected job to run twice after the second jitter, got %d runs", runs.Load())
+			}
+		})
+	})
 }
 
 func (t *testType) testFunc(ctx context.Context) {
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"math"
+	"strings"
+
+	"github.com/vorlif/spreak/localize"
+
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// Comfort indices that describe which derived temperature applies to a weather instant
+const (
+	ComfortWindChill = "windchill"
+	ComfortHeatIndex = "heatindex"
+)
+
+const (
+	// The NOAA wind chill is only defined for temperatures below 10°C and wind speeds above 4.8 km/h
+	windChillMaxTemp  = 10.0
+	windChillMinSpeed = 4.8
+	// The NOAA heat index is only meaningful for temperatures of 27°C and above and a humidity of 40%
+	// and above
+	heatIndexMinTemp     = 27.0
+	heatIndexMinHumidity = 40.0
+)
+
+// comfortLabels maps the comfort index to the labels and their lower (heat index) or upper (wind chill)
+// threshold in °C, ordered from the most to the least severe.
+var comfortLabels = map[string][]struct {
+	threshold float64
+	label     localize.MsgID
+}{
+	ComfortHeatIndex: {
+		{54, "feels extremely hot"},
+		{41, "feels dangerously hot"},
+		{32, "feels very hot"},
+		{math.Inf(-1), "feels hot"},
+	},
+	ComfortWindChill: {
+		{-40, "feels extremely cold"},
+		{-28, "feels dangerously cold"},
+		{-10, "feels very cold"},
+		{math.Inf(1), "feels cold"},
+	},
+}
+
+// comfort calculates the wind chill and heat index for the given weather instant. Both values default
+// to the plain temperature if their formula does not apply. The returned index reports which of the
+// two applies, or is empty if neither does.
+func comfort(in weather.Instant) (windChill, heatIndex float64, index string) {
+	windChill, heatIndex = in.Temperature, in.Temperature
+	fahrenheit := isFahrenheit(in.Units.Temperature)
+	temp := in.Temperature
+	if fahrenheit {
+		temp = fahrenheitToCelsius(temp)
+	}
+
+	speed := windSpeedKmh(in.WindSpeed, in.Units.WindSpeed)
+	if temp < windChillMaxTemp && speed > windChillMinSpeed {
+		windChill = windChillCelsius(temp, speed)
+		if fahrenheit {
+			windChill = celsiusToFahrenheit(windChill)
+		}
+		return windChill, heatIndex, ComfortWindChill
+	}
+
+	if temp >= heatIndexMinTemp && in.RelativeHumidity >= heatIndexMinHumidity {
+		heatIndex = heatIndexFahrenheit(celsiusToFahrenheit(temp), in.RelativeHumidity)
+		if !fahrenheit {
+			heatIndex = fahrenheitToCelsius(heatIndex)
+		}
+		return windChill, heatIndex, ComfortHeatIndex
+	}
+
+	return windChill, heatIndex, ""
+}
+
+// comfortLabel returns the localized label for the given comfort index and derived temperature.
+func (p *Presenter) comfortLabel(index string, value float64, unit string) string {
+	labels, ok := comfortLabels[index]
+	if !ok {
+		return ""
+	}
+	if isFahrenheit(unit) {
+		value = fahrenheitToCelsius(value)
+	}
+	for _, l := range labels {
+		if index == ComfortHeatIndex && value >= l.threshold ||
+			index == ComfortWindChill && value <= l.threshold {
+			return p.localizer.Get(l.label)
+		}
+	}
+	return ""
+}
+
+// windChillCelsius calculates the NOAA wind chill for the given temperature in °C and wind speed in km/h.
+func windChillCelsius(temp, speed float64) float64 {
+	v := math.Pow(speed, 0.16)
+	return 13.12 + 0.6215*temp - 11.37*v + 0.3965*temp*v
+}
+
+// heatIndexFahrenheit calculates the NOAA heat index for the given temperature in °F and relative
+// humidity in percent, using the Rothfusz regression and its adjustments for very low and very high
+// humidity.
+func heatIndexFahrenheit(temp, humidity float64) float64 {
+	simple := 0.5 * (temp + 61.0 + (temp-68.0)*1.2 + humidity*0.094)
+	if (simple+temp)/2 < 80 {
+		return simple
+	}
+
+	index := -42.379 + 2.04901523*temp + 10.14333127*humidity - 0.22475541*temp*humidity -
+		0.00683783*temp*temp - 0.05481717*humidity*humidity + 0.00122874*temp*temp*humidity +
+		0.00085282*temp*humidity*humidity - 0.00000199*temp*temp*humidity*humidity
+	switch {
+	case humidity < 13 && temp >= 80 && temp <= 112:
+		index -= (13 - humidity) / 4 * math.Sqrt((17-math.Abs(temp-95))/17)
+	case humidity > 85 && temp >= 80 && temp <= 87:
+		index += (humidity - 85) / 10 * (87 - temp) / 5
+	}
+	return index
+}
+
+// windSpeedKmh converts the wind speed in the given unit to km/h. Unknown units are assumed to be km/h.
+func windSpeedKmh(speed float64, unit string) float64 {
+	switch strings.ToLower(unit) {
+	case "mph", "mp/h":
+		return speed * 1.609344
+	case "m/s", "ms":
+		return speed * 3.6
+	case "kn", "kt", "knots":
+		return speed * 1.852
+	default:
+		return speed
+	}
+}
+
+// isFahrenheit reports whether the given temperature unit denotes degrees Fahrenheit.
+func isFahrenheit(unit string) bool {
+	return strings.HasSuffix(strings.ToUpper(unit), "F")
+}
+
+func fahrenheitToCelsius(temp float64) float64 {
+	return (temp - 32) * 5 / 9
+}
+
+func celsiusToFahrenheit(temp float64) float64 {
+	return temp*9/5 + 32
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"time"
+)
+
+// age returns the duration since the given time, or zero for the zero time or a time in the future.
+func age(at, now time.Time) time.Duration {
+	if at.IsZero() || at.After(now) {
+		return 0
+	}
+	return now.Sub(at)
+}
+
+// ageStr returns a localized description of the duration since the given time, e. g. "23 minutes". It
+// returns an empty string for the zero time.
+func (p *Presenter) ageStr(at, now time.Time) string {
+	if at.IsZero() {
+		return ""
+	}
+	return p.humanizer.TimeSinceFrom(at, now)
+}
+
+// freshness returns a localized sentence on the age of the weather data, e. g. "Data from 23 minutes ago".
+func (p *Presenter) freshness(dataAge time.Duration) string {
+	now := p.Clock.Now()
+	return p.localizer.Getf("Data from %s ago", p.ageStr(now.Add(-dataAge), now))
+}
+
+// SetUpdateInterval sets the interval in which the weather data is updated, which changes with the power
+// source. It is safe for concurrent use.
+func (p *Presenter) SetUpdateInterval(interval time.Duration) {
+	p.updateInterval.Store(int64(interval))
+}
+
+// isStale reports whether weather data of the given age should have been replaced by a weather update
+// already, e. g. after a resume or a network outage. The data is stale once it is older than the update
+// interval plus the jitter, since the update may be delayed by up to the jitter.
+func (p *Presenter) isStale(dataAge time.Duration) bool {
+	interval := time.Duration(p.updateInterval.Load())
+	return interval > 0 && dataAge > interval+p.jitter
+}
@@ -6,27 +6,66 @@ package presenter
 
 import (
 	"fmt"
+	"maps"
 	"math"
+	"reflect"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/vorlif/humanize"
+
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
+// regionalIndicatorA is the Unicode regional indicator symbol for the letter A. Two regional indicators
+// form the emoji flag of the country with the corresponding ISO country code.
+const regionalIndicatorA = '\U0001F1E6'
+
 func (p *Presenter) templateFuncMap() template.FuncMap {
-	return template.FuncMap{
+	funcs := template.FuncMap{
 		"timeFormat":      p.timeFormat,
 		"localizedTime":   p.localizedTime,
+		"prefTime":        p.prefTime,
+		"inTimezone":      p.inTimezone,
 		"floatFormat":     p.floatFormat,
 		"loc":             p.loc,
 		"hum":             p.hum,
 		"lc":              strings.ToLower,
 		"uc":              strings.ToUpper,
 		"fcastHourOffset": p.forecastByOffset,
+		"yesterdayAt":     p.yesterdayAt,
+		"nowcastAt":       p.nowcastAt,
+		"nextRainMinutes": p.nextRainMinutes,
 		"windDir":         p.degToString,
 		"windDirIcon":     p.windDirIcon,
+		"shortAddress":    shortAddress,
+		"countryFlag":     countryFlag,
+		"padIcon":         p.padIcon,
+		"freshness":       p.freshness,
+		"hours":           p.nextHours,
+		"worstCondition":  p.worstCondition,
+		"minTemp":         forecastMinTemp,
+		"maxTemp":         forecastMaxTemp,
+		"avgTemp":         forecastAvgTemp,
+		"add":             add,
+		"sub":             sub,
+		"round":           round,
+		"gtf":             gtf,
+		"ltf":             ltf,
+		"sparkline":       p.sparkline,
+		"forecastTable":   p.forecastTable,
+		"emojiStrip":      p.emojiStrip,
+		"emojiTempStrip":  p.emojiTempStrip,
+		"bold":            p.bold,
+		"italic":          p.italic,
+		"color":           p.color,
+		escapeFunc:        pangoEscape,
 	}
+	maps.Copy(funcs, p.gradientFuncMap(p.gradientUnit))
+	return funcs
 }
 
 func (p *Presenter) loc(val string) string {
@@ -45,10 +84,35 @@ func (p *Presenter) localizedTime(val time.Time) string {
 	return p.humanizer.FormatTime(val, humanize.TimeFormat)
 }
 
+// FormatTime formats the time like the prefTime template function, e. g. for output outside of the
+// templates.
+func (p *Presenter) FormatTime(val time.Time) string {
+	return p.prefTime(val)
+}
+
+// prefTime formats the time with the configured time format. If no time format is configured, the
+// localized time format is used.
+func (p *Presenter) prefTime(val time.Time) string {
+	if p.timeLayout != "" {
+		return val.Format(p.timeLayout)
+	}
+	return p.localizedTime(val)
+}
+
 func (p *Presenter) timeFormat(val time.Time, fmt string) string {
 	return val.Format(fmt)
 }
 
+// inTimezone formats the time in the given IANA timezone. If the timezone is empty or unknown, the
+// time is formatted in the local timezone.
+func (p *Presenter) inTimezone(val time.Time, tz, layout string) string {
+	loc, err := time.LoadLocation(tz)
+	if tz == "" || err != nil {
+		loc = time.Local
+	}
+	return val.In(loc).Format(layout)
+}
+
 func (p *Presenter) floatFormat(val float64, precision int) string {
 	pow := math.Pow(10, float64(precision))
 	return fmt.Sprintf("%.*f", precision, math.Trunc(val*pow)/pow)
@@ -56,13 +120,12 @@ func (p *Presenter) floatFormat(val float64, precision int) string {
 
 // forecast returns the forecast at the given offset (0-based).
 func (p *Presenter) forecastByOffset(ctx TemplateContext, offset int) WeatherView {
-	if offset < 0 || offset >= len(ctx.Forecasts) {
+	if offset < 0 || offset >= len(ctx.hours) {
 		return WeatherView{}
 	}
 
-	currentUTC := ctx.Current.InstantTime.Truncate(time.Hour)
-	want := currentUTC.In(time.Local).Add(time.Hour * time.Duration(offset))
-	for _, fcast := range ctx.Forecasts {
+	want := weather.NewDayHour(ctx.Current.InstantTime).Time().Add(time.Hour * time.Duration(offset))
+	for _, fcast := range ctx.hours {
 		if fcast.InstantTime.Equal(want) {
 			return fcast
 		}
@@ -71,6 +134,153 @@ func (p *Presenter) forecastByOffset(ctx TemplateContext, offset int) WeatherVie
 	return WeatherView{}
 }
 
+// yesterdayAt returns the weather instant of roughly 24 hours before the given time. If no weather data
+// is available for that hour, an empty WeatherView is returned.
+func (p *Presenter) yesterdayAt(ctx TemplateContext, at time.Time) WeatherView {
+	want := weather.NewDayHour(at.Add(-time.Hour * 24)).Time()
+	for _, fcast := range ctx.hours {
+		if fcast.InstantTime.Equal(want) {
+			return fcast
+		}
+	}
+
+	return WeatherView{}
+}
+
+// nextHours returns the forecasts of the next n hours after the hour of the current weather instant,
+// sorted by time. Fewer forecasts are returned, if the weather data does not cover the requested hours.
+func (p *Presenter) nextHours(ctx TemplateContext, n int) []WeatherView {
+	if n <= 0 {
+		return nil
+	}
+	start := weather.NewDayHour(ctx.Current.InstantTime).Time()
+	end := start.Add(time.Hour * time.Duration(n))
+	views := make([]WeatherView, 0, n)
+	for _, fcast := range ctx.hours {
+		if fcast.InstantTime.After(start) && !fcast.InstantTime.After(end) {
+			views = append(views, fcast)
+		}
+	}
+	slices.SortFunc(views, func(a, b WeatherView) int {
+		return a.InstantTime.Compare(b.InstantTime)
+	})
+	return views
+}
+
+// forecastMinTemp returns the lowest temperature of the given forecasts, or 0 if there are none.
+func forecastMinTemp(views []WeatherView) float64 {
+	if len(views) == 0 {
+		return 0
+	}
+	low := views[0].Temperature
+	for _, view := range views[1:] {
+		low = min(low, view.Temperature)
+	}
+	return low
+}
+
+// forecastMaxTemp returns the highest temperature of the given forecasts, or 0 if there are none.
+func forecastMaxTemp(views []WeatherView) float64 {
+	if len(views) == 0 {
+		return 0
+	}
+	high := views[0].Temperature
+	for _, view := range views[1:] {
+		high = max(high, view.Temperature)
+	}
+	return high
+}
+
+// forecastAvgTemp returns the average temperature of the given forecasts, or 0 if there are none.
+func forecastAvgTemp(views []WeatherView) float64 {
+	if len(views) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, view := range views {
+		sum += view.Temperature
+	}
+	return sum / float64(len(views))
+}
+
+// add returns the sum of two numbers of any integer or float type.
+func add(a, b any) (float64, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return 0, err
+	}
+	return x + y, nil
+}
+
+// sub returns the difference of two numbers of any integer or float type.
+func sub(a, b any) (float64, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return 0, err
+	}
+	return x - y, nil
+}
+
+// round rounds a number of any integer or float type to the given precision.
+func round(val any, precision int) (float64, error) {
+	x, err := toFloat(val)
+	if err != nil {
+		return 0, err
+	}
+	pow := math.Pow(10, float64(precision))
+	return math.Round(x*pow) / pow, nil
+}
+
+// gtf reports whether a is greater than b. Unlike the builtin gt, the numbers may be of any integer or
+// float type, so that e. g. a float64 field can be compared with an integer literal.
+func gtf(a, b any) (bool, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return false, err
+	}
+	return x > y, nil
+}
+
+// ltf reports whether a is less than b. Like gtf, the numbers may be of any integer or float type.
+func ltf(a, b any) (bool, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return false, err
+	}
+	return x < y, nil
+}
+
+// toFloat converts a value of any integer or float type to a float64.
+func toFloat(val any) (float64, error) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", val)
+	}
+}
+
 func (p *Presenter) degToString(deg float64) string {
 	switch {
 	case deg < 22.5:
@@ -100,3 +310,53 @@ func (p *Presenter) windDirIcon(dir string) string {
 	}
 	return ""
 }
+
+// padIcon pads the icon with spaces to the configured display width, so that narrow and wide emoji take up
+// the same space in the output. Icons that are already wider are returned unchanged.
+func (p *Presenter) padIcon(icon string) string {
+	return padRight(icon, p.iconWidth)
+}
+
+// shortAddress returns a short "City, Country" representation of the address. If the city is unknown,
+// the municipality or state is used instead.
+func shortAddress(addr geocode.Address) string {
+	place := addr.City
+	if place == "" {
+		place = addr.Municipality
+	}
+	if place == "" {
+		place = addr.State
+	}
+	return collapseAddress(place + ", " + addr.Country)
+}
+
+// countryFlag returns the emoji flag for the given ISO 3166-1 alpha-2 country code. An empty string is
+// returned for invalid country codes.
+func countryFlag(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 {
+		return ""
+	}
+	flag := make([]rune, 0, 2)
+	for _, char := range code {
+		if char < 'A' || char > 'Z' {
+			return ""
+		}
+		flag = append(flag, regionalIndicatorA+char-'A')
+	}
+	return string(flag)
+}
+
+// collapseAddress removes the empty parts of a comma-separated address, so that missing address fields
+// don't leave dangling commas behind. Consecutive whitespace within the parts is collapsed as well.
+func collapseAddress(val string) string {
+	parts := strings.Split(val, ",")
+	kept := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.Join(strings.Fields(part), " ")
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"fmt"
+	"math"
+	"text/template"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+)
+
+// gradientStop is a stop of the temperature color gradient with the parsed color components.
+type gradientStop struct {
+	temperature float64
+	rgb         [3]uint8
+}
+
+// parseGradient parses the normalized stops of the temperature color gradient.
+func parseGradient(stops []config.GradientStop) ([]gradientStop, error) {
+	gradient := make([]gradientStop, 0, len(stops))
+	for _, stop := range stops {
+		rgb, err := config.ParseHexColor(stop.Color)
+		if err != nil {
+			return nil, err
+		}
+		gradient = append(gradient, gradientStop{temperature: stop.Temperature, rgb: rgb})
+	}
+	return gradient, nil
+}
+
+// gradientFuncMap returns the tempColor and colorize template functions for temperatures in the given unit.
+func (p *Presenter) gradientFuncMap(unit string) template.FuncMap {
+	return template.FuncMap{
+		"tempColor": func(temp any) (string, error) {
+			return p.tempColor(unit, temp)
+		},
+		"colorize": func(temp, val any) (Markup, error) {
+			return p.colorize(unit, temp, val)
+		},
+	}
+}
+
+// tempColor maps the temperature in the given temperature unit to a hex color of the configured
+// gradient. Between two stops, the color components are interpolated linearly. Temperatures outside the
+// gradient are clamped to the color of the first or last stop.
+func (p *Presenter) tempColor(unit string, temp any) (string, error) {
+	val, err := toFloat(temp)
+	if err != nil {
+		return "", err
+	}
+	gradient := p.temperaturesIn(unit).gradient
+	if len(gradient) == 0 {
+		return "", nil
+	}
+
+	first, last := gradient[0], gradient[len(gradient)-1]
+	switch {
+	case val <= first.temperature:
+		return hexColor(first.rgb), nil
+	case val >= last.temperature:
+		return hexColor(last.rgb), nil
+	}
+	for i := 1; i < len(gradient); i++ {
+		lower, upper := gradient[i-1], gradient[i]
+		if val > upper.temperature {
+			continue
+		}
+		frac := (val - lower.temperature) / (upper.temperature - lower.temperature)
+		var rgb [3]uint8
+		for c := range rgb {
+			from, to := float64(lower.rgb[c]), float64(upper.rgb[c])
+			rgb[c] = uint8(math.Round(from + (to-from)*frac))
+		}
+		return hexColor(rgb), nil
+	}
+	return hexColor(last.rgb), nil
+}
+
+// colorize wraps the given value in a Pango span with the gradient color of the temperature, if Pango
+// markup is enabled for the tooltips. Otherwise, the value is returned as is.
+func (p *Presenter) colorize(unit string, temp, val any) (Markup, error) {
+	if !p.tooltipMarkup {
+		return Markup(rawValue(val)), nil
+	}
+	col, err := p.tempColor(unit, temp)
+	if err != nil {
+		return "", err
+	}
+	return p.color(col, val), nil
+}
+
+// hexColor returns the color components as hex color in the form "#rrggbb".
+func hexColor(rgb [3]uint8) string {
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
@@ -18,6 +18,13 @@ var MoonPhaseIcon = map[string]string{
 	"Waning Crescent": "🌘",
 }
 
+const (
+	// unknownCondition describes weather codes that are missing in WMOWeatherCodes
+	unknownCondition localize.MsgID = "Unknown"
+	// unknownConditionIcon is the icon of weather codes that are missing in WMOWeatherIcons
+	unknownConditionIcon = "❓"
+)
+
 // WMOWeatherCodes maps WMO weather code integers to their descriptions
 var WMOWeatherCodes = map[int]localize.MsgID{
 	0:  "Clear sky",
@@ -188,6 +195,18 @@ var i18nVars = map[string]localize.MsgID{
 	"waning gibbous":  "Waning gibbous",
 	"third quarter":   "Third quarter",
 	"waning crescent": "Waning crescent",
+	"locating":        "Locating",
+	"unavailable":     "Weather unavailable",
+	"todayprecip":     "Precipitation today",
+	"today":           "Today",
+	"stationpressure": "Station pressure",
+	"sunelevation":    "Sun elevation",
+	"goldenhour":      "Golden hour",
+	"bluehour":        "Blue hour",
+	"polarnight":      "Polar night",
+	"midnightsun":     "Midnight sun",
+	"yesterday":       "Yesterday",
+	"tomorrow":        "Tomorrow",
 }
 
 var windDirIcons = map[string]string{
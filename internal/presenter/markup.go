@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+)
+
+// escapeFunc is the name of the template function that is appended to every action of a template with
+// Pango markup enabled.
+const escapeFunc = "pangoEscape"
+
+// Markup is a string that already contains valid Pango markup and must not be escaped again.
+type Markup string
+
+// pangoEscaper replaces the characters that have a special meaning in Pango markup with their entities.
+var pangoEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+// pangoEscape returns the Pango markup escaped string representation of the given value. Markup values are
+// returned unchanged.
+func pangoEscape(val any) string {
+	if markup, ok := val.(Markup); ok {
+		return string(markup)
+	}
+	return pangoEscaper.Replace(fmt.Sprint(val))
+}
+
+// rawValue returns the string representation of the given value without any escaping.
+func rawValue(val any) string {
+	if markup, ok := val.(Markup); ok {
+		return string(markup)
+	}
+	return fmt.Sprint(val)
+}
+
+// wrapMarkup wraps the escaped value in the given tags, if Pango markup is enabled for the tooltips.
+// Otherwise, the value is returned as is, since plain text tooltips are neither escaped nor formatted.
+func (p *Presenter) wrapMarkup(open, close string, val any) Markup {
+	if !p.tooltipMarkup {
+		return Markup(rawValue(val))
+	}
+	return Markup(open + pangoEscape(val) + close)
+}
+
+// bold wraps the given value in a Pango bold tag.
+func (p *Presenter) bold(val any) Markup {
+	return p.wrapMarkup("<b>", "</b>", val)
+}
+
+// italic wraps the given value in a Pango italic tag.
+func (p *Presenter) italic(val any) Markup {
+	return p.wrapMarkup("<i>", "</i>", val)
+}
+
+// color wraps the given value in a Pango span with the given foreground color, which can be a color name
+// like "red" or a hex value like "#ff0000".
+func (p *Presenter) color(col string, val any) Markup {
+	return p.wrapMarkup(`<span foreground="`+pangoEscaper.Replace(col)+`">`, "</span>", val)
+}
+
+// escapeTemplate rewrites all actions of the template and its associated templates, so that their output
+// is escaped for Pango markup. Like html/template, the literal text of the template is not escaped.
+func escapeTemplate(tpl *template.Template) {
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			escapeNode(t.Tree.Root)
+		}
+	}
+}
+
+// escapeNode appends the escape function to the pipeline of all actions in the given node and its children.
+// Actions that only declare or assign variables are skipped, since they don't produce any output.
+func escapeNode(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeNode(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	case *parse.RangeNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	case *parse.WithNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	}
+}
+
+// stripControl removes all control characters except for newlines and tabs from the rendered output, since
+// they would garble the Waybar module.
+func stripControl(val string) string {
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, val)
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// Observation is a temperature of the current weather in the given unit and the time it was observed.
+type Observation struct {
+	Temperature float64
+	Unit        string
+	At          time.Time
+}
+
+// SetObserved sets the lowest and highest temperature of the current weather that were observed today in
+// the template context. The temperatures are converted into the preferred temperature unit.
+func (p *Presenter) SetObserved(tplCtx *TemplateContext, low, high Observation) {
+	low, high = p.convertObservation(low), p.convertObservation(high)
+	tplCtx.HasObserved = true
+	tplCtx.ObservedLow, tplCtx.ObservedLowAt = low.Temperature, low.At
+	tplCtx.ObservedHigh, tplCtx.ObservedHighAt = high.Temperature, high.At
+	tplCtx.ObservedLowStr = p.formatValue(low.Temperature, p.precision.temperature, low.Unit)
+	tplCtx.ObservedHighStr = p.formatValue(high.Temperature, p.precision.temperature, high.Unit)
+}
+
+// convertObservation converts the temperature of the observation into the preferred temperature unit.
+func (p *Presenter) convertObservation(obs Observation) Observation {
+	instant := weather.Instant{Temperature: obs.Temperature, Units: weather.Units{Temperature: obs.Unit}}
+	instant = instant.Convert(p.units)
+	obs.Temperature, obs.Unit = instant.Temperature, instant.Units.Temperature
+	return obs
+}
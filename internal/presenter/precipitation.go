@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// precipitationCategories are the weather categories that count as precipitation
+var precipitationCategories = []string{"rain", "snow", "thunderstorm"}
+
+// precipTransition is a transition into or out of precipitation within the hourly forecast.
+type precipTransition struct {
+	// at is the start of the first hour after the transition. It is zero if there is no transition
+	// within the forecast.
+	at time.Time
+	// weatherCode is the weather code of the precipitating hour next to the transition
+	weatherCode int
+}
+
+// nextPrecipitation returns the next transitions into and out of precipitation within the hourly forecast,
+// starting with the current conditions. While it is precipitating, only the end is returned. If no
+// precipitation is forecast, both transitions are zero.
+func (p *Presenter) nextPrecipitation(data *weather.Data) (start, end precipTransition) {
+	now := p.Clock.Now()
+	precipitating := isPrecipitation(data.Current.WeatherCode)
+	end.weatherCode = data.Current.WeatherCode
+	for i := 1; i <= len(data.Forecast); i++ {
+		instant, ok := data.InstantAt(now.Add(time.Hour * time.Duration(i)))
+		if !ok {
+			break
+		}
+		switch {
+		case isPrecipitation(instant.WeatherCode) && !precipitating:
+			start = precipTransition{at: instant.InstantTime, weatherCode: instant.WeatherCode}
+			precipitating = true
+			end.weatherCode = instant.WeatherCode
+		case isPrecipitation(instant.WeatherCode):
+			end.weatherCode = instant.WeatherCode
+		case precipitating:
+			end.at = instant.InstantTime
+			return start, end
+		}
+	}
+	return start, end
+}
+
+// precipStartStr returns a localized description of the start of the precipitation, e. g. "Slight rain
+// starting around 2 p.m." or "Slight rain starting in 40 min" if it starts within the next hour.
+func (p *Presenter) precipStartStr(start precipTransition) string {
+	if start.at.IsZero() {
+		return ""
+	}
+	condition := p.conditionFor(start.weatherCode)
+	if minutes, ok := p.minutesUntil(start.at); ok {
+		return p.localizer.Getf("%s starting in %d min", condition, minutes)
+	}
+	return p.localizer.Getf("%s starting around %s", condition, p.prefTime(start.at))
+}
+
+// precipEndStr returns a localized description of the end of the precipitation, e. g. "Slight rain
+// ending around 2 p.m." or "Slight rain ending in 40 min" if it ends within the next hour.
+func (p *Presenter) precipEndStr(end precipTransition) string {
+	if end.at.IsZero() {
+		return ""
+	}
+	condition := p.conditionFor(end.weatherCode)
+	if minutes, ok := p.minutesUntil(end.at); ok {
+		return p.localizer.Getf("%s ending in %d min", condition, minutes)
+	}
+	return p.localizer.Getf("%s ending around %s", condition, p.prefTime(end.at))
+}
+
+// minutesUntil returns the rounded number of minutes until the given time, but at least one. The second
+// return value is false, if the time is an hour or more ahead.
+func (p *Presenter) minutesUntil(at time.Time) (int, bool) {
+	until := at.Sub(p.Clock.Now())
+	if until >= time.Hour {
+		return 0, false
+	}
+	return max(int(math.Round(until.Minutes())), 1), true
+}
+
+// NowcastView is a step of the precipitation nowcast. Precipitation is the sum of the precipitation
+// between Start and End in the preferred precipitation unit, PrecipitationStr holds it rounded to the
+// configured precision and suffixed with the unit.
+type NowcastView struct {
+	Start            time.Time
+	End              time.Time
+	Precipitation    float64
+	PrecipitationStr string
+}
+
+// nowcastViews returns the views of the nowcast steps converted to the preferred precipitation unit.
+func (p *Presenter) nowcastViews(steps []weather.NowcastStep) []NowcastView {
+	if len(steps) == 0 {
+		return nil
+	}
+	views := make([]NowcastView, 0, len(steps))
+	for _, step := range steps {
+		instant := weather.Instant{Precipitation: step.Precipitation, Units: weather.Units{Precipitation: step.Unit}}
+		instant = instant.Convert(p.units)
+		views = append(views, NowcastView{
+			Start:         step.Time.Add(-weather.NowcastInterval),
+			End:           step.Time,
+			Precipitation: instant.Precipitation,
+			PrecipitationStr: p.formatValue(instant.Precipitation, p.precision.precipitation,
+				instant.Units.Precipitation),
+		})
+	}
+	return views
+}
+
+// nowcastAt returns the step of the nowcast that covers the given number of minutes from now. If the
+// nowcast doesn't cover that time, an empty NowcastView is returned.
+func (p *Presenter) nowcastAt(ctx TemplateContext, minutes int) NowcastView {
+	at := p.Clock.Now().Add(time.Minute * time.Duration(minutes))
+	for _, step := range ctx.Nowcast {
+		if !at.Before(step.Start) && at.Before(step.End) {
+			return step
+		}
+	}
+	return NowcastView{}
+}
+
+// nextRainMinutes returns the rounded number of minutes until the next step of the nowcast with
+// precipitation starts, but at least one. It returns 0 if it is precipitating already and -1 if no
+// precipitation is expected within the nowcast or no nowcast is available.
+func (p *Presenter) nextRainMinutes(ctx TemplateContext) int {
+	now := p.Clock.Now()
+	for _, step := range ctx.Nowcast {
+		if !step.End.After(now) || step.Precipitation <= 0 {
+			continue
+		}
+		if !step.Start.After(now) {
+			return 0
+		}
+		return max(int(math.Round(step.Start.Sub(now).Minutes())), 1)
+	}
+	return -1
+}
+
+// isPrecipitation reports whether the weather code belongs to a precipitation category.
+func isPrecipitation(code int) bool {
+	return slices.Contains(precipitationCategories, weatherCategory(code))
+}
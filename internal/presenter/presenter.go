@@ -6,8 +6,14 @@ package presenter
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -19,8 +25,12 @@ import (
 	"github.com/vorlif/spreak"
 	"golang.org/x/text/message"
 
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/units"
 	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
@@ -31,23 +41,188 @@ type WeatherView struct {
 	Category      string
 	Condition     string
 	ConditionIcon string
+
+	// WindChill and HeatIndex are the NOAA wind chill and heat index. Each defaults to the plain
+	// temperature if its formula does not apply. Comfort reports which one applies (ComfortWindChill
+	// or ComfortHeatIndex) and ComfortLabel holds a localized description like "feels dangerously hot".
+	WindChill    float64
+	HeatIndex    float64
+	Comfort      string
+	ComfortLabel string
+
+	// The *Str fields hold the metrics rounded to the configured precision and suffixed with their unit
+	TemperatureStr         string
+	ApparentTemperatureStr string
+	DewPointStr            string
+	WindSpeedStr           string
+	WindGustsStr           string
+	RelativeHumidityStr    string
+	PressureStr            string
+	PrecipitationStr       string
+
+	// ShowApparent is true if the apparent temperature differs from the temperature by more than
+	// weather.apparent_delta. FeelsLikeLine holds a localized line like "Feels like: 25.0°C" if it is set
+	// and is empty otherwise.
+	ShowApparent  bool
+	FeelsLikeLine string
+	// Hot and Cold are true if the temperature is at or above weather.hot_threshold or at or below
+	// weather.cold_threshold
+	Hot  bool
+	Cold bool
+
+	// StationPressure is the pressure at the elevation of the location, derived from the sea-level
+	// pressure with the barometric formula. It is only set if output.station_pressure is enabled.
+	StationPressure    float64
+	StationPressureStr string
+
+	// DeltaFromYesterday is the temperature difference compared to the same hour of the previous day.
+	// It is only set for the current weather instant.
+	DeltaFromYesterday float64
+	// Severity is the condition severity score from 0 for calm to 100 for severe weather, which combines
+	// the weather category, wind, precipitation and temperature extremes
+	Severity int
+	// SunElevation is the elevation of the sun above the horizon in degrees. For the current weather
+	// instant, it is computed for the time of rendering.
+	SunElevation float64
+	// IconPath is the path of the file that holds the icon of the current weather category. It is only
+	// set for the current weather instant and only if icons.file_output is enabled.
+	IconPath string
+}
+
+// ForecastEntry is an hour of the hourly forecast list. DayLabel is the localized day of the hour at the
+// location, like "Today", "Tomorrow" or the weekday for later days, and HourLabel the start of the hour
+// formatted like the prefTime template function.
+type ForecastEntry struct {
+	WeatherView
+	DayLabel  string
+	HourLabel string
 }
 
 type TemplateContext struct {
 	Latitude  float64
 	Longitude float64
+	Timezone  string
 	Address   geocode.Address
 
-	UpdateTime    time.Time
+	// LocationAccuracy is the accuracy radius of the location in meters (0 if unknown) and LocationSource
+	// the geolocation provider that found it
+	LocationAccuracy float64
+	LocationSource   string
+	// LocationLabel is the name of the geofence that the location is in or empty outside of the geofences
+	LocationLabel string
+
+	UpdateTime time.Time
+	// DataAge is the time since the weather data was fetched and LocationAge the time since the location
+	// was last found by a geolocation provider (0 if unknown). DataAgeStr and LocationAgeStr hold localized
+	// descriptions like "23 minutes".
+	DataAge        time.Duration
+	DataAgeStr     string
+	LocationAge    time.Duration
+	LocationAgeStr string
+	// DataStale is true if the weather data is older than the weather update interval, e. g. after a resume
+	// or a network outage. FreshnessLine holds a localized line like "Data from 23 minutes ago" if it is set
+	// and is empty otherwise.
+	DataStale     bool
+	FreshnessLine string
+
 	PressureUnit  string
+	Elevation     float64
 	SunriseTime   time.Time
 	SunsetTime    time.Time
 	MoonPhase     string
 	MoonPhaseIcon string
 
-	Current   WeatherView
-	Forecast  WeatherView
-	Forecasts []WeatherView
+	// GoldenHourStart and GoldenHourEnd are today's golden hour, in which the sun is between -4° and 6°.
+	// BlueHourStart and BlueHourEnd are today's blue hour, in which it is between -6° and -4°. Both happen
+	// in the morning and in the evening, so the morning times are set until they have passed, then the
+	// evening times. The times are zero if the sun doesn't reach the elevations today.
+	GoldenHourStart time.Time
+	GoldenHourEnd   time.Time
+	BlueHourStart   time.Time
+	BlueHourEnd     time.Time
+	// PolarNight and MidnightSun are set if the sun doesn't rise or set today
+	PolarNight  bool
+	MidnightSun bool
+
+	TemperatureUnit string
+	TodayMin        float64
+	TodayMax        float64
+	// TodayMinStr and TodayMaxStr hold TodayMin and TodayMax rounded to the configured precision and
+	// suffixed with the temperature unit
+	TodayMinStr string
+	TodayMaxStr string
+	// ObservedLow and ObservedHigh are the lowest and highest temperature of the current weather that
+	// were observed across the weather fetches of the current calendar day, unlike TodayMin and TodayMax,
+	// which are taken from the hourly forecast. ObservedLowAt and ObservedHighAt are the times they were
+	// observed, and ObservedLowStr and ObservedHighStr hold them formatted like TodayMinStr. They are only
+	// set if HasObserved is true.
+	HasObserved     bool
+	ObservedLow     float64
+	ObservedHigh    float64
+	ObservedLowAt   time.Time
+	ObservedHighAt  time.Time
+	ObservedLowStr  string
+	ObservedHighStr string
+	TonightLow      float64
+	// FrostRisk is true if any forecast hour until the next sunrise is at or below the frost threshold
+	FrostRisk bool
+	// PeakGust is the highest wind gust within the gust warning window in the preferred wind speed unit.
+	// GustWarning is true if it reaches the gust warning threshold.
+	PeakGust    float64
+	GustWarning bool
+	// PrecipitationToday is the sum of the hourly precipitation since local midnight
+	PrecipitationToday float64
+	PrecipitationUnit  string
+	// NextPrecipStart and NextPrecipEnd are the times the next precipitation starts and ends within the
+	// hourly forecast. While it is precipitating, only NextPrecipEnd is set. NextPrecipStartStr and
+	// NextPrecipEndStr hold localized descriptions like "Slight rain starting around 2 p.m.".
+	NextPrecipStart    time.Time
+	NextPrecipEnd      time.Time
+	NextPrecipStartStr string
+	NextPrecipEndStr   string
+	// Nowcast is the 15-minutely precipitation nowcast, sorted by time. It is empty unless the nowcast is
+	// enabled and supported by the weather provider.
+	Nowcast []NowcastView
+
+	Current WeatherView
+	// Forecast is the weather in forecast_hours hours. If the weather data doesn't hold that hour, e. g.
+	// since the provider only returned the current conditions, it holds the current weather instead and
+	// HasForecast is false.
+	Forecast    WeatherView
+	HasForecast bool
+	// Forecasts is the hourly forecast, sorted by time. It starts with the hour in progress or
+	// weather.forecast_lookback before it.
+	Forecasts []ForecastEntry
+	// WorstForecast is the forecast with the most severe weather category within the next forecast_hours
+	// hours (see CategorySeverity). It is the Forecast, if no forecast hours are available.
+	WorstForecast WeatherView
+
+	Locations map[string]LocationView
+
+	// Attribution is the attribution of the weather provider, unless it is overridden or hidden by the
+	// configuration
+	Attribution string
+	// WeatherSource is the name of the weather provider that produced the weather data
+	WeatherSource string
+
+	// Error holds the error of the last failed weather fetch. It is only set for the error template.
+	Error string
+
+	// hours holds all hours of the weather data including the past ones, sorted by time. The forecast
+	// functions of the templates look up their hours in it.
+	hours []WeatherView
+}
+
+// LocationView holds the weather data of an additional, fixed location.
+type LocationView struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Timezone  string
+
+	UpdateTime time.Time
+	Current    WeatherView
+	Forecast   WeatherView
 }
 
 type Presenter struct {
@@ -55,11 +230,67 @@ type Presenter struct {
 	AltTextTemplate    *template.Template
 	TooltipTemplate    *template.Template
 	AltTooltipTemplate *template.Template
+	DisplayTemplate    *template.Template
+	Clock              clock.Clock
+	// Logger logs the weather codes without description or icon. Nothing is logged if it is nil.
+	Logger *logger.Logger
+
+	// TextNightTemplate and TooltipNightTemplate replace TextTemplate and TooltipTemplate while it is
+	// night at the current location. They are nil if not configured.
+	TextNightTemplate    *template.Template
+	TooltipNightTemplate *template.Template
+	// PendingTemplate and ErrorTemplate render the placeholder states while no weather data is available
+	PendingTemplate *template.Template
+	ErrorTemplate   *template.Template
 
 	localizer     *spreak.Localizer
 	humanizer     *humanize.Humanizer
 	printer       *message.Printer
 	forecastHours uint
+	gustThreshold float64
+	gustWindow    time.Duration
+	tooltipMarkup bool
+	timeLayout    string
+	// updateInterval is the interval in which the weather data is updated, jitter the upper bound of the
+	// random offset that is added to it. Weather data older than both is stale.
+	updateInterval atomic.Int64
+	jitter         time.Duration
+	// forecastLookback is the time before the hour in progress that the forecast list starts at
+	forecastLookback time.Duration
+	// stationPressure enables the computation of the StationPressure of the weather views
+	stationPressure bool
+	units           weather.UnitPreferences
+	precision       precision
+	// temperatures are the temperature settings normalized into each temperature unit, gradientUnit the
+	// unit that tempColor maps temperatures in, unless the rendered context has a unit of its own
+	temperatures map[string]temperatureSettings
+	gradientUnit string
+	// severityWeights are the weights of the components of the condition severity score
+	severityWeights severityWeights
+	iconWidth       int
+	// iconPath is the path of the icon file of the current weather, if icons.file_output is enabled
+	iconPath string
+	// conditionIcons are the icons of the condition_icons setting that replace those of WMOWeatherIcons
+	conditionIcons map[int]map[bool]string
+	// unknownCodes holds the unknown weather codes that have already been logged
+	unknownCodes sync.Map
+}
+
+// precision holds the number of decimal places of the formatted display values.
+type precision struct {
+	temperature   int
+	wind          int
+	pressure      int
+	precipitation int
+}
+
+// temperatureSettings are the temperature thresholds and the color gradient normalized into a temperature unit.
+type temperatureSettings struct {
+	cold          float64
+	hot           float64
+	frost         float64
+	apparentDelta float64
+	gradient      []gradientStop
 }
 
 // Supported languages for humanize
@@ -69,7 +300,65 @@ var supportedHumanizers = []*humanize.LocaleData{de.New(), ptBR.New(), tr.New(),
 // It parses templates, creates a humanizer, and validates the templates for rendering.
 // Returns an error if any step in initialization fails.
 func New(conf *config.Config, loc *spreak.Localizer) (*Presenter, error) {
-	presenter := &Presenter{localizer: loc, forecastHours: conf.Weather.ForecastHours}
+	presenter := &Presenter{
+		localizer:        loc,
+		forecastHours:    conf.Weather.ForecastHours,
+		forecastLookback: conf.Weather.ForecastLookback,
+		gustThreshold:    conf.Weather.GustWarningThreshold,
+		gustWindow:       conf.Weather.GustWarningWindow,
+		jitter:           conf.Intervals.Jitter,
+		tooltipMarkup:    conf.Output.TooltipMarkup,
+		stationPressure:  conf.Output.StationPressure,
+		iconWidth:        int(conf.Output.IconWidth),
+		timeLayout:       conf.Output.TimeFormat,
+		conditionIcons:   conf.ConditionIcons(),
+		Clock:            clock.Real{},
+	}
+	presenter.SetUpdateInterval(conf.Intervals.WeatherUpdate)
+	if conf.Icons.FileOutput {
+		presenter.iconPath = conf.Icons.File
+	}
+
+	// The weather provider reports the metrics in the units of the global setting, so only the
+	// overridden units might need a local conversion
+	presenter.units = weather.UnitPreferences{
+		Temperature:   conf.UnitOverrides.Temperature,
+		WindSpeed:     conf.UnitOverrides.WindSpeed,
+		Pressure:      conf.UnitOverrides.Pressure,
+		Precipitation: conf.UnitOverrides.Precipitation,
+	}
+	presenter.precision = precision{
+		temperature:   precisionOrDefault(conf.Output.TemperaturePrecision, config.DefaultTemperaturePrecision),
+		wind:          precisionOrDefault(conf.Output.WindPrecision, config.DefaultWindPrecision),
+		pressure:      precisionOrDefault(conf.Output.PressurePrecision, config.DefaultPressurePrecision),
+		precipitation: precisionOrDefault(conf.Output.PrecipitationPrecision, config.DefaultPrecipitationPrecision),
+	}
+
+	presenter.severityWeights = severityWeights{
+		category:      weightOrDefault(conf.Severity.CategoryWeight, config.DefaultSeverityCategoryWeight),
+		wind:          weightOrDefault(conf.Severity.WindWeight, config.DefaultSeverityWindWeight),
+		precipitation: weightOrDefault(conf.Severity.PrecipitationWeight, config.DefaultSeverityPrecipitationWeight),
+		temperature:   weightOrDefault(conf.Severity.TemperatureWeight, config.DefaultSeverityTemperatureWeight),
+	}
+
+	// The temperature settings are normalized into both units, since the displayed unit can change with
+	// the location if the units are selected automatically
+	presenter.temperatures = make(map[string]temperatureSettings, 2)
+	for _, unit := range []string{units.Celsius, units.Fahrenheit} {
+		temps := conf.TemperaturesIn(unit)
+		gradient, err := parseGradient(temps.Gradient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse temperature gradient: %w", err)
+		}
+		presenter.temperatures[unit] = temperatureSettings{
+			cold:          temps.ColdThreshold,
+			hot:           temps.HotThreshold,
+			frost:         temps.FrostThreshold,
+			apparentDelta: temps.ApparentDelta,
+			gradient:      gradient,
+		}
+	}
+	presenter.gradientUnit = conf.UnitPreferences().Temperature
 
 	// Parse the templates
 	if err := presenter.parseTemplates(conf); err != nil {
@@ -95,58 +384,222 @@ func New(conf *config.Config, loc *spreak.Localizer) (*Presenter, error) {
 }
 
 // BuildContext constructs and returns a populated TemplateContext based on provided address, weather data,
-// and timings data.
-func (p *Presenter) BuildContext(addr geocode.Address, data *weather.Data, sunrise, sunset time.Time, moonPhase string) TemplateContext {
+// and timings data. locationAt is the time the location was last found by a geolocation provider.
+func (p *Presenter) BuildContext(addr geocode.Address, data *weather.Data, sunrise, sunset time.Time, moonPhase string,
+	locationAt time.Time,
+) TemplateContext {
 	if data == nil {
 		return TemplateContext{}
 	}
 
-	fcastHour := weather.NewDayHour(time.Now().Add(time.Hour * time.Duration(p.forecastHours)))
+	current := p.viewFromInstant(data.Current, data.Elevation)
+	if past, ok := data.InstantAt(data.Current.InstantTime.Add(-time.Hour * 24)); ok {
+		current.DeltaFromYesterday = current.Temperature - past.Convert(p.units).Temperature
+	}
+	current.IconPath = p.iconPath
+	now := p.Clock.Now()
+	current.SunElevation = sunElevation(data.Coordinates, now)
+	forecast, hasForecast := p.forecastView(data)
+	forecast.SunElevation = current.SunElevation
+	if hasForecast {
+		forecast.SunElevation = sunElevation(data.Coordinates, forecast.InstantTime)
+	}
+	hours := p.viewSliceFromMap(data.Forecast, data.Elevation)
+	for i := range hours {
+		hours[i].SunElevation = sunElevation(data.Coordinates, hours[i].InstantTime)
+	}
+	sun := todaySunTimes(data.Coordinates, now)
+	dataAge := age(data.GeneratedAt, now)
+
+	todayMin, todayMax := p.todayMinMax(data)
+	precipToday, precipUnit := p.precipitationToday(data)
+	peakGust := p.peakGust(data)
+	precipStart, precipEnd := p.nextPrecipitation(data)
+	tplCtx := TemplateContext{
+		Latitude:           data.Coordinates.Lat,
+		Longitude:          data.Coordinates.Lon,
+		Timezone:           data.Timezone,
+		Elevation:          data.Elevation,
+		Address:            p.formatAddress(addr),
+		LocationLabel:      addr.Label,
+		UpdateTime:         data.GeneratedAt,
+		DataAge:            dataAge,
+		DataAgeStr:         p.ageStr(data.GeneratedAt, now),
+		LocationAge:        age(locationAt, now),
+		LocationAgeStr:     p.ageStr(locationAt, now),
+		DataStale:          p.isStale(dataAge),
+		SunriseTime:        sunrise,
+		SunsetTime:         sunset,
+		GoldenHourStart:    sun.goldenHour.start,
+		GoldenHourEnd:      sun.goldenHour.end,
+		BlueHourStart:      sun.blueHour.start,
+		BlueHourEnd:        sun.blueHour.end,
+		PolarNight:         sun.polarNight,
+		MidnightSun:        sun.midnightSun,
+		MoonPhase:          moonPhase,
+		MoonPhaseIcon:      MoonPhaseIcon[moonPhase],
+		TemperatureUnit:    current.Units.Temperature,
+		TodayMin:           todayMin,
+		TodayMax:           todayMax,
+		TodayMinStr:        p.formatValue(todayMin, p.precision.temperature, current.Units.Temperature),
+		TodayMaxStr:        p.formatValue(todayMax, p.precision.temperature, current.Units.Temperature),
+		TonightLow:         p.tonightLow(data, sunrise, sunset),
+		FrostRisk:          p.frostRisk(data, sunrise),
+		PeakGust:           peakGust,
+		GustWarning:        p.gustThreshold > 0 && peakGust >= p.gustThreshold,
+		PrecipitationToday: precipToday,
+		PrecipitationUnit:  precipUnit,
+		NextPrecipStart:    precipStart.at,
+		NextPrecipEnd:      precipEnd.at,
+		NextPrecipStartStr: p.precipStartStr(precipStart),
+		NextPrecipEndStr:   p.precipEndStr(precipEnd),
+		Nowcast:            p.nowcastViews(data.Nowcast),
+		Current:            current,
+		Forecast:           forecast,
+		HasForecast:        hasForecast,
+		Forecasts:          p.forecastEntries(data, hours),
+		WeatherSource:      data.Source,
+		hours:              hours,
+	}
+	if tplCtx.DataStale {
+		tplCtx.FreshnessLine = p.freshness(dataAge)
+	}
+	tplCtx.WorstForecast = p.worstCondition(tplCtx, int(p.forecastHours))
+	if tplCtx.WorstForecast.InstantTime.IsZero() {
+		tplCtx.WorstForecast = forecast
+	}
+	return tplCtx
+}
+
+// BuildPendingContext constructs a TemplateContext for the placeholder states from the partial data that
+// is available before the first weather data has been fetched.
+func (p *Presenter) BuildPendingContext(addr geocode.Address, coords geobus.Coordinate) TemplateContext {
 	return TemplateContext{
-		Latitude:      data.Coordinates.Lat,
-		Longitude:     data.Coordinates.Lon,
-		Address:       addr,
-		UpdateTime:    data.GeneratedAt,
-		SunriseTime:   sunrise,
-		SunsetTime:    sunset,
-		MoonPhase:     moonPhase,
-		MoonPhaseIcon: MoonPhaseIcon[moonPhase],
-		Current:       p.viewFromInstant(data.Current),
-		Forecast:      p.viewFromInstant(data.Forecast[fcastHour]),
-		Forecasts:     p.viewSliceFromMap(data.Forecast),
+		Latitude:      coords.Lat,
+		Longitude:     coords.Lon,
+		Address:       p.formatAddress(addr),
+		LocationLabel: addr.Label,
 	}
 }
 
+// BuildLocations constructs the LocationView map for the weather data of the additional locations, keyed
+// by the location name. Locations without weather data are omitted.
+func (p *Presenter) BuildLocations(data map[string]*weather.Data) map[string]LocationView {
+	locations := make(map[string]LocationView, len(data))
+	for name, wthr := range data {
+		if wthr == nil {
+			continue
+		}
+		forecast, _ := p.forecastView(wthr)
+		locations[name] = LocationView{
+			Name:       name,
+			Latitude:   wthr.Coordinates.Lat,
+			Longitude:  wthr.Coordinates.Lon,
+			Timezone:   wthr.Timezone,
+			UpdateTime: wthr.GeneratedAt,
+			Current:    p.viewFromInstant(wthr.Current, wthr.Elevation),
+			Forecast:   forecast,
+		}
+	}
+	return locations
+}
+
+// TemplateErrorMarker replaces the output of a template that failed to render, so that the error is
+// visible in the bar instead of the whole output being dropped.
+const TemplateErrorMarker = "⚠ template error"
+
+// TemplateError is the error of a single template that failed to render.
+type TemplateError struct {
+	// Field is the name of the rendered field, e. g. "alt_text"
+	Field string
+	Err   error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("failed to render %s template: %s", strings.ReplaceAll(e.Field, "_", " "), e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
 // Render processes the given TemplateContext and generates text, alternative text, and tooltip content as strings.
+// Control characters are stripped from the rendered output. Each template is rendered independently: a
+// template that fails is replaced with TemplateErrorMarker and its *TemplateError is part of the returned
+// error, which joins the errors of all failed templates.
 func (p *Presenter) Render(tplCtx TemplateContext) (map[string]string, error) {
+	textTpl, tooltipTpl := p.TextTemplate, p.TooltipTemplate
+	if !tplCtx.Current.IsDay {
+		if p.TextNightTemplate != nil {
+			textTpl = p.TextNightTemplate
+		}
+		if p.TooltipNightTemplate != nil {
+			tooltipTpl = p.TooltipNightTemplate
+		}
+	}
+
 	buf := bytes.NewBuffer(nil)
 	valMap := make(map[string]string)
-
-	if err := p.TextTemplate.Execute(buf, tplCtx); err != nil {
-		return valMap, fmt.Errorf("failed to render text template: %w", err)
+	var errs []error
+	for _, field := range []struct {
+		name string
+		tpl  *template.Template
+	}{
+		{"text", textTpl},
+		{"alt_text", p.AltTextTemplate},
+		{"tooltip", tooltipTpl},
+		{"alt_tooltip", p.AltTooltipTemplate},
+	} {
+		buf.Reset()
+		if err := p.execute(field.tpl, buf, tplCtx); err != nil {
+			valMap[field.name] = TemplateErrorMarker
+			errs = append(errs, &TemplateError{Field: field.name, Err: err})
+			continue
+		}
+		valMap[field.name] = stripControl(buf.String())
 	}
-	valMap["text"] = buf.String()
-	buf.Reset()
+	return valMap, errors.Join(errs...)
+}
 
-	if err := p.AltTextTemplate.Execute(buf, tplCtx); err != nil {
-		return valMap, fmt.Errorf("failed to render alt text template: %w", err)
+// execute renders the template with the given TemplateContext. With automatic units, the displayed
+// temperature unit follows the location, so the temperature color functions are bound to the unit of the
+// context. The parsed templates are shared by concurrent renders, so this is done on a clone.
+func (p *Presenter) execute(tpl *template.Template, buf *bytes.Buffer, tplCtx TemplateContext) error {
+	if tplCtx.TemperatureUnit != "" {
+		clone, err := tpl.Clone()
+		if err != nil {
+			return err
+		}
+		tpl = clone.Funcs(p.gradientFuncMap(tplCtx.TemperatureUnit))
 	}
-	valMap["alt_text"] = buf.String()
-	buf.Reset()
+	return tpl.Execute(buf, tplCtx)
+}
 
-	if err := p.TooltipTemplate.Execute(buf, tplCtx); err != nil {
-		return valMap, fmt.Errorf("failed to render tooltip template: %w", err)
+// RenderPending renders the pending template with the given TemplateContext.
+func (p *Presenter) RenderPending(tplCtx TemplateContext) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := p.PendingTemplate.Execute(buf, tplCtx); err != nil {
+		return "", fmt.Errorf("failed to render pending template: %w", err)
 	}
-	valMap["tooltip"] = buf.String()
-	buf.Reset()
+	return stripControl(buf.String()), nil
+}
 
-	if err := p.AltTooltipTemplate.Execute(buf, tplCtx); err != nil {
-		return valMap, fmt.Errorf("failed to render alt tooltip template: %w", err)
+// RenderError renders the error template with the given TemplateContext.
+func (p *Presenter) RenderError(tplCtx TemplateContext) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := p.ErrorTemplate.Execute(buf, tplCtx); err != nil {
+		return "", fmt.Errorf("failed to render error template: %w", err)
 	}
-	valMap["alt_tooltip"] = buf.String()
-	buf.Reset()
+	return stripControl(buf.String()), nil
+}
 
-	return valMap, nil
+// EscapeTooltip prepares a raw string, that was not rendered by a template, for the use as tooltip. It is
+// escaped for Pango markup if the tooltip markup is enabled and control characters are stripped.
+func (p *Presenter) EscapeTooltip(val string) string {
+	if p.tooltipMarkup {
+		val = pangoEscape(val)
+	}
+	return stripControl(val)
 }
 
 // parseTemplates parses the templates from the config and stores them in the Presenter struct
@@ -175,12 +628,56 @@ func (p *Presenter) parseTemplates(conf *config.Config) error {
 	}
 	p.AltTooltipTemplate = tpl
 
+	if conf.Templates.TextNight != "" {
+		tpl, err = template.New("text_night").Funcs(p.templateFuncMap()).Parse(conf.Templates.TextNight)
+		if err != nil {
+			return fmt.Errorf("failed to parse night text template: %w", err)
+		}
+		p.TextNightTemplate = tpl
+	}
+	if conf.Templates.TooltipNight != "" {
+		tpl, err = template.New("tooltip_night").Funcs(p.templateFuncMap()).Parse(conf.Templates.TooltipNight)
+		if err != nil {
+			return fmt.Errorf("failed to parse night tooltip template: %w", err)
+		}
+		p.TooltipNightTemplate = tpl
+	}
+
+	tpl, err = template.New("pending").Funcs(p.templateFuncMap()).Parse(conf.Templates.Pending)
+	if err != nil {
+		return fmt.Errorf("failed to parse pending template: %w", err)
+	}
+	p.PendingTemplate = tpl
+
+	tpl, err = template.New("error").Funcs(p.templateFuncMap()).Parse(conf.Templates.Error)
+	if err != nil {
+		return fmt.Errorf("failed to parse error template: %w", err)
+	}
+	p.ErrorTemplate = tpl
+
+	if conf.GeoCoder.DisplayFormat != "" {
+		tpl, err = template.New("display_format").Funcs(p.templateFuncMap()).Parse(conf.GeoCoder.DisplayFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse display format template: %w", err)
+		}
+		p.DisplayTemplate = tpl
+	}
+
+	// Escape the tooltips for Pango markup, so that Waybar renders the values verbatim
+	if p.tooltipMarkup {
+		escapeTemplate(p.TooltipTemplate)
+		escapeTemplate(p.AltTooltipTemplate)
+		if p.TooltipNightTemplate != nil {
+			escapeTemplate(p.TooltipNightTemplate)
+		}
+	}
+
 	return nil
 }
 
 // validateTemplates validates that the templates can be rendered
 func (p *Presenter) validateTemplates() error {
-	data := TemplateContext{Forecasts: make([]WeatherView, 1)}
+	data := TemplateContext{Forecasts: make([]ForecastEntry, 1)}
 	if err := p.TextTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
 		return fmt.Errorf("failed to render text template: %w", err)
 	}
@@ -193,26 +690,329 @@ func (p *Presenter) validateTemplates() error {
 	if err := p.AltTooltipTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
 		return fmt.Errorf("failed to render alternative tooltip template: %w", err)
 	}
+	if p.TextNightTemplate != nil {
+		if err := p.TextNightTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
+			return fmt.Errorf("failed to render night text template: %w", err)
+		}
+	}
+	if p.TooltipNightTemplate != nil {
+		if err := p.TooltipNightTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
+			return fmt.Errorf("failed to render night tooltip template: %w", err)
+		}
+	}
+	if err := p.PendingTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
+		return fmt.Errorf("failed to render pending template: %w", err)
+	}
+	if err := p.ErrorTemplate.Execute(bytes.NewBuffer(nil), data); err != nil {
+		return fmt.Errorf("failed to render error template: %w", err)
+	}
+	if p.DisplayTemplate != nil {
+		if err := p.DisplayTemplate.Execute(bytes.NewBuffer(nil), geocode.Address{}); err != nil {
+			return fmt.Errorf("failed to render display format template: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// formatAddress applies the configured display format to the address and stores the result as its
+// DisplayName. Empty address fields are collapsed, so that no dangling commas remain. If no display
+// format is configured, rendering fails or results in an empty name, e. g. because reverse geocoding is
+// disabled, the address is returned unchanged. The label of a geofence replaces City and DisplayName
+// instead.
+func (p *Presenter) formatAddress(addr geocode.Address) geocode.Address {
+	if addr.Label != "" {
+		addr.City, addr.DisplayName = addr.Label, addr.Label
+		return addr
+	}
+	if p.DisplayTemplate == nil {
+		return addr
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := p.DisplayTemplate.Execute(buf, addr); err != nil {
+		return addr
+	}
+	if name := collapseAddress(buf.String()); name != "" {
+		addr.DisplayName = name
+	}
+	return addr
+}
+
+// forecastHour returns the DayHour of the configured forecast hours ahead of now in the time zone of
+// the weather data's location.
+func (p *Presenter) forecastHour(data *weather.Data) weather.DayHour {
+	return data.DayHour(p.Clock.Now().Add(time.Hour * time.Duration(p.forecastHours)))
+}
+
+// forecastView returns the view of the forecast hour. If the weather data doesn't hold the forecast hour,
+// the view of the current weather is returned instead, so that the forecast doesn't render as zero values.
+// The second return value reports whether the forecast hour was found.
+func (p *Presenter) forecastView(data *weather.Data) (WeatherView, bool) {
+	if instant, ok := data.Forecast[p.forecastHour(data)]; ok {
+		return p.viewFromInstant(instant, data.Elevation), true
+	}
+	return p.viewFromInstant(data.Current, data.Elevation), false
+}
+
+// forecastEntries returns the hourly forecast list of the given hours, which starts with the hour in
+// progress or the configured lookback before it. The days of the labels are those of the weather data's
+// location.
+func (p *Presenter) forecastEntries(data *weather.Data, hours []WeatherView) []ForecastEntry {
+	loc := data.Location()
+	now := p.Clock.Now().In(loc)
+	start := data.DayHour(now).Time().Add(-p.forecastLookback)
+	entries := make([]ForecastEntry, 0, len(hours))
+	for _, view := range hours {
+		if view.InstantTime.Before(start) {
+			continue
+		}
+		at := view.InstantTime.In(loc)
+		entries = append(entries, ForecastEntry{
+			WeatherView: view,
+			DayLabel:    p.dayLabel(at, now),
+			HourLabel:   p.prefTime(at),
+		})
+	}
+	return entries
+}
+
+// dayLabel returns the localized day of t relative to the day of now. Days other than yesterday, today
+// and tomorrow are labelled with their localized weekday. Both times are expected in the same time zone.
+func (p *Presenter) dayLabel(t, now time.Time) string {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	switch int(day(t).Sub(day(now)).Hours() / 24) {
+	case -1:
+		return p.loc("yesterday")
+	case 0:
+		return p.loc("today")
+	case 1:
+		return p.loc("tomorrow")
+	}
+	return p.humanizer.FormatTime(t, "l")
+}
+
+// todayMinMax returns the minimum and maximum temperature of all forecast hours that belong to the
+// current calendar day at the weather data's location. If no forecast hours are available for today, zero
+// values are returned.
+func (p *Presenter) todayMinMax(data *weather.Data) (float64, float64) {
+	loc := data.Location()
+	now := p.Clock.Now().In(loc)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	minTemp, maxTemp, _ := p.temperatureRange(data.Forecast, start, start.AddDate(0, 0, 1))
+	return minTemp, maxTemp
+}
+
+// precipitationToday returns the sum of the hourly precipitation since local midnight and its unit. The
+// hourly precipitation covers the preceding hour, so the midnight hour still belongs to yesterday and the
+// hours after the current time are not yet included. If no hourly precipitation is available, zero is
+// returned.
+func (p *Presenter) precipitationToday(data *weather.Data) (float64, string) {
+	loc := data.Location()
+	now := p.Clock.Now().In(loc)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	unit := data.Current.Convert(p.units).Units.Precipitation
+
+	var total float64
+	for hour, instant := range data.Forecast {
+		at := hour.Time()
+		if !at.After(start) || at.After(now) {
+			continue
+		}
+		instant = instant.Convert(p.units)
+		total += instant.Precipitation
+		if instant.Units.Precipitation != "" {
+			unit = instant.Units.Precipitation
+		}
+	}
+	return total, unit
+}
+
+// tonightLow returns the lowest temperature of all forecast hours between sunset and the next sunrise.
+// Before today's sunrise, the night started with yesterday's sunset. If sunrise or sunset are unknown
+// or no forecast hours are available for the night, zero is returned.
+func (p *Presenter) tonightLow(data *weather.Data, sunrise, sunset time.Time) float64 {
+	if sunrise.IsZero() || sunset.IsZero() {
+		return 0
+	}
+	start, end := sunset, sunrise.Add(time.Hour*24)
+	if p.Clock.Now().Before(sunrise) {
+		start, end = sunset.Add(-time.Hour*24), sunrise
+	}
+	low, _, _ := p.temperatureRange(data.Forecast, start, end)
+	return low
+}
+
+// frostRisk reports whether any forecast hour between the current hour and the next sunrise has a
+// temperature at or below the configured frost threshold. If sunrise is unknown, false is returned.
+func (p *Presenter) frostRisk(data *weather.Data, sunrise time.Time) bool {
+	if sunrise.IsZero() {
+		return false
+	}
+	now := p.Clock.Now()
+	nextSunrise := sunrise
+	if !now.Before(sunrise) {
+		nextSunrise = sunrise.Add(time.Hour * 24)
+	}
+	low, _, found := p.temperatureRange(data.Forecast, data.DayHour(now).Time(), nextSunrise)
+	return found && low <= p.temperaturesIn(data.Current.Convert(p.units).Units.Temperature).frost
+}
+
+// temperaturesIn returns the temperature settings normalized into the given temperature unit or symbol.
+// Unknown units get the settings in °C.
+func (p *Presenter) temperaturesIn(unit string) temperatureSettings {
+	if temps, ok := p.temperatures[units.TemperatureUnit(unit)]; ok {
+		return temps
+	}
+	return p.temperatures[units.Celsius]
+}
+
+// peakGust returns the highest wind gust of the current conditions and the forecast hours between the
+// current hour and the end of the gust warning window. The gusts are converted into the preferred wind
+// speed unit, so that they can be compared with the gust warning threshold.
+func (p *Presenter) peakGust(data *weather.Data) float64 {
+	peak := data.Current.Convert(p.units).WindGusts
+	start := data.DayHour(p.Clock.Now()).Time()
+	end := p.Clock.Now().Add(p.gustWindow)
+	for hour, instant := range data.Forecast {
+		at := hour.Time()
+		if at.Before(start) || at.After(end) {
+			continue
+		}
+		peak = max(peak, instant.Convert(p.units).WindGusts)
+	}
+	return peak
+}
+
+// temperatureRange returns the minimum and maximum temperature of the forecast hours within the
+// half-open interval [from, to), converted into the preferred temperature unit. The last return value
+// reports whether any hour was within the interval.
+func (p *Presenter) temperatureRange(forecast map[weather.DayHour]weather.Instant, from, to time.Time) (
+	float64, float64, bool,
+) {
+	var minTemp, maxTemp float64
+	found := false
+	for hour, instant := range forecast {
+		at := hour.Time()
+		if at.Before(from) || !at.Before(to) {
+			continue
+		}
+		instant = instant.Convert(p.units)
+		if !found || instant.Temperature < minTemp {
+			minTemp = instant.Temperature
+		}
+		if !found || instant.Temperature > maxTemp {
+			maxTemp = instant.Temperature
+		}
+		found = true
+	}
+	return minTemp, maxTemp, found
+}
+
 // viewFromInstant converts a weather.Instant into a WeatherView with condition details and corresponding icon.
-func (p *Presenter) viewFromInstant(in weather.Instant) WeatherView {
-	return WeatherView{
+// Metrics that the weather provider did not report in the configured units are converted locally. The
+// elevation of the location in meters is used for the station pressure.
+func (p *Presenter) viewFromInstant(in weather.Instant, elevation float64) WeatherView {
+	in = in.Convert(p.units)
+	windChill, heatIndex, index := comfort(in)
+	label := ""
+	switch index {
+	case ComfortWindChill:
+		label = p.comfortLabel(index, windChill, in.Units.Temperature)
+	case ComfortHeatIndex:
+		label = p.comfortLabel(index, heatIndex, in.Units.Temperature)
+	}
+
+	view := WeatherView{
 		Instant: in,
 
 		Category:      weatherCategory(in.WeatherCode),
-		Condition:     p.localizer.Get(WMOWeatherCodes[in.WeatherCode]),
-		ConditionIcon: WMOWeatherIcons[in.WeatherCode][in.IsDay],
+		Condition:     p.conditionFor(in.WeatherCode),
+		ConditionIcon: p.iconFor(in.WeatherCode, in.IsDay),
+		WindChill:     windChill,
+		HeatIndex:     heatIndex,
+		Comfort:       index,
+		ComfortLabel:  label,
+
+		TemperatureStr:         p.formatValue(in.Temperature, p.precision.temperature, in.Units.Temperature),
+		ApparentTemperatureStr: p.formatValue(in.ApparentTemperature, p.precision.temperature, in.Units.Temperature),
+		DewPointStr:            p.formatValue(in.DewPoint, p.precision.temperature, in.Units.Temperature),
+		WindSpeedStr:           p.formatValue(in.WindSpeed, p.precision.wind, in.Units.WindSpeed),
+		WindGustsStr:           p.formatValue(in.WindGusts, p.precision.wind, in.Units.WindSpeed),
+		RelativeHumidityStr:    p.formatValue(in.RelativeHumidity, 0, in.Units.Humidity),
+		PressureStr:            p.formatValue(in.PressureMSL, p.precision.pressure, in.Units.Pressure),
+		PrecipitationStr:       p.formatValue(in.Precipitation, p.precision.precipitation, in.Units.Precipitation),
+		Severity:               p.severity(in),
+	}
+	temps := p.temperaturesIn(in.Units.Temperature)
+	view.Hot = in.Temperature >= temps.hot
+	view.Cold = in.Temperature <= temps.cold
+	if math.Abs(in.ApparentTemperature-in.Temperature) > temps.apparentDelta {
+		view.ShowApparent = true
+		view.FeelsLikeLine = p.loc("apparent") + ": " + view.ApparentTemperatureStr
 	}
+	if p.stationPressure {
+		view.StationPressure = stationPressure(in.PressureMSL, elevation)
+		view.StationPressureStr = p.formatValue(view.StationPressure, p.precision.pressure, in.Units.Pressure)
+	}
+	return view
+}
+
+// stationPressure derives the pressure at the given elevation in meters from the sea-level pressure with
+// the barometric formula of the international standard atmosphere. Since the formula only scales the
+// pressure, it applies to all pressure units.
+func stationPressure(pressureMSL, elevation float64) float64 {
+	const (
+		lapseRate   = 0.0065  // temperature lapse rate in K/m
+		seaLevelK   = 288.15  // standard temperature at sea level in K
+		barometricE = 5.25588 // g*M/(R*L)
+	)
+	return pressureMSL * math.Pow(1-lapseRate*elevation/seaLevelK, barometricE)
+}
+
+// formatValue rounds the value to the given number of decimal places in the number format of the
+// configured locale and appends the unit. Degree and percent signs are attached to the value, while
+// all other units are separated by a space.
+func (p *Presenter) formatValue(val float64, decimals int, unit string) string {
+	pow := math.Pow(10, float64(decimals))
+	val = math.Round(val*pow) / pow
+	// Negative values that round to zero would otherwise be formatted as "-0"
+	if val == 0 {
+		val = 0
+	}
+	formatted := p.printer.Sprintf("%.*f", decimals, val)
+	switch {
+	case unit == "":
+		return formatted
+	case strings.HasPrefix(unit, "°"), unit == "%":
+		return formatted + unit
+	default:
+		return formatted + " " + unit
+	}
+}
+
+// precisionOrDefault returns the configured precision or the fallback if it is unset.
+func precisionOrDefault(val *uint, fallback int) int {
+	if val == nil {
+		return fallback
+	}
+	return int(*val)
+}
+
+// weightOrDefault returns the configured weight of a severity component or the fallback, if it is unset.
+func weightOrDefault(val *float64, fallback float64) float64 {
+	if val == nil {
+		return fallback
+	}
+	return *val
 }
 
 // viewSliceFromMap converts a map of DayHour-Instant pairs into a sorted slice of WeatherView based on InstantTime.
-func (p *Presenter) viewSliceFromMap(m map[weather.DayHour]weather.Instant) []WeatherView {
+func (p *Presenter) viewSliceFromMap(m map[weather.DayHour]weather.Instant, elevation float64) []WeatherView {
 	views := make([]WeatherView, 0, len(m))
 	for _, inst := range m {
-		views = append(views, p.viewFromInstant(inst))
+		views = append(views, p.viewFromInstant(inst, elevation))
 	}
 	sort.Slice(views, func(i, j int) bool {
 		return views[i].InstantTime.Before(views[j].InstantTime)
@@ -220,6 +1020,39 @@ func (p *Presenter) viewSliceFromMap(m map[weather.DayHour]weather.Instant) []We
 	return views
 }
 
+// conditionFor returns the localized description of the weather code. Unknown codes are described as
+// "Unknown".
+func (p *Presenter) conditionFor(code int) string {
+	msgID, ok := WMOWeatherCodes[code]
+	if !ok {
+		p.logUnknownCode(code)
+		msgID = unknownCondition
+	}
+	return p.localizer.Get(msgID)
+}
+
+// iconFor returns the day or night icon of the weather code. The icons of the condition_icons setting take
+// precedence. Unknown codes are shown with a question mark.
+func (p *Presenter) iconFor(code int, isDay bool) string {
+	if icon, ok := p.conditionIcons[code][isDay]; ok {
+		return icon
+	}
+	icon, ok := WMOWeatherIcons[code][isDay]
+	if !ok {
+		p.logUnknownCode(code)
+		return unknownConditionIcon
+	}
+	return icon
+}
+
+// logUnknownCode logs the unknown weather code, but only the first time it is seen.
+func (p *Presenter) logUnknownCode(code int) {
+	if _, logged := p.unknownCodes.LoadOrStore(code, struct{}{}); logged || p.Logger == nil {
+		return
+	}
+	p.Logger.Warn("unknown weather code, showing generic condition", slog.Int("code", code))
+}
+
 // weatherCategory categorizes a weather code into general weather conditions such as clear, cloudy, rain, snow, etc.
 func weatherCategory(code int) string {
 	switch code {
@@ -5,18 +5,28 @@
 package presenter
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"strings"
 	"testing"
 	"text/template"
 	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/vorlif/spreak"
 
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/config"
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/units"
 	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
@@ -28,6 +38,7 @@ var (
 		Longitude:    67.890,
 		City:         "Test City",
 		Country:      "Test Country",
+		CountryCode:  "TC",
 		DisplayName:  "Test City, Test Country",
 	}
 	sunrise        = time.Date(2026, 1, 18, 7, 1, 2, 0, time.UTC)
@@ -95,6 +106,11 @@ func TestNew(t *testing.T) {
 			{"alt_text", func(conf *config.Config) { conf.Templates.AltText = "{{invalid" }},
 			{"tooltip", func(conf *config.Config) { conf.Templates.Tooltip = "{{invalid" }},
 			{"alt_tooltip", func(conf *config.Config) { conf.Templates.AltTooltip = "{{invalid" }},
+			{"text_night", func(conf *config.Config) { conf.Templates.TextNight = "{{invalid" }},
+			{"tooltip_night", func(conf *config.Config) { conf.Templates.TooltipNight = "{{invalid" }},
+			{"pending", func(conf *config.Config) { conf.Templates.Pending = "{{invalid" }},
+			{"error", func(conf *config.Config) { conf.Templates.Error = "{{invalid" }},
+			{"display_format", func(conf *config.Config) { conf.GeoCoder.DisplayFormat = "{{invalid" }},
 		}
 
 		for _, tt := range tests {
@@ -121,6 +137,11 @@ func TestNew(t *testing.T) {
 			{"alt_text", func(conf *config.Config) { conf.Templates.AltText = "{{.Data}}" }},
 			{"tooltip", func(conf *config.Config) { conf.Templates.Tooltip = "{{.Data}}" }},
 			{"alt_tooltip", func(conf *config.Config) { conf.Templates.AltTooltip = "{{.Data}}" }},
+			{"text_night", func(conf *config.Config) { conf.Templates.TextNight = "{{.Data}}" }},
+			{"tooltip_night", func(conf *config.Config) { conf.Templates.TooltipNight = "{{.Data}}" }},
+			{"pending", func(conf *config.Config) { conf.Templates.Pending = "{{.Data}}" }},
+			{"error", func(conf *config.Config) { conf.Templates.Error = "{{.Data}}" }},
+			{"display_format", func(conf *config.Config) { conf.GeoCoder.DisplayFormat = "{{.Data}}" }},
 		}
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
@@ -146,6 +167,7 @@ func TestPresenter_BuildContext(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed to create presenter: %s", err)
 		}
+		pres.Clock = clock.NewFake(now)
 
 		fcasts := make(map[weather.DayHour]weather.Instant)
 		fcasts[fcastHour] = wthrAlt
@@ -153,13 +175,21 @@ func TestPresenter_BuildContext(t *testing.T) {
 		data := &weather.Data{
 			GeneratedAt: now,
 			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Timezone:    "Europe/Berlin",
 			Current:     wthr,
 			Forecast:    fcasts,
 		}
-		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase)
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
 		if tplCtx.UpdateTime.IsZero() {
 			t.Error("expected update time to be set")
 		}
+		if tplCtx.Timezone != data.Timezone {
+			t.Errorf("expected timezone to be %q, got %q", data.Timezone, tplCtx.Timezone)
+		}
+		if tplCtx.Address.CountryCode != addr.CountryCode {
+			t.Errorf("expected address country code to be %q, got %q", addr.CountryCode,
+				tplCtx.Address.CountryCode)
+		}
 		if tplCtx.Address.City != addr.City {
 			t.Errorf("expected address city to be %q, got %q", addr.City, tplCtx.Address.City)
 		}
@@ -244,20 +274,122 @@ func TestPresenter_BuildContext(t *testing.T) {
 			t.Fatalf("failed to create presenter: %s", err)
 		}
 
-		tplCtx := pres.BuildContext(addr, nil, sunrise, sunset, moonphase)
+		tplCtx := pres.BuildContext(addr, nil, sunrise, sunset, moonphase, time.Time{})
 		if !tplCtx.UpdateTime.IsZero() {
 			t.Errorf("expected update time to be zero, got %s", tplCtx.UpdateTime)
 		}
 	})
 }
 
+func TestPresenter_RenderPending(t *testing.T) {
+	conf, lang := testConfLang(t)
+	conf.Templates.Pending = `{{with .Address.City}}⏳ {{.}}{{else}}⏳ {{loc "locating"}}…{{end}}`
+	conf.Templates.Error = `⚠️ {{.Error}} ({{.Latitude}}, {{.Longitude}})`
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	coords := geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude}
+
+	t.Run("pending without address", func(t *testing.T) {
+		got, err := pres.RenderPending(pres.BuildPendingContext(geocode.Address{}, geobus.Coordinate{}))
+		if err != nil {
+			t.Fatalf("failed to render pending template: %s", err)
+		}
+		if want := "⏳ Locating…"; got != want {
+			t.Errorf("expected pending output to be %q, got %q", want, got)
+		}
+	})
+	t.Run("pending with known address", func(t *testing.T) {
+		got, err := pres.RenderPending(pres.BuildPendingContext(addr, coords))
+		if err != nil {
+			t.Fatalf("failed to render pending template: %s", err)
+		}
+		if want := "⏳ Test City"; got != want {
+			t.Errorf("expected pending output to be %q, got %q", want, got)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		tplCtx := pres.BuildPendingContext(addr, coords)
+		tplCtx.Error = "connection refused"
+		got, err := pres.RenderError(tplCtx)
+		if err != nil {
+			t.Fatalf("failed to render error template: %s", err)
+		}
+		if want := "⚠️ connection refused (12.345, 67.89)"; got != want {
+			t.Errorf("expected error output to be %q, got %q", want, got)
+		}
+	})
+	t.Run("default templates", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		got, err := pres.RenderError(pres.BuildPendingContext(addr, coords))
+		if err != nil {
+			t.Fatalf("failed to render error template: %s", err)
+		}
+		if want := "⚠️ Weather unavailable"; got != want {
+			t.Errorf("expected error output to be %q, got %q", want, got)
+		}
+	})
+}
+
+func TestPresenter_BuildLocations(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	pres.Clock = clock.NewFake(now)
+
+	fcasts := make(map[weather.DayHour]weather.Instant)
+	fcasts[fcastHour] = wthrAlt
+	data := map[string]*weather.Data{
+		"Berlin": {
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: 52.52, Lon: 13.405},
+			Current:     wthr,
+			Forecast:    fcasts,
+		},
+		"Nowhere": nil,
+	}
+	locations := pres.BuildLocations(data)
+	if len(locations) != 1 {
+		t.Fatalf("expected 1 location, got %d", len(locations))
+	}
+	berlin, ok := locations["Berlin"]
+	if !ok {
+		t.Fatal("expected location Berlin to be present")
+	}
+	if berlin.Name != "Berlin" {
+		t.Errorf("expected location name to be %q, got %q", "Berlin", berlin.Name)
+	}
+	if berlin.Latitude != 52.52 || berlin.Longitude != 13.405 {
+		t.Errorf("unexpected location coordinates: %f, %f", berlin.Latitude, berlin.Longitude)
+	}
+	if berlin.Current.Temperature != wthr.Temperature {
+		t.Errorf("expected current temperature to be %f, got %f", wthr.Temperature, berlin.Current.Temperature)
+	}
+	if berlin.Forecast.Temperature != wthrAlt.Temperature {
+		t.Errorf("expected forecast temperature to be %f, got %f", wthrAlt.Temperature,
+			berlin.Forecast.Temperature)
+	}
+	if berlin.Current.Condition != "Fog" {
+		t.Errorf("expected current condition to be %q, got %q", "Fog", berlin.Current.Condition)
+	}
+}
+
 func TestPresenter_Render(t *testing.T) {
 	t.Run("rendering succeeds", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_LEGACY_DEFAULT", "true")
 		conf, lang := testConfLang(t)
 		pres, err := New(conf, lang)
 		if err != nil {
 			t.Fatalf("failed to create presenter: %s", err)
 		}
+		pres.Clock = clock.NewFake(now)
 
 		fcasts := make(map[weather.DayHour]weather.Instant)
 		fcasts[fcastHour] = wthrAlt
@@ -268,7 +400,7 @@ func TestPresenter_Render(t *testing.T) {
 			Current:     wthr,
 			Forecast:    fcasts,
 		}
-		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase)
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
 		outMap, err := pres.Render(tplCtx)
 		if err != nil {
 			t.Fatalf("failed to render: %s", err)
@@ -282,16 +414,16 @@ func TestPresenter_Render(t *testing.T) {
 Mainly clear
 Feels like: 30.0°F
 Humidity: 43%
-Pressure: 1,083.4 hPa
-Wind: 3.0 → 19.0 m/h (S)
+Pressure: 1,083 hPa
+Wind: 3 m/h → 19 m/h (S)
 
 🌅 7:01 a.m. • 🌇 5:39 p.m.`
 		wantTooltip := `Test City, Test Country
 Fog
 Feels like: 25.0°C
 Humidity: 87%
-Pressure: 1,013.2 hPa
-Wind: 10.0 → 30.0 km/h (NE)
+Pressure: 1,013 hPa
+Wind: 10 km/h → 30 km/h (NE)
 
 🌅 7:01 a.m. • 🌇 5:39 p.m.`
 		if outMap["text"] != wantText {
@@ -307,6 +439,168 @@ Wind: 10.0 → 30.0 km/h (NE)
 			t.Errorf("expected tooltip output to be %q, got %q", wantTooltip, outMap["tooltip"])
 		}
 	})
+	t.Run("default tooltip", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			locale     string
+			timeFormat string
+			legacy     string
+			want       string
+		}{
+			{
+				"english locale", "en", "", "false",
+				"Test City, Test Country\n🌫️ Fog, 20.0°C\nFeels like: 25.0°C\nHumidity: 87%\nPressure: 1,013 hPa\n" +
+					"Wind: 10 km/h → 30 km/h (NE)\n\n+3h: 🌫️ 10.0°C\n+6h: 🌫️ 13.0°C\n+12h: 🌫️ 19.0°C\n\n" +
+					"Today: ↑ 21.0°C ↓ -2.0°C\n🌅 7:01 a.m. • 🌇 5:39 p.m. • 🌔 Waxing gibbous",
+			},
+			{
+				"german locale", "de", "", "false",
+				"Test City, Test Country\n🌫️ Nebel, 20,0°C\nGefühlt: 25,0°C\nLuftfeuchtigkeit: 87%\n" +
+					"Luftdruck: 1.013 hPa\nWind: 10 km/h → 30 km/h (NE)\n\n+3h: 🌫️ 10,0°C\n+6h: 🌫️ 13,0°C\n" +
+					"+12h: 🌫️ 19,0°C\n\nHeute: ↑ 21,0°C ↓ -2,0°C\n🌅 07:01 • 🌇 17:39 • 🌔 Zunehmender Mond",
+			},
+			{
+				"configured time format overrides the locale", "de", "3:04 PM", "false",
+				"Test City, Test Country\n🌫️ Nebel, 20,0°C\nGefühlt: 25,0°C\nLuftfeuchtigkeit: 87%\n" +
+					"Luftdruck: 1.013 hPa\nWind: 10 km/h → 30 km/h (NE)\n\n+3h: 🌫️ 10,0°C\n+6h: 🌫️ 13,0°C\n" +
+					"+12h: 🌫️ 19,0°C\n\nHeute: ↑ 21,0°C ↓ -2,0°C\n🌅 7:01 AM • 🌇 5:39 PM • 🌔 Zunehmender Mond",
+			},
+			{
+				"legacy default in english locale", "en", "", "true",
+				"Test City, Test Country\nFog\nFeels like: 25.0°C\nHumidity: 87%\nPressure: 1,013 hPa\n" +
+					"Wind: 10 km/h → 30 km/h (NE)\n\n🌅 7:01 a.m. • 🌇 5:39 p.m.",
+			},
+			{
+				"legacy default in german locale", "de", "", "true",
+				"Test City, Test Country\nNebel\nGefühlt: 25,0°C\nLuftfeuchtigkeit: 87%\nLuftdruck: 1.013 hPa\n" +
+					"Wind: 10 km/h → 30 km/h (NE)\n\n🌅 07:01 • 🌇 17:39",
+			},
+		}
+		at := time.Date(2026, 1, 18, 9, 30, 0, 0, time.UTC)
+		current := wthr
+		current.InstantTime = at
+		data := &weather.Data{
+			GeneratedAt: at,
+			Timezone:    "UTC",
+			Current:     current,
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		for i := 0; i < 24; i++ {
+			fcast := wthr
+			fcast.InstantTime = time.Date(2026, 1, 18, i, 0, 0, 0, time.UTC)
+			fcast.Temperature = float64(i - 2)
+			data.Forecast[weather.NewDayHour(fcast.InstantTime)] = fcast
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_LOCALE", tc.locale)
+				t.Setenv("WAYBARWEATHER_OUTPUT_TIME_FORMAT", tc.timeFormat)
+				t.Setenv("WAYBARWEATHER_TEMPLATES_LEGACY_DEFAULT", tc.legacy)
+				conf, lang := testConfLang(t)
+				pres, err := New(conf, lang)
+				if err != nil {
+					t.Fatalf("failed to create presenter: %s", err)
+				}
+				pres.Clock = clock.NewFake(at)
+				outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{}))
+				if err != nil {
+					t.Fatalf("failed to render: %s", err)
+				}
+				if outMap["tooltip"] != tc.want {
+					t.Errorf("expected tooltip output to be %q, got %q", tc.want, outMap["tooltip"])
+				}
+			})
+		}
+	})
+	t.Run("default tooltip without forecasts omits the forecast section", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		data := &weather.Data{GeneratedAt: now, Current: wthr}
+		outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, "", time.Time{}))
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		want := "Test City, Test Country\n🌫️ Fog, 20.0°C\nFeels like: 25.0°C\nHumidity: 87%\n" +
+			"Pressure: 1,013 hPa\nWind: 10 km/h → 30 km/h (NE)\n\n🌅 7:01 a.m. • 🌇 5:39 p.m."
+		if outMap["tooltip"] != want {
+			t.Errorf("expected tooltip output to be %q, got %q", want, outMap["tooltip"])
+		}
+	})
+	t.Run("default tooltip omits a feels like close to the temperature", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		current := wthr
+		current.ApparentTemperature = current.Temperature + 1.5
+		data := &weather.Data{GeneratedAt: now, Current: current}
+		outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, "", time.Time{}))
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		want := "Test City, Test Country\n🌫️ Fog, 20.0°C\nHumidity: 87%\n" +
+			"Pressure: 1,013 hPa\nWind: 10 km/h → 30 km/h (NE)\n\n🌅 7:01 a.m. • 🌇 5:39 p.m."
+		if outMap["tooltip"] != want {
+			t.Errorf("expected tooltip output to be %q, got %q", want, outMap["tooltip"])
+		}
+	})
+	t.Run("night templates are used at night", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			isDay        bool
+			textNight    string
+			tooltipNight string
+			wantText     string
+			wantTooltip  string
+		}{
+			{"day uses the regular templates", true, "night", "night tooltip", "day", "day tooltip"},
+			{"night uses the night templates", false, "night", "night tooltip", "night", "night tooltip"},
+			{"night without night templates", false, "", "", "day", "day tooltip"},
+			{"night with only a night text template", false, "night", "", "night", "day tooltip"},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				conf, lang := testConfLang(t)
+				conf.Templates.Text = "day"
+				conf.Templates.Tooltip = "day tooltip"
+				conf.Templates.AltText = "alt"
+				conf.Templates.AltTooltip = "alt tooltip"
+				conf.Templates.TextNight = tc.textNight
+				conf.Templates.TooltipNight = tc.tooltipNight
+				pres, err := New(conf, lang)
+				if err != nil {
+					t.Fatalf("failed to create presenter: %s", err)
+				}
+				current := wthr
+				current.IsDay = tc.isDay
+				data := &weather.Data{
+					GeneratedAt: now,
+					Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+					Current:     current,
+				}
+				outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{}))
+				if err != nil {
+					t.Fatalf("failed to render: %s", err)
+				}
+				if outMap["text"] != tc.wantText {
+					t.Errorf("expected text output to be %q, got %q", tc.wantText, outMap["text"])
+				}
+				if outMap["tooltip"] != tc.wantTooltip {
+					t.Errorf("expected tooltip output to be %q, got %q", tc.wantTooltip, outMap["tooltip"])
+				}
+				if outMap["alt_text"] != "alt" || outMap["alt_tooltip"] != "alt tooltip" {
+					t.Errorf("expected alternative outputs to be unchanged, got %q and %q", outMap["alt_text"],
+						outMap["alt_tooltip"])
+				}
+			})
+		}
+	})
 	t.Run("rendering with invalid templates fails", func(t *testing.T) {
 		tests := []struct {
 			name       string
@@ -377,14 +671,132 @@ Wind: 10.0 → 30.0 km/h (NE)
 					Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
 					Current:     wthr,
 				}
-				tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase)
-				_, err = pres.Render(tplCtx)
+				tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+				outMap, err := pres.Render(tplCtx)
 				if err == nil {
 					t.Error("expected rendering to fail, but didn't")
 				}
+				var tplErr *TemplateError
+				if !errors.As(err, &tplErr) || tplErr.Field != tt.name {
+					t.Errorf("expected template error of field %q, got %v", tt.name, err)
+				}
+				for field, value := range outMap {
+					if field == tt.name && value != TemplateErrorMarker {
+						t.Errorf("expected failed field %q to hold the error marker, got %q", field, value)
+					}
+					if field != tt.name && (value == "" || value == TemplateErrorMarker) {
+						t.Errorf("expected field %q to be rendered, got %q", field, value)
+					}
+				}
+				if len(outMap) != 4 {
+					t.Errorf("expected all 4 fields to be rendered, got %d", len(outMap))
+				}
 			})
 		}
 	})
+	t.Run("unit overrides are converted locally", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Pressure = weather.UnitInHg
+		conf.UnitOverrides.WindSpeed = weather.UnitMs
+		conf.Templates.Text = "{{floatFormat .Current.PressureMSL 2}} {{.Current.Units.Pressure}}"
+		conf.Templates.Tooltip = "{{floatFormat .Current.WindSpeed 1}} {{.Current.Units.WindSpeed}}"
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		data := &weather.Data{GeneratedAt: now, Current: wthr}
+		outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{}))
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		if want := "29.91 inHg"; outMap["text"] != want {
+			t.Errorf("expected text output to be %q, got %q", want, outMap["text"])
+		}
+		if want := "2.7 m/s"; outMap["tooltip"] != want {
+			t.Errorf("expected tooltip output to be %q, got %q", want, outMap["tooltip"])
+		}
+	})
+}
+
+func TestPresenter_formatValue(t *testing.T) {
+	type want struct {
+		temp, apparent, dewPoint, wind, gusts, humidity, pressure, precip string
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		instant weather.Instant
+		want    want
+	}{
+		{
+			"metric with default precision",
+			map[string]string{"WAYBARWEATHER_UNITS": "metric"},
+			weather.Instant{
+				Temperature: 20.26, ApparentTemperature: -0.04, DewPoint: 12.35, WindSpeed: 10.4, WindGusts: 29.6,
+				RelativeHumidity: 87, PressureMSL: 1013.25, Precipitation: 0.35,
+				Units: weather.Units{
+					Temperature: "°C", WindSpeed: "km/h", Humidity: "%", Pressure: "hPa", Precipitation: "mm",
+				},
+			},
+			want{"20.3°C", "0.0°C", "12.4°C", "10 km/h", "30 km/h", "87%", "1,013 hPa", "0.4 mm"},
+		},
+		{
+			"imperial with default precision",
+			map[string]string{
+				"WAYBARWEATHER_UNITS":                   "imperial",
+				"WAYBARWEATHER_UNIT_OVERRIDES_PRESSURE": "inhg",
+			},
+			weather.Instant{
+				Temperature: 68.47, ApparentTemperature: 70.04, DewPoint: 54.23, WindSpeed: 6.46, WindGusts: 18.5,
+				RelativeHumidity: 43, PressureMSL: 1013.25, Precipitation: 0.014,
+				Units: weather.Units{
+					Temperature: "°F", WindSpeed: "mph", Humidity: "%", Pressure: "hPa", Precipitation: "inch",
+				},
+			},
+			want{"68.5°F", "70.0°F", "54.2°F", "6 mph", "19 mph", "43%", "29.92 inHg", "0.01 inch"},
+		},
+		{
+			"configured precision",
+			map[string]string{
+				"WAYBARWEATHER_UNITS":                          "metric",
+				"WAYBARWEATHER_OUTPUT_TEMPERATURE_PRECISION":   "0",
+				"WAYBARWEATHER_OUTPUT_WIND_PRECISION":          "1",
+				"WAYBARWEATHER_OUTPUT_PRESSURE_PRECISION":      "1",
+				"WAYBARWEATHER_OUTPUT_PRECIPITATION_PRECISION": "2",
+			},
+			weather.Instant{
+				Temperature: 20.26, ApparentTemperature: -0.4, DewPoint: 12.35, WindSpeed: 10.44, WindGusts: 29.6,
+				RelativeHumidity: 87, PressureMSL: 1013.25, Precipitation: 0.35,
+				Units: weather.Units{
+					Temperature: "°C", WindSpeed: "km/h", Humidity: "%", Pressure: "hPa", Precipitation: "mm",
+				},
+			},
+			want{"20°C", "0°C", "12°C", "10.4 km/h", "29.6 km/h", "87%", "1,013.3 hPa", "0.35 mm"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for key, val := range tc.env {
+				t.Setenv(key, val)
+			}
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			view := pres.viewFromInstant(tc.instant, 0)
+			got := want{
+				view.TemperatureStr, view.ApparentTemperatureStr, view.DewPointStr, view.WindSpeedStr,
+				view.WindGustsStr, view.RelativeHumidityStr, view.PressureStr, view.PrecipitationStr,
+			}
+			if got != tc.want {
+				t.Errorf("expected formatted values to be %+v, got %+v", tc.want, got)
+			}
+			if view.Temperature != tc.instant.Temperature {
+				t.Errorf("expected raw temperature to be %f, got %f", tc.instant.Temperature, view.Temperature)
+			}
+		})
+	}
 }
 
 func TestPresenter_weatherCategory(t *testing.T) {
@@ -414,97 +826,535 @@ func TestPresenter_weatherCategory(t *testing.T) {
 	}
 }
 
-func TestPresenter_degToString(t *testing.T) {
+func TestPresenter_comfort(t *testing.T) {
+	metric := weather.Units{Temperature: "°C", WindSpeed: "km/h"}
+	imperial := weather.Units{Temperature: "°F", WindSpeed: "mp/h"}
 	tests := []struct {
-		name string
-		deg  float64
-		want string
+		name      string
+		instant   weather.Instant
+		windChill float64
+		heatIndex float64
+		index     string
+		label     string
 	}{
-		{"0 -> North", 0, "N"},
-		{"22.4 -> North", 22.4, "N"},
-		{"22.5 -> North-East", 22.5, "NE"},
-		{"67.4 -> North-East", 67.4, "NE"},
-		{"67.5 -> East", 67.5, "E"},
-		{"112.4 -> East", 112.4, "E"},
-		{"112.5 -> South-East", 112.5, "SE"},
-		{"157.4 -> South-East", 157.4, "SE"},
-		{"157.5 -> South", 157.5, "S"},
-		{"202.4 -> South", 202.4, "S"},
-		{"202.5 -> South-West", 202.5, "SW"},
-		{"247.4 -> South-West", 247.4, "SW"},
-		{"247.5 -> West", 247.5, "W"},
-		{"292.4 -> West", 292.4, "W"},
-		{"292.5 -> North-West", 292.5, "NW"},
-		{"337.4 -> North-West", 337.4, "NW"},
-		{"337.5 -> North", 337.5, "N"},
-		{"359.9 -> North", 359.9, "N"},
-		{"360.0 -> North", 360.0, "N"},
+		{
+			"mild weather applies no index",
+			weather.Instant{Temperature: 20, WindSpeed: 20, RelativeHumidity: 50, Units: metric},
+			20, 20, "", "",
+		},
+		{
+			"exactly 10°C returns the plain temperature",
+			weather.Instant{Temperature: 10, WindSpeed: 20, Units: metric},
+			10, 10, "", "",
+		},
+		{
+			"calm wind returns the plain temperature",
+			weather.Instant{Temperature: 5, WindSpeed: 4.8, Units: metric},
+			5, 5, "", "",
+		},
+		{
+			"wind chill in metric units",
+			weather.Instant{Temperature: 5, WindSpeed: 20, Units: metric},
+			1.07, 5, ComfortWindChill, "feels cold",
+		},
+		{
+			"wind chill in imperial units",
+			weather.Instant{Temperature: 41, WindSpeed: 20 / 1.609344, Units: imperial},
+			33.92, 41, ComfortWindChill, "feels cold",
+		},
+		{
+			"dangerous wind chill",
+			weather.Instant{Temperature: -20, WindSpeed: 30, Units: metric},
+			-32.57, -20, ComfortWindChill, "feels dangerously cold",
+		},
+		{
+			"low humidity returns the plain temperature",
+			weather.Instant{Temperature: 32, RelativeHumidity: 39, Units: metric},
+			32, 32, "", "",
+		},
+		{
+			"temperature below 27°C returns the plain temperature",
+			weather.Instant{Temperature: 26.9, RelativeHumidity: 90, Units: metric},
+			26.9, 26.9, "", "",
+		},
+		{
+			"heat index in metric units",
+			weather.Instant{Temperature: 32, RelativeHumidity: 70, Units: metric},
+			32, 40.41, ComfortHeatIndex, "feels very hot",
+		},
+		{
+			"heat index in imperial units",
+			weather.Instant{Temperature: 90, RelativeHumidity: 70, Units: imperial},
+			90, 105.92, ComfortHeatIndex, "feels dangerously hot",
+		},
+		{
+			"extreme heat index",
+			weather.Instant{Temperature: 38, RelativeHumidity: 60, Units: metric},
+			38, 54.96, ComfortHeatIndex, "feels extremely hot",
+		},
 	}
 
-	pres := new(Presenter)
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := pres.degToString(tt.deg)
-			if got != tt.want {
-				t.Errorf("failed to get direction: got %s, want %s", got, tt.want)
+			view := pres.viewFromInstant(tt.instant, 0)
+			if math.Abs(view.WindChill-tt.windChill) > 0.01 {
+				t.Errorf("expected wind chill to be %.2f, got %.2f", tt.windChill, view.WindChill)
+			}
+			if math.Abs(view.HeatIndex-tt.heatIndex) > 0.01 {
+				t.Errorf("expected heat index to be %.2f, got %.2f", tt.heatIndex, view.HeatIndex)
+			}
+			if view.Comfort != tt.index {
+				t.Errorf("expected comfort index to be %q, got %q", tt.index, view.Comfort)
+			}
+			if view.ComfortLabel != tt.label {
+				t.Errorf("expected comfort label to be %q, got %q", tt.label, view.ComfortLabel)
 			}
 		})
 	}
 }
 
-func TestPresenter_loc(t *testing.T) {
-	t.Run("localized value is found", func(t *testing.T) {
-		conf, lang := testConfLang(t)
-		pres, err := New(conf, lang)
-		if err != nil {
-			t.Fatalf("failed to create presenter: %s", err)
-		}
-		want := "Temperature"
-		if got := pres.loc("temp"); got != want {
-			t.Errorf("failed to get localized value: got %s, want %s", got, want)
-		}
-	})
-	t.Run("localized german value is found", func(t *testing.T) {
-		conf, err := config.New()
-		if err != nil {
-			t.Fatalf("failed to create config: %s", err)
-		}
-		lang, err := i18n.New("de-DE")
-		if err != nil {
-			t.Fatalf("failed to create i18n provider: %s", err)
-		}
-		pres, err := New(conf, lang)
-		if err != nil {
-			t.Fatalf("failed to create presenter: %s", err)
-		}
-		want := "Gefühlt"
-		if got := pres.loc("apparent"); got != want {
-			t.Errorf("failed to get localized value: got %s, want %s", got, want)
-		}
-	})
-	t.Run("localized value is not found", func(t *testing.T) {
-		conf, lang := testConfLang(t)
-		pres, err := New(conf, lang)
-		if err != nil {
-			t.Fatalf("failed to create presenter: %s", err)
-		}
-		want := "foobar"
-		if got := pres.loc("foobar"); got != want {
-			t.Errorf("failed to get localized value: got %s, want %s", got, want)
-		}
-	})
-}
-
-func TestPresenter_timeFormat(t *testing.T) {
-	t.Run("RFC3339 format is used", func(t *testing.T) {
-		pres := new(Presenter)
-		if got := pres.timeFormat(now, time.RFC3339); got != now.Format(time.RFC3339) {
-			t.Errorf("failed to get time format: got %s, want %s", got, now.Format(time.RFC3339))
-		}
-	})
+func TestPresenter_showApparent(t *testing.T) {
+	tests := []struct {
+		name     string
+		delta    string
+		apparent float64
+		want     bool
+		wantLine string
+	}{
+		{"apparent temperature differs", "2", 25, true, "Feels like: 25.0°C"},
+		{"apparent temperature is colder", "2", 17.5, true, "Feels like: 17.5°C"},
+		{"apparent temperature is close", "2", 21.5, false, ""},
+		{"apparent temperature at the delta", "2", 22, false, ""},
+		{"apparent temperature equals", "2", 20, false, ""},
+		{"zero delta shows any difference", "0", 20.1, true, "Feels like: 20.1°C"},
+		{"delta in °F is converted", "2F", 21.5, true, "Feels like: 21.5°C"},
+		{"delta in °F is converted and close", "2F", 21, false, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Weather.ApparentDelta = tc.delta
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			instant := weather.Instant{Temperature: 20, ApparentTemperature: tc.apparent,
+				Units: weather.Units{Temperature: "°C"}}
+			view := pres.viewFromInstant(instant, 0)
+			if view.ShowApparent != tc.want {
+				t.Errorf("expected ShowApparent to be %t, got %t", tc.want, view.ShowApparent)
+			}
+			if view.FeelsLikeLine != tc.wantLine {
+				t.Errorf("expected FeelsLikeLine to be %q, got %q", tc.wantLine, view.FeelsLikeLine)
+			}
+		})
+	}
 }
 
-func TestPresenter_floatFormat(t *testing.T) {
+func TestPresenter_hotCold(t *testing.T) {
+	tests := []struct {
+		name     string
+		cold     string
+		hot      string
+		temp     float64
+		unit     string
+		wantHot  bool
+		wantCold bool
+	}{
+		{"default thresholds in °C", "2C", "30C", 30, "°C", true, false},
+		{"default cold threshold in °C", "2C", "30C", 1.5, "°C", false, true},
+		{"default thresholds converted into °F", "2C", "30C", 86, "°F", true, false},
+		{"default cold threshold converted into °F", "2C", "30C", 35.6, "°F", false, true},
+		{"mild temperature in °F", "2C", "30C", 50, "°F", false, false},
+		{"thresholds in °F converted into °C", "32F", "86F", 0, "°C", false, true},
+		{"thresholds without unit in °F", "40", "80", 80, "°F", true, false},
+		{"thresholds without unit in °C", "40", "80", 35, "°C", false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Weather.ColdThreshold = tc.cold
+			conf.Weather.HotThreshold = tc.hot
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			view := pres.viewFromInstant(weather.Instant{Temperature: tc.temp,
+				Units: weather.Units{Temperature: tc.unit}}, 0)
+			if view.Hot != tc.wantHot {
+				t.Errorf("expected Hot to be %t, got %t", tc.wantHot, view.Hot)
+			}
+			if view.Cold != tc.wantCold {
+				t.Errorf("expected Cold to be %t, got %t", tc.wantCold, view.Cold)
+			}
+		})
+	}
+}
+
+func TestPresenter_forecastFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		forecast map[weather.DayHour]weather.Instant
+		want     bool
+		wantTemp float64
+	}{
+		{"empty forecast", map[weather.DayHour]weather.Instant{}, false, wthr.Temperature},
+		{"missing forecast map", nil, false, wthr.Temperature},
+		{"forecast without the forecast hour", map[weather.DayHour]weather.Instant{fcastHourFirst: wthrAlt}, false,
+			wthr.Temperature},
+		{"forecast with the forecast hour", map[weather.DayHour]weather.Instant{fcastHour: wthrAlt}, true,
+			wthrAlt.Temperature},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.Clock = clock.NewFake(now)
+			data := &weather.Data{GeneratedAt: now, Current: wthr, Forecast: tc.forecast}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			if tplCtx.HasForecast != tc.want {
+				t.Errorf("expected HasForecast to be %t, got %t", tc.want, tplCtx.HasForecast)
+			}
+			if tplCtx.Forecast.Temperature != tc.wantTemp {
+				t.Errorf("expected forecast temperature to be %.1f, got %.1f", tc.wantTemp,
+					tplCtx.Forecast.Temperature)
+			}
+			// The worst condition covers all hours up to the forecast hour, so only an empty forecast falls back
+			if len(tc.forecast) == 0 && tplCtx.WorstForecast.Temperature != tc.wantTemp {
+				t.Errorf("expected worst forecast temperature to be %.1f, got %.1f", tc.wantTemp,
+					tplCtx.WorstForecast.Temperature)
+			}
+			if !tc.want {
+				outMap, err := pres.Render(tplCtx)
+				if err != nil {
+					t.Fatalf("failed to render template: %s", err)
+				}
+				if outMap["alt_text"] != outMap["text"] {
+					t.Errorf("expected alternative text to show the current weather %q, got %q", outMap["text"],
+						outMap["alt_text"])
+				}
+			}
+
+			locations := pres.BuildLocations(map[string]*weather.Data{"test": data})
+			if got := locations["test"].Forecast.Temperature; got != tc.wantTemp {
+				t.Errorf("expected location forecast temperature to be %.1f, got %.1f", tc.wantTemp, got)
+			}
+		})
+	}
+}
+
+func TestPresenter_isStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		jitter   time.Duration
+		age      time.Duration
+		want     bool
+	}{
+		{"fresh data", time.Minute * 15, 0, time.Minute * 10, false},
+		{"data older than the interval", time.Minute * 15, 0, time.Minute * 16, true},
+		{"data within the jitter", time.Minute * 15, time.Minute * 5, time.Minute * 19, false},
+		{"data older than the interval and the jitter", time.Minute * 15, time.Minute * 5, time.Minute * 21, true},
+		{"data within the lengthened interval", time.Minute * 45, 0, time.Minute * 40, false},
+		{"disabled interval", 0, 0, time.Hour * 24, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Intervals.Jitter = tc.jitter
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.SetUpdateInterval(tc.interval)
+			if got := pres.isStale(tc.age); got != tc.want {
+				t.Errorf("expected data of %s to be stale: %t, got %t", tc.age, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPresenter_freshness(t *testing.T) {
+	tests := []struct {
+		name        string
+		generated   time.Time
+		locationAt  time.Time
+		wantAge     time.Duration
+		wantAgeStr  string
+		wantLocAge  time.Duration
+		wantLocStr  string
+		wantStale   bool
+		wantLine    string
+		wantTooltip bool
+	}{
+		{
+			name: "fresh data", generated: now.Add(-time.Minute * 5), locationAt: now.Add(-time.Minute * 2),
+			wantAge: time.Minute * 5, wantAgeStr: "5 minutes", wantLocAge: time.Minute * 2, wantLocStr: "2 minutes",
+		},
+		{
+			name: "data at the update interval", generated: now.Add(-time.Minute * 15),
+			wantAge: time.Minute * 15, wantAgeStr: "15 minutes",
+		},
+		{
+			name: "stale data", generated: now.Add(-time.Minute * 23), locationAt: now.Add(-time.Second * 30),
+			wantAge: time.Minute * 23, wantAgeStr: "23 minutes", wantLocAge: time.Second * 30, wantLocStr: "0 minutes",
+			wantStale: true, wantLine: "Data from 23 minutes ago",
+		},
+		{
+			name: "stale data of hours", generated: now.Add(-time.Minute * 90),
+			wantAge: time.Minute * 90, wantAgeStr: "1 hour, 30 minutes", wantStale: true,
+			wantLine: "Data from 1 hour, 30 minutes ago",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.Clock = clock.NewFake(now)
+			data := &weather.Data{
+				GeneratedAt: tc.generated,
+				Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+				Current:     wthr,
+				Forecast:    map[weather.DayHour]weather.Instant{fcastHour: wthrAlt},
+			}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, tc.locationAt)
+			if tplCtx.DataAge != tc.wantAge || tplCtx.DataAgeStr != tc.wantAgeStr {
+				t.Errorf("expected data age to be %s (%q), got %s (%q)", tc.wantAge, tc.wantAgeStr,
+					tplCtx.DataAge, tplCtx.DataAgeStr)
+			}
+			if tplCtx.LocationAge != tc.wantLocAge || tplCtx.LocationAgeStr != tc.wantLocStr {
+				t.Errorf("expected location age to be %s (%q), got %s (%q)", tc.wantLocAge, tc.wantLocStr,
+					tplCtx.LocationAge, tplCtx.LocationAgeStr)
+			}
+			if tplCtx.DataStale != tc.wantStale {
+				t.Errorf("expected data to be stale: %t, got %t", tc.wantStale, tplCtx.DataStale)
+			}
+			if tplCtx.FreshnessLine != tc.wantLine {
+				t.Errorf("expected freshness line to be %q, got %q", tc.wantLine, tplCtx.FreshnessLine)
+			}
+
+			outMap, err := pres.Render(tplCtx)
+			if err != nil {
+				t.Fatalf("failed to render template: %s", err)
+			}
+			hasLine := strings.Contains(outMap["tooltip"], "Data from")
+			if hasLine != tc.wantStale {
+				t.Errorf("expected tooltip to contain the freshness line: %t, got %q", tc.wantStale,
+					outMap["tooltip"])
+			}
+		})
+	}
+	t.Run("freshness template function", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.Templates.Text = `{{freshness .DataAge}}`
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		data := &weather.Data{GeneratedAt: now.Add(-time.Minute * 7), Current: wthr}
+		outMap, err := pres.Render(pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{}))
+		if err != nil {
+			t.Fatalf("failed to render template: %s", err)
+		}
+		if want := "Data from 7 minutes ago"; outMap["text"] != want {
+			t.Errorf("expected text to be %q, got %q", want, outMap["text"])
+		}
+	})
+	t.Run("unknown location time has no age", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		data := &weather.Data{GeneratedAt: now.Add(time.Minute), Current: wthr}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if tplCtx.DataAge != 0 || tplCtx.LocationAge != 0 || tplCtx.LocationAgeStr != "" {
+			t.Errorf("expected no ages, got %s, %s and %q", tplCtx.DataAge, tplCtx.LocationAge,
+				tplCtx.LocationAgeStr)
+		}
+	})
+}
+
+func TestPresenter_stationPressure(t *testing.T) {
+	tests := []struct {
+		name      string
+		pressure  float64
+		unit      string
+		elevation float64
+		want      float64
+		wantStr   string
+	}{
+		{"sea level", 1013.25, "hPa", 0, 1013.25, "1,013 hPa"},
+		{"1500 meters", 1013.25, "hPa", 1500, 845.56, "846 hPa"},
+		{"1500 meters in inHg", 29.92, "inHg", 1500, 24.97, "25 inHg"},
+	}
+
+	t.Run("station pressure is derived from the sea-level pressure", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.Output.StationPressure = true
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				instant := weather.Instant{PressureMSL: tt.pressure, Units: weather.Units{Pressure: tt.unit}}
+				view := pres.viewFromInstant(instant, tt.elevation)
+				if math.Abs(view.StationPressure-tt.want) > 0.01 {
+					t.Errorf("expected station pressure to be %.2f, got %.2f", tt.want, view.StationPressure)
+				}
+				if view.StationPressureStr != tt.wantStr {
+					t.Errorf("expected station pressure string to be %q, got %q", tt.wantStr,
+						view.StationPressureStr)
+				}
+				if view.PressureMSL != tt.pressure {
+					t.Errorf("expected sea-level pressure to be %.2f, got %.2f", tt.pressure, view.PressureMSL)
+				}
+			})
+		}
+	})
+	t.Run("station pressure is not set if disabled", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		instant := weather.Instant{PressureMSL: 1013.25, Units: weather.Units{Pressure: "hPa"}}
+		view := pres.viewFromInstant(instant, 1500)
+		if view.StationPressure != 0 || view.StationPressureStr != "" {
+			t.Errorf("expected station pressure to be empty, got %.2f/%q", view.StationPressure,
+				view.StationPressureStr)
+		}
+	})
+}
+
+func TestPresenter_degToString(t *testing.T) {
+	tests := []struct {
+		name string
+		deg  float64
+		want string
+	}{
+		{"0 -> North", 0, "N"},
+		{"22.4 -> North", 22.4, "N"},
+		{"22.5 -> North-East", 22.5, "NE"},
+		{"67.4 -> North-East", 67.4, "NE"},
+		{"67.5 -> East", 67.5, "E"},
+		{"112.4 -> East", 112.4, "E"},
+		{"112.5 -> South-East", 112.5, "SE"},
+		{"157.4 -> South-East", 157.4, "SE"},
+		{"157.5 -> South", 157.5, "S"},
+		{"202.4 -> South", 202.4, "S"},
+		{"202.5 -> South-West", 202.5, "SW"},
+		{"247.4 -> South-West", 247.4, "SW"},
+		{"247.5 -> West", 247.5, "W"},
+		{"292.4 -> West", 292.4, "W"},
+		{"292.5 -> North-West", 292.5, "NW"},
+		{"337.4 -> North-West", 337.4, "NW"},
+		{"337.5 -> North", 337.5, "N"},
+		{"359.9 -> North", 359.9, "N"},
+		{"360.0 -> North", 360.0, "N"},
+	}
+
+	pres := new(Presenter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pres.degToString(tt.deg)
+			if got != tt.want {
+				t.Errorf("failed to get direction: got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenter_loc(t *testing.T) {
+	t.Run("localized value is found", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		want := "Temperature"
+		if got := pres.loc("temp"); got != want {
+			t.Errorf("failed to get localized value: got %s, want %s", got, want)
+		}
+	})
+	t.Run("localized german value is found", func(t *testing.T) {
+		conf, err := config.New()
+		if err != nil {
+			t.Fatalf("failed to create config: %s", err)
+		}
+		lang, err := i18n.New("de-DE")
+		if err != nil {
+			t.Fatalf("failed to create i18n provider: %s", err)
+		}
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		want := "Gefühlt"
+		if got := pres.loc("apparent"); got != want {
+			t.Errorf("failed to get localized value: got %s, want %s", got, want)
+		}
+	})
+	t.Run("localized value is not found", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		want := "foobar"
+		if got := pres.loc("foobar"); got != want {
+			t.Errorf("failed to get localized value: got %s, want %s", got, want)
+		}
+	})
+}
+
+func TestPresenter_timeFormat(t *testing.T) {
+	t.Run("RFC3339 format is used", func(t *testing.T) {
+		pres := new(Presenter)
+		if got := pres.timeFormat(now, time.RFC3339); got != now.Format(time.RFC3339) {
+			t.Errorf("failed to get time format: got %s, want %s", got, now.Format(time.RFC3339))
+		}
+	})
+}
+
+func TestPresenter_inTimezone(t *testing.T) {
+	val := time.Date(2026, 1, 18, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		tz   string
+		want string
+	}{
+		{"Europe/Berlin", "Europe/Berlin", "13:30"},
+		{"America/New_York", "America/New_York", "07:30"},
+		{"UTC", "UTC", "12:30"},
+		{"empty timezone uses local time", "", val.Local().Format("15:04")},
+		{"unknown timezone uses local time", "Invalid/Zone", val.Local().Format("15:04")},
+	}
+	pres := new(Presenter)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pres.inTimezone(val, tt.tz, "15:04"); got != tt.want {
+				t.Errorf("failed to format time in timezone: got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenter_floatFormat(t *testing.T) {
 	tests := []struct {
 		name string
 		val  float64
@@ -566,53 +1416,2197 @@ func TestPresenter_windDirIcon(t *testing.T) {
 	}
 }
 
-func TestPresenter_forecastByOffset(t *testing.T) {
-	t.Run("forecast is found", func(t *testing.T) {
+func TestPresenter_formatAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		addr   geocode.Address
+		want   string
+	}{
+		{
+			"no display format keeps the provider display name", "",
+			geocode.Address{City: "Berlin", Country: "Germany", DisplayName: "Friedrichstraße 67, Berlin"},
+			"Friedrichstraße 67, Berlin",
+		},
+		{
+			"city and country", "{{.City}}, {{.Country}}",
+			geocode.Address{City: "Berlin", Country: "Germany", DisplayName: "Friedrichstraße 67, Berlin"},
+			"Berlin, Germany",
+		},
+		{
+			"city only", "{{.City}}, {{.State}}, {{.Country}}",
+			geocode.Address{City: "Berlin"},
+			"Berlin",
+		},
+		{
+			"village", "{{.Suburb}}, {{.City}}, {{.Postcode}}, {{.Country}}",
+			geocode.Address{City: "Marshfield", Postcode: "SN14 8LP", Country: "United Kingdom"},
+			"Marshfield, SN14 8LP, United Kingdom",
+		},
+		{
+			"missing country", "{{.City}}, {{.Country}}",
+			geocode.Address{City: "Otley", State: "England"},
+			"Otley",
+		},
+		{
+			"all fields missing", "{{.City}}, {{.Country}}",
+			geocode.Address{},
+			"",
+		},
+		{
+			"coordinates are kept without address fields", "{{.City}}, {{.Country}}",
+			geocode.Address{DisplayName: "52.51°N 13.39°E"},
+			"52.51°N 13.39°E",
+		},
+		{
+			"template functions are available", "{{uc .City}} {{countryFlag \"de\"}}",
+			geocode.Address{City: "Berlin"},
+			"BERLIN 🇩🇪",
+		},
+		{
+			"geofence label replaces the display format", "{{.City}}, {{.Country}}",
+			geocode.Address{City: "Schöneberg", Country: "Germany", Label: "Office"},
+			"Office",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.GeoCoder.DisplayFormat = tt.format
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			if got := pres.formatAddress(tt.addr).DisplayName; got != tt.want {
+				t.Errorf("expected display name to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+	t.Run("display format is applied when building the context", func(t *testing.T) {
 		conf, lang := testConfLang(t)
+		conf.GeoCoder.DisplayFormat = "{{.City}}, {{.Country}}"
 		pres, err := New(conf, lang)
 		if err != nil {
 			t.Fatalf("failed to create presenter: %s", err)
 		}
-		fcasts := make(map[weather.DayHour]weather.Instant)
-		for i := -23; i < 25; i++ {
-			fcast := wthr
-			offset := time.Hour * time.Duration(i)
-			fcast.InstantTime = now.Add(offset).Truncate(time.Hour)
-			hour := weather.NewDayHour(fcast.InstantTime)
-			fcasts[hour] = fcast
-		}
-		data := &weather.Data{
-			GeneratedAt: now,
-			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
-			Current:     wthr,
-			Forecast:    fcasts,
-		}
-		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase)
-
-		got := pres.forecastByOffset(tplCtx, 3)
-		if got.Temperature != wthr.Temperature {
-			t.Errorf("failed to get forecast by offset: got %f, want %f", got.Temperature,
-				wthr.Temperature)
+		data := &weather.Data{Current: wthr, Forecast: map[weather.DayHour]weather.Instant{fcastHour: wthrAlt}}
+		tplCtx := pres.BuildContext(geocode.Address{City: "Otley"}, data, sunrise, sunset, moonphase, time.Time{})
+		if tplCtx.Address.DisplayName != "Otley" {
+			t.Errorf("expected display name to be %q, got %q", "Otley", tplCtx.Address.DisplayName)
 		}
 	})
-	t.Run("forecast is not found", func(t *testing.T) {
+	t.Run("geofence label replaces city and display name in the context", func(t *testing.T) {
 		conf, lang := testConfLang(t)
 		pres, err := New(conf, lang)
 		if err != nil {
 			t.Fatalf("failed to create presenter: %s", err)
 		}
-		fcasts := make(map[weather.DayHour]weather.Instant)
-		data := &weather.Data{
-			GeneratedAt: now,
-			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
-			Current:     wthr,
-			Forecast:    fcasts,
+		addr := geocode.Address{City: "Schöneberg", DisplayName: "Schöneberg, Berlin", Label: "Office"}
+		data := &weather.Data{Current: wthr, Forecast: map[weather.DayHour]weather.Instant{fcastHour: wthrAlt}}
+		for _, tplCtx := range []TemplateContext{
+			pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{}),
+			pres.BuildPendingContext(addr, geobus.Coordinate{Lat: 52.4857, Lon: 13.3539}),
+		} {
+			if tplCtx.LocationLabel != "Office" {
+				t.Errorf("expected location label to be %q, got %q", "Office", tplCtx.LocationLabel)
+			}
+			if tplCtx.Address.City != "Office" || tplCtx.Address.DisplayName != "Office" {
+				t.Errorf("expected city and display name to be the label, got %+v", tplCtx.Address)
+			}
 		}
-		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase)
+	})
+}
 
-		got := pres.forecastByOffset(tplCtx, 3)
-		if got.Temperature != 0 {
-			t.Errorf("failed to get forecast by offset: got %f, want 0", got.Temperature)
+func TestPresenter_shortAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		addr geocode.Address
+		want string
+	}{
+		{"city and country", geocode.Address{City: "Berlin", State: "Berlin", Country: "Germany"}, "Berlin, Germany"},
+		{"city only", geocode.Address{City: "Berlin"}, "Berlin"},
+		{"village", geocode.Address{City: "Marshfield", Country: "United Kingdom"}, "Marshfield, United Kingdom"},
+		{"missing city", geocode.Address{Municipality: "Leeds", Country: "United Kingdom"}, "Leeds, United Kingdom"},
+		{"state only", geocode.Address{State: "England"}, "England"},
+		{"missing country", geocode.Address{City: "Otley"}, "Otley"},
+		{"empty address", geocode.Address{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shortAddress(tt.addr); got != tt.want {
+				t.Errorf("expected short address to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPresenter_countryFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"Germany", "DE", "🇩🇪"},
+		{"Germany lowercase", "de", "🇩🇪"},
+		{"United Kingdom", "GB", "🇬🇧"},
+		{"United States with whitespace", " us ", "🇺🇸"},
+		{"empty", "", ""},
+		{"too long", "DEU", ""},
+		{"invalid characters", "D1", ""},
+		{"non-ASCII", "DÄ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countryFlag(tt.code); got != tt.want {
+				t.Errorf("expected country flag to be %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPresenter_forecastByOffset(t *testing.T) {
+	t.Run("forecast is found", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		fcasts := make(map[weather.DayHour]weather.Instant)
+		for i := -23; i < 25; i++ {
+			fcast := wthr
+			offset := time.Hour * time.Duration(i)
+			fcast.InstantTime = now.Add(offset).Truncate(time.Hour)
+			fcast.Temperature = float64(i)
+			hour := weather.NewDayHour(fcast.InstantTime)
+			fcasts[hour] = fcast
+		}
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Current:     wthr,
+			Forecast:    fcasts,
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+
+		for _, offset := range []int{0, 3, 8} {
+			got := pres.forecastByOffset(tplCtx, offset)
+			if got.Temperature != float64(offset) {
+				t.Errorf("failed to get forecast by offset %d: got %f, want %f", offset, got.Temperature,
+					float64(offset))
+			}
+		}
+		if tplCtx.Forecast.Temperature != float64(conf.Weather.ForecastHours) {
+			t.Errorf("expected forecast temperature to be %f, got %f", float64(conf.Weather.ForecastHours),
+				tplCtx.Forecast.Temperature)
+		}
+	})
+	t.Run("forecast is found in the time zone of the location", func(t *testing.T) {
+		for _, tz := range []string{"Australia/Brisbane", "Asia/Kolkata", "America/New_York"} {
+			t.Run(tz, func(t *testing.T) {
+				loc, err := time.LoadLocation(tz)
+				if err != nil {
+					t.Fatalf("failed to load time zone: %s", err)
+				}
+				conf, lang := testConfLang(t)
+				pres, err := New(conf, lang)
+				if err != nil {
+					t.Fatalf("failed to create presenter: %s", err)
+				}
+				pres.Clock = clock.NewFake(now)
+				data := &weather.Data{Timezone: tz, Forecast: make(map[weather.DayHour]weather.Instant)}
+				data.Current = wthr
+				data.Current.InstantTime = now.In(loc)
+				for i := -23; i < 25; i++ {
+					hour := data.DayHour(now.Add(time.Hour * time.Duration(i)))
+					fcast := wthr
+					fcast.InstantTime = hour.In(loc)
+					fcast.Temperature = float64(i)
+					data.Forecast[hour] = fcast
+				}
+				tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+
+				for _, offset := range []int{0, 3, 8} {
+					got := pres.forecastByOffset(tplCtx, offset)
+					if got.Temperature != float64(offset) {
+						t.Errorf("failed to get forecast by offset %d: got %f, want %f", offset, got.Temperature,
+							float64(offset))
+					}
+				}
+				if tplCtx.Forecast.Temperature != float64(conf.Weather.ForecastHours) {
+					t.Errorf("expected forecast temperature to be %f, got %f", float64(conf.Weather.ForecastHours),
+						tplCtx.Forecast.Temperature)
+				}
+				if got := len(pres.nextHours(tplCtx, 6)); got != 6 {
+					t.Errorf("expected 6 forecasts for the next hours, got %d", got)
+				}
+			})
+		}
+	})
+	t.Run("forecast is not found", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		fcasts := make(map[weather.DayHour]weather.Instant)
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Current:     wthr,
+			Forecast:    fcasts,
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+
+		got := pres.forecastByOffset(tplCtx, 3)
+		if got.Temperature != 0 {
+			t.Errorf("failed to get forecast by offset: got %f, want 0", got.Temperature)
+		}
+	})
+}
+
+func TestPresenter_forecastEntries(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %s", err)
+	}
+	// 23:30 on Sunday, 2026-01-18 in Berlin
+	fixedNow := time.Date(2026, 1, 18, 22, 30, 0, 0, time.UTC)
+	data := &weather.Data{Timezone: "Europe/Berlin", Forecast: make(map[weather.DayHour]weather.Instant)}
+	data.Current = wthr
+	data.Current.InstantTime = fixedNow.In(berlin)
+	for i := -26; i <= 30; i++ {
+		hour := data.DayHour(fixedNow.Add(time.Hour * time.Duration(i)))
+		fcast := wthr
+		fcast.InstantTime = hour.In(berlin)
+		fcast.Temperature = float64(i)
+		data.Forecast[hour] = fcast
+	}
+	buildCtx := func(t *testing.T, lookback time.Duration) (*Presenter, TemplateContext) {
+		t.Helper()
+		conf, lang := testConfLang(t)
+		conf.Weather.ForecastLookback = lookback
+		conf.Output.TimeFormat = "15:04"
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(fixedNow)
+		return pres, pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+	}
+
+	t.Run("forecast list starts with the hour in progress and is sorted", func(t *testing.T) {
+		_, tplCtx := buildCtx(t, 0)
+		if len(tplCtx.Forecasts) != 31 {
+			t.Fatalf("expected 31 forecast entries, got %d", len(tplCtx.Forecasts))
+		}
+		for i, entry := range tplCtx.Forecasts {
+			if entry.Temperature != float64(i) {
+				t.Errorf("expected forecast entry %d to have temperature %d, got %f", i, i, entry.Temperature)
+			}
+		}
+		if got := len(tplCtx.hours); got != 57 {
+			t.Errorf("expected the forecast functions to keep all 57 hours, got %d", got)
+		}
+	})
+	t.Run("forecast list starts the lookback before the hour in progress", func(t *testing.T) {
+		_, tplCtx := buildCtx(t, time.Hour*3)
+		if len(tplCtx.Forecasts) != 34 {
+			t.Fatalf("expected 34 forecast entries, got %d", len(tplCtx.Forecasts))
+		}
+		if got := tplCtx.Forecasts[0].Temperature; got != -3 {
+			t.Errorf("expected first forecast entry to have temperature -3, got %f", got)
+		}
+	})
+	t.Run("forecast entries are labelled with their day and hour", func(t *testing.T) {
+		pres, tplCtx := buildCtx(t, time.Hour*24)
+		tuesday := pres.humanizer.FormatTime(time.Date(2026, 1, 20, 0, 0, 0, 0, berlin), "l")
+		tests := []struct {
+			offset int
+			day    string
+			hour   string
+		}{
+			{-24, "Yesterday", "23:00"},
+			{-23, "Today", "00:00"},
+			{0, "Today", "23:00"},
+			{1, "Tomorrow", "00:00"},
+			{24, "Tomorrow", "23:00"},
+			{25, tuesday, "00:00"},
+		}
+		for _, tc := range tests {
+			entry := tplCtx.Forecasts[tc.offset+24]
+			if entry.Temperature != float64(tc.offset) {
+				t.Fatalf("expected forecast entry at offset %d to have temperature %d, got %f", tc.offset,
+					tc.offset, entry.Temperature)
+			}
+			if entry.DayLabel != tc.day {
+				t.Errorf("expected day label at offset %d to be %q, got %q", tc.offset, tc.day, entry.DayLabel)
+			}
+			if entry.HourLabel != tc.hour {
+				t.Errorf("expected hour label at offset %d to be %q, got %q", tc.offset, tc.hour, entry.HourLabel)
+			}
+		}
+	})
+	t.Run("forecast list can be ranged over in templates", func(t *testing.T) {
+		_, tplCtx := buildCtx(t, 0)
+		tpl := template.Must(template.New("forecasts").Parse(`{{range $i, $e := .Forecasts}}{{if lt $i 3}}` +
+			`{{$e.DayLabel}} {{$e.HourLabel}}: {{$e.Temperature}}; {{end}}{{end}}`))
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, tplCtx); err != nil {
+			t.Fatalf("failed to render template: %s", err)
+		}
+		want := "Today 23:00: 0; Tomorrow 00:00: 1; Tomorrow 01:00: 2; "
+		if buf.String() != want {
+			t.Errorf("expected template output to be %q, got %q", want, buf.String())
+		}
+	})
+}
+
+func TestPresenter_todayMinMax(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	fixedNow := time.Date(2026, 1, 18, 14, 30, 0, 0, time.Local)
+	fakeClock := clock.NewFake(fixedNow)
+	pres.Clock = fakeClock
+	daySunrise := time.Date(2026, 1, 18, 7, 1, 2, 0, time.Local)
+	daySunset := time.Date(2026, 1, 18, 17, 39, 41, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		tempFn      func(time.Time) float64
+		wantMin     float64
+		wantMax     float64
+		wantTonight float64
+	}{
+		{
+			name: "full forecast window",
+			from: time.Date(2026, 1, 17, 0, 0, 0, 0, time.Local),
+			to:   time.Date(2026, 1, 19, 23, 0, 0, 0, time.Local),
+			tempFn: func(at time.Time) float64 {
+				if at.Day() != 18 {
+					return float64(at.Hour()) - 50
+				}
+				return float64(at.Hour())
+			},
+			wantMin:     0,
+			wantMax:     23,
+			wantTonight: -50,
+		},
+		{
+			name: "partial data at the edge of the forecast window",
+			from: time.Date(2026, 1, 18, 10, 0, 0, 0, time.Local),
+			to:   time.Date(2026, 1, 18, 20, 0, 0, 0, time.Local),
+			tempFn: func(at time.Time) float64 {
+				return float64(at.Hour())
+			},
+			wantMin:     10,
+			wantMax:     20,
+			wantTonight: 18,
+		},
+		{
+			name:   "no data for today",
+			from:   time.Date(2026, 1, 20, 0, 0, 0, 0, time.Local),
+			to:     time.Date(2026, 1, 20, 23, 0, 0, 0, time.Local),
+			tempFn: func(at time.Time) float64 { return 5 },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fcasts := make(map[weather.DayHour]weather.Instant)
+			for at := tc.from; !at.After(tc.to); at = at.Add(time.Hour) {
+				fcasts[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: tc.tempFn(at)}
+			}
+			data := &weather.Data{
+				GeneratedAt: fixedNow,
+				Current:     wthr,
+				Forecast:    fcasts,
+			}
+			tplCtx := pres.BuildContext(addr, data, daySunrise, daySunset, moonphase, time.Time{})
+			if tplCtx.TodayMin != tc.wantMin {
+				t.Errorf("expected today min to be %f, got %f", tc.wantMin, tplCtx.TodayMin)
+			}
+			if tplCtx.TodayMax != tc.wantMax {
+				t.Errorf("expected today max to be %f, got %f", tc.wantMax, tplCtx.TodayMax)
+			}
+			if tplCtx.TonightLow != tc.wantTonight {
+				t.Errorf("expected tonight low to be %f, got %f", tc.wantTonight, tplCtx.TonightLow)
+			}
+			if tplCtx.TemperatureUnit != wthr.Units.Temperature {
+				t.Errorf("expected temperature unit to be %q, got %q", wthr.Units.Temperature,
+					tplCtx.TemperatureUnit)
+			}
+		})
+	}
+	t.Run("before sunrise the night started yesterday", func(t *testing.T) {
+		fakeClock.Set(time.Date(2026, 1, 18, 3, 0, 0, 0, time.Local))
+		defer fakeClock.Set(fixedNow)
+		fcasts := make(map[weather.DayHour]weather.Instant)
+		for at := time.Date(2026, 1, 17, 0, 0, 0, 0, time.Local); at.Day() < 20; at = at.Add(time.Hour) {
+			temp := float64(at.Day()*100 + at.Hour())
+			fcasts[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: temp}
+		}
+		data := &weather.Data{Current: wthr, Forecast: fcasts}
+		tplCtx := pres.BuildContext(addr, data, daySunrise, daySunset, moonphase, time.Time{})
+		if tplCtx.TonightLow != 1718 {
+			t.Errorf("expected tonight low to be %d, got %f", 1718, tplCtx.TonightLow)
+		}
+	})
+	t.Run("temperatures are converted into the displayed unit", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Temperature = weather.UnitFahrenheit
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = fakeClock
+		fcasts := make(map[weather.DayHour]weather.Instant)
+		for at := time.Date(2026, 1, 18, 0, 0, 0, 0, time.Local); at.Day() < 20; at = at.Add(time.Hour) {
+			temp := float64(at.Hour())
+			if at.Day() == 19 {
+				temp = -10
+			}
+			fcasts[weather.NewDayHour(at)] = weather.Instant{
+				InstantTime: at, Temperature: temp, Units: weather.Units{Temperature: "°C"},
+			}
+		}
+		data := &weather.Data{Current: wthr, Forecast: fcasts}
+		tplCtx := pres.BuildContext(addr, data, daySunrise, daySunset, moonphase, time.Time{})
+		if tplCtx.TodayMin != 32 || tplCtx.TodayMaxStr != "73.4°F" {
+			t.Errorf("expected today's range to be 32 to %q, got %f to %q", "73.4°F", tplCtx.TodayMin,
+				tplCtx.TodayMaxStr)
+		}
+		if tplCtx.TonightLow != 14 {
+			t.Errorf("expected tonight low to be %d, got %f", 14, tplCtx.TonightLow)
+		}
+	})
+	t.Run("temperature unit is the displayed unit", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Temperature = weather.UnitFahrenheit
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		data := &weather.Data{Current: weather.Instant{Temperature: 10, Units: weather.Units{Temperature: "°C"}}}
+		tplCtx := pres.BuildContext(addr, data, daySunrise, daySunset, moonphase, time.Time{})
+		if tplCtx.TemperatureUnit != "°F" {
+			t.Errorf("expected temperature unit to be %q, got %q", "°F", tplCtx.TemperatureUnit)
+		}
+	})
+}
+
+func TestPresenter_SetObserved(t *testing.T) {
+	at := time.Date(2026, 1, 18, 14, 30, 0, 0, time.Local)
+	low := Observation{Temperature: -2, Unit: "°C", At: at.Add(-8 * time.Hour)}
+	high := Observation{Temperature: 17.24, Unit: "°C", At: at}
+	tests := []struct {
+		name     string
+		override string
+		wantLow  float64
+		wantHigh string
+	}{
+		{"provider unit", "", -2, "17.2°C"},
+		{"converted into the unit override", weather.UnitFahrenheit, 28.4, "63.0°F"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.UnitOverrides.Temperature = tc.override
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			var tplCtx TemplateContext
+			pres.SetObserved(&tplCtx, low, high)
+			if !tplCtx.HasObserved {
+				t.Error("expected observed extremes to be set")
+			}
+			if math.Abs(tplCtx.ObservedLow-tc.wantLow) > 0.001 {
+				t.Errorf("expected observed low to be %g, got %g", tc.wantLow, tplCtx.ObservedLow)
+			}
+			if tplCtx.ObservedHighStr != tc.wantHigh {
+				t.Errorf("expected formatted observed high to be %q, got %q", tc.wantHigh, tplCtx.ObservedHighStr)
+			}
+			if !tplCtx.ObservedLowAt.Equal(low.At) || !tplCtx.ObservedHighAt.Equal(high.At) {
+				t.Errorf("expected observation times %s and %s, got %s and %s", low.At, high.At,
+					tplCtx.ObservedLowAt, tplCtx.ObservedHighAt)
+			}
+		})
+	}
+}
+
+func TestPresenter_precipitationToday(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	fakeClock := clock.NewFake(time.Date(2026, 1, 18, 14, 30, 0, 0, time.Local))
+	pres.Clock = fakeClock
+	units := weather.Units{Temperature: "°C", Precipitation: "mm"}
+	fcasts := make(map[weather.DayHour]weather.Instant)
+	for at := time.Date(2026, 1, 17, 0, 0, 0, 0, time.Local); at.Day() < 20; at = at.Add(time.Hour) {
+		fcasts[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Precipitation: 0.5, Units: units}
+	}
+	current := weather.Instant{InstantTime: fakeClock.Now(), Units: units}
+	round := func(val float64) float64 { return math.Round(val*1000) / 1000 }
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want float64
+	}{
+		{"afternoon", time.Date(2026, 1, 18, 14, 30, 0, 0, time.Local), 7},
+		{"at midnight", time.Date(2026, 1, 18, 0, 0, 0, 0, time.Local), 0},
+		{"just after midnight", time.Date(2026, 1, 18, 0, 30, 0, 0, time.Local), 0},
+		{"first full hour of the day", time.Date(2026, 1, 18, 1, 0, 0, 0, time.Local), 0.5},
+		{"just before midnight", time.Date(2026, 1, 18, 23, 59, 0, 0, time.Local), 11.5},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock.Set(tc.now)
+			data := &weather.Data{Current: current, Forecast: fcasts}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			if round(tplCtx.PrecipitationToday) != tc.want {
+				t.Errorf("expected precipitation today to be %f, got %f", tc.want, tplCtx.PrecipitationToday)
+			}
+			if tplCtx.PrecipitationUnit != "mm" {
+				t.Errorf("expected precipitation unit to be %q, got %q", "mm", tplCtx.PrecipitationUnit)
+			}
+		})
+	}
+	t.Run("no hourly precipitation", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.Templates.Text = `{{loc "todayprecip"}}: {{floatFormat .PrecipitationToday 1}}{{.PrecipitationUnit}}`
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(time.Date(2026, 1, 18, 14, 30, 0, 0, time.Local))
+		data := &weather.Data{Current: wthr, Forecast: map[weather.DayHour]weather.Instant{}}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if tplCtx.PrecipitationToday != 0 {
+			t.Errorf("expected precipitation today to be 0, got %f", tplCtx.PrecipitationToday)
+		}
+		outMap, err := pres.Render(tplCtx)
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		if outMap["text"] != "Precipitation today: 0.0" {
+			t.Errorf("expected text to be %q, got %q", "Precipitation today: 0.0", outMap["text"])
+		}
+	})
+	t.Run("unit override is converted locally", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Precipitation = weather.UnitInch
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(time.Date(2026, 1, 18, 10, 0, 0, 0, time.Local))
+		data := &weather.Data{Current: current, Forecast: fcasts}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if want := round(5 / 25.4); round(tplCtx.PrecipitationToday) != want {
+			t.Errorf("expected precipitation today to be %f, got %f", want, tplCtx.PrecipitationToday)
+		}
+		if tplCtx.PrecipitationUnit != "inch" {
+			t.Errorf("expected precipitation unit to be %q, got %q", "inch", tplCtx.PrecipitationUnit)
+		}
+	})
+}
+
+func TestPresenter_frostRisk(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	fixedNow := time.Date(2026, 1, 18, 20, 30, 0, 0, time.Local)
+	pres.Clock = clock.NewFake(fixedNow)
+	daySunrise := time.Date(2026, 1, 18, 7, 1, 2, 0, time.Local)
+	daySunset := time.Date(2026, 1, 18, 17, 39, 41, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		threshold float64
+		sunrise   time.Time
+		coldHour  time.Time
+		coldTemp  float64
+		want      bool
+	}{
+		{
+			name:     "frost before the next sunrise",
+			sunrise:  daySunrise,
+			coldHour: time.Date(2026, 1, 19, 5, 0, 0, 0, time.Local),
+			coldTemp: -1,
+			want:     true,
+		},
+		{
+			name:     "temperature exactly at the threshold",
+			sunrise:  daySunrise,
+			coldHour: time.Date(2026, 1, 19, 5, 0, 0, 0, time.Local),
+			coldTemp: 0,
+			want:     true,
+		},
+		{
+			name:     "frost in the current hour",
+			sunrise:  daySunrise,
+			coldHour: time.Date(2026, 1, 18, 20, 0, 0, 0, time.Local),
+			coldTemp: -1,
+			want:     true,
+		},
+		{
+			name:     "frost after the next sunrise",
+			sunrise:  daySunrise,
+			coldHour: time.Date(2026, 1, 19, 8, 0, 0, 0, time.Local),
+			coldTemp: -1,
+			want:     false,
+		},
+		{
+			name:     "frost in the past",
+			sunrise:  daySunrise,
+			coldHour: time.Date(2026, 1, 18, 5, 0, 0, 0, time.Local),
+			coldTemp: -1,
+			want:     false,
+		},
+		{
+			name:      "custom threshold",
+			threshold: 3,
+			sunrise:   daySunrise,
+			coldHour:  time.Date(2026, 1, 19, 5, 0, 0, 0, time.Local),
+			coldTemp:  2.5,
+			want:      true,
+		},
+		{
+			name:     "unknown sunrise",
+			coldHour: time.Date(2026, 1, 19, 5, 0, 0, 0, time.Local),
+			coldTemp: -1,
+			want:     false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			temps := pres.temperaturesIn(units.Celsius)
+			temps.frost = tc.threshold
+			pres.temperatures[units.Celsius] = temps
+			fcasts := make(map[weather.DayHour]weather.Instant)
+			for at := time.Date(2026, 1, 18, 0, 0, 0, 0, time.Local); at.Day() < 20; at = at.Add(time.Hour) {
+				fcasts[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: 5}
+			}
+			fcasts[weather.NewDayHour(tc.coldHour)] = weather.Instant{InstantTime: tc.coldHour, Temperature: tc.coldTemp}
+			data := &weather.Data{Current: wthr, Forecast: fcasts}
+			tplCtx := pres.BuildContext(addr, data, tc.sunrise, daySunset, moonphase, time.Time{})
+			if tplCtx.FrostRisk != tc.want {
+				t.Errorf("expected frost risk to be %t, got %t", tc.want, tplCtx.FrostRisk)
+			}
+		})
+	}
+	t.Run("default threshold is converted into °F", func(t *testing.T) {
+		pres, err = New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(fixedNow)
+		current := wthr
+		current.Units.Temperature = "°F"
+		coldHour := time.Date(2026, 1, 19, 5, 0, 0, 0, time.Local)
+		for coldTemp, want := range map[float64]bool{31: true, 32: true, 33: false} {
+			fcasts := map[weather.DayHour]weather.Instant{
+				weather.NewDayHour(coldHour): {InstantTime: coldHour, Temperature: coldTemp},
+			}
+			data := &weather.Data{Current: current, Forecast: fcasts}
+			tplCtx := pres.BuildContext(addr, data, daySunrise, daySunset, moonphase, time.Time{})
+			if tplCtx.FrostRisk != want {
+				t.Errorf("expected frost risk at %g°F to be %t, got %t", coldTemp, want, tplCtx.FrostRisk)
+			}
+		}
+	})
+}
+
+func TestPresenter_gustWarning(t *testing.T) {
+	fixedNow := time.Date(2026, 1, 18, 20, 30, 0, 0, time.Local)
+	kmh := weather.Units{WindSpeed: "km/h"}
+	tests := []struct {
+		name      string
+		threshold float64
+		unit      string
+		gustHour  time.Time
+		gust      float64
+		wantPeak  float64
+		want      bool
+	}{
+		{
+			name:      "gust within the window",
+			threshold: 60,
+			gustHour:  time.Date(2026, 1, 18, 23, 0, 0, 0, time.Local),
+			gust:      65,
+			wantPeak:  65,
+			want:      true,
+		},
+		{
+			name:      "gust at the threshold",
+			threshold: 60,
+			gustHour:  time.Date(2026, 1, 19, 2, 0, 0, 0, time.Local),
+			gust:      60,
+			wantPeak:  60,
+			want:      true,
+		},
+		{
+			name:      "gust below the threshold",
+			threshold: 60,
+			gustHour:  time.Date(2026, 1, 18, 23, 0, 0, 0, time.Local),
+			gust:      55,
+			wantPeak:  55,
+			want:      false,
+		},
+		{
+			name:      "gust after the window",
+			threshold: 60,
+			gustHour:  time.Date(2026, 1, 19, 4, 0, 0, 0, time.Local),
+			gust:      65,
+			wantPeak:  30,
+			want:      false,
+		},
+		{
+			name:      "gust in the past",
+			threshold: 60,
+			gustHour:  time.Date(2026, 1, 18, 18, 0, 0, 0, time.Local),
+			gust:      65,
+			wantPeak:  30,
+			want:      false,
+		},
+		{
+			name:     "disabled warning",
+			gustHour: time.Date(2026, 1, 18, 23, 0, 0, 0, time.Local),
+			gust:     65,
+			wantPeak: 65,
+			want:     false,
+		},
+		{
+			name:      "threshold in the overridden wind speed unit",
+			threshold: 40,
+			unit:      weather.UnitMph,
+			gustHour:  time.Date(2026, 1, 18, 23, 0, 0, 0, time.Local),
+			gust:      65,
+			wantPeak:  65 / 1.609344,
+			want:      true,
+		},
+		{
+			name:      "gust below the threshold in the overridden wind speed unit",
+			threshold: 60,
+			unit:      weather.UnitMph,
+			gustHour:  time.Date(2026, 1, 18, 23, 0, 0, 0, time.Local),
+			gust:      65,
+			wantPeak:  65 / 1.609344,
+			want:      false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_UNIT_OVERRIDES_WIND_SPEED", tc.unit)
+			conf, lang := testConfLang(t)
+			conf.Weather.GustWarningThreshold = tc.threshold
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.Clock = clock.NewFake(fixedNow)
+
+			fcasts := make(map[weather.DayHour]weather.Instant)
+			for at := time.Date(2026, 1, 18, 0, 0, 0, 0, time.Local); at.Day() < 20; at = at.Add(time.Hour) {
+				fcasts[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, WindGusts: 20, Units: kmh}
+			}
+			fcasts[weather.NewDayHour(tc.gustHour)] = weather.Instant{
+				InstantTime: tc.gustHour, WindGusts: tc.gust, Units: kmh,
+			}
+			current := weather.Instant{InstantTime: fixedNow, WindGusts: 30, Units: kmh}
+			data := &weather.Data{Current: current, Forecast: fcasts}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			if math.Abs(tplCtx.PeakGust-tc.wantPeak) > 0.001 {
+				t.Errorf("expected peak gust to be %f, got %f", tc.wantPeak, tplCtx.PeakGust)
+			}
+			if tplCtx.GustWarning != tc.want {
+				t.Errorf("expected gust warning to be %t, got %t", tc.want, tplCtx.GustWarning)
+			}
+		})
+	}
+}
+
+func TestPresenter_nextPrecipitation(t *testing.T) {
+	fixedNow := time.Date(2026, 1, 18, 13, 20, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return time.Date(2026, 1, 18, h, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name      string
+		locale    string
+		current   int
+		codes     map[int]int
+		wantStart time.Time
+		wantEnd   time.Time
+		wantStr   [2]string
+	}{
+		{
+			"no precipitation in the forecast", "en", 3, map[int]int{14: 3, 15: 2, 16: 0},
+			time.Time{}, time.Time{}, [2]string{"", ""},
+		},
+		{
+			"precipitation starts in the first forecast hour", "en", 3, map[int]int{14: 61, 15: 61, 16: 3},
+			hour(14), hour(16), [2]string{"Slight rain starting in 40 min", "Slight rain ending around 4 p.m."},
+		},
+		{
+			"precipitation starts later", "en", 3, map[int]int{14: 3, 15: 71, 16: 73, 17: 2},
+			hour(15), hour(17),
+			[2]string{"Slight snow fall starting around 3 p.m.", "Moderate snow fall ending around 5 p.m."},
+		},
+		{
+			"currently raining", "en", 63, map[int]int{14: 3, 15: 61},
+			time.Time{}, hour(14), [2]string{"", "Moderate rain ending in 40 min"},
+		},
+		{
+			"currently raining without end in the forecast", "en", 63, map[int]int{14: 61, 15: 95},
+			time.Time{}, time.Time{}, [2]string{"", ""},
+		},
+		{
+			"precipitation starts without end in the forecast", "en", 0, map[int]int{14: 1, 15: 95},
+			hour(15), time.Time{}, [2]string{"Thunderstorm starting around 3 p.m.", ""},
+		},
+		{
+			"localized strings", "de", 3, map[int]int{14: 61, 15: 3},
+			hour(14), hour(15), [2]string{"Leichter Regen beginnt in 40 Min.", "Leichter Regen endet gegen 15:00"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_LOCALE", tc.locale)
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.Clock = clock.NewFake(fixedNow)
+
+			fcasts := make(map[weather.DayHour]weather.Instant)
+			for h, code := range tc.codes {
+				fcasts[weather.NewDayHour(hour(h))] = weather.Instant{InstantTime: hour(h), WeatherCode: code}
+			}
+			current := weather.Instant{InstantTime: fixedNow, WeatherCode: tc.current}
+			fcasts[weather.NewDayHour(hour(13))] = current
+			data := &weather.Data{Current: current, Forecast: fcasts}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			if !tplCtx.NextPrecipStart.Equal(tc.wantStart) {
+				t.Errorf("expected precipitation start to be %s, got %s", tc.wantStart, tplCtx.NextPrecipStart)
+			}
+			if !tplCtx.NextPrecipEnd.Equal(tc.wantEnd) {
+				t.Errorf("expected precipitation end to be %s, got %s", tc.wantEnd, tplCtx.NextPrecipEnd)
+			}
+			if tplCtx.NextPrecipStartStr != tc.wantStr[0] {
+				t.Errorf("expected precipitation start string to be %q, got %q", tc.wantStr[0],
+					tplCtx.NextPrecipStartStr)
+			}
+			if tplCtx.NextPrecipEndStr != tc.wantStr[1] {
+				t.Errorf("expected precipitation end string to be %q, got %q", tc.wantStr[1], tplCtx.NextPrecipEndStr)
+			}
+		})
+	}
+}
+
+func TestPresenter_nowcast(t *testing.T) {
+	fixedNow := time.Date(2026, 1, 18, 13, 20, 0, 0, time.UTC)
+	// steps returns nowcast steps ending at 13:15, 13:30, 13:45 and so on with the given precipitation
+	steps := func(precip ...float64) []weather.NowcastStep {
+		nowcast := make([]weather.NowcastStep, 0, len(precip))
+		for i, val := range precip {
+			end := time.Date(2026, 1, 18, 13, 15, 0, 0, time.UTC).Add(weather.NowcastInterval * time.Duration(i))
+			nowcast = append(nowcast, weather.NowcastStep{Time: end, Precipitation: val, Unit: "mm"})
+		}
+		return nowcast
+	}
+	tests := []struct {
+		name    string
+		nowcast []weather.NowcastStep
+		want    int
+	}{
+		{"no nowcast", nil, -1},
+		{"no precipitation within the nowcast", steps(0, 0, 0, 0, 0), -1},
+		{"precipitation only in a past step", steps(0.4, 0, 0, 0), -1},
+		{"already raining", steps(0, 0.3, 0.5, 0), 0},
+		{"rain in the next step", steps(0, 0, 0.2, 0), 10},
+		{"rain later", steps(0, 0, 0, 0, 1.1), 40},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			pres.Clock = clock.NewFake(fixedNow)
+			current := weather.Instant{InstantTime: fixedNow}
+			data := &weather.Data{Current: current, Nowcast: tc.nowcast}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			if got := pres.nextRainMinutes(tplCtx); got != tc.want {
+				t.Errorf("expected next rain in %d minutes, got %d", tc.want, got)
+			}
+		})
+	}
+	t.Run("nowcastAt returns the step covering the offset", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Precipitation = weather.UnitInch
+		conf.Templates.Text = `{{ with nowcastAt . 30 }}{{ .PrecipitationStr }}{{ end }} in {{ nextRainMinutes . }}`
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(fixedNow)
+		data := &weather.Data{Current: weather.Instant{InstantTime: fixedNow}, Nowcast: steps(0, 0, 0, 2.54, 0)}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if len(tplCtx.Nowcast) != 5 {
+			t.Fatalf("expected 5 nowcast steps, got %d", len(tplCtx.Nowcast))
+		}
+		step := pres.nowcastAt(tplCtx, 30)
+		wantStart := time.Date(2026, 1, 18, 13, 45, 0, 0, time.UTC)
+		if !step.Start.Equal(wantStart) || !step.End.Equal(wantStart.Add(weather.NowcastInterval)) {
+			t.Errorf("expected step from %s to %s, got %s to %s", wantStart, wantStart.Add(weather.NowcastInterval),
+				step.Start, step.End)
+		}
+		if math.Abs(step.Precipitation-0.1) > 1e-9 {
+			t.Errorf("expected precipitation to be converted to %f inch, got %f", 0.1, step.Precipitation)
+		}
+		if empty := pres.nowcastAt(tplCtx, 120); !empty.End.IsZero() {
+			t.Errorf("expected no step beyond the nowcast, got %s to %s", empty.Start, empty.End)
+		}
+		outMap, err := pres.Render(tplCtx)
+		if err != nil {
+			t.Fatalf("failed to render: %s", err)
+		}
+		if want := "0.1 inch in 25"; outMap["text"] != want {
+			t.Errorf("expected text to be %q, got %q", want, outMap["text"])
+		}
+	})
+}
+
+func TestPresenter_forecastFuncs(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	pres.Clock = clock.NewFake(now)
+	fcasts := make(map[weather.DayHour]weather.Instant)
+	for i := -23; i < 25; i++ {
+		fcast := wthr
+		fcast.InstantTime = now.Add(time.Hour * time.Duration(i)).Truncate(time.Hour)
+		fcast.Temperature = float64(i)
+		fcasts[weather.NewDayHour(fcast.InstantTime)] = fcast
+	}
+	data := &weather.Data{
+		GeneratedAt: now,
+		Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+		Current:     wthr,
+		Forecast:    fcasts,
+	}
+	tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+	emptyCtx := pres.BuildContext(addr, &weather.Data{GeneratedAt: now, Current: wthr}, sunrise, sunset, moonphase,
+		time.Time{})
+
+	tests := []struct {
+		name string
+		tpl  string
+		ctx  TemplateContext
+		want string
+	}{
+		{"max temperature of the next 12 hours", `{{maxTemp (hours . 12)}}`, tplCtx, "12"},
+		{"min temperature of the next 12 hours", `{{minTemp (hours . 12)}}`, tplCtx, "1"},
+		{"avg temperature of the next 12 hours", `{{avgTemp (hours . 12)}}`, tplCtx, "6.5"},
+		{"rounded avg temperature", `{{round (avgTemp (hours . 12)) 0}}`, tplCtx, "7"},
+		{"temperature span", `{{sub (maxTemp (hours . 12)) (minTemp (hours . 12))}}`, tplCtx, "11"},
+		{"hours are limited by the forecast", `{{len (hours . 48)}}`, tplCtx, "24"},
+		{"hours are sorted", `{{range hours . 3}}{{.Temperature}} {{end}}`, tplCtx, "1 2 3 "},
+		{"zero hours", `{{len (hours . 0)}}`, tplCtx, "0"},
+		{"negative hours", `{{len (hours . -3)}}`, tplCtx, "0"},
+		{"empty forecast max temperature", `{{maxTemp (hours . 12)}}`, emptyCtx, "0"},
+		{"empty forecast min temperature", `{{minTemp (hours . 12)}}`, emptyCtx, "0"},
+		{"empty forecast avg temperature", `{{avgTemp (hours . 12)}}`, emptyCtx, "0"},
+		{"add integers", `{{add 1 2}}`, tplCtx, "3"},
+		{"add integer and float", `{{add (len .Forecasts) .Current.Temperature}}`, tplCtx, "45"},
+		{"sub floats", `{{sub .Current.Temperature 2.5}}`, tplCtx, "17.5"},
+		{"round to precision", `{{round 3.14159 2}}`, tplCtx, "3.14"},
+		{"round integer", `{{round 3 2}}`, tplCtx, "3"},
+		{"gtf float and integer", `{{gtf .Current.Temperature 15}}`, tplCtx, "true"},
+		{"gtf equal numbers", `{{gtf .Current.Temperature 20}}`, tplCtx, "false"},
+		{"ltf float and integer", `{{ltf .Current.Temperature 15}}`, tplCtx, "false"},
+		{"ltf integer and float", `{{ltf 15 .Current.Temperature}}`, tplCtx, "true"},
+		{"gtf in condition", `{{if gtf .Current.ApparentTemperature 22.5}}warm{{end}}`, tplCtx, "warm"},
+		{"floatFormat of forecast helper", `{{floatFormat (avgTemp (hours . 3)) 1}}`, tplCtx, "2.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := template.New("test").Funcs(pres.templateFuncMap()).Parse(tc.tpl)
+			if err != nil {
+				t.Fatalf("failed to parse template: %s", err)
+			}
+			buf := strings.Builder{}
+			if err = tpl.Execute(&buf, tc.ctx); err != nil {
+				t.Fatalf("failed to execute template: %s", err)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("expected template output to be %q, got %q", tc.want, buf.String())
+			}
+		})
+	}
+	t.Run("non-numeric arguments fail", func(t *testing.T) {
+		for _, text := range []string{
+			`{{add "1" 2}}`, `{{sub 1 .Address}}`, `{{round "3.14" 1}}`, `{{gtf "1" 2}}`, `{{ltf 1 .Address}}`,
+		} {
+			tpl, err := template.New("test").Funcs(pres.templateFuncMap()).Parse(text)
+			if err != nil {
+				t.Fatalf("failed to parse template: %s", err)
+			}
+			if err = tpl.Execute(&strings.Builder{}, tplCtx); err == nil {
+				t.Errorf("expected template %q to fail", text)
+			}
+		}
+	})
+}
+
+func TestPresenter_sparkline(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	base := time.Date(2026, 1, 18, 12, 34, 0, 0, time.UTC)
+	sparkCtx := func(temps map[int]float64) TemplateContext {
+		tplCtx := TemplateContext{Current: WeatherView{Instant: weather.Instant{InstantTime: base}}}
+		for offset, temp := range temps {
+			instant := weather.Instant{
+				InstantTime:      base.Truncate(time.Hour).Add(time.Hour * time.Duration(offset)),
+				Temperature:      temp,
+				RelativeHumidity: 50,
+			}
+			tplCtx.hours = append(tplCtx.hours, WeatherView{Instant: instant})
+		}
+		return tplCtx
+	}
+	rising := sparkCtx(map[int]float64{1: 0, 2: 1, 3: 2, 4: 3, 5: 4, 6: 5, 7: 6, 8: 7})
+
+	tests := []struct {
+		name   string
+		ctx    TemplateContext
+		metric string
+		hours  int
+		glyphs []string
+		want   string
+	}{
+		{"rising temperature", rising, "temperature", 8, nil, "▁▂▃▄▅▆▇█"},
+		{"fewer hours than available", rising, "temperature", 4, nil, "▁▃▆█"},
+		{
+			"mixed temperature",
+			sparkCtx(map[int]float64{1: 10, 2: 12, 3: 14, 4: 20, 5: 18, 6: 12, 7: 10, 8: 11}),
+			"temperature", 8, nil, "▁▂▄█▇▂▁▂",
+		},
+		{"metric names are case-insensitive", rising, "Temperature", 8, nil, "▁▂▃▄▅▆▇█"},
+		{"constant values", rising, "humidity", 8, nil, "▄▄▄▄▄▄▄▄"},
+		{
+			"missing hours",
+			sparkCtx(map[int]float64{1: 0, 2: 1, 4: 3, 5: 4, 6: 5, 8: 7}),
+			"temperature", 8, nil, "▁▂ ▄▅▆ █",
+		},
+		{"hours beyond the forecast", sparkCtx(map[int]float64{1: 0, 2: 7}), "temperature", 4, nil, "▁█  "},
+		{
+			"past hours are ignored",
+			sparkCtx(map[int]float64{-1: 100, 0: 50, 1: 0, 2: 7}),
+			"temperature", 2, nil, "▁█",
+		},
+		{"custom glyphs", rising, "temperature", 8, []string{"_-^"}, "__----^^"},
+		{"empty custom glyphs use the default", rising, "temperature", 8, []string{""}, "▁▂▃▄▅▆▇█"},
+		{"single custom glyph", rising, "temperature", 3, []string{"*"}, "***"},
+		{"no forecast data", sparkCtx(nil), "temperature", 8, nil, ""},
+		{"zero hours", rising, "temperature", 0, nil, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pres.sparkline(tc.ctx, tc.metric, tc.hours, tc.glyphs...)
+			if err != nil {
+				t.Fatalf("failed to render sparkline: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected sparkline to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+	t.Run("unknown metric fails", func(t *testing.T) {
+		if _, err := pres.sparkline(rising, "precipitation", 8); err == nil {
+			t.Error("expected unknown metric to fail")
+		}
+	})
+	t.Run("sparkline in a template", func(t *testing.T) {
+		text := `{{sparkline . "temperature" 8}} {{sparkline . "temperature" 8 "_-^"}}`
+		tpl, err := template.New("test").Funcs(pres.templateFuncMap()).Parse(text)
+		if err != nil {
+			t.Fatalf("failed to parse template: %s", err)
+		}
+		buf := strings.Builder{}
+		if err = tpl.Execute(&buf, rising); err != nil {
+			t.Fatalf("failed to execute template: %s", err)
+		}
+		if want := "▁▂▃▄▅▆▇█ __----^^"; buf.String() != want {
+			t.Errorf("expected template output to be %q, got %q", want, buf.String())
+		}
+	})
+}
+
+func TestPresenter_emojiStrip(t *testing.T) {
+	// The sun sets between 16:00 and 17:00, the forecast of 20:00 is missing
+	start := time.Date(2026, 1, 18, 8, 0, 0, 0, time.UTC)
+	data := &weather.Data{
+		GeneratedAt: start,
+		Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+		Forecast:    make(map[weather.DayHour]weather.Instant),
+	}
+	for hour := range 14 {
+		if hour == 12 {
+			continue
+		}
+		instant := weather.Instant{
+			InstantTime: start.Add(time.Hour * time.Duration(hour)),
+			Temperature: float64(hour) + 0.4,
+			WeatherCode: 0,
+			IsDay:       hour <= 8,
+			Units:       weather.Units{Temperature: "°C"},
+		}
+		switch {
+		case hour >= 1 && hour <= 4:
+			instant.WeatherCode = 80
+		case hour >= 5 && hour <= 6:
+			instant.WeatherCode = 2
+		}
+		data.Forecast[weather.NewDayHour(instant.InstantTime)] = instant
+	}
+	data.Current = data.Forecast[weather.NewDayHour(start)]
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		icons    map[string]string
+	}{
+		{"icons cross the sunset", "{{emojiStrip . 6 2}}", "🌦️🌦️⛅☀️🌙·", nil},
+		{"icons every 3 hours", "{{emojiStrip . 5 3}}", "🌦️⛅🌙··", nil},
+		{"icons with temperatures", "{{emojiTempStrip . 6 2}}", "🌦️2° 🌦️4° ⛅6° ☀️8° 🌙10° ·", nil},
+		{"no steps", "{{emojiStrip . 0 3}}{{emojiTempStrip . 0 3}}", "", nil},
+		{
+			"overridden icons", "{{emojiStrip . 6 2}}", "☂️☂️⛅🌞🌛·",
+			map[string]string{"0": "🌞", "0_night": "🌛", "80": "☂️"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Templates.Text = tc.template
+			conf.Templates.ConditionIcons = tc.icons
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			got, err := pres.Render(tplCtx)
+			if err != nil {
+				t.Fatalf("failed to render templates: %s", err)
+			}
+			if got["text"] != tc.want {
+				t.Errorf("expected emoji strip to be %q, got %q", tc.want, got["text"])
+			}
+		})
+	}
+	t.Run("step below 1 fails", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if _, err = pres.emojiStrip(tplCtx, 5, 0); err == nil {
+			t.Error("expected emoji strip to fail")
+		}
+		if _, err = pres.emojiTempStrip(tplCtx, 5, -1); err == nil {
+			t.Error("expected emoji strip with temperatures to fail")
+		}
+	})
+}
+
+func TestPresenter_forecastTable(t *testing.T) {
+	start := time.Date(2026, 1, 18, 9, 0, 0, 0, time.UTC)
+	units := weather.Units{Temperature: "°C", Precipitation: "mm"}
+	hourly := []struct {
+		temp   float64
+		code   int
+		precip float64
+		isDay  bool
+	}{
+		{-2.5, 0, 0, true}, {-1, 0, 0, true}, {0, 1, 0, true},
+		{1, 3, 0, true}, {2, 3, 0, true}, {3, 61, 0.4, true},
+		{12.3, 61, 1.2, true}, {11, 61, 2, true}, {9, 45, 0, false},
+		{8, 45, 0, false}, {7, 95, 4, false}, {6, 95, 8, false},
+		{10.5, 95, 12.5, false},
+	}
+	data := &weather.Data{
+		GeneratedAt: start,
+		Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+		Forecast:    make(map[weather.DayHour]weather.Instant),
+	}
+	for i, h := range hourly {
+		instant := weather.Instant{
+			InstantTime:   start.Add(time.Hour * time.Duration(i)),
+			Temperature:   h.temp,
+			WeatherCode:   h.code,
+			Precipitation: h.precip,
+			IsDay:         h.isDay,
+			Units:         units,
+		}
+		data.Forecast[weather.NewDayHour(instant.InstantTime)] = instant
+	}
+	data.Current = data.Forecast[weather.NewDayHour(start)]
+
+	tests := []struct {
+		name     string
+		locale   string
+		template string
+		want     string
+	}{
+		{
+			"every 3 hours in en", "en", "{{forecastTable . 0 5 3}}",
+			"9 a.m.  ☀️  -2.5°C   0.0 mm\n" +
+				"noon    ☁️   1.0°C   0.0 mm\n" +
+				"3 p.m.  🌦️  12.3°C   1.2 mm\n" +
+				"6 p.m.  🌫️   8.0°C   0.0 mm\n" +
+				"9 p.m.  🌩️  10.5°C  12.5 mm",
+		},
+		{
+			"every 3 hours in de", "de", "{{forecastTable . 0 5 3}}",
+			"09:00  ☀️  -2,5°C   0,0 mm\n" +
+				"12:00  ☁️   1,0°C   0,0 mm\n" +
+				"15:00  🌦️  12,3°C   1,2 mm\n" +
+				"18:00  🌫️   8,0°C   0,0 mm\n" +
+				"21:00  🌩️  10,5°C  12,5 mm",
+		},
+		{
+			"columns adapt to the longest value", "en", "{{forecastTable . 1 3 2}}",
+			"10 a.m.  ☀️  -1.0°C  0.0 mm\n" +
+				"noon     ☁️   1.0°C  0.0 mm\n" +
+				"2 p.m.   🌦️   3.0°C  0.4 mm",
+		},
+		{
+			"hours without forecast data are omitted", "de", "{{forecastTable . 10 4 2}}",
+			"19:00  🌩️   7,0°C   4,0 mm\n" +
+				"21:00  🌩️  10,5°C  12,5 mm",
+		},
+		{"no rows", "en", "{{forecastTable . 0 0 3}}", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_LOCALE", tc.locale)
+			conf, lang := testConfLang(t)
+			conf.Templates.Tooltip = tc.template
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+			got, err := pres.Render(tplCtx)
+			if err != nil {
+				t.Fatalf("failed to render templates: %s", err)
+			}
+			if got["tooltip"] != tc.want {
+				t.Errorf("expected forecast table to be:\n%s\ngot:\n%s", tc.want, got["tooltip"])
+			}
+			lines := strings.Split(got["tooltip"], "\n")
+			for _, line := range lines[1:] {
+				if runewidth.StringWidth(line) != runewidth.StringWidth(lines[0]) {
+					t.Errorf("expected all rows to have the same display width, got %q and %q", lines[0], line)
+				}
+			}
+		})
+	}
+	t.Run("step must be positive", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		if _, err = pres.forecastTable(tplCtx, 0, 8, 0); err == nil {
+			t.Error("expected forecast table with a step of 0 to fail")
+		}
+	})
+}
+
+func TestPresenter_padIcon(t *testing.T) {
+	tests := []struct {
+		name  string
+		width string
+		icon  string
+		want  string
+	}{
+		{"wide emoji", "2", "🌙", "🌙"},
+		{"emoji with variation selector", "2", "🌫️", "🌫️"},
+		{"narrow symbol", "2", "☀", "☀ "},
+		{"empty icon", "2", "", "  "},
+		{"wider icon width", "3", "🌙", "🌙 "},
+		{"icon wider than the icon width", "1", "🌫️", "🌫️"},
+		{"padding disabled", "0", "☀", "☀"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_OUTPUT_ICON_WIDTH", tc.width)
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			if got := pres.padIcon(tc.icon); got != tc.want {
+				t.Errorf("expected padded icon to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+	t.Run("narrow and wide icons render with the same width", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		narrow, wide := pres.padIcon("🌙"), pres.padIcon("🌫️")
+		if displayWidth(narrow) != 2 || displayWidth(wide) != 2 {
+			t.Errorf("expected padded icons to be 2 cells wide, got %d for %q and %d for %q",
+				displayWidth(narrow), narrow, displayWidth(wide), wide)
+		}
+		if runewidth.StringWidth(pres.padIcon("☀")) != runewidth.StringWidth(narrow) {
+			t.Errorf("expected padded symbol to be as wide as %q, got %q", narrow, pres.padIcon("☀"))
+		}
+	})
+	t.Run("default templates pad the condition icon", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		tplData := &weather.Data{
+			GeneratedAt: time.Now(),
+			Current: weather.Instant{
+				Temperature: 20, WeatherCode: 0, IsDay: false,
+				Units: weather.Units{Temperature: "°C"},
+			},
+		}
+		got, err := pres.Render(pres.BuildContext(addr, tplData, sunrise, sunset, moonphase, time.Time{}))
+		if err != nil {
+			t.Fatalf("failed to render templates: %s", err)
+		}
+		icon, _, found := strings.Cut(got["text"], " 20.0°C")
+		if !found {
+			t.Fatalf("expected text output to contain the temperature, got %q", got["text"])
+		}
+		if displayWidth(icon) != 2 {
+			t.Errorf("expected icon in text output to be 2 cells wide, got %d for %q", displayWidth(icon), icon)
+		}
+	})
+}
+
+func TestPresenter_displayWidth(t *testing.T) {
+	tests := []struct {
+		val  string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"🌙", 2},
+		{"☀", 1},
+		{"☀️", 2},
+		{"🌫️", 2},
+		{"🌫️ 8.0°C", 8},
+	}
+	for _, tc := range tests {
+		t.Run(tc.val, func(t *testing.T) {
+			if got := displayWidth(tc.val); got != tc.want {
+				t.Errorf("expected display width of %q to be %d, got %d", tc.val, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIconMaps(t *testing.T) {
+	// iconRanges are the unicode ranges the icons are expected in: arrows, miscellaneous symbols, the emoji
+	// variation selector and miscellaneous symbols and pictographs
+	iconRanges := [][2]rune{{0x2190, 0x21FF}, {0x2600, 0x26FF}, {0xFE0F, 0xFE0F}, {0x1F300, 0x1F5FF}}
+	checkIcon := func(t *testing.T, icon string) {
+		t.Helper()
+		if icon == "" {
+			t.Fatal("expected icon to be non-empty")
+		}
+		if !utf8.ValidString(icon) {
+			t.Fatalf("expected icon %q to be valid UTF-8", icon)
+		}
+		for _, r := range icon {
+			inRange := false
+			for _, rng := range iconRanges {
+				if r >= rng[0] && r <= rng[1] {
+					inRange = true
+					break
+				}
+			}
+			if !inRange {
+				t.Errorf("expected icon %q to consist of emoji only, got rune %U", icon, r)
+			}
+		}
+		if width := runewidth.StringWidth(icon); width > 2 {
+			t.Errorf("expected icon %q to be at most 2 cells wide, got %d", icon, width)
+		}
+	}
+
+	t.Run("moon phase icons", func(t *testing.T) {
+		for phase, icon := range MoonPhaseIcon {
+			t.Run(phase, func(t *testing.T) { checkIcon(t, icon) })
+		}
+	})
+	t.Run("weather icons", func(t *testing.T) {
+		for code, icons := range WMOWeatherIcons {
+			for isDay, icon := range icons {
+				t.Run(fmt.Sprintf("%d/%t", code, isDay), func(t *testing.T) { checkIcon(t, icon) })
+			}
+		}
+	})
+	t.Run("wind direction icons", func(t *testing.T) {
+		for dir, icon := range windDirIcons {
+			t.Run(dir, func(t *testing.T) { checkIcon(t, icon) })
+		}
+	})
+	t.Run("every weather code has day and night icons", func(t *testing.T) {
+		for code := range WMOWeatherCodes {
+			icons, ok := WMOWeatherIcons[code]
+			if !ok {
+				t.Errorf("expected weather code %d to have icons", code)
+				continue
+			}
+			for _, isDay := range []bool{true, false} {
+				if _, ok = icons[isDay]; !ok {
+					t.Errorf("expected weather code %d to have an icon for day=%t", code, isDay)
+				}
+			}
+		}
+	})
+}
+
+func TestCategorySeverity(t *testing.T) {
+	t.Run("categories are ordered by severity", func(t *testing.T) {
+		order := []string{"clear", "cloudy", "fog", "rain", "snow", "thunderstorm"}
+		for i := 1; i < len(order); i++ {
+			if CategorySeverity(order[i]) <= CategorySeverity(order[i-1]) {
+				t.Errorf("expected %s to be more severe than %s", order[i], order[i-1])
+			}
+		}
+	})
+	t.Run("every weather category has a severity", func(t *testing.T) {
+		for code := range 100 {
+			category := weatherCategory(code)
+			if category != "" && CategorySeverity(category) < 0 {
+				t.Errorf("expected category %q of weather code %d to have a severity", category, code)
+			}
+		}
+	})
+	t.Run("unknown category is the mildest", func(t *testing.T) {
+		for _, category := range []string{"", "hurricane"} {
+			if severity := CategorySeverity(category); severity >= CategorySeverity("clear") {
+				t.Errorf("expected category %q to be milder than clear, got severity %d", category, severity)
+			}
+		}
+	})
+}
+
+func TestPresenter_severity(t *testing.T) {
+	metric := weather.Units{Temperature: "°C", WindSpeed: "km/h", Precipitation: "mm"}
+	weight := func(val float64) *float64 { return &val }
+	tests := []struct {
+		name     string
+		weights  [4]*float64
+		instant  weather.Instant
+		want     int
+		wantBand string
+	}{
+		{
+			name:     "clear and mild",
+			instant:  weather.Instant{WeatherCode: 0, Temperature: 15, Units: metric},
+			want:     0,
+			wantBand: SeverityCalm,
+		},
+		{
+			name: "light rain and a breeze",
+			instant: weather.Instant{
+				WeatherCode: 61, Temperature: 10, WindSpeed: 15, Precipitation: 2, Units: metric,
+			},
+			want:     33,
+			wantBand: SeverityMild,
+		},
+		{
+			name: "snow, wind and frost",
+			instant: weather.Instant{
+				WeatherCode: 71, Temperature: -7.5, WindSpeed: 30, Precipitation: 1, Units: metric,
+			},
+			want:     52,
+			wantBand: SeverityRough,
+		},
+		{
+			name: "thunderstorm with damaging gusts",
+			instant: weather.Instant{
+				WeatherCode: 95, Temperature: 20, WindSpeed: 37.5, WindGusts: 100, Precipitation: 5, Units: metric,
+			},
+			want:     75,
+			wantBand: SeveritySevere,
+		},
+		{
+			name: "components are bounded",
+			instant: weather.Instant{
+				WeatherCode: 99, Temperature: 45, WindSpeed: 150, Precipitation: 40, Units: metric,
+			},
+			want:     100,
+			wantBand: SeveritySevere,
+		},
+		{
+			name: "imperial units are converted",
+			instant: weather.Instant{
+				WeatherCode: 0, Temperature: 100.4, Units: weather.Units{Temperature: "°F", WindSpeed: "mph"},
+			},
+			want:     15,
+			wantBand: SeverityCalm,
+		},
+		{
+			name:    "weights override the defaults",
+			weights: [4]*float64{weight(1), weight(0), weight(0), weight(0)},
+			instant: weather.Instant{
+				WeatherCode: 61, Temperature: 10, WindSpeed: 15, Precipitation: 2, Units: metric,
+			},
+			want:     60,
+			wantBand: SeverityRough,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			if tc.weights[0] != nil {
+				conf.Severity.CategoryWeight, conf.Severity.WindWeight = tc.weights[0], tc.weights[1]
+				conf.Severity.PrecipitationWeight, conf.Severity.TemperatureWeight = tc.weights[2], tc.weights[3]
+			}
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			view := pres.viewFromInstant(tc.instant, 0)
+			if view.Severity != tc.want {
+				t.Errorf("expected severity to be %d, got %d", tc.want, view.Severity)
+			}
+			if band := SeverityBand(view.Severity); band != tc.wantBand {
+				t.Errorf("expected severity band to be %q, got %q", tc.wantBand, band)
+			}
+		})
+	}
+}
+
+func TestSeverityBand(t *testing.T) {
+	tests := []struct {
+		score int
+		want  string
+	}{
+		{0, SeverityCalm}, {24, SeverityCalm}, {25, SeverityMild}, {49, SeverityMild},
+		{50, SeverityRough}, {74, SeverityRough}, {75, SeveritySevere}, {100, SeveritySevere},
+	}
+	for _, tc := range tests {
+		if got := SeverityBand(tc.score); got != tc.want {
+			t.Errorf("expected band of score %d to be %q, got %q", tc.score, tc.want, got)
+		}
+	}
+}
+
+func TestPresenter_worstCondition(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	now := time.Date(2026, 6, 10, 12, 15, 0, 0, time.UTC)
+	pres.Clock = clock.NewFake(now)
+	buildContext := func(codes ...int) TemplateContext {
+		data := &weather.Data{
+			GeneratedAt: now,
+			Current:     weather.Instant{InstantTime: now, WeatherCode: 0},
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		for i, code := range codes {
+			at := now.Truncate(time.Hour).Add(time.Hour * time.Duration(i+1))
+			data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, WeatherCode: code,
+				Temperature: float64(i + 1)}
+		}
+		return pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+	}
+
+	tests := []struct {
+		name      string
+		codes     []int
+		hours     int
+		wantHour  int
+		wantCateg string
+	}{
+		{"short shower is not missed", []int{1, 80, 2, 3, 1, 0}, 6, 2, "rain"},
+		{"thunderstorm beats snow", []int{71, 95, 61}, 3, 2, "thunderstorm"},
+		{"earliest of equal severity", []int{3, 61, 63, 2}, 4, 2, "rain"},
+		{"hours limit the scan", []int{1, 2, 95}, 2, 2, "cloudy"},
+		{"clear weather", []int{0, 1, 0}, 3, 1, "clear"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			worst := pres.worstCondition(buildContext(tc.codes...), tc.hours)
+			if worst.Category != tc.wantCateg {
+				t.Errorf("expected worst category to be %q, got %q", tc.wantCateg, worst.Category)
+			}
+			if worst.Temperature != float64(tc.wantHour) {
+				t.Errorf("expected worst condition at +%dh, got +%gh", tc.wantHour, worst.Temperature)
+			}
+			if worst.ConditionIcon == "" || worst.Condition == "" {
+				t.Errorf("expected worst condition to have a condition and icon, got %q and %q",
+					worst.Condition, worst.ConditionIcon)
+			}
+		})
+	}
+	t.Run("no forecasts return an empty view", func(t *testing.T) {
+		if worst := pres.worstCondition(buildContext(), 6); !worst.InstantTime.IsZero() {
+			t.Errorf("expected empty view, got %+v", worst)
+		}
+	})
+	t.Run("context holds the worst condition of the forecast hours", func(t *testing.T) {
+		tplCtx := buildContext(1, 2, 61, 95)
+		if tplCtx.WorstForecast.Category != "rain" {
+			t.Errorf("expected worst forecast within %d hours to be rain, got %q", conf.Weather.ForecastHours,
+				tplCtx.WorstForecast.Category)
+		}
+		tpl, err := template.New("test").Funcs(pres.templateFuncMap()).Parse(
+			`{{with worstCondition . 4}}{{.Category}}{{end}}`)
+		if err != nil {
+			t.Fatalf("failed to parse template: %s", err)
+		}
+		buf := strings.Builder{}
+		if err = tpl.Execute(&buf, tplCtx); err != nil {
+			t.Fatalf("failed to execute template: %s", err)
+		}
+		if buf.String() != "thunderstorm" {
+			t.Errorf("expected worst condition within 4 hours to be thunderstorm, got %q", buf.String())
+		}
+	})
+	t.Run("context falls back to the forecast without forecast hours", func(t *testing.T) {
+		tplCtx := buildContext()
+		if tplCtx.WorstForecast != tplCtx.Forecast {
+			t.Errorf("expected worst forecast to be the forecast, got %+v", tplCtx.WorstForecast)
+		}
+	})
+}
+
+func TestPresenter_conditionFor(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+
+	t.Run("every weather code has a condition and icons", func(t *testing.T) {
+		for code := range 100 {
+			if condition := pres.conditionFor(code); condition == "" {
+				t.Errorf("expected weather code %d to have a condition", code)
+			}
+			for _, isDay := range []bool{true, false} {
+				if icon := pres.iconFor(code, isDay); icon == "" {
+					t.Errorf("expected weather code %d to have an icon for day=%t", code, isDay)
+				}
+			}
+		}
+	})
+	t.Run("known weather code", func(t *testing.T) {
+		if condition := pres.conditionFor(61); condition != "Slight rain" {
+			t.Errorf("expected condition to be %q, got %q", "Slight rain", condition)
+		}
+		if icon := pres.iconFor(0, false); icon != "🌙" {
+			t.Errorf("expected icon to be %q, got %q", "🌙", icon)
+		}
+	})
+	t.Run("unknown weather code falls back to generic condition", func(t *testing.T) {
+		if condition := pres.conditionFor(46); condition != string(unknownCondition) {
+			t.Errorf("expected condition to be %q, got %q", unknownCondition, condition)
+		}
+		if icon := pres.iconFor(46, true); icon != unknownConditionIcon {
+			t.Errorf("expected icon to be %q, got %q", unknownConditionIcon, icon)
+		}
+	})
+	t.Run("unknown weather code is logged once", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Logger = logger.NewLogger(slog.LevelWarn, buf, nil)
+		view := pres.viewFromInstant(weather.Instant{WeatherCode: 104, IsDay: true}, 0)
+		if view.Condition != string(unknownCondition) || view.ConditionIcon != unknownConditionIcon {
+			t.Errorf("expected view to hold the generic condition, got %q and %q", view.Condition,
+				view.ConditionIcon)
+		}
+		_ = pres.conditionFor(104)
+		if count := strings.Count(buf.String(), "unknown weather code"); count != 1 {
+			t.Errorf("expected unknown weather code to be logged once, got %d times: %s", count, buf.String())
+		}
+		if !strings.Contains(buf.String(), "code=104") {
+			t.Errorf("expected log to contain the weather code, got: %s", buf.String())
+		}
+	})
+}
+
+func TestSunElevation(t *testing.T) {
+	berlin := geobus.Coordinate{Lat: 52.52, Lon: 13.405}
+	tests := []struct {
+		name     string
+		at       time.Time
+		min, max float64
+	}{
+		{"solar noon at summer solstice", time.Date(2026, 6, 21, 11, 7, 0, 0, time.UTC), 60, 62},
+		{"solar noon at winter solstice", time.Date(2026, 12, 21, 11, 7, 0, 0, time.UTC), 13, 15},
+		{"midnight", time.Date(2026, 6, 21, 23, 7, 0, 0, time.UTC), -15, -13},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if elevation := sunElevation(berlin, tc.at); elevation < tc.min || elevation > tc.max {
+				t.Errorf("expected sun elevation to be between %g and %g, got %g", tc.min, tc.max, elevation)
+			}
+		})
+	}
+	t.Run("zero time has no elevation", func(t *testing.T) {
+		if elevation := sunElevation(berlin, time.Time{}); elevation != 0 {
+			t.Errorf("expected sun elevation to be 0, got %g", elevation)
+		}
+	})
+}
+
+func TestTodaySunTimes(t *testing.T) {
+	berlin := geobus.Coordinate{Lat: 52.52, Lon: 13.405}
+	tromso := geobus.Coordinate{Lat: 69.65, Lon: 18.96}
+	checkWindow := func(t *testing.T, name string, window sunWindow, day time.Time) {
+		t.Helper()
+		if window.start.IsZero() || window.end.IsZero() {
+			t.Fatalf("expected %s to be set, got %s - %s", name, window.start, window.end)
+		}
+		if !window.start.Before(window.end) {
+			t.Errorf("expected %s to start before it ends, got %s - %s", name, window.start, window.end)
+		}
+		if window.start.Sub(day) < 0 || window.end.Sub(day) > time.Hour*24 {
+			t.Errorf("expected %s to be on %s, got %s - %s", name, day.Format(time.DateOnly), window.start,
+				window.end)
+		}
+	}
+
+	t.Run("morning windows are returned until they have ended", func(t *testing.T) {
+		day := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+		times := todaySunTimes(berlin, day.Add(time.Hour*3))
+		checkWindow(t, "golden hour", times.goldenHour, day)
+		checkWindow(t, "blue hour", times.blueHour, day)
+		if !times.blueHour.end.Equal(times.goldenHour.start) {
+			t.Errorf("expected golden hour to start when the morning blue hour ends, got %s and %s",
+				times.blueHour.end, times.goldenHour.start)
+		}
+		if times.goldenHour.start.UTC().Hour() >= 12 {
+			t.Errorf("expected morning golden hour, got %s", times.goldenHour.start)
+		}
+		if times.polarNight || times.midnightSun {
+			t.Error("expected no polar night or midnight sun")
+		}
+	})
+	t.Run("evening windows are returned after the morning windows", func(t *testing.T) {
+		day := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+		times := todaySunTimes(berlin, day.Add(time.Hour*12))
+		checkWindow(t, "golden hour", times.goldenHour, day)
+		checkWindow(t, "blue hour", times.blueHour, day)
+		if !times.goldenHour.end.Equal(times.blueHour.start) {
+			t.Errorf("expected evening blue hour to start when the golden hour ends, got %s and %s",
+				times.goldenHour.end, times.blueHour.start)
+		}
+		if times.goldenHour.start.UTC().Hour() < 12 {
+			t.Errorf("expected evening golden hour, got %s", times.goldenHour.start)
+		}
+	})
+	t.Run("polar night", func(t *testing.T) {
+		times := todaySunTimes(tromso, time.Date(2026, 12, 21, 11, 0, 0, 0, time.UTC))
+		if !times.polarNight || times.midnightSun {
+			t.Errorf("expected polar night, got polar night %t and midnight sun %t", times.polarNight,
+				times.midnightSun)
+		}
+		if times.goldenHour != (sunWindow{}) || times.blueHour != (sunWindow{}) {
+			t.Errorf("expected empty windows, got %+v and %+v", times.goldenHour, times.blueHour)
+		}
+	})
+	t.Run("midnight sun", func(t *testing.T) {
+		times := todaySunTimes(tromso, time.Date(2026, 6, 21, 23, 0, 0, 0, time.UTC))
+		if times.polarNight || !times.midnightSun {
+			t.Errorf("expected midnight sun, got polar night %t and midnight sun %t", times.polarNight,
+				times.midnightSun)
+		}
+		if times.goldenHour != (sunWindow{}) || times.blueHour != (sunWindow{}) {
+			t.Errorf("expected empty windows, got %+v and %+v", times.goldenHour, times.blueHour)
+		}
+	})
+	t.Run("poles don't panic", func(t *testing.T) {
+		for _, coords := range []geobus.Coordinate{{Lat: 90, Lon: 0}, {Lat: -90, Lon: 0}} {
+			for _, month := range []time.Month{time.March, time.June, time.December} {
+				_ = todaySunTimes(coords, time.Date(2026, month, 21, 12, 0, 0, 0, time.UTC))
+			}
+		}
+	})
+}
+
+func TestPresenter_BuildContext_sun(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	now := time.Date(2026, 6, 21, 11, 7, 0, 0, time.UTC)
+	pres.Clock = clock.NewFake(now)
+	data := &weather.Data{
+		Coordinates: geobus.Coordinate{Lat: 52.52, Lon: 13.405},
+		Current:     weather.Instant{InstantTime: now.Add(-time.Hour * 3)},
+		Forecast: map[weather.DayHour]weather.Instant{
+			weather.NewDayHour(now.Add(time.Hour * 12)): {InstantTime: now.Add(time.Hour * 12)},
+		},
+	}
+	tplCtx := pres.BuildContext(geocode.Address{}, data, time.Time{}, time.Time{}, "", time.Time{})
+	if tplCtx.Current.SunElevation < 60 {
+		t.Errorf("expected current sun elevation to be computed for now, got %g", tplCtx.Current.SunElevation)
+	}
+	if len(tplCtx.Forecasts) != 1 || tplCtx.Forecasts[0].SunElevation >= 0 {
+		t.Errorf("expected forecast sun elevation to be below the horizon, got %+v", tplCtx.Forecasts)
+	}
+	if tplCtx.GoldenHourStart.IsZero() || tplCtx.BlueHourStart.IsZero() {
+		t.Error("expected golden and blue hour to be set")
+	}
+	if tplCtx.PolarNight || tplCtx.MidnightSun {
+		t.Error("expected no polar night or midnight sun")
+	}
+}
+
+func TestPresenter_tooltipMarkup(t *testing.T) {
+	tplCtx := TemplateContext{
+		Address: geocode.Address{City: "Tom & Jerry <Town>"},
+		Current: WeatherView{Instant: weather.Instant{Temperature: 21.5, IsDay: true}},
+	}
+	tests := []struct {
+		name    string
+		markup  bool
+		tooltip string
+		want    string
+	}{
+		{"markup disabled", false, "{{.Address.City}}", "Tom & Jerry <Town>"},
+		{"values are escaped", true, "{{.Address.City}}", "Tom &amp; Jerry &lt;Town&gt;"},
+		{"literal text is not escaped", true, "<b>{{.Address.City}}</b>", "<b>Tom &amp; Jerry &lt;Town&gt;</b>"},
+		{"bold", true, "{{bold .Address.City}}", "<b>Tom &amp; Jerry &lt;Town&gt;</b>"},
+		{"italic", true, "{{italic .Current.Temperature}}", "<i>21.5</i>"},
+		{
+			"color", true, `{{color "#ff0000" .Address.City}}`,
+			`<span foreground="#ff0000">Tom &amp; Jerry &lt;Town&gt;</span>`,
+		},
+		{
+			"nested helpers", true, `{{color "red" (bold .Address.City)}}`,
+			`<span foreground="red"><b>Tom &amp; Jerry &lt;Town&gt;</b></span>`,
+		},
+		{"pipelines", true, "{{.Address.City | uc}}", "TOM &amp; JERRY &lt;TOWN&gt;"},
+		{"control structures", true, "{{with .Address}}{{if .City}}{{.City}}{{end}}{{end}}", "Tom &amp; Jerry &lt;Town&gt;"},
+		{"variables", true, "{{$city := .Address.City}}{{$city}}", "Tom &amp; Jerry &lt;Town&gt;"},
+		{"helpers without markup", false, "{{bold .Address.City}}", "Tom & Jerry <Town>"},
+		{"italic without markup", false, "{{italic .Address.City}}", "Tom & Jerry <Town>"},
+		{"nested helpers without markup", false, `{{color "red" (bold .Address.City)}}`, "Tom & Jerry <Town>"},
+		{"helpers strip control characters", false, "{{bold \"Tom\x1b[0m\"}}", "Tom[0m"},
+		{"control characters are stripped", false, "{{.Address.City}}\x1b[0m\n", "Tom & Jerry <Town>[0m\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Output.TooltipMarkup = tc.markup
+			conf.Templates.Tooltip = tc.tooltip
+			conf.Templates.Text = "{{.Address.City}}"
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			got, err := pres.Render(tplCtx)
+			if err != nil {
+				t.Fatalf("failed to render templates: %s", err)
+			}
+			if got["tooltip"] != tc.want {
+				t.Errorf("expected tooltip to be %q, got %q", tc.want, got["tooltip"])
+			}
+			if got["text"] != "Tom & Jerry <Town>" {
+				t.Errorf("expected text not to be escaped, got %q", got["text"])
+			}
+		})
+	}
+}
+
+func TestPresenter_tempColor(t *testing.T) {
+	tests := []struct {
+		name  string
+		units string
+		temp  float64
+		want  string
+	}{
+		{"celsius below the gradient is clamped", "metric", -25, "#3b82f6"},
+		{"celsius first stop", "metric", -10, "#3b82f6"},
+		{"celsius midpoint between blue and green", "metric", 0, "#2fa4aa"},
+		{"celsius middle stop", "metric", 10, "#22c55e"},
+		{"celsius midpoint between green and red", "metric", 20, "#898551"},
+		{"celsius last stop", "metric", 30, "#ef4444"},
+		{"celsius above the gradient is clamped", "metric", 45, "#ef4444"},
+		{"fahrenheit below the gradient is clamped", "imperial", -10, "#3b82f6"},
+		{"fahrenheit first stop", "imperial", 14, "#3b82f6"},
+		{"fahrenheit midpoint between blue and green", "imperial", 32, "#2fa4aa"},
+		{"fahrenheit middle stop", "imperial", 50, "#22c55e"},
+		{"fahrenheit midpoint between green and red", "imperial", 68, "#898551"},
+		{"fahrenheit last stop", "imperial", 86, "#ef4444"},
+		{"fahrenheit above the gradient is clamped", "imperial", 110, "#ef4444"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_UNITS", tc.units)
+			conf, lang := testConfLang(t)
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			got, err := pres.tempColor(pres.gradientUnit, tc.temp)
+			if err != nil {
+				t.Fatalf("failed to map temperature to color: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected color for %g to be %q, got %q", tc.temp, tc.want, got)
+			}
+		})
+	}
+	t.Run("configured gradient", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.Output.TempGradient = []config.ColorStop{
+			{Temperature: "0", Color: "#000000"},
+			{Temperature: "10", Color: "#FFFFFF"},
+		}
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		for temp, want := range map[any]string{0: "#000000", 2.5: "#404040", uint(5): "#808080", 10.0: "#ffffff"} {
+			got, err := pres.tempColor(pres.gradientUnit, temp)
+			if err != nil {
+				t.Fatalf("failed to map temperature to color: %s", err)
+			}
+			if got != want {
+				t.Errorf("expected color for %v to be %q, got %q", temp, want, got)
+			}
+		}
+	})
+	t.Run("configured gradient with unit suffixes is converted", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_UNITS", "imperial")
+		conf, lang := testConfLang(t)
+		conf.Output.TempGradient = []config.ColorStop{
+			{Temperature: "0C", Color: "#000000"},
+			{Temperature: "10C", Color: "#FFFFFF"},
+		}
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		for temp, want := range map[float64]string{32: "#000000", 41: "#808080", 50: "#ffffff"} {
+			got, err := pres.tempColor(pres.gradientUnit, temp)
+			if err != nil {
+				t.Fatalf("failed to map temperature to color: %s", err)
+			}
+			if got != want {
+				t.Errorf("expected color for %g to be %q, got %q", temp, want, got)
+			}
+		}
+	})
+	t.Run("gradient follows the displayed unit with automatic units", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_UNITS", "auto")
+		conf, lang := testConfLang(t)
+		conf.Output.TempGradient = []config.ColorStop{
+			{Temperature: "0C", Color: "#000000"},
+			{Temperature: "10C", Color: "#FFFFFF"},
+		}
+		conf.Templates.Text = "{{tempColor .Current.Temperature}}"
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		// The location is in the United States, so the data is reported in °F
+		now := time.Now()
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: 40.7128, Lon: -74.006},
+			Current:     weather.Instant{InstantTime: now, Temperature: 41, Units: weather.Units{Temperature: "°F"}},
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		// Building a context in °C must not change the unit of the contexts built before
+		metricData := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: 52.52, Lon: 13.405},
+			Current:     weather.Instant{InstantTime: now, Temperature: 5, Units: weather.Units{Temperature: "°C"}},
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		metricCtx := pres.BuildContext(addr, metricData, sunrise, sunset, moonphase, time.Time{})
+		got, err := pres.Render(tplCtx)
+		if err != nil {
+			t.Fatalf("failed to render templates: %s", err)
+		}
+		if got["text"] != "#808080" {
+			t.Errorf("expected color of 41°F to be %q, got %q", "#808080", got["text"])
+		}
+		if got, err = pres.Render(metricCtx); err != nil {
+			t.Fatalf("failed to render templates: %s", err)
+		}
+		if got["text"] != "#808080" {
+			t.Errorf("expected color of 5°C to be %q, got %q", "#808080", got["text"])
+		}
+	})
+	t.Run("non-numeric temperature fails", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		if _, err = pres.tempColor(pres.gradientUnit, "warm"); err == nil {
+			t.Error("expected non-numeric temperature to fail")
+		}
+	})
+	t.Run("invalid gradient color fails", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.Output.TempGradient = []config.ColorStop{{Temperature: "0", Color: "blue"}}
+		if _, err := New(conf, lang); err == nil {
+			t.Error("expected invalid gradient color to fail")
+		}
+	})
+}
+
+func TestPresenter_colorize(t *testing.T) {
+	tplCtx := TemplateContext{
+		Current: WeatherView{
+			Instant:        weather.Instant{Temperature: 20},
+			TemperatureStr: "20°C <warm>",
+		},
+	}
+	tests := []struct {
+		name    string
+		markup  bool
+		tooltip string
+		want    string
+	}{
+		{
+			"markup enabled", true, "{{colorize .Current.Temperature .Current.TemperatureStr}}",
+			`<span foreground="#898551">20°C &lt;warm&gt;</span>`,
+		},
+		{
+			"nested helpers", true, "{{colorize .Current.Temperature (bold .Current.TemperatureStr)}}",
+			`<span foreground="#898551"><b>20°C &lt;warm&gt;</b></span>`,
+		},
+		{"markup disabled", false, "{{colorize .Current.Temperature .Current.TemperatureStr}}", "20°C <warm>"},
+		{"tempColor", false, "{{tempColor .Current.Temperature}}", "#898551"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, lang := testConfLang(t)
+			conf.Output.TooltipMarkup = tc.markup
+			conf.Templates.Tooltip = tc.tooltip
+			pres, err := New(conf, lang)
+			if err != nil {
+				t.Fatalf("failed to create presenter: %s", err)
+			}
+			got, err := pres.Render(tplCtx)
+			if err != nil {
+				t.Fatalf("failed to render templates: %s", err)
+			}
+			if got["tooltip"] != tc.want {
+				t.Errorf("expected tooltip to be %q, got %q", tc.want, got["tooltip"])
+			}
+		})
+	}
+}
+
+func TestPresenter_yesterdayAt(t *testing.T) {
+	conf, lang := testConfLang(t)
+	pres, err := New(conf, lang)
+	if err != nil {
+		t.Fatalf("failed to create presenter: %s", err)
+	}
+	pres.Clock = clock.NewFake(now)
+	t.Run("yesterday's instant is found", func(t *testing.T) {
+		fcasts := make(map[weather.DayHour]weather.Instant)
+		past := wthr
+		past.Temperature = 17.5
+		past.InstantTime = weather.NewDayHour(now.Add(-time.Hour * 24)).Time()
+		fcasts[weather.NewDayHour(past.InstantTime)] = past
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Current:     wthr,
+			Forecast:    fcasts,
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+
+		got := pres.yesterdayAt(tplCtx, now)
+		if got.Temperature != past.Temperature {
+			t.Errorf("failed to get yesterday's instant: got %f, want %f", got.Temperature, past.Temperature)
+		}
+		wantDelta := wthr.Temperature - past.Temperature
+		if tplCtx.Current.DeltaFromYesterday != wantDelta {
+			t.Errorf("expected delta from yesterday to be %f, got %f", wantDelta,
+				tplCtx.Current.DeltaFromYesterday)
+		}
+	})
+	t.Run("delta from yesterday is in the displayed unit", func(t *testing.T) {
+		conf, lang := testConfLang(t)
+		conf.UnitOverrides.Temperature = weather.UnitFahrenheit
+		pres, err := New(conf, lang)
+		if err != nil {
+			t.Fatalf("failed to create presenter: %s", err)
+		}
+		pres.Clock = clock.NewFake(now)
+		past := wthr
+		past.Temperature = 15
+		past.InstantTime = weather.NewDayHour(now.Add(-time.Hour * 24)).Time()
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Current:     wthr,
+			Forecast:    map[weather.DayHour]weather.Instant{weather.NewDayHour(past.InstantTime): past},
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+		// 20°C is 68°F and 15°C is 59°F
+		if tplCtx.Current.DeltaFromYesterday != 9 {
+			t.Errorf("expected delta from yesterday to be %d, got %f", 9, tplCtx.Current.DeltaFromYesterday)
+		}
+	})
+	t.Run("missing history returns zero values", func(t *testing.T) {
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: addr.Latitude, Lon: addr.Longitude},
+			Current:     wthr,
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		tplCtx := pres.BuildContext(addr, data, sunrise, sunset, moonphase, time.Time{})
+
+		got := pres.yesterdayAt(tplCtx, now)
+		if got.Temperature != 0 || !got.InstantTime.IsZero() {
+			t.Errorf("expected empty instant, got %+v", got)
+		}
+		if tplCtx.Current.DeltaFromYesterday != 0 {
+			t.Errorf("expected delta from yesterday to be 0, got %f", tplCtx.Current.DeltaFromYesterday)
 		}
 	})
 }
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"math"
+	"slices"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// Bands of the condition severity score, which are emitted as CSS classes
+const (
+	SeverityCalm   = "calm"
+	SeverityMild   = "mild"
+	SeverityRough  = "rough"
+	SeveritySevere = "severe"
+)
+
+// The components of the severity score reach their maximum at these values. Wind and gusts are in km/h,
+// the values of a severe gale and of gusts that cause damage. Precipitation is in mm/h, the rate of heavy
+// rain. Temperatures are in °C: the temperature component rises below severityColdStart and above
+// severityHotStart and reaches its maximum at severityColdMax and severityHotMax.
+const (
+	severityWindMax   = 75.0
+	severityGustsMax  = 100.0
+	severityPrecipMax = 10.0
+	severityColdStart = 0.0
+	severityColdMax   = -15.0
+	severityHotStart  = 25.0
+	severityHotMax    = 38.0
+)
+
+// severityWeights are the weights of the components of the severity score
+type severityWeights struct {
+	category      float64
+	wind          float64
+	precipitation float64
+	temperature   float64
+}
+
+// CategorySeverity returns the severity of the weather category, which is its index in
+// config.WeatherCategories. Unknown categories have a severity of -1, so that any known category is more severe.
+func CategorySeverity(category string) int {
+	return slices.Index(config.WeatherCategories, category)
+}
+
+// severity returns the condition severity score of the weather instant, from 0 for calm to 100 for
+// severe weather. It is the weighted average of four components between 0 and 1: the severity of the
+// weather category relative to the most severe category, the wind speed or the gusts, whichever is more
+// severe, the precipitation, and the distance of the temperature to a comfortable range. The metrics are
+// converted into metric units, so that the score does not depend on the preferred units.
+func (p *Presenter) severity(in weather.Instant) int {
+	in = in.Convert(weather.DefaultUnits(weather.UnitSystemMetric))
+	mostSevere := float64(len(config.WeatherCategories) - 1)
+	category := max(float64(CategorySeverity(weatherCategory(in.WeatherCode))), 0) / mostSevere
+	wind := max(in.WindSpeed/severityWindMax, in.WindGusts/severityGustsMax)
+	precipitation := in.Precipitation / severityPrecipMax
+	temperature := max((severityColdStart-in.Temperature)/(severityColdStart-severityColdMax),
+		(in.Temperature-severityHotStart)/(severityHotMax-severityHotStart))
+
+	weights := p.severityWeights
+	total := weights.category + weights.wind + weights.precipitation + weights.temperature
+	if total <= 0 {
+		return 0
+	}
+	score := weights.category*clamp01(category) + weights.wind*clamp01(wind) +
+		weights.precipitation*clamp01(precipitation) + weights.temperature*clamp01(temperature)
+	return int(math.Round(score / total * 100))
+}
+
+// SeverityBand returns the band of the severity score: SeverityCalm below 25, SeverityMild below 50,
+// SeverityRough below 75 and SeveritySevere from 75.
+func SeverityBand(score int) string {
+	switch {
+	case score < 25:
+		return SeverityCalm
+	case score < 50:
+		return SeverityMild
+	case score < 75:
+		return SeverityRough
+	default:
+		return SeveritySevere
+	}
+}
+
+// clamp01 limits the value to the range from 0 to 1. NaN is treated as 0.
+func clamp01(val float64) float64 {
+	if math.IsNaN(val) {
+		return 0
+	}
+	return min(max(val, 0), 1)
+}
+
+// worstCondition returns the forecast with the most severe weather category within the next n hours after
+// the hour of the current weather instant. Of forecasts with the same severity, the earliest one is
+// returned. An empty WeatherView is returned, if no forecast is available.
+func (p *Presenter) worstCondition(ctx TemplateContext, n int) WeatherView {
+	var worst WeatherView
+	severity := 0
+	for i, view := range p.nextHours(ctx, n) {
+		if i == 0 || CategorySeverity(view.Category) > severity {
+			worst, severity = view, CategorySeverity(view.Category)
+		}
+	}
+	return worst
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	// sparklineGlyphs is the default glyph set of the sparkline, ordered from the lowest to the highest value
+	sparklineGlyphs = "▁▂▃▄▅▆▇█"
+	// sparklineGap is rendered for hours without forecast data
+	sparklineGap = ' '
+)
+
+// sparklineMetrics maps the metric names supported by the sparkline to the corresponding weather values.
+var sparklineMetrics = map[string]func(WeatherView) float64{
+	"temperature":          func(v WeatherView) float64 { return v.Temperature },
+	"apparent_temperature": func(v WeatherView) float64 { return v.ApparentTemperature },
+	"dew_point":            func(v WeatherView) float64 { return v.DewPoint },
+	"humidity":             func(v WeatherView) float64 { return v.RelativeHumidity },
+	"pressure":             func(v WeatherView) float64 { return v.PressureMSL },
+	"wind_speed":           func(v WeatherView) float64 { return v.WindSpeed },
+	"wind_gusts":           func(v WeatherView) float64 { return v.WindGusts },
+}
+
+// sparkline renders the given metric of the forecasts of the next n hours after the hour of the current
+// weather instant as a sparkline. The values are normalized into the glyph set, which defaults to
+// sparklineGlyphs and can be overridden by the optional glyphs argument. Hours without forecast data are
+// rendered as a space. If all values are the same, the middle glyph is used for every hour. An empty string
+// is returned if there is no forecast data for any of the hours.
+func (p *Presenter) sparkline(ctx TemplateContext, metric string, n int, glyphs ...string) (string, error) {
+	value, ok := sparklineMetrics[strings.ToLower(metric)]
+	if !ok {
+		return "", fmt.Errorf("unknown sparkline metric: %q", metric)
+	}
+	set := []rune(sparklineGlyphs)
+	if len(glyphs) > 0 && glyphs[0] != "" {
+		set = []rune(glyphs[0])
+	}
+	if n <= 0 {
+		return "", nil
+	}
+
+	forecasts := make(map[int64]WeatherView, len(ctx.hours))
+	for _, fcast := range ctx.hours {
+		forecasts[fcast.InstantTime.Unix()] = fcast
+	}
+
+	start := weather.NewDayHour(ctx.Current.InstantTime).Time()
+	values := make([]float64, n)
+	present := make([]bool, n)
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range n {
+		fcast, ok := forecasts[start.Add(time.Hour*time.Duration(i+1)).Unix()]
+		if !ok {
+			continue
+		}
+		values[i], present[i] = value(fcast), true
+		low, high = min(low, values[i]), max(high, values[i])
+	}
+	if math.IsInf(low, 1) {
+		return "", nil
+	}
+
+	line := make([]rune, n)
+	for i := range values {
+		switch {
+		case !present[i]:
+			line[i] = sparklineGap
+		case high == low:
+			line[i] = set[(len(set)-1)/2]
+		default:
+			line[i] = set[int(math.Round((values[i]-low)/(high-low)*float64(len(set)-1)))]
+		}
+	}
+	return string(line), nil
+}
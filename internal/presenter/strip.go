@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// emojiStripGap is rendered for steps of the emoji strips without forecast data
+const emojiStripGap = "·"
+
+// emojiStrip renders the condition icons of count forecasts as a compact one-line forecast like "🌦️⛅☀️☀️🌙",
+// advancing by step hours from the hour of the current weather instant, e. g. a count of 5 and a step of 3
+// covers the next 15 hours. The icons are those of the weather instants, so hours after sunset are shown
+// with the night icons. Steps without forecast data are rendered as emojiStripGap.
+func (p *Presenter) emojiStrip(ctx TemplateContext, count, step int) (string, error) {
+	views, err := p.stripViews(ctx, count, step)
+	if err != nil {
+		return "", err
+	}
+
+	var strip strings.Builder
+	for _, view := range views {
+		if view.InstantTime.IsZero() {
+			strip.WriteString(emojiStripGap)
+			continue
+		}
+		strip.WriteString(view.ConditionIcon)
+	}
+	return strip.String(), nil
+}
+
+// emojiTempStrip works like emojiStrip, but follows each icon with the temperature rounded to whole
+// degrees, like "🌦️8° ⛅10°". The steps are separated by spaces.
+func (p *Presenter) emojiTempStrip(ctx TemplateContext, count, step int) (string, error) {
+	views, err := p.stripViews(ctx, count, step)
+	if err != nil {
+		return "", err
+	}
+
+	steps := make([]string, 0, len(views))
+	for _, view := range views {
+		if view.InstantTime.IsZero() {
+			steps = append(steps, emojiStripGap)
+			continue
+		}
+		steps = append(steps, view.ConditionIcon+p.formatValue(view.Temperature, 0, "°"))
+	}
+	return strings.Join(steps, " "), nil
+}
+
+// stripViews returns the forecasts of the steps of the emoji strips. Steps without forecast data are
+// returned as empty WeatherView.
+func (p *Presenter) stripViews(ctx TemplateContext, count, step int) ([]WeatherView, error) {
+	if step < 1 {
+		return nil, fmt.Errorf("emoji strip step must be at least 1, got %d", step)
+	}
+	views := make([]WeatherView, max(count, 0))
+	for i := range views {
+		views[i] = p.forecastByOffset(ctx, (i+1)*step)
+	}
+	return views, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"time"
+
+	gosunrise "github.com/nathan-osman/go-sunrise"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+)
+
+const (
+	// horizonElevation is the sun elevation in degrees at sunrise and sunset, which accounts for the
+	// atmospheric refraction and the radius of the sun
+	horizonElevation = -0.833
+	// The golden hour lasts while the sun is between blueHourElevation and goldenHourElevation, the blue
+	// hour while it is between civilTwilightElevation and blueHourElevation (in degrees)
+	goldenHourElevation    = 6.0
+	blueHourElevation      = -4.0
+	civilTwilightElevation = -6.0
+)
+
+// sunWindow is the time window in which the sun passes between two elevations. Both times are zero if the
+// sun doesn't reach one of the elevations on that day.
+type sunWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// sunTimes holds the golden and blue hour of a day and whether the sun doesn't rise or set on that day.
+type sunTimes struct {
+	goldenHour  sunWindow
+	blueHour    sunWindow
+	polarNight  bool
+	midnightSun bool
+}
+
+// sunElevation returns the elevation of the sun above the horizon in degrees at the given time and
+// coordinates. It returns zero for the zero time.
+func sunElevation(coords geobus.Coordinate, at time.Time) float64 {
+	if at.IsZero() {
+		return 0
+	}
+	return gosunrise.Elevation(coords.Lat, coords.Lon, at)
+}
+
+// todaySunTimes returns the golden and blue hour of the day of now at the coordinates. Since both happen
+// in the morning and in the evening, the morning window is returned until it has ended, then the evening
+// window. If the sun doesn't rise or set on that day, the windows are empty and polarNight or midnightSun
+// is set.
+func todaySunTimes(coords geobus.Coordinate, now time.Time) sunTimes {
+	elevationTimes := func(elevation float64) (time.Time, time.Time) {
+		morning, evening := gosunrise.TimeOfElevation(coords.Lat, coords.Lon, elevation, now.Year(), now.Month(),
+			now.Day())
+		return morning.In(time.Local), evening.In(time.Local)
+	}
+
+	var times sunTimes
+	if rise, _ := elevationTimes(horizonElevation); rise.IsZero() {
+		// Without sunrise the sun stays above or below the horizon all day, so any time tells which
+		times.midnightSun = sunElevation(coords, now) > horizonElevation
+		times.polarNight = !times.midnightSun
+		return times
+	}
+
+	twilightMorning, twilightEvening := elevationTimes(civilTwilightElevation)
+	blueMorning, blueEvening := elevationTimes(blueHourElevation)
+	goldenMorning, goldenEvening := elevationTimes(goldenHourElevation)
+	times.blueHour = nextSunWindow(now, sunWindow{twilightMorning, blueMorning},
+		sunWindow{blueEvening, twilightEvening})
+	times.goldenHour = nextSunWindow(now, sunWindow{blueMorning, goldenMorning},
+		sunWindow{goldenEvening, blueEvening})
+	return times
+}
+
+// nextSunWindow returns the morning window until it has ended, then the evening window. Windows with a
+// missing time are returned empty.
+func nextSunWindow(now time.Time, morning, evening sunWindow) sunWindow {
+	window := evening
+	if now.Before(morning.end) {
+		window = morning
+	}
+	if window.start.IsZero() || window.end.IsZero() {
+		return sunWindow{}
+	}
+	return window
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package presenter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// tableColumnSeparator separates the columns of the forecast table
+	tableColumnSeparator = "  "
+	// emojiVariationSelector requests the emoji presentation of the preceding character
+	emojiVariationSelector = '\uFE0F'
+)
+
+// forecastTable renders count forecasts as a table with aligned columns, starting at the given offset from
+// the hour of the current weather instant and advancing by step hours per row, e. g. a start of 0, a count
+// of 8 and a step of 3 renders every third hour of the next 24 hours. Each row holds the localized hour, the
+// condition icon, the temperature and the precipitation. The columns are padded to the display width of
+// their longest value, so that wide emoji don't break the alignment. Hours without forecast data are
+// omitted.
+func (p *Presenter) forecastTable(ctx TemplateContext, start, count, step int) (string, error) {
+	if step < 1 {
+		return "", fmt.Errorf("forecast table step must be at least 1, got %d", step)
+	}
+
+	var rows [][]string
+	for i := range max(count, 0) {
+		view := p.forecastByOffset(ctx, start+i*step)
+		if view.InstantTime.IsZero() {
+			continue
+		}
+		rows = append(rows, []string{
+			p.prefTime(view.InstantTime), view.ConditionIcon, view.TemperatureStr, view.PrecipitationStr,
+		})
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for col, cell := range row {
+			widths[col] = max(widths[col], displayWidth(cell))
+		}
+	}
+
+	// The hour and the icon are aligned left, the values are aligned right
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for col, cell := range row {
+			if col < 2 {
+				cells[col] = padRight(cell, widths[col])
+				continue
+			}
+			cells[col] = padLeft(cell, widths[col])
+		}
+		lines = append(lines, strings.Join(cells, tableColumnSeparator))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// padRight appends spaces to the value until it reaches the given display width.
+func padRight(val string, width int) string {
+	return val + strings.Repeat(" ", max(width-displayWidth(val), 0))
+}
+
+// padLeft prepends spaces to the value until it reaches the given display width.
+func padLeft(val string, width int) string {
+	return strings.Repeat(" ", max(width-displayWidth(val), 0)) + val
+}
+
+// displayWidth returns the number of terminal cells the value takes up. runewidth counts emoji that are
+// text-style by default, e. g. 🌫 or ☀, as a single cell even if they are followed by the emoji variation
+// selector, while terminals and bars render them with emoji presentation as wide characters. Those are
+// counted as two cells instead.
+func displayWidth(val string) int {
+	width := runewidth.StringWidth(val)
+	if !strings.ContainsRune(val, emojiVariationSelector) {
+		return width
+	}
+	var prev rune
+	for _, r := range val {
+		if r == emojiVariationSelector && runewidth.RuneWidth(prev) == 1 {
+			width++
+		}
+		prev = r
+	}
+	return width
+}
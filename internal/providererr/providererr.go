@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package providererr provides the kinds of errors that the weather and geocoding providers
+// return, so that a caller can tell a rejected API key from a rate limit or a network error with errors.Is
+// and react accordingly.
+package providererr
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrAuth is the kind of errors caused by a missing, invalid or expired API key. Retrying the request
+	// won't help.
+	ErrAuth = errors.New("authentication failed")
+	// ErrRateLimited is the kind of errors caused by exceeding the request quota of the API
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrTransient is the kind of errors that are expected to go away on retry, like network errors,
+	// timeouts and server errors
+	ErrTransient = errors.New("transient error")
+	// ErrBadResponse is the kind of errors caused by a response that can't be used, like an invalid or
+	// incomplete response
+	ErrBadResponse = errors.New("bad response")
+)
+
+// kinds are the error kinds, the most recoverable first
+var kinds = []error{ErrTransient, ErrRateLimited, ErrBadResponse, ErrAuth}
+
+// kindError attaches an error kind to an error without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
+
+// Wrap returns err with the given kind attached, so that errors.Is reports the kind for it. The message
+// of err is kept. If err or kind is nil, err is returned as is.
+func Wrap(kind, err error) error {
+	if err == nil || kind == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Status returns the kind of error of a response with the given HTTP status code that could not be used.
+// Responses with a status code that doesn't indicate a temporary or authentication failure are bad
+// responses. Authentication failures are only possible if the request carried credentials. Without them,
+// the API rather blocked the client, e. g. for exceeding its usage policy, which is treated as rate limit.
+func Status(code int, credentials bool) error {
+	switch {
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		if !credentials {
+			return ErrRateLimited
+		}
+		return ErrAuth
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code == http.StatusRequestTimeout, code >= http.StatusInternalServerError:
+		return ErrTransient
+	default:
+		return ErrBadResponse
+	}
+}
+
+// Kind returns the kind of err or nil if it has none. If err combines errors of several kinds, e. g. the
+// errors of several providers, the most recoverable kind is returned, so that the error is only treated as
+// authentication failure if all of them are.
+func Kind(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package providererr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	t.Run("kind is attached without changing the message", func(t *testing.T) {
+		inner := errors.New("API returned non-positive response code: 401")
+		err := Wrap(ErrAuth, inner)
+		if !errors.Is(err, ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", ErrAuth, err)
+		}
+		if !errors.Is(err, inner) {
+			t.Errorf("expected error to wrap %s", inner)
+		}
+		if err.Error() != inner.Error() {
+			t.Errorf("expected error message to be %q, got %q", inner, err)
+		}
+	})
+	t.Run("kind survives further wrapping", func(t *testing.T) {
+		err := fmt.Errorf("failed to fetch: %w", Wrap(ErrTransient, errors.New("timeout")))
+		if !errors.Is(err, ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", ErrTransient, err)
+		}
+	})
+	t.Run("nil error stays nil", func(t *testing.T) {
+		if err := Wrap(ErrAuth, nil); err != nil {
+			t.Errorf("expected error to be nil, got %s", err)
+		}
+	})
+	t.Run("nil kind returns the error as is", func(t *testing.T) {
+		inner := errors.New("failure")
+		if err := Wrap(nil, inner); err != inner {
+			t.Errorf("expected error to be returned as is, got %s", err)
+		}
+	})
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		code        int
+		credentials bool
+		want        error
+	}{
+		{200, true, ErrBadResponse},
+		{204, true, ErrBadResponse},
+		{400, true, ErrBadResponse},
+		{401, true, ErrAuth},
+		{403, true, ErrAuth},
+		{401, false, ErrRateLimited},
+		{403, false, ErrRateLimited},
+		{404, true, ErrBadResponse},
+		{408, true, ErrTransient},
+		{429, true, ErrRateLimited},
+		{429, false, ErrRateLimited},
+		{500, true, ErrTransient},
+		{502, false, ErrTransient},
+		{503, true, ErrTransient},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d with credentials %t", tc.code, tc.credentials), func(t *testing.T) {
+			if got := Status(tc.code, tc.credentials); got != tc.want {
+				t.Errorf("expected kind of status code %d to be %s, got %s", tc.code, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKind(t *testing.T) {
+	auth := Wrap(ErrAuth, errors.New("invalid API key"))
+	limited := Wrap(ErrRateLimited, errors.New("quota exceeded"))
+	transient := Wrap(ErrTransient, errors.New("connection refused"))
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil error", nil, nil},
+		{"error without kind", errors.New("failure"), nil},
+		{"single kind", fmt.Errorf("failed: %w", limited), ErrRateLimited},
+		{"authentication failure of all providers", errors.Join(auth, auth), ErrAuth},
+		{"most recoverable kind of several providers", errors.Join(auth, limited, transient), ErrTransient},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Kind(tc.err); got != tc.want {
+				t.Errorf("expected kind to be %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
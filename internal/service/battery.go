@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	upowerName      = "org.freedesktop.UPower"
+	upowerPath      = dbus.ObjectPath("/org/freedesktop/UPower")
+	upowerOnBattery = "OnBattery"
+)
+
+// monitorPowerSource scales the weather update and geolocation poll intervals with the configured battery
+// multiplier while the system runs on battery power, as reported by UPower. If the battery multiplier is
+// not configured or UPower is not available, the intervals are left unchanged.
+func (s *Service) monitorPowerSource(ctx context.Context) {
+	if s.config.Intervals.BatteryMultiplier <= 1 {
+		return
+	}
+
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		s.logger.Debug("system bus not available, battery-aware polling disabled", logger.Err(err))
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			s.logger.Error("failed to close system bus connection", logger.Err(err))
+		}
+	}()
+
+	// Subscribe before the initial state is read, so that no change in between is missed
+	if err = conn.AddMatchSignal(dbus.WithMatchObjectPath(upowerPath),
+		dbus.WithMatchInterface(dbusPropertiesInterface), dbus.WithMatchMember("PropertiesChanged"),
+	); err != nil {
+		s.logger.Error("failed to subscribe to dbus signal", slog.String("interface", dbusPropertiesInterface),
+			slog.String("path", string(upowerPath)), logger.Err(err))
+		return
+	}
+	sigCh := make(chan *dbus.Signal, signalBufferSize)
+	conn.Signal(sigCh)
+	defer conn.RemoveSignal(sigCh)
+
+	onBattery, err := conn.Object(upowerName, upowerPath).GetProperty(upowerName + "." + upowerOnBattery)
+	if err != nil {
+		s.logger.Debug("UPower not available, battery-aware polling disabled", logger.Err(err))
+		return
+	}
+	if value, ok := onBattery.Value().(bool); ok {
+		s.setOnBattery(value)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sgn, ok := <-sigCh:
+			if !ok {
+				return
+			}
+			if value, ok := onBatteryChanged(sgn); ok {
+				s.setOnBattery(value)
+			}
+		}
+	}
+}
+
+// onBatteryChanged extracts the OnBattery property from a PropertiesChanged signal of UPower. The second
+// return value is false, if the signal does not carry the property.
+func onBatteryChanged(sgn *dbus.Signal) (bool, bool) {
+	if sgn == nil || sgn.Name != dbusPropertiesChanged || sgn.Path != upowerPath || len(sgn.Body) < 2 {
+		return false, false
+	}
+	if iface, ok := sgn.Body[0].(string); !ok || iface != upowerName {
+		return false, false
+	}
+	changed, ok := sgn.Body[1].(map[string]dbus.Variant)
+	if !ok {
+		return false, false
+	}
+	variant, ok := changed[upowerOnBattery]
+	if !ok {
+		return false, false
+	}
+	onBattery, ok := variant.Value().(bool)
+	return onBattery, ok
+}
+
+// setOnBattery applies the power source to the weather update job and the poll periods of the geolocation
+// providers. The intervals are multiplied with the battery multiplier on battery power and restored on AC.
+func (s *Service) setOnBattery(onBattery bool) {
+	if s.onBattery.Swap(onBattery) == onBattery {
+		return
+	}
+
+	factor := 1.0
+	if onBattery {
+		factor = s.config.Intervals.BatteryMultiplier
+	}
+	interval := s.weatherUpdateInterval()
+	for _, j := range s.weatherJobs {
+		j.SetInterval(interval)
+	}
+	s.presenter.SetUpdateInterval(interval)
+	s.clientsLock.Lock()
+	for client := range s.clients {
+		client.currentView().presenter.SetUpdateInterval(interval)
+	}
+	s.clientsLock.Unlock()
+	for _, provider := range s.geoProviders {
+		if scaler, ok := provider.(geobus.PeriodScaler); ok {
+			scaler.ScalePeriod(factor)
+		}
+	}
+	s.logger.Info("power source changed, adjusted polling intervals", slog.Bool("on_battery", onBattery),
+		slog.Duration("weather_update", interval))
+}
+
+// weatherUpdateInterval returns the interval in which the weather data is updated, multiplied with the
+// battery multiplier while the system runs on battery power.
+func (s *Service) weatherUpdateInterval() time.Duration {
+	interval := s.config.Intervals.WeatherUpdate
+	if s.onBattery.Load() && s.config.Intervals.BatteryMultiplier > 1 {
+		interval = time.Duration(float64(interval) * s.config.Intervals.BatteryMultiplier)
+	}
+	return interval
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// A Client reconnects to the service after clientMinBackoff, which doubles with each failed attempt in
+	// a row up to clientMaxBackoff
+	clientMinBackoff = time.Second
+	clientMaxBackoff = 30 * time.Second
+)
+
+// Client subscribes to the output of a running service on its control socket and prints it like the
+// service would, so that several waybar modules share the geolocation lookups and weather fetches of a
+// single service. The service renders the output with the templates of the Client and keeps a view of
+// its own for it, which Toggle switches between the default and the alternative view.
+type Client struct {
+	socket    string
+	templates *ClientTemplates
+	output    io.Writer
+	logger    *logger.Logger
+
+	// alt is set while the alternative view is displayed
+	alt atomic.Bool
+	// toggled wakes up the connection to send the toggled view to the service
+	toggled chan struct{}
+}
+
+// NewClient returns a Client that subscribes on the control socket at the given path and prints the output
+// to w. If templates is nil, the service renders the output with its own templates.
+func NewClient(socket string, templates *ClientTemplates, w io.Writer, log *logger.Logger) *Client {
+	return &Client{
+		socket:    socket,
+		templates: templates,
+		output:    w,
+		logger:    log,
+		toggled:   make(chan struct{}, 1),
+	}
+}
+
+// Toggle switches the Client between the default and the alternative view. The view is kept when the
+// Client reconnects.
+func (c *Client) Toggle() {
+	alt := !c.alt.Load()
+	c.alt.Store(alt)
+	c.logger.Info("toggling display of weather module text and tooltip", slog.Bool("display_alternative", alt))
+	select {
+	case c.toggled <- struct{}{}:
+	default:
+	}
+}
+
+// Run prints the output of the service until the context is cancelled or the output pipe is broken. If the
+// service is not running or restarts, the Client reconnects with an increasing backoff. The latest output
+// stays visible meanwhile.
+func (c *Client) Run(ctx context.Context) {
+	backoff := clientMinBackoff
+	for {
+		subscribed, err := c.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// Waybar spawns a new client when it restarts, so the pipe of this one never accepts writes again
+		if errors.Is(err, syscall.EPIPE) {
+			c.logger.Info("output pipe is broken, the reader is gone, shutting down the client")
+			return
+		}
+		if subscribed {
+			backoff = clientMinBackoff
+		}
+		c.logger.Warn("lost the connection to the service, reconnecting", logger.Err(err),
+			slog.String("socket", c.socket), slog.Duration("retry_in", backoff))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, clientMaxBackoff)
+	}
+}
+
+// subscribe subscribes to the output of the service and prints the frames until the connection is lost,
+// the output pipe is broken or the context is cancelled. It reports whether the service accepted the
+// subscription.
+func (c *Client) subscribe(ctx context.Context) (bool, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to control socket: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	enc := json.NewEncoder(conn)
+	req := controlRequest{Command: controlCommandSubscribe, Templates: c.templates, Alt: c.alt.Load()}
+	if err = enc.Encode(req); err != nil {
+		return false, fmt.Errorf("failed to send subscribe request: %w", err)
+	}
+
+	var subscribed atomic.Bool
+	readErr := make(chan error, 1)
+	go func() {
+		dec := json.NewDecoder(conn)
+		for {
+			var frame clientFrame
+			if err := dec.Decode(&frame); err != nil {
+				readErr <- fmt.Errorf("failed to read output: %w", err)
+				return
+			}
+			if frame.Error != "" {
+				readErr <- fmt.Errorf("subscription rejected: %s", frame.Error)
+				return
+			}
+			subscribed.Store(true)
+			if _, err := c.output.Write(append(frame.Output, '\n')); err != nil {
+				if errors.Is(err, syscall.EPIPE) {
+					readErr <- fmt.Errorf("failed to print output: %w", err)
+					return
+				}
+				c.logger.Error("failed to print output", logger.Err(err))
+			}
+		}
+	}()
+
+	for {
+		select {
+		case err = <-readErr:
+			return subscribed.Load(), err
+		case <-c.toggled:
+			req = controlRequest{Command: controlCommandSetView, Alt: c.alt.Load()}
+			if err = enc.Encode(req); err != nil {
+				return subscribed.Load(), fmt.Errorf("failed to send view: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/presenter"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// ClientTemplates are the templates that the service renders the output of a Client with. Empty templates
+// are replaced with the templates of the service.
+type ClientTemplates struct {
+	Text           string `json:"text,omitempty"`
+	AltText        string `json:"alt_text,omitempty"`
+	Tooltip        string `json:"tooltip,omitempty"`
+	AltTooltip     string `json:"alt_tooltip,omitempty"`
+	TextNight      string `json:"text_night,omitempty"`
+	TooltipNight   string `json:"tooltip_night,omitempty"`
+	Pending        string `json:"pending,omitempty"`
+	Error          string `json:"error,omitempty"`
+	UseCSSIcon     bool   `json:"use_css_icon,omitempty"`
+	WorstCondition bool   `json:"worst_condition,omitempty"`
+	// ConditionIcons replaces the condition icons like the condition_icons setting of the templates
+	ConditionIcons map[string]string `json:"condition_icons,omitempty"`
+}
+
+// NewClientTemplates returns the templates of the config for a Client.
+func NewClientTemplates(conf *config.Config) *ClientTemplates {
+	return &ClientTemplates{
+		Text:           conf.Templates.Text,
+		AltText:        conf.Templates.AltText,
+		Tooltip:        conf.Templates.Tooltip,
+		AltTooltip:     conf.Templates.AltTooltip,
+		TextNight:      conf.Templates.TextNight,
+		TooltipNight:   conf.Templates.TooltipNight,
+		Pending:        conf.Templates.Pending,
+		Error:          conf.Templates.Error,
+		UseCSSIcon:     conf.Templates.UseCSSIcon,
+		WorstCondition: conf.Templates.WorstCondition,
+		ConditionIcons: conf.Templates.ConditionIcons,
+	}
+}
+
+// apply replaces the templates of the config with the non-empty templates.
+func (t *ClientTemplates) apply(conf *config.Config) {
+	for _, tpl := range []struct {
+		dst *string
+		src string
+	}{
+		{&conf.Templates.Text, t.Text},
+		{&conf.Templates.AltText, t.AltText},
+		{&conf.Templates.Tooltip, t.Tooltip},
+		{&conf.Templates.AltTooltip, t.AltTooltip},
+		{&conf.Templates.TextNight, t.TextNight},
+		{&conf.Templates.TooltipNight, t.TooltipNight},
+		{&conf.Templates.Pending, t.Pending},
+		{&conf.Templates.Error, t.Error},
+	} {
+		if tpl.src != "" {
+			*tpl.dst = tpl.src
+		}
+	}
+	conf.Templates.UseCSSIcon = t.UseCSSIcon
+	conf.Templates.WorstCondition = t.WorstCondition
+	conf.Templates.ConditionIcons = t.ConditionIcons
+}
+
+// clientFrame is a frame that the service sends to a subscribed Client. Each frame is a JSON object on a
+// line of its own. Output is the waybar JSON of a render, Error is set if the subscription was rejected.
+// Weather is the weather data of the render as encoded by weather.Encode, if the client requested it.
+type clientFrame struct {
+	Output  json.RawMessage `json:"output,omitempty"`
+	Weather json.RawMessage `json:"weather,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// renderView is what an output is rendered with: the config with the templates, the presenter of these
+// templates and whether the alternative view is displayed. The service prints its own view and renders
+// one view for each subscribed client.
+type renderView struct {
+	conf      *config.Config
+	presenter *presenter.Presenter
+	alt       bool
+}
+
+// subscriber is a client that subscribed to the output of the service on the control socket.
+type subscriber struct {
+	lock sync.Mutex
+	view renderView
+	// weather is set if the client requested the weather data in its frames.
+	weather bool
+	// frames holds the latest frame that was not sent to the client yet. A frame that the client didn't
+	// receive before the next render is replaced, since it is outdated anyway.
+	frames chan []byte
+}
+
+// currentView returns the view of the client.
+func (c *subscriber) currentView() renderView {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.view
+}
+
+// setAlt switches the view of the client between the default and the alternative view.
+func (c *subscriber) setAlt(alt bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.view.alt = alt
+}
+
+// send queues the frame for the client and replaces the frame that is still queued.
+func (c *subscriber) send(frame []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-c.frames:
+	default:
+	}
+	c.frames <- frame
+}
+
+// subscribe registers a client with the templates and view of the subscribe request. It fails if the
+// templates can't be parsed.
+func (s *Service) subscribe(req controlRequest) (*subscriber, error) {
+	conf := *s.config
+	if req.Templates != nil {
+		req.Templates.apply(&conf)
+	}
+	pres, err := presenter.New(&conf, s.t)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client templates: %w", err)
+	}
+	pres.Logger, pres.Clock = s.logger, s.presenter.Clock
+	pres.SetUpdateInterval(s.weatherUpdateInterval())
+
+	client := &subscriber{
+		view:    renderView{conf: &conf, presenter: pres, alt: req.Alt},
+		weather: req.Weather,
+		frames:  make(chan []byte, 1),
+	}
+	s.clientsLock.Lock()
+	if s.clients == nil {
+		s.clients = make(map[*subscriber]struct{})
+	}
+	s.clients[client] = struct{}{}
+	count := len(s.clients)
+	s.clientsLock.Unlock()
+	s.logger.Info("client subscribed to the output", slog.Int("clients", count))
+	return client, nil
+}
+
+// unsubscribe removes the client, so that it is no longer rendered for.
+func (s *Service) unsubscribe(client *subscriber) {
+	s.clientsLock.Lock()
+	delete(s.clients, client)
+	count := len(s.clients)
+	s.clientsLock.Unlock()
+	s.logger.Info("client unsubscribed from the output", slog.Int("clients", count))
+}
+
+// serveClient subscribes the client of the connection and sends it a frame for every render until the
+// client disconnects or the context is cancelled. Meanwhile, the client can switch its view with set_view
+// requests on the same connection.
+func (s *Service) serveClient(ctx context.Context, conn *net.UnixConn, dec *json.Decoder, req controlRequest) {
+	client, err := s.subscribe(req)
+	if err != nil {
+		s.logger.Warn("rejected client subscription", logger.Err(err))
+		if err = json.NewEncoder(conn).Encode(clientFrame{Error: err.Error()}); err != nil {
+			s.logger.Debug("failed to send control response", logger.Err(err))
+		}
+		return
+	}
+	defer s.unsubscribe(client)
+
+	// The subscription lasts until the client disconnects, so only the writes of the frames time out
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		s.logger.Error("failed to reset control connection deadline", logger.Err(err))
+		return
+	}
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			var viewReq controlRequest
+			if err := dec.Decode(&viewReq); err != nil {
+				return
+			}
+			if viewReq.Command != controlCommandSetView {
+				s.logger.Debug("ignoring unknown client command", slog.String("command", viewReq.Command))
+				continue
+			}
+			client.setAlt(viewReq.Alt)
+			s.renderClient(client)
+		}
+	}()
+
+	s.renderClient(client)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-disconnected:
+			return
+		case frame := <-client.frames:
+			if err = conn.SetWriteDeadline(time.Now().Add(controlTimeout)); err != nil {
+				s.logger.Error("failed to set control connection deadline", logger.Err(err))
+				return
+			}
+			if _, err = conn.Write(frame); err != nil {
+				s.logger.Debug("failed to send output to client", logger.Err(err))
+				return
+			}
+		}
+	}
+}
+
+// renderClients renders the current output for all subscribed clients.
+func (s *Service) renderClients() {
+	s.clientsLock.Lock()
+	clients := make([]*subscriber, 0, len(s.clients))
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.clientsLock.Unlock()
+	for _, client := range clients {
+		s.renderClient(client)
+	}
+}
+
+// renderClient renders the current weather data or placeholder with the view of the client and queues it
+// for the client. Nothing is queued while weather data is refreshed, like printPlaceholder does.
+func (s *Service) renderClient(client *subscriber) {
+	view := client.currentView()
+	s.weatherLock.RLock()
+	isSet := s.weatherIsSet
+	s.weatherLock.RUnlock()
+
+	var output outputData
+	var data *weather.Data
+	switch isSet {
+	case true:
+		var tplCtx presenter.TemplateContext
+		tplCtx, data = s.buildContext()
+		renderMap, err := view.presenter.Render(tplCtx)
+		if err != nil {
+			s.logger.Debug("failed to render weather template for client", logger.Err(err))
+		}
+		output = s.weatherOutput(tplCtx, renderMap, view)
+	default:
+		var ok bool
+		if output, ok = s.placeholderOutput(view); !ok {
+			return
+		}
+	}
+
+	line, err := json.Marshal(output)
+	if err != nil {
+		s.logger.Error("failed to encode client output", logger.Err(err))
+		return
+	}
+	next := clientFrame{Output: line}
+	if client.weather && data != nil {
+		if next.Weather, err = weather.Encode(data); err != nil {
+			s.logger.Error("failed to encode weather data for client", logger.Err(err))
+		}
+	}
+	frame, err := json.Marshal(next)
+	if err != nil {
+		s.logger.Error("failed to encode client frame", logger.Err(err))
+		return
+	}
+	client.send(append(frame, '\n'))
+}
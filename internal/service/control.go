@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// controlCommandStatus requests the Status of the service
+	controlCommandStatus = "status"
+	// controlCommandSubscribe subscribes a Client to the output of the service
+	controlCommandSubscribe = "subscribe"
+	// controlCommandSetView switches a subscribed Client between the default and the alternative view
+	controlCommandSetView = "set_view"
+	// controlTimeout is the time a client has to send its request and read the response
+	controlTimeout = 5 * time.Second
+	// controlSocketMode restricts the control socket to the user running the service
+	controlSocketMode = 0o600
+)
+
+// Render modes of the Status
+const (
+	RenderModePending = "pending"
+	RenderModeError   = "error"
+	RenderModeDefault = "default"
+	RenderModeAlt     = "alt"
+)
+
+// ErrControlSocketInUse is returned if another instance of the service listens on the control socket.
+var ErrControlSocketInUse = errors.New("control socket is in use by another instance")
+
+// Status is the state of a running service, as reported on the control socket.
+type Status struct {
+	// Time is the time the status was created at
+	Time time.Time `json:"time"`
+	// GeolocationSource is the geolocation provider of the latest location update and GeolocationAt the
+	// time of its result
+	GeolocationSource string    `json:"geolocation_source,omitempty"`
+	GeolocationAt     time.Time `json:"geolocation_at,omitzero"`
+	Latitude          float64   `json:"latitude"`
+	Longitude         float64   `json:"longitude"`
+	City              string    `json:"city,omitempty"`
+	DisplayName       string    `json:"display_name,omitempty"`
+	// WeatherProvider is the provider of the current weather data, or the selected provider if no weather
+	// data has been fetched yet
+	WeatherProvider  string    `json:"weather_provider,omitempty"`
+	WeatherFetchedAt time.Time `json:"weather_fetched_at,omitzero"`
+	// RenderMode is one of RenderModePending, RenderModeError, RenderModeDefault or RenderModeAlt
+	RenderMode string `json:"render_mode"`
+	// Errors holds the last error of each subsystem, e. g. "weather" or "geolocation/geoip"
+	Errors map[string]SubsystemError `json:"errors,omitempty"`
+	// RenderHistory holds the latest outputs, the oldest first
+	RenderHistory []RenderRecord `json:"render_history,omitempty"`
+}
+
+// SubsystemError is the last error of a subsystem of the service. At is zero if the time of the error is
+// not known.
+type SubsystemError struct {
+	Error string    `json:"error"`
+	At    time.Time `json:"at,omitzero"`
+}
+
+// controlRequest is a request on the control socket. Templates and Alt are only used by the subscribe
+// and set_view commands of a Client. Weather requests the weather data in the frames of a subscription.
+type controlRequest struct {
+	Command   string           `json:"command"`
+	Templates *ClientTemplates `json:"templates,omitempty"`
+	Alt       bool             `json:"alt,omitempty"`
+	Weather   bool             `json:"weather,omitempty"`
+}
+
+// controlResponse is the response to a controlRequest. Error is set if the request failed.
+type controlResponse struct {
+	Status *Status `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// QueryStatus requests the Status of the service that listens on the control socket at the given path.
+func QueryStatus(ctx context.Context, socket string) (Status, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to connect to control socket: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	deadline := time.Now().Add(controlTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return Status{}, fmt.Errorf("failed to set control socket deadline: %w", err)
+	}
+
+	if err = json.NewEncoder(conn).Encode(controlRequest{Command: controlCommandStatus}); err != nil {
+		return Status{}, fmt.Errorf("failed to send status request: %w", err)
+	}
+	var resp controlResponse
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Status{}, fmt.Errorf("failed to read status response: %w", err)
+	}
+	if resp.Error != "" {
+		return Status{}, fmt.Errorf("status request failed: %s", resp.Error)
+	}
+	if resp.Status == nil {
+		return Status{}, errors.New("status response holds no status")
+	}
+	return *resp.Status, nil
+}
+
+// startControl listens on the control socket, unless it is disabled. Since the control socket is optional,
+// a failure to listen is only logged and returned, so that a daemon can fail to start without it. The
+// listener is closed and the socket removed once the context is cancelled, which ends the subscriptions
+// of the clients as well.
+func (s *Service) startControl(ctx context.Context) error {
+	if s.config.Control.Disabled {
+		return nil
+	}
+
+	listener, err := listenControl(s.config.Control.Socket)
+	if err != nil {
+		s.logger.Warn("control socket not available", logger.Err(err),
+			slog.String("socket", s.config.Control.Socket))
+		return err
+	}
+	s.work.Go(func() {
+		<-ctx.Done()
+		if err := listener.Close(); err != nil {
+			s.logger.Error("failed to close control socket", logger.Err(err))
+		}
+	})
+	s.work.Go(func() { s.serveControl(ctx, listener) })
+	return nil
+}
+
+// listenControl listens on the unix socket at the given path with permissions for the user only. A stale
+// socket of a previous instance is replaced, while the socket of a running instance is left alone.
+func listenControl(socket string) (*net.UnixListener, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			_ = conn.Close()
+			return nil, ErrControlSocketInUse
+		}
+		if err = os.Remove(socket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale control socket: %w", err)
+		}
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err = os.Chmod(socket, controlSocketMode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set control socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// serveControl accepts the connections on the control socket until the listener is closed.
+func (s *Service) serveControl(ctx context.Context, listener *net.UnixListener) {
+	for {
+		conn, err := listener.AcceptUnix()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.logger.Error("failed to accept control connection", logger.Err(err))
+			continue
+		}
+		s.work.Go(func() { s.handleControl(ctx, conn) })
+	}
+}
+
+// handleControl answers the single request of a control connection. A subscription keeps the connection
+// open until the client disconnects or the context is cancelled.
+func (s *Service) handleControl(ctx context.Context, conn *net.UnixConn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err := conn.SetDeadline(time.Now().Add(controlTimeout)); err != nil {
+		s.logger.Error("failed to set control connection deadline", logger.Err(err))
+		return
+	}
+
+	var req controlRequest
+	var resp controlResponse
+	dec := json.NewDecoder(conn)
+	err := dec.Decode(&req)
+	switch {
+	case err != nil:
+		resp.Error = fmt.Sprintf("invalid request: %s", err)
+	case req.Command == controlCommandStatus:
+		status := s.status()
+		resp.Status = &status
+	case req.Command == controlCommandSubscribe:
+		s.serveClient(ctx, conn, dec, req)
+		return
+	default:
+		resp.Error = fmt.Sprintf("unknown command: %s", req.Command)
+	}
+	if err = json.NewEncoder(conn).Encode(resp); err != nil {
+		s.logger.Debug("failed to send control response", logger.Err(err))
+	}
+}
+
+// status returns the current Status of the service.
+func (s *Service) status() Status {
+	status := Status{Time: s.Clock.Now(), RenderMode: RenderModeDefault}
+
+	s.locationLock.RLock()
+	status.GeolocationSource, status.GeolocationAt = s.geoResult.Source, s.geoResult.At
+	status.Latitude, status.Longitude = s.location.Lat, s.location.Lon
+	status.City, status.DisplayName = s.address.City, s.address.DisplayName
+	s.locationLock.RUnlock()
+
+	s.weatherLock.RLock()
+	if s.weather != nil {
+		status.WeatherProvider, status.WeatherFetchedAt = s.weather.Source, s.weatherFetchedAt
+	}
+	switch {
+	case s.weather == nil && s.fetchErr != nil && s.fetchFailures >= int(s.config.Weather.FailureThreshold):
+		status.RenderMode = RenderModeError
+	case s.weather == nil:
+		status.RenderMode = RenderModePending
+	}
+	s.weatherLock.RUnlock()
+	if status.WeatherProvider == "" && s.weatherProv != nil {
+		status.WeatherProvider = s.weatherProv.Name()
+	}
+	s.displayAltLock.RLock()
+	if status.RenderMode == RenderModeDefault && s.displayAltText {
+		status.RenderMode = RenderModeAlt
+	}
+	s.displayAltLock.RUnlock()
+
+	s.errorsLock.RLock()
+	status.Errors = maps.Clone(s.lastErrors)
+	s.errorsLock.RUnlock()
+	if s.geoOrch != nil {
+		for name, health := range s.geoOrch.Health() {
+			if health.LastError == nil {
+				continue
+			}
+			if status.Errors == nil {
+				status.Errors = make(map[string]SubsystemError)
+			}
+			status.Errors["geolocation/"+name] = SubsystemError{Error: health.LastError.Error()}
+		}
+	}
+	status.RenderHistory = s.history.list()
+	return status
+}
+
+// recordError records the error as the last error of the subsystem for the Status.
+func (s *Service) recordError(subsystem string, err error) {
+	s.errorsLock.Lock()
+	defer s.errorsLock.Unlock()
+	if s.lastErrors == nil {
+		s.lastErrors = make(map[string]SubsystemError)
+	}
+	s.lastErrors[subsystem] = SubsystemError{Error: err.Error(), At: s.Clock.Now()}
+}
+
+// clearError removes the last error of the subsystem from the Status, once the subsystem recovered.
+func (s *Service) clearError(subsystem string) {
+	s.errorsLock.Lock()
+	defer s.errorsLock.Unlock()
+	delete(s.lastErrors, subsystem)
+}
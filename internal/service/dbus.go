@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/presenter"
+)
+
+const (
+	DBusName             = "dev.neessen.WaybarWeather"
+	DBusWeatherInterface = "dev.neessen.WaybarWeather"
+	DBusObjectPath       = dbus.ObjectPath("/dev/neessen/WaybarWeather")
+
+	dbusPropertiesInterface = "org.freedesktop.DBus.Properties"
+	dbusPropertiesChanged   = dbusPropertiesInterface + ".PropertiesChanged"
+)
+
+// errNoWeatherData is returned by GetWeatherJSON until the first weather data has been fetched.
+var errNoWeatherData = errors.New("no weather data available yet")
+
+// dbusObject is the object that exports the current weather data on the session bus. It implements the
+// org.freedesktop.DBus.Properties interface itself, so that all properties are announced in a single
+// PropertiesChanged signal after every successful weather fetch.
+type dbusObject struct {
+	conn   *dbus.Conn
+	logger *logger.Logger
+
+	lock   sync.RWMutex
+	props  map[string]dbus.Variant
+	tplCtx *presenter.TemplateContext
+}
+
+// startDBus connects to the session bus and exports the weather data under DBusName, if the D-Bus
+// service is enabled. Since the D-Bus service is optional, failures are only logged. The connection is
+// closed once the context is cancelled.
+func (s *Service) startDBus(ctx context.Context) {
+	if !s.config.DBus.Enabled {
+		return
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		s.logger.Error("failed to connect to session bus", logger.Err(err))
+		return
+	}
+	obj := &dbusObject{conn: conn, logger: s.logger, props: dbusProperties(presenter.TemplateContext{})}
+	if err = obj.export(); err != nil {
+		s.logger.Error("failed to export weather data on session bus", logger.Err(err))
+		_ = conn.Close()
+		return
+	}
+	reply, err := conn.RequestName(DBusName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		s.logger.Error("failed to acquire name on session bus", slog.String("name", DBusName),
+			logger.Err(err))
+		_ = conn.Close()
+		return
+	}
+	s.logger.Debug("exported weather data on session bus", slog.String("name", DBusName),
+		slog.String("path", string(DBusObjectPath)))
+
+	s.dbus = obj
+	s.work.Go(func() {
+		<-ctx.Done()
+		if err := conn.Close(); err != nil {
+			s.logger.Error("failed to close session bus connection", logger.Err(err))
+		}
+	})
+}
+
+// export exports the methods, the properties and the introspection data of the object.
+func (o *dbusObject) export() error {
+	methods := map[string]any{"GetWeatherJSON": o.getWeatherJSON}
+	if err := o.conn.ExportMethodTable(methods, DBusObjectPath, DBusWeatherInterface); err != nil {
+		return err
+	}
+	properties := map[string]any{"Get": o.get, "GetAll": o.getAll, "Set": o.set}
+	if err := o.conn.ExportMethodTable(properties, DBusObjectPath, dbusPropertiesInterface); err != nil {
+		return err
+	}
+	return o.conn.Export(introspect.NewIntrospectable(dbusIntrospection()), DBusObjectPath,
+		"org.freedesktop.DBus.Introspectable")
+}
+
+// publish updates the properties with the template context and emits the PropertiesChanged signal.
+func (o *dbusObject) publish(tplCtx presenter.TemplateContext) {
+	props := dbusProperties(tplCtx)
+	o.lock.Lock()
+	o.props = props
+	o.tplCtx = &tplCtx
+	o.lock.Unlock()
+
+	err := o.conn.Emit(DBusObjectPath, dbusPropertiesChanged, DBusWeatherInterface, props, []string{})
+	if err != nil {
+		o.logger.Error("failed to emit properties changed signal", logger.Err(err))
+	}
+}
+
+// getWeatherJSON returns the template context of the latest weather data as JSON.
+func (o *dbusObject) getWeatherJSON() (string, *dbus.Error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	if o.tplCtx == nil {
+		return "", dbus.MakeFailedError(errNoWeatherData)
+	}
+	data, err := json.Marshal(o.tplCtx)
+	if err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	return string(data), nil
+}
+
+func (o *dbusObject) get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if iface != DBusWeatherInterface {
+		return dbus.Variant{}, prop.ErrIfaceNotFound
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	value, ok := o.props[name]
+	if !ok {
+		return dbus.Variant{}, prop.ErrPropNotFound
+	}
+	return value, nil
+}
+
+func (o *dbusObject) getAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != DBusWeatherInterface {
+		return nil, prop.ErrIfaceNotFound
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	props := make(map[string]dbus.Variant, len(o.props))
+	for name, value := range o.props {
+		props[name] = value
+	}
+	return props, nil
+}
+
+func (o *dbusObject) set(iface, name string, _ dbus.Variant) *dbus.Error {
+	if _, err := o.get(iface, name); err != nil {
+		return err
+	}
+	return prop.ErrReadOnly
+}
+
+// dbusIntrospection returns the introspection data of the exported object.
+func dbusIntrospection() *introspect.Node {
+	iface := introspect.Interface{
+		Name: DBusWeatherInterface,
+		Methods: []introspect.Method{{
+			Name: "GetWeatherJSON",
+			Args: []introspect.Arg{{Name: "json", Type: "s", Direction: "out"}},
+		}},
+	}
+	props := dbusProperties(presenter.TemplateContext{})
+	for _, name := range []string{"Temperature", "Condition", "City", "UpdateTime"} {
+		iface.Properties = append(iface.Properties, introspect.Property{
+			Name:   name,
+			Type:   props[name].Signature().String(),
+			Access: "read",
+			Annotations: []introspect.Annotation{
+				{Name: "org.freedesktop.DBus.Property.EmitsChangedSignal", Value: "true"},
+			},
+		})
+	}
+	return &introspect.Node{
+		Name:       string(DBusObjectPath),
+		Interfaces: []introspect.Interface{prop.IntrospectData, iface},
+	}
+}
+
+// dbusProperties returns the exported properties for the template context. The update time is
+// exported as Unix timestamp.
+func dbusProperties(tplCtx presenter.TemplateContext) map[string]dbus.Variant {
+	var updateTime int64
+	if !tplCtx.UpdateTime.IsZero() {
+		updateTime = tplCtx.UpdateTime.Unix()
+	}
+	return map[string]dbus.Variant{
+		"Temperature": dbus.MakeVariant(tplCtx.Current.Temperature),
+		"Condition":   dbus.MakeVariant(tplCtx.Current.Condition),
+		"City":        dbus.MakeVariant(tplCtx.Address.City),
+		"UpdateTime":  dbus.MakeVariant(updateTime),
+	}
+}
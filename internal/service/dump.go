@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	// maxRawResponseSize is the maximum size of the raw provider response that is retained for debug dumps
+	maxRawResponseSize = 1 << 20
+	// dumpTimeFormat is the time format of the timestamp in the file name of debug dumps
+	dumpTimeFormat = "20060102-150405"
+)
+
+// debugDump holds everything that is involved in rendering the weather data, from the raw provider response
+// to the rendered templates. It allows to tell whether the provider, the mapping or a template is at fault.
+type debugDump struct {
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `json:"provider"`
+	// RawResponse is the raw JSON of the last provider response. It is a string if it was truncated.
+	RawResponse          any               `json:"raw_response,omitempty"`
+	RawResponseTruncated bool              `json:"raw_response_truncated,omitempty"`
+	Weather              *weather.Data     `json:"weather"`
+	Address              geocode.Address   `json:"address"`
+	Rendered             map[string]string `json:"rendered,omitempty"`
+	// RenderError holds the errors of the templates that failed to render
+	RenderError string `json:"render_error,omitempty"`
+	// RenderHistory holds the latest outputs that were printed to waybar, the oldest first
+	RenderHistory []RenderRecord `json:"render_history,omitempty"`
+}
+
+// writeDump writes a debug dump of the current state of the service into a timestamped file in the dump
+// directory. It returns the path of the written file.
+func (s *Service) writeDump() (string, error) {
+	now := s.Clock.Now()
+	dump := debugDump{CreatedAt: now, RenderHistory: s.history.list()}
+	if s.weatherProv != nil {
+		dump.Provider = s.weatherProv.Name()
+	}
+	if retainer, ok := s.weatherProv.(weather.RawResponseRetainer); ok {
+		raw, truncated := retainer.LastRawResponse()
+		switch {
+		case len(raw) == 0:
+		case json.Valid(raw):
+			dump.RawResponse = json.RawMessage(raw)
+		default:
+			dump.RawResponse = string(raw)
+		}
+		dump.RawResponseTruncated = truncated
+	}
+
+	s.weatherLock.RLock()
+	weatherIsSet := s.weather != nil
+	s.weatherLock.RUnlock()
+	tplCtx, weathr := s.buildContext()
+	dump.Weather = weathr
+	dump.Address = tplCtx.Address
+	if weatherIsSet {
+		rendered, err := s.presenter.Render(tplCtx)
+		if err != nil {
+			dump.RenderError = err.Error()
+		}
+		dump.Rendered = rendered
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode debug dump: %w", err)
+	}
+	if err = os.MkdirAll(s.config.Debug.DumpDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create dump directory: %w", err)
+	}
+	path := filepath.Join(s.config.Debug.DumpDir, "dump-"+now.Format(dumpTimeFormat)+".json")
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write debug dump: %w", err)
+	}
+	return path, nil
+}
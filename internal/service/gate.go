@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+)
+
+const (
+	// After a rate limit, the requests to a provider are paused for rateLimitMinBackoff, which doubles with
+	// each rate limit in a row up to rateLimitMaxBackoff
+	rateLimitMinBackoff = time.Minute
+	rateLimitMaxBackoff = time.Hour
+)
+
+var (
+	// ErrProviderDisabled is returned by providerGate.allow after the provider rejected its credentials
+	ErrProviderDisabled = errors.New("provider is disabled after it rejected its credentials")
+	// ErrProviderPaused is returned by providerGate.allow while the requests back off from a rate limit
+	ErrProviderPaused = errors.New("provider is paused after it exceeded its rate limit")
+)
+
+// providerGate decides whether a provider may be requested, depending on the kinds of its previous
+// errors. A provider that rejected its credentials is not requested again until restart, since retrying
+// won't help. After a rate limit, the requests are paused with an increasing backoff, which is reset by
+// the next successful request. Transient and other errors keep the gate open, so that the request is
+// retried as usual. The zero value is an open gate. providerGate is safe for concurrent use.
+type providerGate struct {
+	lock        sync.Mutex
+	disabled    bool
+	backoff     time.Duration
+	pausedUntil time.Time
+}
+
+// allow returns nil if the provider may be requested at the given time, otherwise the reason why not.
+func (g *providerGate) allow(now time.Time) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	switch {
+	case g.disabled:
+		return ErrProviderDisabled
+	case now.Before(g.pausedUntil):
+		return ErrProviderPaused
+	}
+	return nil
+}
+
+// fail records the failed request at the given time. It returns the kind of the error if it closed the
+// gate, ErrProviderDisabled if the gate was closed already, and nil otherwise. For rate limits, the
+// backoff until the next request is returned as well.
+func (g *providerGate) fail(err error, now time.Time) (time.Duration, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.disabled {
+		return 0, ErrProviderDisabled
+	}
+	switch kind := providererr.Kind(err); kind {
+	case providererr.ErrAuth:
+		g.disabled = true
+		return 0, kind
+	case providererr.ErrRateLimited:
+		g.backoff = min(max(g.backoff*2, rateLimitMinBackoff), rateLimitMaxBackoff)
+		g.pausedUntil = now.Add(g.backoff)
+		return g.backoff, kind
+	}
+	return 0, nil
+}
+
+// succeed records a successful request, which resets the rate limit backoff.
+func (g *providerGate) succeed() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.backoff = 0
+	g.pausedUntil = time.Time{}
+}
+
+// closeGate records the failed request of a provider in its gate and logs the error if it closed the
+// gate. Authentication errors are logged once at error level, since the provider is not requested again,
+// rate limits as warning along with the backoff. It reports whether the gate is closed, otherwise the
+// caller handles the error as usual.
+func (s *Service) closeGate(gate *providerGate, err error, msg string, args ...any) bool {
+	backoff, kind := gate.fail(err, s.Clock.Now())
+	switch kind {
+	case providererr.ErrAuth:
+		s.logger.Error(msg+", the provider rejected its credentials and is disabled until restart",
+			append(args, logger.Err(err))...)
+	case providererr.ErrRateLimited:
+		s.logger.Warn(msg+", the provider rate limit is exceeded",
+			append(args, logger.Err(err), slog.Duration("retry_in", backoff))...)
+	case ErrProviderDisabled:
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+)
+
+// matchGeofence returns the geofence that the coordinates are in. If they are in several overlapping
+// geofences, the one with the smallest radius is returned, since it is the more specific one.
+func matchGeofence(fences []config.Geofence, coords geobus.Coordinate) (config.Geofence, bool) {
+	var match config.Geofence
+	found := false
+	for _, fence := range fences {
+		center := geobus.Coordinate{Lat: fence.Latitude, Lon: fence.Longitude}
+		if coords.DistanceTo(center) > fence.Radius {
+			continue
+		}
+		if !found || fence.Radius < match.Radius {
+			match, found = fence, true
+		}
+	}
+	return match, found
+}
+
+// geofenceAddress returns the address of the coordinates within the geofence. The name of the geofence
+// replaces the reverse geocoded address, so that the geocoder is not requested.
+func geofenceAddress(fence config.Geofence, coords geobus.Coordinate) geocode.Address {
+	return geocode.Address{
+		AddressFound: true,
+		Latitude:     coords.Lat,
+		Longitude:    coords.Lon,
+		City:         fence.Name,
+		DisplayName:  fence.Name,
+		Label:        fence.Name,
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// RenderTrigger is the reason why the weather data was rendered
+type RenderTrigger string
+
+const (
+	// TriggerSchedule is the render of the output interval
+	TriggerSchedule RenderTrigger = "schedule"
+	// TriggerLocation is the render after a location change or weather update
+	TriggerLocation RenderTrigger = "location"
+	// TriggerSignal is the render after the USR1 signal toggled the alternative view
+	TriggerSignal RenderTrigger = "signal"
+	// TriggerResume is the render after the system resumed from sleep
+	TriggerResume RenderTrigger = "resume"
+)
+
+// RenderRecord is an output that was printed to waybar. Output is the JSON line that was printed.
+type RenderRecord struct {
+	Time    time.Time     `json:"time"`
+	Trigger RenderTrigger `json:"trigger"`
+	Output  string        `json:"output"`
+}
+
+// renderHistory is a ring buffer of the latest outputs. Only the printed JSON lines are stored, so that
+// its memory use is bounded by the number of records and the size of the outputs. A nil renderHistory
+// records nothing.
+type renderHistory struct {
+	lock    sync.Mutex
+	records []RenderRecord
+	// next is the index the next record is stored at
+	next int
+	full bool
+}
+
+// newRenderHistory returns a render history that holds the given number of records. It returns nil if the
+// size is zero.
+func newRenderHistory(size uint) *renderHistory {
+	if size == 0 {
+		return nil
+	}
+	return &renderHistory{records: make([]RenderRecord, size)}
+}
+
+// add stores the record and replaces the oldest record if the history is full.
+func (h *renderHistory) add(record RenderRecord) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns a copy of the records, the oldest first.
+func (h *renderHistory) list() []RenderRecord {
+	if h == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.full {
+		return append([]RenderRecord(nil), h.records[:h.next]...)
+	}
+	return append(append([]RenderRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// maxStartupDelay is the upper bound of the random delay of the initial geolocation lookup and weather
+// fetch. It keeps a large jitter from leaving the module without weather data for a long time.
+const maxStartupDelay = 30 * time.Second
+
+// jitter returns a random duration between zero and the configured jitter. It returns zero if the jitter
+// is disabled.
+func (s *Service) jitter() time.Duration {
+	return s.randomDuration(s.config.Intervals.Jitter)
+}
+
+// fetchedWeatherJitter returns the jitter of the current weather data, which delays its expiry.
+func (s *Service) fetchedWeatherJitter() time.Duration {
+	s.weatherLock.RLock()
+	defer s.weatherLock.RUnlock()
+	return s.weatherJitter
+}
+
+// randomDuration returns a random duration between zero and the given bound.
+func (s *Service) randomDuration(bound time.Duration) time.Duration {
+	if bound <= 0 {
+		return 0
+	}
+	s.randLock.Lock()
+	defer s.randLock.Unlock()
+	return time.Duration(s.Rand.Int64N(int64(bound)))
+}
+
+// awaitStartupDelay waits for a random delay between zero and the configured jitter, but at most
+// maxStartupDelay. It returns early if the context is cancelled.
+func (s *Service) awaitStartupDelay(ctx context.Context) {
+	delay := s.randomDuration(min(s.config.Intervals.Jitter, maxStartupDelay))
+	if delay <= 0 {
+		return
+	}
+	s.logger.Debug("delaying initial weather fetch", slog.Duration("delay", delay))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+// locationStagger is the delay between the weather requests of the additional locations, so that we
+// are polite to the weather provider API.
+const locationStagger = 2 * time.Second
+
+// fetchLocationsWeather retrieves the weather data for all configured additional locations. The requests
+// are staggered by locationStagger. A location that fails to resolve or fetch is logged and skipped, so
+// that it does not affect the other locations or the main output. Every fetched location triggers an
+// output, so that it is shown without waiting for the next update of the main weather data.
+func (s *Service) fetchLocationsWeather(ctx context.Context) {
+	for i, loc := range s.config.Locations {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(locationStagger):
+			}
+		}
+
+		coords, err := s.resolveLocation(ctx, loc)
+		if err != nil {
+			s.logger.Error("failed to resolve additional location", logger.Err(err),
+				slog.String("location", loc.Name))
+			s.recordError("locations/"+loc.Name, err)
+			continue
+		}
+		if err = s.weatherGate.allow(s.Clock.Now()); err != nil {
+			s.logger.Debug("skipping weather update of the additional locations", logger.Err(err),
+				slog.String("source", s.weatherProv.Name()))
+			return
+		}
+		data, err := s.weatherProv.GetWeather(ctx, s.outboundCoordinates(coords))
+		if err != nil {
+			if !s.closeGate(&s.weatherGate, err, "failed to fetch weather data for additional location",
+				slog.String("location", loc.Name), slog.String("source", s.weatherProv.Name())) {
+				s.logger.Error("failed to fetch weather data for additional location", logger.Err(err),
+					slog.String("location", loc.Name), slog.String("source", s.weatherProv.Name()))
+			}
+			s.recordError("locations/"+loc.Name, err)
+			continue
+		}
+		s.weatherGate.succeed()
+		if !s.validateWeather(data, slog.String("location", loc.Name),
+			slog.String("source", s.weatherProv.Name())) {
+			continue
+		}
+		data.ResolveIsDay()
+
+		s.locationsLock.Lock()
+		if kept := data.Merge(s.locations[loc.Name], s.config.Weather.ForecastMaxAge, s.Clock.Now()); kept > 0 {
+			s.logger.Debug("kept forecast entries missing in the fetched weather data", slog.Int("entries", kept),
+				slog.String("location", loc.Name))
+		}
+		s.locations[loc.Name] = data
+		s.locationsLock.Unlock()
+		s.clearError("locations/" + loc.Name)
+		s.logger.Debug("weather data for additional location fetched successfully",
+			slog.String("location", loc.Name))
+		s.requestRender(TriggerSchedule)
+	}
+}
+
+// resolveLocation returns the coordinates for the given location. If the location is configured with a
+// city name, the coordinates are looked up using the geocoder.
+func (s *Service) resolveLocation(ctx context.Context, loc config.Location) (geobus.Coordinate, error) {
+	if loc.City == "" {
+		return geobus.Coordinate{Lat: loc.Latitude, Lon: loc.Longitude}, nil
+	}
+	coords, err := s.geocoder.Search(ctx, loc.City)
+	if err != nil {
+		return coords, fmt.Errorf("failed to look up city %q: %w", loc.City, err)
+	}
+	return coords, nil
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/vorlif/spreak"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/presenter"
+)
+
+const (
+	notificationsName   = "org.freedesktop.Notifications"
+	notificationsPath   = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsNotify = notificationsName + ".Notify"
+	notificationAppName = "waybar-weather"
+	// notificationLookahead is how far ahead the forecast is checked for a change of the weather condition
+	notificationLookahead = time.Hour
+)
+
+// notificationIcons maps the weather categories to the icon names of the freedesktop icon naming
+// specification.
+var notificationIcons = map[string]string{
+	"clear":        "weather-clear",
+	"cloudy":       "weather-overcast",
+	"fog":          "weather-fog",
+	"rain":         "weather-showers",
+	"snow":         "weather-snow",
+	"thunderstorm": "weather-storm",
+}
+
+// notifier sends desktop notifications over the session bus when the weather condition of the next hour
+// changes to one of the configured categories.
+type notifier struct {
+	conn       *dbus.Conn
+	logger     *logger.Logger
+	presenter  *presenter.Presenter
+	t          *spreak.Localizer
+	categories []string
+
+	lock sync.Mutex
+	// upcoming is the category of the condition change that the previous fetch found
+	upcoming string
+	// notified identifies the last notified condition change by its category and start hour
+	notified string
+	// id is the ID of the last notification, which is replaced by the next notification
+	id uint32
+}
+
+// startNotifier connects to the session bus for the desktop notifications, if they are enabled. Since
+// the notifications are optional, a missing session bus is only logged. The connection is closed once
+// the context is cancelled.
+func (s *Service) startNotifier(ctx context.Context) {
+	if !s.config.Notifications.Enabled {
+		return
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		s.logger.Debug("session bus not available, notifications disabled", logger.Err(err))
+		return
+	}
+	s.notifier = &notifier{
+		conn:       conn,
+		logger:     s.logger,
+		presenter:  s.presenter,
+		t:          s.t,
+		categories: s.config.Notifications.Categories,
+	}
+	s.work.Go(func() {
+		<-ctx.Done()
+		if err := conn.Close(); err != nil {
+			s.logger.Error("failed to close session bus connection", logger.Err(err))
+		}
+	})
+}
+
+// check compares the condition change within the next hour with the one of the previous fetch and sends
+// a notification if it is new. The same condition change is notified only once.
+func (n *notifier) check(ctx context.Context, tplCtx presenter.TemplateContext, now time.Time) {
+	change, found := upcomingChange(tplCtx, now, n.categories)
+
+	n.lock.Lock()
+	previous := n.upcoming
+	n.upcoming = change.Category
+	key := change.Category + "@" + change.InstantTime.Format(time.RFC3339)
+	if !found || change.Category == previous || key == n.notified {
+		n.lock.Unlock()
+		return
+	}
+	n.notified = key
+	replaces := n.id
+	n.lock.Unlock()
+
+	id, err := n.send(ctx, change, replaces)
+	if err != nil {
+		n.logger.Debug("failed to send notification", logger.Err(err),
+			slog.String("category", change.Category))
+		return
+	}
+	n.lock.Lock()
+	n.id = id
+	n.lock.Unlock()
+}
+
+// send sends the notification about the condition change. It replaces the notification with the given
+// ID, if it is still shown, and returns the ID of the new notification.
+func (n *notifier) send(ctx context.Context, change presenter.WeatherView, replaces uint32) (uint32, error) {
+	summary := change.ConditionIcon + " " + change.Condition
+	body := n.t.Getf("Expected from %s", n.presenter.FormatTime(change.InstantTime))
+
+	var id uint32
+	err := n.conn.Object(notificationsName, notificationsPath).CallWithContext(ctx, notificationsNotify, 0,
+		notificationAppName, replaces, notificationIcons[change.Category], summary, body, []string{},
+		map[string]dbus.Variant{}, int32(-1)).Store(&id)
+	return id, err
+}
+
+// upcomingChange returns the first forecast within the notification lookahead whose weather category
+// differs from the current one and is one of the given categories. The second return value is false, if
+// there is no such forecast.
+func upcomingChange(tplCtx presenter.TemplateContext, now time.Time,
+	categories []string,
+) (presenter.WeatherView, bool) {
+	for _, view := range tplCtx.Forecasts {
+		if !view.InstantTime.After(now) {
+			continue
+		}
+		if view.InstantTime.After(now.Add(notificationLookahead)) {
+			break
+		}
+		if view.Category != tplCtx.Current.Category && slices.Contains(categories, view.Category) {
+			return view.WeatherView, true
+		}
+	}
+	return presenter.WeatherView{}, false
+}
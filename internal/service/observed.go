@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/presenter"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// observedTemperature is a temperature of the current weather and the time it was observed.
+type observedTemperature struct {
+	Temperature float64   `json:"temperature"`
+	Unit        string    `json:"unit"`
+	At          time.Time `json:"at"`
+}
+
+// observedExtremes are the lowest and highest temperature of the current weather that were observed across
+// the weather fetches of a calendar day. Day is the date in the time zone of the location.
+type observedExtremes struct {
+	Day  string              `json:"day"`
+	Low  observedTemperature `json:"low"`
+	High observedTemperature `json:"high"`
+}
+
+// observationDay returns the calendar day of the time in the time zone of the weather data.
+func observationDay(data *weather.Data, at time.Time) string {
+	return at.In(data.Location()).Format(time.DateOnly)
+}
+
+// observe records the current temperature of the weather data in the observed extremes of the day. The
+// extremes start over with the first observation of a new day and if the temperature unit changed, e. g.
+// since automatic units switched the unit system. It reports whether the extremes changed.
+func (s *Service) observe(data *weather.Data) bool {
+	at := data.Current.InstantTime
+	if at.IsZero() {
+		at = s.Clock.Now()
+	}
+	obs := observedTemperature{Temperature: data.Current.Temperature, Unit: data.Current.Units.Temperature, At: at}
+	day := observationDay(data, at)
+
+	s.observedLock.Lock()
+	defer s.observedLock.Unlock()
+	switch {
+	case day < s.observed.Day:
+		// An observation of the previous day that arrived after midnight
+		return false
+	case day != s.observed.Day || obs.Unit != s.observed.Low.Unit:
+		s.observed = observedExtremes{Day: day, Low: obs, High: obs}
+	case obs.Temperature < s.observed.Low.Temperature:
+		s.observed.Low = obs
+	case obs.Temperature > s.observed.High.Temperature:
+		s.observed.High = obs
+	default:
+		return false
+	}
+	return true
+}
+
+// setObserved sets the observed extremes in the template context, if they were observed on the current
+// day of the weather data. After midnight, they are omitted until the first observation of the new day.
+func (s *Service) setObserved(tplCtx *presenter.TemplateContext, data *weather.Data) {
+	if data == nil {
+		return
+	}
+	s.observedLock.Lock()
+	observed := s.observed
+	s.observedLock.Unlock()
+	if observed.Day == "" || observed.Day != observationDay(data, s.Clock.Now()) {
+		return
+	}
+	s.presenter.SetObserved(tplCtx, presenter.Observation(observed.Low), presenter.Observation(observed.High))
+}
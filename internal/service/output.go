@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	// outputRetryInterval is the interval in which the latest output is written again while the output
+	// pipe is broken.
+	outputRetryInterval = time.Second
+	// stdoutBrokenTimeout is how long stdout may stay broken before the service shuts down. Waybar spawns
+	// a new process when it restarts, so the pipe of the previous one never accepts writes again.
+	stdoutBrokenTimeout = 30 * time.Second
+)
+
+// errOutputClosed is returned by retry if the output pipe stayed broken for longer than the timeout
+var errOutputClosed = errors.New("output pipe stayed broken, the reader is gone")
+
+// outputWriter wraps the writer that the waybar JSON is printed to. If a write fails with a broken pipe,
+// e. g. because waybar restarted, the latest output is kept in memory and written again in the retry
+// interval. As soon as the pipe accepts writes again, the latest output is emitted right away instead of
+// waiting for the next output interval. If a broken timeout is set, the pipe is given up on once it stayed
+// broken for that long.
+type outputWriter struct {
+	logger *logger.Logger
+	// brokenTimeout is how long the pipe may stay broken before retry gives up. Zero retries forever.
+	brokenTimeout time.Duration
+
+	lock   sync.Mutex
+	writer io.Writer
+	latest []byte
+	broken bool
+	// brokenCh wakes up retry when the pipe breaks
+	brokenCh chan struct{}
+}
+
+// newOutputWriter returns a new outputWriter that writes to w.
+func newOutputWriter(w io.Writer, log *logger.Logger) *outputWriter {
+	return &outputWriter{
+		logger:   log,
+		writer:   w,
+		brokenCh: make(chan struct{}, 1),
+	}
+}
+
+// newStdoutWriter returns a new outputWriter that writes to stdout and gives up on it, once it stayed broken
+// for the stdout broken timeout.
+func newStdoutWriter(log *logger.Logger) *outputWriter {
+	out := newOutputWriter(os.Stdout, log)
+	out.brokenTimeout = stdoutBrokenTimeout
+	return out
+}
+
+// Write writes p and keeps it as the latest output. While the pipe is broken, no error is returned,
+// since the latest output is written as soon as the pipe accepts writes again.
+func (o *outputWriter) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.latest = append(o.latest[:0], p...)
+	if err := o.write(); err != nil && !o.broken {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Reconnect replaces the underlying writer, e. g. with a fresh pipe, and emits the latest output to it
+// right away.
+func (o *outputWriter) Reconnect(w io.Writer) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.writer = w
+	if len(o.latest) == 0 {
+		o.broken = false
+		return nil
+	}
+	return o.write()
+}
+
+// retry writes the latest output again in the retry interval while the pipe is broken, until the context
+// is cancelled. It returns errOutputClosed if the pipe stayed broken for longer than the broken timeout.
+func (o *outputWriter) retry(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-o.brokenCh:
+		}
+
+		brokenSince := time.Now()
+		ticker := time.NewTicker(outputRetryInterval)
+		for broken := true; broken; {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return nil
+			case <-ticker.C:
+			}
+			o.lock.Lock()
+			if o.broken {
+				_ = o.write()
+			}
+			broken = o.broken
+			o.lock.Unlock()
+			if broken && o.brokenTimeout > 0 && time.Since(brokenSince) >= o.brokenTimeout {
+				ticker.Stop()
+				return errOutputClosed
+			}
+		}
+		ticker.Stop()
+	}
+}
+
+// write writes the latest output and keeps track of whether the pipe is broken. The caller must hold
+// the lock.
+func (o *outputWriter) write() error {
+	_, err := o.writer.Write(o.latest)
+	switch {
+	case errors.Is(err, syscall.EPIPE):
+		if !o.broken {
+			o.logger.Warn("output pipe is broken, keeping the latest output until it can be written again")
+			o.broken = true
+			select {
+			case o.brokenCh <- struct{}{}:
+			default:
+			}
+		}
+		return err
+	case err != nil:
+		return err
+	}
+
+	if o.broken {
+		o.logger.Info("output pipe accepts writes again, emitted the latest output")
+		o.broken = false
+	}
+	return nil
+}
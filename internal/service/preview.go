@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// previewSample holds the sample address and weather data of the preview in the format of the debug dumps
+//
+//go:embed preview_sample.json
+var previewSample []byte
+
+// PreviewFields are the rendered templates of a preview in the order they are printed.
+var PreviewFields = []string{"text", "alt_text", "tooltip", "alt_tooltip"}
+
+// PreviewOptions selects the weather data that the templates are rendered against in a preview.
+type PreviewOptions struct {
+	// Sample renders the templates against the embedded sample weather data instead of live data
+	Sample bool
+	// City is looked up with the geocoder to get the location of the live data. If it is empty, the
+	// coordinates are used.
+	City        string
+	Coordinates geobus.Coordinate
+}
+
+// Preview renders the templates once against the embedded sample weather data or a single fetch of the
+// live weather data for the given location, without starting the service. It returns the rendered
+// templates, keyed by the names in PreviewFields.
+func (s *Service) Preview(ctx context.Context, opts PreviewOptions) (map[string]string, error) {
+	if opts.Sample {
+		addr, data, err := sampleWeather(s.Clock.Now())
+		if err != nil {
+			return nil, err
+		}
+		s.setPreviewState(addr, data)
+	} else if err := s.fetchPreview(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	tplCtx, _ := s.buildContext()
+	return s.presenter.Render(tplCtx)
+}
+
+// fetchPreview resolves the location of the preview and fetches its address and weather data once.
+func (s *Service) fetchPreview(ctx context.Context, opts PreviewOptions) error {
+	geocoder, err := s.selectGeocodeProvider(s.config, s.logger, s.t.Language())
+	if err != nil {
+		return fmt.Errorf("failed to create geocode provider: %w", err)
+	}
+	s.geocoder = geocoder
+	weatherProv, err := s.selectWeatherProvider()
+	if err != nil {
+		return fmt.Errorf("failed to create weather provider: %w", err)
+	}
+	s.weatherProv = weatherProv
+
+	coords, err := s.resolveLocation(ctx, config.Location{
+		City: opts.City, Latitude: opts.Coordinates.Lat, Longitude: opts.Coordinates.Lon,
+	})
+	if err != nil {
+		return err
+	}
+	if !coords.Valid() {
+		return fmt.Errorf("invalid coordinates: %f, %f", coords.Lat, coords.Lon)
+	}
+	addr, err := s.geocoder.Reverse(ctx, s.outboundCoordinates(coords))
+	if err != nil {
+		return fmt.Errorf("failed reverse geocode coordinates: %w", err)
+	}
+	if !addr.AddressFound {
+		addr = geocode.Address{Latitude: coords.Lat, Longitude: coords.Lon}
+	}
+
+	s.locationLock.Lock()
+	s.location, s.address, s.locationIsSet = coords, addr, true
+	s.locationLock.Unlock()
+	if !s.updateWeather(ctx) {
+		s.weatherLock.RLock()
+		defer s.weatherLock.RUnlock()
+		return fmt.Errorf("failed to fetch weather data: %w", s.fetchErr)
+	}
+	return nil
+}
+
+// setPreviewState stores the address and weather data of the preview in the service state.
+func (s *Service) setPreviewState(addr geocode.Address, data *weather.Data) {
+	s.locationLock.Lock()
+	s.location = data.Coordinates
+	s.address, s.locationIsSet = addr, true
+	s.locationLock.Unlock()
+	s.weatherLock.Lock()
+	s.weather, s.weatherIsSet = data, true
+	s.weatherLock.Unlock()
+}
+
+// sampleWeather returns the embedded sample address and weather data. The times of the weather data are
+// moved to the hour of the given time, so that the forecast functions of the templates find their data.
+func sampleWeather(now time.Time) (geocode.Address, *weather.Data, error) {
+	var sample struct {
+		Address geocode.Address `json:"address"`
+		Weather json.RawMessage `json:"weather"`
+	}
+	if err := json.Unmarshal(previewSample, &sample); err != nil {
+		return geocode.Address{}, nil, fmt.Errorf("failed to decode sample weather data: %w", err)
+	}
+	if len(sample.Weather) == 0 || string(sample.Weather) == "null" {
+		return geocode.Address{}, nil, errors.New("sample weather data is empty")
+	}
+	data, err := weather.Decode(sample.Weather)
+	if err != nil {
+		return geocode.Address{}, nil, fmt.Errorf("failed to decode sample weather data: %w", err)
+	}
+
+	shift := now.Truncate(time.Hour).Sub(data.Current.InstantTime)
+	data.GeneratedAt = data.GeneratedAt.Add(shift)
+	data.Current.InstantTime = data.Current.InstantTime.Add(shift)
+	forecast := make(map[weather.DayHour]weather.Instant, len(data.Forecast))
+	for _, instant := range data.Forecast {
+		instant.InstantTime = instant.InstantTime.Add(shift)
+		forecast[data.DayHour(instant.InstantTime)] = instant
+	}
+	data.Forecast = forecast
+	return sample.Address, data, nil
+}
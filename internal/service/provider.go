@@ -6,6 +6,8 @@ package service
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
 	"strings"
 
 	"golang.org/x/text/language"
@@ -20,16 +22,31 @@ import (
 	"github.com/wneessen/waybar-weather/internal/geobus/provider/ichnaea"
 	"github.com/wneessen/waybar-weather/internal/geocode"
 	geocodeearth "github.com/wneessen/waybar-weather/internal/geocode/provider/geocode-earth"
+	"github.com/wneessen/waybar-weather/internal/geocode/provider/googlemaps"
+	"github.com/wneessen/waybar-weather/internal/geocode/provider/none"
 	"github.com/wneessen/waybar-weather/internal/geocode/provider/opencage"
 	nominatim "github.com/wneessen/waybar-weather/internal/geocode/provider/osm-nominatim"
+	"github.com/wneessen/waybar-weather/internal/geocode/provider/photon"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
 	"github.com/wneessen/waybar-weather/internal/weather"
 	openmeteo "github.com/wneessen/waybar-weather/internal/weather/provider/open-meteo"
+	"github.com/wneessen/waybar-weather/internal/weather/provider/pirateweather"
+	"github.com/wneessen/waybar-weather/internal/weather/provider/wttr"
 )
 
+// newHTTPClient returns a new HTTP client for the API requests of the providers. The clients share the
+// transport of the service with each other and the connectivity probe, so that they reuse each other's idle
+// connections. If the service has an HTTPTransport set, it replaces the shared transport.
+func (s *Service) newHTTPClient(log *logger.Logger) *http.Client {
+	if s.HTTPTransport != nil {
+		return http.NewWithTransport(log, s.HTTPTransport)
+	}
+	return http.NewWithTransport(log, s.transport)
+}
+
 func (s *Service) selectGeobusProviders() ([]geobus.Provider, error) {
-	httpClient := http.New(s.logger)
+	httpClient := s.newHTTPClient(s.logger)
 	var provider []geobus.Provider
 
 	if !s.config.GeoLocation.DisableGeolocationFile {
@@ -37,11 +54,13 @@ func (s *Service) selectGeobusProviders() ([]geobus.Provider, error) {
 	}
 
 	if !s.config.GeoLocation.DisableCitynameFile {
-		cnf, err := cityname_file.NewCitynameFileProvider(s.config.GeoLocation.CitynameFile, s.geocoder)
+		cnf, err := s.newCitynameFileProvider()
 		if err != nil {
 			return nil, fmt.Errorf("failed to create cityname file provider: %w", err)
 		}
-		provider = append(provider, cnf)
+		if cnf != nil {
+			provider = append(provider, cnf)
+		}
 	}
 
 	if !s.config.GeoLocation.DisableGPSD {
@@ -49,7 +68,8 @@ func (s *Service) selectGeobusProviders() ([]geobus.Provider, error) {
 	}
 
 	if !s.config.GeoLocation.DisableGeoIP {
-		gip, err := geoip.NewGeolocationGeoIPProvider(httpClient)
+		gip, err := geoip.NewGeolocationGeoIPProvider(httpClient, s.config.GeoLocation.GeoIPBackend,
+			s.config.GeoLocation.GeoIPToken)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create GeoIP provider: %w", err)
 		}
@@ -79,40 +99,119 @@ func (s *Service) selectGeobusProviders() ([]geobus.Provider, error) {
 	return provider, nil
 }
 
+// newCitynameFileProvider creates the cityname file provider, which looks up the city names with the
+// geocoder of the service, so that the lookups share its cache. Without geocoder, the city names can't be
+// looked up, which fails if the cityname file is present. Otherwise, nil is returned, since the provider
+// would never find a location.
+func (s *Service) newCitynameFileProvider() (*cityname_file.CitynameFileProvider, error) {
+	path := s.config.GeoLocation.CitynameFile
+	if s.geocoder != nil && s.geocoder.Name() == none.Name {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("cityname file %q requires a geocoder that supports city name lookups, "+
+				"but the geocoder is disabled", path)
+		}
+		s.logger.Debug("skipping cityname file provider, since the geocoder is disabled",
+			slog.String("path", path))
+		return nil, nil
+	}
+	return cityname_file.NewCitynameFileProvider(path, s.geocoder)
+}
+
 func (s *Service) selectGeocodeProvider(conf *config.Config, log *logger.Logger, lang language.Tag) (geocode.Geocoder, error) {
 	var geocoder geocode.Geocoder
 
 	switch strings.ToLower(conf.GeoCoder.Provider) {
+	case none.Name:
+		// Nothing to cache, since no geocoding service is contacted
+		return none.New(int(conf.GeoCoder.CoordinatePrecision)), nil
 	case "nominatim":
-		geocoder = geocode.NewCachedGeocoder(nominatim.New(http.New(log), lang), cacheHitTTL, cacheMissTTL)
+		geocoder = nominatim.New(s.newHTTPClient(log), lang)
 	case "opencage":
 		if conf.GeoCoder.APIKey == "" {
 			return nil, fmt.Errorf("opencage geocoder requires an API key")
 		}
-		geocoder = geocode.NewCachedGeocoder(opencage.New(http.New(log), lang, conf.GeoCoder.APIKey),
-			cacheHitTTL, cacheMissTTL)
+		geocoder = opencage.New(s.newHTTPClient(log), lang, conf.GeoCoder.APIKey)
 	case "geocode-earth":
 		if conf.GeoCoder.APIKey == "" {
 			return nil, fmt.Errorf("geocode-earth geocoder requires an API key")
 		}
-		geocoder = geocode.NewCachedGeocoder(geocodeearth.New(http.New(log), lang, conf.GeoCoder.APIKey),
-			cacheHitTTL, cacheMissTTL)
+		geocoder = geocodeearth.New(s.newHTTPClient(log), lang, conf.GeoCoder.APIKey)
+	case "photon":
+		geocoder = photon.New(s.newHTTPClient(log), lang, conf.GeoCoder.BaseURL)
+	case "google":
+		if conf.GeoCoder.APIKey == "" {
+			return nil, fmt.Errorf("google geocoder requires an API key")
+		}
+		geocoder = googlemaps.New(s.newHTTPClient(log), lang, conf.GeoCoder.APIKey)
 	default:
 		return nil, fmt.Errorf("unsupported geocoder type: %s", conf.GeoCoder.Provider)
 	}
 
-	return geocoder, nil
+	var cached *geocode.CachedGeocoder
+	if conf.GeoCoder.DiskCache {
+		cached = geocode.NewDiskCachedGeocoder(geocoder, cacheHitTTL, cacheMissTTL, conf.GeoCoder.DiskCacheFile, log)
+	} else {
+		cached = geocode.NewCachedGeocoder(geocoder, cacheHitTTL, cacheMissTTL)
+	}
+	cached.SetSearchTTL(searchCacheHitTTL, searchCacheMissTTL)
+	return cached, nil
+}
+
+// selectWeatherProvider creates the configured weather providers. If more than one provider is configured,
+// they are combined into a failover provider that tries them in the configured order.
+func (s *Service) selectWeatherProvider() (weather.Provider, error) {
+	providers := make([]weather.Provider, 0, len(s.config.Weather.Provider))
+	for _, name := range s.config.Weather.Provider {
+		provider, err := s.newWeatherProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		if retainer, ok := provider.(weather.RawResponseRetainer); ok && s.config.Debug.Enabled {
+			retainer.RetainRawResponse(maxRawResponseSize)
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return weather.NewFailover(s.logger, s.config.Weather.ProbeInterval, s.config.Weather.FailoverThreshold,
+		providers...)
 }
 
-func (s *Service) selectWeatherProvider() (provider weather.Provider, err error) {
-	switch strings.ToLower(s.config.Weather.Provider) {
+// newWeatherProvider creates the weather provider with the given name.
+func (s *Service) newWeatherProvider(name string) (provider weather.Provider, err error) {
+	switch strings.ToLower(name) {
 	case "open-meteo":
-		provider, err = openmeteo.New(http.New(s.logger), s.logger, s.config.Units)
+		meteo, err := openmeteo.New(s.newHTTPClient(s.logger), s.logger, s.config.Units)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Open-Meteo weather provider: %w", err)
+		}
+		meteo.SetUnits(s.config.UnitPreferences())
+		meteo.SetModel(s.config.Weather.Model)
+		if s.config.Weather.Elevation != nil {
+			meteo.SetElevation(*s.config.Weather.Elevation)
+		}
+		meteo.SetNowcast(s.config.Weather.Nowcast)
+		provider = meteo
+	case "wttr":
+		wttrProvider, err := wttr.New(s.newHTTPClient(s.logger), s.logger, s.config.Units, s.config.Weather.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create wttr.in weather provider: %w", err)
+		}
+		wttrProvider.SetUnits(s.config.UnitPreferences())
+		provider = wttrProvider
+	case "pirateweather":
+		if s.config.Weather.APIKey == "" {
+			return nil, fmt.Errorf("pirateweather weather provider requires an API key")
+		}
+		pirate, err := pirateweather.New(s.newHTTPClient(s.logger), s.logger, s.config.Units, s.config.Weather.APIKey)
 		if err != nil {
-			return provider, fmt.Errorf("failed to create Open-Meteo weather provider: %w", err)
+			return nil, fmt.Errorf("failed to create Pirate Weather provider: %w", err)
 		}
+		pirate.SetUnits(s.config.UnitPreferences())
+		provider = pirate
 	default:
-		return nil, fmt.Errorf("unsupported weather provider: %s", s.config.Weather.Provider)
+		return nil, fmt.Errorf("unsupported weather provider: %s", name)
 	}
 	return provider, nil
 }
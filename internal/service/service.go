@@ -7,68 +7,211 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
+	"maps"
+	"math/rand/v2"
+	stdhttp "net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nathan-osman/go-sunrise"
 	"github.com/vorlif/spreak"
 	"github.com/wneessen/go-moonphase"
 
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/config"
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/geocode/provider/none"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/icons"
 	"github.com/wneessen/waybar-weather/internal/job"
 	"github.com/wneessen/waybar-weather/internal/logger"
 	"github.com/wneessen/waybar-weather/internal/presenter"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
 const (
 	OutputClass      = "waybar-weather"
 	ColdOutputClass  = "cold"
+	FrostOutputClass = "frost"
+	GustWarningClass = "gust-warning"
 	HotOutputClass   = "hot"
 	DayOutputClass   = "day"
 	AltViewClass     = "alt-view"
 	NightOutputClass = "night"
+	IsNightClass     = "is-night"
+	ApproxLocClass   = "approx-location"
+	NoForecastClass  = "no-forecast"
+	PendingClass     = "pending"
+	ErrorClass       = "error"
 	SubID            = "location-update"
 	cacheHitTTL      = 1 * time.Hour
 	cacheMissTTL     = 10 * time.Minute
+	// Search results (i. e. city names) rarely change their coordinates, so they can be cached longer
+	searchCacheHitTTL  = 24 * time.Hour
+	searchCacheMissTTL = 10 * time.Minute
+	// The health of the geolocation providers is logged at debug level in this interval
+	providerHealthInterval = 15 * time.Minute
+	// On shutdown, the background work is given this long to finish after the context was cancelled
+	shutdownGracePeriod = 5 * time.Second
 )
 
+// ErrNoAccurateLocation is shown as error if no location with the required accuracy was found in time
+var ErrNoAccurateLocation = errors.New("no location with the required accuracy found")
+
+// ErrImplausibleWeather is recorded as fetch error if the weather data was discarded because its current
+// conditions are out of plausible bounds.
+var ErrImplausibleWeather = errors.New("weather data with implausible current conditions")
+
+// outputData is the JSON object printed to waybar. Alt holds the rendered alternative text, so that
+// waybar can switch to it with format-alt on its own instead of the USR1 toggle.
 type outputData struct {
 	Text    string   `json:"text"`
+	Alt     string   `json:"alt,omitempty"`
 	Tooltip string   `json:"tooltip"`
 	Classes []string `json:"class"`
 }
 
+// Render is a rendering of the weather data as it is printed to waybar, together with the template
+// context and the weather data it was rendered from.
+type Render struct {
+	Text    string
+	Tooltip string
+	Classes []string
+	Context presenter.TemplateContext
+	// Weather is shared with the service and must not be modified
+	Weather *weather.Data
+}
+
 type Service struct {
 	SignalSrc signalSource
+	Clock     clock.Clock
+	// HTTPTransport replaces the transport of the HTTP clients of the providers, e. g. to route their API
+	// requests to a fake server in tests. If nil, the default transport is used.
+	HTTPTransport stdhttp.RoundTripper
+	// OnRender is called after the weather data has been rendered and printed. It is called from the
+	// goroutine that rendered the weather data and must not block.
+	OnRender func(Render)
+	// Rand is the random source of the jitter. It can be replaced with a seeded source before the service
+	// is started, e. g. to make the jitter reproducible in tests.
+	Rand *rand.Rand
+	// Daemon makes the service serve its output only to the clients that subscribe on the control socket
+	// instead of printing it. The service then fails to start if it can't listen on the control socket.
+	Daemon bool
 
 	config      *config.Config
 	geobus      *geobus.GeoBus
+	geoOrch     *geobus.Orchestrator
+	httpClient  *http.Client
+	transport   *stdhttp.Transport
 	logger      *logger.Logger
 	geocoder    geocode.Geocoder
 	weatherProv weather.Provider
 	output      io.Writer
 	jobs        []*job.Job
-	presenter   *presenter.Presenter
-	t           *spreak.Localizer
+	// weatherJobs are the jobs that run in the weather update interval
+	weatherJobs []*job.Job
+	// weatherJob refreshes the weather data of the current location once it is stale. It is rescheduled
+	// with every fetch, so that it runs one update interval after the latest fetch.
+	weatherJob *job.Job
+	presenter  *presenter.Presenter
+	t          *spreak.Localizer
 
-	locationLock  sync.RWMutex
-	address       geocode.Address
-	locationIsSet bool
-	location      geobus.Coordinate
+	locationLock    sync.RWMutex
+	address         geocode.Address
+	locationIsSet   bool
+	location        geobus.Coordinate
+	locationWaiters []chan struct{}
+	// geoResult is the latest geolocation result that the service received from the geobus
+	geoResult geobus.Result
 
-	weatherLock  sync.RWMutex
-	weatherIsSet bool
-	weather      *weather.Data
+	weatherLock      sync.RWMutex
+	weatherIsSet     bool
+	weather          *weather.Data
+	weatherFetchedAt time.Time
+	// weatherJitter is the random offset that is added to the weather update interval of the current
+	// weather data
+	weatherJitter time.Duration
+	// cachedWeather is the weather data of the previous run from the state file. It is not displayed, but
+	// the first fetch is merged with it, so that the forecast hours of the previous run are kept.
+	cachedWeather *weather.Data
+	// fetchFailures counts the consecutive failed fetches before the first weather data is available
+	fetchFailures int
+	fetchErr      error
+	// unitSystem is the unit system that the weather is requested in. With automatic units, it follows
+	// the country of the location.
+	unitSystem string
+
+	// weatherGate and geocoderGate hold back the requests to the weather provider and the geocoder after
+	// they rejected their credentials or exceeded their rate limit
+	weatherGate  providerGate
+	geocoderGate providerGate
 
 	displayAltLock sync.RWMutex
 	displayAltText bool
+
+	// observed holds the observed extremes of the current temperature of the day, which are kept in the
+	// state file across restarts
+	observedLock sync.Mutex
+	observed     observedExtremes
+
+	resumeLock   sync.Mutex
+	resumeCancel context.CancelFunc
+
+	// work tracks the goroutines of the background work, which Run waits for on shutdown
+	work sync.WaitGroup
+
+	// onBattery is set while the system runs on battery power and the polling intervals are lengthened
+	onBattery    atomic.Bool
+	geoProviders []geobus.Provider
+
+	locationsLock sync.RWMutex
+	locations     map[string]*weather.Data
+
+	// dbus is the exported D-Bus object. It is nil unless the D-Bus service is enabled and running.
+	dbus *dbusObject
+	// notifier sends the desktop notifications. It is nil unless the notifications are enabled and the
+	// session bus is available.
+	notifier *notifier
+	// systemd sends the state notifications to systemd. It is nil unless the service is started by systemd
+	// with a notify socket.
+	systemd *systemdNotifier
+
+	clientsLock sync.Mutex
+	// clients are the clients that subscribed to the output on the control socket
+	clients map[*subscriber]struct{}
+
+	errorsLock sync.RWMutex
+	// lastErrors holds the last error of each subsystem for the Status
+	lastErrors map[string]SubsystemError
+
+	renderLock  sync.RWMutex
+	render      Render
+	renderIsSet bool
+	// renderTrigger funnels all render triggers to renderOutput. Its buffer of one coalesces the
+	// triggers that arrive while an output is pending.
+	renderTrigger chan RenderTrigger
+	// heartbeat asks renderOutput to report that it is alive, which it does by updating progress with the
+	// current time in Unix nanoseconds. The systemd watchdog is only pinged while it makes progress.
+	heartbeat chan struct{}
+	progress  atomic.Int64
+	// history holds the latest outputs. It is nil if the render history is disabled.
+	history *renderHistory
+	// renderErrors are the template errors that have been logged since the last successful render. It is
+	// only accessed by printWeather.
+	renderErrors map[string]struct{}
+	// iconName is the name of the icon that was last written to the icon file. It is only accessed by
+	// printWeather.
+	iconName string
+	// randLock guards Rand, which is not safe for concurrent use
+	randLock sync.Mutex
 }
 
 func New(conf *config.Config, log *logger.Logger, t *spreak.Localizer) (*Service, error) {
@@ -76,40 +219,70 @@ func New(conf *config.Config, log *logger.Logger, t *spreak.Localizer) (*Service
 	if err != nil {
 		return nil, fmt.Errorf("failed to create presenter: %w", err)
 	}
+	pres.Logger = log
 
 	bus, err := geobus.New(log)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create geobus: %w", err)
 	}
+	bus.SetHysteresis(conf.GeoLocation.GracePeriod, conf.GeoLocation.ConfirmDistance*1000)
+	for _, warning := range conf.Warnings() {
+		log.Warn(warning)
+	}
 
+	transport := http.NewTransport(conf.Privacy.DisableKeepAlives)
 	service := &Service{
 		SignalSrc: stdLibSignalSource{},
+		Clock:     clock.Real{},
+		Rand:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
 
 		config:         conf,
 		geobus:         bus,
+		httpClient:     http.NewWithTransport(log, transport),
+		transport:      transport,
 		logger:         log,
-		output:         os.Stdout,
+		output:         newStdoutWriter(log),
 		presenter:      pres,
 		t:              t,
 		displayAltText: false,
+		locations:      make(map[string]*weather.Data),
+		renderTrigger:  make(chan RenderTrigger, 1),
+		heartbeat:      make(chan struct{}, 1),
+		history:        newRenderHistory(conf.Debug.RenderHistory),
+		unitSystem:     initialUnitSystem(conf.Units),
 	}
 
 	// Schedule jobs
-	outputJob := job.New(service.config.Intervals.Output, service.printWeather)
-	// weatherUpdateJob := job.New(service.config.Intervals.WeatherUpdate, service.fetchWeather)
-	service.jobs = append(service.jobs, outputJob)
+	outputJob := job.New(service.config.Intervals.Output, func(context.Context) {
+		service.requestRender(TriggerSchedule)
+	})
+	service.weatherJob = job.New(service.config.Intervals.WeatherUpdate, service.refreshWeather)
+	service.jobs = append(service.jobs, outputJob, service.weatherJob)
+	service.weatherJobs = append(service.weatherJobs, service.weatherJob)
+	if len(conf.Locations) > 0 {
+		locationsJob := job.New(service.config.Intervals.WeatherUpdate, service.fetchLocationsWeather)
+		service.jobs = append(service.jobs, locationsJob)
+		service.weatherJobs = append(service.weatherJobs, locationsJob)
+	}
+	if conf.Intervals.Jitter > 0 {
+		for _, j := range service.jobs {
+			j.SetJitter(service.jitter)
+		}
+		// The weather data expires after the interval plus its own jitter, so the weather job is delayed
+		// by the same jitter instead of a new one
+		service.weatherJob.SetJitter(service.fetchedWeatherJitter)
+	}
+
+	// Display the view that was toggled in the previous run
+	service.restoreState()
 
 	return service, nil
 }
 
 func (s *Service) Run(ctx context.Context) (err error) {
-	// Start scheduled jobs as go routines
-	for _, j := range s.jobs {
-		if j == nil {
-			continue
-		}
-		go j.Start(ctx)
-	}
+	// The service shuts down on its own if nobody reads its output anymore
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
 
 	// Select the geocode provider for the address lookup
 	geocodeProvider, err := s.selectGeocodeProvider(s.config, s.logger, s.t.Language())
@@ -125,115 +298,420 @@ func (s *Service) Run(ctx context.Context) (err error) {
 	}
 	s.weatherProv = weatherProv
 
+	// Report the service state to systemd, if it runs as a Type=notify service
+	s.systemd = newSystemdNotifier(s.logger)
+
+	// Export the weather data on the session bus, before the first weather data is fetched
+	s.startDBus(ctx)
+
+	// Send desktop notifications about upcoming changes of the weather condition
+	s.startNotifier(ctx)
+
+	// Answer the status requests of the status subcommand and serve the subscribed clients
+	if err = s.startControl(ctx); err != nil && s.Daemon {
+		return fmt.Errorf("failed to listen for clients: %w", err)
+	}
+
+	// Print the weather data whenever a render is requested and re-emit it after a broken output pipe
+	if s.Daemon {
+		s.output = io.Discard
+	}
+	s.work.Go(func() { s.renderOutput(ctx) })
+	if out, ok := s.output.(*outputWriter); ok {
+		s.work.Go(func() {
+			if err := out.retry(ctx); err != nil {
+				s.logger.Error("giving up on the output, shutting down", logger.Err(err),
+					slog.Duration("timeout", out.brokenTimeout))
+				stop()
+			}
+		})
+	}
+
+	// Start scheduled jobs as go routines
+	for _, j := range s.jobs {
+		if j == nil {
+			continue
+		}
+		s.work.Go(func() { j.Start(ctx) })
+	}
+
+	// Desynchronize the initial geolocation lookup and weather fetch from other instances
+	s.awaitStartupDelay(ctx)
+
 	// Select the geobus providers and track them in the geobus
 	geobusProvider, err := s.selectGeobusProviders()
 	if err != nil {
 		return fmt.Errorf("failed to create geobus orchestrator: %w", err)
 	}
-	geobus.TrackProviders(ctx, s.geobus, SubID, geobusProvider...)
+	s.geoProviders = geobusProvider
+	s.geoOrch = geobus.NewOrchestrator(s.geobus, s.subscriptionKey(), geobusProvider...)
+	s.geoOrch.Start(ctx)
+	healthJob := job.New(providerHealthInterval, s.logProviderHealth)
+	s.work.Go(func() { healthJob.Start(ctx) })
 
 	// Subscribe to geolocation updates from the geobus
-	sub, unsub := s.geobus.Subscribe(SubID, 1)
-	go s.processLocationUpdates(ctx, sub)
+	sub, unsub := s.geobus.Subscribe(s.subscriptionKey(), 1)
+	s.work.Go(func() { s.processLocationUpdates(ctx, sub) })
+	s.work.Go(func() { s.awaitAccurateLocation(ctx) })
+
+	// Fetch the weather data for the additional locations
+	if len(s.config.Locations) > 0 {
+		s.work.Go(func() { s.fetchLocationsWeather(ctx) })
+	}
 
 	// Detect sleep/wake events and update the weather
-	go s.monitorSleepResume(ctx)
+	s.work.Go(func() { s.monitorSleepResume(ctx) })
+
+	// Lengthen the polling intervals while on battery power
+	s.work.Go(func() { s.monitorPowerSource(ctx) })
 
-	// Wait for the context to cancel
-	<-ctx.Done()
+	// Wait for the context to cancel, while pinging the systemd watchdog
+	s.awaitShutdown(ctx)
 	if unsub != nil {
 		unsub()
 	}
+	// The in-flight requests are cancelled along with the context, so the background work should finish
+	// right away. The geocode cache is flushed afterward, so that it includes their results.
+	if !s.awaitWork(shutdownGracePeriod) {
+		s.logger.Warn("background work did not finish in time, shutting down anyway",
+			slog.Duration("grace_period", shutdownGracePeriod))
+	}
+	if cache, ok := s.geocoder.(*geocode.CachedGeocoder); ok {
+		if err = cache.Flush(); err != nil {
+			s.logger.Error("failed to flush geocode cache", logger.Err(err))
+		}
+	}
 	return nil
 }
 
+// awaitWork waits for the background work to finish, but no longer than the grace period. It reports
+// whether all work finished in time.
+func (s *Service) awaitWork(grace time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.work.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// SetOutput sets the writer that the rendered weather data is printed to as waybar JSON. It defaults to
+// stdout and must be set before the service is started. Unlike stdout, the writer is retried until it is
+// replaced, if its pipe breaks.
+func (s *Service) SetOutput(w io.Writer) {
+	s.output = newOutputWriter(w, s.logger)
+}
+
+// Snapshot returns the latest rendering of the weather data. The second return value is false, if no
+// weather data has been rendered yet.
+func (s *Service) Snapshot() (Render, bool) {
+	s.renderLock.RLock()
+	defer s.renderLock.RUnlock()
+	return s.render, s.renderIsSet
+}
+
+// GeoBus returns the GeoBus of the service. It allows library users to subscribe to the geolocation
+// updates of the service's providers themselves.
+func (s *Service) GeoBus() *geobus.GeoBus {
+	return s.geobus
+}
+
+// subscriptionKey returns the configured geobus subscription key or SubID if none is configured.
+func (s *Service) subscriptionKey() string {
+	if s.config.GeoLocation.SubscriptionKey != "" {
+		return s.config.GeoLocation.SubscriptionKey
+	}
+	return SubID
+}
+
 // fetchWeather retrieves the current weather data from the weather provider.
 func (s *Service) fetchWeather(ctx context.Context) {
+	if !s.updateWeather(ctx) || (s.dbus == nil && s.notifier == nil) {
+		return
+	}
+	tplCtx, _ := s.buildContext()
+	if s.dbus != nil {
+		s.dbus.publish(tplCtx)
+	}
+	if s.notifier != nil {
+		s.notifier.check(ctx, tplCtx, s.Clock.Now())
+	}
+}
+
+// refreshWeather fetches the weather data of the current location if it is stale. It is the task of the
+// weather job, which keeps the weather up to date while the location doesn't change, e. g. if the
+// geolocation providers only report changed positions.
+func (s *Service) refreshWeather(ctx context.Context) {
+	s.locationLock.RLock()
+	isSet := s.locationIsSet
+	s.locationLock.RUnlock()
+	if !isSet || !s.weatherIsStale() {
+		return
+	}
+	s.logger.Debug("weather data is stale, refreshing")
+	s.fetchWeather(ctx)
+	s.requestRender(TriggerSchedule)
+}
+
+// weatherIsStale reports whether no weather data was fetched yet or the weather data is older than the
+// update interval plus its jitter.
+func (s *Service) weatherIsStale() bool {
+	s.weatherLock.RLock()
+	defer s.weatherLock.RUnlock()
+	return !s.weatherIsSet || s.Clock.Now().Sub(s.weatherFetchedAt) >= s.weatherUpdateInterval()+s.weatherJitter
+}
+
+// updateWeather fetches the weather data for the current location and stores it in the service state.
+// It reports whether the fetch was successful.
+func (s *Service) updateWeather(ctx context.Context) bool {
 	s.weatherLock.Lock()
 	defer s.weatherLock.Unlock()
 
-	data, err := s.weatherProv.GetWeather(ctx, s.location)
+	if err := s.weatherGate.allow(s.Clock.Now()); err != nil {
+		s.logger.Debug("skipping weather update", logger.Err(err), slog.String("source", s.weatherProv.Name()))
+		return false
+	}
+	data, err := s.weatherProv.GetWeather(ctx, s.outboundCoordinates(s.location))
 	if err != nil {
-		s.logger.Error("failed to fetch weather data", logger.Err(err),
-			slog.String("source", s.weatherProv.Name()))
-		return
+		if !s.closeGate(&s.weatherGate, err, "failed to fetch weather data",
+			slog.String("source", s.weatherProv.Name())) {
+			s.logger.Error("failed to fetch weather data", logger.Err(err),
+				slog.String("source", s.weatherProv.Name()))
+		}
+		s.recordFetchFailure(err)
+		return false
+	}
+	s.weatherGate.succeed()
+	if data.Source == "" {
+		data.Source = s.weatherProv.Name()
+	}
+	if !s.validateWeather(data, slog.String("source", data.Source)) {
+		s.recordFetchFailure(ErrImplausibleWeather)
+		return false
+	}
+	data.ResolveIsDay()
+	prev := s.weather
+	if prev == nil {
+		prev = s.cachedWeather
+	}
+	if kept := data.Merge(prev, s.config.Weather.ForecastMaxAge, s.Clock.Now()); kept > 0 {
+		s.logger.Debug("kept forecast entries missing in the fetched weather data", slog.Int("entries", kept),
+			slog.String("source", data.Source))
 	}
-	s.weather = data
+	s.weather, s.cachedWeather = data, nil
 	s.weatherIsSet = true
+	s.weatherFetchedAt = s.Clock.Now()
+	s.weatherJob.SetInterval(s.weatherUpdateInterval())
+	s.weatherJitter = s.jitter()
+	s.fetchFailures, s.fetchErr = 0, nil
+	s.clearError("weather")
+	s.observe(data)
+	s.persistStateWith(data)
 
-	s.logger.Debug("weather data fetched successfully")
+	s.logger.Debug("weather data fetched successfully", slog.String("source", data.Source))
+	return true
 }
 
-// printWeather retrieves and displays the current weather data using the service's state and rendering logic.
-func (s *Service) printWeather(context.Context) {
-	if !s.weatherIsSet {
-		return
-	}
-
+// buildContext builds the template context from the current service state. It returns the weather data
+// that the context was built from along with it.
+func (s *Service) buildContext() (presenter.TemplateContext, *weather.Data) {
 	// Read relevant data from the service state
 	s.locationLock.RLock()
 	s.weatherLock.RLock()
 	addr := s.address
+	geoResult := s.geoResult
 	weathr := s.weather
 	s.locationLock.RUnlock()
 	s.weatherLock.RUnlock()
+	s.locationsLock.RLock()
+	locations := s.presenter.BuildLocations(s.locations)
+	s.locationsLock.RUnlock()
 
 	// Moonphase and sunrise/sunset times
-	now := time.Now()
-	moon := moonphase.New(time.Now().In(time.Local))
+	now := s.Clock.Now()
+	moon := moonphase.New(now.In(time.Local))
 	sunriseTimeUTC, sunsetTimeUTC := sunrise.SunriseSunset(addr.Latitude, addr.Longitude, now.Year(),
 		now.Month(), now.Day())
 
-	// Render the weather data
 	tplCtx := s.presenter.BuildContext(addr, weathr, sunriseTimeUTC.In(time.Local), sunsetTimeUTC.In(time.Local),
-		moon.PhaseName())
+		moon.PhaseName(), geoResult.At)
+	s.setObserved(&tplCtx, weathr)
+	tplCtx.Locations = locations
+	tplCtx.Attribution = s.attribution()
+	tplCtx.LocationAccuracy, tplCtx.LocationSource = geoResult.AccuracyMeters, geoResult.Source
+	return tplCtx, weathr
+}
+
+// attribution returns the attribution of the weather provider, the configured attribution that replaces
+// it, or an empty string if the attribution is hidden.
+func (s *Service) attribution() string {
+	switch {
+	case s.config.Output.HideAttribution:
+		return ""
+	case s.config.Output.Attribution != "":
+		return s.config.Output.Attribution
+	case s.weatherProv != nil:
+		return s.weatherProv.Attribution()
+	default:
+		return ""
+	}
+}
+
+// requestRender triggers an output of the weather data for the given reason without blocking. If an output
+// is already pending, the trigger is coalesced with it, since the output always renders the latest state.
+func (s *Service) requestRender(trigger RenderTrigger) {
+	select {
+	case s.renderTrigger <- trigger:
+	default:
+	}
+}
+
+// renderOutput prints the weather data for the render triggers until the context is cancelled. After a
+// trigger, it waits for the minimum render interval, so that all triggers within that interval result in
+// a single output and waybar receives at most one output per interval. The USR1 toggle is a direct user
+// action and is printed without waiting, together with the triggers that are pending at that time. The
+// output is recorded with the trigger that started it, or the toggle.
+func (s *Service) renderOutput(ctx context.Context) {
+	for {
+		var trigger RenderTrigger
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.heartbeat:
+			s.progress.Store(time.Now().UnixNano())
+			continue
+		case trigger = <-s.renderTrigger:
+		}
+		if trigger != TriggerSignal && s.config.Intervals.MinRender > 0 {
+			timer := time.NewTimer(s.config.Intervals.MinRender)
+		wait:
+			for {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+					break wait
+				case next := <-s.renderTrigger:
+					if next == TriggerSignal {
+						timer.Stop()
+						trigger = next
+						break wait
+					}
+				}
+			}
+		}
+		select {
+		case <-s.renderTrigger:
+		default:
+		}
+		s.printWeather(ctx, trigger)
+		s.progress.Store(time.Now().UnixNano())
+	}
+}
+
+// printWeather retrieves and displays the current weather data using the service's state and rendering logic.
+func (s *Service) printWeather(_ context.Context, trigger RenderTrigger) {
+	if !s.weatherIsSet {
+		s.printPlaceholder(trigger)
+		return
+	}
+
+	// Render the weather data
+	tplCtx, weathr := s.buildContext()
 	renderMap, err := s.presenter.Render(tplCtx)
+	s.logRenderErrors(err)
 	if err != nil {
-		s.logger.Error("failed to render weather template", logger.Err(err))
+		s.recordError("render", err)
+	} else {
+		s.clearError("render")
 	}
-	for _, key := range []string{"text", "alt_text", "tooltip", "alt_tooltip"} {
-		if _, ok := renderMap[key]; !ok {
-			renderMap[key] = ""
-		}
+
+	// Present the rendered weather data
+	output := s.weatherOutput(tplCtx, renderMap, s.view())
+	if err = s.writeOutput(output, trigger); err != nil {
+		s.logger.Error("failed to encode weather data", logger.Err(err))
+		s.recordError("output", err)
+	} else {
+		s.clearError("output")
+	}
+	s.writeIconFile(tplCtx.Current)
+
+	render := Render{
+		Text:    output.Text,
+		Tooltip: output.Tooltip,
+		Classes: output.Classes,
+		Context: tplCtx,
+		Weather: weathr,
+	}
+	s.renderLock.Lock()
+	s.render, s.renderIsSet = render, true
+	s.renderLock.Unlock()
+	s.systemd.notifyReady()
+	if s.OnRender != nil {
+		s.OnRender(render)
 	}
+	s.renderClients()
+}
 
+// view returns the view that the service prints to waybar.
+func (s *Service) view() renderView {
+	s.displayAltLock.RLock()
+	defer s.displayAltLock.RUnlock()
+	return renderView{conf: s.config, presenter: s.presenter, alt: s.displayAltText}
+}
+
+// weatherOutput builds the waybar output of the rendered templates for the view. The CSS classes are
+// derived from the template context.
+func (s *Service) weatherOutput(tplCtx presenter.TemplateContext, renderMap map[string]string,
+	view renderView,
+) outputData {
 	// Are we in alternative text mode?
-	altMode := false
+	altMode := view.alt
 	displayText := renderMap["text"]
 	displayTooltip := renderMap["tooltip"]
-	s.displayAltLock.RLock()
-	if s.displayAltText {
-		altMode = true
+	if altMode {
 		displayText = renderMap["alt_text"]
 		displayTooltip = renderMap["alt_tooltip"]
 	}
-	s.displayAltLock.RUnlock()
 
-	// Add output classes based cold/hot thresholds and the weather category
+	// Add output classes based cold/hot thresholds and the weather category. The alternative view shows
+	// the most severe condition within the forecast hours, if templates.worst_condition is set.
+	altView := tplCtx.Forecast
+	if view.conf.Templates.WorstCondition {
+		altView = tplCtx.WorstForecast
+	}
 	outputClasses := []string{OutputClass}
 	switch altMode {
 	case true:
 		outputClasses = append(outputClasses, AltViewClass)
-		if tplCtx.Forecast.Temperature >= s.config.Weather.HotThreshold {
+		if altView.Hot {
 			outputClasses = append(outputClasses, HotOutputClass)
 		}
-		if tplCtx.Forecast.Temperature <= s.config.Weather.ColdThreshold {
+		if altView.Cold {
 			outputClasses = append(outputClasses, ColdOutputClass)
 		}
-		if tplCtx.Forecast.Category != "" {
-			outputClasses = append(outputClasses, tplCtx.Forecast.Category)
+		if altView.Category != "" {
+			outputClasses = append(outputClasses, altView.Category)
 		}
-		if tplCtx.Forecast.IsDay {
+		if altView.IsDay {
 			outputClasses = append(outputClasses, DayOutputClass)
 		}
-		if !tplCtx.Forecast.IsDay {
+		if !altView.IsDay {
 			outputClasses = append(outputClasses, NightOutputClass)
 		}
 	default:
-		if tplCtx.Current.Temperature >= s.config.Weather.HotThreshold {
+		if tplCtx.Current.Hot {
 			outputClasses = append(outputClasses, HotOutputClass)
 		}
-		if tplCtx.Current.Temperature <= s.config.Weather.ColdThreshold {
+		if tplCtx.Current.Cold {
 			outputClasses = append(outputClasses, ColdOutputClass)
 		}
 		if tplCtx.Current.Category != "" {
@@ -247,59 +725,367 @@ func (s *Service) printWeather(context.Context) {
 		}
 	}
 
+	// Frost risk covers the whole night, so it does not depend on the alternative mode
+	if tplCtx.FrostRisk {
+		outputClasses = append(outputClasses, FrostOutputClass)
+	}
+	// The gust warning covers the whole warning window as well
+	if tplCtx.GustWarning {
+		outputClasses = append(outputClasses, GustWarningClass)
+	}
+	// Themes that follow the time of day need the current conditions, regardless of the alternative mode
+	if !tplCtx.Current.IsDay {
+		outputClasses = append(outputClasses, IsNightClass)
+	}
+	if s.isApproxLocation(tplCtx.LocationAccuracy) {
+		outputClasses = append(outputClasses, ApproxLocClass)
+	}
+	if !tplCtx.HasForecast {
+		outputClasses = append(outputClasses, NoForecastClass)
+	}
+	// The severity band follows the displayed weather, like the category
+	severity := tplCtx.Current.Severity
+	if altMode {
+		severity = altView.Severity
+	}
+	outputClasses = append(outputClasses, presenter.SeverityBand(severity))
+
 	// In CSS Icon mode we add the WMO code to the output class list
-	if s.config.Templates.UseCSSIcon {
+	if view.conf.Templates.UseCSSIcon {
 		code := tplCtx.Current.WeatherCode
 		if altMode {
-			code = tplCtx.Forecast.WeatherCode
+			code = altView.WeatherCode
 		}
 		outputClasses = append(outputClasses, fmt.Sprintf("wmo-%d", code))
 	}
 
-	// Present the rendered weather data
-	output := outputData{
+	return outputData{
 		Text:    displayText,
+		Alt:     renderMap["alt_text"],
 		Tooltip: displayTooltip,
 		Classes: outputClasses,
 	}
-	if err = json.NewEncoder(s.output).Encode(output); err != nil {
-		s.logger.Error("failed to encode weather data", logger.Err(err))
+}
+
+// writeIconFile writes the icon of the current weather category to the icon file, if icons.file_output is
+// enabled. The file is only rewritten if the icon changed.
+func (s *Service) writeIconFile(current presenter.WeatherView) {
+	if !s.config.Icons.FileOutput {
+		return
+	}
+	name := icons.Name(current.Category, current.IsDay)
+	if name == s.iconName {
+		return
+	}
+	if err := icons.WriteFile(s.config.Icons.File, name); err != nil {
+		s.logger.Error("failed to write icon file", logger.Err(err), slog.String("path", s.config.Icons.File))
+		s.recordError("icon", err)
+		s.iconName = ""
+		return
+	}
+	s.clearError("icon")
+	s.iconName = name
+}
+
+// isApproxLocation reports whether the accuracy radius of the location in meters exceeds the configured
+// approximation threshold. An unknown accuracy of zero is not considered approximate.
+func (s *Service) isApproxLocation(accuracy float64) bool {
+	threshold := s.config.GeoLocation.ApproxThreshold * 1000
+	return threshold > 0 && accuracy > threshold
+}
+
+// isAccurateLocation reports whether the accuracy radius in meters meets the required minimum accuracy.
+func (s *Service) isAccurateLocation(accuracy float64) bool {
+	required := s.config.GeoLocation.MinAccuracy
+	return required <= 0 || accuracy <= required
+}
+
+// awaitAccurateLocation shows the error state if no location with the required accuracy was found within
+// the timeout after start. Otherwise, the pending state would be shown indefinitely, since less accurate
+// locations are ignored. The weather is shown as soon as an accurate location is found afterward.
+func (s *Service) awaitAccurateLocation(ctx context.Context) {
+	timeout := s.config.GeoLocation.MinAccuracyTimeout
+	if s.config.GeoLocation.MinAccuracy <= 0 || timeout <= 0 {
+		return
+	}
+	updated := s.awaitLocationUpdate()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-updated:
+		return
+	case <-timer.C:
+	}
+
+	s.locationLock.RLock()
+	isSet := s.locationIsSet
+	s.locationLock.RUnlock()
+	if isSet {
+		return
+	}
+	s.weatherLock.Lock()
+	if s.weather == nil {
+		s.fetchErr = ErrNoAccurateLocation
+		s.fetchFailures = max(s.fetchFailures, int(s.config.Weather.FailureThreshold))
+	}
+	s.weatherLock.Unlock()
+	s.recordError("geolocation", ErrNoAccurateLocation)
+	s.logger.Warn("no location with the required accuracy found",
+		slog.Float64("min_accuracy", s.config.GeoLocation.MinAccuracy), slog.Duration("timeout", timeout))
+	s.requestRender(TriggerLocation)
+}
+
+// logRenderErrors logs the errors of the templates that failed to render. Since a broken template fails on
+// every render, each distinct error is only logged once until all templates render successfully again.
+func (s *Service) logRenderErrors(err error) {
+	if err == nil {
+		clear(s.renderErrors)
+		return
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	if s.renderErrors == nil {
+		s.renderErrors = make(map[string]struct{})
+	}
+	for _, renderErr := range errs {
+		if _, ok := s.renderErrors[renderErr.Error()]; ok {
+			continue
+		}
+		s.renderErrors[renderErr.Error()] = struct{}{}
+		s.logger.Error("failed to render weather template", logger.Err(renderErr))
 	}
 }
 
 // updateLocation updates the service's location and address based on provided latitude and longitude.
 // It locks the location for thread-safe updates and retrieves the address information using reverse geocoding.
-// If valid coordinates are not provided, the update is skipped. The method also triggers all scheduled jobs.
+// If valid coordinates are not provided, the update is skipped. If the location did not change significantly
+// and the weather data is still fresh, neither the reverse geocoding nor the weather update is performed.
 func (s *Service) updateLocation(ctx context.Context, coords geobus.Coordinate) error {
 	if !coords.Valid() {
 		return fmt.Errorf("invalid coordinates: %f, %f", coords.Lat, coords.Lon)
 	}
+	if !s.locationNeedsUpdate(coords) {
+		s.logger.Debug("location did not change significantly, skipping update",
+			slog.Any("coordinates", coords))
+		return nil
+	}
 
-	address, err := s.geocoder.Reverse(ctx, coords)
+	address, err := s.reverseGeocode(ctx, coords)
 	if err != nil {
-		return fmt.Errorf("failed reverse geocode coordinates: %w", err)
+		return err
 	}
 
 	s.locationLock.Lock()
 	s.location = coords
-	if address.AddressFound {
+	// Without reverse geocoding, the address only holds the formatted coordinates and is always replaced. The
+	// name of a geofence is replaced as well after leaving it, even if no address was found.
+	if address.AddressFound || s.geocoder.Name() == none.Name || s.address.Label != "" {
 		s.address = address
 	}
 	s.locationIsSet = true
 	s.locationLock.Unlock()
+	s.clearError("geolocation")
 	s.logger.Debug("address successfully resolved", slog.Any("address", s.address.DisplayName),
 		slog.Any("coordinates", s.location), slog.String("source", s.geocoder.Name()),
 		slog.Bool("cache_hit", address.CacheHit))
 
+	// The weather of the new location is fetched right away anyway, but the additional locations need to be
+	// fetched again if they are in the previous units
+	if s.selectUnitSystem(address) && len(s.config.Locations) > 0 {
+		s.work.Go(func() { s.fetchLocationsWeather(ctx) })
+	}
 	s.fetchWeather(ctx)
-	s.printWeather(ctx)
+	s.requestRender(TriggerLocation)
+	s.notifyLocationWaiters()
 
 	return nil
 }
 
+// reverseGeocode looks up the address of the coordinates. Within a geofence, its name is returned as address
+// without requesting the geocoder. While the geocoder is held back after it rejected its credentials or
+// exceeded its rate limit, no address is returned, so that the weather is still updated for the new location.
+func (s *Service) reverseGeocode(ctx context.Context, coords geobus.Coordinate) (geocode.Address, error) {
+	if fence, ok := matchGeofence(s.config.Geofences, coords); ok {
+		s.logger.Debug("coordinates are within a geofence, skipping reverse geocoding",
+			slog.String("geofence", fence.Name))
+		return geofenceAddress(fence, coords), nil
+	}
+	if err := s.geocoderGate.allow(s.Clock.Now()); err != nil {
+		s.logger.Debug("skipping reverse geocoding", logger.Err(err), slog.String("source", s.geocoder.Name()))
+		return geocode.Address{}, nil
+	}
+	address, err := s.geocoder.Reverse(ctx, s.outboundCoordinates(coords))
+	if err != nil {
+		err = fmt.Errorf("failed reverse geocode coordinates: %w", err)
+		s.recordError("geocoder", err)
+		if s.closeGate(&s.geocoderGate, err, "failed to look up the address",
+			slog.String("source", s.geocoder.Name())) {
+			return geocode.Address{}, nil
+		}
+		return address, err
+	}
+	s.geocoderGate.succeed()
+	s.clearError("geocoder")
+	return address, nil
+}
+
+// printPlaceholder prints the pending state until the first weather data has been fetched, or the error
+// state if the fetch failed too many times in a row. If weather data has been fetched before (e. g. while
+// the data is refreshed after a resume), nothing is printed, so that the previous output stays visible.
+func (s *Service) printPlaceholder(trigger RenderTrigger) {
+	output, ok := s.placeholderOutput(s.view())
+	if !ok {
+		return
+	}
+	if err := s.writeOutput(output, trigger); err != nil {
+		s.logger.Error("failed to encode placeholder data", logger.Err(err))
+	}
+	s.renderClients()
+}
+
+// placeholderOutput renders the pending or error state for the view. It returns false if weather data has
+// been fetched before, so that no placeholder is printed.
+func (s *Service) placeholderOutput(view renderView) (outputData, bool) {
+	s.weatherLock.RLock()
+	hasData, failures, fetchErr := s.weather != nil, s.fetchFailures, s.fetchErr
+	s.weatherLock.RUnlock()
+	if hasData {
+		return outputData{}, false
+	}
+
+	s.locationLock.RLock()
+	tplCtx := s.presenter.BuildPendingContext(s.address, s.location)
+	s.locationLock.RUnlock()
+
+	output := outputData{Classes: []string{OutputClass, PendingClass}}
+	text, err := view.presenter.RenderPending(tplCtx)
+	if fetchErr != nil && failures >= int(s.config.Weather.FailureThreshold) {
+		tplCtx.Error = fetchErr.Error()
+		output.Classes = []string{OutputClass, ErrorClass}
+		output.Tooltip = view.presenter.EscapeTooltip(tplCtx.Error)
+		text, err = view.presenter.RenderError(tplCtx)
+	}
+	if err != nil {
+		s.logger.Error("failed to render placeholder template", logger.Err(err))
+	}
+	output.Text = text
+	if output.Tooltip == "" {
+		output.Tooltip = text
+	}
+	return output, true
+}
+
+// writeOutput prints the output as a JSON line for waybar and records it in the render history.
+func (s *Service) writeOutput(output outputData, trigger RenderTrigger) error {
+	line, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	s.history.add(RenderRecord{Time: s.Clock.Now(), Trigger: trigger, Output: string(line)})
+	_, err = s.output.Write(append(line, '\n'))
+	return err
+}
+
+// recordFetchFailure counts a failed fetch as long as no weather data has been fetched yet, so that
+// printWeather can switch from the pending to the error state. The caller must hold the weather lock.
+func (s *Service) recordFetchFailure(err error) {
+	s.recordError("weather", err)
+	if s.weather != nil {
+		return
+	}
+	s.fetchFailures++
+	s.fetchErr = err
+	// The fetch is not retried after an authentication error, so the error state is shown right away
+	if providererr.Kind(err) == providererr.ErrAuth {
+		s.fetchFailures = max(s.fetchFailures, int(s.config.Weather.FailureThreshold))
+	}
+}
+
+// validateWeather checks the weather data against plausible physical bounds and logs the values that were
+// discarded. It reports whether the current conditions are plausible. If they are not, the data must not
+// replace the previous fetch.
+func (s *Service) validateWeather(data *weather.Data, args ...any) bool {
+	validation := data.Validate()
+	if validation.Clean() {
+		return true
+	}
+	args = append(args, slog.Any("validation", validation))
+	if !validation.CurrentValid() {
+		s.logger.Warn("discarding weather data with implausible current conditions", args...)
+		return false
+	}
+	s.logger.Warn("dropped forecast entries with implausible values", args...)
+	return true
+}
+
+// logProviderHealth logs the health status of the geolocation providers at debug level.
+func (s *Service) logProviderHealth(context.Context) {
+	if s.geoOrch == nil {
+		return
+	}
+	health := s.geoOrch.Health()
+	for _, name := range slices.Sorted(maps.Keys(health)) {
+		s.logger.Debug("geolocation provider health", slog.String("provider", name),
+			slog.Any("status", health[name]))
+	}
+}
+
+// awaitLocationUpdate returns a channel that is closed once the next location update has been applied.
+func (s *Service) awaitLocationUpdate() <-chan struct{} {
+	ch := make(chan struct{})
+	s.locationLock.Lock()
+	s.locationWaiters = append(s.locationWaiters, ch)
+	s.locationLock.Unlock()
+	return ch
+}
+
+// notifyLocationWaiters closes the channels of all callers waiting for a location update.
+func (s *Service) notifyLocationWaiters() {
+	s.locationLock.Lock()
+	waiters := s.locationWaiters
+	s.locationWaiters = nil
+	s.locationLock.Unlock()
+	for _, ch := range waiters {
+		close(ch)
+	}
+}
+
+// outboundCoordinates returns the coordinates truncated to the configured privacy precision for the
+// requests to the weather and geocoding providers. The service state keeps the full precision, which the
+// movement detection relies on.
+func (s *Service) outboundCoordinates(coords geobus.Coordinate) geobus.Coordinate {
+	precision := int(s.config.Privacy.CoordinatePrecision)
+	coords.Lat = geobus.Truncate(coords.Lat, precision)
+	coords.Lon = geobus.Truncate(coords.Lon, precision)
+	return coords
+}
+
+// locationNeedsUpdate reports whether the given coordinates require a new address and weather lookup. This
+// is the case if no location has been set yet, the position changed significantly compared to the current
+// location, it entered or left a geofence or the last successful weather fetch is older than the configured
+// weather update interval.
+func (s *Service) locationNeedsUpdate(coords geobus.Coordinate) bool {
+	s.locationLock.RLock()
+	isSet, current, label := s.locationIsSet, s.location, s.address.Label
+	s.locationLock.RUnlock()
+	if !isSet || coords.PosHasSignificantChange(current) {
+		return true
+	}
+	if fence, _ := matchGeofence(s.config.Geofences, coords); fence.Name != label {
+		return true
+	}
+	return s.weatherIsStale()
+}
+
 // processLocationUpdates subscribes to geolocation updates, processes location data, and updates the
-// service state accordingly.
+// service state accordingly. Updates that are less accurate than required are ignored. Only the first one
+// of a row is logged as warning, since a provider keeps sending them in its poll period.
 func (s *Service) processLocationUpdates(ctx context.Context, sub <-chan geobus.Result) {
+	rejecting := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -311,7 +1097,23 @@ func (s *Service) processLocationUpdates(ctx context.Context, sub <-chan geobus.
 			s.logger.Debug("received geolocation update",
 				slog.Float64("lat", r.Lat), slog.Float64("lon", r.Lon),
 				slog.Float64("accuracy", r.AccuracyMeters), slog.String("source", r.Source))
-			if err := s.updateLocation(ctx, geobus.Coordinate{Lat: r.Lat, Lon: r.Lon}); err != nil {
+			if !s.isAccurateLocation(r.AccuracyMeters) {
+				level := slog.LevelWarn
+				if rejecting {
+					level = slog.LevelDebug
+				}
+				s.logger.Log(ctx, level, "ignoring geolocation update that is less accurate than required",
+					slog.Float64("accuracy", r.AccuracyMeters),
+					slog.Float64("min_accuracy", s.config.GeoLocation.MinAccuracy), slog.String("source", r.Source))
+				rejecting = true
+				continue
+			}
+			rejecting = false
+			s.locationLock.Lock()
+			s.geoResult = r
+			s.locationLock.Unlock()
+			coords := geobus.Coordinate{Lat: r.Lat, Lon: r.Lon, Acc: r.AccuracyMeters}
+			if err := s.updateLocation(ctx, coords); err != nil {
 				s.logger.Error("failed to apply geo update", logger.Err(err), slog.String("source", r.Source))
 			}
 		}
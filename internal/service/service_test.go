@@ -12,24 +12,42 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"net"
 	stdhttp "net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"testing/synctest"
 	tt "text/template"
 	"time"
 
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/wneessen/waybar-weather/internal/clock"
 	"github.com/wneessen/waybar-weather/internal/config"
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/geocode/provider/none"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/icons"
+	"github.com/wneessen/waybar-weather/internal/job"
 	"github.com/wneessen/waybar-weather/internal/logger"
 	"github.com/wneessen/waybar-weather/internal/presenter"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
+	"github.com/wneessen/waybar-weather/internal/testhelper/fakeapi"
 	"github.com/wneessen/waybar-weather/internal/weather"
 	openmeteo "github.com/wneessen/waybar-weather/internal/weather/provider/open-meteo"
 )
@@ -84,6 +102,36 @@ func TestNew(t *testing.T) {
 				"geocode-earth",
 				false,
 			},
+			{
+				"photon",
+				[]string{"WAYBARWEATHER_GEOCODER_PROVIDER=photon"},
+				"photon",
+				false,
+			},
+			{
+				"photon with custom base URL",
+				[]string{
+					"WAYBARWEATHER_GEOCODER_PROVIDER=photon",
+					"WAYBARWEATHER_GEOCODER_BASE_URL=http://localhost:2322",
+				},
+				"photon",
+				false,
+			},
+			{
+				"google without api-key",
+				[]string{"WAYBARWEATHER_GEOCODER_PROVIDER=google"},
+				"google",
+				true,
+			},
+			{
+				"google with api-key",
+				[]string{
+					"WAYBARWEATHER_GEOCODER_PROVIDER=google",
+					"WAYBARWEATHER_GEOCODER_APIKEY=abc",
+				},
+				"google",
+				false,
+			},
 			{
 				"unsupported provider",
 				[]string{"WAYBARWEATHER_GEOCODER_PROVIDER=invalid"},
@@ -127,6 +175,169 @@ func TestNew(t *testing.T) {
 			})
 		}
 	})
+	t.Run("none geocoder is not cached", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_GEOCODER_PROVIDER", "none")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		provider, err := serv.selectGeocodeProvider(serv.config, serv.logger, serv.t.Language())
+		if err != nil {
+			t.Fatalf("failed to select geocode provider: %s", err)
+		}
+		if _, ok := provider.(*none.None); !ok {
+			t.Errorf("expected geocoder to be of type *none.None, got %T", provider)
+		}
+	})
+	t.Run("initializing service with different weather providers", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			env      []string
+			wantName string
+			wantFail bool
+		}{
+			{
+				"open-meteo",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=open-meteo"},
+				"open-meteo",
+				false,
+			},
+			{
+				"wttr",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=wttr"},
+				"wttr",
+				false,
+			},
+			{
+				"wttr with custom base URL",
+				[]string{
+					"WAYBARWEATHER_WEATHER_PROVIDER=wttr",
+					"WAYBARWEATHER_WEATHER_BASE_URL=http://localhost:8002",
+				},
+				"wttr",
+				false,
+			},
+			{
+				"pirateweather without api-key",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=pirateweather"},
+				"pirateweather",
+				true,
+			},
+			{
+				"pirateweather with api-key",
+				[]string{
+					"WAYBARWEATHER_WEATHER_PROVIDER=pirateweather",
+					"WAYBARWEATHER_WEATHER_APIKEY=abc",
+				},
+				"pirateweather",
+				false,
+			},
+			{
+				"unsupported provider",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=invalid"},
+				"",
+				true,
+			},
+			{
+				"provider list starts with the primary provider",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=open-meteo,wttr"},
+				"open-meteo",
+				false,
+			},
+			{
+				"provider list with unsupported fallback",
+				[]string{"WAYBARWEATHER_WEATHER_PROVIDER=open-meteo,invalid"},
+				"",
+				true,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				for _, envVars := range tc.env {
+					vals := strings.Split(envVars, "=")
+					if len(vals) != 2 {
+						t.Fatalf("invalid env var %q", envVars)
+					}
+					t.Setenv(vals[0], vals[1])
+				}
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				provider, err := serv.selectWeatherProvider()
+				if tc.wantFail && err == nil {
+					t.Fatal("expected weather provider selection to fail")
+				}
+				if !tc.wantFail && err != nil {
+					t.Fatalf("failed to select weather provider: %s", err)
+				}
+				if tc.wantFail {
+					return
+				}
+				if provider.Name() != tc.wantName {
+					t.Errorf("expected weather provider name to be %q, got %q", tc.wantName, provider.Name())
+				}
+			})
+		}
+	})
+	t.Run("debug mode retains the raw response of the weather provider", func(t *testing.T) {
+		for _, enabled := range []bool{true, false} {
+			t.Run(fmt.Sprintf("debug enabled: %t", enabled), func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_DEBUG_ENABLED", fmt.Sprintf("%t", enabled))
+				fake := fakeapi.New(t)
+				fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				serv.HTTPTransport = fake.Transport()
+				provider, err := serv.selectWeatherProvider()
+				if err != nil {
+					t.Fatalf("failed to select weather provider: %s", err)
+				}
+				if _, err = provider.GetWeather(t.Context(), geobus.Coordinate{Lat: 52.5, Lon: 13.4}); err != nil {
+					t.Fatalf("failed to fetch weather data: %s", err)
+				}
+				retainer, ok := provider.(weather.RawResponseRetainer)
+				if !ok {
+					t.Fatal("expected weather provider to retain raw responses")
+				}
+				if raw, _ := retainer.LastRawResponse(); (len(raw) > 0) != enabled {
+					t.Errorf("expected raw response to be retained: %t, got %d bytes", enabled, len(raw))
+				}
+			})
+		}
+	})
+	t.Run("weather model and elevation are passed to Open-Meteo", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_WEATHER_MODEL", "icon_seamless")
+		t.Setenv("WAYBARWEATHER_WEATHER_ELEVATION", "1850")
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		fake.Expect(fakeapi.OpenMeteoForecast, func(t testing.TB, req *stdhttp.Request) {
+			if got := req.URL.Query().Get("models"); got != "icon_seamless" {
+				t.Errorf("expected models to be %q, got %q", "icon_seamless", got)
+			}
+			if got := req.URL.Query().Get("elevation"); got != "1850" {
+				t.Errorf("expected elevation to be %q, got %q", "1850", got)
+			}
+		})
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.HTTPTransport = fake.Transport()
+		provider, err := serv.selectWeatherProvider()
+		if err != nil {
+			t.Fatalf("failed to select weather provider: %s", err)
+		}
+		if _, err = provider.GetWeather(t.Context(), geobus.Coordinate{Lat: 52.5, Lon: 13.4}); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if len(fake.Requests(fakeapi.OpenMeteoForecast)) != 1 {
+			t.Errorf("expected one request to the Open-Meteo API, got %d",
+				len(fake.Requests(fakeapi.OpenMeteoForecast)))
+		}
+	})
 	t.Run("invalid template configuration should fail", func(t *testing.T) {
 		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{")
 		_, err := testService(t, false)
@@ -225,7 +436,7 @@ func TestService_Run(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to create service: %s", err)
 			}
-			serv.config.Weather.Provider = "invalid"
+			serv.config.Weather.Provider = []string{"invalid"}
 			err = serv.Run(t.Context())
 			if err == nil {
 				t.Fatal("expected service to fail")
@@ -238,797 +449,5761 @@ func TestService_Run(t *testing.T) {
 	})
 }
 
-func TestService_printWeather(t *testing.T) {
-	t.Run("print weather to a buffer", func(t *testing.T) {
-		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
-		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+func TestService_Run_endToEnd(t *testing.T) {
+	fake := fakeapi.New(t)
+	fake.Script(fakeapi.GeoIP,
+		fakeapi.Response{Body: `{"country_code":"DE","region_code":"BE","city":"Berlin","zip_code":"10117",` +
+			`"latitude":52.5126,"longitude":13.3898}`},
+		fakeapi.Response{Body: `{"country_code":"GB","region_code":"ENG","city":"Otley","zip_code":"LS21",` +
+			`"latitude":53.9071,"longitude":-1.6938}`},
+	)
+	fake.Script(fakeapi.NominatimReverse,
+		fakeapi.Response{File: "../../testdata/nominatim_berlin.json"},
+		fakeapi.Response{File: "../../testdata/nominatim_otley.json"},
+	)
+	fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+	fake.Expect(fakeapi.OpenMeteoForecast, func(t testing.TB, req *stdhttp.Request) {
+		if req.URL.Query().Get("latitude") == "" || req.URL.Query().Get("longitude") == "" {
+			t.Errorf("expected weather request to contain the coordinates, got %q", req.URL.RawQuery)
+		}
+	})
+
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path=/nonexistent")
+	t.Setenv("WAYBARWEATHER_LOCALE", "en")
+	t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}: {{.Current.TemperatureStr}}")
+	// The weather update interval is out of step with the GeoIP poll, so that the refresh of the weather job
+	// doesn't coincide with the confirmed location change
+	t.Setenv("WAYBARWEATHER_INTERVALS_WEATHER_UPDATE", "20m")
+	for _, provider := range []string{"GEOAPI", "GPSD", "GEOLOCATION_FILE", "CITYNAME_FILE", "ICHNAEA"} {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_DISABLE_"+provider, "true")
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
 
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		buf := bytes.NewBuffer(nil)
+		serv.HTTPTransport = fake.Transport()
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
 		serv.output = buf
-		serv.weatherIsSet = true
 
-		serv.printWeather(t.Context())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serv.Run(ctx); err != nil {
+				t.Errorf("failed to run service: %s", err)
+			}
+		}()
+		outputs := func() []outputData {
+			var out []outputData
+			dec := json.NewDecoder(strings.NewReader(buf.String()))
+			for dec.More() {
+				var data outputData
+				if err := dec.Decode(&data); err != nil {
+					t.Fatalf("failed to decode output: %s", err)
+				}
+				out = append(out, data)
+			}
+			return out
+		}
 
-		var output outputData
-		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
-			t.Fatalf("failed to unmarshal JSON: %s", err)
+		// The location is looked up on start, which triggers the geocoding, the weather fetch and, after the
+		// minimum render interval, the output
+		time.Sleep(serv.config.Intervals.MinRender)
+		synctest.Wait()
+		out := outputs()
+		if len(out) != 1 {
+			t.Fatalf("expected 1 output after start, got %d", len(out))
 		}
-		if output.Text != "text" {
-			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
+		if out[0].Text != "Berlin: -5.3°C" {
+			t.Errorf("expected text to be %q, got %q", "Berlin: -5.3°C", out[0].Text)
 		}
-		if output.Tooltip != "tooltip" {
-			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
+		if !strings.HasPrefix(out[0].Tooltip, "Berlin, Germany\n") {
+			t.Errorf("expected tooltip to start with the address, got %q", out[0].Tooltip)
 		}
-		wantClasses := 3
-		if len(output.Classes) != wantClasses {
-			t.Errorf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
+		if !slices.Contains(out[0].Classes, OutputClass) || slices.Contains(out[0].Classes, PendingClass) {
+			t.Errorf("expected classes of rendered weather, got %v", out[0].Classes)
 		}
-		if output.Classes[0] != OutputClass {
-			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
+
+		// The output job renders the same weather data on every tick
+		time.Sleep(serv.config.Intervals.Output)
+		synctest.Wait()
+		out = outputs()
+		if len(out) != 2 {
+			t.Fatalf("expected 2 outputs after the first output tick, got %d", len(out))
+		}
+		if out[1].Text != out[0].Text {
+			t.Errorf("expected text of output tick to be %q, got %q", out[0].Text, out[1].Text)
+		}
+
+		// The next GeoIP poll reports a distant location, which has to be confirmed by another poll
+		time.Sleep(time.Minute*15 - serv.config.Intervals.Output)
+		synctest.Wait()
+		out = outputs()
+		if out[len(out)-1].Text != "Berlin: -5.3°C" {
+			t.Errorf("expected text before the location change is confirmed to be %q, got %q", "Berlin: -5.3°C",
+				out[len(out)-1].Text)
+		}
+
+		// The confirmed location is geocoded and renders new weather data
+		time.Sleep(time.Minute * 15)
+		synctest.Wait()
+		out = outputs()
+		if out[len(out)-1].Text != "Otley: -5.3°C" {
+			t.Errorf("expected text after location change to be %q, got %q", "Otley: -5.3°C",
+				out[len(out)-1].Text)
 		}
-		if output.Classes[1] != ColdOutputClass {
-			t.Errorf("expected 2nd class to be %q, got %q", ColdOutputClass, output.Classes[1])
+
+		// The weather job refreshes the weather of the unchanged location after the update interval
+		for endpoint, want := range map[fakeapi.Endpoint]int{
+			fakeapi.GeoIP:             3,
+			fakeapi.NominatimReverse:  2,
+			fakeapi.OpenMeteoForecast: 3,
+		} {
+			if got := len(fake.Requests(endpoint)); got != want {
+				t.Errorf("expected %d requests to %s, got %d", want, endpoint, got)
+			}
 		}
-		if output.Classes[2] != NightOutputClass {
-			t.Errorf("expected 3nd class to be %q, got %q", NightOutputClass, output.Classes[2])
+		if reverse := fake.Requests(fakeapi.NominatimReverse); len(reverse) == 2 {
+			if lat := reverse[1].URL.Query().Get("lat"); lat != "53.907100" {
+				t.Errorf("expected reverse geocoding of the new location, got lat %q", lat)
+			}
 		}
+
+		cancel()
+		<-done
 	})
-	t.Run("print weather to a buffer with corresponding CSS icon classes", func(t *testing.T) {
-		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
-		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "text")
-		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
-		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TOOLTIP", "tooltip")
+}
+
+func TestService_Run_minAccuracy(t *testing.T) {
+	// Without the city, the GeoIP location is only accurate to the country
+	fake := fakeapi.New(t)
+	fake.Script(fakeapi.GeoIP, fakeapi.Response{Body: `{"country_code":"DE","latitude":51.2993,"longitude":9.491}`})
+
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path=/nonexistent")
+	t.Setenv("WAYBARWEATHER_GEOLOCATION_MIN_ACCURACY_M", "15000")
+	t.Setenv("WAYBARWEATHER_GEOLOCATION_MIN_ACCURACY_TIMEOUT", "2m")
+	for _, provider := range []string{"GEOAPI", "GPSD", "GEOLOCATION_FILE", "CITYNAME_FILE", "ICHNAEA"} {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_DISABLE_"+provider, "true")
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
 
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		buf := bytes.NewBuffer(nil)
+		serv.HTTPTransport = fake.Transport()
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
 		serv.output = buf
-		serv.weatherIsSet = true
-		serv.config.Templates.UseCSSIcon = true
 
-		now := time.Now()
-		serv.weather = &weather.Data{
-			Current: weather.Instant{
-				InstantTime: now,
-				Temperature: 10.0,
-				IsDay:       true,
-				WeatherCode: 23,
-			},
-			Forecast: make(map[weather.DayHour]weather.Instant),
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serv.Run(ctx); err != nil {
+				t.Errorf("failed to run service: %s", err)
+			}
+		}()
+		lastOutput := func() outputData {
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			var data outputData
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &data); err != nil {
+				t.Fatalf("failed to decode output: %s", err)
+			}
+			return data
 		}
-		fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
-		fcast := serv.weather.Current
-		fcast.InstantTime = fcastNow
-		fcast.WeatherCode = 15
-		serv.weather.Forecast[weather.NewDayHour(fcastNow)] = fcast
 
-		serv.printWeather(t.Context())
-		var output outputData
-		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
-			t.Fatalf("failed to unmarshal JSON: %s", err)
-		}
-		if output.Text != "text" {
-			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
-		}
-		if output.Tooltip != "tooltip" {
-			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
-		}
-		wantClasses := 3
-		if len(output.Classes) != wantClasses {
-			t.Errorf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
-		}
-		if output.Classes[0] != OutputClass {
-			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
-		}
-		if output.Classes[1] != DayOutputClass {
-			t.Errorf("expected 2nd class to be %q, got %q", DayOutputClass, output.Classes[1])
+		// The coarse location is ignored, so the pending state is shown instead of the weather
+		time.Sleep(serv.config.Intervals.Output + serv.config.Intervals.MinRender)
+		synctest.Wait()
+		if got := len(fake.Requests(fakeapi.GeoIP)); got != 1 {
+			t.Fatalf("expected 1 GeoIP request, got %d", got)
 		}
-		wantCSSIcon := "wmo-23"
-		if output.Classes[2] != wantCSSIcon {
-			t.Errorf("expected 2nd class to be %q, got %q", wantCSSIcon, output.Classes[2])
+		if out := lastOutput(); !slices.Contains(out.Classes, PendingClass) {
+			t.Errorf("expected pending state while no accurate location is found, got %v", out.Classes)
 		}
 
-		buf.Reset()
-		serv.displayAltText = true
-		serv.printWeather(t.Context())
-		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
-			t.Fatalf("failed to unmarshal JSON: %s", err)
-		}
-		if output.Text != "text" {
-			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
-		}
-		if output.Tooltip != "tooltip" {
-			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
-		}
-		wantClasses = 4
-		if len(output.Classes) != wantClasses {
-			t.Errorf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
-		}
-		if output.Classes[0] != OutputClass {
-			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
+		// Once the timeout has passed without an accurate location, the error state is shown
+		time.Sleep(serv.config.GeoLocation.MinAccuracyTimeout)
+		synctest.Wait()
+		out := lastOutput()
+		if !slices.Contains(out.Classes, ErrorClass) {
+			t.Errorf("expected error state after the timeout, got %v", out.Classes)
 		}
-		if output.Classes[1] != AltViewClass {
-			t.Errorf("expected first class to be %q, got %q", AltViewClass, output.Classes[1])
+		if !strings.Contains(out.Tooltip, ErrNoAccurateLocation.Error()) {
+			t.Errorf("expected tooltip to contain %q, got %q", ErrNoAccurateLocation, out.Tooltip)
 		}
-		if output.Classes[2] != DayOutputClass {
-			t.Errorf("expected 2nd class to be %q, got %q", DayOutputClass, output.Classes[2])
+
+		serv.locationLock.RLock()
+		isSet := serv.locationIsSet
+		serv.locationLock.RUnlock()
+		if isSet {
+			t.Error("expected coarse location not to be applied")
 		}
-		wantCSSIcon = "wmo-15"
-		if output.Classes[3] != wantCSSIcon {
-			t.Errorf("expected 3rd class to be %q, got %q", wantCSSIcon, output.Classes[3])
+		if got := len(fake.Requests(fakeapi.OpenMeteoForecast)); got != 0 {
+			t.Errorf("expected no weather request for the coarse location, got %d", got)
 		}
+
+		cancel()
+		<-done
 	})
-	t.Run("print alt_text to a buffer", func(t *testing.T) {
-		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "alt_text")
+}
 
+func TestService_processLocationUpdates(t *testing.T) {
+	t.Run("only the first inaccurate update of a row is logged as warning", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_MIN_ACCURACY_M", "15000")
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		buf := bytes.NewBuffer(nil)
-		serv.output = buf
-		serv.weatherIsSet = true
-		serv.displayAltText = true
-
-		serv.printWeather(t.Context())
+		logBuf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		serv.logger = logger.NewLogger(slog.LevelWarn, logBuf, nil)
+		serv.weatherProv = &weatherProv{}
+		serv.geocoder = &mockGeocoder{}
+		serv.output = io.Discard
 
-		var output outputData
-		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
-			t.Fatalf("failed to unmarshal JSON: %s", err)
+		sub := make(chan geobus.Result, 5)
+		for _, accuracy := range []float64{500000, 500000, 500000, 1000, 500000} {
+			sub <- geobus.Result{Lat: 52.5126, Lon: 13.3898, AccuracyMeters: accuracy, Source: "geoip"}
 		}
-		if output.Text != "alt_text" {
-			t.Errorf("expected Text to be %q, got %q", "alt_text", output.Text)
+		close(sub)
+		serv.processLocationUpdates(t.Context(), sub)
+
+		// The accurate update ends the first row, so that the next inaccurate update is logged again
+		if got := strings.Count(logBuf.String(), "less accurate than required"); got != 2 {
+			t.Errorf("expected 2 warnings, got %d: %s", got, logBuf)
 		}
 	})
-	t.Run("print weather returns when weather is not set", func(t *testing.T) {
+}
+
+func TestService_Run_shutdown(t *testing.T) {
+	fake := fakeapi.New(t)
+	fake.Script(fakeapi.GeoIP, fakeapi.Response{Body: `{"country_code":"DE","region_code":"BE","city":"Berlin",` +
+		`"zip_code":"10117","latitude":52.5126,"longitude":13.3898}`})
+	fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+	// The weather API doesn't answer, so the weather fetch is still in flight on shutdown
+	fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{Block: true})
+
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path=/nonexistent")
+	for _, provider := range []string{"GEOAPI", "GPSD", "GEOLOCATION_FILE", "CITYNAME_FILE", "ICHNAEA"} {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_DISABLE_"+provider, "true")
+	}
+
+	// synctest.Test fails if goroutines of the bubble are left blocked, so it asserts that none leaked as well
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		buf := bytes.NewBuffer(nil)
-		serv.output = buf
-		serv.printWeather(t.Context())
-		if buf.Len() != 0 {
-			t.Errorf("expected output buffer to be empty, got %q", buf.String())
-		}
-	})
-	t.Run("output is empty on failing writer", func(t *testing.T) {
+		serv.HTTPTransport = slowCancelTransport{RoundTripper: fake.Transport(), delay: time.Second}
+		serv.output = &syncBuffer{buf: bytes.NewBuffer(nil)}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serv.Run(ctx); err != nil {
+				t.Errorf("failed to run service: %s", err)
+			}
+		}()
+		synctest.Wait()
+		if got := len(fake.Requests(fakeapi.OpenMeteoForecast)); got != 1 {
+			t.Fatalf("expected weather request to be in flight, got %d requests", got)
+		}
+
+		start := time.Now()
+		cancel()
+		<-done
+		if elapsed := time.Since(start); elapsed != time.Second {
+			t.Errorf("expected service to shut down once the cancelled request returned, took %s", elapsed)
+		}
+		// The in-flight request was cancelled and its fetch finished before Run returned
+		serv.weatherLock.RLock()
+		fetchErr := serv.fetchErr
+		serv.weatherLock.RUnlock()
+		if !errors.Is(fetchErr, context.Canceled) {
+			t.Errorf("expected weather fetch to fail with %s, got %v", context.Canceled, fetchErr)
+		}
+	})
+}
+
+func TestService_Run_brokenOutput(t *testing.T) {
+	fake := fakeapi.New(t)
+	fake.Script(fakeapi.GeoIP, fakeapi.Response{Body: `{"country_code":"DE","region_code":"BE","city":"Berlin",` +
+		`"zip_code":"10117","latitude":52.5126,"longitude":13.3898}`})
+	fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+	fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path=/nonexistent")
+	for _, provider := range []string{"GEOAPI", "GPSD", "GEOLOCATION_FILE", "CITYNAME_FILE", "ICHNAEA"} {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_DISABLE_"+provider, "true")
+	}
+
+	synctest.Test(t, func(t *testing.T) {
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		serv.output = &failWriter{}
-		serv.weatherIsSet = true
-		serv.printWeather(t.Context())
+		serv.HTTPTransport = fake.Transport()
+		out := newOutputWriter(&reopenablePipe{buf: bytes.NewBuffer(nil), closed: true}, serv.logger)
+		out.brokenTimeout = stdoutBrokenTimeout
+		serv.output = out
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := serv.Run(t.Context()); err != nil {
+				t.Errorf("failed to run service: %s", err)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(stdoutBrokenTimeout + time.Minute):
+			t.Fatal("expected service to shut down once the output stayed broken")
+		}
 	})
-	t.Run("printing weather fails on template rendering", func(t *testing.T) {
-		tests := []struct {
-			name    string
-			confFn  func(*config.Config)
-			tplFn   func(pres *presenter.Presenter, conf *config.Config) error
-			wantErr string
-		}{
-			{
-				name: "text template",
-				confFn: func(c *config.Config) {
-					c.Templates.Text = "{{.AbsolutelyInvalid}}"
-				},
-				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
-					tpl, err := tt.New("text").Parse(conf.Templates.Text)
-					if err != nil {
-						return err
-					}
-					pres.TextTemplate = tpl
-					return nil
-				},
-				wantErr: "text template",
-			},
-			{
-				name: "alternative text template",
-				confFn: func(c *config.Config) {
-					c.Templates.AltText = "{{.AbsolutelyInvalid}}"
-				},
-				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
-					tpl, err := tt.New("alt_text").Parse(conf.Templates.AltText)
-					if err != nil {
-						return err
-					}
-					pres.AltTextTemplate = tpl
-					return nil
-				},
-				wantErr: "alt text template",
-			},
-			{
-				name: "tooltip template",
-				confFn: func(c *config.Config) {
-					c.Templates.Tooltip = "{{.AbsolutelyInvalid}}"
-				},
-				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
-					tpl, err := tt.New("tooltip").Parse(conf.Templates.Tooltip)
-					if err != nil {
-						return err
-					}
-					pres.TooltipTemplate = tpl
-					return nil
-				},
-				wantErr: "tooltip template",
-			},
-			{
-				name: "alternative tooltip template",
-				confFn: func(c *config.Config) {
-					c.Templates.AltTooltip = "{{.AbsolutelyInvalid}}"
-				},
-				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
-					tpl, err := tt.New("alt_tooltip").Parse(conf.Templates.AltTooltip)
-					if err != nil {
-						return err
-					}
-					pres.AltTooltipTemplate = tpl
-					return nil
-				},
-				wantErr: "alt tooltip template",
-			},
+}
+
+// slowCancelTransport delays the failure of cancelled requests, like a provider that takes a moment to
+// return after the cancellation.
+type slowCancelTransport struct {
+	stdhttp.RoundTripper
+	delay time.Duration
+}
+
+func (tr slowCancelTransport) RoundTrip(req *stdhttp.Request) (*stdhttp.Response, error) {
+	res, err := tr.RoundTripper.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		time.Sleep(tr.delay)
+	}
+	return res, err
+}
+
+func TestService_awaitWork(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
 		}
+		release := make(chan struct{})
+		serv.work.Go(func() {
+			<-release
+		})
+
+		start := time.Now()
+		if serv.awaitWork(time.Second) {
+			t.Error("expected unfinished work to exceed the grace period")
+		}
+		if elapsed := time.Since(start); elapsed != time.Second {
+			t.Errorf("expected to wait for the grace period of %s, waited %s", time.Second, elapsed)
+		}
+
+		close(release)
+		if !serv.awaitWork(time.Second) {
+			t.Error("expected finished work to be awaited")
+		}
+	})
+}
+
+func TestService_renderOutput(t *testing.T) {
+	t.Run("rapid render triggers are coalesced into a single output", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
 
-		for _, tc := range tests {
 			serv, err := testService(t, false)
 			if err != nil {
 				t.Fatalf("failed to create service: %s", err)
 			}
-			tc.confFn(serv.config)
-			if err = tc.tplFn(serv.presenter, serv.config); err != nil {
-				t.Fatalf("failed to update presenter template: %s", err)
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+			serv.output = buf
+			go serv.renderOutput(ctx)
+
+			for range 10 {
+				serv.requestRender(TriggerSchedule)
 			}
-			serv.weatherIsSet = true
+			synctest.Wait()
+			if buf.String() != "" {
+				t.Errorf("expected no output before the minimum render interval, got %q", buf.String())
+			}
+			time.Sleep(serv.config.Intervals.MinRender)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected exactly 1 output line, got %d", lines)
+			}
+		})
+	})
+	t.Run("outputs are at least the minimum render interval apart", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
 
-			logBuf := bytes.NewBuffer(nil)
-			serv.logger = logger.NewLogger(slog.LevelError, logBuf, nil)
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+			serv.output = buf
+			go serv.renderOutput(ctx)
 
-			buf := bytes.NewBuffer(nil)
+			serv.requestRender(TriggerSchedule)
+			time.Sleep(serv.config.Intervals.MinRender)
+			synctest.Wait()
+			serv.requestRender(TriggerSchedule)
+			time.Sleep(serv.config.Intervals.MinRender / 2)
+			serv.requestRender(TriggerSchedule)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected 1 output line within the minimum render interval, got %d", lines)
+			}
+			time.Sleep(serv.config.Intervals.MinRender / 2)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+				t.Errorf("expected 2 output lines after the minimum render interval, got %d", lines)
+			}
+		})
+	})
+	t.Run("the toggle is printed without waiting for the minimum render interval", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
 			serv.output = buf
-			serv.printWeather(t.Context())
-			wantErr1 := `msg="failed to render weather template" error="failed to render ` + tc.wantErr
-			wantErr2 := `can't evaluate field AbsolutelyInvalid in type presenter.TemplateContext`
-			if !strings.Contains(logBuf.String(), wantErr1) || !strings.Contains(logBuf.String(), wantErr2) {
-				t.Errorf("expected error to contain %q and %q, got %q", wantErr1, wantErr2, logBuf.String())
+			go serv.renderOutput(ctx)
+
+			serv.requestRender(TriggerSignal)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected exactly 1 output line, got %d", lines)
 			}
-		}
+		})
 	})
-	t.Run("hot and cold thresholds return correct output classes", func(t *testing.T) {
-		tests := []struct {
-			name        string
-			weatherData *weather.Data
-			altMode     bool
-			wantClass   string
-		}{
-			{
-				name: "it is hot",
-				weatherData: &weather.Data{
-					Current:  weather.Instant{Temperature: 25},
-					Forecast: make(map[weather.DayHour]weather.Instant),
-				},
-				altMode:   false,
-				wantClass: "hot",
-			},
-			{
-				name: "it is cold",
-				weatherData: &weather.Data{
-					Current:  weather.Instant{Temperature: -25},
-					Forecast: make(map[weather.DayHour]weather.Instant),
-				},
-				altMode:   false,
-				wantClass: "cold",
-			},
-			{
-				name: "it is hot (alt)",
-				weatherData: &weather.Data{
-					Current:  weather.Instant{Temperature: 25},
-					Forecast: make(map[weather.DayHour]weather.Instant),
-				},
-				altMode:   true,
-				wantClass: "hot",
-			},
-			{
-				name: "it is cold (alt)",
-				weatherData: &weather.Data{
-					Current:  weather.Instant{Temperature: -25},
-					Forecast: make(map[weather.DayHour]weather.Instant),
-				},
-				altMode:   true,
-				wantClass: "cold",
-			},
-		}
+	t.Run("the toggle cuts a pending minimum render interval short", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
 
-		for _, tc := range tests {
 			serv, err := testService(t, false)
 			if err != nil {
 				t.Fatalf("failed to create service: %s", err)
 			}
-			serv.config.Weather.HotThreshold = 10
-			serv.config.Weather.ColdThreshold = -10
-			now := time.Now()
-			fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
-			tc.weatherData.Current.InstantTime = now
-			fcast := tc.weatherData.Current
-			fcast.InstantTime = fcastNow
-			tc.weatherData.Forecast[weather.NewDayHour(fcastNow)] = fcast
-			serv.weatherIsSet = true
-			serv.weather = tc.weatherData
-			serv.displayAltText = tc.altMode
-			buf := bytes.NewBuffer(nil)
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
 			serv.output = buf
-			serv.printWeather(t.Context())
+			go serv.renderOutput(ctx)
 
-			var output outputData
-			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
-				t.Fatalf("failed to unmarshal JSON: %s", err)
+			serv.requestRender(TriggerSchedule)
+			time.Sleep(serv.config.Intervals.MinRender / 2)
+			synctest.Wait()
+			serv.requestRender(TriggerSignal)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected exactly 1 output line, got %d", lines)
+			}
+			time.Sleep(serv.config.Intervals.MinRender)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected the pending trigger to be coalesced with the toggle, got %d output lines", lines)
 			}
+		})
+	})
+	t.Run("render triggers are printed immediately without minimum render interval", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_MIN_RENDER", "0s")
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
 
-			found := false
-			for _, class := range output.Classes {
-				if class == tc.wantClass {
-					found = true
-				}
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
 			}
-			if !found {
-				t.Errorf("expected output class to be %q, got %#v", tc.wantClass, output.Classes)
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+			serv.output = buf
+			go serv.renderOutput(ctx)
+
+			serv.requestRender(TriggerSchedule)
+			synctest.Wait()
+			if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+				t.Errorf("expected exactly 1 output line, got %d", lines)
 			}
-		}
+		})
 	})
 }
 
-func TestService_fetchWeather(t *testing.T) {
-	t.Run("fetching weather with mock providers succeeds", func(t *testing.T) {
+func TestService_printWeather(t *testing.T) {
+	t.Run("print weather to a buffer", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "alt_text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		serv.weatherProv = &weatherProv{}
-		serv.fetchWeather(t.Context())
-		if serv.weather == nil {
-			t.Error("expected weather to be set")
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.weatherIsSet = true
+
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
 		}
-		if serv.weather.Current.InstantTime.IsZero() {
-			t.Errorf("expected weather instant time to be set, got %s", serv.weather.Current.InstantTime)
+		if output.Text != "text" {
+			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
 		}
-		if serv.weather.GeneratedAt.IsZero() {
-			t.Errorf("expected weather generated at to be set, got %s", serv.weather.GeneratedAt)
+		if output.Alt != "alt_text" {
+			t.Errorf("expected Alt to be %q, got %q", "alt_text", output.Alt)
 		}
-		wantTemp := 20.0
-		if serv.weather.Current.Temperature != wantTemp {
-			t.Errorf("expected weather temperature to be %f, got %f", wantTemp, serv.weather.Current.Temperature)
+		if output.Tooltip != "tooltip" {
+			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
+		}
+		// Without weather data, there is no temperature for the hot and cold classes
+		wantClasses := 5
+		if len(output.Classes) != wantClasses {
+			t.Fatalf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
+		}
+		if output.Classes[0] != OutputClass {
+			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
+		}
+		if output.Classes[1] != NightOutputClass {
+			t.Errorf("expected 2nd class to be %q, got %q", NightOutputClass, output.Classes[1])
+		}
+		if output.Classes[2] != IsNightClass {
+			t.Errorf("expected 3rd class to be %q, got %q", IsNightClass, output.Classes[2])
+		}
+		if output.Classes[3] != NoForecastClass {
+			t.Errorf("expected 4th class to be %q, got %q", NoForecastClass, output.Classes[3])
+		}
+		if output.Classes[4] != presenter.SeverityCalm {
+			t.Errorf("expected 5th class to be %q, got %q", presenter.SeverityCalm, output.Classes[4])
 		}
 	})
-	t.Run("fetching weather with mock providers fails", func(t *testing.T) {
+	t.Run("severity band class follows the displayed weather", func(t *testing.T) {
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
 		buf := bytes.NewBuffer(nil)
-		serv.logger = logger.NewLogger(slog.LevelError, buf, nil)
-		serv.weatherProv = &weatherProv{shouldFail: true}
-		serv.fetchWeather(t.Context())
-		if serv.weather != nil {
-			t.Errorf("expected weather to not bet set, got: %+v", serv.weather)
+		serv.output = buf
+		now := time.Now()
+		serv.Clock = clock.NewFake(now)
+		serv.presenter.Clock = serv.Clock
+		units := weather.Units{Temperature: "°C", WindSpeed: "km/h", Precipitation: "mm"}
+		serv.weather = &weather.Data{
+			Current: weather.Instant{
+				InstantTime: now, WeatherCode: 95, Temperature: 20, WindGusts: 100, Precipitation: 5,
+				IsDay: true, Units: units,
+			},
+			Forecast: make(map[weather.DayHour]weather.Instant),
 		}
-		wantErr := `msg="failed to fetch weather data" error="intentionally failing" source="mock weather provider"`
-		if !strings.Contains(buf.String(), wantErr) {
-			t.Errorf("expected error to contain %q, got %q", wantErr, buf.String())
+		fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
+		serv.weather.Forecast[weather.NewDayHour(fcastNow)] = weather.Instant{
+			InstantTime: fcastNow, WeatherCode: 61, Temperature: 10, WindSpeed: 15, Precipitation: 2,
+			IsDay: true, Units: units,
 		}
-	})
-}
-
-func TestService_selectProvider(t *testing.T) {
-	tests := []struct {
-		name       string
-		confFn     func(*config.Config)
-		shouldFail bool
-	}{
-		{
-			name: "all providers enabled",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = false
-				c.GeoLocation.DisableGeoIP = false
-				c.GeoLocation.DisableGeolocationFile = false
-				c.GeoLocation.DisableCitynameFile = false
-				c.GeoLocation.DisableGPSD = false
-				c.GeoLocation.DisableICHNAEA = false
-			},
-			shouldFail: false,
-		},
-		{
-			name: "only geo api",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = false
-				c.GeoLocation.DisableGeoIP = true
-				c.GeoLocation.DisableGeolocationFile = true
-				c.GeoLocation.DisableCitynameFile = true
-				c.GeoLocation.DisableGPSD = true
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: false,
-		},
-		{
-			name: "only geo ip",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = true
-				c.GeoLocation.DisableGeoIP = false
-				c.GeoLocation.DisableGeolocationFile = true
-				c.GeoLocation.DisableCitynameFile = true
-				c.GeoLocation.DisableGPSD = true
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: false,
-		},
-		{
-			name: "only geolocation file",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = true
-				c.GeoLocation.DisableGeoIP = true
-				c.GeoLocation.DisableGeolocationFile = false
-				c.GeoLocation.DisableCitynameFile = true
-				c.GeoLocation.DisableGPSD = true
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: false,
-		},
-		{
-			name: "only cityname file",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = true
-				c.GeoLocation.DisableGeoIP = true
-				c.GeoLocation.DisableGeolocationFile = true
-				c.GeoLocation.DisableCitynameFile = false
-				c.GeoLocation.DisableGPSD = true
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: false,
-		},
-		{
-			name: "only gpsd",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = true
-				c.GeoLocation.DisableGeoIP = true
-				c.GeoLocation.DisableGeolocationFile = true
-				c.GeoLocation.DisableCitynameFile = true
-				c.GeoLocation.DisableGPSD = false
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: false,
-		},
-		{
-			name: "no provider fails",
-			confFn: func(c *config.Config) {
-				c.GeoLocation.DisableGeoAPI = true
-				c.GeoLocation.DisableGeoIP = true
-				c.GeoLocation.DisableGeolocationFile = true
-				c.GeoLocation.DisableCitynameFile = true
-				c.GeoLocation.DisableGPSD = true
-				c.GeoLocation.DisableICHNAEA = true
-			},
-			shouldFail: true,
-		},
-	}
+		serv.weatherIsSet = true
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			serv, err := testService(t, false)
-			if err != nil {
-				t.Fatalf("failed to create service: %s", err)
+		for _, tc := range []struct {
+			altMode bool
+			want    string
+		}{{false, presenter.SeveritySevere}, {true, presenter.SeverityMild}} {
+			buf.Reset()
+			serv.displayAltText = tc.altMode
+			serv.printWeather(t.Context(), TriggerSchedule)
+			var output outputData
+			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("failed to unmarshal JSON: %s", err)
 			}
-			tc.confFn(serv.config)
-			serv.geocoder = new(mockGeocoder)
-
-			_, err = serv.selectGeobusProviders()
-			if !tc.shouldFail && err != nil {
-				t.Fatalf("failed to select provider: %s", err)
+			if !slices.Contains(output.Classes, tc.want) {
+				t.Errorf("alternative mode %t: expected classes to contain %q, got %v", tc.altMode, tc.want,
+					output.Classes)
 			}
-			if tc.shouldFail && err == nil {
-				t.Fatal("expected select provider to fail")
+		}
+	})
+	t.Run("alt field is omitted if the alternative text is empty", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "{{if false}}alt{{end}}")
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.weatherIsSet = true
+
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var fields map[string]any
+		if err = json.Unmarshal(buf.Bytes(), &fields); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if _, ok := fields["alt"]; ok {
+			t.Errorf("expected alt field to be omitted, got %q", buf.String())
+		}
+		for _, key := range []string{"text", "tooltip", "class"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("expected %s field to be present, got %q", key, buf.String())
 			}
-		})
-	}
-}
+		}
+	})
+	t.Run("night templates and is-night class follow the current conditions", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			isDay       bool
+			altMode     bool
+			wantText    string
+			wantTooltip string
+			wantIsNight bool
+		}{
+			{"day", true, false, "text", "tooltip", false},
+			{"night", false, false, "night", "night tooltip", true},
+			{"night in alternative mode", false, true, "alt_text", "alt_tooltip", true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "alt_text")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TOOLTIP", "alt_tooltip")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT_NIGHT", "night")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP_NIGHT", "night tooltip")
 
-func testService(_ *testing.T, nilLogger bool) (*Service, error) {
-	conf, err := config.New()
-	if err != nil {
-		return nil, err
-	}
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				now := time.Now()
+				serv.Clock = clock.NewFake(now)
+				serv.presenter.Clock = serv.Clock
+				serv.weather = &weather.Data{
+					Current:  weather.Instant{InstantTime: now, Temperature: 15.0, IsDay: tc.isDay},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				}
+				fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
+				serv.weather.Forecast[weather.NewDayHour(fcastNow)] = weather.Instant{
+					InstantTime: fcastNow, Temperature: 15.0, IsDay: true,
+				}
+				serv.weatherIsSet = true
+				serv.displayAltText = tc.altMode
+				serv.printWeather(t.Context(), TriggerSchedule)
 
-	var log *logger.Logger
-	if !nilLogger {
-		log = logger.NewLogger(conf.LogLevel, io.Discard, nil)
-	}
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if output.Text != tc.wantText {
+					t.Errorf("expected Text to be %q, got %q", tc.wantText, output.Text)
+				}
+				if output.Tooltip != tc.wantTooltip {
+					t.Errorf("expected Tooltip to be %q, got %q", tc.wantTooltip, output.Tooltip)
+				}
+				if got := slices.Contains(output.Classes, IsNightClass); got != tc.wantIsNight {
+					t.Errorf("expected is-night class to be present: %t, got %#v", tc.wantIsNight, output.Classes)
+				}
+			})
+		}
+	})
+	t.Run("icon of the current weather is written to the icon file", func(t *testing.T) {
+		iconFile := filepath.Join(t.TempDir(), "current.svg")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Current.IconPath}}")
+		t.Setenv("WAYBARWEATHER_ICONS_FILE_OUTPUT", "true")
+		t.Setenv("WAYBARWEATHER_ICONS_FILE", iconFile)
 
-	lang, err := i18n.New(conf.Locale)
-	if err != nil {
-		return nil, err
-	}
-	serv, err := New(conf, log, lang)
-	if err != nil {
-		return nil, err
-	}
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.weather = &weather.Data{
+			Current:  weather.Instant{InstantTime: time.Now(), WeatherCode: 61, IsDay: true},
+			Forecast: make(map[weather.DayHour]weather.Instant),
+		}
+		serv.weatherIsSet = true
 
-	return serv, nil
-}
+		checkIcon := func(t *testing.T, name string) {
+			t.Helper()
+			data, err := os.ReadFile(iconFile)
+			if err != nil {
+				t.Fatalf("failed to read icon file: %s", err)
+			}
+			want, err := icons.SVG(name)
+			if err != nil {
+				t.Fatalf("failed to read icon: %s", err)
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("expected icon file to hold the %q icon", name)
+			}
+		}
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != iconFile {
+			t.Errorf("expected Text to be the icon path %q, got %q", iconFile, output.Text)
+		}
+		checkIcon(t, "rain")
 
-func TestService_updateLocation(t *testing.T) {
-	t.Run("different coordinates are updated", func(t *testing.T) {
+		serv.weather.Current = weather.Instant{InstantTime: time.Now(), WeatherCode: 0, IsDay: false}
+		serv.printWeather(t.Context(), TriggerSchedule)
+		checkIcon(t, "clear-night")
+	})
+	t.Run("tooltips are escaped for Pango markup", func(t *testing.T) {
 		tests := []struct {
-			name      string
-			latitude  float64
-			longitude float64
-			wantErr   bool
+			name        string
+			markup      string
+			wantTooltip string
 		}{
-			{
-				name:      "positive lat positive lon",
-				latitude:  44.4375,
-				longitude: 26.125,
-				wantErr:   false,
-			},
-			{
-				name:      "negative lat positive lon",
-				latitude:  -33.8688,
-				longitude: 151.2093,
-				wantErr:   false,
-			},
-			{
-				name:      "positive lat negative lon",
-				latitude:  40.7128,
-				longitude: -74.0060,
-				wantErr:   false,
+			{"markup disabled", "false", "Tom & Jerry"},
+			{"markup enabled", "true", "<b>Tom &amp; Jerry</b>"},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}")
+				t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "{{if .Address.City}}{{bold .Address.City}}{{end}}")
+				t.Setenv("WAYBARWEATHER_OUTPUT_TOOLTIP_MARKUP", tc.markup)
+
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.address = geocode.Address{City: "Tom & Jerry"}
+				serv.weather = weather.NewData()
+				serv.weatherIsSet = true
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if output.Text != "Tom & Jerry" {
+					t.Errorf("expected Text to be %q, got %q", "Tom & Jerry", output.Text)
+				}
+				if output.Tooltip != tc.wantTooltip {
+					t.Errorf("expected Tooltip to be %q, got %q", tc.wantTooltip, output.Tooltip)
+				}
+			})
+		}
+	})
+	t.Run("print weather to a buffer with corresponding CSS icon classes", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TOOLTIP", "tooltip")
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.weatherIsSet = true
+		serv.config.Templates.UseCSSIcon = true
+
+		now := time.Now()
+		serv.Clock = clock.NewFake(now)
+		serv.presenter.Clock = serv.Clock
+		serv.weather = &weather.Data{
+			Current: weather.Instant{
+				InstantTime: now,
+				Temperature: 10.0,
+				IsDay:       true,
+				WeatherCode: 23,
 			},
+			Forecast: make(map[weather.DayHour]weather.Instant),
+		}
+		fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
+		fcast := serv.weather.Current
+		fcast.InstantTime = fcastNow
+		fcast.WeatherCode = 15
+		serv.weather.Forecast[weather.NewDayHour(fcastNow)] = fcast
+
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "text" {
+			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
+		}
+		if output.Tooltip != "tooltip" {
+			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
+		}
+		wantClasses := 4
+		if len(output.Classes) != wantClasses {
+			t.Fatalf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
+		}
+		if output.Classes[0] != OutputClass {
+			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
+		}
+		if output.Classes[1] != DayOutputClass {
+			t.Errorf("expected 2nd class to be %q, got %q", DayOutputClass, output.Classes[1])
+		}
+		if output.Classes[2] != presenter.SeverityCalm {
+			t.Errorf("expected 3rd class to be %q, got %q", presenter.SeverityCalm, output.Classes[2])
+		}
+		wantCSSIcon := "wmo-23"
+		if output.Classes[3] != wantCSSIcon {
+			t.Errorf("expected 4th class to be %q, got %q", wantCSSIcon, output.Classes[3])
+		}
+
+		buf.Reset()
+		serv.displayAltText = true
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "text" {
+			t.Errorf("expected Text to be %q, got %q", "text", output.Text)
+		}
+		if output.Tooltip != "tooltip" {
+			t.Errorf("expected Tooltip to be %q, got %q", "tooltip", output.Tooltip)
+		}
+		wantClasses = 5
+		if len(output.Classes) != wantClasses {
+			t.Fatalf("expected Classes to have length %d, got %d", wantClasses, len(output.Classes))
+		}
+		if output.Classes[0] != OutputClass {
+			t.Errorf("expected first class to be %q, got %q", OutputClass, output.Classes[0])
+		}
+		if output.Classes[1] != AltViewClass {
+			t.Errorf("expected first class to be %q, got %q", AltViewClass, output.Classes[1])
+		}
+		if output.Classes[2] != DayOutputClass {
+			t.Errorf("expected 2nd class to be %q, got %q", DayOutputClass, output.Classes[2])
+		}
+		if output.Classes[3] != presenter.SeverityCalm {
+			t.Errorf("expected 4th class to be %q, got %q", presenter.SeverityCalm, output.Classes[3])
+		}
+		wantCSSIcon = "wmo-15"
+		if output.Classes[4] != wantCSSIcon {
+			t.Errorf("expected 5th class to be %q, got %q", wantCSSIcon, output.Classes[4])
+		}
+	})
+	t.Run("print alt_text to a buffer", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT", "alt_text")
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.weatherIsSet = true
+		serv.displayAltText = true
+
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "alt_text" {
+			t.Errorf("expected Text to be %q, got %q", "alt_text", output.Text)
+		}
+	})
+	t.Run("print weather prints the pending state when weather is not set", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		wantText := "⏳ Locating…"
+		if output.Text != wantText {
+			t.Errorf("expected Text to be %q, got %q", wantText, output.Text)
+		}
+		if output.Tooltip != wantText {
+			t.Errorf("expected Tooltip to be %q, got %q", wantText, output.Tooltip)
+		}
+		if !slices.Equal(output.Classes, []string{OutputClass, PendingClass}) {
+			t.Errorf("expected Classes to be %v, got %v", []string{OutputClass, PendingClass}, output.Classes)
+		}
+	})
+	t.Run("pending state, error state and weather output", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Current.Temperature}}")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_PENDING", "pending {{.Address.City}}")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ERROR", "error {{.Address.City}}")
+		t.Setenv("WAYBARWEATHER_WEATHER_FAILURE_THRESHOLD", "2")
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.logger = logger.NewLogger(slog.LevelError, io.Discard, nil)
+		serv.address = geocode.Address{City: "Berlin"}
+		prov := &weatherProv{shouldFail: true}
+		serv.weatherProv = prov
+
+		tests := []struct {
+			name        string
+			fail        bool
+			wantText    string
+			wantTooltip string
+			wantClasses []string
+		}{
+			{"first failure is pending", true, "pending Berlin", "pending Berlin", []string{OutputClass, PendingClass}},
 			{
-				name:      "negative lat negative lon",
-				latitude:  -22.9068,
-				longitude: -43.1729,
-				wantErr:   false,
+				"failure threshold reached", true, "error Berlin", "intentionally failing",
+				[]string{OutputClass, ErrorClass},
 			},
 			{
-				name:      "zero lat zero lon",
-				latitude:  0.0,
-				longitude: 0.0,
-				wantErr:   false,
+				"successful fetch", false, "20", "tooltip",
+				[]string{OutputClass, "clear", NightOutputClass, IsNightClass, NoForecastClass, presenter.SeverityCalm},
 			},
+		}
+		for _, tc := range tests {
+			prov.shouldFail = tc.fail
+			serv.fetchWeather(t.Context())
+			buf := bytes.NewBuffer(nil)
+			serv.output = buf
+			serv.printWeather(t.Context(), TriggerSchedule)
+
+			var output outputData
+			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("%s: failed to unmarshal JSON: %s", tc.name, err)
+			}
+			if output.Text != tc.wantText {
+				t.Errorf("%s: expected Text to be %q, got %q", tc.name, tc.wantText, output.Text)
+			}
+			if output.Tooltip != tc.wantTooltip {
+				t.Errorf("%s: expected Tooltip to be %q, got %q", tc.name, tc.wantTooltip, output.Tooltip)
+			}
+			if !slices.Equal(output.Classes, tc.wantClasses) {
+				t.Errorf("%s: expected Classes to be %v, got %v", tc.name, tc.wantClasses, output.Classes)
+			}
+		}
+
+		// Once weather data has been fetched, failures no longer result in placeholder states
+		prov.shouldFail = true
+		serv.fetchWeather(t.Context())
+		if serv.fetchFailures != 0 {
+			t.Errorf("expected no fetch failures to be counted, got %d", serv.fetchFailures)
+		}
+		serv.weatherIsSet = false
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if buf.Len() != 0 {
+			t.Errorf("expected output buffer to be empty, got %q", buf.String())
+		}
+	})
+	t.Run("output is empty on failing writer", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = &failWriter{}
+		serv.weatherIsSet = true
+		serv.printWeather(t.Context(), TriggerSchedule)
+	})
+	t.Run("printing weather fails on template rendering", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			confFn  func(*config.Config)
+			tplFn   func(pres *presenter.Presenter, conf *config.Config) error
+			wantErr string
+		}{
 			{
-				name:      "extreme north east",
-				latitude:  90.0,
-				longitude: 180.0,
-				wantErr:   false,
+				name: "text template",
+				confFn: func(c *config.Config) {
+					c.Templates.Text = "{{.AbsolutelyInvalid}}"
+				},
+				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
+					tpl, err := tt.New("text").Parse(conf.Templates.Text)
+					if err != nil {
+						return err
+					}
+					pres.TextTemplate = tpl
+					return nil
+				},
+				wantErr: "text template",
 			},
 			{
-				name:      "extreme south west",
-				latitude:  -90.0,
-				longitude: -180.0,
-				wantErr:   false,
+				name: "alternative text template",
+				confFn: func(c *config.Config) {
+					c.Templates.AltText = "{{.AbsolutelyInvalid}}"
+				},
+				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
+					tpl, err := tt.New("alt_text").Parse(conf.Templates.AltText)
+					if err != nil {
+						return err
+					}
+					pres.AltTextTemplate = tpl
+					return nil
+				},
+				wantErr: "alt text template",
 			},
 			{
-				name:      "invalid positive latitude",
-				latitude:  91.0,
-				longitude: 180.0,
-				wantErr:   true,
+				name: "tooltip template",
+				confFn: func(c *config.Config) {
+					c.Templates.Tooltip = "{{.AbsolutelyInvalid}}"
+				},
+				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
+					tpl, err := tt.New("tooltip").Parse(conf.Templates.Tooltip)
+					if err != nil {
+						return err
+					}
+					pres.TooltipTemplate = tpl
+					return nil
+				},
+				wantErr: "tooltip template",
 			},
 			{
-				name:      "invalid positive longitude",
-				latitude:  90.0,
-				longitude: 181.0,
-				wantErr:   true,
-			},
-			{
-				name:      "invalid positive values",
-				latitude:  91.0,
-				longitude: 181.0,
-				wantErr:   true,
-			},
-			{
-				name:      "invalid negative latitude",
-				latitude:  -91.0,
-				longitude: 180.0,
-				wantErr:   true,
-			},
-			{
-				name:      "invalid negative longitude",
-				latitude:  90.0,
-				longitude: -181.0,
-				wantErr:   true,
+				name: "alternative tooltip template",
+				confFn: func(c *config.Config) {
+					c.Templates.AltTooltip = "{{.AbsolutelyInvalid}}"
+				},
+				tplFn: func(pres *presenter.Presenter, conf *config.Config) error {
+					tpl, err := tt.New("alt_tooltip").Parse(conf.Templates.AltTooltip)
+					if err != nil {
+						return err
+					}
+					pres.AltTooltipTemplate = tpl
+					return nil
+				},
+				wantErr: "alt tooltip template",
 			},
+		}
+
+		for _, tc := range tests {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			tc.confFn(serv.config)
+			if err = tc.tplFn(serv.presenter, serv.config); err != nil {
+				t.Fatalf("failed to update presenter template: %s", err)
+			}
+			serv.weatherIsSet = true
+
+			logBuf := bytes.NewBuffer(nil)
+			serv.logger = logger.NewLogger(slog.LevelError, logBuf, nil)
+
+			buf := bytes.NewBuffer(nil)
+			serv.output = buf
+			serv.printWeather(t.Context(), TriggerSchedule)
+			wantErr1 := `msg="failed to render weather template" error="failed to render ` + tc.wantErr
+			wantErr2 := `can't evaluate field AbsolutelyInvalid in type presenter.TemplateContext`
+			if !strings.Contains(logBuf.String(), wantErr1) || !strings.Contains(logBuf.String(), wantErr2) {
+				t.Errorf("expected error to contain %q and %q, got %q", wantErr1, wantErr2, logBuf.String())
+			}
+		}
+	})
+	t.Run("a broken template only replaces its own field", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.presenter.AltTextTemplate = tt.Must(tt.New("alt_text").Parse("{{.AbsolutelyInvalid}}"))
+		serv.weatherIsSet = true
+		logBuf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelError, logBuf, nil)
+
+		for _, altMode := range []bool{false, true, false} {
+			buf := bytes.NewBuffer(nil)
+			serv.output = buf
+			serv.displayAltText = altMode
+			serv.printWeather(t.Context(), TriggerSchedule)
+
+			var output outputData
+			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("failed to unmarshal JSON: %s", err)
+			}
+			wantText := "text"
+			if altMode {
+				wantText = presenter.TemplateErrorMarker
+			}
+			if output.Text != wantText {
+				t.Errorf("expected text to be %q, got %q", wantText, output.Text)
+			}
+			if !altMode && output.Tooltip != "tooltip" {
+				t.Errorf("expected tooltip to be %q, got %q", "tooltip", output.Tooltip)
+			}
+		}
+		if count := strings.Count(logBuf.String(), "failed to render alt text template"); count != 1 {
+			t.Errorf("expected the template error to be logged once, got %d times", count)
+		}
+		if _, ok := serv.status().Errors["render"]; !ok {
+			t.Error("expected the template error to be reported in the status")
+		}
+	})
+	t.Run("a template error is logged again after a successful render", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherIsSet = true
+		serv.output = io.Discard
+		logBuf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelError, logBuf, nil)
+
+		broken := tt.Must(tt.New("text").Parse("{{.AbsolutelyInvalid}}"))
+		serv.presenter.TextTemplate = broken
+		serv.printWeather(t.Context(), TriggerSchedule)
+		serv.printWeather(t.Context(), TriggerSchedule)
+		serv.presenter.TextTemplate = tt.Must(tt.New("text").Parse("text"))
+		serv.printWeather(t.Context(), TriggerSchedule)
+		serv.presenter.TextTemplate = broken
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if count := strings.Count(logBuf.String(), "failed to render text template"); count != 2 {
+			t.Errorf("expected the template error to be logged twice, got %d times", count)
+		}
+	})
+	t.Run("hot and cold thresholds return correct output classes", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			weatherData *weather.Data
+			altMode     bool
+			wantClass   string
+		}{
 			{
-				name:      "invalid negative values",
-				latitude:  -91.0,
-				longitude: -181.0,
-				wantErr:   true,
+				name: "it is hot",
+				weatherData: &weather.Data{
+					Current:  weather.Instant{Temperature: 25},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				},
+				altMode:   false,
+				wantClass: "hot",
 			},
 			{
-				name:      "equator prime meridian",
-				latitude:  0.0,
-				longitude: 0.0,
-				wantErr:   false,
+				name: "it is cold",
+				weatherData: &weather.Data{
+					Current:  weather.Instant{Temperature: -25},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				},
+				altMode:   false,
+				wantClass: "cold",
 			},
 			{
-				name:      "small positive values",
-				latitude:  0.000001,
-				longitude: 0.000001,
-				wantErr:   false,
+				name: "it is hot (alt)",
+				weatherData: &weather.Data{
+					Current:  weather.Instant{Temperature: 25},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				},
+				altMode:   true,
+				wantClass: "hot",
 			},
 			{
-				name:      "small negative values",
-				latitude:  -0.000001,
-				longitude: -0.000001,
-				wantErr:   false,
+				name: "it is cold (alt)",
+				weatherData: &weather.Data{
+					Current:  weather.Instant{Temperature: -25},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				},
+				altMode:   true,
+				wantClass: "cold",
 			},
 		}
 
-		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
-			return &stdhttp.Response{
-				StatusCode: 200,
-				Body:       io.NopCloser(bytes.NewBufferString("{}")),
-				Header:     make(stdhttp.Header),
-			}, nil
+		t.Setenv("WAYBARWEATHER_WEATHER_HOT_THRESHOLD", "10")
+		t.Setenv("WAYBARWEATHER_WEATHER_COLD_THRESHOLD", "-10")
+		for _, tc := range tests {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			now := time.Now()
+			serv.Clock = clock.NewFake(now)
+			serv.presenter.Clock = serv.Clock
+			fcastNow := now.Add(time.Hour * time.Duration(serv.config.Weather.ForecastHours))
+			tc.weatherData.Current.InstantTime = now
+			fcast := tc.weatherData.Current
+			fcast.InstantTime = fcastNow
+			tc.weatherData.Forecast[weather.NewDayHour(fcastNow)] = fcast
+			serv.weatherIsSet = true
+			serv.weather = tc.weatherData
+			serv.displayAltText = tc.altMode
+			buf := bytes.NewBuffer(nil)
+			serv.output = buf
+			serv.printWeather(t.Context(), TriggerSchedule)
+
+			var output outputData
+			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("failed to unmarshal JSON: %s", err)
+			}
+
+			found := false
+			for _, class := range output.Classes {
+				if class == tc.wantClass {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected output class to be %q, got %#v", tc.wantClass, output.Classes)
+			}
+		}
+	})
+	t.Run("frost risk returns the frost output class", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			temp      float64
+			wantFrost bool
+		}{
+			{"frosty night", -5, true},
+			{"mild night", 5, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				now := time.Now()
+				serv.Clock = clock.NewFake(now)
+				serv.presenter.Clock = serv.Clock
+				data := &weather.Data{
+					Current:  weather.Instant{InstantTime: now, Temperature: tc.temp},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				}
+				for at := now; at.Before(now.Add(time.Hour * 48)); at = at.Add(time.Hour) {
+					data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: tc.temp}
+				}
+				serv.weatherIsSet = true
+				serv.weather = data
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				found := false
+				for _, class := range output.Classes {
+					if class == FrostOutputClass {
+						found = true
+					}
+				}
+				if found != tc.wantFrost {
+					t.Errorf("expected frost output class to be present: %t, got %#v", tc.wantFrost, output.Classes)
+				}
+			})
+		}
+	})
+	t.Run("gust warning returns the gust warning output class", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			gust     float64
+			wantGust bool
+		}{
+			{"stormy gusts", 75, true},
+			{"calm gusts", 25, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_WEATHER_GUST_WARNING_THRESHOLD", "60")
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				now := time.Now()
+				serv.Clock = clock.NewFake(now)
+				serv.presenter.Clock = serv.Clock
+				data := &weather.Data{
+					Current:  weather.Instant{InstantTime: now, Temperature: 10},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				}
+				at := now.Add(time.Hour * 2)
+				data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, WindGusts: tc.gust}
+				serv.weatherIsSet = true
+				serv.weather = data
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if found := slices.Contains(output.Classes, GustWarningClass); found != tc.wantGust {
+					t.Errorf("expected gust warning output class to be present: %t, got %#v", tc.wantGust,
+						output.Classes)
+				}
+			})
+		}
+	})
+	t.Run("coarse location returns the approximate location output class", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			threshold  string
+			accuracy   float64
+			wantApprox bool
+		}{
+			{"geoip fix", "10", 300000, true},
+			{"fix just above the threshold", "10", 10001, true},
+			{"fix at the threshold", "10", 10000, false},
+			{"gps fix", "10", 5, false},
+			{"unknown accuracy", "10", 0, false},
+			{"threshold disabled", "0", 300000, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_GEOLOCATION_APPROX_THRESHOLD", tc.threshold)
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				serv.weatherIsSet = true
+				serv.weather = &weather.Data{Current: weather.Instant{InstantTime: time.Now(), Temperature: 10}}
+				serv.geoResult = geobus.Result{AccuracyMeters: tc.accuracy, Source: "geoip"}
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if found := slices.Contains(output.Classes, ApproxLocClass); found != tc.wantApprox {
+					t.Errorf("expected approximate location output class to be present: %t, got %#v",
+						tc.wantApprox, output.Classes)
+				}
+				render, _ := serv.Snapshot()
+				if render.Context.LocationAccuracy != tc.accuracy || render.Context.LocationSource != "geoip" {
+					t.Errorf("expected location accuracy %g from geoip, got %g from %q", tc.accuracy,
+						render.Context.LocationAccuracy, render.Context.LocationSource)
+				}
+			})
+		}
+	})
+	t.Run("context holds the age of the weather data and the location", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.Local)
+		serv.Clock = clock.NewFake(now)
+		serv.presenter.Clock = serv.Clock
+		serv.weatherIsSet = true
+		serv.weather = &weather.Data{
+			GeneratedAt: now.Add(-time.Minute * 40),
+			Current:     weather.Instant{InstantTime: now, Temperature: 10},
+		}
+		serv.geoResult = geobus.Result{Source: "geoip", At: now.Add(-time.Minute * 3)}
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		render, _ := serv.Snapshot()
+		if render.Context.DataAge != time.Minute*40 || !render.Context.DataStale {
+			t.Errorf("expected stale data of 40m, got %s (stale: %t)", render.Context.DataAge,
+				render.Context.DataStale)
+		}
+		if render.Context.LocationAge != time.Minute*3 {
+			t.Errorf("expected location age to be %s, got %s", time.Minute*3, render.Context.LocationAge)
+		}
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if want := "Data from 40 minutes ago"; !strings.Contains(output.Tooltip, want) {
+			t.Errorf("expected tooltip to contain %q, got %q", want, output.Tooltip)
+		}
+	})
+	t.Run("weather without forecast hour returns the no-forecast output class", func(t *testing.T) {
+		now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.Local)
+		fcast := weather.Instant{InstantTime: now.Add(time.Hour * 3), Temperature: 15}
+		tests := []struct {
+			name           string
+			forecast       map[weather.DayHour]weather.Instant
+			wantNoForecast bool
+		}{
+			{"empty forecast", nil, true},
+			{"forecast without the forecast hour", map[weather.DayHour]weather.Instant{
+				weather.NewDayHour(now.Add(time.Hour)): fcast,
+			}, true},
+			{"forecast with the forecast hour", map[weather.DayHour]weather.Instant{
+				weather.NewDayHour(fcast.InstantTime): fcast,
+			}, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				serv.Clock = clock.NewFake(now)
+				serv.presenter.Clock = serv.Clock
+				serv.weatherIsSet = true
+				serv.weather = &weather.Data{
+					Current:  weather.Instant{InstantTime: now, Temperature: 10},
+					Forecast: tc.forecast,
+				}
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if found := slices.Contains(output.Classes, NoForecastClass); found != tc.wantNoForecast {
+					t.Errorf("expected no-forecast output class to be present: %t, got %#v", tc.wantNoForecast,
+						output.Classes)
+				}
+			})
+		}
+	})
+	t.Run("missing day flags are derived from the position of the sun", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{with fcastHourOffset . 1}}{{.IsDay}} {{.ConditionIcon}}{{end}}|"+
+			"{{with fcastHourOffset . 4}}{{.IsDay}} {{.ConditionIcon}}{{end}}")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		zone, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Fatalf("failed to load time zone: %s", err)
+		}
+		// The sun sets around 21:30 in Berlin in June, so 20:00 is still day and 23:00 is night
+		now := time.Date(2025, 6, 14, 19, 0, 0, 0, zone)
+		data := &weather.Data{
+			GeneratedAt: now,
+			Coordinates: geobus.Coordinate{Lat: 52.52, Lon: 13.405},
+			Current:     weather.Instant{InstantTime: now, Temperature: 20, IsDay: true, HasIsDay: true},
+			Forecast:    make(map[weather.DayHour]weather.Instant),
+		}
+		for hour := range 5 {
+			at := now.Add(time.Hour * time.Duration(hour))
+			data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: 20}
+		}
+		serv.Clock = clock.NewFake(now)
+		serv.presenter.Clock = serv.Clock
+		serv.weatherProv = &weatherProv{data: data}
+		serv.fetchWeather(t.Context())
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		evening, night, _ := strings.Cut(output.Text, "|")
+		if !strings.HasPrefix(evening, "true ") || !strings.HasPrefix(night, "false ") {
+			t.Errorf("expected day at 20:00 and night at 23:00, got %q", output.Text)
+		}
+		if strings.TrimPrefix(evening, "true ") == strings.TrimPrefix(night, "false ") {
+			t.Errorf("expected the icons of day and night to differ, got %q", output.Text)
+		}
+	})
+	t.Run("alternative view classes follow the worst condition", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			worst        string
+			wantCategory string
+		}{
+			{"forecast hour", "false", "cloudy"},
+			{"worst condition", "true", "thunderstorm"},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Setenv("WAYBARWEATHER_TEMPLATES_WORST_CONDITION", tc.worst)
+				t.Setenv("WAYBARWEATHER_WEATHER_FORECAST_HOURS", "3")
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				now := time.Date(2026, 6, 10, 12, 15, 0, 0, time.UTC)
+				serv.Clock = clock.NewFake(now)
+				serv.presenter.Clock = serv.Clock
+				data := &weather.Data{
+					Current:  weather.Instant{InstantTime: now, Temperature: 10, IsDay: true},
+					Forecast: make(map[weather.DayHour]weather.Instant),
+				}
+				for i, code := range []int{2, 95, 3} {
+					at := now.Truncate(time.Hour).Add(time.Hour * time.Duration(i+1))
+					data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, WeatherCode: code,
+						Temperature: 10, IsDay: true}
+				}
+				serv.weatherIsSet = true
+				serv.weather = data
+				serv.displayAltText = true
+				buf := bytes.NewBuffer(nil)
+				serv.output = buf
+				serv.printWeather(t.Context(), TriggerSchedule)
+
+				var output outputData
+				if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
+				if !slices.Contains(output.Classes, tc.wantCategory) {
+					t.Errorf("expected output classes to contain %q, got %#v", tc.wantCategory, output.Classes)
+				}
+			})
+		}
+	})
+}
+
+func TestService_refreshWeather(t *testing.T) {
+	t.Run("weather job refreshes stale weather data without location updates", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+			serv.location, serv.locationIsSet = geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}, true
+			serv.fetchWeather(ctx)
+			calls := func() int {
+				serv.weatherLock.RLock()
+				defer serv.weatherLock.RUnlock()
+				return prov.calls
+			}
+			go serv.weatherJob.Start(ctx)
+
+			interval := serv.config.Intervals.WeatherUpdate
+			time.Sleep(interval / 2)
+			synctest.Wait()
+			if got := calls(); got != 1 {
+				t.Fatalf("expected fresh weather data not to be refetched, got %d fetches", got)
+			}
+			time.Sleep(interval)
+			synctest.Wait()
+			if got := calls(); got != 2 {
+				t.Errorf("expected stale weather data to be refetched, got %d fetches", got)
+			}
+			time.Sleep(interval)
+			synctest.Wait()
+			if got := calls(); got != 3 {
+				t.Errorf("expected weather data to be refetched in every interval, got %d fetches", got)
+			}
+		})
+	})
+	t.Run("weather job waits for the location", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		prov := &weatherProv{}
+		serv.weatherProv = prov
+		serv.refreshWeather(t.Context())
+		if prov.calls != 0 {
+			t.Errorf("expected no fetch without location, got %d fetches", prov.calls)
+		}
+	})
+}
+
+func TestService_fetchWeather(t *testing.T) {
+	t.Run("fetching weather with mock providers succeeds", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &weatherProv{}
+		serv.fetchWeather(t.Context())
+		if serv.weather == nil {
+			t.Error("expected weather to be set")
+		}
+		if serv.weather.Current.InstantTime.IsZero() {
+			t.Errorf("expected weather instant time to be set, got %s", serv.weather.Current.InstantTime)
+		}
+		if serv.weather.GeneratedAt.IsZero() {
+			t.Errorf("expected weather generated at to be set, got %s", serv.weather.GeneratedAt)
+		}
+		wantTemp := 20.0
+		if serv.weather.Current.Temperature != wantTemp {
+			t.Errorf("expected weather temperature to be %f, got %f", wantTemp, serv.weather.Current.Temperature)
+		}
+	})
+	t.Run("fetching weather with mock providers fails", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelError, buf, nil)
+		serv.weatherProv = &weatherProv{shouldFail: true}
+		serv.fetchWeather(t.Context())
+		if serv.weather != nil {
+			t.Errorf("expected weather to not bet set, got: %+v", serv.weather)
+		}
+		wantErr := `msg="failed to fetch weather data" error="intentionally failing" source="mock weather provider"`
+		if !strings.Contains(buf.String(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, buf.String())
+		}
+	})
+	t.Run("rejected credentials stop the weather updates", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		logBuf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelError, logBuf, nil)
+		prov := &weatherProv{err: providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))}
+		serv.weatherProv = prov
+		serv.fetchWeather(t.Context())
+		serv.fetchWeather(t.Context())
+		if prov.calls != 1 {
+			t.Errorf("expected the weather provider to be called once, got %d calls", prov.calls)
+		}
+		if count := strings.Count(logBuf.String(), "rejected its credentials"); count != 1 {
+			t.Errorf("expected the authentication error to be logged once, got %d times: %s", count, logBuf)
+		}
+
+		// The error state is shown right away, since the fetch is not retried
+		outBuf := bytes.NewBuffer(nil)
+		serv.output = outBuf
+		serv.printPlaceholder(TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(outBuf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal output: %s", err)
+		}
+		if !slices.Contains(output.Classes, ErrorClass) {
+			t.Errorf("expected output classes to contain %q, got %v", ErrorClass, output.Classes)
+		}
+	})
+	t.Run("rate limits pause the weather updates with an increasing backoff", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.Clock = clock.NewFake(time.Now())
+		fake := serv.Clock.(*clock.Fake)
+		prov := &weatherProv{err: providererr.Wrap(providererr.ErrRateLimited, errors.New("quota exceeded"))}
+		serv.weatherProv = prov
+
+		wantCalls := func(want int) {
+			t.Helper()
+			serv.fetchWeather(t.Context())
+			if prov.calls != want {
+				t.Errorf("expected %d calls to the weather provider, got %d", want, prov.calls)
+			}
+		}
+		wantCalls(1)
+		wantCalls(1)
+		fake.Advance(rateLimitMinBackoff)
+		wantCalls(2)
+		// The second rate limit in a row doubles the backoff
+		fake.Advance(rateLimitMinBackoff)
+		wantCalls(2)
+		fake.Advance(rateLimitMinBackoff)
+		prov.err = nil
+		wantCalls(3)
+		if serv.weather == nil {
+			t.Fatal("expected weather to be set")
+		}
+		if err = serv.weatherGate.allow(fake.Now()); err != nil {
+			t.Errorf("expected the successful fetch to reset the backoff, got %s", err)
+		}
+	})
+	t.Run("transient errors are retried", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		prov := &weatherProv{err: providererr.Wrap(providererr.ErrTransient, errors.New("connection refused"))}
+		serv.weatherProv = prov
+		serv.fetchWeather(t.Context())
+		serv.fetchWeather(t.Context())
+		if prov.calls != 2 {
+			t.Errorf("expected the weather provider to be called twice, got %d calls", prov.calls)
+		}
+	})
+	t.Run("failing weather fetch keeps the previous weather data", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		prov := &weatherProv{}
+		serv.weatherProv = prov
+		serv.fetchWeather(t.Context())
+		previous := serv.weather
+		if previous == nil {
+			t.Fatal("expected weather to be set")
+		}
+
+		prov.shouldFail = true
+		serv.fetchWeather(t.Context())
+		if serv.weather != previous {
+			t.Errorf("expected previous weather data to be kept, got: %+v", serv.weather)
+		}
+		if !serv.weatherIsSet {
+			t.Error("expected weather to still be set")
+		}
+	})
+	t.Run("forecast entries missing in a new fetch are kept", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", `{{(fcastHourOffset . 18).Temperature}}`)
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Now()
+		forecast := func(temp float64, hours int) *weather.Data {
+			data := weather.NewData()
+			data.GeneratedAt = time.Now()
+			data.Current = weather.Instant{InstantTime: now, Temperature: temp, RelativeHumidity: 50}
+			for i := range hours + 1 {
+				at := weather.NewDayHour(now.Add(time.Hour * time.Duration(i)))
+				data.Forecast[at] = weather.Instant{InstantTime: at.In(time.Local), Temperature: temp + float64(i)}
+			}
+			return data
+		}
+		prov := &weatherProv{data: forecast(10, 24)}
+		serv.weatherProv = prov
+		serv.fetchWeather(t.Context())
+
+		prov.data = forecast(5, 12)
+		serv.fetchWeather(t.Context())
+		if len(serv.weather.Forecast) != 25 {
+			t.Errorf("expected 25 forecast entries, got %d", len(serv.weather.Forecast))
+		}
+		if instant, _ := serv.weather.InstantAt(now.Add(time.Hour * 6)); instant.Temperature != 11 {
+			t.Errorf("expected new entry to replace the old one, got temperature %f", instant.Temperature)
+		}
+
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "28" {
+			t.Errorf("expected Text to be %q, got %q", "28", output.Text)
+		}
+	})
+	t.Run("failing primary provider fails over to the fallback provider", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+			serv.logger = logger.NewLogger(slog.LevelDebug, buf, nil)
+			primary := &weatherProv{name: "primary", shouldFail: true}
+			fallback := &weatherProv{name: "fallback"}
+			serv.weatherProv, err = weather.NewFailover(serv.logger, time.Hour, 1, primary, fallback)
+			if err != nil {
+				t.Fatalf("failed to create failover provider: %s", err)
+			}
+
+			serv.fetchWeather(t.Context())
+			if serv.weather == nil {
+				t.Fatal("expected weather to be set by the fallback provider")
+			}
+			if serv.weather.Source != "fallback" {
+				t.Errorf("expected weather source to be %q, got %q", "fallback", serv.weather.Source)
+			}
+			if tplCtx, _ := serv.buildContext(); tplCtx.WeatherSource != "fallback" {
+				t.Errorf("expected template weather source to be %q, got %q", "fallback", tplCtx.WeatherSource)
+			}
+			wantLog := `msg="weather data fetched successfully" source=fallback`
+			if !strings.Contains(buf.String(), wantLog) {
+				t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+			}
+
+			// The fallback provider stays active until the probe interval has passed
+			primary.shouldFail = false
+			serv.fetchWeather(t.Context())
+			if primary.calls != 1 || fallback.calls != 2 {
+				t.Errorf("expected the fallback provider to be used, got %d primary and %d fallback calls",
+					primary.calls, fallback.calls)
+			}
+
+			time.Sleep(time.Hour)
+			serv.fetchWeather(t.Context())
+			if primary.calls != 2 || fallback.calls != 2 {
+				t.Errorf("expected the primary provider to be probed, got %d primary and %d fallback calls",
+					primary.calls, fallback.calls)
+			}
+			if serv.weather.Source != "primary" {
+				t.Errorf("expected weather source to be %q, got %q", "primary", serv.weather.Source)
+			}
+		})
+	})
+	t.Run("weather source defaults to the provider name", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &weatherProv{}
+		serv.fetchWeather(t.Context())
+		if tplCtx, _ := serv.buildContext(); tplCtx.WeatherSource != "mock weather provider" {
+			t.Errorf("expected template weather source to be %q, got %q", "mock weather provider",
+				tplCtx.WeatherSource)
+		}
+	})
+}
+
+func TestService_validateWeather(t *testing.T) {
+	now := time.Now()
+	garbage := func() *weather.Data {
+		data := weather.NewData()
+		data.GeneratedAt = now
+		data.Current = weather.Instant{
+			InstantTime:      now,
+			Temperature:      18.0,
+			RelativeHumidity: 60,
+			PressureMSL:      1013.2,
+			Units:            weather.Units{Temperature: "°C", Pressure: "hPa"},
+		}
+		data.Forecast[weather.NewDayHour(now)] = data.Current
+		data.Forecast[weather.NewDayHour(now.Add(time.Hour))] = weather.Instant{
+			InstantTime: now.Add(time.Hour), Temperature: 17.0, RelativeHumidity: 250, PressureMSL: 1013.0,
+		}
+		data.Forecast[weather.NewDayHour(now.Add(time.Hour*2))] = weather.Instant{
+			InstantTime: now.Add(time.Hour * 2), Temperature: 3276.7, RelativeHumidity: 50, PressureMSL: 1012.0,
+		}
+		return data
+	}
+
+	t.Run("implausible forecast entries are dropped", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", `{{.Current.Temperature}} {{len .Forecasts}}`)
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelWarn, buf, nil)
+		serv.weatherProv = &weatherProv{data: garbage()}
+		serv.fetchWeather(t.Context())
+		if !serv.weatherIsSet {
+			t.Fatal("expected weather to be set")
+		}
+		if len(serv.weather.Forecast) != 1 {
+			t.Errorf("expected 1 forecast entry to be kept, got %d", len(serv.weather.Forecast))
+		}
+		wantLog := `msg="dropped forecast entries with implausible values" source="mock weather provider" ` +
+			`validation.current="" validation.dropped_forecasts=2 ` +
+			`validation.forecast_fields=relative_humidity,temperature`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+
+		out := bytes.NewBuffer(nil)
+		serv.output = out
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(out.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "18 1" {
+			t.Errorf("expected Text to be %q, got %q", "18 1", output.Text)
+		}
+	})
+	t.Run("implausible current conditions keep the previous weather data", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", `{{.Current.Temperature}}`)
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.logger = logger.NewLogger(slog.LevelWarn, buf, nil)
+		prov := &weatherProv{}
+		serv.weatherProv = prov
+		serv.fetchWeather(t.Context())
+		previous := serv.weather
+		if previous == nil {
+			t.Fatal("expected weather to be set")
+		}
+
+		data := garbage()
+		data.Current.Temperature = 3276.7
+		data.Current.RelativeHumidity = 250
+		prov.data = data
+		serv.fetchWeather(t.Context())
+		if serv.weather != previous {
+			t.Errorf("expected previous weather data to be kept, got: %+v", serv.weather)
+		}
+		wantLog := `msg="discarding weather data with implausible current conditions" ` +
+			`source="mock weather provider" validation.current=temperature,relative_humidity`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+
+		out := bytes.NewBuffer(nil)
+		serv.output = out
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var output outputData
+		if err = json.Unmarshal(out.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if output.Text != "20" {
+			t.Errorf("expected Text to be %q, got %q", "20", output.Text)
+		}
+	})
+	t.Run("implausible additional locations are skipped", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.config.Locations = []config.Location{{Name: "Tokyo", Latitude: 35.6764, Longitude: 139.65}}
+		serv.logger = logger.NewLogger(slog.LevelError, io.Discard, nil)
+		data := garbage()
+		data.Current.PressureMSL = 400
+		serv.weatherProv = &weatherProv{data: data}
+		serv.fetchLocationsWeather(t.Context())
+		if _, ok := serv.locations["Tokyo"]; ok {
+			t.Error("expected implausible location to not be set")
+		}
+	})
+}
+
+func TestService_fetchLocationsWeather(t *testing.T) {
+	t.Run("fetching additional locations succeeds", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", `{{with index .Locations "Tokyo"}}{{.Name}}: {{.Current.Temperature}}{{end}}`)
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.config.Locations = []config.Location{
+				{Name: "Berlin", City: "Berlin, Germany"},
+				{Name: "Tokyo", Latitude: 35.6764, Longitude: 139.65},
+			}
+			buf := bytes.NewBuffer(nil)
+			serv.logger = logger.NewLogger(slog.LevelError, buf, nil)
+			serv.geocoder = &mockGeocoder{}
+			serv.weatherProv = &weatherProv{}
+
+			serv.fetchLocationsWeather(t.Context())
+			if _, ok := serv.locations["Berlin"]; ok {
+				t.Error("expected failing location to not be set")
+			}
+			tokyo, ok := serv.locations["Tokyo"]
+			if !ok {
+				t.Fatal("expected location to be set")
+			}
+			if tokyo.Coordinates.Lat != 35.6764 || tokyo.Coordinates.Lon != 139.65 {
+				t.Errorf("unexpected coordinates for location: %+v", tokyo.Coordinates)
+			}
+			wantErr := `msg="failed to resolve additional location"`
+			if !strings.Contains(buf.String(), wantErr) {
+				t.Errorf("expected log to contain %q, got %q", wantErr, buf.String())
+			}
+			select {
+			case <-serv.renderTrigger:
+			default:
+				t.Error("expected the fetched location to trigger an output")
+			}
+
+			out := bytes.NewBuffer(nil)
+			serv.output = out
+			serv.weatherIsSet = true
+			serv.printWeather(t.Context(), TriggerSchedule)
+			var output outputData
+			if err = json.Unmarshal(out.Bytes(), &output); err != nil {
+				t.Fatalf("failed to unmarshal JSON: %s", err)
+			}
+			if output.Text != "Tokyo: 20" {
+				t.Errorf("expected Text to be %q, got %q", "Tokyo: 20", output.Text)
+			}
+		})
+	})
+	t.Run("failing weather provider does not set the location", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.config.Locations = []config.Location{{Name: "Tokyo", Latitude: 35.6764, Longitude: 139.65}}
+		serv.geocoder = &mockGeocoder{}
+		serv.weatherProv = &weatherProv{shouldFail: true}
+		serv.fetchLocationsWeather(t.Context())
+		if len(serv.locations) != 0 {
+			t.Errorf("expected no locations to be set, got %d", len(serv.locations))
+		}
+		select {
+		case trigger := <-serv.renderTrigger:
+			t.Errorf("expected no output to be triggered, got %v", trigger)
+		default:
+		}
+	})
+}
+
+func TestService_newHTTPClient(t *testing.T) {
+	t.Run("provider clients share the transport of the service", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		for _, client := range []*http.Client{serv.newHTTPClient(serv.logger), serv.newHTTPClient(serv.logger)} {
+			if client.Transport != serv.transport {
+				t.Error("expected provider client to use the shared transport")
+			}
+		}
+		if serv.httpClient.Transport != serv.transport {
+			t.Error("expected connectivity probe client to use the shared transport")
+		}
+		if serv.transport.DisableKeepAlives {
+			t.Error("expected keep-alives to be enabled by default")
+		}
+	})
+	t.Run("keep-alives can be disabled", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_PRIVACY_DISABLE_KEEPALIVES", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if !serv.transport.DisableKeepAlives {
+			t.Error("expected keep-alives to be disabled")
+		}
+	})
+	t.Run("HTTPTransport replaces the shared transport", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		transport := &testhelper.MockRoundTripper{}
+		serv.HTTPTransport = transport
+		if client := serv.newHTTPClient(serv.logger); client.Transport != stdhttp.RoundTripper(transport) {
+			t.Error("expected provider client to use the HTTPTransport")
+		}
+	})
+}
+
+func TestService_selectProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		confFn func(*config.Config)
+		// geocoder replaces the mock geocoder, if set
+		geocoder   geocode.Geocoder
+		shouldFail bool
+	}{
+		{
+			name: "all providers enabled",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = false
+				c.GeoLocation.DisableGeoIP = false
+				c.GeoLocation.DisableGeolocationFile = false
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.DisableGPSD = false
+				c.GeoLocation.DisableICHNAEA = false
+			},
+			shouldFail: false,
+		},
+		{
+			name: "only geo api",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = false
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = true
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "only geo ip",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = false
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = true
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "only geolocation file",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = false
+				c.GeoLocation.DisableCitynameFile = true
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "only cityname file",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "only cityname file with the geocoder of the service",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.CitynameFile = "../../testdata/cityname"
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			geocoder: geocode.NewCachedGeocoder(new(mockGeocoder), time.Hour, time.Hour),
+		},
+		{
+			name: "cityname file without geocoder fails",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = false
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.CitynameFile = "../../testdata/cityname"
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			geocoder:   none.New(2),
+			shouldFail: true,
+		},
+		{
+			name: "missing cityname file without geocoder is skipped",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = false
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.CitynameFile = "../../testdata/cityname_missing"
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			geocoder: none.New(2),
+		},
+		{
+			name: "only missing cityname file without geocoder fails",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = false
+				c.GeoLocation.CitynameFile = "../../testdata/cityname_missing"
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			geocoder:   none.New(2),
+			shouldFail: true,
+		},
+		{
+			name: "only gpsd",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = true
+				c.GeoLocation.DisableGPSD = false
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "no provider fails",
+			confFn: func(c *config.Config) {
+				c.GeoLocation.DisableGeoAPI = true
+				c.GeoLocation.DisableGeoIP = true
+				c.GeoLocation.DisableGeolocationFile = true
+				c.GeoLocation.DisableCitynameFile = true
+				c.GeoLocation.DisableGPSD = true
+				c.GeoLocation.DisableICHNAEA = true
+			},
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			tc.confFn(serv.config)
+			serv.geocoder = new(mockGeocoder)
+			if tc.geocoder != nil {
+				serv.geocoder = tc.geocoder
+			}
+
+			_, err = serv.selectGeobusProviders()
+			if !tc.shouldFail && err != nil {
+				t.Fatalf("failed to select provider: %s", err)
+			}
+			if tc.shouldFail && err == nil {
+				t.Fatal("expected select provider to fail")
+			}
+		})
+	}
+}
+
+func testService(t *testing.T, nilLogger bool) (*Service, error) {
+	conf, err := config.New()
+	if err != nil {
+		return nil, err
+	}
+	// Tests that run the service must not listen on the control socket of the user
+	conf.Control.Disabled = true
+	// Tests must neither restore nor overwrite the state of the user, unless they set a state file
+	if os.Getenv("WAYBARWEATHER_STATE_FILE") == "" {
+		conf.State.File = filepath.Join(t.TempDir(), "state.json")
+	}
+
+	var log *logger.Logger
+	if !nilLogger {
+		log = logger.NewLogger(conf.LogLevel, io.Discard, nil)
+	}
+
+	lang, err := i18n.New(conf.Locale)
+	if err != nil {
+		return nil, err
+	}
+	serv, err := New(conf, log, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return serv, nil
+}
+
+// privateBus starts a private D-Bus daemon and uses it as session or system bus for the test. It returns
+// a client connection to the private bus. The test is skipped if dbus-daemon is not available.
+func privateBus(t *testing.T, busType string) *dbus.Conn {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	dir := t.TempDir()
+	address := "unix:path=" + filepath.Join(dir, "bus")
+	busConfig := `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>` + busType + `</type>
+  <listen>` + address + `</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>`
+	configFile := filepath.Join(dir, busType+".conf")
+	if err = os.WriteFile(configFile, []byte(busConfig), 0o600); err != nil {
+		t.Fatalf("failed to write D-Bus config: %s", err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configFile, "--nofork", "--nopidfile")
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	t.Setenv("DBUS_"+strings.ToUpper(busType)+"_BUS_ADDRESS", address)
+
+	var conn *dbus.Conn
+	for range 50 {
+		if conn, err = dbus.Connect(address); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect to private %s bus: %s", busType, err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestService_updateLocation(t *testing.T) {
+	t.Run("different coordinates are updated", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			latitude  float64
+			longitude float64
+			wantErr   bool
+		}{
+			{
+				name:      "positive lat positive lon",
+				latitude:  44.4375,
+				longitude: 26.125,
+				wantErr:   false,
+			},
+			{
+				name:      "negative lat positive lon",
+				latitude:  -33.8688,
+				longitude: 151.2093,
+				wantErr:   false,
+			},
+			{
+				name:      "positive lat negative lon",
+				latitude:  40.7128,
+				longitude: -74.0060,
+				wantErr:   false,
+			},
+			{
+				name:      "negative lat negative lon",
+				latitude:  -22.9068,
+				longitude: -43.1729,
+				wantErr:   false,
+			},
+			{
+				name:      "zero lat zero lon",
+				latitude:  0.0,
+				longitude: 0.0,
+				wantErr:   false,
+			},
+			{
+				name:      "extreme north east",
+				latitude:  90.0,
+				longitude: 180.0,
+				wantErr:   false,
+			},
+			{
+				name:      "extreme south west",
+				latitude:  -90.0,
+				longitude: -180.0,
+				wantErr:   false,
+			},
+			{
+				name:      "invalid positive latitude",
+				latitude:  91.0,
+				longitude: 180.0,
+				wantErr:   true,
+			},
+			{
+				name:      "invalid positive longitude",
+				latitude:  90.0,
+				longitude: 181.0,
+				wantErr:   true,
+			},
+			{
+				name:      "invalid positive values",
+				latitude:  91.0,
+				longitude: 181.0,
+				wantErr:   true,
+			},
+			{
+				name:      "invalid negative latitude",
+				latitude:  -91.0,
+				longitude: 180.0,
+				wantErr:   true,
+			},
+			{
+				name:      "invalid negative longitude",
+				latitude:  90.0,
+				longitude: -181.0,
+				wantErr:   true,
+			},
+			{
+				name:      "invalid negative values",
+				latitude:  -91.0,
+				longitude: -181.0,
+				wantErr:   true,
+			},
+			{
+				name:      "NaN latitude",
+				latitude:  math.NaN(),
+				longitude: 26.125,
+				wantErr:   true,
+			},
+			{
+				name:      "NaN longitude",
+				latitude:  44.4375,
+				longitude: math.NaN(),
+				wantErr:   true,
+			},
+			{
+				name:      "infinite latitude",
+				latitude:  math.Inf(1),
+				longitude: 26.125,
+				wantErr:   true,
+			},
+			{
+				name:      "infinite longitude",
+				latitude:  44.4375,
+				longitude: math.Inf(-1),
+				wantErr:   true,
+			},
+			{
+				name:      "equator prime meridian",
+				latitude:  0.0,
+				longitude: 0.0,
+				wantErr:   false,
+			},
+			{
+				name:      "small positive values",
+				latitude:  0.000001,
+				longitude: 0.000001,
+				wantErr:   false,
+			},
+			{
+				name:      "small negative values",
+				latitude:  -0.000001,
+				longitude: -0.000001,
+				wantErr:   false,
+			},
+		}
+
+		rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewBufferString("{}")),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				serv.output = io.Discard
+				serv.geocoder = &mockGeocoder{}
+
+				httpclient := http.New(serv.logger)
+				httpclient.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+				weatherProv, err := openmeteo.New(httpclient, serv.logger, serv.config.Units)
+				if err != nil {
+					t.Fatalf("failed to create weather provider: %s", err)
+				}
+				serv.weatherProv = weatherProv
+				err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: tc.latitude, Lon: tc.longitude})
+
+				if tc.wantErr && err == nil {
+					t.Error("expected error but got none")
+				}
+				if !tc.wantErr && err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("geocoder fails", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.geocoder = &mockGeocoder{shouldFail: true}
+		err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 44.4375, Lon: 26.125})
+		if err == nil {
+			t.Error("expected error but got none")
+		}
+		wantErr := `failed reverse geocode coordinates: intentionally failing`
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+	})
+	t.Run("coordinates within a geofence skip the geocoder", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.config.Geofences = []config.Geofence{{Name: "Office", Latitude: 52.5076, Longitude: 13.3904, Radius: 500}}
+		coder := &mockGeocoder{}
+		prov := &weatherProv{}
+		serv.geocoder = coder
+		serv.weatherProv = prov
+
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 52.5080, Lon: 13.3910}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if coder.calls != 0 {
+			t.Errorf("expected the geocoder not to be called, got %d calls", coder.calls)
+		}
+		if serv.address.Label != "Office" || serv.address.City != "Office" || serv.address.DisplayName != "Office" {
+			t.Errorf("expected the address to be the geofence, got %+v", serv.address)
+		}
+		if prov.calls != 1 {
+			t.Errorf("expected the weather to be fetched, got %d calls", prov.calls)
+		}
+
+		// Leaving the geofence updates the address, even though the position didn't change significantly
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 52.5140, Lon: 13.3904}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if coder.calls != 1 {
+			t.Errorf("expected the geocoder to be called after leaving the geofence, got %d calls", coder.calls)
+		}
+		if serv.address.Label != "" || !strings.HasPrefix(serv.address.DisplayName, "Test Location") {
+			t.Errorf("expected the geocoded address after leaving the geofence, got %+v", serv.address)
+		}
+		if serv.locationNeedsUpdate(geobus.Coordinate{Lat: 52.5141, Lon: 13.3904}) {
+			t.Error("expected no update outside of the geofence without a significant change")
+		}
+	})
+	t.Run("geocoder rejecting its credentials does not block the weather update", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		coder := &mockGeocoder{err: providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))}
+		prov := &weatherProv{}
+		serv.geocoder = coder
+		serv.weatherProv = prov
+
+		for _, coords := range []geobus.Coordinate{{Lat: 52.52, Lon: 13.405}, {Lat: 48.1375, Lon: 11.575}} {
+			if err = serv.updateLocation(t.Context(), coords); err != nil {
+				t.Fatalf("failed to update location: %s", err)
+			}
+		}
+		if coder.calls != 1 {
+			t.Errorf("expected the geocoder to be called once, got %d calls", coder.calls)
+		}
+		if prov.calls != 2 {
+			t.Errorf("expected the weather to be fetched for both locations, got %d calls", prov.calls)
+		}
+		if serv.location.Lat != 48.1375 {
+			t.Errorf("expected location to be updated, got %+v", serv.location)
+		}
+	})
+	t.Run("near-identical coordinates only trigger one update", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		coder := &mockGeocoder{}
+		prov := &weatherProv{}
+		serv.geocoder = coder
+		serv.weatherProv = prov
+
+		coords := []geobus.Coordinate{
+			{Lat: 52.5200, Lon: 13.4050},
+			{Lat: 52.5210, Lon: 13.4060},
+			{Lat: 52.5200, Lon: 13.4050},
+			{Lat: 52.5190, Lon: 13.4040},
+		}
+		for _, c := range coords {
+			if err = serv.updateLocation(t.Context(), c); err != nil {
+				t.Fatalf("failed to update location: %s", err)
+			}
+		}
+		if coder.calls != 1 {
+			t.Errorf("expected geocoder to be called once, got %d", coder.calls)
+		}
+		if prov.calls != 1 {
+			t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+		}
+	})
+	t.Run("significant location change triggers an update", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		coder := &mockGeocoder{}
+		prov := &weatherProv{}
+		serv.geocoder = coder
+		serv.weatherProv = prov
+
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 52.5200, Lon: 13.4050}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 48.1351, Lon: 11.5820}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if prov.calls != 2 {
+			t.Errorf("expected weather provider to be called twice, got %d", prov.calls)
+		}
+	})
+	t.Run("stale weather data forces an update", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		coder := &mockGeocoder{}
+		prov := &weatherProv{}
+		serv.geocoder = coder
+		serv.weatherProv = prov
+
+		fakeClock := clock.NewFake(time.Now())
+		serv.Clock = fakeClock
+
+		coords := geobus.Coordinate{Lat: 52.5200, Lon: 13.4050}
+		if err = serv.updateLocation(t.Context(), coords); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		fakeClock.Advance(serv.config.Intervals.WeatherUpdate - time.Second)
+		if err = serv.updateLocation(t.Context(), coords); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if prov.calls != 1 {
+			t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+		}
+		fakeClock.Advance(time.Second)
+		if err = serv.updateLocation(t.Context(), coords); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if coder.calls != 2 {
+			t.Errorf("expected geocoder to be called twice, got %d", coder.calls)
+		}
+		if prov.calls != 2 {
+			t.Errorf("expected weather provider to be called twice, got %d", prov.calls)
+		}
+	})
+	t.Run("none geocoder replaces the address with the coordinates", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.geocoder = none.New(2)
+		serv.weatherProv = &weatherProv{}
+
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 52.5200, Lon: 13.4050}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 48.1351, Lon: 11.5820}); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		want := "48.14°N 11.58°E"
+		if serv.address.DisplayName != want {
+			t.Errorf("expected address display name to be %q, got %q", want, serv.address.DisplayName)
+		}
+	})
+}
+
+func TestMatchGeofence(t *testing.T) {
+	campus := config.Geofence{Name: "Campus", Latitude: 52.5076, Longitude: 13.3904, Radius: 1000}
+	office := config.Geofence{Name: "Office", Latitude: 52.5080, Longitude: 13.3910, Radius: 100}
+	home := config.Geofence{Name: "Home", Latitude: 48.1375, Longitude: 11.575, Radius: 200}
+	tests := []struct {
+		name   string
+		fences []config.Geofence
+		coords geobus.Coordinate
+		want   string
+	}{
+		{"no geofences", nil, geobus.Coordinate{Lat: 52.5080, Lon: 13.3910}, ""},
+		{"center of a geofence", []config.Geofence{home}, geobus.Coordinate{Lat: 48.1375, Lon: 11.575}, "Home"},
+		{"within the radius", []config.Geofence{home}, geobus.Coordinate{Lat: 48.1390, Lon: 11.575}, "Home"},
+		{"outside of the radius", []config.Geofence{home}, geobus.Coordinate{Lat: 48.1400, Lon: 11.575}, ""},
+		{
+			"overlapping geofences pick the smallest radius", []config.Geofence{campus, office, home},
+			geobus.Coordinate{Lat: 52.5081, Lon: 13.3911}, "Office",
+		},
+		{
+			"order of overlapping geofences doesn't matter", []config.Geofence{office, campus},
+			geobus.Coordinate{Lat: 52.5081, Lon: 13.3911}, "Office",
+		},
+		{
+			"larger geofence outside of the smaller one", []config.Geofence{office, campus},
+			geobus.Coordinate{Lat: 52.5030, Lon: 13.3904}, "Campus",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fence, ok := matchGeofence(tc.fences, tc.coords)
+			if ok != (tc.want != "") || fence.Name != tc.want {
+				t.Errorf("expected geofence %q, got %q (found: %t)", tc.want, fence.Name, ok)
+			}
+		})
+	}
+}
+
+func TestProviderGate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	t.Run("gate is open by default", func(t *testing.T) {
+		var gate providerGate
+		if err := gate.allow(now); err != nil {
+			t.Errorf("expected gate to be open, got %s", err)
+		}
+	})
+	t.Run("authentication errors disable the provider", func(t *testing.T) {
+		var gate providerGate
+		authErr := providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))
+		if _, kind := gate.fail(authErr, now); kind != providererr.ErrAuth {
+			t.Errorf("expected kind to be %s, got %v", providererr.ErrAuth, kind)
+		}
+		if _, kind := gate.fail(authErr, now); kind != ErrProviderDisabled {
+			t.Errorf("expected kind of the closed gate to be %s, got %v", ErrProviderDisabled, kind)
+		}
+		gate.succeed()
+		if err := gate.allow(now.Add(time.Hour * 24)); !errors.Is(err, ErrProviderDisabled) {
+			t.Errorf("expected error to be %s, got %v", ErrProviderDisabled, err)
+		}
+	})
+	t.Run("rate limits back off up to the maximum", func(t *testing.T) {
+		var gate providerGate
+		limitErr := providererr.Wrap(providererr.ErrRateLimited, errors.New("quota exceeded"))
+		want := rateLimitMinBackoff
+		at := now
+		for range 10 {
+			backoff, kind := gate.fail(limitErr, at)
+			if kind != providererr.ErrRateLimited {
+				t.Fatalf("expected kind to be %s, got %v", providererr.ErrRateLimited, kind)
+			}
+			if backoff != want {
+				t.Errorf("expected backoff to be %s, got %s", want, backoff)
+			}
+			if err := gate.allow(at.Add(backoff - time.Second)); !errors.Is(err, ErrProviderPaused) {
+				t.Errorf("expected error to be %s, got %v", ErrProviderPaused, err)
+			}
+			at = at.Add(backoff)
+			if err := gate.allow(at); err != nil {
+				t.Errorf("expected gate to be open after the backoff, got %s", err)
+			}
+			want = min(want*2, rateLimitMaxBackoff)
+		}
+		gate.succeed()
+		if backoff, _ := gate.fail(limitErr, at); backoff != rateLimitMinBackoff {
+			t.Errorf("expected the backoff to be reset, got %s", backoff)
+		}
+	})
+	t.Run("other errors keep the gate open", func(t *testing.T) {
+		var gate providerGate
+		for _, err := range []error{
+			errors.New("intentionally failing"),
+			providererr.Wrap(providererr.ErrTransient, errors.New("connection refused")),
+			providererr.Wrap(providererr.ErrBadResponse, errors.New("invalid JSON")),
+		} {
+			if _, kind := gate.fail(err, now); kind != nil {
+				t.Errorf("expected error %q to keep the gate open, got %s", err, kind)
+			}
+		}
+		if err := gate.allow(now); err != nil {
+			t.Errorf("expected gate to be open, got %s", err)
+		}
+	})
+}
+
+func TestService_outboundCoordinates(t *testing.T) {
+	t.Run("requests to the providers contain the truncated coordinates", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		assertQuery := func(latKey, lonKey string) func(t testing.TB, req *stdhttp.Request) {
+			return func(t testing.TB, req *stdhttp.Request) {
+				if req.URL.Query().Get(latKey) != "52.510000" || req.URL.Query().Get(lonKey) != "13.380000" {
+					t.Errorf("expected request with truncated coordinates, got %q", req.URL.RawQuery)
+				}
+			}
+		}
+		fake.Expect(fakeapi.NominatimReverse, assertQuery("lat", "lon"))
+		fake.Expect(fakeapi.OpenMeteoForecast, assertQuery("latitude", "longitude"))
+		t.Setenv("WAYBARWEATHER_PRIVACY_COORDINATE_PRECISION", "2")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.HTTPTransport = fake.Transport()
+		if serv.geocoder, err = serv.selectGeocodeProvider(serv.config, serv.logger, serv.t.Language()); err != nil {
+			t.Fatalf("failed to select geocode provider: %s", err)
+		}
+		if serv.weatherProv, err = serv.selectWeatherProvider(); err != nil {
+			t.Fatalf("failed to select weather provider: %s", err)
+		}
+
+		coords := geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}
+		if err = serv.updateLocation(t.Context(), coords); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		if len(fake.Requests(fakeapi.NominatimReverse)) != 1 || len(fake.Requests(fakeapi.OpenMeteoForecast)) != 1 {
+			t.Errorf("expected one request to each provider, got %d and %d",
+				len(fake.Requests(fakeapi.NominatimReverse)), len(fake.Requests(fakeapi.OpenMeteoForecast)))
+		}
+		if serv.location != coords {
+			t.Errorf("expected location to keep the full precision %v, got %v", coords, serv.location)
+		}
+	})
+	t.Run("default precision keeps four decimal places", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		coords := geobus.Coordinate{Lat: -33.86889, Lon: 151.20931, Acc: 15}
+		want := geobus.Coordinate{Lat: -33.8688, Lon: 151.2093, Acc: 15}
+		if got := serv.outboundCoordinates(coords); got != want {
+			t.Errorf("expected outbound coordinates to be %v, got %v", want, got)
+		}
+	})
+}
+
+func TestService_selectUnitSystem(t *testing.T) {
+	usAddress := `{"lat":"40.7128","lon":"-74.0060","display_name":"New York, United States",` +
+		`"address":{"city":"New York","country":"United States","country_code":"us"}}`
+	newService := func(t *testing.T, fake *fakeapi.Server) (*Service, *syncBuffer) {
+		t.Helper()
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		serv.logger = logger.NewLogger(slog.LevelDebug, buf, nil)
+		serv.output = io.Discard
+		serv.HTTPTransport = fake.Transport()
+		if serv.geocoder, err = serv.selectGeocodeProvider(serv.config, serv.logger, serv.t.Language()); err != nil {
+			t.Fatalf("failed to select geocode provider: %s", err)
+		}
+		if serv.weatherProv, err = serv.selectWeatherProvider(); err != nil {
+			t.Fatalf("failed to select weather provider: %s", err)
+		}
+		return serv, buf
+	}
+	newYork := geobus.Coordinate{Lat: 40.7128, Lon: -74.006}
+	berlin := geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}
+
+	t.Run("travelling from the US to Germany switches to metric units", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{Body: usAddress},
+			fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo-fahrenheit.json"},
+			fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		t.Setenv("WAYBARWEATHER_UNITS", "auto")
+		serv, buf := newService(t, fake)
+
+		if err := serv.updateLocation(t.Context(), newYork); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		requests := fake.Requests(fakeapi.OpenMeteoForecast)
+		if len(requests) != 1 {
+			t.Fatalf("expected one weather request, got %d", len(requests))
+		}
+		query := requests[0].URL.Query()
+		if query.Get("temperature_unit") != "fahrenheit" || query.Get("wind_speed_unit") != "mph" ||
+			query.Get("precipitation_unit") != "inch" {
+			t.Errorf("expected weather request in imperial units, got %q", requests[0].URL.RawQuery)
+		}
+		if serv.weather.Current.Units.Temperature != "°F" {
+			t.Errorf("expected temperature in °F, got %q", serv.weather.Current.Units.Temperature)
+		}
+
+		if err := serv.updateLocation(t.Context(), berlin); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		requests = fake.Requests(fakeapi.OpenMeteoForecast)
+		if len(requests) != 2 {
+			t.Fatalf("expected the weather to be fetched again, got %d requests", len(requests))
+		}
+		query = requests[1].URL.Query()
+		if query.Has("temperature_unit") || query.Has("wind_speed_unit") || query.Has("precipitation_unit") {
+			t.Errorf("expected weather request in metric units, got %q", requests[1].URL.RawQuery)
+		}
+		if serv.weather.Current.Units.Temperature != "°C" {
+			t.Errorf("expected temperature in °C, got %q", serv.weather.Current.Units.Temperature)
+		}
+		wantLog := `msg="selected unit system by the country of the location" country=DE units=metric ` +
+			`previous=imperial changed=true`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+	})
+	t.Run("unit overrides take precedence over the unit system of the country", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{Body: usAddress})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		t.Setenv("WAYBARWEATHER_UNITS", "auto")
+		t.Setenv("WAYBARWEATHER_UNIT_OVERRIDES_TEMPERATURE", "celsius")
+		serv, _ := newService(t, fake)
+
+		if err := serv.updateLocation(t.Context(), newYork); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		requests := fake.Requests(fakeapi.OpenMeteoForecast)
+		if len(requests) != 1 {
+			t.Fatalf("expected one weather request, got %d", len(requests))
+		}
+		query := requests[0].URL.Query()
+		if query.Has("temperature_unit") || query.Get("wind_speed_unit") != "mph" {
+			t.Errorf("expected weather request in °C and mph, got %q", requests[0].URL.RawQuery)
+		}
+	})
+	t.Run("explicit units don't follow the country", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{Body: usAddress})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		t.Setenv("WAYBARWEATHER_UNITS", "metric")
+		serv, _ := newService(t, fake)
+
+		if err := serv.updateLocation(t.Context(), newYork); err != nil {
+			t.Fatalf("failed to update location: %s", err)
+		}
+		requests := fake.Requests(fakeapi.OpenMeteoForecast)
+		if len(requests) != 1 {
+			t.Fatalf("expected one weather request, got %d", len(requests))
+		}
+		if requests[0].URL.Query().Has("temperature_unit") {
+			t.Errorf("expected weather request in metric units, got %q", requests[0].URL.RawQuery)
+		}
+	})
+	t.Run("address without country keeps the unit system", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_UNITS", "auto")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if serv.selectUnitSystem(geocode.Address{City: "Home"}) {
+			t.Error("expected unit system to be kept")
+		}
+		if serv.unitSystem != weather.UnitSystemMetric {
+			t.Errorf("expected unit system to be %s, got %s", weather.UnitSystemMetric, serv.unitSystem)
+		}
+	})
+}
+
+func TestService_GeoBus(t *testing.T) {
+	serv, err := testService(t, false)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	if serv.GeoBus() == nil {
+		t.Fatal("expected geobus to be non-nil")
+	}
+	if serv.GeoBus() != serv.geobus {
+		t.Error("expected geobus accessor to return the service's geobus")
+	}
+}
+
+func TestService_Snapshot(t *testing.T) {
+	t.Run("no snapshot before the weather data has been rendered", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.SetOutput(buf)
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		if _, ok := serv.Snapshot(); ok {
+			t.Error("expected no snapshot for the pending state")
+		}
+		if buf.Len() == 0 {
+			t.Error("expected pending state to be printed to the output")
+		}
+	})
+	t.Run("snapshot and render hook receive the printed rendering", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Current.Temperature}}")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TOOLTIP", "tooltip")
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		serv.SetOutput(buf)
+		var hooked []Render
+		serv.OnRender = func(render Render) {
+			hooked = append(hooked, render)
+		}
+		now := time.Now()
+		serv.weather = &weather.Data{
+			Current:  weather.Instant{InstantTime: now, Temperature: 15.0, IsDay: true},
+			Forecast: make(map[weather.DayHour]weather.Instant),
+		}
+		serv.weatherIsSet = true
+		serv.printWeather(t.Context(), TriggerSchedule)
+
+		var output outputData
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		render, ok := serv.Snapshot()
+		if !ok {
+			t.Fatal("expected snapshot after the weather data has been rendered")
+		}
+		if render.Text != output.Text || render.Tooltip != output.Tooltip {
+			t.Errorf("expected snapshot to match output %q/%q, got %q/%q", output.Text, output.Tooltip,
+				render.Text, render.Tooltip)
+		}
+		if !slices.Equal(render.Classes, output.Classes) {
+			t.Errorf("expected snapshot classes to be %v, got %v", output.Classes, render.Classes)
+		}
+		if render.Weather != serv.weather {
+			t.Error("expected snapshot to reference the weather data of the service")
+		}
+		if render.Context.Current.Temperature != 15.0 {
+			t.Errorf("expected snapshot context temperature to be %f, got %f", 15.0,
+				render.Context.Current.Temperature)
+		}
+		if len(hooked) != 1 || hooked[0].Text != render.Text {
+			t.Errorf("expected render hook to be called once with the snapshot, got %v", hooked)
+		}
+	})
+}
+
+func TestService_startDBus(t *testing.T) {
+	t.Run("nothing is exported if the D-Bus service is disabled", func(t *testing.T) {
+		client := privateBus(t, "session")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startDBus(t.Context())
+		if serv.dbus != nil {
+			t.Fatal("expected D-Bus object to be nil")
+		}
+		var hasOwner bool
+		if err = client.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, DBusName).
+			Store(&hasOwner); err != nil {
+			t.Fatalf("failed to query name owner: %s", err)
+		}
+		if hasOwner {
+			t.Errorf("expected %s to be unowned on the session bus", DBusName)
+		}
+
+		// Fetching weather data must not fail without D-Bus object
+		serv.weatherProv = &weatherProv{}
+		serv.fetchWeather(t.Context())
+		if serv.weather == nil {
+			t.Error("expected weather to be set")
+		}
+	})
+	t.Run("weather data is exported on the session bus", func(t *testing.T) {
+		client := privateBus(t, "session")
+		t.Setenv("WAYBARWEATHER_DBUS_ENABLED", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startDBus(t.Context())
+		if serv.dbus == nil {
+			t.Fatal("expected D-Bus object to be exported")
+		}
+
+		if err = client.AddMatchSignal(dbus.WithMatchObjectPath(DBusObjectPath),
+			dbus.WithMatchInterface(dbusPropertiesInterface), dbus.WithMatchMember("PropertiesChanged"),
+		); err != nil {
+			t.Fatalf("failed to subscribe to properties changed signal: %s", err)
+		}
+		signals := make(chan *dbus.Signal, 10)
+		client.Signal(signals)
+		obj := client.Object(DBusName, DBusObjectPath)
+
+		// GetWeatherJSON fails until the first weather data has been fetched
+		var data string
+		if err = obj.Call(DBusWeatherInterface+".GetWeatherJSON", 0).Store(&data); err == nil {
+			t.Error("expected GetWeatherJSON to fail without weather data")
+		}
+
+		// A failed fetch does not emit a signal
+		prov := &weatherProv{shouldFail: true}
+		serv.weatherProv = prov
+		serv.logger = logger.NewLogger(slog.LevelError, io.Discard, nil)
+		serv.fetchWeather(t.Context())
+
+		prov.shouldFail = false
+		serv.address = geocode.Address{City: "Berlin", AddressFound: true}
+		serv.fetchWeather(t.Context())
+		select {
+		case sig := <-signals:
+			if len(sig.Body) != 3 || sig.Body[0] != DBusWeatherInterface {
+				t.Fatalf("expected properties changed signal for %s, got %v", DBusWeatherInterface, sig.Body)
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok || len(changed) != 4 {
+				t.Fatalf("expected 4 changed properties, got %v", sig.Body[1])
+			}
+			if changed["City"].Value() != "Berlin" {
+				t.Errorf("expected changed city to be %q, got %v", "Berlin", changed["City"].Value())
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("expected properties changed signal after successful fetch")
+		}
+		select {
+		case sig := <-signals:
+			t.Errorf("expected only one properties changed signal, got %v", sig.Body)
+		default:
+		}
+
+		temp, err := obj.GetProperty(DBusWeatherInterface + ".Temperature")
+		if err != nil {
+			t.Fatalf("failed to get temperature property: %s", err)
+		}
+		if temp.Value() != 20.0 {
+			t.Errorf("expected temperature to be %f, got %v", 20.0, temp.Value())
+		}
+		updateTime, err := obj.GetProperty(DBusWeatherInterface + ".UpdateTime")
+		if err != nil {
+			t.Fatalf("failed to get update time property: %s", err)
+		}
+		if updateTime.Value() != serv.weather.GeneratedAt.Unix() {
+			t.Errorf("expected update time to be %d, got %v", serv.weather.GeneratedAt.Unix(), updateTime.Value())
+		}
+		if err = obj.SetProperty(DBusWeatherInterface+".City", dbus.MakeVariant("Hamburg")); err == nil {
+			t.Error("expected setting a read-only property to fail")
+		}
+
+		if err = obj.Call(DBusWeatherInterface+".GetWeatherJSON", 0).Store(&data); err != nil {
+			t.Fatalf("failed to call GetWeatherJSON: %s", err)
+		}
+		var tplCtx presenter.TemplateContext
+		if err = json.Unmarshal([]byte(data), &tplCtx); err != nil {
+			t.Fatalf("failed to unmarshal weather JSON: %s", err)
+		}
+		if tplCtx.Address.City != "Berlin" {
+			t.Errorf("expected city in weather JSON to be %q, got %q", "Berlin", tplCtx.Address.City)
+		}
+		if tplCtx.Current.Temperature != 20.0 {
+			t.Errorf("expected temperature in weather JSON to be %f, got %f", 20.0, tplCtx.Current.Temperature)
+		}
+
+		var introspection string
+		if err = obj.Call("org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&introspection); err != nil {
+			t.Fatalf("failed to introspect object: %s", err)
+		}
+		if !strings.Contains(introspection, `<property name="Temperature" type="d" access="read">`) {
+			t.Errorf("expected introspection to contain the temperature property, got %s", introspection)
+		}
+	})
+}
+
+func TestService_logProviderHealth(t *testing.T) {
+	t.Run("health of the providers is logged", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		serv.logger = logger.NewLogger(slog.LevelDebug, buf, nil)
+		sub, unsub := serv.geobus.Subscribe(SubID, 1)
+		defer unsub()
+
+		serv.geoOrch = geobus.NewOrchestrator(serv.geobus, SubID, &geoProv{lat: 52.5200, lon: 13.4050})
+		serv.geoOrch.Start(ctx)
+		<-sub
+
+		serv.logProviderHealth(ctx)
+		wantLog := `msg="geolocation provider health" provider="mock geolocation provider" status.last_result=`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+		if !strings.Contains(buf.String(), "status.restarts=0") {
+			t.Errorf("expected log to contain restart count, got %q", buf.String())
+		}
+	})
+	t.Run("nothing is logged without orchestrator", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		serv.logger = logger.NewLogger(slog.LevelDebug, buf, nil)
+		serv.logProviderHealth(t.Context())
+		if buf.String() != "" {
+			t.Errorf("expected no log output, got %q", buf.String())
+		}
+	})
+}
+
+func TestService_subscriptionKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"configured key", "status-daemon", "status-daemon"},
+		{"empty key falls back to default", "", SubID},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.config.GeoLocation.SubscriptionKey = tc.key
+			if got := serv.subscriptionKey(); got != tc.want {
+				t.Errorf("expected subscription key to be %q, got %q", tc.want, got)
+			}
+		})
+	}
+	t.Run("default configuration uses the default key", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if got := serv.subscriptionKey(); got != SubID {
+			t.Errorf("expected subscription key to be %q, got %q", SubID, got)
+		}
+	})
+}
+
+func TestService_attribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		attribution string
+		hide        bool
+		want        string
+	}{
+		{"provider attribution", "", false, "Weather data by mock weather provider"},
+		{"configured attribution replaces the provider attribution", "Data: mock", false, "Data: mock"},
+		{"hidden attribution", "Data: mock", true, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_OUTPUT_ATTRIBUTION", tc.attribution)
+			t.Setenv("WAYBARWEATHER_OUTPUT_HIDE_ATTRIBUTION", fmt.Sprintf("%t", tc.hide))
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.weatherProv = &weatherProv{}
+			if got := serv.attribution(); got != tc.want {
+				t.Errorf("expected attribution to be %q, got %q", tc.want, got)
+			}
+
+			buf := bytes.NewBuffer(nil)
+			serv.output = buf
+			serv.fetchWeather(t.Context())
+			serv.printWeather(t.Context(), TriggerSchedule)
+			var output outputData
+			if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("failed to unmarshal output: %s", err)
+			}
+			lastLine := output.Tooltip[strings.LastIndex(output.Tooltip, "\n")+1:]
+			hasAttribution := lastLine == tc.want
+			if hasAttribution != (tc.want != "") {
+				t.Errorf("expected default tooltip to end with attribution %q, got %q", tc.want, lastLine)
+			}
+		})
+	}
+	t.Run("no attribution without weather provider", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if got := serv.attribution(); got != "" {
+			t.Errorf("expected attribution to be empty, got %q", got)
+		}
+	})
+}
+
+func TestService_refreshLocation(t *testing.T) {
+	t.Run("a fresh location is applied after the refresh", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.output = io.Discard
+			serv.geocoder = &mockGeocoder{}
+			serv.weatherProv = &weatherProv{}
+
+			sub, unsub := serv.geobus.Subscribe(SubID, 1)
+			defer unsub()
+			go serv.processLocationUpdates(ctx, sub)
+
+			prov := &geoProv{lat: 52.5200, lon: 13.4050}
+			serv.geoOrch = geobus.NewOrchestrator(serv.geobus, SubID, prov)
+			serv.geoOrch.Start(ctx)
+			synctest.Wait()
+
+			prov.setPosition(48.1351, 11.5820)
+			if !serv.refreshLocation(ctx, time.Second) {
+				t.Fatal("expected location to be refreshed")
+			}
+			serv.locationLock.RLock()
+			defer serv.locationLock.RUnlock()
+			if serv.location.Lat != 48.1351 || serv.location.Lon != 11.5820 {
+				t.Errorf("expected location to be updated, got %+v", serv.location)
+			}
+		})
+	})
+	t.Run("an unchanged location is applied after the refresh", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.output = io.Discard
+			serv.geocoder = &mockGeocoder{}
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			sub, unsub := serv.geobus.Subscribe(SubID, 1)
+			defer unsub()
+			go serv.processLocationUpdates(ctx, sub)
+
+			serv.geoOrch = geobus.NewOrchestrator(serv.geobus, SubID, &geoProv{lat: 52.5200, lon: 13.4050})
+			serv.geoOrch.Start(ctx)
+			synctest.Wait()
+
+			if !serv.refreshLocation(ctx, time.Second) {
+				t.Fatal("expected location to be refreshed")
+			}
+			if prov.calls != 2 {
+				t.Errorf("expected weather provider to be called twice, got %d", prov.calls)
+			}
+		})
+	})
+	t.Run("refresh times out without a fresh location", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.geoOrch = geobus.NewOrchestrator(serv.geobus, SubID)
+			serv.geoOrch.Start(ctx)
+			if serv.refreshLocation(ctx, time.Second) {
+				t.Fatal("expected location refresh to time out")
+			}
+		})
+	})
+	t.Run("refresh without orchestrator fails", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if serv.refreshLocation(t.Context(), time.Second) {
+			t.Fatal("expected location refresh to fail without orchestrator")
+		}
+	})
+}
+
+func TestService_handleResumeEvent(t *testing.T) {
+	t.Run("consecutive resume events are debounced", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, probes := testResumeService(t, 0)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(networkWakeupDelay)
+			synctest.Wait()
+			if prov.calls != 1 {
+				t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+			}
+			if got := probes.Load(); got != 1 {
+				t.Errorf("expected one connectivity probe, got %d", got)
+			}
+
+			serv.Clock.(*clock.Fake).Advance(time.Second * debounceWindow)
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(networkWakeupDelay)
+			synctest.Wait()
+			if prov.calls != 2 {
+				t.Errorf("expected weather provider to be called twice, got %d", prov.calls)
+			}
+		})
+	})
+	t.Run("resume event does not block", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, _ := testResumeService(t, 0)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			start := time.Now()
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			if elapsed := time.Since(start); elapsed != 0 {
+				t.Errorf("expected resume event to return immediately, took %s", elapsed)
+			}
+			if prov.calls != 0 {
+				t.Errorf("expected weather provider not to be called yet, got %d", prov.calls)
+			}
+			time.Sleep(networkWakeupDelay)
+			synctest.Wait()
+			if prov.calls != 1 {
+				t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+			}
+		})
+	})
+	t.Run("context cancel aborts the pending refresh", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			serv, probes := testResumeService(t, 0)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			serv.handleResumeEvent(ctx, &lastResume)
+			cancel()
+			synctest.Wait()
+			if prov.calls != 0 {
+				t.Errorf("expected weather provider not to be called, got %d", prov.calls)
+			}
+			if got := probes.Load(); got != 0 {
+				t.Errorf("expected no connectivity probe, got %d", got)
+			}
+		})
+	})
+	t.Run("a new resume event cancels the pending refresh", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, _ := testResumeService(t, 0)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(networkWakeupDelay - time.Second)
+			serv.Clock.(*clock.Fake).Advance(time.Second * debounceWindow)
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(networkWakeupDelay)
+			synctest.Wait()
+			if prov.calls != 1 {
+				t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+			}
+		})
+	})
+	t.Run("connectivity probe is retried with increasing delay", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, probes := testResumeService(t, 2)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(networkWakeupDelay + networkWakeupDelay*2)
+			synctest.Wait()
+			if got := probes.Load(); got != 2 {
+				t.Errorf("expected two connectivity probes, got %d", got)
+			}
+			if prov.calls != 0 {
+				t.Errorf("expected weather provider not to be called yet, got %d", prov.calls)
+			}
+			time.Sleep(networkWakeupDelay * 4)
+			synctest.Wait()
+			if got := probes.Load(); got != 3 {
+				t.Errorf("expected three connectivity probes, got %d", got)
+			}
+			if prov.calls != 1 {
+				t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+			}
+		})
+	})
+	t.Run("weather is refreshed once the probe retries are exhausted", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, probes := testResumeService(t, connectivityProbeRetries)
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+
+			var lastResume int64
+			serv.handleResumeEvent(t.Context(), &lastResume)
+			time.Sleep(time.Hour)
+			synctest.Wait()
+			if got := probes.Load(); got != connectivityProbeRetries {
+				t.Errorf("expected %d connectivity probes, got %d", connectivityProbeRetries, got)
+			}
+			if prov.calls != 1 {
+				t.Errorf("expected weather provider to be called once, got %d", prov.calls)
+			}
+		})
+	})
+}
+
+// testResumeService returns a service for resume tests with a fake clock and a mocked connectivity
+// probe that fails the given number of times before it succeeds.
+func testResumeService(t *testing.T, failures int32) (*Service, *atomic.Int32) {
+	t.Helper()
+	serv, err := testService(t, false)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	serv.output = io.Discard
+	serv.Clock = clock.NewFake(time.Now())
+
+	probes := &atomic.Int32{}
+	rtFn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		if probes.Add(1) <= failures {
+			return nil, errors.New("network is unreachable")
+		}
+		return &stdhttp.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+	serv.httpClient.Transport = testhelper.MockRoundTripper{Fn: rtFn}
+
+	return serv, probes
+}
+
+func TestService_HandleSignals(t *testing.T) {
+	t.Run("USR1 signal is handled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		sigChan := make(chan os.Signal, 1)
+		serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			defer serv.SignalSrc.Stop(sigChan)
+			serv.HandleSignals(ctx, sigChan)
+		}()
+
+		sigChan <- syscall.SIGUSR1
+		time.Sleep(time.Millisecond * 100)
+		serv.displayAltLock.RLock()
+		defer serv.displayAltLock.RUnlock()
+		if !serv.displayAltText {
+			t.Errorf("expected alt mode to be enabled, got %t", serv.displayAltText)
+		}
+		cancel()
+	})
+	t.Run("USR1 signal is ignored without the signal toggle", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		t.Setenv("WAYBARWEATHER_OUTPUT_SIGNAL_TOGGLE", "false")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		sigChan := make(chan os.Signal, 1)
+		go serv.HandleSignals(ctx, sigChan)
+
+		sigChan <- syscall.SIGUSR1
+		time.Sleep(time.Millisecond * 100)
+		serv.displayAltLock.RLock()
+		defer serv.displayAltLock.RUnlock()
+		if serv.displayAltText {
+			t.Error("expected alt mode to stay disabled")
+		}
+		if _, err = os.Stat(serv.config.State.File); !os.IsNotExist(err) {
+			t.Errorf("expected no state file to be written, got: %v", err)
+		}
+		cancel()
+	})
+	t.Run("USR1 signal persists the display mode", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		sigChan := make(chan os.Signal, 1)
+		serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			defer serv.SignalSrc.Stop(sigChan)
+			serv.HandleSignals(ctx, sigChan)
+		}()
+
+		for _, want := range []bool{true, false} {
+			sigChan <- syscall.SIGUSR1
+			time.Sleep(time.Millisecond * 100)
+			state, err := loadState(serv.config.State.File)
+			if err != nil {
+				t.Fatalf("failed to load state: %s", err)
+			}
+			if state.DisplayAltText != want {
+				t.Errorf("expected persisted alt mode to be %t, got %t", want, state.DisplayAltText)
+			}
+		}
+		cancel()
+	})
+	t.Run("USR2 signal is handled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		serv.logger = logger.NewLogger(slog.LevelInfo, buf, nil)
+		sigChan := make(chan os.Signal, 1)
+		serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			defer serv.SignalSrc.Stop(sigChan)
+			serv.HandleSignals(ctx, sigChan)
+		}()
+
+		sigChan <- syscall.SIGUSR2
+		time.Sleep(time.Millisecond * 100)
+		wantLog := `msg="currently resolved address" address="" latitude=0 longitude=0`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+		cancel()
+		time.Sleep(time.Millisecond * 100)
+	})
+	t.Run("USR2 signal writes a debug dump in debug mode", func(t *testing.T) {
+		for _, enabled := range []bool{true, false} {
+			t.Run(fmt.Sprintf("debug enabled: %t", enabled), func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				dir := t.TempDir()
+				t.Setenv("WAYBARWEATHER_DEBUG_ENABLED", fmt.Sprintf("%t", enabled))
+				t.Setenv("WAYBARWEATHER_DEBUG_DUMP_DIR", dir)
+				serv, err := testService(t, false)
+				if err != nil {
+					t.Fatalf("failed to create service: %s", err)
+				}
+				buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+				serv.logger = logger.NewLogger(slog.LevelInfo, buf, nil)
+				sigChan := make(chan os.Signal, 1)
+				go serv.HandleSignals(ctx, sigChan)
+
+				sigChan <- syscall.SIGUSR2
+				time.Sleep(time.Millisecond * 100)
+				cancel()
+				files, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatalf("failed to read dump directory: %s", err)
+				}
+				wantFiles := 0
+				if enabled {
+					wantFiles = 1
+				}
+				if len(files) != wantFiles {
+					t.Fatalf("expected %d dump files, got %d", wantFiles, len(files))
+				}
+				if enabled && !strings.Contains(buf.String(), `msg="debug dump written"`) {
+					t.Errorf("expected log to contain the written dump, got %q", buf.String())
+				}
+			})
+		}
+	})
+}
+
+func TestOutputWriter(t *testing.T) {
+	t.Run("broken pipe keeps the latest output and reconnecting emits it", func(t *testing.T) {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("failed to create pipe: %s", err)
+		}
+		defer func() { _ = writer.Close() }()
+		out := newOutputWriter(writer, logger.NewLogger(slog.LevelDebug, io.Discard, nil))
+
+		if _, err = out.Write([]byte("first\n")); err != nil {
+			t.Fatalf("failed to write output: %s", err)
+		}
+		line := make([]byte, 6)
+		if _, err = io.ReadFull(reader, line); err != nil {
+			t.Fatalf("failed to read output: %s", err)
+		}
+		if string(line) != "first\n" {
+			t.Errorf("expected output to be %q, got %q", "first\n", line)
+		}
+
+		// Waybar restarts and the pipe is closed
+		_ = reader.Close()
+		for _, output := range []string{"second\n", "latest\n"} {
+			n, err := out.Write([]byte(output))
+			if err != nil {
+				t.Errorf("expected write to broken pipe to succeed, got %s", err)
+			}
+			if n != len(output) {
+				t.Errorf("expected %d bytes to be written, got %d", len(output), n)
+			}
+		}
+		if !out.broken {
+			t.Error("expected pipe to be detected as broken")
+		}
+
+		// The module is respawned with a fresh pipe
+		reader, writer2, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("failed to create pipe: %s", err)
+		}
+		defer func() { _ = reader.Close() }()
+		defer func() { _ = writer2.Close() }()
+		if err = out.Reconnect(writer2); err != nil {
+			t.Fatalf("failed to reconnect output: %s", err)
+		}
+		line = make([]byte, 7)
+		if _, err = io.ReadFull(reader, line); err != nil {
+			t.Fatalf("failed to read output: %s", err)
+		}
+		if string(line) != "latest\n" {
+			t.Errorf("expected latest output to be emitted on reconnect, got %q", line)
+		}
+		if out.broken {
+			t.Error("expected pipe to be no longer broken")
+		}
+	})
+	t.Run("latest output is emitted as soon as the pipe accepts writes again", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			pipe := &reopenablePipe{buf: bytes.NewBuffer(nil), closed: true}
+			out := newOutputWriter(pipe, logger.NewLogger(slog.LevelDebug, io.Discard, nil))
+			go func() { _ = out.retry(ctx) }()
+
+			if _, err := out.Write([]byte("latest\n")); err != nil {
+				t.Fatalf("expected write to broken pipe to succeed, got %s", err)
+			}
+			time.Sleep(outputRetryInterval * 3)
+			synctest.Wait()
+			if pipe.String() != "" {
+				t.Errorf("expected no output while the pipe is broken, got %q", pipe.String())
+			}
+
+			pipe.reopen()
+			time.Sleep(outputRetryInterval)
+			synctest.Wait()
+			if pipe.String() != "latest\n" {
+				t.Errorf("expected latest output to be emitted once, got %q", pipe.String())
+			}
+			time.Sleep(outputRetryInterval * 3)
+			synctest.Wait()
+			if pipe.String() != "latest\n" {
+				t.Errorf("expected no further output after the pipe was reopened, got %q", pipe.String())
+			}
+		})
+	})
+	t.Run("retry gives up once the pipe stayed broken for the broken timeout", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			pipe := &reopenablePipe{buf: bytes.NewBuffer(nil), closed: true}
+			out := newOutputWriter(pipe, logger.NewLogger(slog.LevelDebug, io.Discard, nil))
+			out.brokenTimeout = stdoutBrokenTimeout
+			errCh := make(chan error, 1)
+			go func() { errCh <- out.retry(t.Context()) }()
+
+			start := time.Now()
+			if _, err := out.Write([]byte("latest\n")); err != nil {
+				t.Fatalf("expected write to broken pipe to succeed, got %s", err)
+			}
+			if err := <-errCh; !errors.Is(err, errOutputClosed) {
+				t.Errorf("expected error to be %s, got %v", errOutputClosed, err)
+			}
+			if elapsed := time.Since(start); elapsed != stdoutBrokenTimeout {
+				t.Errorf("expected retry to give up after %s, took %s", stdoutBrokenTimeout, elapsed)
+			}
+		})
+	})
+	t.Run("retry keeps going without broken timeout", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			pipe := &reopenablePipe{buf: bytes.NewBuffer(nil), closed: true}
+			out := newOutputWriter(pipe, logger.NewLogger(slog.LevelDebug, io.Discard, nil))
+			errCh := make(chan error, 1)
+			go func() { errCh <- out.retry(ctx) }()
+
+			if _, err := out.Write([]byte("latest\n")); err != nil {
+				t.Fatalf("expected write to broken pipe to succeed, got %s", err)
+			}
+			time.Sleep(stdoutBrokenTimeout * 2)
+			synctest.Wait()
+			cancel()
+			if err := <-errCh; err != nil {
+				t.Errorf("expected retry to stop without error, got %s", err)
+			}
+		})
+	})
+	t.Run("other write errors are returned", func(t *testing.T) {
+		out := newOutputWriter(&failWriter{}, logger.NewLogger(slog.LevelDebug, io.Discard, nil))
+		if _, err := out.Write([]byte("output\n")); err == nil {
+			t.Error("expected write to fail")
+		}
+		if out.broken {
+			t.Error("expected pipe not to be detected as broken")
+		}
+	})
+}
+
+func TestService_writeDump(t *testing.T) {
+	t.Run("dump contains the raw response, the weather data, the address and the rendered templates",
+		func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_DEBUG_DUMP_DIR", filepath.Join(t.TempDir(), "dumps"))
+			t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}: {{.Current.Temperature}}")
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			now := time.Date(2026, 1, 16, 14, 30, 0, 0, time.Local)
+			serv.Clock = clock.NewFake(now)
+			serv.presenter.Clock = serv.Clock
+			serv.weatherProv = &rawWeatherProv{raw: []byte(`{"current":{"temperature_2m":-5.3}}`)}
+			serv.address = geocode.Address{City: "Berlin", AddressFound: true}
+			serv.weather = &weather.Data{
+				Current:  weather.Instant{InstantTime: now, Temperature: -5.3},
+				Forecast: make(map[weather.DayHour]weather.Instant),
+			}
+			serv.weatherIsSet = true
+
+			path, err := serv.writeDump()
+			if err != nil {
+				t.Fatalf("failed to write debug dump: %s", err)
+			}
+			wantPath := filepath.Join(serv.config.Debug.DumpDir, "dump-20260116-143000.json")
+			if path != wantPath {
+				t.Errorf("expected dump path to be %q, got %q", wantPath, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read debug dump: %s", err)
+			}
+			var dump struct {
+				Provider    string            `json:"provider"`
+				RawResponse json.RawMessage   `json:"raw_response"`
+				Weather     weather.Data      `json:"weather"`
+				Address     geocode.Address   `json:"address"`
+				Rendered    map[string]string `json:"rendered"`
+			}
+			if err = json.Unmarshal(data, &dump); err != nil {
+				t.Fatalf("failed to unmarshal debug dump: %s", err)
+			}
+			if dump.Provider != "mock weather provider" {
+				t.Errorf("expected provider to be %q, got %q", "mock weather provider", dump.Provider)
+			}
+			var raw map[string]any
+			if err = json.Unmarshal(dump.RawResponse, &raw); err != nil || raw["current"] == nil {
+				t.Errorf("expected raw response to be embedded as JSON, got %s", dump.RawResponse)
+			}
+			if dump.Weather.Current.Temperature != -5.3 {
+				t.Errorf("expected weather temperature to be %f, got %f", -5.3, dump.Weather.Current.Temperature)
+			}
+			if dump.Address.City != "Berlin" {
+				t.Errorf("expected address city to be %q, got %q", "Berlin", dump.Address.City)
+			}
+			if dump.Rendered["text"] != "Berlin: -5.3" {
+				t.Errorf("expected rendered text to be %q, got %q", "Berlin: -5.3", dump.Rendered["text"])
+			}
+		})
+	t.Run("truncated raw responses are embedded as string", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_DEBUG_DUMP_DIR", t.TempDir())
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &rawWeatherProv{raw: []byte(`{"current":{"tempera`), truncated: true}
+
+		path, err := serv.writeDump()
+		if err != nil {
+			t.Fatalf("failed to write debug dump: %s", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read debug dump: %s", err)
+		}
+		var dump struct {
+			RawResponse          string            `json:"raw_response"`
+			RawResponseTruncated bool              `json:"raw_response_truncated"`
+			Rendered             map[string]string `json:"rendered"`
+		}
+		if err = json.Unmarshal(data, &dump); err != nil {
+			t.Fatalf("failed to unmarshal debug dump: %s", err)
+		}
+		if dump.RawResponse != `{"current":{"tempera` || !dump.RawResponseTruncated {
+			t.Errorf("expected truncated raw response as string, got %q (truncated: %t)", dump.RawResponse,
+				dump.RawResponseTruncated)
+		}
+		if dump.Rendered != nil {
+			t.Errorf("expected no rendered templates without weather data, got %v", dump.Rendered)
+		}
+	})
+	t.Run("dump fails if the dump directory can't be created", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatalf("failed to create file: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_DEBUG_DUMP_DIR", filepath.Join(file, "dumps"))
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if _, err = serv.writeDump(); err == nil {
+			t.Error("expected debug dump to fail")
+		}
+	})
+}
+
+func TestService_Preview(t *testing.T) {
+	t.Run("sample data is rendered for the current hour", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_LOCALE", "en")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}: {{.Current.TemperatureStr}}")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_ALT_TEXT",
+			"{{with fcastHourOffset . 3}}{{.ConditionIcon}} {{.TemperatureStr}}{{end}}")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+		serv.Clock = clock.NewFake(now)
+		serv.presenter.Clock = serv.Clock
+
+		rendered, err := serv.Preview(t.Context(), PreviewOptions{Sample: true})
+		if err != nil {
+			t.Fatalf("failed to render preview: %s", err)
+		}
+		if rendered["text"] != "Berlin: -1.5°C" {
+			t.Errorf("expected text to be %q, got %q", "Berlin: -1.5°C", rendered["text"])
+		}
+		if rendered["alt_text"] != "⛅ 1.9°C" {
+			t.Errorf("expected alt text to be %q, got %q", "⛅ 1.9°C", rendered["alt_text"])
+		}
+		for _, field := range PreviewFields {
+			if rendered[field] == "" {
+				t.Errorf("expected %s to be rendered", field)
+			}
+		}
+		if !serv.weather.Current.InstantTime.Equal(now.Truncate(time.Hour)) {
+			t.Errorf("expected sample data to be moved to %s, got %s", now.Truncate(time.Hour),
+				serv.weather.Current.InstantTime)
+		}
+	})
+	t.Run("live data is fetched once for the coordinates", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+		fake.Expect(fakeapi.OpenMeteoForecast, func(t testing.TB, req *stdhttp.Request) {
+			if req.URL.Query().Get("latitude") != "52.512600" || req.URL.Query().Get("longitude") != "13.389800" {
+				t.Errorf("expected weather request for the given coordinates, got %q", req.URL.RawQuery)
+			}
+		})
+		t.Setenv("WAYBARWEATHER_LOCALE", "en")
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}: {{.Current.TemperatureStr}}")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.HTTPTransport = fake.Transport()
+
+		rendered, err := serv.Preview(t.Context(), PreviewOptions{
+			Coordinates: geobus.Coordinate{Lat: 52.5126, Lon: 13.3898},
+		})
+		if err != nil {
+			t.Fatalf("failed to render preview: %s", err)
+		}
+		if rendered["text"] != "Berlin: -5.3°C" {
+			t.Errorf("expected text to be %q, got %q", "Berlin: -5.3°C", rendered["text"])
+		}
+		if len(fake.Requests(fakeapi.OpenMeteoForecast)) != 1 {
+			t.Errorf("expected one request to the Open-Meteo API, got %d",
+				len(fake.Requests(fakeapi.OpenMeteoForecast)))
+		}
+	})
+	t.Run("invalid coordinates fail", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		_, err = serv.Preview(t.Context(), PreviewOptions{Coordinates: geobus.Coordinate{Lat: 91, Lon: 13.4}})
+		if err == nil {
+			t.Fatal("expected preview with invalid coordinates to fail")
+		}
+		if !strings.Contains(err.Error(), "invalid coordinates") {
+			t.Errorf("expected error to contain %q, got %q", "invalid coordinates", err)
+		}
+	})
+	t.Run("failed fetch returns the fetch error", func(t *testing.T) {
+		fake := fakeapi.New(t)
+		fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+		fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{Status: stdhttp.StatusInternalServerError})
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.HTTPTransport = fake.Transport()
+		_, err = serv.Preview(t.Context(), PreviewOptions{Coordinates: geobus.Coordinate{Lat: 52.5, Lon: 13.4}})
+		if err == nil {
+			t.Fatal("expected preview with a failing weather provider to fail")
+		}
+		if !strings.Contains(err.Error(), "failed to fetch weather data") {
+			t.Errorf("expected error to contain %q, got %q", "failed to fetch weather data", err)
+		}
+	})
+}
+
+func TestSampleWeather(t *testing.T) {
+	now := time.Date(2026, 7, 4, 18, 45, 0, 0, time.UTC)
+	addr, data, err := sampleWeather(now)
+	if err != nil {
+		t.Fatalf("failed to load sample weather data: %s", err)
+	}
+	if !addr.AddressFound || addr.City == "" {
+		t.Errorf("expected sample address to be found, got %+v", addr)
+	}
+	if len(data.Forecast) < 24 {
+		t.Errorf("expected sample data to cover at least 24 hours, got %d", len(data.Forecast))
+	}
+	for hour := range 24 {
+		instant, ok := data.InstantAt(now.Add(time.Hour * time.Duration(hour)))
+		if !ok {
+			t.Errorf("expected sample data to have a forecast %d hours from now", hour)
+			continue
+		}
+		if !instant.InstantTime.Equal(now.Truncate(time.Hour).Add(time.Hour * time.Duration(hour))) {
+			t.Errorf("expected forecast time to be moved to %s, got %s",
+				now.Truncate(time.Hour).Add(time.Hour*time.Duration(hour)), instant.InstantTime)
+		}
+	}
+	if validation := data.Validate(); !validation.Clean() {
+		t.Errorf("expected sample data to be plausible, got %+v", validation)
+	}
+}
+
+// notification is a notification received by fakeNotifications
+type notification struct {
+	replaces uint32
+	icon     string
+	summary  string
+	body     string
+}
+
+// fakeNotifications emulates the org.freedesktop.Notifications service on a private session bus.
+type fakeNotifications struct {
+	mu            sync.Mutex
+	notifications []notification
+}
+
+func (f *fakeNotifications) Notify(_ string, replaces uint32, icon, summary, body string, _ []string,
+	_ map[string]dbus.Variant, _ int32,
+) (uint32, *dbus.Error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifications = append(f.notifications, notification{replaces, icon, summary, body})
+	return uint32(len(f.notifications)), nil
+}
+
+func (f *fakeNotifications) received() []notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.notifications)
+}
+
+func TestService_startNotifier(t *testing.T) {
+	now := time.Date(2026, 1, 18, 14, 20, 0, 0, time.UTC)
+	views := func(categories ...string) presenter.TemplateContext {
+		conditions := map[string]string{"cloudy": "Overcast", "rain": "Slight rain", "snow": "Slight snow fall"}
+		tplCtx := presenter.TemplateContext{}
+		for i, category := range categories {
+			view := presenter.WeatherView{
+				Instant:       weather.Instant{InstantTime: now.Truncate(time.Hour).Add(time.Hour * time.Duration(i))},
+				Category:      category,
+				Condition:     conditions[category],
+				ConditionIcon: "🌧️",
+			}
+			if i == 0 {
+				tplCtx.Current = view
+			}
+			tplCtx.Forecasts = append(tplCtx.Forecasts, presenter.ForecastEntry{WeatherView: view})
+		}
+		return tplCtx
+	}
+
+	t.Run("notifications are disabled by default", func(t *testing.T) {
+		privateBus(t, "session")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startNotifier(t.Context())
+		if serv.notifier != nil {
+			t.Fatal("expected notifier to be nil")
+		}
+	})
+	t.Run("condition changes are notified once", func(t *testing.T) {
+		client := privateBus(t, "session")
+		fake := &fakeNotifications{}
+		if err := client.Export(fake, notificationsPath, notificationsName); err != nil {
+			t.Fatalf("failed to export notifications service: %s", err)
+		}
+		if _, err := client.RequestName(notificationsName, dbus.NameFlagDoNotQueue); err != nil {
+			t.Fatalf("failed to request notifications name: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_LOCALE", "de")
+		t.Setenv("WAYBARWEATHER_NOTIFICATIONS_ENABLED", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startNotifier(t.Context())
+		if serv.notifier == nil {
+			t.Fatal("expected notifier to be started")
+		}
+
+		serv.notifier.check(t.Context(), views("cloudy", "cloudy"), now)
+		serv.notifier.check(t.Context(), views("cloudy", "rain"), now)
+		serv.notifier.check(t.Context(), views("cloudy", "rain"), now)
+		serv.notifier.check(t.Context(), views("cloudy", "cloudy"), now)
+		serv.notifier.check(t.Context(), views("cloudy", "rain"), now)
+		serv.notifier.check(t.Context(), views("cloudy", "snow"), now)
+		got := fake.received()
+		want := []notification{
+			{0, "weather-showers", "🌧️ Slight rain", "Erwartet ab 15:00"},
+			{1, "weather-snow", "🌧️ Slight snow fall", "Erwartet ab 15:00"},
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected notifications to be %+v, got %+v", want, got)
+		}
+	})
+	t.Run("notifier is inert without notification service", func(t *testing.T) {
+		privateBus(t, "session")
+		t.Setenv("WAYBARWEATHER_NOTIFICATIONS_ENABLED", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startNotifier(t.Context())
+		if serv.notifier == nil {
+			t.Fatal("expected notifier to be started")
+		}
+		serv.notifier.check(t.Context(), views("cloudy", "rain"), now)
+
+		// Fetching weather data must not fail without notification service
+		serv.weatherProv = &weatherProv{}
+		serv.fetchWeather(t.Context())
+		if serv.weather == nil {
+			t.Error("expected weather to be set")
+		}
+	})
+	t.Run("notifier is not started without session bus", func(t *testing.T) {
+		t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/nonexistent")
+		t.Setenv("WAYBARWEATHER_NOTIFICATIONS_ENABLED", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.startNotifier(t.Context())
+		if serv.notifier != nil {
+			t.Fatal("expected notifier to be nil")
+		}
+	})
+}
+
+func TestUpcomingChange(t *testing.T) {
+	now := time.Date(2026, 1, 18, 14, 20, 0, 0, time.UTC)
+	hour := now.Truncate(time.Hour)
+	categories := []string{"rain", "snow", "thunderstorm"}
+	tests := []struct {
+		name      string
+		current   string
+		forecasts map[int]string
+		wantFound bool
+		wantHour  int
+	}{
+		{"change within the next hour", "cloudy", map[int]string{0: "cloudy", 1: "rain"}, true, 1},
+		{"change later than the next hour", "cloudy", map[int]string{0: "cloudy", 1: "cloudy", 2: "rain"}, false, 0},
+		{"category is not allowed", "clear", map[int]string{0: "clear", 1: "cloudy"}, false, 0},
+		{"condition does not change", "rain", map[int]string{0: "rain", 1: "rain"}, false, 0},
+		{"past hours are ignored", "cloudy", map[int]string{-1: "rain", 0: "cloudy", 1: "cloudy"}, false, 0},
+		{"no forecast", "cloudy", nil, false, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tplCtx := presenter.TemplateContext{Current: presenter.WeatherView{Category: tc.current}}
+			for offset := -1; offset <= 2; offset++ {
+				if category, ok := tc.forecasts[offset]; ok {
+					tplCtx.Forecasts = append(tplCtx.Forecasts, presenter.ForecastEntry{
+						WeatherView: presenter.WeatherView{
+							Instant:  weather.Instant{InstantTime: hour.Add(time.Hour * time.Duration(offset))},
+							Category: category,
+						},
+					})
+				}
+			}
+			change, found := upcomingChange(tplCtx, now, categories)
+			if found != tc.wantFound {
+				t.Fatalf("expected found to be %t, got %t", tc.wantFound, found)
+			}
+			if found && !change.InstantTime.Equal(hour.Add(time.Hour*time.Duration(tc.wantHour))) {
+				t.Errorf("expected change at %s, got %s", hour.Add(time.Hour*time.Duration(tc.wantHour)),
+					change.InstantTime)
+			}
+		})
+	}
+}
+
+func TestService_monitorPowerSource(t *testing.T) {
+	// newService returns a service with its weather job and a geolocation provider, whose intervals are
+	// scaled on battery power
+	newService := func(t *testing.T) (*Service, *job.Job, *geoProv) {
+		t.Helper()
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		provider := &geoProv{}
+		serv.geoProviders = []geobus.Provider{provider}
+		return serv, serv.weatherJob, provider
+	}
+	// waitFor polls the condition until it is met or a timeout is reached
+	waitFor := func(t *testing.T, condition func() bool) bool {
+		t.Helper()
+		for range 50 {
+			if condition() {
+				return true
+			}
+			time.Sleep(time.Millisecond * 100)
+		}
+		return false
+	}
+
+	t.Run("intervals are lengthened on battery and restored on AC", func(t *testing.T) {
+		client := privateBus(t, "system")
+		props, err := prop.Export(client, upowerPath, prop.Map{upowerName: {
+			upowerOnBattery: {Value: true, Emit: prop.EmitTrue},
+		}})
+		if err != nil {
+			t.Fatalf("failed to export UPower properties: %s", err)
+		}
+		if _, err = client.RequestName(upowerName, dbus.NameFlagDoNotQueue); err != nil {
+			t.Fatalf("failed to request UPower name: %s", err)
+		}
+
+		t.Setenv("WAYBARWEATHER_INTERVALS_BATTERY_MULTIPLIER", "3")
+		serv, weatherJob, provider := newService(t)
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		go serv.monitorPowerSource(ctx)
+
+		if !waitFor(t, func() bool { return serv.weatherUpdateInterval() == time.Minute*45 }) {
+			t.Fatalf("expected weather update interval to be lengthened, got %s", serv.weatherUpdateInterval())
+		}
+		if weatherJob.Interval() != time.Minute*45 {
+			t.Errorf("expected weather job interval to be %s, got %s", time.Minute*45, weatherJob.Interval())
+		}
+		if got := provider.Scaled(time.Minute * 15); got != time.Minute*45 {
+			t.Errorf("expected provider period to be %s, got %s", time.Minute*45, got)
+		}
+
+		props.SetMust(upowerName, upowerOnBattery, false)
+		if !waitFor(t, func() bool { return serv.weatherUpdateInterval() == time.Minute*15 }) {
+			t.Fatalf("expected weather update interval to be restored, got %s", serv.weatherUpdateInterval())
+		}
+		if weatherJob.Interval() != time.Minute*15 {
+			t.Errorf("expected weather job interval to be %s, got %s", time.Minute*15, weatherJob.Interval())
+		}
+		if got := provider.Scaled(time.Minute * 15); got != time.Minute*15 {
+			t.Errorf("expected provider period to be %s, got %s", time.Minute*15, got)
+		}
+	})
+	t.Run("intervals are unchanged without UPower", func(t *testing.T) {
+		privateBus(t, "system")
+		t.Setenv("WAYBARWEATHER_INTERVALS_BATTERY_MULTIPLIER", "3")
+		serv, weatherJob, provider := newService(t)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			serv.monitorPowerSource(t.Context())
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second * 5):
+			t.Fatal("expected power source monitoring to stop without UPower")
+		}
+		if serv.weatherUpdateInterval() != time.Minute*15 || weatherJob.Interval() != time.Minute*15 ||
+			provider.Scaled(time.Minute*15) != time.Minute*15 {
+			t.Error("expected intervals to be unchanged")
+		}
+	})
+	t.Run("data is stale after the lengthened interval on battery", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_BATTERY_MULTIPLIER", "3")
+		serv, _, _ := newService(t)
+		client, err := serv.subscribe(controlRequest{})
+		if err != nil {
+			t.Fatalf("failed to subscribe client: %s", err)
+		}
+		serv.setOnBattery(true)
+
+		now := time.Now()
+		data := &weather.Data{GeneratedAt: now.Add(-time.Minute * 40)}
+		tplCtx := serv.presenter.BuildContext(geocode.Address{}, data, now, now, "", time.Time{})
+		if tplCtx.DataStale {
+			t.Error("expected data of 40m not to be stale on battery")
+		}
+		tplCtx = client.currentView().presenter.BuildContext(geocode.Address{}, data, now, now, "", time.Time{})
+		if tplCtx.DataStale {
+			t.Error("expected data of 40m not to be stale for the client on battery")
+		}
+
+		serv.setOnBattery(false)
+		tplCtx = serv.presenter.BuildContext(geocode.Address{}, data, now, now, "", time.Time{})
+		if !tplCtx.DataStale {
+			t.Error("expected data of 40m to be stale on AC")
+		}
+	})
+	t.Run("power source is not monitored without battery multiplier", func(t *testing.T) {
+		serv, _, _ := newService(t)
+		serv.monitorPowerSource(t.Context())
+		serv.setOnBattery(true)
+		if serv.weatherUpdateInterval() != time.Minute*15 {
+			t.Errorf("expected weather update interval to be unchanged, got %s", serv.weatherUpdateInterval())
+		}
+	})
+}
+
+func TestOnBatteryChanged(t *testing.T) {
+	changed := func(iface string, props map[string]dbus.Variant) *dbus.Signal {
+		return &dbus.Signal{Path: upowerPath, Name: dbusPropertiesChanged, Body: []any{iface, props, []string{}}}
+	}
+	tests := []struct {
+		name          string
+		signal        *dbus.Signal
+		wantBattery   bool
+		wantSupported bool
+	}{
+		{"on battery", changed(upowerName, map[string]dbus.Variant{
+			upowerOnBattery: dbus.MakeVariant(true),
+		}), true, true},
+		{"on AC", changed(upowerName, map[string]dbus.Variant{
+			upowerOnBattery: dbus.MakeVariant(false),
+		}), false, true},
+		{"other property", changed(upowerName, map[string]dbus.Variant{
+			"LidIsClosed": dbus.MakeVariant(true),
+		}), false, false},
+		{"other interface", changed("org.freedesktop.UPower.Device", map[string]dbus.Variant{
+			upowerOnBattery: dbus.MakeVariant(true),
+		}), false, false},
+		{"invalid value", changed(upowerName, map[string]dbus.Variant{
+			upowerOnBattery: dbus.MakeVariant("yes"),
+		}), false, false},
+		{"invalid body", &dbus.Signal{Path: upowerPath, Name: dbusPropertiesChanged, Body: []any{upowerName}},
+			false, false},
+		{"nil signal", nil, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			onBattery, ok := onBatteryChanged(tc.signal)
+			if ok != tc.wantSupported || onBattery != tc.wantBattery {
+				t.Errorf("expected %t, %t, got %t, %t", tc.wantBattery, tc.wantSupported, onBattery, ok)
+			}
+		})
+	}
+}
+
+type (
+	weatherProv struct {
+		name       string
+		shouldFail bool
+		// err is returned instead of the weather data if set
+		err   error
+		calls int
+		data  *weather.Data
+	}
+	rawWeatherProv struct {
+		weatherProv
+		raw       []byte
+		truncated bool
+	}
+	failWriter   struct{}
+	mockGeocoder struct {
+		shouldFail bool
+		// err is returned instead of the address if set
+		err   error
+		calls int
+		// accuracy is the accuracy of the coordinates of the last reverse lookup
+		accuracy float64
+	}
+	geoProv struct {
+		geobus.PeriodScale
+
+		mu  sync.Mutex
+		lat float64
+		lon float64
+	}
+	// reopenablePipe fails with EPIPE while it is closed
+	reopenablePipe struct {
+		mu     sync.Mutex
+		buf    *bytes.Buffer
+		closed bool
+	}
+	syncBuffer struct {
+		mu  sync.Mutex
+		buf *bytes.Buffer
+	}
+)
+
+func (f failWriter) Write([]byte) (int, error) { return 0, fmt.Errorf("failed to write") }
+
+func (p *reopenablePipe) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, &os.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}
+	}
+	return p.buf.Write(data)
+}
+
+func (p *reopenablePipe) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.String()
+}
+
+func (p *reopenablePipe) reopen() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = false
+}
+
+func (m *mockGeocoder) Name() string {
+	return "mock geocoder"
+}
+
+func (m *mockGeocoder) Reverse(_ context.Context, coords geobus.Coordinate) (geocode.Address, error) {
+	m.calls++
+	m.accuracy = coords.Acc
+	if m.err != nil {
+		return geocode.Address{}, m.err
+	}
+	if m.shouldFail {
+		return geocode.Address{}, errors.New("intentionally failing")
+	}
+	return geocode.Address{
+		AddressFound: true,
+		Latitude:     coords.Lat,
+		Longitude:    coords.Lon,
+		DisplayName:  fmt.Sprintf("Test Location %.6f,%.6f", coords.Lat, coords.Lon),
+	}, nil
+}
+
+func (c *mockGeocoder) Search(_ context.Context, _ string) (geobus.Coordinate, error) {
+	return geobus.Coordinate{}, errors.New("not implemented")
+}
+
+func (g *geoProv) Name() string {
+	return "mock geolocation provider"
+}
+
+func (g *geoProv) setPosition(lat, lon float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lat, g.lon = lat, lon
+}
+
+// LookupStream performs a single lookup on stream start, like the real providers do
+func (g *geoProv) LookupStream(ctx context.Context, key string) <-chan geobus.Result {
+	g.mu.Lock()
+	result := geobus.Result{
+		Key:            key,
+		Lat:            g.lat,
+		Lon:            g.lon,
+		AccuracyMeters: 10,
+		At:             time.Now(),
+		Source:         g.Name(),
+	}
+	g.mu.Unlock()
+
+	ch := make(chan geobus.Result, 1)
+	ch <- result
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func (w *weatherProv) Name() string {
+	if w.name != "" {
+		return w.name
+	}
+	return "mock weather provider"
+}
+
+func (w *weatherProv) Attribution() string {
+	return "Weather data by mock weather provider"
+}
+
+func (w *weatherProv) GetWeather(_ context.Context, coords geobus.Coordinate) (*weather.Data, error) {
+	w.calls++
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.shouldFail {
+		return nil, errors.New("intentionally failing")
+	}
+	if w.data != nil {
+		return w.data, nil
+	}
+	return &weather.Data{
+		GeneratedAt: time.Now(),
+		Coordinates: coords,
+		Current: weather.Instant{
+			InstantTime: time.Now(),
+			Temperature: 20.0,
+			HasIsDay:    true,
+		},
+	}, nil
+}
+
+func (r *rawWeatherProv) RetainRawResponse(int) {}
+
+func (r *rawWeatherProv) LastRawResponse() ([]byte, bool) {
+	return r.raw, r.truncated
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+// notifySocket creates a unix datagram socket for the systemd notifications and sets NOTIFY_SOCKET to it.
+func notifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "notify"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatalf("failed to listen on notify socket: %s", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", addr.Name)
+	return conn
+}
+
+// readNotification returns the next systemd notification that was sent to the socket, or an empty string
+// if no notification arrives within the timeout.
+func readNotification(t *testing.T, conn *net.UnixConn, timeout time.Duration) string {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		t.Fatalf("failed to set read deadline: %s", err)
+	}
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+func TestNewSystemdNotifier(t *testing.T) {
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	t.Run("notifier is nil without notify socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		if newSystemdNotifier(log) != nil {
+			t.Error("expected notifier to be nil")
+		}
+	})
+	tests := []struct {
+		name         string
+		watchdogUsec string
+		watchdogPID  string
+		want         time.Duration
+	}{
+		{"watchdog disabled", "", "", 0},
+		{"watchdog interval", "30000000", "", time.Second * 30},
+		{"watchdog for this process", "30000000", strconv.Itoa(os.Getpid()), time.Second * 30},
+		{"watchdog for another process", "30000000", "1", 0},
+		{"invalid watchdog interval", "invalid", "", 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NOTIFY_SOCKET", "@waybar-weather")
+			t.Setenv("WATCHDOG_USEC", tc.watchdogUsec)
+			t.Setenv("WATCHDOG_PID", tc.watchdogPID)
+			notifier := newSystemdNotifier(log)
+			if notifier == nil {
+				t.Fatal("expected notifier to be non-nil")
+			}
+			if notifier.watchdog != tc.want {
+				t.Errorf("expected watchdog interval to be %s, got %s", tc.want, notifier.watchdog)
+			}
+		})
+	}
+}
+
+func TestSystemdNotifier_notify(t *testing.T) {
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	t.Run("ready is only sent once", func(t *testing.T) {
+		conn := notifySocket(t)
+		notifier := newSystemdNotifier(log)
+		notifier.notifyReady()
+		notifier.notifyReady()
+		if got := readNotification(t, conn, time.Second); got != systemdReady {
+			t.Errorf("expected notification to be %q, got %q", systemdReady, got)
+		}
+		if got := readNotification(t, conn, time.Millisecond*50); got != "" {
+			t.Errorf("expected no further notification, got %q", got)
+		}
+	})
+	t.Run("nil notifier is a no-op", func(t *testing.T) {
+		var notifier *systemdNotifier
+		notifier.notify(systemdStopping)
+		notifier.notifyReady()
+	})
+	t.Run("missing socket is ignored", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing"))
+		newSystemdNotifier(log).notify(systemdReady)
+	})
+}
+
+func TestService_systemdNotifications(t *testing.T) {
+	t.Run("ready is sent after the first render", func(t *testing.T) {
+		conn := notifySocket(t)
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.systemd = newSystemdNotifier(serv.logger)
+		if got := readNotification(t, conn, time.Millisecond*50); got != "" {
+			t.Errorf("expected no notification before the first render, got %q", got)
+		}
+		serv.weatherIsSet = true
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if got := readNotification(t, conn, time.Second); got != systemdReady {
+			t.Errorf("expected notification to be %q, got %q", systemdReady, got)
+		}
+	})
+	t.Run("watchdog is pinged until shutdown", func(t *testing.T) {
+		conn := notifySocket(t)
+		t.Setenv("WATCHDOG_USEC", "40000")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.systemd = newSystemdNotifier(serv.logger)
+		serv.output = io.Discard
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan struct{})
+		go serv.renderOutput(ctx)
+		go func() {
+			defer close(done)
+			serv.awaitShutdown(ctx)
+		}()
+		for range 5 {
+			if got := readNotification(t, conn, time.Second); got != systemdWatchdog {
+				t.Errorf("expected notification to be %q, got %q", systemdWatchdog, got)
+			}
+		}
+		cancel()
+		<-done
+		for {
+			got := readNotification(t, conn, time.Second)
+			if got == systemdWatchdog {
+				continue
+			}
+			if got != systemdStopping {
+				t.Errorf("expected notification to be %q, got %q", systemdStopping, got)
+			}
+			break
+		}
+	})
+	t.Run("watchdog is not pinged while the render loop hangs", func(t *testing.T) {
+		conn := notifySocket(t)
+		t.Setenv("WATCHDOG_USEC", "40000")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.systemd = newSystemdNotifier(serv.logger)
+
+		// The render loop is not running, so it never reports progress after the start
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			serv.awaitShutdown(ctx)
+		}()
+		pings := 0
+		for pings < 10 && readNotification(t, conn, time.Millisecond*100) == systemdWatchdog {
+			pings++
+		}
+		if pings == 0 || pings > 2 {
+			t.Errorf("expected the pings to stop after the watchdog interval, got %d pings", pings)
+		}
+		cancel()
+		<-done
+	})
+	t.Run("without notify socket, shutdown is not delayed", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		serv.awaitShutdown(ctx)
+	})
+}
+
+// controlSocketPath returns a path for a control socket in a short temporary directory, since the path
+// of a unix socket is limited to about 100 characters.
+func controlSocketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ww")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "control.sock")
+}
+
+func TestService_startControl(t *testing.T) {
+	t.Run("status is answered until the context is cancelled", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.config.Control.Disabled = false
+		serv.config.Control.Socket = controlSocketPath(t)
+		serv.weatherProv = &weatherProv{}
+		serv.location = geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}
+		serv.address = geocode.Address{City: "Berlin", DisplayName: "Berlin, Germany"}
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		serv.startControl(ctx)
+
+		info, err := os.Stat(serv.config.Control.Socket)
+		if err != nil {
+			t.Fatalf("failed to stat control socket: %s", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("expected control socket permissions to be 0600, got %o", info.Mode().Perm())
+		}
+		status, err := QueryStatus(t.Context(), serv.config.Control.Socket)
+		if err != nil {
+			t.Fatalf("failed to query status: %s", err)
+		}
+		if status.City != "Berlin" || status.Latitude != 52.5126 || status.Longitude != 13.3898 {
+			t.Errorf("expected status of the location, got %+v", status)
+		}
+		if status.RenderMode != RenderModePending {
+			t.Errorf("expected render mode to be %q, got %q", RenderModePending, status.RenderMode)
+		}
+
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if _, err = os.Stat(serv.config.Control.Socket); errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		if _, err = os.Stat(serv.config.Control.Socket); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected control socket to be removed after shutdown, got %v", err)
+		}
+		if _, err = QueryStatus(t.Context(), serv.config.Control.Socket); err == nil {
+			t.Error("expected status query after shutdown to fail")
+		}
+	})
+	t.Run("disabled control socket is not created", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.config.Control.Socket = controlSocketPath(t)
+		serv.startControl(t.Context())
+		if _, err = os.Stat(serv.config.Control.Socket); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected no control socket, got %v", err)
+		}
+	})
+	t.Run("unknown commands are rejected", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.config.Control.Disabled = false
+		serv.config.Control.Socket = controlSocketPath(t)
+		serv.startControl(t.Context())
+
+		conn, err := net.Dial("unix", serv.config.Control.Socket)
+		if err != nil {
+			t.Fatalf("failed to connect to control socket: %s", err)
+		}
+		defer func() { _ = conn.Close() }()
+		if _, err = conn.Write([]byte(`{"command":"reboot"}`)); err != nil {
+			t.Fatalf("failed to send request: %s", err)
+		}
+		var resp controlResponse
+		if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+			t.Fatalf("failed to read response: %s", err)
+		}
+		if resp.Error != "unknown command: reboot" || resp.Status != nil {
+			t.Errorf("expected unknown command error, got %+v", resp)
+		}
+	})
+}
+
+func TestListenControl(t *testing.T) {
+	t.Run("stale socket is replaced", func(t *testing.T) {
+		socket := controlSocketPath(t)
+		stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+		if err != nil {
+			t.Fatalf("failed to listen on socket: %s", err)
+		}
+		stale.SetUnlinkOnClose(false)
+		if err = stale.Close(); err != nil {
+			t.Fatalf("failed to close socket: %s", err)
+		}
+
+		listener, err := listenControl(socket)
+		if err != nil {
+			t.Fatalf("failed to listen on control socket: %s", err)
+		}
+		if err = listener.Close(); err != nil {
+			t.Errorf("failed to close control socket: %s", err)
+		}
+	})
+	t.Run("socket of a running instance is kept", func(t *testing.T) {
+		socket := controlSocketPath(t)
+		listener, err := listenControl(socket)
+		if err != nil {
+			t.Fatalf("failed to listen on control socket: %s", err)
+		}
+		defer func() { _ = listener.Close() }()
+		if _, err = listenControl(socket); !errors.Is(err, ErrControlSocketInUse) {
+			t.Errorf("expected error to be %q, got %v", ErrControlSocketInUse, err)
+		}
+	})
+}
+
+// clientService returns a service with weather data that serves the clients on a control socket until
+// the test ends.
+func clientService(t *testing.T, ctx context.Context) *Service {
+	t.Helper()
+	serv, err := testService(t, false)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	serv.output = io.Discard
+	serv.config.Control.Disabled = false
+	serv.config.Control.Socket = controlSocketPath(t)
+	serv.weather = &weather.Data{
+		Current:  weather.Instant{InstantTime: time.Now(), Temperature: 20, WeatherCode: 1, IsDay: true},
+		Forecast: make(map[weather.DayHour]weather.Instant),
+	}
+	serv.weatherIsSet = true
+	if err = serv.startControl(ctx); err != nil {
+		t.Fatalf("failed to start control socket: %s", err)
+	}
+	return serv
+}
+
+// subscribeClient subscribes to the output of the service on the control socket. It returns the
+// connection and a function that reads the next frame.
+func subscribeClient(t *testing.T, socket string, req controlRequest) (net.Conn, func() (clientFrame, error)) {
+	t.Helper()
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to connect to control socket: %s", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	req.Command = controlCommandSubscribe
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		t.Fatalf("failed to send subscribe request: %s", err)
+	}
+	dec := json.NewDecoder(conn)
+	return conn, func() (clientFrame, error) {
+		var frame clientFrame
+		if err := conn.SetReadDeadline(time.Now().Add(time.Second * 5)); err != nil {
+			return frame, err
+		}
+		err := dec.Decode(&frame)
+		return frame, err
+	}
+}
+
+// frameOutput reads the next frame and returns its waybar output.
+func frameOutput(t *testing.T, next func() (clientFrame, error)) outputData {
+	t.Helper()
+	frame, err := next()
+	if err != nil {
+		t.Fatalf("failed to read frame: %s", err)
+	}
+	var output outputData
+	if err = json.Unmarshal(frame.Output, &output); err != nil {
+		t.Fatalf("failed to unmarshal output of frame %+v: %s", frame, err)
+	}
+	return output
+}
+
+// clientCount returns the number of subscribed clients.
+func clientCount(serv *Service) int {
+	serv.clientsLock.Lock()
+	defer serv.clientsLock.Unlock()
+	return len(serv.clients)
+}
+
+// waitFor polls the condition until it is met or a second has passed.
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestService_serveClient(t *testing.T) {
+	t.Run("clients render with their own templates and views", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		connA, nextA := subscribeClient(t, serv.config.Control.Socket, controlRequest{
+			Templates: &ClientTemplates{Text: "A {{.Current.TemperatureStr}}", AltText: "A alt"},
+		})
+		_, nextB := subscribeClient(t, serv.config.Control.Socket, controlRequest{
+			Templates: &ClientTemplates{Text: "B"},
+		})
+		if output := frameOutput(t, nextA); !strings.HasPrefix(output.Text, "A 20") {
+			t.Errorf("expected text of client A to be rendered with its template, got %q", output.Text)
+		}
+		if output := frameOutput(t, nextB); output.Text != "B" {
+			t.Errorf("expected text of client B to be rendered with its template, got %q", output.Text)
+		}
+
+		if err := json.NewEncoder(connA).Encode(controlRequest{Command: controlCommandSetView, Alt: true}); err != nil {
+			t.Fatalf("failed to send view: %s", err)
+		}
+		output := frameOutput(t, nextA)
+		if output.Text != "A alt" || !slices.Contains(output.Classes, AltViewClass) {
+			t.Errorf("expected client A to show the alternative view, got %+v", output)
+		}
+
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if output = frameOutput(t, nextA); output.Text != "A alt" {
+			t.Errorf("expected client A to keep the alternative view, got %q", output.Text)
+		}
+		output = frameOutput(t, nextB)
+		if output.Text != "B" || slices.Contains(output.Classes, AltViewClass) {
+			t.Errorf("expected client B to keep the default view, got %+v", output)
+		}
+		if serv.view().alt {
+			t.Error("expected the view of the service to be unchanged")
+		}
+	})
+	t.Run("pending state is sent until weather data is available", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		serv.weatherLock.Lock()
+		serv.weather, serv.weatherIsSet = nil, false
+		serv.weatherLock.Unlock()
+		_, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{
+			Templates: &ClientTemplates{Pending: "client pending"},
+		})
+		output := frameOutput(t, next)
+		if output.Text != "client pending" || !slices.Contains(output.Classes, PendingClass) {
+			t.Errorf("expected pending output, got %+v", output)
+		}
+	})
+	t.Run("weather data is sent to clients that request it", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		_, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{Weather: true})
+		frame, err := next()
+		if err != nil {
+			t.Fatalf("failed to read frame: %s", err)
+		}
+		data, err := weather.Decode(frame.Weather)
+		if err != nil {
+			t.Fatalf("failed to decode weather data of the frame: %s", err)
+		}
+		if data.Current.Temperature != serv.weather.Current.Temperature {
+			t.Errorf("expected temperature %f, got %f", serv.weather.Current.Temperature, data.Current.Temperature)
+		}
+
+		_, next = subscribeClient(t, serv.config.Control.Socket, controlRequest{})
+		if frame, err = next(); err != nil {
+			t.Fatalf("failed to read frame: %s", err)
+		}
+		if frame.Weather != nil {
+			t.Errorf("expected no weather data without request, got %s", frame.Weather)
+		}
+	})
+	t.Run("clients without templates render with the templates of the service", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+		serv.printWeather(t.Context(), TriggerSchedule)
+		var want outputData
+		if err := json.Unmarshal(buf.Bytes(), &want); err != nil {
+			t.Fatalf("failed to unmarshal output: %s", err)
+		}
+		_, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{})
+		if output := frameOutput(t, next); output.Text != want.Text || output.Tooltip != want.Tooltip {
+			t.Errorf("expected output of the service %+v, got %+v", want, output)
+		}
+	})
+	t.Run("clients are removed when they disconnect", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		conn, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{})
+		frameOutput(t, next)
+		if count := clientCount(serv); count != 1 {
+			t.Errorf("expected one subscribed client, got %d", count)
+		}
+		if err := conn.Close(); err != nil {
+			t.Fatalf("failed to close connection: %s", err)
+		}
+		waitFor(t, func() bool { return clientCount(serv) == 0 }, "expected client to be removed")
+	})
+	t.Run("subscriptions end on shutdown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		serv := clientService(t, ctx)
+		_, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{})
+		frameOutput(t, next)
+		cancel()
+		if _, err := next(); !errors.Is(err, io.EOF) {
+			t.Errorf("expected subscription to end with EOF, got %v", err)
+		}
+		waitFor(t, func() bool { return clientCount(serv) == 0 }, "expected client to be removed")
+	})
+	t.Run("invalid templates are rejected", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		_, next := subscribeClient(t, serv.config.Control.Socket, controlRequest{
+			Templates: &ClientTemplates{Text: "{{.Current"},
+		})
+		frame, err := next()
+		if err != nil {
+			t.Fatalf("failed to read frame: %s", err)
+		}
+		if !strings.Contains(frame.Error, "invalid client templates") || frame.Output != nil {
+			t.Errorf("expected subscription to be rejected, got %+v", frame)
+		}
+		if count := clientCount(serv); count != 0 {
+			t.Errorf("expected no subscribed clients, got %d", count)
+		}
+	})
+}
+
+func TestClient(t *testing.T) {
+	templates := &ClientTemplates{Text: "client", AltText: "client alt"}
+	t.Run("output is printed and the view toggled", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		client := NewClient(serv.config.Control.Socket, templates, buf, logger.NewLogger(slog.LevelError,
+			io.Discard, nil))
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			client.Run(ctx)
+		}()
+
+		waitFor(t, func() bool { return strings.Contains(buf.String(), `"text":"client"`) },
+			"expected the output of the client to be printed")
+		client.Toggle()
+		waitFor(t, func() bool { return strings.Contains(buf.String(), `"text":"client alt"`) },
+			"expected the alternative view to be printed after the toggle")
+		for line := range strings.Lines(buf.String()) {
+			var output outputData
+			if err := json.Unmarshal([]byte(line), &output); err != nil {
+				t.Errorf("expected each line to be a waybar output, got %q: %s", line, err)
+			}
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected client to stop after the context is cancelled")
+		}
+	})
+	t.Run("client reconnects after the service restarts and keeps its view", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		serv := clientService(t, ctx)
+		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
+		client := NewClient(serv.config.Control.Socket, templates, buf, logger.NewLogger(slog.LevelError,
+			io.Discard, nil))
+		go client.Run(t.Context())
+		waitFor(t, func() bool { return strings.Contains(buf.String(), `"text":"client"`) },
+			"expected the output of the client to be printed")
+
+		cancel()
+		waitFor(t, func() bool { return clientCount(serv) == 0 }, "expected client to be removed")
+		client.Toggle()
+		if err := serv.startControl(t.Context()); err != nil {
+			t.Fatalf("failed to restart control socket: %s", err)
+		}
+		deadline := time.Now().Add(clientMinBackoff * 3)
+		for !strings.Contains(buf.String(), `"text":"client alt"`) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected client to reconnect with the alternative view, got %s", buf)
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	})
+	t.Run("client stops when the output pipe is broken", func(t *testing.T) {
+		serv := clientService(t, t.Context())
+		pipe := &reopenablePipe{buf: bytes.NewBuffer(nil), closed: true}
+		client := NewClient(serv.config.Control.Socket, templates, pipe, logger.NewLogger(slog.LevelError,
+			io.Discard, nil))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			client.Run(t.Context())
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second * 5):
+			t.Fatal("expected client to stop after the output pipe broke")
+		}
+		waitFor(t, func() bool { return clientCount(serv) == 0 }, "expected client to be removed")
+	})
+}
+
+func TestService_jitter(t *testing.T) {
+	t.Run("jitter is disabled by default", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if jitter := serv.jitter(); jitter != 0 {
+			t.Errorf("expected jitter to be 0, got %s", jitter)
+		}
+	})
+	t.Run("jitter is reproducible with a seeded source", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_JITTER", "5m")
+		jitters := func(t *testing.T) []time.Duration {
+			t.Helper()
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.Rand = rand.New(rand.NewPCG(1, 2))
+			values := make([]time.Duration, 10)
+			for i := range values {
+				values[i] = serv.jitter()
+				if values[i] < 0 || values[i] >= time.Minute*5 {
+					t.Errorf("expected jitter to be within [0, 5m), got %s", values[i])
+				}
+			}
+			return values
+		}
+		first, second := jitters(t), jitters(t)
+		if !slices.Equal(first, second) {
+			t.Errorf("expected jitter to be reproducible, got %v and %v", first, second)
+		}
+	})
+	t.Run("jitter delays the weather update", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_INTERVALS_JITTER", "5m")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Now()
+		fakeClock := clock.NewFake(now)
+		serv.Clock = fakeClock
+		serv.location, serv.locationIsSet = geobus.Coordinate{Lat: 52.5, Lon: 13.4}, true
+		serv.weatherIsSet, serv.weatherFetchedAt = true, now
+		serv.weatherJitter = time.Minute * 2
+
+		fakeClock.Set(now.Add(serv.config.Intervals.WeatherUpdate + time.Minute))
+		if serv.locationNeedsUpdate(serv.location) {
+			t.Error("expected weather update to be delayed by the jitter")
+		}
+		fakeClock.Set(now.Add(serv.config.Intervals.WeatherUpdate + time.Minute*2))
+		if !serv.locationNeedsUpdate(serv.location) {
+			t.Error("expected weather to be updated after the interval and jitter")
+		}
+	})
+	t.Run("jitter delays the weather job", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_INTERVALS_JITTER", "5m")
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			prov := &weatherProv{}
+			serv.weatherProv = prov
+			serv.location, serv.locationIsSet = geobus.Coordinate{Lat: 52.5, Lon: 13.4}, true
+			serv.fetchWeather(ctx)
+			calls := func() int {
+				serv.weatherLock.RLock()
+				defer serv.weatherLock.RUnlock()
+				return prov.calls
+			}
+			go serv.weatherJob.Start(ctx)
+
+			delay := serv.config.Intervals.WeatherUpdate + serv.fetchedWeatherJitter()
+			time.Sleep(delay - time.Second)
+			synctest.Wait()
+			if got := calls(); got != 1 {
+				t.Fatalf("expected the weather job to be delayed by the jitter, got %d fetches", got)
+			}
+			time.Sleep(time.Second)
+			synctest.Wait()
+			if got := calls(); got != 2 {
+				t.Errorf("expected the weather job to refresh after the interval and jitter, got %d fetches", got)
+			}
+		})
+	})
+	t.Run("initial fetch is delayed", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			t.Setenv("WAYBARWEATHER_INTERVALS_JITTER", "1h")
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.Rand = rand.New(rand.NewPCG(1, 2))
+			want := rand.New(rand.NewPCG(1, 2)).Int64N(int64(maxStartupDelay))
+
+			start := time.Now()
+			serv.awaitStartupDelay(t.Context())
+			if waited := time.Since(start); waited != time.Duration(want) {
+				t.Errorf("expected startup delay to be %s, got %s", time.Duration(want), waited)
+			}
+		})
+	})
+}
+
+func TestRenderHistory(t *testing.T) {
+	record := func(i int) RenderRecord {
+		return RenderRecord{Trigger: TriggerSchedule, Output: strconv.Itoa(i)}
+	}
+	outputs := func(records []RenderRecord) []string {
+		values := make([]string, 0, len(records))
+		for _, r := range records {
+			values = append(values, r.Output)
+		}
+		return values
+	}
+
+	t.Run("records are listed oldest first", func(t *testing.T) {
+		history := newRenderHistory(3)
+		history.add(record(1))
+		history.add(record(2))
+		if got := outputs(history.list()); !slices.Equal(got, []string{"1", "2"}) {
+			t.Errorf("expected history to be %v, got %v", []string{"1", "2"}, got)
+		}
+	})
+	t.Run("oldest records are replaced", func(t *testing.T) {
+		history := newRenderHistory(3)
+		for i := range 5 {
+			history.add(record(i + 1))
+		}
+		if got := outputs(history.list()); !slices.Equal(got, []string{"3", "4", "5"}) {
+			t.Errorf("expected history to be %v, got %v", []string{"3", "4", "5"}, got)
+		}
+	})
+	t.Run("disabled history records nothing", func(t *testing.T) {
+		history := newRenderHistory(0)
+		history.add(record(1))
+		if got := history.list(); got != nil {
+			t.Errorf("expected history to be empty, got %v", got)
+		}
+	})
+}
+
+func TestService_renderHistory(t *testing.T) {
+	t.Run("outputs are recorded with their trigger", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "text")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+		serv.Clock = clock.NewFake(now)
+		buf := bytes.NewBuffer(nil)
+		serv.output = buf
+
+		serv.printWeather(t.Context(), TriggerResume)
+		serv.weatherIsSet = true
+		serv.printWeather(t.Context(), TriggerSignal)
+
+		history := serv.status().RenderHistory
+		if len(history) != 2 {
+			t.Fatalf("expected 2 recorded outputs, got %d", len(history))
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		for i, want := range []RenderTrigger{TriggerResume, TriggerSignal} {
+			if history[i].Trigger != want {
+				t.Errorf("expected trigger of output %d to be %q, got %q", i, want, history[i].Trigger)
+			}
+			if !history[i].Time.Equal(now) {
+				t.Errorf("expected time of output %d to be %s, got %s", i, now, history[i].Time)
+			}
+			if history[i].Output != lines[i] {
+				t.Errorf("expected output %d to be %q, got %q", i, lines[i], history[i].Output)
+			}
+		}
+	})
+	t.Run("render output records the trigger of the render request", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			serv, err := testService(t, false)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err)
+			}
+			serv.output = io.Discard
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			go serv.renderOutput(ctx)
+
+			serv.requestRender(TriggerLocation)
+			time.Sleep(serv.config.Intervals.MinRender)
+			synctest.Wait()
+			history := serv.history.list()
+			if len(history) != 1 || history[0].Trigger != TriggerLocation {
+				t.Errorf("expected one output triggered by %q, got %+v", TriggerLocation, history)
+			}
+		})
+	})
+	t.Run("history size is configurable", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_DEBUG_RENDER_HISTORY", "0")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.output = io.Discard
+		serv.printWeather(t.Context(), TriggerSchedule)
+		if history := serv.status().RenderHistory; len(history) != 0 {
+			t.Errorf("expected no recorded outputs, got %d", len(history))
+		}
+	})
+}
+
+func TestService_status(t *testing.T) {
+	t.Run("render mode follows the weather state", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if mode := serv.status().RenderMode; mode != RenderModePending {
+			t.Errorf("expected render mode to be %q, got %q", RenderModePending, mode)
+		}
+		for range serv.config.Weather.FailureThreshold {
+			serv.recordFetchFailure(errors.New("connection refused"))
+		}
+		if mode := serv.status().RenderMode; mode != RenderModeError {
+			t.Errorf("expected render mode to be %q, got %q", RenderModeError, mode)
+		}
+		serv.weather = &weather.Data{Source: "open-meteo"}
+		if mode := serv.status().RenderMode; mode != RenderModeDefault {
+			t.Errorf("expected render mode to be %q, got %q", RenderModeDefault, mode)
+		}
+		serv.displayAltText = true
+		status := serv.status()
+		if status.RenderMode != RenderModeAlt {
+			t.Errorf("expected render mode to be %q, got %q", RenderModeAlt, status.RenderMode)
+		}
+		if status.WeatherProvider != "open-meteo" {
+			t.Errorf("expected weather provider to be %q, got %q", "open-meteo", status.WeatherProvider)
+		}
+	})
+	t.Run("last errors are reported per subsystem", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+		serv.Clock = clock.NewFake(now)
+		serv.recordError("geocoder", errors.New("first error"))
+		serv.recordError("geocoder", errors.New("second error"))
+		serv.recordFetchFailure(errors.New("connection refused"))
+
+		status := serv.status()
+		want := map[string]SubsystemError{
+			"geocoder": {Error: "second error", At: now},
+			"weather":  {Error: "connection refused", At: now},
+		}
+		if !maps.Equal(status.Errors, want) {
+			t.Errorf("expected errors to be %+v, got %+v", want, status.Errors)
+		}
+	})
+	t.Run("last errors are cleared once the subsystem recovers", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &weatherProv{}
+		serv.geocoder = &mockGeocoder{}
+		serv.recordError("geocoder", errors.New("geocoder failed"))
+		serv.recordFetchFailure(errors.New("connection refused"))
+		serv.recordError("output", errors.New("broken pipe"))
+
+		if _, err = serv.reverseGeocode(t.Context(), geobus.Coordinate{Lat: 52.5, Lon: 13.4}); err != nil {
+			t.Fatalf("failed to look up the address: %s", err)
+		}
+		serv.fetchWeather(t.Context())
+		status := serv.status()
+		for _, subsystem := range []string{"geocoder", "weather"} {
+			if _, ok := status.Errors[subsystem]; ok {
+				t.Errorf("expected %s error to be cleared, got %+v", subsystem, status.Errors)
+			}
+		}
+		if _, ok := status.Errors["output"]; !ok {
+			t.Errorf("expected output error to be kept, got %+v", status.Errors)
+		}
+	})
+	t.Run("geolocation source of the latest update is reported", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &weatherProv{}
+		geocoder := &mockGeocoder{}
+		serv.geocoder = geocoder
+		serv.output = io.Discard
+		at := time.Now()
+		sub := make(chan geobus.Result, 1)
+		sub <- geobus.Result{Lat: 52.5126, Lon: 13.3898, AccuracyMeters: 15000, Source: "geoip", At: at}
+		close(sub)
+		serv.processLocationUpdates(t.Context(), sub)
+		if geocoder.accuracy != 15000 {
+			t.Errorf("expected the accuracy to be passed to the geocoder, got %g", geocoder.accuracy)
+		}
+
+		status := serv.status()
+		if status.GeolocationSource != "geoip" || !status.GeolocationAt.Equal(at) {
+			t.Errorf("expected geolocation source geoip at %s, got %q at %s", at, status.GeolocationSource,
+				status.GeolocationAt)
+		}
+	})
+}
+
+func TestService_observed(t *testing.T) {
+	observedData := func(at time.Time, temp float64) *weather.Data {
+		data := weather.NewData()
+		data.GeneratedAt = at
+		data.Timezone = "Europe/Berlin"
+		data.Current = weather.Instant{
+			InstantTime: at, Temperature: temp, IsDay: true, Units: weather.Units{Temperature: "°C"},
+		}
+		return data
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %s", err)
+	}
+
+	t.Run("extremes follow the fetches and start over at midnight", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		now := time.Date(2026, 3, 10, 8, 0, 0, 0, berlin)
+		fakeClock := clock.NewFake(now)
+		serv.Clock, serv.presenter.Clock = fakeClock, fakeClock
+		prov := &weatherProv{}
+		serv.weatherProv = prov
+
+		fetch := func(at time.Time, temp float64) {
+			t.Helper()
+			fakeClock.Set(at)
+			prov.data = observedData(at, temp)
+			if !serv.updateWeather(t.Context()) {
+				t.Fatal("expected weather update to succeed")
+			}
+		}
+		fetch(now, 5)
+		fetch(now.Add(6*time.Hour), 17.24)
+		fetch(now.Add(9*time.Hour), 11)
+		fetch(now.Add(14*time.Hour), 2)
+
+		tplCtx, _ := serv.buildContext()
+		if !tplCtx.HasObserved {
+			t.Fatal("expected observed extremes to be set")
+		}
+		if tplCtx.ObservedHigh != 17.24 || !tplCtx.ObservedHighAt.Equal(now.Add(6*time.Hour)) {
+			t.Errorf("expected observed high of 17.24 at %s, got %g at %s", now.Add(6*time.Hour),
+				tplCtx.ObservedHigh, tplCtx.ObservedHighAt)
+		}
+		if tplCtx.ObservedLow != 2 || !tplCtx.ObservedLowAt.Equal(now.Add(14*time.Hour)) {
+			t.Errorf("expected observed low of 2 at %s, got %g at %s", now.Add(14*time.Hour),
+				tplCtx.ObservedLow, tplCtx.ObservedLowAt)
+		}
+		if tplCtx.ObservedHighStr != "17.2°C" {
+			t.Errorf("expected formatted observed high %q, got %q", "17.2°C", tplCtx.ObservedHighStr)
+		}
+		state, err := loadState(serv.config.State.File)
+		if err != nil {
+			t.Fatalf("failed to load state: %s", err)
+		}
+		if state.Observed.Day != "2026-03-10" || state.Observed.High.Temperature != 17.24 {
+			t.Errorf("expected observed extremes to be persisted, got %+v", state.Observed)
 		}
 
+		// Past midnight at the location, the extremes of yesterday are no longer shown
+		fakeClock.Set(time.Date(2026, 3, 11, 0, 10, 0, 0, berlin))
+		if tplCtx, _ = serv.buildContext(); tplCtx.HasObserved {
+			t.Errorf("expected observed extremes to be omitted after midnight, got %g/%g", tplCtx.ObservedLow,
+				tplCtx.ObservedHigh)
+		}
+		midnight := time.Date(2026, 3, 11, 0, 30, 0, 0, berlin)
+		fetch(midnight, 4)
+		tplCtx, _ = serv.buildContext()
+		if !tplCtx.HasObserved || tplCtx.ObservedLow != 4 || tplCtx.ObservedHigh != 4 {
+			t.Errorf("expected observed extremes to start over at 4, got %g/%g", tplCtx.ObservedLow,
+				tplCtx.ObservedHigh)
+		}
+		if !tplCtx.ObservedHighAt.Equal(midnight) {
+			t.Errorf("expected observed high at %s, got %s", midnight, tplCtx.ObservedHighAt)
+		}
+	})
+	t.Run("extremes are restored from the state file of the same day", func(t *testing.T) {
+		now := time.Date(2026, 3, 10, 18, 0, 0, 0, berlin)
+		tests := []struct {
+			name string
+			day  string
+			want bool
+		}{
+			{"same day", "2026-03-10", true},
+			{"stale state of the previous day", "2026-03-09", false},
+		}
 		for _, tc := range tests {
 			t.Run(tc.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "state.json")
+				observed := observedExtremes{
+					Day:  tc.day,
+					Low:  observedTemperature{Temperature: -1.5, Unit: "°C", At: now.Add(-12 * time.Hour)},
+					High: observedTemperature{Temperature: 9, Unit: "°C", At: now.Add(-3 * time.Hour)},
+				}
+				if err := saveState(path, persistentState{Observed: observed}); err != nil {
+					t.Fatalf("failed to save state: %s", err)
+				}
+				t.Setenv("WAYBARWEATHER_STATE_FILE", path)
 				serv, err := testService(t, false)
 				if err != nil {
 					t.Fatalf("failed to create service: %s", err)
 				}
-				serv.output = io.Discard
-				serv.geocoder = &mockGeocoder{}
+				fakeClock := clock.NewFake(now)
+				serv.Clock, serv.presenter.Clock = fakeClock, fakeClock
+				serv.weather, serv.weatherIsSet = observedData(now, 6), true
 
-				httpclient := http.New(serv.logger)
-				httpclient.Transport = testhelper.MockRoundTripper{Fn: rtFn}
-				weatherProv, err := openmeteo.New(httpclient, serv.logger, serv.config.Units)
-				if err != nil {
-					t.Fatalf("failed to create weather provider: %s", err)
+				tplCtx, _ := serv.buildContext()
+				if tplCtx.HasObserved != tc.want {
+					t.Fatalf("expected observed extremes to be set: %t, got %t", tc.want, tplCtx.HasObserved)
+				}
+				if tc.want && (tplCtx.ObservedLow != -1.5 || tplCtx.ObservedHigh != 9) {
+					t.Errorf("expected restored extremes -1.5/9, got %g/%g", tplCtx.ObservedLow,
+						tplCtx.ObservedHigh)
 				}
-				serv.weatherProv = weatherProv
-				err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: tc.latitude, Lon: tc.longitude})
 
-				if tc.wantErr && err == nil {
-					t.Error("expected error but got none")
+				// The next observation replaces stale extremes and extends those of the same day
+				serv.weatherProv = &weatherProv{data: observedData(now, 6)}
+				if !serv.updateWeather(t.Context()) {
+					t.Fatal("expected weather update to succeed")
 				}
-				if !tc.wantErr && err != nil {
-					t.Errorf("unexpected error: %s", err)
+				tplCtx, _ = serv.buildContext()
+				wantLow := 6.0
+				if tc.want {
+					wantLow = -1.5
+				}
+				if tplCtx.ObservedLow != wantLow {
+					t.Errorf("expected observed low of %g, got %g", wantLow, tplCtx.ObservedLow)
 				}
 			})
 		}
 	})
-	t.Run("geocoder fails", func(t *testing.T) {
+	t.Run("extremes start over if the temperature unit changes", func(t *testing.T) {
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		serv.output = io.Discard
-		serv.geocoder = &mockGeocoder{shouldFail: true}
-		err = serv.updateLocation(t.Context(), geobus.Coordinate{Lat: 44.4375, Lon: 26.125})
-		if err == nil {
-			t.Error("expected error but got none")
+		now := time.Date(2026, 3, 10, 12, 0, 0, 0, berlin)
+		if !serv.observe(observedData(now, 20)) {
+			t.Fatal("expected the first observation to be recorded")
 		}
-		wantErr := `failed reverse geocode coordinates: intentionally failing`
-		if !strings.Contains(err.Error(), wantErr) {
-			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		data := observedData(now.Add(time.Hour), 60)
+		data.Current.Units.Temperature = "°F"
+		if !serv.observe(data) {
+			t.Fatal("expected the observation in another unit to be recorded")
+		}
+		if serv.observed.Low.Temperature != 60 || serv.observed.Low.Unit != "°F" {
+			t.Errorf("expected the extremes to start over in °F, got %+v", serv.observed)
 		}
 	})
 }
 
-func TestService_HandleSignals(t *testing.T) {
-	t.Run("USR1 signal is handled", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
+func TestService_State(t *testing.T) {
+	t.Run("state round trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "waybar-weather", "state.json")
+		for _, want := range []bool{true, false} {
+			if err := saveState(path, persistentState{DisplayAltText: want}); err != nil {
+				t.Fatalf("failed to save state: %s", err)
+			}
+			state, err := loadState(path)
+			if err != nil {
+				t.Fatalf("failed to load state: %s", err)
+			}
+			if state.DisplayAltText != want {
+				t.Errorf("expected alt mode to be %t, got %t", want, state.DisplayAltText)
+			}
+		}
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("expected temporary state file to be removed, got: %v", err)
+		}
+	})
+	t.Run("missing state file results in the primary view", func(t *testing.T) {
+		state, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+		if err != nil {
+			t.Fatalf("failed to load state: %s", err)
+		}
+		if state.DisplayAltText {
+			t.Error("expected alt mode to be disabled")
+		}
+	})
+	t.Run("new service restores the display mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		if err := saveState(path, persistentState{DisplayAltText: true}); err != nil {
+			t.Fatalf("failed to save state: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_STATE_FILE", path)
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		sigChan := make(chan os.Signal, 1)
-		serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
-		go func() {
-			defer serv.SignalSrc.Stop(sigChan)
-			serv.HandleSignals(ctx, sigChan)
-		}()
-
-		sigChan <- syscall.SIGUSR1
-		time.Sleep(time.Millisecond * 100)
-		serv.displayAltLock.RLock()
-		defer serv.displayAltLock.RUnlock()
 		if !serv.displayAltText {
-			t.Errorf("expected alt mode to be enabled, got %t", serv.displayAltText)
+			t.Error("expected alt mode to be restored")
 		}
-		cancel()
 	})
-	t.Run("USR2 signal is handled", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
+	t.Run("corrupt state file results in the primary view", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+			t.Fatalf("failed to write state file: %s", err)
+		}
+		if _, err := loadState(path); err == nil {
+			t.Error("expected loading a corrupt state file to fail")
+		}
+		t.Setenv("WAYBARWEATHER_STATE_FILE", path)
 		serv, err := testService(t, false)
 		if err != nil {
 			t.Fatalf("failed to create service: %s", err)
 		}
-		buf := &syncBuffer{buf: bytes.NewBuffer(nil)}
-		serv.logger = logger.NewLogger(slog.LevelInfo, buf, nil)
-		sigChan := make(chan os.Signal, 1)
-		serv.SignalSrc.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
-		go func() {
-			defer serv.SignalSrc.Stop(sigChan)
-			serv.HandleSignals(ctx, sigChan)
-		}()
-
-		sigChan <- syscall.SIGUSR2
-		time.Sleep(time.Millisecond * 100)
-		wantLog := `msg="currently resolved address" address="" latitude=0 longitude=0`
-		if !strings.Contains(buf.String(), wantLog) {
-			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		if serv.displayAltText {
+			t.Error("expected alt mode to be disabled")
 		}
-		cancel()
-		time.Sleep(time.Millisecond * 100)
 	})
-}
-
-type (
-	weatherProv  struct{ shouldFail bool }
-	failWriter   struct{}
-	mockGeocoder struct{ shouldFail bool }
-	syncBuffer   struct {
-		mu  sync.Mutex
-		buf *bytes.Buffer
-	}
-)
-
-func (f failWriter) Write([]byte) (int, error) { return 0, fmt.Errorf("failed to write") }
-
-func (m *mockGeocoder) Name() string {
-	return "mock geocoder"
-}
-
-func (m *mockGeocoder) Reverse(_ context.Context, coords geobus.Coordinate) (geocode.Address, error) {
-	if m.shouldFail {
-		return geocode.Address{}, errors.New("intentionally failing")
-	}
-	return geocode.Address{
-		AddressFound: true,
-		Latitude:     coords.Lat,
-		Longitude:    coords.Lon,
-		DisplayName:  fmt.Sprintf("Test Location %.6f,%.6f", coords.Lat, coords.Lon),
-	}, nil
-}
-
-func (c *mockGeocoder) Search(_ context.Context, _ string) (geobus.Coordinate, error) {
-	return geobus.Coordinate{}, errors.New("not implemented")
-}
-
-func (w *weatherProv) Name() string {
-	return "mock weather provider"
-}
-
-func (w *weatherProv) GetWeather(_ context.Context, coords geobus.Coordinate) (*weather.Data, error) {
-	if w.shouldFail {
-		return nil, errors.New("intentionally failing")
-	}
-	return &weather.Data{
-		GeneratedAt: time.Now(),
-		Coordinates: coords,
-		Current: weather.Instant{
-			InstantTime: time.Now(),
-			Temperature: 20.0,
-		},
-	}, nil
-}
+	t.Run("disabled state is neither restored nor persisted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		if err := saveState(path, persistentState{DisplayAltText: true}); err != nil {
+			t.Fatalf("failed to save state: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_STATE_FILE", path)
+		t.Setenv("WAYBARWEATHER_STATE_DISABLE", "true")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if serv.displayAltText {
+			t.Error("expected alt mode not to be restored")
+		}
+		serv.persistState()
+		state, err := loadState(path)
+		if err != nil {
+			t.Fatalf("failed to load state: %s", err)
+		}
+		if !state.DisplayAltText {
+			t.Error("expected state file not to be overwritten")
+		}
+	})
+	t.Run("state is not restored without the signal toggle", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		if err := saveState(path, persistentState{DisplayAltText: true}); err != nil {
+			t.Fatalf("failed to save state: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_STATE_FILE", path)
+		t.Setenv("WAYBARWEATHER_OUTPUT_SIGNAL_TOGGLE", "false")
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if serv.displayAltText {
+			t.Error("expected alt mode not to be restored")
+		}
+	})
+	t.Run("weather data of the previous run is merged with the first fetch", func(t *testing.T) {
+		t.Setenv("WAYBARWEATHER_STATE_FILE", filepath.Join(t.TempDir(), "state.json"))
+		now := time.Now()
+		coords := geobus.Coordinate{Lat: 52.5126, Lon: 13.3898}
+		weatherData := func(hours ...int) *weather.Data {
+			data := &weather.Data{
+				GeneratedAt: now,
+				Coordinates: coords,
+				Current:     weather.Instant{InstantTime: now, Temperature: 20, HasIsDay: true},
+				Forecast:    make(map[weather.DayHour]weather.Instant),
+			}
+			for _, hour := range hours {
+				at := now.Add(time.Hour * time.Duration(hour))
+				data.Forecast[weather.NewDayHour(at)] = weather.Instant{InstantTime: at, Temperature: 20}
+			}
+			return data
+		}
 
-func (s *syncBuffer) Write(p []byte) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.buf.Write(p)
-}
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		serv.weatherProv = &weatherProv{data: weatherData(1, 2)}
+		serv.location = coords
+		if !serv.updateWeather(t.Context()) {
+			t.Fatal("expected weather update to succeed")
+		}
 
-func (s *syncBuffer) String() string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.buf.String()
+		restarted, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if restarted.cachedWeather == nil || len(restarted.cachedWeather.Forecast) != 2 {
+			t.Fatalf("expected weather data of the previous run to be restored, got %+v", restarted.cachedWeather)
+		}
+		if restarted.weatherIsSet || restarted.weather != nil {
+			t.Error("expected weather data of the previous run not to be displayed")
+		}
+		restarted.weatherProv = &weatherProv{data: weatherData(2)}
+		restarted.location = coords
+		if !restarted.updateWeather(t.Context()) {
+			t.Fatal("expected weather update to succeed")
+		}
+		if got := len(restarted.weather.Forecast); got != 2 {
+			t.Errorf("expected forecast hour of the previous run to be kept, got %d hours", got)
+		}
+		if restarted.cachedWeather != nil {
+			t.Error("expected weather data of the previous run to be dropped after the fetch")
+		}
+	})
+	t.Run("weather data of another schema version is ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		state := persistentState{DisplayAltText: true, Weather: json.RawMessage(`{"schema_version":0}`)}
+		if err := saveState(path, state); err != nil {
+			t.Fatalf("failed to save state: %s", err)
+		}
+		t.Setenv("WAYBARWEATHER_STATE_FILE", path)
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		if serv.cachedWeather != nil {
+			t.Errorf("expected weather data to be ignored, got %+v", serv.cachedWeather)
+		}
+		if !serv.displayAltText {
+			t.Error("expected alt mode to be restored anyway")
+		}
+	})
+	t.Run("failing to persist the state is recorded", func(t *testing.T) {
+		serv, err := testService(t, false)
+		if err != nil {
+			t.Fatalf("failed to create service: %s", err)
+		}
+		// The parent of the state file is a regular file, so the state directory can't be created
+		parent := filepath.Join(t.TempDir(), "file")
+		if err = os.WriteFile(parent, nil, 0o600); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+		serv.config.State.File = filepath.Join(parent, "state.json")
+		serv.persistState()
+		if _, ok := serv.status().Errors["state"]; !ok {
+			t.Error("expected the error to be recorded")
+		}
+	})
 }
@@ -10,6 +10,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
 )
 
 type signalSource interface {
@@ -38,19 +40,33 @@ func (s *Service) HandleSignals(ctx context.Context, sigChan chan os.Signal) {
 			switch sig {
 			// USR1 toggles between displaying the text and the alt text
 			case syscall.SIGUSR1:
+				if !s.config.SignalToggleEnabled() {
+					s.logger.Info("ignoring USR1 signal, toggling the view is disabled with output.signal_toggle")
+					continue
+				}
 				s.logger.Info("toggling display of weather module text and tooltip",
 					slog.Bool("display_alternative", !s.displayAltText))
 				s.displayAltLock.Lock()
 				s.displayAltText = !s.displayAltText
 				s.displayAltLock.Unlock()
-				s.printWeather(ctx)
-			// USR2 prints the current address with the stderr logger
+				s.persistState()
+				s.requestRender(TriggerSignal)
+			// USR2 prints the current address with the stderr logger and writes a debug dump in debug mode
 			case syscall.SIGUSR2:
 				s.locationLock.Lock()
 				address := s.address
 				s.locationLock.Unlock()
 				s.logger.Info("currently resolved address", slog.String("address", address.DisplayName),
 					slog.Float64("latitude", address.Latitude), slog.Float64("longitude", address.Longitude))
+				if !s.config.Debug.Enabled {
+					continue
+				}
+				path, err := s.writeDump()
+				if err != nil {
+					s.logger.Error("failed to write debug dump", logger.Err(err))
+					continue
+				}
+				s.logger.Info("debug dump written", slog.String("file", path))
 			}
 		}
 	}
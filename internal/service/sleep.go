@@ -22,10 +22,17 @@ const (
 	debounceWindow   = 2 // seconds
 	signalBufferSize = 8
 
-	busReconnectDelay   = 5 * time.Second
-	networkWakeupDelay  = 20 * time.Second
-	reconnectDelay      = 2 * time.Second
-	subscribeRetryDelay = 10 * time.Second
+	busReconnectDelay      = 5 * time.Second
+	networkWakeupDelay     = 2 * time.Second
+	locationRefreshTimeout = 30 * time.Second
+	reconnectDelay         = 2 * time.Second
+	subscribeRetryDelay    = 10 * time.Second
+
+	// The connectivity probe is retried with a doubling delay, starting at networkWakeupDelay,
+	// which gives the network up to a minute to come back up after a resume.
+	connectivityProbeURL     = "https://api.open-meteo.com/"
+	connectivityProbeRetries = 5
+	connectivityProbeTimeout = 5 * time.Second
 )
 
 // monitorSleepResume monitors system sleep and resume events using D-Bus signals and handles
@@ -83,12 +90,12 @@ func (s *Service) connectToSystemBus(ctx context.Context) *dbus.Conn {
 		}
 
 		// Ensure cleanup on context cancellation
-		go func() {
+		s.work.Go(func() {
 			<-ctx.Done()
 			if err := conn.Close(); err != nil {
 				s.logger.Error("failed to close system bus connection", logger.Err(err))
 			}
-		}()
+		})
 
 		return conn
 	}
@@ -146,10 +153,12 @@ func (s *Service) processSleepSignal(ctx context.Context, sgn *dbus.Signal, last
 	s.handleResumeEvent(ctx, lastResumeUnix)
 }
 
-// handleResumeEvent handles the system wake-up event and triggers necessary actions to refresh weather data.
-// It ensures debouncing of multiple consecutive resume events and provides time for network readiness.
+// handleResumeEvent handles the system wake-up event and schedules the refresh of the weather data.
+// It ensures debouncing of multiple consecutive resume events. The refresh itself runs asynchronously,
+// so that the signal processing is not blocked while waiting for the network. A refresh that is still
+// pending from a previous resume event is cancelled.
 func (s *Service) handleResumeEvent(ctx context.Context, lastResumeUnix *int64) {
-	now := time.Now().Unix()
+	now := s.Clock.Now().Unix()
 
 	// debounce in case of multiple resume events
 	if now-atomic.LoadInt64(lastResumeUnix) < debounceWindow {
@@ -157,15 +166,101 @@ func (s *Service) handleResumeEvent(ctx context.Context, lastResumeUnix *int64)
 	}
 	atomic.StoreInt64(lastResumeUnix, now)
 
-	// Give the system time to wake up and establish network connection
-	time.Sleep(networkWakeupDelay)
+	s.resumeLock.Lock()
+	if s.resumeCancel != nil {
+		s.resumeCancel()
+	}
+	resumeCtx, cancel := context.WithCancel(ctx)
+	s.resumeCancel = cancel
+	s.resumeLock.Unlock()
+
+	s.work.Go(func() {
+		defer cancel()
+		s.refreshAfterResume(resumeCtx)
+	})
+}
+
+// refreshAfterResume waits for the network to become reachable and refreshes the location and weather
+// data afterward. Since the system might have been moved while sleeping, the current location is
+// invalidated and all geolocation providers are asked for a fresh lookup. The weather data is fetched as
+// soon as the fresh location has been applied, or with the previous location once the location refresh
+// timed out.
+func (s *Service) refreshAfterResume(ctx context.Context) {
+	if err := s.waitForNetwork(ctx); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn("network not reachable after resume, refreshing anyway", logger.Err(err))
+	}
 
-	s.logger.Debug("resuming from sleep, fetching latest weather data")
+	s.logger.Debug("resuming from sleep, refreshing location and weather data")
 
 	s.weatherLock.Lock()
 	s.weatherIsSet = false
 	s.weatherLock.Unlock()
 
+	if s.refreshLocation(ctx, locationRefreshTimeout) {
+		return
+	}
+
+	s.logger.Debug("no fresh location received after resume, fetching weather for previous location")
 	s.fetchWeather(ctx)
-	s.printWeather(ctx)
+	s.requestRender(TriggerResume)
+}
+
+// waitForNetwork gives the system time to wake up and establish a network connection. It probes the
+// connectivity with an increasing delay until the network is reachable or the retries are exhausted.
+// It returns the last probe error or the context error if the context was cancelled while waiting.
+func (s *Service) waitForNetwork(ctx context.Context) error {
+	delay := networkWakeupDelay
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		err := s.httpClient.Probe(ctx, connectivityProbeURL, connectivityProbeTimeout)
+		if err == nil {
+			return nil
+		}
+		if attempt >= connectivityProbeRetries {
+			return err
+		}
+
+		delay *= 2
+		s.logger.Debug("network not reachable yet, retrying", slog.Int("attempt", attempt),
+			slog.Duration("retry_in", delay), logger.Err(err))
+		timer.Reset(delay)
+	}
+}
+
+// refreshLocation invalidates the current location and requests an immediate lookup from all
+// geolocation providers. It reports whether a fresh location has been applied within the timeout.
+func (s *Service) refreshLocation(ctx context.Context, timeout time.Duration) bool {
+	if s.geoOrch == nil {
+		return false
+	}
+
+	s.locationLock.Lock()
+	s.locationIsSet = false
+	s.locationLock.Unlock()
+
+	updated := s.awaitLocationUpdate()
+	s.geobus.Invalidate(s.subscriptionKey())
+	s.geoOrch.Refresh()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-updated:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
 }
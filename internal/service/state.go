@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// persistentState is the state of the service that is kept across restarts in the state file. Weather is
+// the latest weather data in the format of weather.Encode.
+type persistentState struct {
+	DisplayAltText bool             `json:"display_alt_text"`
+	Observed       observedExtremes `json:"observed,omitzero"`
+	Weather        json.RawMessage  `json:"weather,omitempty"`
+}
+
+// loadState reads the state file at the given path. A missing file results in the zero state without error.
+func loadState(path string) (persistentState, error) {
+	var state persistentState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return persistentState{}, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return state, nil
+}
+
+// saveState writes the state to the state file at the given path. The state is written to a temporary file
+// first and then renamed, so that an interrupted write doesn't corrupt the state file.
+func saveState(path string, state persistentState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmpFile := path + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err = os.Rename(tmpFile, path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+// restoreState restores the display mode, the observed temperature extremes and the weather data of the
+// previous run from the state file. A corrupt state file is ignored, so that the primary view is displayed.
+// Without the USR1 toggle, the primary view is always displayed. Extremes of a previous day are restored as
+// well, but are not shown and start over with the next observation. Weather data of a different schema
+// version is ignored.
+func (s *Service) restoreState() {
+	if s.config.State.Disabled {
+		return
+	}
+	state, err := loadState(s.config.State.File)
+	if err != nil {
+		s.logger.Warn("ignoring state of the previous run", logger.Err(err),
+			slog.String("path", s.config.State.File))
+		return
+	}
+	s.observedLock.Lock()
+	s.observed = state.Observed
+	s.observedLock.Unlock()
+	if len(state.Weather) > 0 {
+		data, err := weather.Decode(state.Weather)
+		if err != nil {
+			s.logger.Warn("ignoring weather data of the previous run", logger.Err(err),
+				slog.String("path", s.config.State.File))
+		}
+		s.weatherLock.Lock()
+		s.cachedWeather = data
+		s.weatherLock.Unlock()
+	}
+	if !s.config.SignalToggleEnabled() {
+		return
+	}
+	s.displayAltLock.Lock()
+	s.displayAltText = state.DisplayAltText
+	s.displayAltLock.Unlock()
+}
+
+// persistState writes the current display mode, the observed temperature extremes and the latest weather
+// data to the state file.
+func (s *Service) persistState() {
+	s.weatherLock.RLock()
+	data := s.weather
+	if data == nil {
+		data = s.cachedWeather
+	}
+	s.weatherLock.RUnlock()
+	s.persistStateWith(data)
+}
+
+// persistStateWith writes the state file like persistState, but with the given weather data, for callers
+// that hold the weather lock already.
+func (s *Service) persistStateWith(data *weather.Data) {
+	if s.config.State.Disabled {
+		return
+	}
+	s.displayAltLock.RLock()
+	state := persistentState{DisplayAltText: s.displayAltText}
+	s.displayAltLock.RUnlock()
+	s.observedLock.Lock()
+	state.Observed = s.observed
+	s.observedLock.Unlock()
+	if data != nil {
+		encoded, err := weather.Encode(data)
+		if err != nil {
+			s.logger.Error("failed to encode weather data for the state file", logger.Err(err))
+		}
+		state.Weather = encoded
+	}
+	if err := saveState(s.config.State.File, state); err != nil {
+		s.logger.Error("failed to persist state", logger.Err(err), slog.String("path", s.config.State.File))
+		s.recordError("state", err)
+		return
+	}
+	s.clearError("state")
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/logger"
+)
+
+const (
+	systemdReady    = "READY=1"
+	systemdWatchdog = "WATCHDOG=1"
+	systemdStopping = "STOPPING=1"
+)
+
+// systemdNotifier sends state notifications to systemd using the sd_notify datagram protocol, so that
+// waybar-weather can run as a Type=notify service with a watchdog. All methods are no-ops on a nil
+// notifier, which is the case if the service is not started by systemd.
+type systemdNotifier struct {
+	addr   *net.UnixAddr
+	logger *logger.Logger
+	// watchdog is the interval in which systemd expects the watchdog pings. It is zero if the watchdog
+	// is disabled.
+	watchdog time.Duration
+	ready    sync.Once
+}
+
+// newSystemdNotifier returns a notifier for the socket in NOTIFY_SOCKET and the watchdog interval in
+// WATCHDOG_USEC. It returns nil if NOTIFY_SOCKET is not set.
+func newSystemdNotifier(log *logger.Logger) *systemdNotifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// A leading @ denotes an abstract socket, which the net package handles the same way
+	notifier := &systemdNotifier{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}, logger: log}
+	usec, err := strconv.ParseUint(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec == 0 {
+		return notifier
+	}
+	// The watchdog is meant for another process, if WATCHDOG_PID is set to a different PID
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return notifier
+	}
+	notifier.watchdog = time.Duration(usec) * time.Microsecond
+	return notifier
+}
+
+// notify sends the given state to systemd. Since the notifications are optional, errors are only logged.
+func (n *systemdNotifier) notify(state string) {
+	if n == nil {
+		return
+	}
+	conn, err := net.DialUnix(n.addr.Net, nil, n.addr)
+	if err != nil {
+		n.logger.Debug("failed to connect to systemd notify socket", logger.Err(err))
+		return
+	}
+	defer func() {
+		if err = conn.Close(); err != nil {
+			n.logger.Debug("failed to close systemd notify socket", logger.Err(err))
+		}
+	}()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		n.logger.Debug("failed to send systemd notification", logger.Err(err), slog.String("state", state))
+	}
+}
+
+// notifyReady tells systemd that the service is ready. Only the first call sends the notification.
+func (n *systemdNotifier) notifyReady() {
+	if n == nil {
+		return
+	}
+	n.ready.Do(func() {
+		n.notify(systemdReady)
+	})
+}
+
+// awaitShutdown blocks until the context is cancelled. Meanwhile, the systemd watchdog is pinged at half
+// of its interval, as long as the render loop made progress within the watchdog interval, so that systemd
+// restarts the service if the loop hangs. Once the context is cancelled, systemd is told that the service
+// is stopping.
+func (s *Service) awaitShutdown(ctx context.Context) {
+	var watchdog <-chan time.Time
+	if s.systemd != nil && s.systemd.watchdog > 0 {
+		ticker := time.NewTicker(s.systemd.watchdog / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+
+	s.progress.Store(time.Now().UnixNano())
+	for {
+		select {
+		case <-ctx.Done():
+			s.systemd.notify(systemdStopping)
+			return
+		case <-watchdog:
+			since := time.Since(time.Unix(0, s.progress.Load()))
+			if since > s.systemd.watchdog {
+				s.logger.Warn("render loop made no progress, skipping the watchdog ping",
+					slog.Duration("since", since))
+			} else {
+				s.systemd.notify(systemdWatchdog)
+			}
+			// The render loop reports its progress until the next ping
+			select {
+			case s.heartbeat <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
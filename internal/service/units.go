@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package service
+
+import (
+	"log/slog"
+
+	"github.com/wneessen/waybar-weather/internal/geocode"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// initialUnitSystem returns the unit system that the weather is requested in before the country of the
+// location is known. With automatic units, this is the metric system.
+func initialUnitSystem(units string) string {
+	if units == weather.UnitSystemAuto {
+		return weather.UnitSystemMetric
+	}
+	return units
+}
+
+// selectUnitSystem switches the weather provider to the unit system of the country of the address, if
+// the units are selected automatically. Addresses without country, e. g. within a geofence or without
+// reverse geocoding, keep the current unit system. The unit overrides take precedence over the unit system
+// as usual. It reports whether the unit system changed, in which case the current weather data is in the
+// previous units and needs to be fetched again.
+func (s *Service) selectUnitSystem(address geocode.Address) bool {
+	if s.config.Units != weather.UnitSystemAuto || address.CountryCode == "" {
+		return false
+	}
+	system := weather.CountryUnitSystem(address.CountryCode)
+
+	// The weather lock keeps the unit system of the provider in line with the unit system of the service
+	// while the weather data is fetched
+	s.weatherLock.Lock()
+	previous := s.unitSystem
+	s.unitSystem = system
+	if setter, ok := s.weatherProv.(weather.UnitSetter); ok && system != previous {
+		setter.SetUnits(s.config.UnitPreferencesFor(system))
+	}
+	s.weatherLock.Unlock()
+
+	s.logger.Debug("selected unit system by the country of the location",
+		slog.String("country", address.CountryCode), slog.String("units", system),
+		slog.String("previous", previous), slog.Bool("changed", system != previous))
+	return system != previous
+}
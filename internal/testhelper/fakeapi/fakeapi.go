@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package fakeapi provides a local fake of the third-party APIs that waybar-weather talks to by
+// default: the Open-Meteo forecast API, the Nominatim reverse geocoding API and the GeoIP API. The
+// responses are scripted per endpoint and all requests are recorded, so that tests can assert them.
+package fakeapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Endpoint identifies an emulated API endpoint by its URL path.
+type Endpoint string
+
+const (
+	// OpenMeteoForecast emulates https://api.open-meteo.com/v1/forecast
+	OpenMeteoForecast Endpoint = "/v1/forecast"
+	// NominatimReverse emulates https://nominatim.openstreetmap.org/reverse
+	NominatimReverse Endpoint = "/reverse"
+	// GeoIP emulates https://reallyfreegeoip.org/json/
+	GeoIP Endpoint = "/json/"
+)
+
+// Response is a scripted response of an endpoint.
+type Response struct {
+	// Status is the HTTP status code. It defaults to 200.
+	Status int
+	// Body is the JSON body of the response. It is ignored if File is set.
+	Body string
+	// File is the path of a file whose content is sent as JSON body of the response.
+	File string
+	// Block holds the request until its context is cancelled, e. g. to emulate an API that doesn't answer
+	// before shutdown. The request then fails with the error of the context.
+	Block bool
+}
+
+// Server is a fake API server. Every request to an endpoint consumes the next scripted response of
+// that endpoint. Once only one response is left, it is repeated for all further requests. Requests to
+// endpoints without a scripted response fail the test.
+type Server struct {
+	*httptest.Server
+
+	t         testing.TB
+	mu        sync.Mutex
+	responses map[Endpoint][]Response
+	requests  map[Endpoint][]*http.Request
+	asserts   map[Endpoint][]func(testing.TB, *http.Request)
+}
+
+// New starts a new fake API server, which is closed when the test finishes.
+func New(t testing.TB) *Server {
+	t.Helper()
+	server := &Server{
+		t:         t,
+		responses: make(map[Endpoint][]Response),
+		requests:  make(map[Endpoint][]*http.Request),
+		asserts:   make(map[Endpoint][]func(testing.TB, *http.Request)),
+	}
+	server.Server = httptest.NewServer(server)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Script appends responses to the scripted responses of the endpoint.
+func (s *Server) Script(endpoint Endpoint, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[endpoint] = append(s.responses[endpoint], responses...)
+}
+
+// Expect registers an assertion that is called for every request to the endpoint.
+func (s *Server) Expect(endpoint Endpoint, assert func(t testing.TB, req *http.Request)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asserts[endpoint] = append(s.asserts[endpoint], assert)
+}
+
+// Requests returns the requests that the endpoint received so far.
+func (s *Server) Requests(endpoint Endpoint) []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests[endpoint]...)
+}
+
+// Transport returns a RoundTripper that serves all requests in memory by the fake server, regardless
+// of their host. This allows the providers with fixed API endpoints to be used with the fake. Since no
+// network connections are involved, it can be used within a synctest bubble.
+func (s *Server) Transport() http.RoundTripper {
+	return roundTripper{handler: s}
+}
+
+// ServeHTTP serves the next scripted response of the requested endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	endpoint := Endpoint(req.URL.Path)
+	s.mu.Lock()
+	s.requests[endpoint] = append(s.requests[endpoint], req.Clone(req.Context()))
+	asserts := s.asserts[endpoint]
+	scripted := s.responses[endpoint]
+	var res Response
+	if len(scripted) > 0 {
+		res = scripted[0]
+		if len(scripted) > 1 {
+			s.responses[endpoint] = scripted[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	for _, assert := range asserts {
+		assert(s.t, req)
+	}
+	if len(scripted) == 0 {
+		s.t.Errorf("fake API received request to endpoint without scripted response: %s", req.URL)
+		http.NotFound(w, req)
+		return
+	}
+	if res.Block {
+		<-req.Context().Done()
+		http.Error(w, req.Context().Err().Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	body := []byte(res.Body)
+	if res.File != "" {
+		var err error
+		if body, err = os.ReadFile(res.File); err != nil {
+			s.t.Errorf("failed to read fake API response file: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// roundTripper serves the requests in memory by the handler.
+type roundTripper struct {
+	handler http.Handler
+}
+
+func (r roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	r.handler.ServeHTTP(rec, req)
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	res := rec.Result()
+	res.Request = req
+	return res, nil
+}
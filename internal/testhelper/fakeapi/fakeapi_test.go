@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package fakeapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"testing/synctest"
+)
+
+func TestServer(t *testing.T) {
+	t.Run("scripted responses are served in order and the last one is repeated", func(t *testing.T) {
+		server := New(t)
+		server.Script(GeoIP, Response{Body: `{"city":"first"}`}, Response{Status: 503, Body: `{"city":"last"}`})
+		client := server.Client()
+
+		wants := []struct {
+			status int
+			body   string
+		}{
+			{200, `{"city":"first"}`},
+			{503, `{"city":"last"}`},
+			{503, `{"city":"last"}`},
+		}
+		for _, want := range wants {
+			res, err := client.Get(server.URL + string(GeoIP))
+			if err != nil {
+				t.Fatalf("request to fake API failed: %s", err)
+			}
+			body, err := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			if err != nil {
+				t.Fatalf("failed to read response body: %s", err)
+			}
+			if res.StatusCode != want.status {
+				t.Errorf("expected status code %d, got %d", want.status, res.StatusCode)
+			}
+			if string(body) != want.body {
+				t.Errorf("expected body %q, got %q", want.body, body)
+			}
+			if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected content type %q, got %q", "application/json", ct)
+			}
+		}
+		if len(server.Requests(GeoIP)) != 3 {
+			t.Errorf("expected 3 recorded requests, got %d", len(server.Requests(GeoIP)))
+		}
+	})
+	t.Run("transport serves requests for any host in memory", func(t *testing.T) {
+		server := New(t)
+		server.Script(OpenMeteoForecast, Response{File: "../../../testdata/open-meteo.json"})
+		asserted := 0
+		server.Expect(OpenMeteoForecast, func(t testing.TB, req *http.Request) {
+			asserted++
+			if got := req.URL.Query().Get("latitude"); got != "52.5" {
+				t.Errorf("expected latitude %q, got %q", "52.5", got)
+			}
+		})
+		client := &http.Client{Transport: server.Transport()}
+
+		res, err := client.Get("https://api.open-meteo.com/v1/forecast?latitude=52.5")
+		if err != nil {
+			t.Fatalf("request to fake API failed: %s", err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, res.StatusCode)
+		}
+		if asserted != 1 {
+			t.Errorf("expected assertion to be called once, got %d", asserted)
+		}
+		requests := server.Requests(OpenMeteoForecast)
+		if len(requests) != 1 || requests[0].URL.Host != "api.open-meteo.com" {
+			t.Errorf("expected one recorded request to the Open-Meteo host, got %v", requests)
+		}
+	})
+	t.Run("blocking response holds the request until it is cancelled", func(t *testing.T) {
+		server := New(t)
+		server.Script(GeoIP, Response{Block: true})
+		client := &http.Client{Transport: server.Transport()}
+		synctest.Test(t, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			errs := make(chan error, 1)
+			go func() {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://reallyfreegeoip.org/json/", nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				res, err := client.Do(req)
+				if err == nil {
+					_ = res.Body.Close()
+				}
+				errs <- err
+			}()
+			synctest.Wait()
+			select {
+			case err := <-errs:
+				t.Fatalf("expected request to be held, got %v", err)
+			default:
+			}
+
+			cancel()
+			if err := <-errs; !errors.Is(err, context.Canceled) {
+				t.Errorf("expected request to fail with %s, got %v", context.Canceled, err)
+			}
+		})
+	})
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package units parses and converts the temperature values of the configuration, like thresholds and
+// gradient stops. A value can carry an explicit unit suffix like "68F" or "20C". Values without suffix
+// are in the temperature unit that the weather is displayed in, whichever unit that is.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The temperature units. The names are the same as the unit names of the weather package.
+const (
+	Celsius    = "celsius"
+	Fahrenheit = "fahrenheit"
+)
+
+// Temperature is a temperature value of the configuration. Unit is Celsius or Fahrenheit if the value
+// has an explicit unit suffix, and empty if it is in the display unit.
+type Temperature struct {
+	Value float64
+	Unit  string
+}
+
+// ParseTemperature parses a temperature value like "20", "-3.5", "68F", "20C" or "20 °C". The suffix is
+// case-insensitive.
+func ParseTemperature(s string) (Temperature, error) {
+	value := strings.TrimSpace(s)
+	var unit string
+	switch {
+	case strings.HasSuffix(strings.ToUpper(value), "C"):
+		unit = Celsius
+	case strings.HasSuffix(strings.ToUpper(value), "F"):
+		unit = Fahrenheit
+	}
+	if unit != "" {
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value[:len(value)-1]), "°"))
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return Temperature{}, fmt.Errorf("invalid temperature: %q", s)
+	}
+	return Temperature{Value: parsed, Unit: unit}, nil
+}
+
+// String returns the temperature in the form that ParseTemperature parses, like "68F" or "20".
+func (t Temperature) String() string {
+	value := strconv.FormatFloat(t.Value, 'f', -1, 64)
+	switch t.Unit {
+	case Celsius:
+		return value + "C"
+	case Fahrenheit:
+		return value + "F"
+	default:
+		return value
+	}
+}
+
+// In returns the temperature in the given unit. Temperatures without unit are returned unchanged.
+func (t Temperature) In(unit string) float64 {
+	return ConvertTemperature(t.Value, t.Unit, unit)
+}
+
+// DeltaIn returns the temperature as a difference of temperatures in the given unit, e. g. a difference
+// of 2C is 3.6F. Differences without unit are returned unchanged.
+func (t Temperature) DeltaIn(unit string) float64 {
+	return ConvertTemperatureDelta(t.Value, t.Unit, unit)
+}
+
+// TemperatureUnit returns the temperature unit of a unit name or symbol like "fahrenheit", "°F" or "F".
+// Unknown units are returned as empty string.
+func TemperatureUnit(unit string) string {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(unit), "°")) {
+	case Celsius, "c":
+		return Celsius
+	case Fahrenheit, "f":
+		return Fahrenheit
+	default:
+		return ""
+	}
+}
+
+// ConvertTemperature converts the temperature from one unit into the other. If either of the units is
+// unknown or empty, the temperature is returned unchanged.
+func ConvertTemperature(value float64, from, to string) float64 {
+	from, to = TemperatureUnit(from), TemperatureUnit(to)
+	switch {
+	case from == Celsius && to == Fahrenheit:
+		return value*9/5 + 32
+	case from == Fahrenheit && to == Celsius:
+		return (value - 32) * 5 / 9
+	default:
+		return value
+	}
+}
+
+// ConvertTemperatureDelta converts a difference of temperatures from one unit into the other. Unlike
+// ConvertTemperature, the offset of the scales doesn't apply. If either of the units is unknown or empty,
+// the difference is returned unchanged.
+func ConvertTemperatureDelta(value float64, from, to string) float64 {
+	from, to = TemperatureUnit(from), TemperatureUnit(to)
+	switch {
+	case from == Celsius && to == Fahrenheit:
+		return value * 9 / 5
+	case from == Fahrenheit && to == Celsius:
+		return value * 5 / 9
+	default:
+		return value
+	}
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Temperature
+		wantErr bool
+	}{
+		{"20", Temperature{Value: 20}, false},
+		{"-3.5", Temperature{Value: -3.5}, false},
+		{"68F", Temperature{Value: 68, Unit: Fahrenheit}, false},
+		{"68f", Temperature{Value: 68, Unit: Fahrenheit}, false},
+		{"20C", Temperature{Value: 20, Unit: Celsius}, false},
+		{"-10.5c", Temperature{Value: -10.5, Unit: Celsius}, false},
+		{" 20 °C ", Temperature{Value: 20, Unit: Celsius}, false},
+		{"68°F", Temperature{Value: 68, Unit: Fahrenheit}, false},
+		{"", Temperature{}, true},
+		{"F", Temperature{}, true},
+		{"20K", Temperature{}, true},
+		{"warm", Temperature{}, true},
+		{"NaN", Temperature{}, true},
+		{"Inf", Temperature{}, true},
+		{"20CC", Temperature{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseTemperature(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected parsing %q to fail, got %+v", tc.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse %q: %s", tc.in, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTemperature_String(t *testing.T) {
+	tests := []string{"20", "-3.5", "68F", "20C", "-0.25C", "0F", "100.125"}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			temp, err := ParseTemperature(in)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %s", in, err)
+			}
+			if got := temp.String(); got != in {
+				t.Errorf("expected %q, got %q", in, got)
+			}
+			again, err := ParseTemperature(temp.String())
+			if err != nil {
+				t.Fatalf("failed to parse %q again: %s", temp.String(), err)
+			}
+			if again != temp {
+				t.Errorf("expected %+v after round-trip, got %+v", temp, again)
+			}
+		})
+	}
+}
+
+func TestTemperature_In(t *testing.T) {
+	tests := []struct {
+		name string
+		temp Temperature
+		unit string
+		want float64
+	}{
+		{"celsius in fahrenheit", Temperature{Value: 20, Unit: Celsius}, Fahrenheit, 68},
+		{"fahrenheit in celsius", Temperature{Value: 68, Unit: Fahrenheit}, Celsius, 20},
+		{"celsius in celsius", Temperature{Value: 20, Unit: Celsius}, Celsius, 20},
+		{"freezing point", Temperature{Value: 0, Unit: Celsius}, Fahrenheit, 32},
+		{"equal on both scales", Temperature{Value: -40, Unit: Fahrenheit}, Celsius, -40},
+		{"unit symbol", Temperature{Value: 30, Unit: Celsius}, "°F", 86},
+		{"without unit in fahrenheit", Temperature{Value: 20}, Fahrenheit, 20},
+		{"without unit in celsius", Temperature{Value: 68}, Celsius, 68},
+		{"unknown unit", Temperature{Value: 20, Unit: Celsius}, "kelvin", 20},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.temp.In(tc.unit); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("expected %g, got %g", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTemperature_DeltaIn(t *testing.T) {
+	tests := []struct {
+		name string
+		temp Temperature
+		unit string
+		want float64
+	}{
+		{"celsius in fahrenheit", Temperature{Value: 2, Unit: Celsius}, Fahrenheit, 3.6},
+		{"fahrenheit in celsius", Temperature{Value: 9, Unit: Fahrenheit}, Celsius, 5},
+		{"fahrenheit in fahrenheit", Temperature{Value: 4, Unit: Fahrenheit}, Fahrenheit, 4},
+		{"without unit", Temperature{Value: 2}, Fahrenheit, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.temp.DeltaIn(tc.unit); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("expected %g, got %g", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertTemperature(t *testing.T) {
+	t.Run("conversions round-trip", func(t *testing.T) {
+		for _, value := range []float64{-40, -17.5, 0, 21.3, 37, 100} {
+			back := ConvertTemperature(ConvertTemperature(value, Celsius, Fahrenheit), Fahrenheit, Celsius)
+			if math.Abs(back-value) > 1e-9 {
+				t.Errorf("expected %g after round-trip, got %g", value, back)
+			}
+			back = ConvertTemperatureDelta(ConvertTemperatureDelta(value, Fahrenheit, Celsius), Celsius, Fahrenheit)
+			if math.Abs(back-value) > 1e-9 {
+				t.Errorf("expected delta %g after round-trip, got %g", value, back)
+			}
+		}
+	})
+}
+
+func TestTemperatureUnit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"celsius", Celsius},
+		{"°C", Celsius},
+		{"c", Celsius},
+		{"fahrenheit", Fahrenheit},
+		{"°F", Fahrenheit},
+		{"F", Fahrenheit},
+		{"kelvin", ""},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			if got := TemperatureUnit(tc.in); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
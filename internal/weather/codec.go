@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+)
+
+// SchemaVersion is the version of the JSON form of Data. It is increased with every change of the format
+// that older versions can't decode, so that stale serialized data is rejected instead of misread.
+const SchemaVersion = 1
+
+// encodedData is the JSON form of Data. The forecast is encoded as array sorted by time instead of an
+// object keyed by DayHour, so that the encoding is stable and readable without knowing about DayHour.
+type encodedData struct {
+	SchemaVersion int               `json:"schema_version"`
+	GeneratedAt   time.Time         `json:"generated_at"`
+	Coordinates   geobus.Coordinate `json:"coordinates"`
+	Timezone      string            `json:"timezone,omitempty"`
+	Elevation     float64           `json:"elevation"`
+	Source        string            `json:"source,omitempty"`
+	Current       Instant           `json:"current"`
+	Forecast      []encodedHour     `json:"forecast"`
+	Nowcast       []NowcastStep     `json:"nowcast,omitempty"`
+}
+
+// encodedHour is an entry of the forecast map. Time is the start of the hour, FetchedAt the time the
+// entry was fetched, if the data was merged with previous data.
+type encodedHour struct {
+	Time      time.Time `json:"time"`
+	Instant   Instant   `json:"instant"`
+	FetchedAt time.Time `json:"fetched_at,omitzero"`
+}
+
+// Encode returns the JSON form of the weather data. It is the format of the weather data wherever it is
+// stored or sent to another process.
+func Encode(d *Data) ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode weather data: %w", err)
+	}
+	return data, nil
+}
+
+// Decode returns the weather data of its JSON form, as returned by Encode. It fails if the data was
+// encoded with a different schema version.
+func Decode(b []byte) (*Data, error) {
+	d := new(Data)
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("failed to decode weather data: %w", err)
+	}
+	return d, nil
+}
+
+// MarshalJSON encodes the weather data with the current schema version.
+func (d *Data) MarshalJSON() ([]byte, error) {
+	enc := encodedData{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   d.GeneratedAt,
+		Coordinates:   d.Coordinates,
+		Timezone:      d.Timezone,
+		Elevation:     d.Elevation,
+		Source:        d.Source,
+		Current:       d.Current,
+		Forecast:      make([]encodedHour, 0, len(d.Forecast)),
+		Nowcast:       d.Nowcast,
+	}
+	for _, hour := range slices.Sorted(maps.Keys(d.Forecast)) {
+		enc.Forecast = append(enc.Forecast, encodedHour{
+			Time:      hour.Time(),
+			Instant:   d.Forecast[hour],
+			FetchedAt: d.fetchedAt[hour],
+		})
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes the weather data. It fails if the data was encoded with a different schema version.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	var enc encodedData
+	if err := json.Unmarshal(b, &enc); err != nil {
+		return err
+	}
+	if enc.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", enc.SchemaVersion, SchemaVersion)
+	}
+
+	*d = Data{
+		GeneratedAt: enc.GeneratedAt,
+		Coordinates: enc.Coordinates,
+		Elevation:   enc.Elevation,
+		Source:      enc.Source,
+		Current:     enc.Current,
+		Forecast:    make(map[DayHour]Instant, len(enc.Forecast)),
+		Nowcast:     enc.Nowcast,
+	}
+	d.SetTimezone(enc.Timezone)
+	for _, entry := range enc.Forecast {
+		hour := DayHour(entry.Time.Unix())
+		d.Forecast[hour] = entry.Instant
+		if entry.FetchedAt.IsZero() {
+			continue
+		}
+		if d.fetchedAt == nil {
+			d.fetchedAt = make(map[DayHour]time.Time, len(enc.Forecast))
+		}
+		d.fetchedAt[hour] = entry.FetchedAt
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"time"
+
+	gosunrise "github.com/nathan-osman/go-sunrise"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+)
+
+// ResolveIsDay sets the IsDay flag of the current weather and the forecast instants that the provider
+// didn't report it for. It is derived from the sunrise and sunset on the date of the instant at the
+// coordinates of the data.
+func (d *Data) ResolveIsDay() {
+	if d == nil {
+		return
+	}
+	d.Current = d.Current.resolveIsDay(d.Coordinates)
+	for hour, instant := range d.Forecast {
+		d.Forecast[hour] = instant.resolveIsDay(d.Coordinates)
+	}
+}
+
+// resolveIsDay returns the Instant with the IsDay flag derived from the position of the sun, unless the
+// provider reported it.
+func (i Instant) resolveIsDay(coords geobus.Coordinate) Instant {
+	if i.HasIsDay || i.InstantTime.IsZero() {
+		return i
+	}
+	i.IsDay = isDayAt(coords, i.InstantTime)
+	return i
+}
+
+// isDayAt reports whether the given time is between sunrise and sunset of its date at the coordinates.
+// If the sun doesn't rise or set on that date, the elevation of the sun tells polar night and midnight
+// sun apart.
+func isDayAt(coords geobus.Coordinate, at time.Time) bool {
+	sunrise, sunset := gosunrise.SunriseSunset(coords.Lat, coords.Lon, at.Year(), at.Month(), at.Day())
+	if sunrise.IsZero() || sunset.IsZero() {
+		return gosunrise.Elevation(coords.Lat, coords.Lon, at) > 0
+	}
+	return !at.Before(sunrise) && at.Before(sunset)
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/clock"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+)
+
+var (
+	// ErrNoProviders is returned by NewFailover if no weather providers are given.
+	ErrNoProviders = errors.New("no weather providers given")
+	// ErrAllDisabled is returned by Failover.GetWeather if all weather providers rejected their credentials.
+	ErrAllDisabled = providererr.Wrap(providererr.ErrAuth,
+		errors.New("all weather providers rejected their credentials"))
+)
+
+// Failover is a Provider that fetches the weather data from a list of weather providers. The providers
+// are tried in order until one of them succeeds, starting with the last working provider, once it failed
+// threshold times in a row. While a fallback provider is active, the primary provider is probed again once
+// the probe interval has passed.
+// A provider that rejects its credentials is disabled and not tried again, since retrying won't help.
+// Failover is safe for concurrent use.
+type Failover struct {
+	providers     []Provider
+	probeInterval time.Duration
+	threshold     int
+	logger        *logger.Logger
+	clock         clock.Clock
+
+	lock sync.Mutex
+	// active is the index of the last working provider and failures the number of its consecutive failures
+	active   int
+	failures int
+	// probedAt is the time the primary provider failed last
+	probedAt time.Time
+	// disabled are the indexes of the providers that rejected their credentials
+	disabled map[int]bool
+}
+
+// NewFailover returns a new Failover for the given providers. The first provider is the primary
+// provider, which is probed again in the probe interval while a fallback provider is active. The other
+// providers are only tried once the active provider failed threshold times in a row. A threshold of 0
+// is the same as 1, which fails over on the first error.
+func NewFailover(log *logger.Logger, probeInterval time.Duration, threshold uint,
+	providers ...Provider,
+) (*Failover, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	return &Failover{
+		providers:     providers,
+		probeInterval: probeInterval,
+		threshold:     max(int(threshold), 1),
+		logger:        log,
+		clock:         clock.Real{},
+		disabled:      make(map[int]bool),
+	}, nil
+}
+
+// Name returns the name of the active provider.
+func (f *Failover) Name() string {
+	return f.activeProvider().Name()
+}
+
+// Attribution returns the attribution of the active provider.
+func (f *Failover) Attribution() string {
+	return f.activeProvider().Attribution()
+}
+
+// GetWeather fetches the weather data from the first provider that succeeds. The Source of the returned
+// data is set to the name of that provider. If the active provider fails, its error is returned until it
+// reached the failure threshold. If all providers fail, the errors of all providers are returned. If all
+// providers are disabled, ErrAllDisabled is returned.
+func (f *Failover) GetWeather(ctx context.Context, coords geobus.Coordinate) (*Data, error) {
+	order := f.order()
+	if len(order) == 0 {
+		return nil, ErrAllDisabled
+	}
+
+	var errs []error
+	for _, idx := range order {
+		provider := f.providers[idx]
+		data, err := provider.GetWeather(ctx, coords)
+		if err != nil {
+			failOver := f.failOver(idx, err)
+			switch {
+			case providererr.Kind(err) == providererr.ErrAuth:
+				f.disable(idx, err)
+			case failOver:
+				f.logger.Debug("weather provider failed, trying next provider", logger.Err(err),
+					slog.String("source", provider.Name()))
+			default:
+				f.logger.Debug("weather provider failed, retrying it with the next fetch", logger.Err(err),
+					slog.String("source", provider.Name()))
+			}
+			if idx == 0 {
+				f.lock.Lock()
+				f.probedAt = f.clock.Now()
+				f.lock.Unlock()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			if ctx.Err() != nil || !failOver {
+				break
+			}
+			continue
+		}
+
+		f.activate(idx)
+		data.Source = provider.Name()
+		return data, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// RetainRawResponse enables the retention of the raw responses of all providers that support it.
+func (f *Failover) RetainRawResponse(limit int) {
+	for _, provider := range f.providers {
+		if retainer, ok := provider.(RawResponseRetainer); ok {
+			retainer.RetainRawResponse(limit)
+		}
+	}
+}
+
+// SetUnits sets the units of all providers that support it.
+func (f *Failover) SetUnits(units UnitPreferences) {
+	for _, provider := range f.providers {
+		if setter, ok := provider.(UnitSetter); ok {
+			setter.SetUnits(units)
+		}
+	}
+}
+
+// LastRawResponse returns the retained raw response of the active provider.
+func (f *Failover) LastRawResponse() ([]byte, bool) {
+	if retainer, ok := f.activeProvider().(RawResponseRetainer); ok {
+		return retainer.LastRawResponse()
+	}
+	return nil, false
+}
+
+// order returns the indexes of the providers in the order they are tried. The active provider comes
+// first, followed by the others in the configured order. Once the probe interval has passed, the
+// primary provider is tried first again. Disabled providers are left out.
+func (f *Failover) order() []int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	order := make([]int, 0, len(f.providers))
+	if f.active != 0 && !f.disabled[f.active] && f.clock.Now().Sub(f.probedAt) < f.probeInterval {
+		order = append(order, f.active)
+	}
+	for idx := range f.providers {
+		if f.disabled[idx] {
+			continue
+		}
+		if len(order) == 0 || idx != order[0] {
+			order = append(order, idx)
+		}
+	}
+	return order
+}
+
+// failOver counts the failure of the provider with the given index and reports whether the next provider
+// is tried. Failures of providers other than the active one, e. g. a probe of the primary provider, always
+// fail over, as do rejected credentials, since retrying won't help.
+func (f *Failover) failOver(idx int, err error) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if idx != f.active || providererr.Kind(err) == providererr.ErrAuth {
+		return true
+	}
+	f.failures++
+	return f.failures >= f.threshold
+}
+
+// disable disables the provider with the given index after it rejected its credentials. The error is
+// logged once, since the provider is not tried again.
+func (f *Failover) disable(idx int, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.disabled[idx] {
+		return
+	}
+	f.disabled[idx] = true
+	f.logger.Error("weather provider rejected its credentials, disabling it until restart", logger.Err(err),
+		slog.String("source", f.providers[idx].Name()))
+}
+
+// activate makes the provider with the given index the active provider.
+func (f *Failover) activate(idx int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failures = 0
+	if idx == f.active {
+		return
+	}
+	f.logger.Info("switched weather provider", slog.String("from", f.providers[f.active].Name()),
+		slog.String("to", f.providers[idx].Name()))
+	f.active = idx
+}
+
+// activeProvider returns the last working provider.
+func (f *Failover) activeProvider() Provider {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.providers[f.active]
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"time"
+)
+
+// pruneAge is the age after which past forecast entries are removed from the forecast map. It keeps the
+// hours that are needed for the comparison with yesterday.
+const pruneAge = time.Hour * 24
+
+// Merge merges the forecast of the previous weather data into d, so that a fetch with a shorter forecast
+// horizon does not drop hours that were known before. Entries of d replace the previous entries of the
+// same hour. Previous entries that are missing in d are kept, unless they were fetched more than maxAge
+// before now. A maxAge of zero disables keeping previous entries. Previous data of a different location
+// or in different units is ignored. Finally, entries that are more than 24 hours in the past are pruned
+// from d. It returns the number of kept previous entries.
+func (d *Data) Merge(prev *Data, maxAge time.Duration, now time.Time) int {
+	if d == nil {
+		return 0
+	}
+	if d.Forecast == nil {
+		d.Forecast = make(map[DayHour]Instant)
+	}
+	d.fetchedAt = make(map[DayHour]time.Time, len(d.Forecast))
+	for hour := range d.Forecast {
+		d.fetchedAt[hour] = d.GeneratedAt
+	}
+
+	kept := 0
+	if maxAge > 0 && prev != nil && prev.sameLocation(d) && prev.Current.Units == d.Current.Units {
+		for hour, instant := range prev.Forecast {
+			if _, ok := d.Forecast[hour]; ok {
+				continue
+			}
+			fetchedAt := prev.entryFetchedAt(hour)
+			if now.Sub(fetchedAt) > maxAge {
+				continue
+			}
+			d.Forecast[hour] = instant
+			d.fetchedAt[hour] = fetchedAt
+			kept++
+		}
+	}
+
+	cutoff := d.DayHour(now.Add(-pruneAge))
+	for hour := range d.Forecast {
+		if hour < cutoff {
+			delete(d.Forecast, hour)
+			delete(d.fetchedAt, hour)
+		}
+	}
+
+	return kept
+}
+
+// entryFetchedAt returns the time the forecast entry of the given hour was fetched from the provider.
+// Entries that were not merged are as old as the data itself.
+func (d *Data) entryFetchedAt(hour DayHour) time.Time {
+	if fetchedAt, ok := d.fetchedAt[hour]; ok {
+		return fetchedAt
+	}
+	return d.GeneratedAt
+}
+
+// sameLocation reports whether the weather data of d and other belong to the same location. Differences
+// in the accuracy of the coordinates are ignored.
+func (d *Data) sameLocation(other *Data) bool {
+	a, b := d.Coordinates, other.Coordinates
+	a.Acc, b.Acc = 0, 0
+	return !a.PosHasSignificantChange(b)
+}
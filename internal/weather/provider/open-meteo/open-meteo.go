@@ -6,47 +6,85 @@ package openmeteo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/weather"
 )
 
 const (
 	name        = "open-meteo"
+	attribution = "Weather data by Open-Meteo.com"
 	apiEndpoint = "https://api.open-meteo.com/v1/forecast"
 	apiTimeout  = time.Second * 10
+
+	// nowcastSteps is the number of 15-minutely steps of the nowcast, which covers the next two hours
+	nowcastSteps = 8
 )
 
+// ErrNoWeatherData is returned if the API response does not contain any current weather data
+var ErrNoWeatherData = providererr.Wrap(providererr.ErrBadResponse,
+	errors.New("Open-Meteo API returned no current weather data"))
+
 var dataFields = []string{
 	"temperature_2m", "apparent_temperature", "weather_code", "wind_speed_10m", "is_day",
-	"wind_direction_10m", "relative_humidity_2m", "pressure_msl", "wind_gusts_10m",
+	"wind_direction_10m", "relative_humidity_2m", "pressure_msl", "wind_gusts_10m", "dew_point_2m",
+	"precipitation",
 }
 
 type OpenMeteo struct {
-	unit string
-	log  *logger.Logger
-	http *http.Client
+	unit      string
+	unitsLock sync.RWMutex
+	units     weather.UnitPreferences
+	model     string
+	elevation *float64
+	nowcast   bool
+	log       *logger.Logger
+	http      *http.Client
+
+	// rawLimit is the maximum size of the retained raw response body. If 0, it is not retained.
+	rawLimit     int
+	rawLock      sync.RWMutex
+	raw          []byte
+	rawTruncated bool
+}
+
+// rawResponse decodes the API response while retaining its raw body up to the limit.
+type rawResponse struct {
+	*response
+	limit     int
+	raw       []byte
+	truncated bool
 }
 
 type resTime struct {
 	time.Time
 }
 
+// resBool is a boolean of the API response. set reports whether the response held a value.
 type resBool struct {
 	bool
+	set bool
 }
 
 type response struct {
+	Error                bool    `json:"error"`
+	Reason               string  `json:"reason"`
 	Latitude             float64 `json:"latitude"`
 	Longitude            float64 `json:"longitude"`
 	GenerationTimeMs     float64 `json:"generationtime_ms"`
-	UTCOffsetSeconds     int     `json:"utc_offset_seconds"`
+	UTCOffsetSeconds     *int    `json:"utc_offset_seconds"`
 	Timezone             string  `json:"timezone"`
 	TimezoneAbbreviation string  `json:"timezone_abbreviation"`
 	Elevation            float64 `json:"elevation"`
@@ -61,6 +99,8 @@ type response struct {
 		WindDirection       string `json:"wind_direction_10m"`
 		RelativeHumidity    string `json:"relative_humidity_2m"`
 		PressureMsl         string `json:"pressure_msl"`
+		DewPoint            string `json:"dew_point_2m"`
+		Precipitation       string `json:"precipitation"`
 	} `json:"current_units"`
 	Current struct {
 		Time                resTime `json:"time"`
@@ -74,6 +114,8 @@ type response struct {
 		WindDirection       int     `json:"wind_direction_10m"`
 		RelativeHumidity    int     `json:"relative_humidity_2m"`
 		PressureMSL         float64 `json:"pressure_msl"`
+		DewPoint            float64 `json:"dew_point_2m"`
+		Precipitation       float64 `json:"precipitation"`
 	} `json:"current"`
 	HourlyUnits struct {
 		Time                string `json:"time"`
@@ -85,6 +127,8 @@ type response struct {
 		WindDirection       string `json:"wind_direction_10m"`
 		RelativeHumidity    string `json:"relative_humidity_2m"`
 		PressureMsl         string `json:"pressure_msl"`
+		DewPoint            string `json:"dew_point_2m"`
+		Precipitation       string `json:"precipitation"`
 	} `json:"hourly_units"`
 	Hourly struct {
 		Time                []resTime `json:"time"`
@@ -97,7 +141,17 @@ type response struct {
 		WindDirection       []int     `json:"wind_direction_10m"`
 		RelativeHumidity    []int     `json:"relative_humidity_2m"`
 		PressureMsl         []float64 `json:"pressure_msl"`
+		DewPoint            []float64 `json:"dew_point_2m"`
+		Precipitation       []float64 `json:"precipitation"`
 	} `json:"hourly"`
+	Minutely15Units struct {
+		Time          string `json:"time"`
+		Precipitation string `json:"precipitation"`
+	} `json:"minutely_15_units"`
+	Minutely15 struct {
+		Time          []resTime `json:"time"`
+		Precipitation []float64 `json:"precipitation"`
+	} `json:"minutely_15"`
 }
 
 type Hourly struct {
@@ -113,13 +167,68 @@ func New(http *http.Client, log *logger.Logger, unit string) (*OpenMeteo, error)
 		return nil, fmt.Errorf("logger is required")
 	}
 
-	return &OpenMeteo{unit: unit, http: http, log: log}, nil
+	return &OpenMeteo{unit: unit, units: weather.DefaultUnits(unit), http: http, log: log}, nil
+}
+
+// SetUnits overrides the units of the individual metrics that are requested from the API. Units that
+// the API does not support, like the pressure units, are left to the presenter to convert. It is safe to
+// call while weather data is requested.
+func (o *OpenMeteo) SetUnits(units weather.UnitPreferences) {
+	o.unitsLock.Lock()
+	defer o.unitsLock.Unlock()
+	o.units = units
+}
+
+// preferredUnits returns the units that the metrics are requested in.
+func (o *OpenMeteo) preferredUnits() weather.UnitPreferences {
+	o.unitsLock.RLock()
+	defer o.unitsLock.RUnlock()
+	return o.units
+}
+
+// SetModel selects the weather model of the forecast instead of the automatic selection of the API.
+func (o *OpenMeteo) SetModel(model string) {
+	o.model = model
+}
+
+// SetElevation overrides the elevation in meters that is used to downscale the forecast, instead of the
+// average elevation of the grid cell. This matters in mountain regions.
+func (o *OpenMeteo) SetElevation(elevation float64) {
+	o.elevation = &elevation
+}
+
+// SetNowcast enables the request of the 15-minutely precipitation, which is returned as nowcast of the
+// weather data. It is not requested by default.
+func (o *OpenMeteo) SetNowcast(enabled bool) {
+	o.nowcast = enabled
+}
+
+// RetainRawResponse enables the retention of up to limit bytes of the raw body of the last API response,
+// which can be retrieved with LastRawResponse for debugging.
+func (o *OpenMeteo) RetainRawResponse(limit int) {
+	o.rawLock.Lock()
+	defer o.rawLock.Unlock()
+	o.rawLimit = limit
+}
+
+// LastRawResponse returns the retained raw body of the last API response and whether it was truncated.
+func (o *OpenMeteo) LastRawResponse() ([]byte, bool) {
+	o.rawLock.RLock()
+	defer o.rawLock.RUnlock()
+	return o.raw, o.rawTruncated
 }
 
 func (o *OpenMeteo) Name() string {
 	return name
 }
 
+func (o *OpenMeteo) Attribution() string {
+	if o.model != "" {
+		return fmt.Sprintf("%s (%s)", attribution, o.model)
+	}
+	return attribution
+}
+
 func (o *OpenMeteo) GetWeather(ctx context.Context, coords geobus.Coordinate) (*weather.Data, error) {
 	res := new(response)
 	data := weather.NewData()
@@ -139,24 +248,73 @@ func (o *OpenMeteo) GetWeather(ctx context.Context, coords geobus.Coordinate) (*
 	query.Set("hourly", strings.Join(dataFields, ","))
 	query.Set("timezone", tz)
 	query.Set("past_days", "1")
-	if strings.ToLower(o.unit) == "imperial" {
+	units := o.preferredUnits()
+	if units.Temperature == weather.UnitFahrenheit {
 		query.Set("temperature_unit", "fahrenheit")
-		query.Set("wind_speed_unit", "mph")
+	}
+	if units.WindSpeed != "" && units.WindSpeed != weather.UnitKmh {
+		query.Set("wind_speed_unit", units.WindSpeed)
+	}
+	if units.Precipitation == weather.UnitInch {
 		query.Set("precipitation_unit", "inch")
 	}
+	logArgs := []any{slog.String("timezone", tz)}
+	if o.model != "" {
+		query.Set("models", o.model)
+		logArgs = append(logArgs, slog.String("model", o.model))
+	}
+	if o.elevation != nil {
+		query.Set("elevation", strconv.FormatFloat(*o.elevation, 'f', -1, 64))
+		logArgs = append(logArgs, slog.Float64("elevation", *o.elevation))
+	}
+	if o.nowcast {
+		query.Set("minutely_15", "precipitation")
+		// The step in progress is included, so that ongoing precipitation is part of the nowcast
+		query.Set("past_minutely_15", "1")
+		query.Set("forecast_minutely_15", strconv.Itoa(nowcastSteps))
+		logArgs = append(logArgs, slog.Bool("nowcast", true))
+	}
+	o.log.Debug("requesting weather data from Open-Meteo API", logArgs...)
 
-	code, err := o.http.GetWithTimeout(ctx, apiEndpoint, res, query, nil, apiTimeout)
+	var target any = res
+	o.rawLock.RLock()
+	if o.rawLimit > 0 {
+		target = &rawResponse{response: res, limit: o.rawLimit}
+	}
+	o.rawLock.RUnlock()
+	code, err := o.http.GetWithTimeout(ctx, apiEndpoint, target, query, nil, apiTimeout)
+	if raw, ok := target.(*rawResponse); ok {
+		o.rawLock.Lock()
+		o.raw, o.rawTruncated = raw.raw, raw.truncated
+		o.rawLock.Unlock()
+	}
+	// Error responses are decoded as well and explain the error better than the response body snippet
+	if res.Error {
+		return data, providererr.Wrap(providererr.Status(code, false),
+			fmt.Errorf("Open-Meteo API returned an error: %s", res.Reason))
+	}
 	if err != nil {
 		return data, fmt.Errorf("failed to retrieve weather data from Open-Meteo API: %w", err)
 	}
-	if code != 200 {
-		return data, fmt.Errorf("Open-Meteo API returned non-positive response code: %d", code)
+	// A response without the current weather would render as zero values, so it's treated as failure
+	if res.Current.Time.IsZero() {
+		return data, ErrNoWeatherData
 	}
 
+	// The API reports the times in the wall clock of the requested time zone, which is not necessarily
+	// the local time zone of the system
+	loc := res.location()
+	// The unit strings are missing from the response if a metric was not returned. In this case the
+	// units that were requested are assumed, with the pressure always being reported in hPa.
+	requested := units
+	requested.Pressure = weather.UnitHPa
+	defaultUnits := requested.Units()
 	data.GeneratedAt = time.Now()
 	data.Coordinates = coords
+	data.SetTimezone(res.Timezone)
+	data.Elevation = res.Elevation
 	data.Current = weather.Instant{
-		InstantTime:         res.Current.Time.Time,
+		InstantTime:         res.Current.Time.in(loc),
 		Temperature:         res.Current.Temperature,
 		ApparentTemperature: res.Current.ApparentTemperature,
 		WeatherCode:         res.Current.WeatherCode,
@@ -165,43 +323,152 @@ func (o *OpenMeteo) GetWeather(ctx context.Context, coords geobus.Coordinate) (*
 		WindDirection:       float64(res.Current.WindDirection),
 		RelativeHumidity:    float64(res.Current.RelativeHumidity),
 		PressureMSL:         res.Current.PressureMSL,
+		DewPoint:            res.Current.DewPoint,
+		Precipitation:       res.Current.Precipitation,
 		IsDay:               res.Current.IsDay.bool,
+		HasIsDay:            res.Current.IsDay.set,
 		Units: weather.Units{
 			Temperature:   res.CurrentUnits.Temperature,
 			WindSpeed:     res.CurrentUnits.WindSpeed,
 			Humidity:      res.CurrentUnits.RelativeHumidity,
 			Pressure:      res.CurrentUnits.PressureMsl,
 			WindDirection: res.CurrentUnits.WindDirection,
-		},
+			Precipitation: res.CurrentUnits.Precipitation,
+		}.WithDefaults(defaultUnits),
+	}
+	hourlyUnits := weather.Units{
+		Temperature:   res.HourlyUnits.Temperature,
+		WindSpeed:     res.HourlyUnits.WindSpeed,
+		Humidity:      res.HourlyUnits.RelativeHumidity,
+		Pressure:      res.HourlyUnits.PressureMsl,
+		WindDirection: res.HourlyUnits.WindDirection,
+		Precipitation: res.HourlyUnits.Precipitation,
+	}.WithDefaults(defaultUnits)
+
+	// The API occasionally returns hourly arrays of differing lengths if a metric is not available.
+	// Metrics are filled as far as they are available and left empty for the remaining hours.
+	if truncated := res.truncatedHourlyFields(); len(truncated) > 0 {
+		o.log.Warn("Open-Meteo API returned incomplete hourly data", slog.Int("hours", len(res.Hourly.Time)),
+			slog.String("truncated_fields", strings.Join(truncated, ",")))
 	}
 	for i := range res.Hourly.Time {
-		timePos := weather.NewDayHour(res.Hourly.Time[i].Time)
+		// Hours without time can't be placed in the forecast
+		if res.Hourly.Time[i].IsZero() {
+			continue
+		}
+		timePos := weather.NewDayHourIn(res.Hourly.Time[i].in(loc), loc)
 		instant := weather.Instant{
-			InstantTime:         timePos.Time(),
-			Temperature:         res.Hourly.Temperature[i],
-			ApparentTemperature: res.Hourly.ApparentTemperature[i],
-			WeatherCode:         res.Hourly.WeatherCode[i],
-			WindSpeed:           res.Hourly.WindSpeed[i],
-			WindGusts:           res.Hourly.WindGusts[i],
-			WindDirection:       float64(res.Hourly.WindDirection[i]),
-			RelativeHumidity:    float64(res.Hourly.RelativeHumidity[i]),
-			PressureMSL:         res.Hourly.PressureMsl[i],
-			IsDay:               res.Hourly.IsDay[i].bool,
-			Units: weather.Units{
-				Temperature:   res.HourlyUnits.Temperature,
-				WindSpeed:     res.HourlyUnits.WindSpeed,
-				Humidity:      res.HourlyUnits.RelativeHumidity,
-				Pressure:      res.HourlyUnits.PressureMsl,
-				WindDirection: res.HourlyUnits.WindDirection,
-			},
+			InstantTime:         timePos.In(loc),
+			Temperature:         valueAt(res.Hourly.Temperature, i),
+			ApparentTemperature: valueAt(res.Hourly.ApparentTemperature, i),
+			WeatherCode:         valueAt(res.Hourly.WeatherCode, i),
+			WindSpeed:           valueAt(res.Hourly.WindSpeed, i),
+			WindGusts:           valueAt(res.Hourly.WindGusts, i),
+			WindDirection:       float64(valueAt(res.Hourly.WindDirection, i)),
+			RelativeHumidity:    float64(valueAt(res.Hourly.RelativeHumidity, i)),
+			PressureMSL:         valueAt(res.Hourly.PressureMsl, i),
+			DewPoint:            valueAt(res.Hourly.DewPoint, i),
+			Precipitation:       valueAt(res.Hourly.Precipitation, i),
+			IsDay:               valueAt(res.Hourly.IsDay, i).bool,
+			HasIsDay:            valueAt(res.Hourly.IsDay, i).set,
+			Units:               hourlyUnits,
 		}
 		data.Forecast[timePos] = instant
 	}
 
+	nowcastUnit := res.Minutely15Units.Precipitation
+	if nowcastUnit == "" {
+		nowcastUnit = defaultUnits.Precipitation
+	}
+	for i := range res.Minutely15.Time {
+		if res.Minutely15.Time[i].IsZero() {
+			continue
+		}
+		data.Nowcast = append(data.Nowcast, weather.NowcastStep{
+			Time:          res.Minutely15.Time[i].in(loc),
+			Precipitation: valueAt(res.Minutely15.Precipitation, i),
+			Unit:          nowcastUnit,
+		})
+	}
+
 	return data, nil
 }
 
+// location returns the time zone of the response. The IANA name of the time zone is preferred, since it
+// covers DST transitions within the forecast. If it is unknown to the system or the time zone database of
+// the system disagrees with the UTC offset that the API reported for the current time, the UTC offset is
+// used instead.
+func (r *response) location() *time.Location {
+	offset := 0
+	if r.UTCOffsetSeconds != nil {
+		offset = *r.UTCOffsetSeconds
+	}
+	if r.Timezone != "" {
+		if loc, err := time.LoadLocation(r.Timezone); err == nil {
+			_, current := r.Current.Time.in(loc).Zone()
+			if r.UTCOffsetSeconds == nil || current == offset {
+				return loc
+			}
+		}
+	}
+	return time.FixedZone(r.TimezoneAbbreviation, offset)
+}
+
+// truncatedHourlyFields returns the names of all hourly metrics that hold fewer values than the
+// hourly time array.
+func (r *response) truncatedHourlyFields() []string {
+	hours := len(r.Hourly.Time)
+	lengths := []struct {
+		field  string
+		length int
+	}{
+		{"temperature_2m", len(r.Hourly.Temperature)},
+		{"apparent_temperature", len(r.Hourly.ApparentTemperature)},
+		{"weather_code", len(r.Hourly.WeatherCode)},
+		{"wind_speed_10m", len(r.Hourly.WindSpeed)},
+		{"wind_gusts_10m", len(r.Hourly.WindGusts)},
+		{"is_day", len(r.Hourly.IsDay)},
+		{"wind_direction_10m", len(r.Hourly.WindDirection)},
+		{"relative_humidity_2m", len(r.Hourly.RelativeHumidity)},
+		{"pressure_msl", len(r.Hourly.PressureMsl)},
+		{"dew_point_2m", len(r.Hourly.DewPoint)},
+		{"precipitation", len(r.Hourly.Precipitation)},
+	}
+
+	var truncated []string
+	for _, l := range lengths {
+		if l.length < hours {
+			truncated = append(truncated, l.field)
+		}
+	}
+	return truncated
+}
+
+// valueAt returns the value at index i of values or the zero value if values is too short.
+func valueAt[T any](values []T, i int) T {
+	var zero T
+	if i >= len(values) {
+		return zero
+	}
+	return values[i]
+}
+
+// UnmarshalJSON retains up to limit bytes of the raw response body and decodes it into the response.
+func (r *rawResponse) UnmarshalJSON(b []byte) error {
+	r.truncated = len(b) > r.limit
+	r.raw = append([]byte(nil), b[:min(len(b), r.limit)]...)
+	return json.Unmarshal(b, r.response)
+}
+
+// UnmarshalJSON parses the wall clock time of the API. A null value results in the zero time, so that a
+// missing time does not fail the decoding of the whole response. The wall clock is kept in UTC, since it
+// belongs to the time zone of the response, which is only attached by in. Parsing it in the local time zone
+// of the system would shift the wall clock times that fall into a DST gap of the system.
 func (r *resTime) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		r.Time = time.Time{}
+		return nil
+	}
 	if b[0] != '"' {
 		return fmt.Errorf("invalid time format: %s", string(b))
 	}
@@ -210,17 +477,27 @@ func (r *resTime) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return fmt.Errorf("failed to parse time: %w", err)
 	}
-	localApiTime := time.Date(apiTime.Year(), apiTime.Month(), apiTime.Day(), apiTime.Hour(), apiTime.Minute(),
-		apiTime.Second(), 0, time.Local)
-	r.Time = localApiTime
+	r.Time = apiTime
 
 	return nil
 }
 
+// in returns the wall clock time of the resTime in the given time zone.
+func (r resTime) in(loc *time.Location) time.Time {
+	return time.Date(r.Year(), r.Month(), r.Day(), r.Hour(), r.Minute(), r.Second(), 0, loc)
+}
+
+// UnmarshalJSON accepts 0 and 1, true and false and their quoted variants. A null value is ignored, like
+// encoding/json does for the built-in types. Any other value fails.
 func (r *resBool) UnmarshalJSON(b []byte) error {
-	if b[0] == '0' {
-		return nil
+	switch string(b) {
+	case "null":
+	case "0", "false", `"0"`, `"false"`:
+		r.bool, r.set = false, true
+	case "1", "true", `"1"`, `"true"`:
+		r.bool, r.set = true, true
+	default:
+		return fmt.Errorf("invalid boolean value: %s", string(b))
 	}
-	r.bool = true
 	return nil
 }
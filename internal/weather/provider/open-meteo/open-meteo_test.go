@@ -7,17 +7,23 @@ package openmeteo
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	stdhttp "net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/wneessen/waybar-weather/internal/geobus"
 	"github.com/wneessen/waybar-weather/internal/http"
 	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 	"github.com/wneessen/waybar-weather/internal/testhelper"
 	"github.com/wneessen/waybar-weather/internal/weather"
 )
@@ -27,6 +33,8 @@ const (
 	testLon          = 26.125
 	testDataMetric   = "../../../../testdata/open-meteo.json"
 	testDataImperial = "../../../../testdata/open-meteo-fahrenheit.json"
+	testDataError    = "../../../../testdata/open-meteo-error.json"
+	testDataTokyo    = "../../../../testdata/open-meteo-tokyo.json"
 )
 
 func TestNew(t *testing.T) {
@@ -81,7 +89,9 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 	t.Run("weather lookup succeeds", func(t *testing.T) {
 		unit := "metric"
 		client := testClient(t, unit, false)
+		var query url.Values
 		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			query = req.URL.Query()
 			data, err := os.Open(testDataMetric)
 			if err != nil {
 				t.Fatalf("failed to open JSON response file: %s", err)
@@ -102,8 +112,19 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 		if data.GeneratedAt.IsZero() {
 			t.Error("expected generated at to be set")
 		}
+		for _, block := range []string{"current", "hourly"} {
+			fields := strings.Split(query.Get(block), ",")
+			for _, field := range []string{"wind_gusts_10m", "precipitation"} {
+				if !slices.Contains(fields, field) {
+					t.Errorf("expected %s fields to contain %q, got %q", block, field, query.Get(block))
+				}
+			}
+		}
+		if data.Timezone != "Europe/Bucharest" {
+			t.Errorf("expected timezone to be %q, got %q", "Europe/Bucharest", data.Timezone)
+		}
 		wantCurrent := weather.Instant{
-			InstantTime:         time.Date(2026, 1, 16, 22, 0o0, 0o0, 0o0, time.Local),
+			InstantTime:         time.Date(2026, 1, 16, 22, 0o0, 0o0, 0o0, data.Location()),
 			Temperature:         -5.3,
 			ApparentTemperature: -9.2,
 			WeatherCode:         0,
@@ -112,6 +133,7 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			WindDirection:       81,
 			RelativeHumidity:    72,
 			PressureMSL:         1034.7,
+			DewPoint:            -9.6,
 		}
 		if data.Current.Temperature != wantCurrent.Temperature {
 			t.Errorf("expected current temperature to be %f, got %f", wantCurrent.Temperature,
@@ -145,6 +167,13 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			t.Errorf("expected current pressure MSL to be %f, got %f", wantCurrent.PressureMSL,
 				data.Current.PressureMSL)
 		}
+		if data.Current.DewPoint != wantCurrent.DewPoint {
+			t.Errorf("expected current dew point to be %f, got %f", wantCurrent.DewPoint, data.Current.DewPoint)
+		}
+		if data.Current.Precipitation != wantCurrent.Precipitation {
+			t.Errorf("expected current precipitation to be %f, got %f", wantCurrent.Precipitation,
+				data.Current.Precipitation)
+		}
 		wantFCast := weather.Instant{
 			Temperature:         -3.0,
 			ApparentTemperature: -6.6,
@@ -154,8 +183,9 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			WindDirection:       232,
 			RelativeHumidity:    91,
 			PressureMSL:         1022.2,
+			DewPoint:            -4.3,
 		}
-		fcastTime := weather.NewDayHour(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+		fcastTime := data.DayHour(time.Date(2026, 1, 15, 0, 0, 0, 0, data.Location()))
 		fcast := data.Forecast[fcastTime]
 		if fcast.Temperature != wantFCast.Temperature {
 			t.Errorf("expected forecast temperature to be %f, got %f", wantFCast.Temperature, fcast.Temperature)
@@ -184,12 +214,20 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 		if fcast.PressureMSL != wantFCast.PressureMSL {
 			t.Errorf("expected forecast pressure MSL to be %f, got %f", wantFCast.PressureMSL, fcast.PressureMSL)
 		}
+		if fcast.DewPoint != wantFCast.DewPoint {
+			t.Errorf("expected forecast dew point to be %f, got %f", wantFCast.DewPoint, fcast.DewPoint)
+		}
+		precipTime := data.DayHour(time.Date(2026, 1, 16, 14, 0, 0, 0, data.Location()))
+		if precip := data.Forecast[precipTime].Precipitation; precip != 1.3 {
+			t.Errorf("expected forecast precipitation to be %f, got %f", 1.3, precip)
+		}
 		wantUnits := map[string]string{
 			"temperature": "°C",
 			"pressure":    "hPa",
 			"windspeed":   "km/h",
 			"humidity":    "%",
 			"winddir":     "°",
+			"precip":      "mm",
 		}
 		if data.Current.Units.Temperature != wantUnits["temperature"] {
 			t.Errorf("expected current temperature units to be %q, got %q", wantUnits["temperature"],
@@ -211,6 +249,188 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			t.Errorf("expected current wind direction units to be %q, got %q", wantUnits["winddir"],
 				data.Current.Units.WindDirection)
 		}
+		if data.Current.Units.Precipitation != wantUnits["precip"] {
+			t.Errorf("expected current precipitation units to be %q, got %q", wantUnits["precip"],
+				data.Current.Units.Precipitation)
+		}
+		wantFCastUnits := weather.Units{
+			Temperature:   wantUnits["temperature"],
+			WindSpeed:     wantUnits["windspeed"],
+			Humidity:      wantUnits["humidity"],
+			Pressure:      wantUnits["pressure"],
+			WindDirection: wantUnits["winddir"],
+			Precipitation: wantUnits["precip"],
+		}
+		if fcast.Units != wantFCastUnits {
+			t.Errorf("expected forecast units to be %+v, got %+v", wantFCastUnits, fcast.Units)
+		}
+	})
+	t.Run("model and elevation are mapped to the request parameters", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			model        string
+			setElevation bool
+			elevation    float64
+			want         map[string]string
+		}{
+			{"unset", "", false, 0, map[string]string{"models": "", "elevation": ""}},
+			{"model", "icon_seamless", false, 0, map[string]string{"models": "icon_seamless", "elevation": ""}},
+			{"sea level", "", true, 0, map[string]string{"models": "", "elevation": "0"}},
+			{"mountain", "gfs_seamless", true, 2962.5, map[string]string{"models": "gfs_seamless", "elevation": "2962.5"}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				client := testClient(t, "metric", true)
+				client.SetModel(tc.model)
+				if tc.setElevation {
+					client.SetElevation(tc.elevation)
+				}
+				var query url.Values
+				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					query = req.URL.Query()
+					data, err := os.Open(testDataMetric)
+					if err != nil {
+						t.Fatalf("failed to open JSON response file: %s", err)
+					}
+					return &stdhttp.Response{StatusCode: 200, Body: data, Header: make(stdhttp.Header)}, nil
+				}
+				client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+				if _, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon}); err != nil {
+					t.Fatalf("weather lookup failed: %s", err)
+				}
+				for param, want := range tc.want {
+					if got := query.Get(param); got != want {
+						t.Errorf("expected query parameter %s to be %q, got %q", param, want, got)
+					}
+				}
+				if tc.model != "" && !strings.Contains(client.Attribution(), tc.model) {
+					t.Errorf("expected attribution to contain the model, got %q", client.Attribution())
+				}
+			})
+		}
+	})
+	t.Run("nowcast is only requested if enabled", func(t *testing.T) {
+		for _, enabled := range []bool{false, true} {
+			t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+				client := testClient(t, "metric", true)
+				client.SetNowcast(enabled)
+				var query url.Values
+				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					query = req.URL.Query()
+					data, err := os.Open(testDataMetric)
+					if err != nil {
+						t.Fatalf("failed to open JSON response file: %s", err)
+					}
+					return &stdhttp.Response{StatusCode: 200, Body: data, Header: make(stdhttp.Header)}, nil
+				}
+				client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+				if _, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon}); err != nil {
+					t.Fatalf("weather lookup failed: %s", err)
+				}
+				for _, param := range []string{"minutely_15", "past_minutely_15", "forecast_minutely_15"} {
+					if query.Has(param) != enabled {
+						t.Errorf("expected query parameter %s to be present: %t, got %t", param, enabled, query.Has(param))
+					}
+				}
+				if enabled && query.Get("minutely_15") != "precipitation" {
+					t.Errorf("expected query parameter minutely_15 to be %q, got %q", "precipitation",
+						query.Get("minutely_15"))
+				}
+			})
+		}
+	})
+	t.Run("nowcast is mapped to the weather data", func(t *testing.T) {
+		client := testClient(t, "metric", true)
+		client.SetNowcast(true)
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"Europe/Bucharest","current":{"time":"2026-01-16T22:00"},
+				"minutely_15_units":{"time":"iso8601","precipitation":"mm"},
+				"minutely_15":{"time":["2026-01-16T21:45",null,"2026-01-16T22:00","2026-01-16T22:15"],
+				"precipitation":[0.0,0.7,0.3]}}`)
+			return &stdhttp.Response{StatusCode: 200, Body: io.NopCloser(data), Header: make(stdhttp.Header)}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		loc, err := time.LoadLocation("Europe/Bucharest")
+		if err != nil {
+			t.Fatalf("failed to load time zone: %s", err)
+		}
+		want := []weather.NowcastStep{
+			{Time: time.Date(2026, 1, 16, 21, 45, 0, 0, loc), Precipitation: 0, Unit: "mm"},
+			{Time: time.Date(2026, 1, 16, 22, 0, 0, 0, loc), Precipitation: 0.3, Unit: "mm"},
+			{Time: time.Date(2026, 1, 16, 22, 15, 0, 0, loc), Precipitation: 0, Unit: "mm"},
+		}
+		if len(data.Nowcast) != len(want) {
+			t.Fatalf("expected %d nowcast steps, got %d", len(want), len(data.Nowcast))
+		}
+		for i, step := range data.Nowcast {
+			if !step.Time.Equal(want[i].Time) || step.Precipitation != want[i].Precipitation ||
+				step.Unit != want[i].Unit {
+				t.Errorf("expected nowcast step %d to be %+v, got %+v", i, want[i], step)
+			}
+		}
+	})
+	t.Run("unit overrides are mapped to the request parameters", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			unit  string
+			units *weather.UnitPreferences
+			want  map[string]string
+		}{
+			{
+				"metric", "metric", nil,
+				map[string]string{"temperature_unit": "", "wind_speed_unit": "", "precipitation_unit": ""},
+			},
+			{
+				"imperial", "imperial", nil,
+				map[string]string{"temperature_unit": "fahrenheit", "wind_speed_unit": "mph", "precipitation_unit": "inch"},
+			},
+			{
+				"celsius with wind in mph", "metric",
+				&weather.UnitPreferences{
+					Temperature: weather.UnitCelsius, WindSpeed: weather.UnitMph,
+					Pressure: weather.UnitInHg, Precipitation: weather.UnitMillimeter,
+				},
+				map[string]string{"temperature_unit": "", "wind_speed_unit": "mph", "precipitation_unit": ""},
+			},
+			{
+				"fahrenheit with wind in knots", "metric",
+				&weather.UnitPreferences{Temperature: weather.UnitFahrenheit, WindSpeed: weather.UnitKnots},
+				map[string]string{"temperature_unit": "fahrenheit", "wind_speed_unit": "kn", "precipitation_unit": ""},
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				client := testClient(t, tc.unit, true)
+				if tc.units != nil {
+					client.SetUnits(*tc.units)
+				}
+				var query url.Values
+				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					query = req.URL.Query()
+					data, err := os.Open(testDataImperial)
+					if err != nil {
+						t.Fatalf("failed to open JSON response file: %s", err)
+					}
+					return &stdhttp.Response{StatusCode: 200, Body: data, Header: make(stdhttp.Header)}, nil
+				}
+				client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+				if _, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon}); err != nil {
+					t.Fatalf("weather lookup failed: %s", err)
+				}
+				for param, want := range tc.want {
+					if got := query.Get(param); got != want {
+						t.Errorf("expected query parameter %q to be %q, got %q", param, want, got)
+					}
+				}
+			})
+		}
 	})
 	t.Run("weather lookup with imperial unit succeeds", func(t *testing.T) {
 		unit := "imperial"
@@ -244,6 +464,7 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			WindDirection:       81,
 			RelativeHumidity:    72,
 			PressureMSL:         1034.7,
+			DewPoint:            14.8,
 		}
 		if data.Current.Temperature != wantCurrent.Temperature {
 			t.Errorf("expected current temperature to be %f, got %f", wantCurrent.Temperature,
@@ -277,12 +498,16 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			t.Errorf("expected current pressure MSL to be %f, got %f", wantCurrent.PressureMSL,
 				data.Current.PressureMSL)
 		}
+		if data.Current.DewPoint != wantCurrent.DewPoint {
+			t.Errorf("expected current dew point to be %f, got %f", wantCurrent.DewPoint, data.Current.DewPoint)
+		}
 		wantUnits := map[string]string{
 			"temperature": "°F",
 			"pressure":    "hPa",
 			"windspeed":   "mp/h",
 			"humidity":    "%",
 			"winddir":     "°",
+			"precip":      "inch",
 		}
 		if data.Current.Units.Temperature != wantUnits["temperature"] {
 			t.Errorf("expected current temperature units to be %q, got %q", wantUnits["temperature"],
@@ -304,28 +529,92 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 			t.Errorf("expected current wind direction units to be %q, got %q", wantUnits["winddir"],
 				data.Current.Units.WindDirection)
 		}
+		if data.Current.Units.Precipitation != wantUnits["precip"] {
+			t.Errorf("expected current precipitation units to be %q, got %q", wantUnits["precip"],
+				data.Current.Units.Precipitation)
+		}
+		wantFCastUnits := weather.Units{
+			Temperature:   wantUnits["temperature"],
+			WindSpeed:     wantUnits["windspeed"],
+			Humidity:      wantUnits["humidity"],
+			Pressure:      wantUnits["pressure"],
+			WindDirection: wantUnits["winddir"],
+			Precipitation: wantUnits["precip"],
+		}
+		for dayHour, fcast := range data.Forecast {
+			if fcast.Units != wantFCastUnits {
+				t.Fatalf("expected forecast units at %d to be %+v, got %+v", dayHour, wantFCastUnits, fcast.Units)
+			}
+		}
+	})
+	t.Run("missing unit strings fall back to the requested units", func(t *testing.T) {
+		tests := []struct {
+			unit string
+			want weather.Units
+		}{
+			{"metric", weather.Units{
+				Temperature: "°C", WindSpeed: "km/h", Humidity: "%", Pressure: "hPa", WindDirection: "°",
+				Precipitation: "mm",
+			}},
+			{"imperial", weather.Units{
+				Temperature: "°F", WindSpeed: "mph", Humidity: "%", Pressure: "hPa", WindDirection: "°",
+				Precipitation: "inch",
+			}},
+		}
+		for _, tc := range tests {
+			t.Run(tc.unit, func(t *testing.T) {
+				client := testClient(t, tc.unit, true)
+				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					data := bytes.NewBufferString(`{"timezone":"UTC","current_units":{"temperature_2m":"K"},
+						"current":{"time":"2026-01-16T22:00","temperature_2m":-5.3},
+						"hourly":{"time":["2026-01-16T22:00"],"temperature_2m":[-5.3]}}`)
+					return &stdhttp.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(data),
+						Header:     make(stdhttp.Header),
+					}, nil
+				}
+				client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+				data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+				if err != nil {
+					t.Fatalf("weather lookup failed: %s", err)
+				}
+				wantCurrent := tc.want
+				wantCurrent.Temperature = "K"
+				if data.Current.Units != wantCurrent {
+					t.Errorf("expected current units to be %+v, got %+v", wantCurrent, data.Current.Units)
+				}
+				fcast, ok := data.InstantAt(time.Date(2026, 1, 16, 22, 0, 0, 0, time.UTC))
+				if !ok {
+					t.Fatal("expected forecast for the current hour to be set")
+				}
+				if fcast.Units != tc.want {
+					t.Errorf("expected forecast units to be %+v, got %+v", tc.want, fcast.Units)
+				}
+			})
+		}
 	})
 	t.Run("weather lookup with different timezones succeeds", func(t *testing.T) {
+		bucharest, err := time.LoadLocation("Europe/Bucharest")
+		if err != nil {
+			t.Fatalf("failed to load time zone: %s", err)
+		}
+		want := time.Date(2026, 1, 16, 22, 15, 0, 0, bucharest)
 		tests := []struct {
 			name   string
 			tz     string
 			offset int
-			want   time.Time
 		}{
-			{"UTC", "UTC", 0, time.Date(2026, 1, 16, 22, 15, 0, 0, time.UTC)},
-			{"Local", "Local", 0, time.Date(2026, 1, 16, 22, 15, 0, 0, time.Local)},
-			{
-				"Europe/Berlin",
-				"Europe/Berlin",
-				3600,
-				time.Date(2026, 1, 16, 22, 15, 0, 0,
-					time.FixedZone("Europe/Berlin", 3600)),
-			},
+			{"UTC", "UTC", 0},
+			{"Local", "Local", 0},
+			{"Europe/Berlin", "Europe/Berlin", 3600},
+			{"+10h", "Australia/Brisbane", 36000},
 		}
 
 		for _, tc := range tests {
 			t.Run(tc.name, func(t *testing.T) {
-				time.Local = time.FixedZone(tc.tz, tc.offset)
+				setLocal(t, time.FixedZone(tc.tz, tc.offset))
 				client := testClient(t, "", false)
 				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
 					data, err := os.Open(testDataImperial)
@@ -347,16 +636,372 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 				if data.GeneratedAt.IsZero() {
 					t.Error("expected generated at to be set")
 				}
-				if data.Current.InstantTime.Location().String() != tc.tz {
-					t.Errorf("expected current time to be in %q, got %q", tc.tz,
+				if data.Current.InstantTime.Location().String() != bucharest.String() {
+					t.Errorf("expected current time to be in %q, got %q", bucharest,
 						data.Current.InstantTime.Location().String())
 				}
-				if !data.Current.InstantTime.Equal(tc.want) {
-					t.Errorf("expected current time to be %s, got %s", tc.want, data.Current.InstantTime)
+				if !data.Current.InstantTime.Equal(want) {
+					t.Errorf("expected current time to be %s, got %s", want, data.Current.InstantTime)
+				}
+				if _, ok := data.InstantAt(want); !ok {
+					t.Errorf("expected forecast for the current hour to be found")
 				}
 			})
 		}
 	})
+	t.Run("weather lookup with partial hourly arrays succeeds", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		log := logger.NewLogger(slog.LevelDebug, buf, nil)
+		client, err := New(http.New(log), log, "metric")
+		if err != nil {
+			t.Fatalf("failed to create open-meteo client: %s", err)
+		}
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"UTC","current":{"time":"2026-01-16T22:00"},"hourly":{
+				"time":["2026-01-16T20:00","2026-01-16T21:00","2026-01-16T22:00"],
+				"temperature_2m":[-3.1,-4.2,-5.3],
+				"apparent_temperature":[-7.0,-8.1,-9.2],
+				"weather_code":[0,1,2],
+				"wind_speed_10m":[4.5,4.6,4.7],
+				"wind_gusts_10m":[12.0,12.1,12.2],
+				"is_day":[0,0,0],
+				"wind_direction_10m":[79,80,81],
+				"relative_humidity_2m":[70,71,72],
+				"pressure_msl":[1034.5],
+				"dew_point_2m":[-6.9,-8.0,-9.6]}}`)
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(data),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if len(data.Forecast) != 3 {
+			t.Fatalf("expected 3 forecast entries, got %d", len(data.Forecast))
+		}
+		first, ok := data.InstantAt(time.Date(2026, 1, 16, 20, 0, 0, 0, time.UTC))
+		if !ok {
+			t.Fatal("expected forecast for first hour to be set")
+		}
+		if first.PressureMSL != 1034.5 {
+			t.Errorf("expected pressure of first hour to be %f, got %f", 1034.5, first.PressureMSL)
+		}
+		last, ok := data.InstantAt(time.Date(2026, 1, 16, 22, 0, 0, 0, time.UTC))
+		if !ok {
+			t.Fatal("expected forecast for last hour to be set")
+		}
+		if last.PressureMSL != 0 {
+			t.Errorf("expected pressure of last hour to be empty, got %f", last.PressureMSL)
+		}
+		if last.Temperature != -5.3 {
+			t.Errorf("expected temperature of last hour to be %f, got %f", -5.3, last.Temperature)
+		}
+		if last.WeatherCode != 2 {
+			t.Errorf("expected weather code of last hour to be %d, got %d", 2, last.WeatherCode)
+		}
+		wantLog := `truncated_fields=pressure_msl`
+		if !strings.Contains(buf.String(), wantLog) {
+			t.Errorf("expected log to contain %q, got %q", wantLog, buf.String())
+		}
+	})
+	t.Run("weather lookup with null times and boolean literals succeeds", func(t *testing.T) {
+		client := testClient(t, "metric", false)
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"UTC","current":{"time":"2026-01-16T12:00","is_day":true},
+				"hourly":{
+				"time":["2026-01-16T12:00",null,"2026-01-16T14:00"],
+				"temperature_2m":[-3.1,-4.2,-5.3],
+				"is_day":[true,null,false]}}`)
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(data),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if !data.Current.IsDay {
+			t.Error("expected current weather to be at day")
+		}
+		if len(data.Forecast) != 2 {
+			t.Fatalf("expected 2 forecast entries, got %d", len(data.Forecast))
+		}
+		last, ok := data.InstantAt(time.Date(2026, 1, 16, 14, 0, 0, 0, time.UTC))
+		if !ok {
+			t.Fatal("expected forecast for last hour to be set")
+		}
+		if last.IsDay || last.Temperature != -5.3 {
+			t.Errorf("expected last hour to be at night with %f, got %t with %f", -5.3, last.IsDay, last.Temperature)
+		}
+	})
+	t.Run("forecast lookups across a DST transition succeed", func(t *testing.T) {
+		setLocal(t, time.UTC)
+		client := testClient(t, "", true)
+		client.http.Transport = testhelper.MockRoundTripper{Fn: func(*stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"Europe/Berlin","utc_offset_seconds":7200,
+				"current":{"time":"2026-03-29T03:15","temperature_2m":4.0},"hourly":{
+				"time":["2026-03-29T00:00","2026-03-29T01:00","2026-03-29T03:00","2026-03-29T04:00"],
+				"temperature_2m":[1.0,2.0,3.0,4.0]}}`)
+			return &stdhttp.Response{StatusCode: 200, Body: io.NopCloser(data), Header: make(stdhttp.Header)}, nil
+		}}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if len(data.Forecast) != 4 {
+			t.Fatalf("expected 4 forecast entries, got %d", len(data.Forecast))
+		}
+		// 01:00 CET and 03:00 CEST are consecutive hours
+		for hour, want := range map[int]float64{23: 1.0, 0: 2.0, 1: 3.0, 2: 4.0} {
+			at := time.Date(2026, 3, 29, hour, 30, 0, 0, time.UTC)
+			if hour == 23 {
+				at = at.AddDate(0, 0, -1)
+			}
+			instant, ok := data.InstantAt(at)
+			if !ok {
+				t.Errorf("expected forecast for %s to be found", at)
+				continue
+			}
+			if instant.Temperature != want {
+				t.Errorf("expected temperature at %s to be %f, got %f", at, want, instant.Temperature)
+			}
+		}
+		next, ok := data.InstantAt(data.Current.InstantTime.Add(time.Hour))
+		if !ok {
+			t.Fatal("expected forecast for the next hour to be found")
+		}
+		if next.Temperature != 4.0 {
+			t.Errorf("expected temperature of the next hour to be %f, got %f", 4.0, next.Temperature)
+		}
+	})
+	t.Run("forecast lookups with a +10h time zone offset succeed", func(t *testing.T) {
+		setLocal(t, time.FixedZone("CET", 3600))
+		client := testClient(t, "", true)
+		client.http.Transport = testhelper.MockRoundTripper{Fn: func(*stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"Australia/Brisbane","utc_offset_seconds":36000,
+				"current":{"time":"2026-01-17T08:15","temperature_2m":24.0},"hourly":{
+				"time":["2026-01-17T08:00","2026-01-17T09:00","2026-01-17T10:00"],
+				"temperature_2m":[24.0,25.0,26.0]}}`)
+			return &stdhttp.Response{StatusCode: 200, Body: io.NopCloser(data), Header: make(stdhttp.Header)}, nil
+		}}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		want := time.Date(2026, 1, 16, 22, 15, 0, 0, time.UTC)
+		if !data.Current.InstantTime.Equal(want) {
+			t.Errorf("expected current time to be %s, got %s", want, data.Current.InstantTime)
+		}
+		for offset, temp := range []float64{24.0, 25.0, 26.0} {
+			instant, ok := data.InstantAt(want.Add(time.Hour * time.Duration(offset)))
+			if !ok {
+				t.Errorf("expected forecast at offset %d to be found", offset)
+				continue
+			}
+			if instant.Temperature != temp {
+				t.Errorf("expected temperature at offset %d to be %f, got %f", offset, temp, instant.Temperature)
+			}
+			if instant.InstantTime.Location().String() != "Australia/Brisbane" {
+				t.Errorf("expected forecast time to be in %q, got %q", "Australia/Brisbane",
+					instant.InstantTime.Location())
+			}
+		}
+	})
+	t.Run("forecast of a +09:00 location is independent of the system time zone", func(t *testing.T) {
+		tests := []struct {
+			name string
+			tz   string
+		}{
+			{"UTC", "UTC"},
+			// The forecast covers the DST gap of the system time zone on 2026-03-29
+			{"Europe/Berlin", "Europe/Berlin"},
+			{"America/New_York", "America/New_York"},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				loc, err := time.LoadLocation(tc.tz)
+				if err != nil {
+					t.Fatalf("failed to load time zone: %s", err)
+				}
+				setLocal(t, loc)
+				client := testClient(t, "", true)
+				client.http.Transport = testhelper.MockRoundTripper{Fn: func(*stdhttp.Request) (*stdhttp.Response, error) {
+					data, err := os.Open(testDataTokyo)
+					if err != nil {
+						t.Fatalf("failed to open JSON response file: %s", err)
+					}
+					return &stdhttp.Response{StatusCode: 200, Body: data, Header: make(stdhttp.Header)}, nil
+				}}
+
+				data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: 35.6895, Lon: 139.6917})
+				if err != nil {
+					t.Fatalf("weather lookup failed: %s", err)
+				}
+				want := time.Date(2026, 3, 29, 2, 15, 0, 0, time.UTC)
+				if !data.Current.InstantTime.Equal(want) {
+					t.Errorf("expected current time to be %s, got %s", want, data.Current.InstantTime)
+				}
+				if len(data.Forecast) != 24 {
+					t.Fatalf("expected 24 forecast entries, got %d", len(data.Forecast))
+				}
+				for at, temp := range map[time.Time]float64{
+					time.Date(2026, 3, 28, 17, 30, 0, 0, time.UTC): 2.2,
+					time.Date(2026, 3, 29, 2, 30, 0, 0, time.UTC):  11.0,
+				} {
+					instant, ok := data.InstantAt(at)
+					if !ok {
+						t.Errorf("expected forecast for %s to be found", at)
+						continue
+					}
+					if instant.Temperature != temp {
+						t.Errorf("expected temperature at %s to be %f, got %f", at, temp, instant.Temperature)
+					}
+					if !instant.InstantTime.Equal(at.Truncate(time.Hour)) {
+						t.Errorf("expected forecast time to be %s, got %s", at.Truncate(time.Hour),
+							instant.InstantTime)
+					}
+				}
+			})
+		}
+	})
+	t.Run("UTC offset of the response is preferred over a disagreeing time zone database", func(t *testing.T) {
+		setLocal(t, time.UTC)
+		client := testClient(t, "", true)
+		client.http.Transport = testhelper.MockRoundTripper{Fn: func(*stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"Asia/Tokyo","timezone_abbreviation":"GMT+10",
+				"utc_offset_seconds":36000,"current":{"time":"2026-01-17T08:15"}}`)
+			return &stdhttp.Response{StatusCode: 200, Body: io.NopCloser(data), Header: make(stdhttp.Header)}, nil
+		}}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		want := time.Date(2026, 1, 16, 22, 15, 0, 0, time.UTC)
+		if !data.Current.InstantTime.Equal(want) {
+			t.Errorf("expected current time to be %s, got %s", want, data.Current.InstantTime)
+		}
+		if name := data.Current.InstantTime.Location().String(); name != "GMT+10" {
+			t.Errorf("expected current time to be in %q, got %q", "GMT+10", name)
+		}
+	})
+	t.Run("unknown time zones fall back to the UTC offset", func(t *testing.T) {
+		setLocal(t, time.UTC)
+		client := testClient(t, "", true)
+		client.http.Transport = testhelper.MockRoundTripper{Fn: func(*stdhttp.Request) (*stdhttp.Response, error) {
+			data := bytes.NewBufferString(`{"timezone":"Invalid/Zone","timezone_abbreviation":"GMT+10",
+				"utc_offset_seconds":36000,"current":{"time":"2026-01-17T08:15"}}`)
+			return &stdhttp.Response{StatusCode: 200, Body: io.NopCloser(data), Header: make(stdhttp.Header)}, nil
+		}}
+
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		want := time.Date(2026, 1, 16, 22, 15, 0, 0, time.UTC)
+		if !data.Current.InstantTime.Equal(want) {
+			t.Errorf("expected current time to be %s, got %s", want, data.Current.InstantTime)
+		}
+	})
+	t.Run("weather lookup with an error payload fails", func(t *testing.T) {
+		for _, code := range []int{200, 400} {
+			t.Run(strconv.Itoa(code), func(t *testing.T) {
+				client := testClient(t, "", false)
+				fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+					data, err := os.Open(testDataError)
+					if err != nil {
+						t.Fatalf("failed to open JSON response file: %s", err)
+					}
+
+					return &stdhttp.Response{
+						StatusCode: code,
+						Body:       data,
+						Header:     make(stdhttp.Header),
+					}, nil
+				}
+				client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+				_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+				if err == nil {
+					t.Fatal("expected error to be returned")
+				}
+				wantErr := `Open-Meteo API returned an error: Latitude must be in range of -90 to 90°. Given: 144.4375.`
+				if !strings.Contains(err.Error(), wantErr) {
+					t.Errorf("expected error to contain %q, got %q", wantErr, err)
+				}
+				if !errors.Is(err, providererr.ErrBadResponse) {
+					t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+				}
+			})
+		}
+	})
+	t.Run("weather lookup without current weather data fails", func(t *testing.T) {
+		client := testClient(t, "", false)
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, ErrNoWeatherData) {
+			t.Errorf("expected error to be %s, got %s", ErrNoWeatherData, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+	t.Run("weather lookup with an empty body fails", func(t *testing.T) {
+		client := testClient(t, "", false)
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewBufferString(``)),
+				Header:     make(stdhttp.Header),
+			}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected error to be returned")
+		}
+	})
+	t.Run("weather lookup with an unexpected content type fails", func(t *testing.T) {
+		client := testClient(t, "", false)
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			header := make(stdhttp.Header)
+			header.Set("Content-Type", "text/html; charset=utf-8")
+			return &stdhttp.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewBufferString(`<html><body>Bad Gateway</body></html>`)),
+				Header:     header,
+			}, nil
+		}
+		client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, http.ErrUnexpectedContentType) {
+			t.Errorf("expected error to be %s, got %s", http.ErrUnexpectedContentType, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
 	t.Run("http request fails with a 401", func(t *testing.T) {
 		client := testClient(t, "", false)
 		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
@@ -373,17 +1018,21 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 		if err == nil {
 			t.Error("expected error to be returned")
 		}
-		wantErr := `Open-Meteo API returned non-positive response code: 401`
+		wantErr := `unsuccessful response status 401 Unauthorized: {"status": 401, "message": "Unauthorized"}`
 		if !strings.Contains(err.Error(), wantErr) {
 			t.Errorf("expected error to contain %q, got %q", wantErr, err)
 		}
+		// Open-Meteo requests carry no credentials, so that they can't be rejected
+		if !errors.Is(err, providererr.ErrRateLimited) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrRateLimited, err)
+		}
 	})
 	t.Run("http request fails unmarshalling the JSON", func(t *testing.T) {
 		client := testClient(t, "", false)
 		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
 			data := bytes.NewBufferString(`invalid`)
 			return &stdhttp.Response{
-				StatusCode: 401,
+				StatusCode: 200,
 				Body:       io.NopCloser(data),
 				Header:     make(stdhttp.Header),
 			}, nil
@@ -398,10 +1047,62 @@ func TestOpenMeteo_GetWeather(t *testing.T) {
 		if !strings.Contains(err.Error(), wantErr) {
 			t.Errorf("expected error to contain %q, got %q", wantErr, err)
 		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
 	})
 }
 
+func TestOpenMeteo_RetainRawResponse(t *testing.T) {
+	body, err := os.ReadFile(testDataMetric)
+	if err != nil {
+		t.Fatalf("failed to read JSON response file: %s", err)
+	}
+	fn := func(*stdhttp.Request) (*stdhttp.Response, error) {
+		return &stdhttp.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Header:     make(stdhttp.Header),
+		}, nil
+	}
+	tests := []struct {
+		name          string
+		limit         int
+		wantRaw       []byte
+		wantTruncated bool
+	}{
+		{"raw response is not retained by default", 0, nil, false},
+		{"raw response is retained", len(body) * 2, bytes.TrimSpace(body), false},
+		{"raw response is truncated at the limit", 100, body[:100], true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testClient(t, "", true)
+			client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+			client.RetainRawResponse(tc.limit)
+
+			data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+			if err != nil {
+				t.Fatalf("weather lookup failed: %s", err)
+			}
+			if data.Current.Temperature != -5.3 {
+				t.Errorf("expected temperature to be %f, got %f", -5.3, data.Current.Temperature)
+			}
+			raw, truncated := client.LastRawResponse()
+			if !bytes.Equal(raw, tc.wantRaw) {
+				t.Errorf("expected raw response of %d bytes, got %d bytes", len(tc.wantRaw), len(raw))
+			}
+			if truncated != tc.wantTruncated {
+				t.Errorf("expected truncated to be %t, got %t", tc.wantTruncated, truncated)
+			}
+		})
+	}
+}
+
 func TestResBool_UnmarshalJSON(t *testing.T) {
+	type data struct {
+		Value resBool `json:"value"`
+	}
 	t.Run("true/false are correctly unmarshalled", func(t *testing.T) {
 		tests := []struct {
 			name string
@@ -410,13 +1111,17 @@ func TestResBool_UnmarshalJSON(t *testing.T) {
 		}{
 			{"true", []byte(`{"value":1}`), true},
 			{"false", []byte(`{"value":0}`), false},
+			{"true literal", []byte(`{"value":true}`), true},
+			{"false literal", []byte(`{"value":false}`), false},
+			{"quoted 1", []byte(`{"value":"1"}`), true},
+			{"quoted 0", []byte(`{"value":"0"}`), false},
+			{"quoted true", []byte(`{"value":"true"}`), true},
+			{"quoted false", []byte(`{"value":"false"}`), false},
+			{"null", []byte(`{"value":null}`), false},
 		}
 
 		for _, tc := range tests {
 			t.Run(tc.name, func(t *testing.T) {
-				type data struct {
-					Value resBool `json:"value"`
-				}
 				var output data
 				if err := json.Unmarshal(tc.json, &output); err != nil {
 					t.Fatalf("failed to unmarshal JSON: %s", err)
@@ -427,6 +1132,55 @@ func TestResBool_UnmarshalJSON(t *testing.T) {
 			})
 		}
 	})
+	t.Run("mixed values in an array are unmarshalled", func(t *testing.T) {
+		var values struct {
+			Value []resBool `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(`{"value":[1, false, "true", 0]}`), &values); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		got := make([]bool, 0, len(values.Value))
+		for _, value := range values.Value {
+			got = append(got, value.bool)
+		}
+		if want := []bool{true, false, true, false}; !slices.Equal(got, want) {
+			t.Errorf("expected values to be %v, got %v", want, got)
+		}
+	})
+	t.Run("values are marked as set unless null", func(t *testing.T) {
+		var values struct {
+			Value []resBool `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(`{"value":[1, null, 0]}`), &values); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		got := make([]bool, 0, len(values.Value))
+		for _, value := range values.Value {
+			got = append(got, value.set)
+		}
+		if want := []bool{true, false, true}; !slices.Equal(got, want) {
+			t.Errorf("expected values to be set %v, got %v", want, got)
+		}
+	})
+	t.Run("null keeps the previous value", func(t *testing.T) {
+		output := data{Value: resBool{bool: true}}
+		if err := json.Unmarshal([]byte(`{"value":null}`), &output); err != nil {
+			t.Fatalf("failed to unmarshal JSON: %s", err)
+		}
+		if !output.Value.bool {
+			t.Error("expected value to be kept")
+		}
+	})
+	t.Run("invalid values fail", func(t *testing.T) {
+		for _, value := range []string{`2`, `-1`, `0.5`, `"yes"`, `"TRUE"`, `""`, `"01"`, `{}`, `[]`, `10`} {
+			t.Run(value, func(t *testing.T) {
+				var output data
+				if err := json.Unmarshal([]byte(`{"value":`+value+`}`), &output); err == nil {
+					t.Errorf("expected unmarshalling %s to fail", value)
+				}
+			})
+		}
+	})
 }
 
 func TestResTime_UnmarshalJSON(t *testing.T) {
@@ -450,26 +1204,37 @@ func TestResTime_UnmarshalJSON(t *testing.T) {
 				true,
 			},
 			{
-				"nil",
+				"null results in the zero time",
 				[]byte(`{"value":null}`),
 				time.Time{},
+				false,
+			},
+			{
+				"number fails",
+				[]byte(`{"value":1136214240}`),
+				time.Time{},
 				true,
 			},
 		}
 
 		for _, tc := range tests {
 			t.Run(tc.name, func(t *testing.T) {
-				time.Local = time.UTC
+				// The wall clock is kept regardless of the system time zone
+				setLocal(t, time.FixedZone("CET", 3600))
 				type data struct {
 					Value resTime `json:"value"`
 				}
 				var output data
-				if err := json.Unmarshal(tc.json, &output); err != nil && !tc.fails {
-					t.Fatalf("failed to unmarshal JSON: %s", err)
-				}
+				err := json.Unmarshal(tc.json, &output)
 				if tc.fails {
+					if err == nil {
+						t.Error("expected unmarshalling to fail")
+					}
 					return
 				}
+				if err != nil {
+					t.Fatalf("failed to unmarshal JSON: %s", err)
+				}
 				if !output.Value.Equal(tc.want) {
 					t.Errorf("expected value to be %s, got %s", tc.want, output.Value.Time)
 				}
@@ -478,6 +1243,14 @@ func TestResTime_UnmarshalJSON(t *testing.T) {
 	})
 }
 
+// setLocal replaces the local time zone for the duration of the test.
+func setLocal(t *testing.T, loc *time.Location) {
+	t.Helper()
+	orig := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = orig })
+}
+
 func testClient(t *testing.T, unit string, nilLogger bool) *OpenMeteo {
 	var output io.Writer = os.Stdout
 	if nilLogger {
@@ -0,0 +1,364 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package pirateweather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	name        = "pirateweather"
+	attribution = "Powered by Pirate Weather"
+	apiEndpoint = "https://api.pirateweather.net/forecast"
+	apiTimeout  = time.Second * 10
+	mmPerInch   = 25.4
+)
+
+var (
+	// ErrNoWeatherData is returned if the API response does not contain any current weather data
+	ErrNoWeatherData = providererr.Wrap(providererr.ErrBadResponse,
+		errors.New("Pirate Weather API returned no current weather data"))
+	// ErrInvalidAPIKey is returned if the API rejects the configured API key
+	ErrInvalidAPIKey = providererr.Wrap(providererr.ErrAuth, errors.New("Pirate Weather API rejected the "+
+		"API key, please check the apikey setting in the weather section of your configuration"))
+	// ErrRateLimited is returned if the API key exceeded the request quota of its plan
+	ErrRateLimited = providererr.Wrap(providererr.ErrRateLimited, errors.New("Pirate Weather API request "+
+		"quota exceeded, please reduce the weather update interval or upgrade your Pirate Weather plan"))
+)
+
+type PirateWeather struct {
+	apikey    string
+	unit      string
+	unitsLock sync.RWMutex
+	units     weather.UnitPreferences
+	log       *logger.Logger
+	http      *http.Client
+}
+
+type response struct {
+	Message   string    `json:"message"`
+	Error     string    `json:"error"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Timezone  string    `json:"timezone"`
+	Elevation float64   `json:"elevation"`
+	Offset    float64   `json:"offset"`
+	Currently dataPoint `json:"currently"`
+	Hourly    struct {
+		Data []dataPoint `json:"data"`
+	} `json:"hourly"`
+	Daily struct {
+		Data []struct {
+			Time        int64 `json:"time"`
+			SunriseTime int64 `json:"sunriseTime"`
+			SunsetTime  int64 `json:"sunsetTime"`
+		} `json:"data"`
+	} `json:"daily"`
+	Flags struct {
+		Units string `json:"units"`
+	} `json:"flags"`
+}
+
+// redactedError wraps an error whose message contains the API key, which is part of the request URL, and
+// removes the API key from the message.
+type redactedError struct {
+	err    error
+	secret string
+}
+
+type dataPoint struct {
+	Time                int64   `json:"time"`
+	Summary             string  `json:"summary"`
+	Icon                string  `json:"icon"`
+	PrecipIntensity     float64 `json:"precipIntensity"`
+	Temperature         float64 `json:"temperature"`
+	ApparentTemperature float64 `json:"apparentTemperature"`
+	DewPoint            float64 `json:"dewPoint"`
+	Humidity            float64 `json:"humidity"`
+	Pressure            float64 `json:"pressure"`
+	WindSpeed           float64 `json:"windSpeed"`
+	WindGust            float64 `json:"windGust"`
+	WindBearing         float64 `json:"windBearing"`
+	CloudCover          float64 `json:"cloudCover"`
+}
+
+// New returns a new Pirate Weather provider. An API key is required to access the API.
+func New(http *http.Client, log *logger.Logger, unit, apikey string) (*PirateWeather, error) {
+	if http == nil {
+		return nil, fmt.Errorf("http client is required")
+	}
+	if log == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	if apikey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+
+	// The API key is part of the path, so it's redacted from the response bodies of unsuccessful requests
+	http = http.WithPathSecrets(apikey, url.PathEscape(apikey))
+	return &PirateWeather{apikey: apikey, unit: unit, units: weather.DefaultUnits(unit), http: http, log: log}, nil
+}
+
+// SetUnits overrides the units of the individual metrics. The API is queried in the "us" unit system
+// for Fahrenheit and in the "si" unit system otherwise. The wind speed is converted into the preferred
+// unit, while the pressure is left to the presenter to convert. It is safe to call while weather data is
+// requested.
+func (p *PirateWeather) SetUnits(units weather.UnitPreferences) {
+	p.unitsLock.Lock()
+	defer p.unitsLock.Unlock()
+	p.units = units
+}
+
+// preferredUnits returns the units that the metrics are requested in.
+func (p *PirateWeather) preferredUnits() weather.UnitPreferences {
+	p.unitsLock.RLock()
+	defer p.unitsLock.RUnlock()
+	return p.units
+}
+
+func (e *redactedError) Error() string {
+	return strings.ReplaceAll(e.err.Error(), e.secret, "***")
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func (p *PirateWeather) Name() string {
+	return name
+}
+
+func (p *PirateWeather) Attribution() string {
+	return attribution
+}
+
+func (p *PirateWeather) GetWeather(ctx context.Context, coords geobus.Coordinate) (*weather.Data, error) {
+	res := new(response)
+	data := weather.NewData()
+
+	preferred := p.preferredUnits()
+	query := url.Values{}
+	query.Set("units", "si")
+	if preferred.Temperature == weather.UnitFahrenheit {
+		query.Set("units", "us")
+	}
+	query.Set("exclude", "minutely,alerts")
+
+	// The API key is part of the path, so it must not end up in error messages
+	endpoint := fmt.Sprintf("%s/%s/%f,%f", apiEndpoint, url.PathEscape(p.apikey), coords.Lat, coords.Lon)
+	code, err := p.http.GetWithTimeout(ctx, endpoint, res, query, nil, apiTimeout)
+	switch code {
+	case 401, 403:
+		return data, ErrInvalidAPIKey
+	case 429:
+		return data, ErrRateLimited
+	}
+	// Error responses are decoded as well and explain the error better than the response body snippet
+	if reason := res.reason(); code != 200 && reason != "" {
+		return data, providererr.Wrap(providererr.Status(code, true), fmt.Errorf("Pirate Weather API returned "+
+			"non-positive response code: %d (%s)", code, reason))
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to retrieve weather data from Pirate Weather API: %w",
+			&redactedError{err: err, secret: p.apikey})
+	}
+	if res.Currently.Time == 0 {
+		return data, ErrNoWeatherData
+	}
+
+	loc := res.location()
+	units := res.units()
+	data.GeneratedAt = time.Now()
+	data.Coordinates = coords
+	data.SetTimezone(res.Timezone)
+	data.Elevation = res.Elevation
+	data.Current = p.instant(res, res.Currently, loc, units, preferred)
+	for _, point := range res.Hourly.Data {
+		instant := p.instant(res, point, loc, units, preferred)
+		data.Forecast[weather.NewDayHourIn(instant.InstantTime, loc)] = instant
+	}
+
+	return data, nil
+}
+
+// instant converts the data point into a weather.Instant. The wind speed is converted from the unit
+// system of the response into the preferred unit.
+func (p *PirateWeather) instant(res *response, point dataPoint, loc *time.Location,
+	units weather.Units, preferred weather.UnitPreferences,
+) weather.Instant {
+	intensity := point.PrecipIntensity
+	if strings.EqualFold(res.Flags.Units, "us") {
+		intensity *= mmPerInch
+	}
+	at := time.Unix(point.Time, 0).In(loc)
+
+	code, ok := wmoCode(point.Icon, point.Summary, intensity, point.CloudCover)
+	if !ok {
+		p.log.Debug("unknown Pirate Weather icon", slog.String("icon", point.Icon),
+			slog.String("summary", point.Summary))
+	}
+
+	instant := weather.Instant{
+		InstantTime:         at,
+		Temperature:         point.Temperature,
+		ApparentTemperature: point.ApparentTemperature,
+		WeatherCode:         code,
+		WindSpeed:           point.WindSpeed,
+		WindGusts:           point.WindGust,
+		WindDirection:       point.WindBearing,
+		RelativeHumidity:    point.Humidity * 100,
+		PressureMSL:         point.Pressure,
+		DewPoint:            point.DewPoint,
+		Units:               units,
+	}
+	instant.IsDay, instant.HasIsDay = res.isDay(at, point.Icon)
+	return instant.Convert(weather.UnitPreferences{WindSpeed: preferred.WindSpeed})
+}
+
+// location returns the time zone of the response. The IANA name of the time zone is preferred, since it
+// covers DST transitions within the forecast. If it is unknown to the system, the UTC offset is used.
+func (r *response) location() *time.Location {
+	if r.Timezone != "" {
+		if loc, err := time.LoadLocation(r.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone("", int(r.Offset*60*60))
+}
+
+// units returns the units of the metrics in the unit system reported by the response.
+func (r *response) units() weather.Units {
+	units := weather.Units{
+		Temperature:   "°C",
+		WindSpeed:     "m/s",
+		Humidity:      "%",
+		Pressure:      "hPa",
+		WindDirection: "°",
+	}
+	switch strings.ToLower(r.Flags.Units) {
+	case "us":
+		units.Temperature = "°F"
+		units.WindSpeed = "mph"
+	case "ca":
+		units.WindSpeed = "km/h"
+	case "uk", "uk2":
+		units.WindSpeed = "mph"
+	}
+	return units
+}
+
+// isDay reports whether the given time is between sunrise and sunset of its day. If the daily data does
+// not cover the time, the icon is used instead, which only distinguishes day and night for clear and
+// partly cloudy conditions. The second return value reports whether the daily data or the icon told
+// day and night apart.
+func (r *response) isDay(at time.Time, icon string) (bool, bool) {
+	for _, day := range r.Daily.Data {
+		if day.SunriseTime == 0 || day.SunsetTime == 0 {
+			continue
+		}
+		dayStart := time.Unix(day.Time, 0).In(at.Location())
+		if at.Before(dayStart) || !at.Before(dayStart.AddDate(0, 0, 1)) {
+			continue
+		}
+		return at.Unix() >= day.SunriseTime && at.Unix() < day.SunsetTime, true
+	}
+	switch {
+	case strings.HasSuffix(icon, "-day"):
+		return true, true
+	case strings.HasSuffix(icon, "-night"):
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// reason returns the error message of an API error response.
+func (r *response) reason() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	return r.Message
+}
+
+// wmoCode translates the icon and summary of a data point into a WMO weather code. The intensity of
+// precipitation in mm/h grades rain and snow, while the cloud cover is used for icons without a
+// matching WMO code. The second return value reports whether the icon is known.
+func wmoCode(icon, summary string, intensity, cloudCover float64) (int, bool) {
+	summary = strings.ToLower(summary)
+	switch icon {
+	case "clear-day", "clear-night":
+		return 0, true
+	case "partly-cloudy-day", "partly-cloudy-night":
+		return 2, true
+	case "cloudy":
+		return 3, true
+	case "fog":
+		return 45, true
+	case "wind":
+		return cloudCode(cloudCover), true
+	case "thunderstorm":
+		return 95, true
+	case "hail":
+		return 96, true
+	case "sleet":
+		return grade(intensity, 66, 66, 67), true
+	case "snow":
+		if strings.Contains(summary, "thunder") {
+			return 95, true
+		}
+		return grade(intensity, 71, 73, 75), true
+	case "rain":
+		switch {
+		case strings.Contains(summary, "thunder"):
+			return 95, true
+		case strings.Contains(summary, "freezing"):
+			return grade(intensity, 66, 66, 67), true
+		case strings.Contains(summary, "drizzle"):
+			return grade(intensity, 51, 53, 55), true
+		}
+		return grade(intensity, 61, 63, 65), true
+	default:
+		return cloudCode(cloudCover), false
+	}
+}
+
+// grade returns the light, moderate or heavy weather code based on the precipitation intensity in mm/h.
+func grade(intensity float64, light, moderate, heavy int) int {
+	switch {
+	case intensity < 2.5:
+		return light
+	case intensity < 7.6:
+		return moderate
+	default:
+		return heavy
+	}
+}
+
+// cloudCode returns the WMO weather code for the given cloud cover fraction.
+func cloudCode(cloudCover float64) int {
+	switch {
+	case cloudCover < 0.2:
+		return 0
+	case cloudCover < 0.5:
+		return 1
+	case cloudCover < 0.8:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,529 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package pirateweather
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	stdhttp "net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/testhelper"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	testLat      = 50.94
+	testLon      = 6.96
+	testAPIKey   = "s3cr3t"
+	testDataSI   = "../../../../testdata/pirateweather.json"
+	testDataUS   = "../../../../testdata/pirateweather-us.json"
+	testTimezone = "Europe/Berlin"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("creating a new provider succeeds", func(t *testing.T) {
+		client := testClient(t, "metric")
+		if client.http == nil {
+			t.Fatal("expected http client to be non-nil")
+		}
+		if client.log == nil {
+			t.Fatal("expected logger to be non-nil")
+		}
+		if client.apikey != testAPIKey {
+			t.Errorf("expected API key to be %q, got %q", testAPIKey, client.apikey)
+		}
+	})
+	t.Run("creating a provider without http client fails", func(t *testing.T) {
+		client, err := New(nil, logger.New(slog.LevelDebug), "metric", testAPIKey)
+		if err == nil {
+			t.Fatal("expected client to fail")
+		}
+		if client != nil {
+			t.Fatal("expected client to be nil")
+		}
+	})
+	t.Run("creating a provider without logger fails", func(t *testing.T) {
+		log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+		client, err := New(http.New(log), nil, "metric", testAPIKey)
+		if err == nil {
+			t.Fatal("expected client to fail")
+		}
+		if client != nil {
+			t.Fatal("expected client to be nil")
+		}
+	})
+	t.Run("creating a provider without API key fails", func(t *testing.T) {
+		log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+		client, err := New(http.New(log), log, "metric", "")
+		if err == nil {
+			t.Fatal("expected client to fail")
+		}
+		if client != nil {
+			t.Fatal("expected client to be nil")
+		}
+	})
+}
+
+func TestPirateWeather_Name(t *testing.T) {
+	client := testClient(t, "metric")
+	if client.Name() != "pirateweather" {
+		t.Errorf("expected provider name to be %q, got %q", "pirateweather", client.Name())
+	}
+}
+
+func TestPirateWeather_GetWeather(t *testing.T) {
+	berlin, err := time.LoadLocation(testTimezone)
+	if err != nil {
+		t.Fatalf("failed to load time zone: %s", err)
+	}
+
+	t.Run("weather lookup with SI units succeeds", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", fileResponse(t, 200, testDataSI))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if data.GeneratedAt.IsZero() {
+			t.Error("expected generated at to be set")
+		}
+		if data.Timezone != testTimezone {
+			t.Errorf("expected timezone to be %q, got %q", testTimezone, data.Timezone)
+		}
+
+		want := weather.Instant{
+			InstantTime:         time.Date(2025, 6, 14, 10, 42, 0, 0, berlin),
+			Temperature:         15,
+			ApparentTemperature: 13.9,
+			WeatherCode:         2,
+			WindSpeed:           12.96,
+			WindGusts:           21.96,
+			WindDirection:       225,
+			RelativeHumidity:    72,
+			PressureMSL:         1012.4,
+			DewPoint:            9.6,
+			IsDay:               true,
+			Units: weather.Units{
+				Temperature:   "°C",
+				WindSpeed:     "km/h",
+				Humidity:      "%",
+				Pressure:      "hPa",
+				WindDirection: "°",
+			},
+		}
+		assertInstant(t, data.Current, want)
+		if data.Current.InstantTime.Location().String() != testTimezone {
+			t.Errorf("expected current time to be in %q, got %q", testTimezone, data.Current.InstantTime.Location())
+		}
+
+		if len(data.Forecast) != 48 {
+			t.Errorf("expected %d forecast entries, got %d", 48, len(data.Forecast))
+		}
+		fcast, ok := data.InstantAt(time.Date(2025, 6, 14, 12, 0, 0, 0, berlin))
+		if !ok {
+			t.Fatal("expected forecast for 12:00 to be present")
+		}
+		want = weather.Instant{
+			InstantTime:         time.Date(2025, 6, 14, 12, 0, 0, 0, berlin),
+			Temperature:         16,
+			ApparentTemperature: 14.9,
+			WeatherCode:         61,
+			WindSpeed:           13.68,
+			WindGusts:           22.68,
+			WindDirection:       227,
+			RelativeHumidity:    72,
+			PressureMSL:         1012.4,
+			DewPoint:            10.6,
+			IsDay:               true,
+			Units:               want.Units,
+		}
+		assertInstant(t, fcast, want)
+	})
+	t.Run("weather lookup with US units succeeds", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "imperial", fileResponse(t, 200, testDataUS))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		want := weather.Instant{
+			InstantTime:         time.Date(2025, 6, 14, 10, 42, 0, 0, berlin),
+			Temperature:         59,
+			ApparentTemperature: 57.02,
+			WeatherCode:         2,
+			WindSpeed:           8.05,
+			WindGusts:           13.65,
+			WindDirection:       225,
+			RelativeHumidity:    72,
+			PressureMSL:         1012.4,
+			DewPoint:            49.28,
+			IsDay:               true,
+			Units: weather.Units{
+				Temperature:   "°F",
+				WindSpeed:     "mph",
+				Humidity:      "%",
+				Pressure:      "hPa",
+				WindDirection: "°",
+			},
+		}
+		assertInstant(t, data.Current, want)
+
+		// The precipitation intensity is reported in in/h and must be graded like mm/h
+		fcast, _ := data.InstantAt(time.Date(2025, 6, 14, 12, 0, 0, 0, berlin))
+		if fcast.WeatherCode != 61 {
+			t.Errorf("expected forecast weather code to be %d, got %d", 61, fcast.WeatherCode)
+		}
+	})
+	t.Run("unit overrides select the unit system and wind speed unit", func(t *testing.T) {
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			if units := req.URL.Query().Get("units"); units != "us" {
+				t.Errorf("expected units to be %q, got %q", "us", units)
+			}
+			return fileResponse(t, 200, testDataUS)(req)
+		}
+		client := testClientWithRoundtripFunc(t, "metric", fn)
+		client.SetUnits(weather.UnitPreferences{Temperature: weather.UnitFahrenheit, WindSpeed: weather.UnitKnots})
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if data.Current.Units.WindSpeed != "kn" {
+			t.Errorf("expected wind speed unit to be %q, got %q", "kn", data.Current.Units.WindSpeed)
+		}
+		if math.Abs(data.Current.WindSpeed-7.0) > 0.01 {
+			t.Errorf("expected wind speed to be %.2f, got %.2f", 7.0, data.Current.WindSpeed)
+		}
+	})
+	t.Run("weather codes and day/night are derived from the data points", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", fileResponse(t, 200, testDataSI))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		tests := []struct {
+			name  string
+			hour  int
+			code  int
+			isDay bool
+		}{
+			{"partly cloudy", 10, 2, true},
+			{"overcast", 11, 3, true},
+			{"light rain", 12, 61, true},
+			{"thunderstorm", 14, 95, true},
+			{"clear", 16, 0, true},
+			{"night icon before sunset", 20, 0, true},
+			{"day icon after sunset", 22, 2, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				fcast, ok := data.InstantAt(time.Date(2025, 6, 14, tc.hour, 0, 0, 0, berlin))
+				if !ok {
+					t.Fatalf("expected forecast for %02d:00 to be present", tc.hour)
+				}
+				if fcast.WeatherCode != tc.code {
+					t.Errorf("expected weather code to be %d, got %d", tc.code, fcast.WeatherCode)
+				}
+				if fcast.IsDay != tc.isDay {
+					t.Errorf("expected is day to be %t, got %t", tc.isDay, fcast.IsDay)
+				}
+			})
+		}
+	})
+	t.Run("request contains the API key, coordinates and units", func(t *testing.T) {
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			wantPath := "/forecast/" + testAPIKey + "/50.940000,6.960000"
+			if req.URL.Path != wantPath {
+				t.Errorf("expected path to be %q, got %q", wantPath, req.URL.Path)
+			}
+			if units := req.URL.Query().Get("units"); units != "si" {
+				t.Errorf("expected units to be %q, got %q", "si", units)
+			}
+			if exclude := req.URL.Query().Get("exclude"); exclude != "minutely,alerts" {
+				t.Errorf("expected exclude to be %q, got %q", "minutely,alerts", exclude)
+			}
+			return fileResponse(t, 200, testDataSI)(req)
+		}
+		client := testClientWithRoundtripFunc(t, "metric", fn)
+		if _, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon}); err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+	})
+	t.Run("rejected API key fails with an actionable error", func(t *testing.T) {
+		for _, code := range []int{401, 403} {
+			client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, code, `{"message":"Forbidden"}`))
+			_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+			if !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("expected error to be %s, got %s", ErrInvalidAPIKey, err)
+			}
+			if !errors.Is(err, providererr.ErrAuth) {
+				t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+			}
+		}
+	})
+	t.Run("rejected API key with non-JSON response fails with an actionable error", func(t *testing.T) {
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return &stdhttp.Response{
+				StatusCode: 403,
+				Body:       io.NopCloser(bytes.NewBufferString("<html>Forbidden</html>")),
+				Header:     stdhttp.Header{"Content-Type": []string{"text/html"}},
+			}, nil
+		}
+		client := testClientWithRoundtripFunc(t, "metric", fn)
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("expected error to be %s, got %s", ErrInvalidAPIKey, err)
+		}
+		if !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
+	})
+	t.Run("exceeded quota fails with an actionable error", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, 429, `{"message":"Too Many Requests"}`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected error to be %s, got %s", ErrRateLimited, err)
+		}
+		if !errors.Is(err, providererr.ErrRateLimited) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrRateLimited, err)
+		}
+	})
+	t.Run("non-positive response code fails with the API error", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, 400, `{"error":"Invalid Location"}`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		wantErr := "Pirate Weather API returned non-positive response code: 400 (Invalid Location)"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+	t.Run("failing request does not leak the API key", func(t *testing.T) {
+		failure := errors.New("intentionally failing")
+		client := testClient(t, "metric")
+		client.http.Transport = testhelper.MockRoundTripper{Fn: func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, failure
+		}}
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		wantErr := "failed to retrieve weather data from Pirate Weather API"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+		if !errors.Is(err, failure) {
+			t.Errorf("expected error to wrap %s, got %s", failure, err)
+		}
+		if strings.Contains(err.Error(), testAPIKey) {
+			t.Errorf("expected error to not contain the API key, got %q", err)
+		}
+	})
+	t.Run("unsuccessful response does not leak the API key", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, 500, `no forecast for /forecast/`+
+			testAPIKey+`/50.94,6.96`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		if strings.Contains(err.Error(), testAPIKey) {
+			t.Errorf("expected error to not contain the API key, got %q", err)
+		}
+		if !strings.Contains(err.Error(), "no forecast for /forecast/[REDACTED]/50.94,6.96") {
+			t.Errorf("expected error to contain the redacted response body, got %q", err)
+		}
+	})
+	t.Run("response without current weather fails", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, 200, `{"timezone":"Europe/Berlin"}`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, ErrNoWeatherData) {
+			t.Errorf("expected error to be %s, got %s", ErrNoWeatherData, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+	t.Run("unknown time zone falls back to the UTC offset", func(t *testing.T) {
+		body := `{"timezone":"Invalid/Zone","offset":5.5,"currently":{"time":1749890520,"icon":"clear-day"}}`
+		client := testClientWithRoundtripFunc(t, "metric", jsonResponse(t, 200, body))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		_, offset := data.Current.InstantTime.Zone()
+		if offset != 5*60*60+30*60 {
+			t.Errorf("expected UTC offset to be %d, got %d", 5*60*60+30*60, offset)
+		}
+	})
+}
+
+func TestResponse_isDay(t *testing.T) {
+	dayStart := time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)
+	res := new(response)
+	res.Daily.Data = append(res.Daily.Data, struct {
+		Time        int64 `json:"time"`
+		SunriseTime int64 `json:"sunriseTime"`
+		SunsetTime  int64 `json:"sunsetTime"`
+	}{
+		Time:        dayStart.Unix(),
+		SunriseTime: dayStart.Add(time.Hour * 5).Unix(),
+		SunsetTime:  dayStart.Add(time.Hour * 21).Unix(),
+	})
+	tests := []struct {
+		name   string
+		at     time.Time
+		icon   string
+		isDay  bool
+		hasDay bool
+	}{
+		{"day from the daily data", dayStart.Add(time.Hour * 12), "rain", true, true},
+		{"night from the daily data", dayStart.Add(time.Hour * 22), "rain", false, true},
+		{"day from the icon", dayStart.AddDate(0, 0, 1), "clear-day", true, true},
+		{"night from the icon", dayStart.AddDate(0, 0, 1), "partly-cloudy-night", false, true},
+		{"unknown without daily data and day icon", dayStart.AddDate(0, 0, 1), "rain", false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isDay, hasDay := res.isDay(tc.at, tc.icon)
+			if isDay != tc.isDay || hasDay != tc.hasDay {
+				t.Errorf("expected is day to be %t (known: %t), got %t (known: %t)", tc.isDay, tc.hasDay, isDay,
+					hasDay)
+			}
+		})
+	}
+}
+
+func TestWmoCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		icon       string
+		summary    string
+		intensity  float64
+		cloudCover float64
+		want       int
+		known      bool
+	}{
+		{"clear day", "clear-day", "Clear", 0, 0, 0, true},
+		{"clear night", "clear-night", "Clear", 0, 0, 0, true},
+		{"partly cloudy", "partly-cloudy-night", "Partly Cloudy", 0, 0.4, 2, true},
+		{"cloudy", "cloudy", "Overcast", 0, 1, 3, true},
+		{"fog", "fog", "Foggy", 0, 1, 45, true},
+		{"windy with few clouds", "wind", "Windy", 0, 0.3, 1, true},
+		{"thunderstorm", "thunderstorm", "Thunderstorm", 5, 1, 95, true},
+		{"hail", "hail", "Hail", 5, 1, 96, true},
+		{"light rain", "rain", "Light Rain", 1, 1, 61, true},
+		{"moderate rain", "rain", "Rain", 5, 1, 63, true},
+		{"heavy rain", "rain", "Heavy Rain", 10, 1, 65, true},
+		{"drizzle", "rain", "Drizzle", 0.2, 1, 51, true},
+		{"freezing rain", "rain", "Freezing Rain", 8, 1, 67, true},
+		{"rain with thunder", "rain", "Rain and Thunder", 5, 1, 95, true},
+		{"light snow", "snow", "Light Snow", 0.5, 1, 71, true},
+		{"heavy snow", "snow", "Heavy Snow", 8, 1, 75, true},
+		{"sleet", "sleet", "Sleet", 1, 1, 66, true},
+		{"unknown icon", "tornado", "Tornado", 0, 0.9, 3, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, known := wmoCode(tc.icon, tc.summary, tc.intensity, tc.cloudCover)
+			if code != tc.want {
+				t.Errorf("expected weather code to be %d, got %d", tc.want, code)
+			}
+			if known != tc.known {
+				t.Errorf("expected known to be %t, got %t", tc.known, known)
+			}
+		})
+	}
+}
+
+func assertInstant(t *testing.T, got, want weather.Instant) {
+	t.Helper()
+	if !got.InstantTime.Equal(want.InstantTime) {
+		t.Errorf("expected time to be %s, got %s", want.InstantTime, got.InstantTime)
+	}
+	floats := []struct {
+		name      string
+		got, want float64
+	}{
+		{"temperature", got.Temperature, want.Temperature},
+		{"apparent temperature", got.ApparentTemperature, want.ApparentTemperature},
+		{"wind speed", got.WindSpeed, want.WindSpeed},
+		{"wind gusts", got.WindGusts, want.WindGusts},
+		{"wind direction", got.WindDirection, want.WindDirection},
+		{"relative humidity", got.RelativeHumidity, want.RelativeHumidity},
+		{"pressure", got.PressureMSL, want.PressureMSL},
+		{"dew point", got.DewPoint, want.DewPoint},
+	}
+	for _, f := range floats {
+		if math.Abs(f.got-f.want) > 0.01 {
+			t.Errorf("expected %s to be %.2f, got %.2f", f.name, f.want, f.got)
+		}
+	}
+	if got.WeatherCode != want.WeatherCode {
+		t.Errorf("expected weather code to be %d, got %d", want.WeatherCode, got.WeatherCode)
+	}
+	if got.IsDay != want.IsDay {
+		t.Errorf("expected is day to be %t, got %t", want.IsDay, got.IsDay)
+	}
+	if got.Units != want.Units {
+		t.Errorf("expected units to be %+v, got %+v", want.Units, got.Units)
+	}
+}
+
+func testClient(t *testing.T, unit string) *PirateWeather {
+	t.Helper()
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	client, err := New(http.New(log), log, unit, testAPIKey)
+	if err != nil {
+		t.Fatalf("failed to create Pirate Weather client: %s", err)
+	}
+	return client
+}
+
+func testClientWithRoundtripFunc(t *testing.T, unit string,
+	fn func(req *stdhttp.Request) (*stdhttp.Response, error),
+) *PirateWeather {
+	t.Helper()
+	client := testClient(t, unit)
+	client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+	return client
+}
+
+func fileResponse(t *testing.T, code int, file string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read JSON response file: %s", err)
+		}
+		return jsonResponse(t, code, string(data))(req)
+	}
+}
+
+func jsonResponse(t *testing.T, code int, body string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		return &stdhttp.Response{
+			StatusCode: code,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Header:     stdhttp.Header{"Content-Type": []string{"application/json"}},
+		}, nil
+	}
+}
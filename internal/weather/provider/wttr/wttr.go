@@ -0,0 +1,416 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package wttr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	name           = "wttr"
+	attribution    = "Weather data by wttr.in"
+	DefaultBaseURL = "https://wttr.in"
+	apiTimeout     = time.Second * 10
+	// The hourly forecast of wttr.in is only available in 3-hour steps. The hours in between are
+	// interpolated, as long as the neighbouring blocks are at most this far apart.
+	maxInterpolationGap = time.Hour * 3
+	// wmoOvercast is used for weather codes that are missing in the WWO to WMO mapping
+	wmoOvercast = 3
+)
+
+// ErrNoWeatherData is returned if the API response does not contain any current weather data
+var ErrNoWeatherData = providererr.Wrap(providererr.ErrBadResponse,
+	errors.New("wttr.in API returned no current weather data"))
+
+// wwoToWMO maps the World Weather Online weather codes used by wttr.in to the WMO weather codes.
+var wwoToWMO = map[int]int{
+	113: 0,  // Clear/Sunny
+	116: 2,  // Partly cloudy
+	119: 3,  // Cloudy
+	122: 3,  // Overcast
+	143: 45, // Mist
+	176: 61, // Patchy rain possible
+	179: 71, // Patchy snow possible
+	182: 66, // Patchy sleet possible
+	185: 56, // Patchy freezing drizzle possible
+	200: 95, // Thundery outbreaks possible
+	227: 73, // Blowing snow
+	230: 75, // Blizzard
+	248: 45, // Fog
+	260: 48, // Freezing fog
+	263: 51, // Patchy light drizzle
+	266: 51, // Light drizzle
+	281: 56, // Freezing drizzle
+	284: 57, // Heavy freezing drizzle
+	293: 61, // Patchy light rain
+	296: 61, // Light rain
+	299: 63, // Moderate rain at times
+	302: 63, // Moderate rain
+	305: 65, // Heavy rain at times
+	308: 65, // Heavy rain
+	311: 66, // Light freezing rain
+	314: 67, // Moderate or heavy freezing rain
+	317: 66, // Light sleet
+	320: 67, // Moderate or heavy sleet
+	323: 71, // Patchy light snow
+	326: 71, // Light snow
+	329: 73, // Patchy moderate snow
+	332: 73, // Moderate snow
+	335: 75, // Patchy heavy snow
+	338: 75, // Heavy snow
+	350: 77, // Ice pellets
+	353: 80, // Light rain shower
+	356: 81, // Moderate or heavy rain shower
+	359: 82, // Torrential rain shower
+	362: 85, // Light sleet showers
+	365: 86, // Moderate or heavy sleet showers
+	368: 85, // Light snow showers
+	371: 86, // Moderate or heavy snow showers
+	374: 77, // Light showers of ice pellets
+	377: 77, // Moderate or heavy showers of ice pellets
+	386: 95, // Patchy light rain with thunder
+	389: 95, // Moderate or heavy rain with thunder
+	392: 95, // Patchy light snow with thunder
+	395: 95, // Moderate or heavy snow with thunder
+}
+
+type Wttr struct {
+	baseURL   string
+	unit      string
+	unitsLock sync.RWMutex
+	units     weather.UnitPreferences
+	log       *logger.Logger
+	http      *http.Client
+}
+
+// number is a numeric value that wttr.in encodes as JSON string.
+type number float64
+
+type response struct {
+	CurrentCondition []struct {
+		LocalObsDateTime string `json:"localObsDateTime"`
+		ObservationTime  string `json:"observation_time"`
+		conditions
+	} `json:"current_condition"`
+	Weather []struct {
+		Date      string `json:"date"`
+		Astronomy []struct {
+			Sunrise string `json:"sunrise"`
+			Sunset  string `json:"sunset"`
+		} `json:"astronomy"`
+		Hourly []struct {
+			Time      number `json:"time"`
+			TempC     number `json:"tempC"`
+			TempF     number `json:"tempF"`
+			DewPointC number `json:"DewPointC"`
+			DewPointF number `json:"DewPointF"`
+			GustKmph  number `json:"WindGustKmph"`
+			GustMiles number `json:"WindGustMiles"`
+			conditions
+		} `json:"hourly"`
+	} `json:"weather"`
+}
+
+// conditions holds the fields that the current conditions and the hourly forecast have in common.
+type conditions struct {
+	TempC          number `json:"temp_C"`
+	TempF          number `json:"temp_F"`
+	FeelsLikeC     number `json:"FeelsLikeC"`
+	FeelsLikeF     number `json:"FeelsLikeF"`
+	Humidity       number `json:"humidity"`
+	Pressure       number `json:"pressure"`
+	WeatherCode    number `json:"weatherCode"`
+	WindDirection  number `json:"winddirDegree"`
+	WindSpeedKmph  number `json:"windspeedKmph"`
+	WindSpeedMiles number `json:"windspeedMiles"`
+}
+
+// New returns a new wttr.in weather provider. If baseURL is empty, the public wttr.in instance is used.
+func New(http *http.Client, log *logger.Logger, unit, baseURL string) (*Wttr, error) {
+	if http == nil {
+		return nil, errors.New("wttr.in provider requires an http client")
+	}
+	if log == nil {
+		return nil, errors.New("wttr.in provider requires a logger")
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Wttr{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		unit:    unit,
+		units:   weather.DefaultUnits(unit),
+		log:     log,
+		http:    http,
+	}, nil
+}
+
+// SetUnits overrides the units of the individual metrics. wttr.in reports the temperatures in °C and °F
+// and the wind speeds in km/h and mph. Other units are left to the presenter to convert. It is safe to
+// call while weather data is requested.
+func (w *Wttr) SetUnits(units weather.UnitPreferences) {
+	w.unitsLock.Lock()
+	defer w.unitsLock.Unlock()
+	w.units = units
+}
+
+// preferredUnits returns the units that the metrics are reported in.
+func (w *Wttr) preferredUnits() weather.UnitPreferences {
+	w.unitsLock.RLock()
+	defer w.unitsLock.RUnlock()
+	return w.units
+}
+
+func (w *Wttr) Name() string {
+	return name
+}
+
+func (w *Wttr) Attribution() string {
+	return attribution
+}
+
+func (w *Wttr) GetWeather(ctx context.Context, coords geobus.Coordinate) (*weather.Data, error) {
+	res := new(response)
+	data := weather.NewData()
+
+	query := url.Values{}
+	query.Set("format", "j1")
+	endpoint := fmt.Sprintf("%s/%f,%f", w.baseURL, coords.Lat, coords.Lon)
+	_, err := w.http.GetWithTimeout(ctx, endpoint, res, query, nil, apiTimeout)
+	if err != nil {
+		return data, fmt.Errorf("failed to retrieve weather data from wttr.in API: %w", err)
+	}
+	if len(res.CurrentCondition) == 0 {
+		return data, ErrNoWeatherData
+	}
+
+	// wttr.in reports local times without time zone, but the observation time is also reported in UTC,
+	// which allows to derive the UTC offset of the location
+	current := res.CurrentCondition[0]
+	loc := zoneFromObservation(current.LocalObsDateTime, current.ObservationTime)
+	obsTime, err := time.ParseInLocation("2006-01-02 03:04 PM", current.LocalObsDateTime, loc)
+	if err != nil {
+		return data, providererr.Wrap(providererr.ErrBadResponse,
+			fmt.Errorf("failed to parse wttr.in observation time: %w", err))
+	}
+
+	units := w.preferredUnits()
+	data.GeneratedAt = time.Now()
+	data.Coordinates = coords
+	data.Current = w.instant(current.conditions, obsTime, units)
+	data.Current.IsDay, data.Current.HasIsDay = res.isDay(obsTime)
+	data.Current.DewPoint = dewPoint(data.Current.Temperature, data.Current.RelativeHumidity,
+		units.Temperature == weather.UnitFahrenheit)
+
+	var blocks []weather.Instant
+	for _, day := range res.Weather {
+		date, err := time.ParseInLocation(time.DateOnly, day.Date, loc)
+		if err != nil {
+			w.log.Warn("skipping wttr.in forecast day with invalid date", logger.Err(err))
+			continue
+		}
+		for _, hourly := range day.Hourly {
+			hhmm := int(hourly.Time)
+			at := date.Add(time.Hour*time.Duration(hhmm/100) + time.Minute*time.Duration(hhmm%100))
+			instant := w.instant(hourly.conditions, at, units)
+			instant.IsDay, instant.HasIsDay = res.isDay(at)
+			instant.Temperature = temperature(units, hourly.TempC, hourly.TempF)
+			instant.DewPoint = temperature(units, hourly.DewPointC, hourly.DewPointF)
+			instant.WindGusts = windSpeed(units, hourly.GustKmph, hourly.GustMiles)
+			blocks = append(blocks, instant)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].InstantTime.Before(blocks[j].InstantTime)
+	})
+	for i, block := range blocks {
+		data.Forecast[weather.NewDayHour(block.InstantTime)] = block
+		if i+1 == len(blocks) {
+			break
+		}
+		for _, instant := range interpolate(block, blocks[i+1]) {
+			instant.IsDay, instant.HasIsDay = res.isDay(instant.InstantTime)
+			data.Forecast[weather.NewDayHour(instant.InstantTime)] = instant
+		}
+	}
+
+	return data, nil
+}
+
+// instant converts the conditions into a weather.Instant in the given units.
+func (w *Wttr) instant(cond conditions, at time.Time, units weather.UnitPreferences) weather.Instant {
+	code, ok := wwoToWMO[int(cond.WeatherCode)]
+	if !ok {
+		w.log.Debug("unknown wttr.in weather code", slog.Int("code", int(cond.WeatherCode)))
+		code = wmoOvercast
+	}
+
+	temperatureUnit, windSpeedUnit := "°C", "km/h"
+	if units.Temperature == weather.UnitFahrenheit {
+		temperatureUnit = "°F"
+	}
+	if units.WindSpeed == weather.UnitMph {
+		windSpeedUnit = "mph"
+	}
+
+	return weather.Instant{
+		InstantTime:         at,
+		Temperature:         temperature(units, cond.TempC, cond.TempF),
+		ApparentTemperature: temperature(units, cond.FeelsLikeC, cond.FeelsLikeF),
+		WeatherCode:         code,
+		WindSpeed:           windSpeed(units, cond.WindSpeedKmph, cond.WindSpeedMiles),
+		WindDirection:       float64(cond.WindDirection),
+		RelativeHumidity:    float64(cond.Humidity),
+		PressureMSL:         float64(cond.Pressure),
+		Units: weather.Units{
+			Temperature:   temperatureUnit,
+			WindSpeed:     windSpeedUnit,
+			Humidity:      "%",
+			Pressure:      "hPa",
+			WindDirection: "°",
+		},
+	}
+}
+
+// temperature returns the temperature in the given unit.
+func temperature(units weather.UnitPreferences, celsius, fahrenheit number) float64 {
+	if units.Temperature == weather.UnitFahrenheit {
+		return float64(fahrenheit)
+	}
+	return float64(celsius)
+}
+
+// windSpeed returns the wind speed in the given unit. Units other than mph are reported in km/h.
+func windSpeed(units weather.UnitPreferences, kmh, mph number) float64 {
+	if units.WindSpeed == weather.UnitMph {
+		return float64(mph)
+	}
+	return float64(kmh)
+}
+
+// isDay reports whether the given time is between sunrise and sunset of its day. The second return value
+// reports whether the astronomy data of the day is available.
+func (r *response) isDay(at time.Time) (bool, bool) {
+	date := at.Format(time.DateOnly)
+	for _, day := range r.Weather {
+		if day.Date != date || len(day.Astronomy) == 0 {
+			continue
+		}
+		sunrise, errRise := time.ParseInLocation("2006-01-02 03:04 PM", date+" "+day.Astronomy[0].Sunrise,
+			at.Location())
+		sunset, errSet := time.ParseInLocation("2006-01-02 03:04 PM", date+" "+day.Astronomy[0].Sunset,
+			at.Location())
+		if errRise != nil || errSet != nil {
+			return false, false
+		}
+		return !at.Before(sunrise) && at.Before(sunset), true
+	}
+	return false, false
+}
+
+// interpolate returns the linearly interpolated Instants for the full hours between the two given
+// Instants. The weather code and wind direction are taken from the nearer Instant. No Instants are
+// returned if the Instants are further apart than maxInterpolationGap.
+func interpolate(from, to weather.Instant) []weather.Instant {
+	gap := to.InstantTime.Sub(from.InstantTime)
+	if gap <= time.Hour || gap > maxInterpolationGap {
+		return nil
+	}
+
+	var instants []weather.Instant
+	for at := from.InstantTime.Add(time.Hour); at.Before(to.InstantTime); at = at.Add(time.Hour) {
+		ratio := float64(at.Sub(from.InstantTime)) / float64(gap)
+		nearest := from
+		if ratio > 0.5 {
+			nearest = to
+		}
+		instants = append(instants, weather.Instant{
+			InstantTime:         at,
+			Temperature:         lerp(from.Temperature, to.Temperature, ratio),
+			ApparentTemperature: lerp(from.ApparentTemperature, to.ApparentTemperature, ratio),
+			WeatherCode:         nearest.WeatherCode,
+			WindSpeed:           lerp(from.WindSpeed, to.WindSpeed, ratio),
+			WindGusts:           lerp(from.WindGusts, to.WindGusts, ratio),
+			WindDirection:       nearest.WindDirection,
+			RelativeHumidity:    lerp(from.RelativeHumidity, to.RelativeHumidity, ratio),
+			PressureMSL:         lerp(from.PressureMSL, to.PressureMSL, ratio),
+			DewPoint:            lerp(from.DewPoint, to.DewPoint, ratio),
+			Units:               from.Units,
+		})
+	}
+	return instants
+}
+
+func lerp(from, to, ratio float64) float64 {
+	return math.Round((from+(to-from)*ratio)*10) / 10
+}
+
+// dewPoint approximates the dew point with the Magnus formula, since wttr.in does not report it for the
+// current conditions.
+func dewPoint(temp, humidity float64, fahrenheit bool) float64 {
+	if humidity <= 0 {
+		return temp
+	}
+	if fahrenheit {
+		temp = (temp - 32) * 5 / 9
+	}
+	const b, c = 17.62, 243.12
+	gamma := math.Log(humidity/100) + b*temp/(c+temp)
+	point := c * gamma / (b - gamma)
+	if fahrenheit {
+		point = point*9/5 + 32
+	}
+	return math.Round(point*10) / 10
+}
+
+// zoneFromObservation derives the time zone of the location from the local and the UTC observation time.
+// If the times can't be parsed, UTC is returned.
+func zoneFromObservation(local, utc string) *time.Location {
+	localTime, errLocal := time.Parse("2006-01-02 03:04 PM", local)
+	utcTime, errUTC := time.Parse("03:04 PM", utc)
+	if errLocal != nil || errUTC != nil {
+		return time.UTC
+	}
+
+	minutes := (localTime.Hour()*60 + localTime.Minute()) - (utcTime.Hour()*60 + utcTime.Minute())
+	switch {
+	case minutes > 14*60:
+		minutes -= 24 * 60
+	case minutes < -12*60:
+		minutes += 24 * 60
+	}
+	return time.FixedZone("", minutes*60)
+}
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	value := strings.Trim(string(b), `"`)
+	if value == "" || value == "null" {
+		*n = 0
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse number: %w", err)
+	}
+	*n = number(parsed)
+	return nil
+}
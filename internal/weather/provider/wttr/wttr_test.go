@@ -0,0 +1,505 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package wttr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	stdhttp "net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
+	"github.com/wneessen/waybar-weather/internal/testhelper"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+const (
+	testLat  = 50.94
+	testLon  = 6.96
+	testData = "../../../../testdata/wttr_cologne.json"
+)
+
+// testZone is the time zone that is derived from the observation times of the test data
+var testZone = time.FixedZone("", 2*60*60)
+
+func TestNew(t *testing.T) {
+	t.Run("creating a new provider succeeds", func(t *testing.T) {
+		client := testClient(t, "metric", "")
+		if client.http == nil {
+			t.Fatal("expected http client to be non-nil")
+		}
+		if client.log == nil {
+			t.Fatal("expected logger to be non-nil")
+		}
+		if client.units != weather.DefaultUnits("metric") {
+			t.Errorf("expected units to be %+v, got %+v", weather.DefaultUnits("metric"), client.units)
+		}
+	})
+	t.Run("empty base URL uses the public instance", func(t *testing.T) {
+		client := testClient(t, "metric", "")
+		if client.baseURL != DefaultBaseURL {
+			t.Errorf("expected base URL to be %q, got %q", DefaultBaseURL, client.baseURL)
+		}
+	})
+	t.Run("custom base URL is used without trailing slash", func(t *testing.T) {
+		client := testClient(t, "metric", "http://localhost:8002/")
+		if client.baseURL != "http://localhost:8002" {
+			t.Errorf("expected base URL to be %q, got %q", "http://localhost:8002", client.baseURL)
+		}
+	})
+	t.Run("creating a provider without http client fails", func(t *testing.T) {
+		client, err := New(nil, logger.New(slog.LevelDebug), "metric", "")
+		if err == nil {
+			t.Fatal("expected client to fail")
+		}
+		if client != nil {
+			t.Fatal("expected client to be nil")
+		}
+		if !strings.Contains(err.Error(), "wttr.in") {
+			t.Errorf("expected error to name the wttr.in provider, got %q", err)
+		}
+	})
+	t.Run("creating a provider without logger fails", func(t *testing.T) {
+		log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+		client, err := New(http.New(log), nil, "metric", "")
+		if err == nil {
+			t.Fatal("expected client to fail")
+		}
+		if client != nil {
+			t.Fatal("expected client to be nil")
+		}
+	})
+}
+
+func TestWttr_Name(t *testing.T) {
+	client := testClient(t, "metric", "")
+	if client.Name() != "wttr" {
+		t.Errorf("expected provider name to be %q, got %q", "wttr", client.Name())
+	}
+}
+
+func TestWttr_GetWeather(t *testing.T) {
+	t.Run("weather lookup succeeds", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", fileResponse(t, testData))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if data.GeneratedAt.IsZero() {
+			t.Error("expected generated at to be set")
+		}
+		if data.Coordinates.Lat != testLat || data.Coordinates.Lon != testLon {
+			t.Errorf("expected coordinates to be %f,%f, got %f,%f", testLat, testLon, data.Coordinates.Lat,
+				data.Coordinates.Lon)
+		}
+
+		want := weather.Instant{
+			InstantTime:         time.Date(2025, 6, 14, 10, 42, 0, 0, testZone),
+			Temperature:         15,
+			ApparentTemperature: 14,
+			WeatherCode:         2,
+			WindSpeed:           13,
+			WindDirection:       225,
+			RelativeHumidity:    72,
+			PressureMSL:         1012,
+			DewPoint:            10,
+			IsDay:               true,
+			HasIsDay:            true,
+			Units: weather.Units{
+				Temperature:   "°C",
+				WindSpeed:     "km/h",
+				Humidity:      "%",
+				Pressure:      "hPa",
+				WindDirection: "°",
+			},
+		}
+		if !data.Current.InstantTime.Equal(want.InstantTime) {
+			t.Errorf("expected current time to be %s, got %s", want.InstantTime, data.Current.InstantTime)
+		}
+		_, offset := data.Current.InstantTime.Zone()
+		if offset != 2*60*60 {
+			t.Errorf("expected current time to have a UTC offset of 2h, got %ds", offset)
+		}
+		data.Current.InstantTime = want.InstantTime
+		if data.Current != want {
+			t.Errorf("expected current weather to be %+v, got %+v", want, data.Current)
+		}
+
+		fcast, ok := data.InstantAt(time.Date(2025, 6, 14, 12, 0, 0, 0, testZone))
+		if !ok {
+			t.Fatal("expected forecast for 12:00 to be present")
+		}
+		if fcast.Temperature != 18 {
+			t.Errorf("expected forecast temperature to be %f, got %f", 18.0, fcast.Temperature)
+		}
+		if fcast.WeatherCode != 61 {
+			t.Errorf("expected forecast weather code to be %d, got %d", 61, fcast.WeatherCode)
+		}
+		if fcast.DewPoint != 13 {
+			t.Errorf("expected forecast dew point to be %f, got %f", 13.0, fcast.DewPoint)
+		}
+		if fcast.WindGusts != 22 {
+			t.Errorf("expected forecast wind gusts to be %f, got %f", 22.0, fcast.WindGusts)
+		}
+		if !fcast.IsDay {
+			t.Error("expected forecast for 12:00 to be at day")
+		}
+	})
+	t.Run("hours between the 3-hourly blocks are interpolated", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", fileResponse(t, testData))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		tests := []struct {
+			name     string
+			at       time.Time
+			temp     float64
+			pressure float64
+			code     int
+			isDay    bool
+		}{
+			{"block at 00:00", time.Date(2025, 6, 14, 0, 0, 0, 0, testZone), 10, 1015, 0, false},
+			{"nearer to 00:00", time.Date(2025, 6, 14, 1, 0, 0, 0, testZone), 9.3, 1014.7, 0, false},
+			{"nearer to 03:00", time.Date(2025, 6, 14, 2, 0, 0, 0, testZone), 8.7, 1014.3, 2, false},
+			{"block at 03:00", time.Date(2025, 6, 14, 3, 0, 0, 0, testZone), 8, 1014, 2, false},
+			{"after sunrise", time.Date(2025, 6, 14, 7, 0, 0, 0, testZone), 10.7, 1012.7, 3, true},
+			{"across midnight", time.Date(2025, 6, 14, 22, 0, 0, 0, testZone), 11.7, 1013.3, 0, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				fcast, ok := data.InstantAt(tc.at)
+				if !ok {
+					t.Fatalf("expected forecast for %s to be present", tc.at)
+				}
+				if !fcast.InstantTime.Equal(tc.at) {
+					t.Errorf("expected forecast time to be %s, got %s", tc.at, fcast.InstantTime)
+				}
+				if fcast.Temperature != tc.temp {
+					t.Errorf("expected temperature to be %.1f, got %.1f", tc.temp, fcast.Temperature)
+				}
+				if fcast.PressureMSL != tc.pressure {
+					t.Errorf("expected pressure to be %.1f, got %.1f", tc.pressure, fcast.PressureMSL)
+				}
+				if fcast.WeatherCode != tc.code {
+					t.Errorf("expected weather code to be %d, got %d", tc.code, fcast.WeatherCode)
+				}
+				if fcast.IsDay != tc.isDay {
+					t.Errorf("expected is day to be %t, got %t", tc.isDay, fcast.IsDay)
+				}
+			})
+		}
+	})
+	t.Run("missing blocks are left as gaps", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", fileResponse(t, testData))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		// The second day of the test data misses the 09:00 block and ends with the 21:00 block
+		for _, hour := range []int{7, 8, 9, 10, 11, 22, 23} {
+			at := time.Date(2025, 6, 15, hour, 0, 0, 0, testZone)
+			if _, ok := data.InstantAt(at); ok {
+				t.Errorf("expected no forecast for %s", at)
+			}
+		}
+		for _, hour := range []int{6, 12, 13, 21} {
+			at := time.Date(2025, 6, 15, hour, 0, 0, 0, testZone)
+			if _, ok := data.InstantAt(at); !ok {
+				t.Errorf("expected forecast for %s to be present", at)
+			}
+		}
+		if len(data.Forecast) != 41 {
+			t.Errorf("expected %d forecast entries, got %d", 41, len(data.Forecast))
+		}
+	})
+	t.Run("weather codes are mapped to WMO codes", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", fileResponse(t, testData))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		tests := []struct {
+			hour int
+			want int
+		}{
+			{0, 45},  // Mist
+			{3, 45},  // Fog
+			{6, 48},  // Freezing fog
+			{12, 95}, // Thundery outbreaks possible
+			{15, 3},  // Unknown code falls back to overcast
+			{18, 95}, // Moderate or heavy rain with thunder
+		}
+		for _, tc := range tests {
+			fcast, _ := data.InstantAt(time.Date(2025, 6, 15, tc.hour, 0, 0, 0, testZone))
+			if fcast.WeatherCode != tc.want {
+				t.Errorf("expected weather code at %02d:00 to be %d, got %d", tc.hour, tc.want, fcast.WeatherCode)
+			}
+		}
+	})
+	t.Run("imperial units are used", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "imperial", "", fileResponse(t, testData))
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if data.Current.Temperature != 59 {
+			t.Errorf("expected current temperature to be %f, got %f", 59.0, data.Current.Temperature)
+		}
+		if data.Current.WindSpeed != 8 {
+			t.Errorf("expected current wind speed to be %f, got %f", 8.0, data.Current.WindSpeed)
+		}
+		if data.Current.DewPoint != 50 {
+			t.Errorf("expected current dew point to be %f, got %f", 50.0, data.Current.DewPoint)
+		}
+		if data.Current.Units.Temperature != "°F" {
+			t.Errorf("expected temperature unit to be %q, got %q", "°F", data.Current.Units.Temperature)
+		}
+		if data.Current.Units.WindSpeed != "mph" {
+			t.Errorf("expected wind speed unit to be %q, got %q", "mph", data.Current.Units.WindSpeed)
+		}
+		fcast, _ := data.InstantAt(time.Date(2025, 6, 14, 12, 0, 0, 0, testZone))
+		if fcast.Temperature != 64 {
+			t.Errorf("expected forecast temperature to be %f, got %f", 64.0, fcast.Temperature)
+		}
+		if fcast.WindSpeed != 10 {
+			t.Errorf("expected forecast wind speed to be %f, got %f", 10.0, fcast.WindSpeed)
+		}
+	})
+	t.Run("unit overrides select the reported units", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", fileResponse(t, testData))
+		client.SetUnits(weather.UnitPreferences{Temperature: weather.UnitFahrenheit, WindSpeed: weather.UnitKmh})
+		data, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+		if data.Current.Temperature != 59 || data.Current.Units.Temperature != "°F" {
+			t.Errorf("expected current temperature to be 59°F, got %f%s", data.Current.Temperature,
+				data.Current.Units.Temperature)
+		}
+		if data.Current.WindSpeed != 13 || data.Current.Units.WindSpeed != "km/h" {
+			t.Errorf("expected current wind speed to be 13km/h, got %f%s", data.Current.WindSpeed,
+				data.Current.Units.WindSpeed)
+		}
+	})
+	t.Run("request uses the base URL and coordinates", func(t *testing.T) {
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			if req.URL.Host != "localhost:8002" {
+				t.Errorf("expected host to be %q, got %q", "localhost:8002", req.URL.Host)
+			}
+			if req.URL.Path != "/50.940000,6.960000" {
+				t.Errorf("expected path to be %q, got %q", "/50.940000,6.960000", req.URL.Path)
+			}
+			if format := req.URL.Query().Get("format"); format != "j1" {
+				t.Errorf("expected format to be %q, got %q", "j1", format)
+			}
+			return fileResponse(t, testData)(req)
+		}
+		client := testClientWithRoundtripFunc(t, "metric", "http://localhost:8002/", fn)
+		if _, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon}); err != nil {
+			t.Fatalf("weather lookup failed: %s", err)
+		}
+	})
+	t.Run("response without current condition fails", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", jsonResponse(t, 200, `{"weather":[]}`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if !errors.Is(err, ErrNoWeatherData) {
+			t.Errorf("expected error to be %s, got %s", ErrNoWeatherData, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+	t.Run("unsuccessful response status fails", func(t *testing.T) {
+		client := testClientWithRoundtripFunc(t, "metric", "", jsonResponse(t, 500, `{}`))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		wantErr := "unsuccessful response status 500 Internal Server Error: {}"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("failing request fails", func(t *testing.T) {
+		fn := func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			return nil, errors.New("intentionally failing")
+		}
+		client := testClientWithRoundtripFunc(t, "metric", "", fn)
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		wantErr := "failed to retrieve weather data from wttr.in API"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrTransient) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrTransient, err)
+		}
+	})
+	t.Run("invalid observation time fails", func(t *testing.T) {
+		body := `{"current_condition":[{"localObsDateTime":"yesterday","observation_time":"08:42 AM"}]}`
+		client := testClientWithRoundtripFunc(t, "metric", "", jsonResponse(t, 200, body))
+		_, err := client.GetWeather(t.Context(), geobus.Coordinate{Lat: testLat, Lon: testLon})
+		if err == nil {
+			t.Fatal("expected weather lookup to fail")
+		}
+		wantErr := "failed to parse wttr.in observation time"
+		if !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("expected error to contain %q, got %q", wantErr, err)
+		}
+		if !errors.Is(err, providererr.ErrBadResponse) {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrBadResponse, err)
+		}
+	})
+}
+
+func TestResponse_isDay(t *testing.T) {
+	var res response
+	body := `{"weather":[{"date":"2025-06-14","astronomy":[{"sunrise":"05:00 AM","sunset":"09:30 PM"}]},` +
+		`{"date":"2025-06-15","astronomy":[{"sunrise":"No sunrise","sunset":"No sunset"}]}]}`
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %s", err)
+	}
+	tests := []struct {
+		name   string
+		at     time.Time
+		isDay  bool
+		hasDay bool
+	}{
+		{"day", time.Date(2025, 6, 14, 12, 0, 0, 0, testZone), true, true},
+		{"night", time.Date(2025, 6, 14, 22, 0, 0, 0, testZone), false, true},
+		{"unparsable astronomy data", time.Date(2025, 6, 15, 12, 0, 0, 0, testZone), false, false},
+		{"missing astronomy data", time.Date(2025, 6, 16, 12, 0, 0, 0, testZone), false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isDay, hasDay := res.isDay(tc.at)
+			if isDay != tc.isDay || hasDay != tc.hasDay {
+				t.Errorf("expected is day to be %t (known: %t), got %t (known: %t)", tc.isDay, tc.hasDay, isDay,
+					hasDay)
+			}
+		})
+	}
+}
+
+func TestZoneFromObservation(t *testing.T) {
+	tests := []struct {
+		name  string
+		local string
+		utc   string
+		want  time.Duration
+	}{
+		{"same time", "2025-06-14 10:42 AM", "10:42 AM", 0},
+		{"positive offset", "2025-06-14 10:42 AM", "08:42 AM", time.Hour * 2},
+		{"half hour offset", "2025-06-14 02:12 PM", "08:42 AM", time.Hour*5 + time.Minute*30},
+		{"positive offset across midnight", "2025-06-14 01:30 AM", "11:00 PM", time.Hour*2 + time.Minute*30},
+		{"negative offset", "2025-06-14 04:42 AM", "08:42 AM", -time.Hour * 4},
+		{"negative offset across midnight", "2025-06-14 01:00 PM", "11:00 PM", -time.Hour * 10},
+		{"largest positive offset", "2025-06-14 10:00 PM", "08:00 AM", time.Hour * 14},
+		{"invalid local time", "invalid", "08:42 AM", 0},
+		{"invalid UTC time", "2025-06-14 10:42 AM", "invalid", 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			loc := zoneFromObservation(tc.local, tc.utc)
+			_, offset := time.Date(2025, 6, 14, 0, 0, 0, 0, loc).Zone()
+			if time.Duration(offset)*time.Second != tc.want {
+				t.Errorf("expected offset to be %s, got %s", tc.want, time.Duration(offset)*time.Second)
+			}
+		})
+	}
+}
+
+func TestNumber_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		want  float64
+		fails bool
+	}{
+		{"string", `{"value":"12"}`, 12, false},
+		{"decimal string", `{"value":"0.3"}`, 0.3, false},
+		{"negative string", `{"value":"-5"}`, -5, false},
+		{"number", `{"value":7}`, 7, false},
+		{"empty string", `{"value":""}`, 0, false},
+		{"null", `{"value":null}`, 0, false},
+		{"invalid", `{"value":"n/a"}`, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var output struct {
+				Value number `json:"value"`
+			}
+			err := json.Unmarshal([]byte(tc.json), &output)
+			if tc.fails {
+				if err == nil {
+					t.Fatal("expected unmarshalling to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to unmarshal JSON: %s", err)
+			}
+			if float64(output.Value) != tc.want {
+				t.Errorf("expected value to be %f, got %f", tc.want, float64(output.Value))
+			}
+		})
+	}
+}
+
+func testClient(t *testing.T, unit, baseURL string) *Wttr {
+	t.Helper()
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	client, err := New(http.New(log), log, unit, baseURL)
+	if err != nil {
+		t.Fatalf("failed to create wttr client: %s", err)
+	}
+	return client
+}
+
+func testClientWithRoundtripFunc(t *testing.T, unit, baseURL string,
+	fn func(req *stdhttp.Request) (*stdhttp.Response, error),
+) *Wttr {
+	t.Helper()
+	client := testClient(t, unit, baseURL)
+	client.http.Transport = testhelper.MockRoundTripper{Fn: fn}
+	return client
+}
+
+func fileResponse(t *testing.T, file string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read JSON response file: %s", err)
+		}
+		return jsonResponse(t, 200, string(data))(req)
+	}
+}
+
+func jsonResponse(t *testing.T, code int, body string) func(req *stdhttp.Request) (*stdhttp.Response, error) {
+	t.Helper()
+	return func(req *stdhttp.Request) (*stdhttp.Response, error) {
+		return &stdhttp.Response{
+			StatusCode: code,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Header:     stdhttp.Header{"Content-Type": []string{"application/json"}},
+		}, nil
+	}
+}
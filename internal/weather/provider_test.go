@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/wneessen/waybar-weather/internal/http"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/weather"
+	openmeteo "github.com/wneessen/waybar-weather/internal/weather/provider/open-meteo"
+	"github.com/wneessen/waybar-weather/internal/weather/provider/pirateweather"
+	"github.com/wneessen/waybar-weather/internal/weather/provider/wttr"
+)
+
+// TestProvider_conformance checks the contract of the weather.Provider interface for all weather
+// providers. New providers must be added here.
+func TestProvider_conformance(t *testing.T) {
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	client := http.New(log)
+	providers := []struct {
+		name string
+		new  func() (weather.Provider, error)
+	}{
+		{"open-meteo", func() (weather.Provider, error) { return openmeteo.New(client, log, "metric") }},
+		{"pirateweather", func() (weather.Provider, error) { return pirateweather.New(client, log, "metric", "key") }},
+		{"wttr", func() (weather.Provider, error) { return wttr.New(client, log, "metric", "") }},
+	}
+	for _, tc := range providers {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := tc.new()
+			if err != nil {
+				t.Fatalf("failed to create weather provider: %s", err)
+			}
+			if provider.Name() != tc.name {
+				t.Errorf("expected provider name to be %q, got %q", tc.name, provider.Name())
+			}
+			if provider.Attribution() == "" {
+				t.Error("expected provider attribution to be non-empty")
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"slices"
+	"strings"
+)
+
+// Unit systems that select the default unit of all metrics. UnitSystemAuto selects the unit system that
+// is customary in the country of the location.
+const (
+	UnitSystemMetric   = "metric"
+	UnitSystemImperial = "imperial"
+	UnitSystemAuto     = "auto"
+)
+
+// imperialCountries are the ISO 3166-1 alpha-2 codes of the countries that customarily use the imperial
+// unit system
+var imperialCountries = []string{"US", "LR", "MM"}
+
+// Unit names that can be selected for the individual metrics
+const (
+	UnitCelsius    = "celsius"
+	UnitFahrenheit = "fahrenheit"
+	UnitKmh        = "kmh"
+	UnitMs         = "ms"
+	UnitMph        = "mph"
+	UnitKnots      = "kn"
+	UnitHPa        = "hpa"
+	UnitInHg       = "inhg"
+	UnitMmHg       = "mmhg"
+	UnitMillimeter = "mm"
+	UnitInch       = "inch"
+)
+
+const (
+	hPaPerMmHg = 1.333224
+	kmhPerMph  = 1.609344
+	kmhPerMs   = 3.6
+	kmhPerKnot = 1.852
+	mmPerInch  = 25.4
+)
+
+// SupportedUnits maps the metrics to the unit names that can be selected for them.
+var SupportedUnits = map[string][]string{
+	"temperature":   {UnitCelsius, UnitFahrenheit},
+	"wind_speed":    {UnitKmh, UnitMs, UnitMph, UnitKnots},
+	"pressure":      {UnitHPa, UnitInHg, UnitMmHg},
+	"precipitation": {UnitMillimeter, UnitInch},
+}
+
+// unitSymbols maps the unit names to the symbols that are used in the Units of an Instant.
+var unitSymbols = map[string]string{
+	UnitCelsius:    "°C",
+	UnitFahrenheit: "°F",
+	UnitKmh:        "km/h",
+	UnitMs:         "m/s",
+	UnitMph:        "mph",
+	UnitKnots:      "kn",
+	UnitHPa:        "hPa",
+	UnitInHg:       "inHg",
+	UnitMmHg:       "mmHg",
+	UnitMillimeter: "mm",
+	UnitInch:       "inch",
+}
+
+// UnitPreferences holds the unit names in which the metrics are requested from the weather provider
+// and presented to the user.
+type UnitPreferences struct {
+	Temperature   string
+	WindSpeed     string
+	Pressure      string
+	Precipitation string
+}
+
+// DefaultUnits returns the UnitPreferences of the given unit system. Unknown systems default to metric.
+// The pressure is reported in hPa in both systems.
+func DefaultUnits(system string) UnitPreferences {
+	if strings.EqualFold(system, UnitSystemImperial) {
+		return UnitPreferences{
+			Temperature:   UnitFahrenheit,
+			WindSpeed:     UnitMph,
+			Pressure:      UnitHPa,
+			Precipitation: UnitInch,
+		}
+	}
+	return UnitPreferences{
+		Temperature:   UnitCelsius,
+		WindSpeed:     UnitKmh,
+		Pressure:      UnitHPa,
+		Precipitation: UnitMillimeter,
+	}
+}
+
+// CountryUnitSystem returns the unit system that is customary in the country with the given ISO 3166-1
+// alpha-2 code: imperial for the United States, Liberia and Myanmar, metric everywhere else.
+func CountryUnitSystem(countryCode string) string {
+	if slices.Contains(imperialCountries, strings.ToUpper(countryCode)) {
+		return UnitSystemImperial
+	}
+	return UnitSystemMetric
+}
+
+// Units returns the unit symbols of the preferences as used in the Units of an Instant. Humidity and
+// wind direction are always reported in percent and degrees.
+func (p UnitPreferences) Units() Units {
+	return Units{
+		Temperature:   unitSymbols[p.Temperature],
+		WindSpeed:     unitSymbols[p.WindSpeed],
+		Humidity:      "%",
+		Pressure:      unitSymbols[p.Pressure],
+		WindDirection: "°",
+		Precipitation: unitSymbols[p.Precipitation],
+	}
+}
+
+// WithDefaults returns the Units with every empty unit replaced by the corresponding unit of defaults.
+func (u Units) WithDefaults(defaults Units) Units {
+	fields := []struct {
+		unit     *string
+		fallback string
+	}{
+		{&u.Temperature, defaults.Temperature},
+		{&u.WindSpeed, defaults.WindSpeed},
+		{&u.Humidity, defaults.Humidity},
+		{&u.Pressure, defaults.Pressure},
+		{&u.WindDirection, defaults.WindDirection},
+		{&u.Precipitation, defaults.Precipitation},
+	}
+	for _, field := range fields {
+		if *field.unit == "" {
+			*field.unit = field.fallback
+		}
+	}
+	return u
+}
+
+// Convert converts the metrics of the Instant into the preferred units and updates its Units accordingly.
+// Metrics with an unknown unit or without a preference are left unchanged.
+func (i Instant) Convert(prefs UnitPreferences) Instant {
+	if symbol, ok := unitSymbols[prefs.Temperature]; ok && !strings.EqualFold(i.Units.Temperature, symbol) {
+		switch {
+		case prefs.Temperature == UnitFahrenheit && strings.EqualFold(i.Units.Temperature, "°C"):
+			i.Temperature = i.Temperature*9/5 + 32
+			i.ApparentTemperature = i.ApparentTemperature*9/5 + 32
+			i.DewPoint = i.DewPoint*9/5 + 32
+			i.Units.Temperature = symbol
+		case prefs.Temperature == UnitCelsius && strings.EqualFold(i.Units.Temperature, "°F"):
+			i.Temperature = (i.Temperature - 32) * 5 / 9
+			i.ApparentTemperature = (i.ApparentTemperature - 32) * 5 / 9
+			i.DewPoint = (i.DewPoint - 32) * 5 / 9
+			i.Units.Temperature = symbol
+		}
+	}
+
+	if symbol, ok := unitSymbols[prefs.WindSpeed]; ok && !strings.EqualFold(i.Units.WindSpeed, symbol) {
+		from, fromOK := kmhFactor(i.Units.WindSpeed)
+		to, toOK := kmhFactor(symbol)
+		if fromOK && toOK {
+			i.WindSpeed = i.WindSpeed * from / to
+			i.WindGusts = i.WindGusts * from / to
+			i.Units.WindSpeed = symbol
+		}
+	}
+
+	if symbol, ok := unitSymbols[prefs.Pressure]; ok && !strings.EqualFold(i.Units.Pressure, symbol) {
+		from, fromOK := hPaFactor(i.Units.Pressure)
+		to, toOK := hPaFactor(symbol)
+		if fromOK && toOK {
+			i.PressureMSL = i.PressureMSL * from / to
+			i.Units.Pressure = symbol
+		}
+	}
+
+	if symbol, ok := unitSymbols[prefs.Precipitation]; ok && !strings.EqualFold(i.Units.Precipitation, symbol) {
+		switch {
+		case prefs.Precipitation == UnitInch && strings.EqualFold(i.Units.Precipitation, "mm"):
+			i.Precipitation /= mmPerInch
+			i.Units.Precipitation = symbol
+		case prefs.Precipitation == UnitMillimeter && strings.EqualFold(i.Units.Precipitation, "inch"):
+			i.Precipitation *= mmPerInch
+			i.Units.Precipitation = symbol
+		}
+	}
+
+	return i
+}
+
+// kmhFactor returns the factor that converts a wind speed in the given unit symbol to km/h.
+func kmhFactor(symbol string) (float64, bool) {
+	switch strings.ToLower(symbol) {
+	case "km/h":
+		return 1, true
+	case "m/s":
+		return kmhPerMs, true
+	case "mph", "mp/h":
+		return kmhPerMph, true
+	case "kn", "kt", "knots":
+		return kmhPerKnot, true
+	default:
+		return 0, false
+	}
+}
+
+// hPaFactor returns the factor that converts a pressure in the given unit symbol to hPa.
+func hPaFactor(symbol string) (float64, bool) {
+	switch strings.ToLower(symbol) {
+	case "hpa":
+		return 1, true
+	case "inhg":
+		return hPaPerInHg, true
+	case "mmhg":
+		return hPaPerMmHg, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package weather
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Plausible physical bounds of the weather metrics. Values outside these bounds are considered garbage
+// returned by the weather provider.
+const (
+	minTemperatureCelsius = -90.0
+	maxTemperatureCelsius = 60.0
+	minHumidity           = 0.0
+	maxHumidity           = 100.0
+	minPressureHPa        = 850.0
+	maxPressureHPa        = 1100.0
+	minWindSpeed          = 0.0
+	hPaPerInHg            = 33.8639
+)
+
+// Validation summarizes the metrics that failed the sanity checks of Data.Validate.
+type Validation struct {
+	// Current holds the names of the metrics of the current conditions that are out of bounds
+	Current []string
+	// Dropped is the number of forecast entries that were discarded
+	Dropped int
+	// Fields holds the names of the metrics that caused forecast entries to be discarded
+	Fields []string
+}
+
+// CurrentValid reports whether the current conditions passed the sanity checks.
+func (v Validation) CurrentValid() bool {
+	return len(v.Current) == 0
+}
+
+// Clean reports whether all data passed the sanity checks.
+func (v Validation) Clean() bool {
+	return v.CurrentValid() && v.Dropped == 0
+}
+
+// LogValue implements slog.LogValuer for the Validation.
+func (v Validation) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("current", strings.Join(v.Current, ",")),
+		slog.Int("dropped_forecasts", v.Dropped),
+		slog.String("forecast_fields", strings.Join(v.Fields, ",")),
+	)
+}
+
+// Validate checks the weather data against plausible physical bounds. Forecast entries with out-of-range
+// values are removed from the forecast map. The current conditions are not modified; the returned
+// Validation reports if they are out of bounds, so that the caller can decide to discard the data.
+func (d *Data) Validate() Validation {
+	var v Validation
+	if d == nil {
+		return v
+	}
+
+	v.Current = d.Current.invalidFields()
+	for hour, instant := range d.Forecast {
+		fields := instant.invalidFields()
+		if len(fields) == 0 {
+			continue
+		}
+		delete(d.Forecast, hour)
+		v.Dropped++
+		for _, field := range fields {
+			if !slices.Contains(v.Fields, field) {
+				v.Fields = append(v.Fields, field)
+			}
+		}
+	}
+	slices.Sort(v.Fields)
+
+	return v
+}
+
+// invalidFields returns the names of all metrics of the Instant that are out of their plausible bounds.
+// A pressure of zero is treated as not reported by the provider.
+func (i Instant) invalidFields() []string {
+	var fields []string
+	temperature, dewPoint := i.Temperature, i.DewPoint
+	if strings.HasSuffix(strings.ToUpper(i.Units.Temperature), "F") {
+		temperature, dewPoint = (temperature-32)*5/9, (dewPoint-32)*5/9
+	}
+	if !inBounds(temperature, minTemperatureCelsius, maxTemperatureCelsius) {
+		fields = append(fields, "temperature")
+	}
+	if !inBounds(dewPoint, minTemperatureCelsius, maxTemperatureCelsius) {
+		fields = append(fields, "dew_point")
+	}
+	if !inBounds(i.RelativeHumidity, minHumidity, maxHumidity) {
+		fields = append(fields, "relative_humidity")
+	}
+	pressure := i.PressureMSL
+	if strings.EqualFold(i.Units.Pressure, "inHg") {
+		pressure *= hPaPerInHg
+	}
+	if pressure != 0 && !inBounds(pressure, minPressureHPa, maxPressureHPa) {
+		fields = append(fields, "pressure_msl")
+	}
+	if !inBounds(i.WindSpeed, minWindSpeed, math.MaxFloat64) {
+		fields = append(fields, "wind_speed")
+	}
+	if !inBounds(i.WindGusts, minWindSpeed, math.MaxFloat64) {
+		fields = append(fields, "wind_gusts")
+	}
+	return fields
+}
+
+// inBounds reports whether the value is within the given bounds. NaN is never within bounds.
+func inBounds(value, lower, upper float64) bool {
+	return value >= lower && value <= upper
+}
@@ -14,15 +14,50 @@ import (
 // Provider is implemented by each weather API backend.
 type Provider interface {
 	Name() string
+	// Attribution returns the attribution that the terms of the weather data source ask for. It must not
+	// be empty.
+	Attribution() string
 	GetWeather(ctx context.Context, coords geobus.Coordinate) (*Data, error)
 }
 
+// RawResponseRetainer is implemented by providers that can retain the raw body of their last API
+// response for debugging.
+type RawResponseRetainer interface {
+	// RetainRawResponse enables the retention of up to limit bytes of the raw response body
+	RetainRawResponse(limit int)
+	// LastRawResponse returns the retained raw body of the last response and whether it was truncated
+	LastRawResponse() ([]byte, bool)
+}
+
+// UnitSetter is implemented by providers whose units can be changed after they were created, e. g. when
+// the unit system follows the country of the location.
+type UnitSetter interface {
+	// SetUnits sets the units of the following requests. It must be safe to call while weather data is
+	// requested.
+	SetUnits(units UnitPreferences)
+}
+
 type Data struct {
 	GeneratedAt time.Time
 	Coordinates geobus.Coordinate
+	// Timezone is the IANA timezone name of the location, if provided by the weather provider. It is set
+	// with SetTimezone, which resolves it once, so that the time zone isn't loaded for every lookup.
+	Timezone string
+	// Elevation is the elevation of the location in meters, if provided by the weather provider
+	Elevation float64
+	// Source is the name of the weather provider that produced the data
+	Source string
 
 	Current  Instant
 	Forecast map[DayHour]Instant
+	// Nowcast is the short-term precipitation nowcast in steps of NowcastInterval, sorted by time. It is
+	// only set by providers that support it and if it was enabled.
+	Nowcast []NowcastStep
+
+	// fetchedAt holds the fetch time of each forecast entry, once the data was merged with previous data
+	fetchedAt map[DayHour]time.Time
+	// location is the resolved time zone of Timezone, if it is known to the system
+	location *time.Location
 }
 
 type Instant struct {
@@ -35,8 +70,24 @@ type Instant struct {
 	WindDirection       float64
 	RelativeHumidity    float64
 	PressureMSL         float64
+	DewPoint            float64
+	Precipitation       float64
 	IsDay               bool
-	Units               Units
+	// HasIsDay is set if the provider reported IsDay. Otherwise, it is derived from the position of the sun
+	// with Data.ResolveIsDay.
+	HasIsDay bool
+	Units    Units
+}
+
+// NowcastInterval is the length of the steps of the precipitation nowcast
+const NowcastInterval = time.Minute * 15
+
+// NowcastStep is a step of the precipitation nowcast. Like the hourly precipitation of an Instant,
+// Precipitation is the sum of the NowcastInterval preceding Time.
+type NowcastStep struct {
+	Time          time.Time
+	Precipitation float64
+	Unit          string
 }
 
 type Units struct {
@@ -45,8 +96,13 @@ type Units struct {
 	Humidity      string
 	Pressure      string
 	WindDirection string
+	Precipitation string
 }
 
+// DayHour identifies the hour of an Instant in the forecast map. It holds the start of the hour as Unix
+// timestamp and is therefore independent of the time zone it was derived from. The start of the hour is
+// determined by the wall clock of a time zone, since it differs from the UTC hour in time zones with an
+// offset that is not a whole hour.
 type DayHour int64
 
 func NewData() *Data {
@@ -55,10 +111,77 @@ func NewData() *Data {
 	}
 }
 
+// InstantAt returns the Instant of the forecast map for the hour of the given time. The second return
+// value reports whether an Instant for that hour was present.
+func (d *Data) InstantAt(t time.Time) (Instant, bool) {
+	if d == nil || d.Forecast == nil {
+		return Instant{}, false
+	}
+	instant, ok := d.Forecast[d.DayHour(t)]
+	return instant, ok
+}
+
+// SetTimezone sets the IANA timezone name of the location and resolves it for Location.
+func (d *Data) SetTimezone(name string) {
+	d.Timezone = name
+	d.location = nil
+	if name == "" {
+		return
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		d.location = loc
+	}
+}
+
+// Location returns the time zone of the weather data's location. If the provider did not report a time
+// zone name or it is unknown to the system, the time zone of the current Instant is used. If that is not
+// available either, the local time zone is returned.
+func (d *Data) Location() *time.Location {
+	if d == nil {
+		return time.Local
+	}
+	if d.location != nil && d.location.String() == d.Timezone {
+		return d.location
+	}
+	// Timezone was set without SetTimezone, e. g. in a struct literal
+	if d.Timezone != "" {
+		if loc, err := time.LoadLocation(d.Timezone); err == nil {
+			return loc
+		}
+	}
+	if !d.Current.InstantTime.IsZero() {
+		return d.Current.InstantTime.Location()
+	}
+	return time.Local
+}
+
+// DayHour returns the DayHour of the given time in the time zone of the weather data's location.
+func (d *Data) DayHour(t time.Time) DayHour {
+	return NewDayHourIn(t, d.Location())
+}
+
+// NewDayHour returns the DayHour of the given time in the time zone of the time itself.
 func NewDayHour(t time.Time) DayHour {
-	return DayHour(t.Truncate(time.Hour).Unix())
+	return NewDayHourIn(t, t.Location())
 }
 
+// NewDayHourIn returns the DayHour of the given time in the given time zone.
+func NewDayHourIn(t time.Time, loc *time.Location) DayHour {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	offset := time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second +
+		time.Duration(local.Nanosecond())
+	return DayHour(local.Add(-offset).Unix())
+}
+
+// Time returns the start of the hour in UTC.
 func (t DayHour) Time() time.Time {
-	return time.Unix(int64(t), 0)
+	return time.Unix(int64(t), 0).UTC()
+}
+
+// In returns the start of the hour in the given time zone.
+func (t DayHour) In(loc *time.Location) time.Time {
+	return t.Time().In(loc)
 }
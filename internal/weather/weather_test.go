@@ -5,8 +5,23 @@
 package weather
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
+
+	"github.com/wneessen/waybar-weather/internal/clock"
+	"github.com/wneessen/waybar-weather/internal/geobus"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/providererr"
 )
 
 func TestNewData(t *testing.T) {
@@ -26,3 +41,846 @@ func TestNewDayHour(t *testing.T) {
 		t.Errorf("expected time to be %s, got %s", want, dayhour.Time())
 	}
 }
+
+func TestNewDayHourIn(t *testing.T) {
+	tests := []struct {
+		name string
+		tz   string
+		at   time.Time
+		want time.Time
+	}{
+		{
+			"+10h offset", "Australia/Brisbane", time.Date(2026, 1, 16, 22, 45, 0, 0, time.UTC),
+			time.Date(2026, 1, 16, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			"half hour offset", "Asia/Kolkata", time.Date(2026, 1, 16, 22, 15, 0, 0, time.UTC),
+			time.Date(2026, 1, 16, 21, 30, 0, 0, time.UTC),
+		},
+		{
+			"before DST transition", "Europe/Berlin", time.Date(2026, 3, 29, 0, 59, 59, 0, time.UTC),
+			time.Date(2026, 3, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"after DST transition", "Europe/Berlin", time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			"repeated hour at DST end", "Europe/Berlin", time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tc.tz)
+			if err != nil {
+				t.Fatalf("failed to load time zone: %s", err)
+			}
+			got := NewDayHourIn(tc.at, loc)
+			if !got.Time().Equal(tc.want) {
+				t.Errorf("expected day hour to be %s, got %s", tc.want, got.Time())
+			}
+			if got != NewDayHourIn(tc.at.In(loc), loc) || got != NewDayHour(tc.at.In(loc)) {
+				t.Error("expected day hour to be independent of the time zone of the given time")
+			}
+			if got.In(loc).Location() != loc {
+				t.Errorf("expected time to be in %s, got %s", loc, got.In(loc).Location())
+			}
+		})
+	}
+	t.Run("consecutive hours across DST transitions", func(t *testing.T) {
+		data := &Data{Timezone: "Europe/Berlin"}
+		for _, start := range []time.Time{
+			time.Date(2026, 3, 28, 22, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 24, 22, 0, 0, 0, time.UTC),
+		} {
+			prev := data.DayHour(start)
+			for i := 1; i < 6; i++ {
+				hour := data.DayHour(start.Add(time.Hour * time.Duration(i)))
+				if hour-prev != 3600 {
+					t.Errorf("expected hours to be consecutive, got %s and %s", prev.Time(), hour.Time())
+				}
+				prev = hour
+			}
+		}
+	})
+}
+
+func TestData_Location(t *testing.T) {
+	if loc := (&Data{Timezone: "Australia/Brisbane"}).Location(); loc.String() != "Australia/Brisbane" {
+		t.Errorf("expected location to be %q, got %q", "Australia/Brisbane", loc)
+	}
+	for _, data := range []*Data{nil, {}, {Timezone: "Invalid/Zone"}} {
+		if loc := data.Location(); loc != time.Local {
+			t.Errorf("expected location to fall back to the local time zone, got %q", loc)
+		}
+	}
+	t.Run("time zone is resolved once", func(t *testing.T) {
+		data := NewData()
+		data.SetTimezone("Australia/Brisbane")
+		if data.Location() != data.Location() {
+			t.Error("expected the resolved time zone to be reused")
+		}
+		data.Timezone = "Europe/Berlin"
+		if loc := data.Location(); loc.String() != "Europe/Berlin" {
+			t.Errorf("expected changed time zone to be %q, got %q", "Europe/Berlin", loc)
+		}
+		data.SetTimezone("Invalid/Zone")
+		if loc := data.Location(); loc != time.Local {
+			t.Errorf("expected unknown time zone to fall back to the local time zone, got %q", loc)
+		}
+	})
+	zone := time.FixedZone("", 5*60*60+30*60)
+	data := &Data{Current: Instant{InstantTime: time.Date(2025, 1, 1, 12, 0, 0, 0, zone)}}
+	if loc := data.Location(); loc != zone {
+		t.Errorf("expected location to fall back to the time zone of the current instant, got %q", loc)
+	}
+}
+
+func TestData_InstantAt(t *testing.T) {
+	t.Run("instant is found", func(t *testing.T) {
+		data := NewData()
+		at := time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC)
+		data.Forecast[NewDayHour(at)] = Instant{InstantTime: at, Temperature: 12.3}
+		instant, ok := data.InstantAt(at.Add(time.Minute * 30))
+		if !ok {
+			t.Fatal("expected instant to be found")
+		}
+		if instant.Temperature != 12.3 {
+			t.Errorf("expected temperature to be %f, got %f", 12.3, instant.Temperature)
+		}
+	})
+	t.Run("instant is not found", func(t *testing.T) {
+		data := NewData()
+		if _, ok := data.InstantAt(time.Now()); ok {
+			t.Error("expected instant to not be found")
+		}
+	})
+	t.Run("nil data returns no instant", func(t *testing.T) {
+		var data *Data
+		if _, ok := data.InstantAt(time.Now()); ok {
+			t.Error("expected instant to not be found")
+		}
+	})
+}
+
+func TestData_Validate(t *testing.T) {
+	celsius := Units{Temperature: "°C", Pressure: "hPa"}
+	fahrenheit := Units{Temperature: "°F", Pressure: "inHg"}
+	valid := Instant{Temperature: 12.3, DewPoint: 4.2, RelativeHumidity: 57, PressureMSL: 1013.2, WindSpeed: 12.4,
+		WindGusts: 20.1, Units: celsius}
+	tests := []struct {
+		name    string
+		modify  func(*Instant)
+		invalid []string
+	}{
+		{"plausible values", func(*Instant) {}, nil},
+		{"missing pressure", func(i *Instant) { i.PressureMSL = 0 }, nil},
+		{"extreme but plausible cold", func(i *Instant) { i.Temperature, i.DewPoint = -89.2, -90 }, nil},
+		{"temperature overflow", func(i *Instant) { i.Temperature = 3276.7 }, []string{"temperature"}},
+		{"temperature underflow", func(i *Instant) { i.Temperature = -3276.8 }, []string{"temperature"}},
+		{"NaN temperature", func(i *Instant) { i.Temperature = math.NaN() }, []string{"temperature"}},
+		{"dew point out of range", func(i *Instant) { i.DewPoint = 99 }, []string{"dew_point"}},
+		{"humidity above 100%", func(i *Instant) { i.RelativeHumidity = 250 }, []string{"relative_humidity"}},
+		{"negative humidity", func(i *Instant) { i.RelativeHumidity = -1 }, []string{"relative_humidity"}},
+		{"pressure too low", func(i *Instant) { i.PressureMSL = 400 }, []string{"pressure_msl"}},
+		{"pressure too high", func(i *Instant) { i.PressureMSL = 1500 }, []string{"pressure_msl"}},
+		{"negative wind speed", func(i *Instant) { i.WindSpeed = -3 }, []string{"wind_speed"}},
+		{"infinite wind gusts", func(i *Instant) { i.WindGusts = math.Inf(1) }, []string{"wind_gusts"}},
+		{"NaN wind gusts", func(i *Instant) { i.WindGusts = math.NaN() }, []string{"wind_gusts"}},
+		{"plausible fahrenheit values", func(i *Instant) {
+			i.Temperature, i.DewPoint, i.PressureMSL, i.Units = 104, 68, 29.92, fahrenheit
+		}, nil},
+		{"implausible fahrenheit temperature", func(i *Instant) {
+			i.Temperature, i.DewPoint, i.PressureMSL, i.Units = 150, 68, 29.92, fahrenheit
+		}, []string{"temperature"}},
+		{"implausible inHg pressure", func(i *Instant) {
+			i.Temperature, i.DewPoint, i.PressureMSL, i.Units = 50, 32, 1013.2, fahrenheit
+		}, []string{"pressure_msl"}},
+		{"multiple fields", func(i *Instant) { i.Temperature, i.RelativeHumidity = 3276.7, 250 },
+			[]string{"temperature", "relative_humidity"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			instant := valid
+			tc.modify(&instant)
+			data := NewData()
+			data.Current = instant
+			data.Forecast[NewDayHour(now)] = valid
+			data.Forecast[NewDayHour(now.Add(time.Hour))] = instant
+
+			validation := data.Validate()
+			if !slices.Equal(validation.Current, tc.invalid) {
+				t.Errorf("expected invalid current fields to be %v, got %v", tc.invalid, validation.Current)
+			}
+			if validation.CurrentValid() != (len(tc.invalid) == 0) {
+				t.Errorf("expected current valid to be %t", len(tc.invalid) == 0)
+			}
+			wantDropped, wantForecasts := 0, 2
+			if len(tc.invalid) > 0 {
+				wantDropped, wantForecasts = 1, 1
+			}
+			if validation.Dropped != wantDropped {
+				t.Errorf("expected %d dropped forecast entries, got %d", wantDropped, validation.Dropped)
+			}
+			if len(data.Forecast) != wantForecasts {
+				t.Errorf("expected %d forecast entries, got %d", wantForecasts, len(data.Forecast))
+			}
+			if _, ok := data.InstantAt(now); !ok {
+				t.Error("expected plausible forecast entry to be kept")
+			}
+			if validation.Clean() != (len(tc.invalid) == 0) {
+				t.Errorf("expected clean to be %t", len(tc.invalid) == 0)
+			}
+		})
+	}
+	t.Run("fields of dropped forecast entries are sorted and unique", func(t *testing.T) {
+		data := NewData()
+		data.Current = valid
+		for i, temp := range []float64{3276.7, 99, 3276.7} {
+			at := time.Date(2025, 1, 1, i, 0, 0, 0, time.UTC)
+			data.Forecast[NewDayHour(at)] = Instant{Temperature: temp, RelativeHumidity: 250}
+		}
+		validation := data.Validate()
+		want := []string{"relative_humidity", "temperature"}
+		if !slices.Equal(validation.Fields, want) {
+			t.Errorf("expected fields to be %v, got %v", want, validation.Fields)
+		}
+		if validation.Dropped != 3 || len(data.Forecast) != 0 {
+			t.Errorf("expected all 3 forecast entries to be dropped, got %d", validation.Dropped)
+		}
+	})
+	t.Run("nil data is valid", func(t *testing.T) {
+		var data *Data
+		if !data.Validate().Clean() {
+			t.Error("expected nil data to be valid")
+		}
+	})
+}
+
+func TestDefaultUnits(t *testing.T) {
+	if got := DefaultUnits(UnitSystemImperial); got.Temperature != UnitFahrenheit || got.WindSpeed != UnitMph ||
+		got.Pressure != UnitHPa || got.Precipitation != UnitInch {
+		t.Errorf("unexpected imperial units: %+v", got)
+	}
+	if got := DefaultUnits("unknown"); got != DefaultUnits(UnitSystemMetric) {
+		t.Errorf("expected unknown unit system to default to metric, got %+v", got)
+	}
+}
+
+func TestCountryUnitSystem(t *testing.T) {
+	for code, want := range map[string]string{
+		"US": UnitSystemImperial,
+		"lr": UnitSystemImperial,
+		"MM": UnitSystemImperial,
+		"DE": UnitSystemMetric,
+		"GB": UnitSystemMetric,
+		"":   UnitSystemMetric,
+	} {
+		if got := CountryUnitSystem(code); got != want {
+			t.Errorf("expected unit system %s for country %q, got %s", want, code, got)
+		}
+	}
+}
+
+func TestUnits_WithDefaults(t *testing.T) {
+	defaults := DefaultUnits(UnitSystemImperial).Units()
+	want := Units{
+		Temperature: "°F", WindSpeed: "mph", Humidity: "%", Pressure: "hPa", WindDirection: "°", Precipitation: "inch",
+	}
+	if defaults != want {
+		t.Errorf("expected imperial unit symbols to be %+v, got %+v", want, defaults)
+	}
+
+	got := Units{Temperature: "°C", Precipitation: "mm"}.WithDefaults(defaults)
+	want.Temperature, want.Precipitation = "°C", "mm"
+	if got != want {
+		t.Errorf("expected units with defaults to be %+v, got %+v", want, got)
+	}
+}
+
+func TestInstant_Convert(t *testing.T) {
+	metric := Instant{
+		Temperature: 20, ApparentTemperature: 10, DewPoint: 0, WindSpeed: 36, WindGusts: 72, PressureMSL: 1013.25,
+		Units: Units{Temperature: "°C", WindSpeed: "km/h", Pressure: "hPa", Humidity: "%"},
+	}
+	tests := []struct {
+		name  string
+		in    Instant
+		prefs UnitPreferences
+		want  Instant
+	}{
+		{"no preferences", metric, UnitPreferences{}, metric},
+		{"same units", metric, DefaultUnits(UnitSystemMetric), metric},
+		{
+			"uk style units", metric,
+			UnitPreferences{Temperature: UnitCelsius, WindSpeed: UnitMph, Pressure: UnitMmHg},
+			Instant{
+				Temperature: 20, ApparentTemperature: 10, DewPoint: 0, WindSpeed: 22.369, WindGusts: 44.739,
+				PressureMSL: 760,
+				Units:       Units{Temperature: "°C", WindSpeed: "mph", Pressure: "mmHg", Humidity: "%"},
+			},
+		},
+		{
+			"all converted", metric,
+			UnitPreferences{Temperature: UnitFahrenheit, WindSpeed: UnitMs, Pressure: UnitInHg},
+			Instant{
+				Temperature: 68, ApparentTemperature: 50, DewPoint: 32, WindSpeed: 10, WindGusts: 20,
+				PressureMSL: 29.921,
+				Units:       Units{Temperature: "°F", WindSpeed: "m/s", Pressure: "inHg", Humidity: "%"},
+			},
+		},
+		{
+			"fahrenheit and knots to metric",
+			Instant{Temperature: 50, WindSpeed: 10, Units: Units{Temperature: "°F", WindSpeed: "kn"}},
+			UnitPreferences{Temperature: UnitCelsius, WindSpeed: UnitKmh},
+			Instant{Temperature: 10, DewPoint: -17.778, ApparentTemperature: -17.778, WindSpeed: 18.52,
+				Units: Units{Temperature: "°C", WindSpeed: "km/h"}},
+		},
+		{
+			"millimeter to inch",
+			Instant{Precipitation: 12.7, Units: Units{Precipitation: "mm"}},
+			UnitPreferences{Precipitation: UnitInch},
+			Instant{Precipitation: 0.5, Units: Units{Precipitation: "inch"}},
+		},
+		{
+			"inch to millimeter",
+			Instant{Precipitation: 0.5, Units: Units{Precipitation: "inch"}},
+			UnitPreferences{Precipitation: UnitMillimeter},
+			Instant{Precipitation: 12.7, Units: Units{Precipitation: "mm"}},
+		},
+		{
+			"unknown source units are kept",
+			Instant{WindSpeed: 10, Units: Units{WindSpeed: "furlongs"}},
+			UnitPreferences{WindSpeed: UnitKmh},
+			Instant{WindSpeed: 10, Units: Units{WindSpeed: "furlongs"}},
+		},
+	}
+	round := func(val float64) float64 { return math.Round(val*1000) / 1000 }
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.in.Convert(tc.prefs)
+			got.Temperature, got.ApparentTemperature = round(got.Temperature), round(got.ApparentTemperature)
+			got.DewPoint, got.PressureMSL = round(got.DewPoint), round(got.PressureMSL)
+			got.WindSpeed, got.WindGusts = round(got.WindSpeed), round(got.WindGusts)
+			got.Precipitation = round(got.Precipitation)
+			if got != tc.want {
+				t.Errorf("expected converted instant to be %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestData_ResolveIsDay(t *testing.T) {
+	// Berlin sets around 21:30 CEST in June and the sun doesn't set at the North Cape
+	berlin := geobus.Coordinate{Lat: 52.52, Lon: 13.405}
+	northCape := geobus.Coordinate{Lat: 71.17, Lon: 25.78}
+	zone, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %s", err)
+	}
+	afternoon := time.Date(2025, 6, 14, 17, 0, 0, 0, zone)
+	evening := time.Date(2025, 6, 14, 23, 0, 0, 0, zone)
+	tests := []struct {
+		name    string
+		coords  geobus.Coordinate
+		instant Instant
+		want    bool
+	}{
+		{"afternoon", berlin, Instant{InstantTime: afternoon}, true},
+		{"evening", berlin, Instant{InstantTime: evening}, false},
+		{"morning", berlin, Instant{InstantTime: time.Date(2025, 6, 14, 6, 0, 0, 0, zone)}, true},
+		{"before sunrise", berlin, Instant{InstantTime: time.Date(2025, 6, 14, 4, 0, 0, 0, zone)}, false},
+		{"midnight sun", northCape, Instant{InstantTime: evening}, true},
+		{"polar night", northCape, Instant{InstantTime: time.Date(2025, 12, 14, 12, 0, 0, 0, zone)}, false},
+		{"reported flag is kept", berlin, Instant{InstantTime: evening, IsDay: true, HasIsDay: true}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := &Data{
+				Coordinates: tc.coords,
+				Current:     tc.instant,
+				Forecast:    map[DayHour]Instant{NewDayHour(tc.instant.InstantTime): tc.instant},
+			}
+			data.ResolveIsDay()
+			if data.Current.IsDay != tc.want {
+				t.Errorf("expected current is day to be %t, got %t", tc.want, data.Current.IsDay)
+			}
+			if fcast := data.Forecast[NewDayHour(tc.instant.InstantTime)]; fcast.IsDay != tc.want {
+				t.Errorf("expected forecast is day to be %t, got %t", tc.want, fcast.IsDay)
+			}
+		})
+	}
+	t.Run("nil data is ignored", func(t *testing.T) {
+		var data *Data
+		data.ResolveIsDay()
+	})
+}
+
+func TestData_Merge(t *testing.T) {
+	now := time.Date(2026, 1, 16, 12, 30, 0, 0, time.UTC)
+	coords := geobus.Coordinate{Lat: 52.52, Lon: 13.405}
+	newData := func(generatedAt time.Time, temp float64, offsets ...int) *Data {
+		data := NewData()
+		data.GeneratedAt = generatedAt
+		data.Coordinates = coords
+		data.Timezone = "UTC"
+		for _, offset := range offsets {
+			at := now.Add(time.Hour * time.Duration(offset))
+			data.Forecast[data.DayHour(at)] = Instant{InstantTime: at, Temperature: temp}
+		}
+		return data
+	}
+	temperatureAt := func(data *Data, offset int) (float64, bool) {
+		instant, ok := data.InstantAt(now.Add(time.Hour * time.Duration(offset)))
+		return instant.Temperature, ok
+	}
+
+	t.Run("new entries replace old entries and missing future entries are kept", func(t *testing.T) {
+		prev := newData(now.Add(-time.Hour), 1, 0, 1, 2, 3, 4, 5)
+		data := newData(now, 2, 0, 1, 2)
+		if kept := data.Merge(prev, time.Hour*6, now); kept != 3 {
+			t.Errorf("expected 3 entries to be kept, got %d", kept)
+		}
+		for offset, want := range map[int]float64{0: 2, 1: 2, 2: 2, 3: 1, 4: 1, 5: 1} {
+			got, ok := temperatureAt(data, offset)
+			if !ok {
+				t.Errorf("expected entry at offset %d to be present", offset)
+				continue
+			}
+			if got != want {
+				t.Errorf("expected temperature at offset %d to be %f, got %f", offset, want, got)
+			}
+		}
+	})
+	t.Run("entries older than the max age are dropped", func(t *testing.T) {
+		prev := newData(now.Add(-time.Hour*7), 1, 0, 1, 2, 3)
+		data := newData(now, 2, 0, 1)
+		if kept := data.Merge(prev, time.Hour*6, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+		if len(data.Forecast) != 2 {
+			t.Errorf("expected 2 forecast entries, got %d", len(data.Forecast))
+		}
+	})
+	t.Run("kept entries retain their original fetch time", func(t *testing.T) {
+		first := newData(now.Add(-time.Hour*5), 1, 0, 1, 2, 3)
+		second := newData(now.Add(-time.Hour*2), 2, 0, 1)
+		second.Merge(first, time.Hour*6, now.Add(-time.Hour*2))
+		third := newData(now, 3, 0)
+		if kept := third.Merge(second, time.Hour*4, now); kept != 1 {
+			t.Errorf("expected 1 entry to be kept, got %d", kept)
+		}
+		if _, ok := temperatureAt(third, 2); ok {
+			t.Error("expected entry of the first fetch to be dropped")
+		}
+		if got, _ := temperatureAt(third, 1); got != 2 {
+			t.Errorf("expected entry of the second fetch to be kept, got temperature %f", got)
+		}
+	})
+	t.Run("entries more than 24 hours in the past are pruned", func(t *testing.T) {
+		prev := newData(now.Add(-time.Hour), 1, -26, -25, 3)
+		data := newData(now, 2, -25, -24, -23, 0)
+		data.Merge(prev, time.Hour*6, now)
+		for offset, want := range map[int]bool{-26: false, -25: false, -24: true, -23: true, 0: true, 3: true} {
+			if _, ok := temperatureAt(data, offset); ok != want {
+				t.Errorf("expected entry at offset %d to be present: %t, got %t", offset, want, ok)
+			}
+		}
+	})
+	t.Run("previous data of a different location is ignored", func(t *testing.T) {
+		prev := newData(now.Add(-time.Hour), 1, 0, 1, 2)
+		prev.Coordinates = geobus.Coordinate{Lat: 48.137, Lon: 11.575}
+		data := newData(now, 2, 0)
+		if kept := data.Merge(prev, time.Hour*6, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+	})
+	t.Run("previous data in different units is ignored", func(t *testing.T) {
+		prev := newData(now.Add(-time.Hour), 34, 0, 1, 2)
+		prev.Current.Units = DefaultUnits(UnitSystemImperial).Units()
+		data := newData(now, 2, 0)
+		data.Current.Units = DefaultUnits(UnitSystemMetric).Units()
+		if kept := data.Merge(prev, time.Hour*6, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+	})
+	t.Run("zero max age disables keeping entries", func(t *testing.T) {
+		prev := newData(now, 1, 0, 1, 2)
+		data := newData(now, 2, 0)
+		if kept := data.Merge(prev, 0, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+	})
+	t.Run("nil data", func(t *testing.T) {
+		var data *Data
+		if kept := data.Merge(newData(now, 1, 0), time.Hour, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+		if kept := newData(now, 1, 0).Merge(nil, time.Hour, now); kept != 0 {
+			t.Errorf("expected no entries to be kept, got %d", kept)
+		}
+	})
+}
+
+func TestEncode(t *testing.T) {
+	now := time.Date(2026, 1, 16, 12, 0, 0, 0, time.UTC)
+	units := Units{Temperature: "°C", WindSpeed: "km/h", Humidity: "%", Pressure: "hPa", Precipitation: "mm"}
+	newData := func(generatedAt time.Time, offsets ...int) *Data {
+		data := NewData()
+		data.GeneratedAt = generatedAt
+		data.Coordinates = geobus.Coordinate{Lat: 52.52, Lon: 13.405, Found: true}
+		data.SetTimezone("Europe/Berlin")
+		data.Elevation = 38
+		data.Source = "open-meteo"
+		data.Current = Instant{InstantTime: generatedAt, Temperature: 4.2, IsDay: true, Units: units}
+		for _, offset := range offsets {
+			at := now.Add(time.Hour * time.Duration(offset))
+			data.Forecast[data.DayHour(at)] = Instant{InstantTime: at, Temperature: float64(offset), Units: units}
+		}
+		return data
+	}
+	roundTrip := func(t *testing.T, data *Data) *Data {
+		t.Helper()
+		encoded, err := Encode(data)
+		if err != nil {
+			t.Fatalf("failed to encode weather data: %s", err)
+		}
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode weather data: %s", err)
+		}
+		return decoded
+	}
+
+	t.Run("weather data survives the round trip", func(t *testing.T) {
+		data := newData(now, 3, 0, 2, 1)
+		data.Nowcast = []NowcastStep{
+			{Time: now.Add(NowcastInterval), Precipitation: 0.4, Unit: "mm"},
+			{Time: now.Add(NowcastInterval * 2), Precipitation: 1.2, Unit: "mm"},
+		}
+		if got := roundTrip(t, data); !reflect.DeepEqual(got, data) {
+			t.Errorf("expected decoded weather data to be %+v, got %+v", data, got)
+		}
+	})
+	t.Run("fetch times of merged data survive the round trip", func(t *testing.T) {
+		data := newData(now, 0, 1)
+		data.Merge(newData(now.Add(-time.Hour), 0, 1, 2, 3), time.Hour*6, now)
+		got := roundTrip(t, data)
+		if !reflect.DeepEqual(got, data) {
+			t.Errorf("expected decoded weather data to be %+v, got %+v", data, got)
+		}
+		if fetchedAt := got.entryFetchedAt(NewDayHour(now.Add(time.Hour * 3))); !fetchedAt.Equal(now.Add(-time.Hour)) {
+			t.Errorf("expected kept entry to be fetched at %s, got %s", now.Add(-time.Hour), fetchedAt)
+		}
+	})
+	t.Run("empty weather data survives the round trip", func(t *testing.T) {
+		data := NewData()
+		got := roundTrip(t, data)
+		if !reflect.DeepEqual(got, data) {
+			t.Errorf("expected decoded weather data to be %+v, got %+v", data, got)
+		}
+		if got.Forecast == nil {
+			t.Error("expected forecast map to be initialized")
+		}
+	})
+	t.Run("forecast is encoded in chronological order", func(t *testing.T) {
+		encoded, err := Encode(newData(now, 5, 3, 1, 4, 2, 0))
+		if err != nil {
+			t.Fatalf("failed to encode weather data: %s", err)
+		}
+		var raw struct {
+			SchemaVersion int `json:"schema_version"`
+			Forecast      []struct {
+				Time time.Time `json:"time"`
+			} `json:"forecast"`
+		}
+		if err = json.Unmarshal(encoded, &raw); err != nil {
+			t.Fatalf("failed to decode encoded weather data: %s", err)
+		}
+		if raw.SchemaVersion != SchemaVersion {
+			t.Errorf("expected schema version to be %d, got %d", SchemaVersion, raw.SchemaVersion)
+		}
+		if len(raw.Forecast) != 6 {
+			t.Fatalf("expected 6 forecast entries, got %d", len(raw.Forecast))
+		}
+		for i, entry := range raw.Forecast {
+			if want := now.Add(time.Hour * time.Duration(i)); !entry.Time.Equal(want) {
+				t.Errorf("expected forecast entry %d to be at %s, got %s", i, want, entry.Time)
+			}
+		}
+	})
+	t.Run("unsupported schema version fails", func(t *testing.T) {
+		for _, input := range []string{`{"schema_version":2,"forecast":[]}`, `{"forecast":{}}`} {
+			if _, err := Decode([]byte(input)); err == nil {
+				t.Errorf("expected decoding of %s to fail", input)
+			}
+		}
+	})
+	t.Run("invalid JSON fails", func(t *testing.T) {
+		if _, err := Decode([]byte("{")); err == nil {
+			t.Error("expected decoding to fail")
+		}
+	})
+}
+
+func TestFailover(t *testing.T) {
+	coords := geobus.Coordinate{Lat: 52.5, Lon: 13.4}
+	log := logger.NewLogger(slog.LevelDebug, io.Discard, nil)
+	newFailover := func(t *testing.T, providers ...Provider) (*Failover, *clock.Fake) {
+		t.Helper()
+		failover, err := NewFailover(log, time.Minute*30, 1, providers...)
+		if err != nil {
+			t.Fatalf("failed to create failover provider: %s", err)
+		}
+		fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+		failover.clock = fake
+		return failover, fake
+	}
+
+	t.Run("providers are required", func(t *testing.T) {
+		if _, err := NewFailover(log, time.Minute, 1); !errors.Is(err, ErrNoProviders) {
+			t.Errorf("expected error to be %s, got %s", ErrNoProviders, err)
+		}
+	})
+	t.Run("primary provider is used while it succeeds", func(t *testing.T) {
+		primary, fallback := &scriptedProvider{name: "primary"}, &scriptedProvider{name: "fallback"}
+		failover, _ := newFailover(t, primary, fallback)
+		for range 3 {
+			data, err := failover.GetWeather(t.Context(), coords)
+			if err != nil {
+				t.Fatalf("failed to fetch weather data: %s", err)
+			}
+			if data.Source != "primary" {
+				t.Errorf("expected source to be %q, got %q", "primary", data.Source)
+			}
+		}
+		if primary.calls != 3 || fallback.calls != 0 {
+			t.Errorf("expected 3 primary and 0 fallback calls, got %d and %d", primary.calls, fallback.calls)
+		}
+		if failover.Name() != "primary" || failover.Attribution() != "Weather data by primary" {
+			t.Errorf("expected name and attribution of the primary provider, got %q and %q", failover.Name(),
+				failover.Attribution())
+		}
+	})
+	t.Run("failing primary provider fails over and is probed again", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		fallback := &scriptedProvider{name: "fallback"}
+		failover, fake := newFailover(t, primary, fallback)
+
+		data, err := failover.GetWeather(t.Context(), coords)
+		if err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "fallback" || failover.Name() != "fallback" {
+			t.Errorf("expected the fallback provider to be active, got source %q and name %q", data.Source,
+				failover.Name())
+		}
+
+		// The last working provider is tried first until the probe interval has passed
+		primary.fail = false
+		fake.Advance(time.Minute * 29)
+		if data, err = failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "fallback" || primary.calls != 1 || fallback.calls != 2 {
+			t.Errorf("expected the fallback provider to be used, got source %q, %d primary and %d fallback calls",
+				data.Source, primary.calls, fallback.calls)
+		}
+
+		fake.Advance(time.Minute)
+		if data, err = failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "primary" || failover.Name() != "primary" || fallback.calls != 2 {
+			t.Errorf("expected the primary provider to be active again, got source %q and %d fallback calls",
+				data.Source, fallback.calls)
+		}
+	})
+	t.Run("failing probe keeps the fallback provider", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		fallback := &scriptedProvider{name: "fallback"}
+		failover, fake := newFailover(t, primary, fallback)
+		if _, err := failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+
+		fake.Advance(time.Minute * 30)
+		data, err := failover.GetWeather(t.Context(), coords)
+		if err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "fallback" || primary.calls != 2 || fallback.calls != 2 {
+			t.Errorf("expected the probe to fail over, got source %q, %d primary and %d fallback calls",
+				data.Source, primary.calls, fallback.calls)
+		}
+
+		// The failed probe restarts the probe interval
+		fake.Advance(time.Minute * 15)
+		if _, err = failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if primary.calls != 2 || fallback.calls != 3 {
+			t.Errorf("expected no probe within the interval, got %d primary and %d fallback calls", primary.calls,
+				fallback.calls)
+		}
+	})
+	t.Run("failing provider is retried until the failover threshold", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		fallback := &scriptedProvider{name: "fallback"}
+		failover, err := NewFailover(log, time.Minute*30, 3, primary, fallback)
+		if err != nil {
+			t.Fatalf("failed to create failover provider: %s", err)
+		}
+		for range 2 {
+			if _, err = failover.GetWeather(t.Context(), coords); err == nil {
+				t.Fatal("expected weather fetch below the failover threshold to fail")
+			}
+		}
+		if fallback.calls != 0 {
+			t.Errorf("expected no fallback calls below the failover threshold, got %d", fallback.calls)
+		}
+
+		data, err := failover.GetWeather(t.Context(), coords)
+		if err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "fallback" || primary.calls != 3 {
+			t.Errorf("expected the fallback provider after 3 primary calls, got source %q and %d primary calls",
+				data.Source, primary.calls)
+		}
+	})
+	t.Run("success resets the consecutive failures", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		fallback := &scriptedProvider{name: "fallback"}
+		failover, err := NewFailover(log, time.Minute*30, 2, primary, fallback)
+		if err != nil {
+			t.Fatalf("failed to create failover provider: %s", err)
+		}
+		for _, fail := range []bool{true, false, true} {
+			primary.fail = fail
+			_, err = failover.GetWeather(t.Context(), coords)
+			if fail == (err == nil) {
+				t.Errorf("expected weather fetch to fail: %t, got error: %v", fail, err)
+			}
+		}
+		if fallback.calls != 0 {
+			t.Errorf("expected no fallback calls, got %d", fallback.calls)
+		}
+	})
+	t.Run("failing fallback provider fails over to the next provider", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		second := &scriptedProvider{name: "second"}
+		third := &scriptedProvider{name: "third"}
+		failover, _ := newFailover(t, primary, second, third)
+		if _, err := failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+
+		second.fail = true
+		data, err := failover.GetWeather(t.Context(), coords)
+		if err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "third" || primary.calls != 2 {
+			t.Errorf("expected the third provider after trying the primary again, got source %q and %d "+
+				"primary calls", data.Source, primary.calls)
+		}
+	})
+	t.Run("all providers failing returns all errors", func(t *testing.T) {
+		primary := &scriptedProvider{name: "primary", fail: true}
+		fallback := &scriptedProvider{name: "fallback", fail: true}
+		failover, _ := newFailover(t, primary, fallback)
+		_, err := failover.GetWeather(t.Context(), coords)
+		if err == nil {
+			t.Fatal("expected weather fetch to fail")
+		}
+		for _, want := range []string{"primary: intentionally failing", "fallback: intentionally failing"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got %q", want, err)
+			}
+		}
+		if failover.Name() != "primary" {
+			t.Errorf("expected the primary provider to stay active, got %q", failover.Name())
+		}
+	})
+	t.Run("provider rejecting its credentials is disabled", func(t *testing.T) {
+		authErr := providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))
+		primary := &scriptedProvider{name: "primary", err: authErr}
+		fallback := &scriptedProvider{name: "fallback"}
+		failover, fake := newFailover(t, primary, fallback)
+		if _, err := failover.GetWeather(t.Context(), coords); err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+
+		// The disabled primary provider is not probed again
+		fake.Advance(time.Minute * 30)
+		data, err := failover.GetWeather(t.Context(), coords)
+		if err != nil {
+			t.Fatalf("failed to fetch weather data: %s", err)
+		}
+		if data.Source != "fallback" || primary.calls != 1 || fallback.calls != 2 {
+			t.Errorf("expected the disabled primary provider to be skipped, got source %q, %d primary and %d "+
+				"fallback calls", data.Source, primary.calls, fallback.calls)
+		}
+	})
+	t.Run("all providers rejecting their credentials fails with an authentication error", func(t *testing.T) {
+		authErr := providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))
+		primary := &scriptedProvider{name: "primary", err: authErr}
+		fallback := &scriptedProvider{name: "fallback", err: authErr}
+		failover, _ := newFailover(t, primary, fallback)
+		_, err := failover.GetWeather(t.Context(), coords)
+		if providererr.Kind(err) != providererr.ErrAuth {
+			t.Errorf("expected error to be %s, got %s", providererr.ErrAuth, err)
+		}
+		_, err = failover.GetWeather(t.Context(), coords)
+		if !errors.Is(err, ErrAllDisabled) || !errors.Is(err, providererr.ErrAuth) {
+			t.Errorf("expected error to be %s, got %s", ErrAllDisabled, err)
+		}
+		if primary.calls != 1 || fallback.calls != 1 {
+			t.Errorf("expected no further calls to the disabled providers, got %d primary and %d fallback calls",
+				primary.calls, fallback.calls)
+		}
+	})
+	t.Run("mixed errors are not treated as authentication failure", func(t *testing.T) {
+		authErr := providererr.Wrap(providererr.ErrAuth, errors.New("invalid API key"))
+		transientErr := providererr.Wrap(providererr.ErrTransient, errors.New("connection refused"))
+		primary := &scriptedProvider{name: "primary", err: authErr}
+		fallback := &scriptedProvider{name: "fallback", err: transientErr}
+		failover, _ := newFailover(t, primary, fallback)
+		_, err := failover.GetWeather(t.Context(), coords)
+		if kind := providererr.Kind(err); kind != providererr.ErrTransient {
+			t.Errorf("expected error kind to be %s, got %v", providererr.ErrTransient, kind)
+		}
+	})
+}
+
+// scriptedProvider is a weather provider that succeeds or fails as scripted. If err is set, it fails with
+// that error.
+type scriptedProvider struct {
+	name  string
+	fail  bool
+	err   error
+	calls int
+}
+
+func (p *scriptedProvider) Name() string {
+	return p.name
+}
+
+func (p *scriptedProvider) Attribution() string {
+	return "Weather data by " + p.name
+}
+
+func (p *scriptedProvider) GetWeather(_ context.Context, coords geobus.Coordinate) (*Data, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.fail {
+		return nil, errors.New("intentionally failing")
+	}
+	return &Data{Coordinates: coords, Forecast: make(map[DayHour]Instant)}, nil
+}
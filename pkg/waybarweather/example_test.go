@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package waybarweather_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/wneessen/waybar-weather/pkg/waybarweather"
+)
+
+// This example runs the service and prints the temperature of the current location on every rendering.
+func ExampleClient() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	conf, err := waybarweather.NewConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		return
+	}
+	client, err := waybarweather.New(conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create client:", err)
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case render := <-client.Events():
+				fmt.Printf("%s: %.1f%s\n", render.Context.Address.City, render.Weather.Current.Temperature,
+					render.Weather.Current.Units.Temperature)
+			}
+		}
+	}()
+
+	if err = client.Run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to run client:", err)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+// Package waybarweather allows the waybar-weather service to be embedded into other Go programs, e. g.
+// a custom status bar. The Client is a thin facade over the service: it locates the user, fetches the
+// weather data from the configured providers and renders it with the configured templates, just like the
+// waybar-weather binary does. Instead of reading the waybar JSON from stdout, the renderings can be
+// retrieved with Client.Snapshot or received from Client.Events.
+package waybarweather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"github.com/wneessen/waybar-weather/internal/config"
+	"github.com/wneessen/waybar-weather/internal/i18n"
+	"github.com/wneessen/waybar-weather/internal/logger"
+	"github.com/wneessen/waybar-weather/internal/presenter"
+	"github.com/wneessen/waybar-weather/internal/service"
+	"github.com/wneessen/waybar-weather/internal/weather"
+)
+
+// eventBufferSize is the number of renderings that are buffered in the events channel
+const eventBufferSize = 8
+
+type (
+	// Config is the configuration of the service. See etc/config.toml for the available settings.
+	Config = config.Config
+	// Render is a rendering of the weather data, together with the template context and the raw weather
+	// data it was rendered from.
+	Render = service.Render
+	// TemplateContext is the context that the templates are rendered with.
+	TemplateContext = presenter.TemplateContext
+	// WeatherData is the raw weather data as returned by the weather provider.
+	WeatherData = weather.Data
+)
+
+// ErrConfigRequired is returned if no configuration is passed to New.
+var ErrConfigRequired = errors.New("config is required")
+
+// Client runs the waybar-weather service within the program.
+type Client struct {
+	service *service.Service
+	events  chan Render
+}
+
+// Option configures the Client.
+type Option func(*options)
+
+type options struct {
+	logger    *slog.Logger
+	output    io.Writer
+	transport http.RoundTripper
+}
+
+// WithLogger sets the logger of the service. By default, nothing is logged.
+func WithLogger(log *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+// WithOutput sets the writer that the renderings are printed to as waybar JSON. By default, they are
+// discarded.
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// WithHTTPTransport sets the transport of the HTTP clients that the providers use to query their APIs,
+// e. g. to route the requests through a proxy.
+func WithHTTPTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// NewConfig returns the default configuration, with overrides from the WAYBARWEATHER_ environment
+// variables applied.
+func NewConfig() (*Config, error) {
+	return config.New()
+}
+
+// LoadConfig reads the configuration from the given TOML, YAML or JSON file.
+func LoadConfig(file string) (*Config, error) {
+	return config.NewFromFile(filepath.Dir(file), filepath.Base(file))
+}
+
+// New returns a new Client for the given configuration. The configuration is validated and must not be
+// modified afterward.
+func New(conf *Config, opts ...Option) (*Client, error) {
+	if conf == nil {
+		return nil, ErrConfigRequired
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	// The control socket and the state file belong to the waybar-weather daemon, which an embedded client
+	// must not block or overwrite
+	conf.Control.Disabled = true
+	conf.State.Disabled = true
+
+	o := options{
+		logger: slog.New(slog.DiscardHandler),
+		output: io.Discard,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	t, err := i18n.New(conf.Locale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize localizer: %w", err)
+	}
+	serv, err := service.New(conf, &logger.Logger{Logger: o.logger}, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize waybar-weather service: %w", err)
+	}
+	serv.HTTPTransport = o.transport
+	serv.SetOutput(o.output)
+
+	client := &Client{service: serv, events: make(chan Render, eventBufferSize)}
+	serv.OnRender = client.publish
+	return client, nil
+}
+
+// Run starts the service and blocks until the context is canceled.
+func (c *Client) Run(ctx context.Context) error {
+	return c.service.Run(ctx)
+}
+
+// Snapshot returns the latest rendering of the weather data. The second return value is false, if no
+// weather data has been rendered yet.
+func (c *Client) Snapshot() (Render, bool) {
+	return c.service.Snapshot()
+}
+
+// Events returns a channel that receives every rendering of the weather data. The weather data is
+// rendered on every output interval and whenever it has been updated. If the receiver falls behind,
+// the oldest buffered renderings are dropped. The channel is never closed.
+func (c *Client) Events() <-chan Render {
+	return c.events
+}
+
+// publish sends the rendering to the events channel without blocking the service. If the buffer is
+// full, the oldest rendering is dropped to make room.
+func (c *Client) publish(render Render) {
+	for {
+		select {
+		case c.events <- render:
+			return
+		default:
+		}
+		select {
+		case <-c.events:
+		default:
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package waybarweather
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/wneessen/waybar-weather/internal/testhelper/fakeapi"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("new client with default config", func(t *testing.T) {
+		conf, err := NewConfig()
+		if err != nil {
+			t.Fatalf("failed to create config: %s", err)
+		}
+		client, err := New(conf, nil)
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		if client.Events() == nil {
+			t.Error("expected events channel to be non-nil")
+		}
+		if _, ok := client.Snapshot(); ok {
+			t.Error("expected no snapshot before the service was started")
+		}
+	})
+	t.Run("new client without config fails", func(t *testing.T) {
+		_, err := New(nil)
+		if !errors.Is(err, ErrConfigRequired) {
+			t.Errorf("expected error to be %s, got %s", ErrConfigRequired, err)
+		}
+	})
+	t.Run("new client with invalid config fails", func(t *testing.T) {
+		conf, err := NewConfig()
+		if err != nil {
+			t.Fatalf("failed to create config: %s", err)
+		}
+		conf.Units = "invalid"
+		if _, err = New(conf); err == nil {
+			t.Error("expected client creation with invalid config to fail")
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("config is loaded from file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(file, []byte("units = \"imperial\"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %s", err)
+		}
+		conf, err := LoadConfig(file)
+		if err != nil {
+			t.Fatalf("failed to load config: %s", err)
+		}
+		if conf.Units != "imperial" {
+			t.Errorf("expected units to be %q, got %q", "imperial", conf.Units)
+		}
+	})
+	t.Run("loading a non-existing config file fails", func(t *testing.T) {
+		if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.toml")); err == nil {
+			t.Error("expected loading a non-existing config file to fail")
+		}
+	})
+}
+
+func TestClient_Run(t *testing.T) {
+	fake := fakeapi.New(t)
+	fake.Script(fakeapi.GeoIP, fakeapi.Response{Body: `{"country_code":"DE","region_code":"BE","city":"Berlin",` +
+		`"zip_code":"10117","latitude":52.5126,"longitude":13.3898}`})
+	fake.Script(fakeapi.NominatimReverse, fakeapi.Response{File: "../../testdata/nominatim_berlin.json"})
+	fake.Script(fakeapi.OpenMeteoForecast, fakeapi.Response{File: "../../testdata/open-meteo.json"})
+
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", "unix:path=/nonexistent")
+	t.Setenv("WAYBARWEATHER_LOCALE", "en")
+	t.Setenv("WAYBARWEATHER_TEMPLATES_TEXT", "{{.Address.City}}: {{.Current.TemperatureStr}}")
+	for _, provider := range []string{"GEOAPI", "GPSD", "GEOLOCATION_FILE", "CITYNAME_FILE", "ICHNAEA"} {
+		t.Setenv("WAYBARWEATHER_GEOLOCATION_DISABLE_"+provider, "true")
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		conf, err := NewConfig()
+		if err != nil {
+			t.Fatalf("failed to create config: %s", err)
+		}
+		buf := &lockedBuffer{}
+		client, err := New(conf, WithHTTPTransport(fake.Transport()), WithOutput(buf))
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := client.Run(ctx); err != nil {
+				t.Errorf("failed to run client: %s", err)
+			}
+		}()
+
+		// The location is looked up on start, which renders the weather data once after the minimum render
+		// interval
+		time.Sleep(conf.Intervals.MinRender)
+		synctest.Wait()
+		snapshot, ok := client.Snapshot()
+		if !ok {
+			t.Fatal("expected snapshot after start")
+		}
+		if snapshot.Text != "Berlin: -5.3°C" {
+			t.Errorf("expected snapshot text to be %q, got %q", "Berlin: -5.3°C", snapshot.Text)
+		}
+		if snapshot.Context.Address.City != "Berlin" {
+			t.Errorf("expected snapshot context city to be %q, got %q", "Berlin", snapshot.Context.Address.City)
+		}
+		if snapshot.Weather == nil || snapshot.Weather.Current.Temperature != -5.3 {
+			t.Errorf("expected snapshot to contain the raw weather data, got %v", snapshot.Weather)
+		}
+		var output struct {
+			Text string `json:"text"`
+		}
+		if err = json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("failed to unmarshal waybar output: %s", err)
+		}
+		if output.Text != snapshot.Text {
+			t.Errorf("expected waybar output text to be %q, got %q", snapshot.Text, output.Text)
+		}
+		select {
+		case event := <-client.Events():
+			if event.Text != snapshot.Text {
+				t.Errorf("expected event text to be %q, got %q", snapshot.Text, event.Text)
+			}
+		default:
+			t.Error("expected render event after start")
+		}
+
+		// Every output tick renders the weather data again
+		time.Sleep(conf.Intervals.Output)
+		synctest.Wait()
+		select {
+		case event := <-client.Events():
+			if event.Text != snapshot.Text {
+				t.Errorf("expected event text to be %q, got %q", snapshot.Text, event.Text)
+			}
+		default:
+			t.Error("expected render event after output tick")
+		}
+
+		cancel()
+		<-done
+	})
+}
+
+func TestClient_publish(t *testing.T) {
+	t.Run("oldest renderings are dropped if the buffer is full", func(t *testing.T) {
+		client := &Client{events: make(chan Render, 2)}
+		for _, text := range []string{"first", "second", "third"} {
+			client.publish(Render{Text: text})
+		}
+		for _, want := range []string{"second", "third"} {
+			if got := (<-client.Events()).Text; got != want {
+				t.Errorf("expected event text to be %q, got %q", want, got)
+			}
+		}
+	})
+}
+
+// lockedBuffer is a bytes.Buffer that can be written by the service while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}